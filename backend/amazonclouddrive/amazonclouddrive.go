@@ -89,6 +89,10 @@ func init() {
 		}, {
 			Name: config.ConfigTokenURL,
 			Help: "Token server url - leave blank to use Amazon's.",
+		}, {
+			Name: "checkpoint",
+			Help: "Checkpoint for the changes feed - set by rclone when polling for changes.",
+			Hide: true,
 		}},
 	})
 	flags.VarP(&tempLinkThreshold, "acd-templink-threshold", "", "Files >= this size will be downloaded via their tempLink.")
@@ -198,7 +202,7 @@ func filterRequest(req *http.Request) {
 // NewFs constructs an Fs from the path, container:path
 func NewFs(name, root string) (fs.Fs, error) {
 	root = parsePath(root)
-	baseClient := fshttp.NewClient(fs.Config)
+	baseClient := fshttp.NewClient(fs.ConfigForRemote(name))
 	if do, ok := baseClient.Transport.(interface {
 		SetRequestFilter(f func(req *http.Request))
 	}); ok {
@@ -217,7 +221,7 @@ func NewFs(name, root string) (fs.Fs, error) {
 		root:         root,
 		c:            c,
 		pacer:        pacer.New().SetMinSleep(minSleep).SetPacer(pacer.AmazonCloudDrivePacer),
-		noAuthClient: fshttp.NewClient(fs.Config),
+		noAuthClient: fshttp.NewClient(fs.ConfigForRemote(name)),
 	}
 	f.features = (&fs.Features{
 		CaseInsensitive:         true,
@@ -0,0 +1,452 @@
+// Package archive provides a read only view of a remote with the
+// contents of zip and tar archives shown as directories
+package archive
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/lib/readers"
+	"github.com/pkg/errors"
+)
+
+// Globals
+var (
+	errorReadOnly = errors.New("archive remotes are read only")
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "archive",
+		Description: "Browse zip and tar archives on a remote as directories",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "remote",
+			Help: "Remote containing the archives.\nNormally should contain a ':' and a path, eg \"myremote:path/to/dir\",\n\"myremote:bucket\" or maybe \"myremote:\".",
+		}},
+	})
+}
+
+// NewFs contstructs an Fs from the path, container:path
+func NewFs(name, rpath string) (fs.Fs, error) {
+	remote := config.FileGet(name, "remote")
+	if remote == "" {
+		return nil, errors.New("remote not set in config file")
+	}
+	if strings.HasPrefix(remote, name+":") {
+		return nil, errors.New("can't point archive remote at itself - check the value of the remote setting")
+	}
+	wrappedFs, err := fs.NewFs(remote)
+	if err == fs.ErrorIsFile {
+		return nil, errors.Errorf("remote %q to wrap must be a directory", remote)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to make remote %q to wrap", remote)
+	}
+	f := &Fs{
+		name:    name,
+		root:    strings.Trim(rpath, "/"),
+		wrapped: wrappedFs,
+		indexes: make(map[string]*index),
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(f)
+	if f.root != "" {
+		if _, err := f.NewObject(""); err == nil {
+			f.root = parentDir(f.root)
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// Fs represents a remote with archives shown as directories
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on
+	wrapped  fs.Fs        // the remote containing the archives
+	features *fs.Features // optional features
+	indexMu  sync.Mutex
+	indexes  map[string]*index // indexes of the archives read so far by path
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("Archives in '%s:%s'", f.name, f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	return f.wrapped.Precision()
+}
+
+// Hashes returns the supported hash sets.
+//
+// These are only available for files which aren't in archives.
+func (f *Fs) Hashes() hash.Set {
+	return f.wrapped.Hashes()
+}
+
+// parentDir returns the parent directory of the full path p with ""
+// for the root
+func parentDir(p string) string {
+	dir := path.Dir(p)
+	if dir == "." || dir == "/" {
+		return ""
+	}
+	return dir
+}
+
+// fullPath returns the path in the wrapped remote of remote
+func (f *Fs) fullPath(remote string) string {
+	return path.Join(f.root, remote)
+}
+
+// findArchive looks for an archive in the full path p.
+//
+// If found it returns the archive object and the path inside the
+// archive, which is "" for the root of the archive.
+func (f *Fs) findArchive(p string) (archive fs.Object, inner string, found bool) {
+	if p == "" {
+		return nil, "", false
+	}
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		if !isArchiveName(part) {
+			continue
+		}
+		o, err := f.wrapped.NewObject(strings.Join(parts[:i+1], "/"))
+		if err != nil {
+			// may be a directory with an archive like name
+			continue
+		}
+		return o, strings.Join(parts[i+1:], "/"), true
+	}
+	return nil, "", false
+}
+
+// getIndex returns the index of archive, reading it if it hasn't been
+// read already or if the archive has changed
+func (f *Fs) getIndex(archive fs.Object) (*index, error) {
+	f.indexMu.Lock()
+	defer f.indexMu.Unlock()
+	idx, found := f.indexes[archive.Remote()]
+	if found && idx.matches(archive) {
+		return idx, nil
+	}
+	fs.Debugf(archive, "Reading archive index")
+	idx, err := readIndex(archive)
+	if err != nil {
+		return nil, err
+	}
+	f.indexes[archive.Remote()] = idx
+	return idx, nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+	full := f.fullPath(dir)
+	if archive, inner, found := f.findArchive(full); found {
+		return f.listArchive(dir, archive, inner)
+	}
+	wrappedEntries, err := f.wrapped.List(full)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range wrappedEntries {
+		remote := path.Join(dir, path.Base(entry.Remote()))
+		switch x := entry.(type) {
+		case fs.Object:
+			if isArchiveName(remote) {
+				entries = append(entries, fs.NewDir(remote, x.ModTime()))
+			} else {
+				entries = append(entries, &Object{
+					fs:     f,
+					remote: remote,
+					o:      x,
+				})
+			}
+		case fs.Directory:
+			entries = append(entries, fs.NewDirCopy(x).SetRemote(remote))
+		default:
+			return nil, errors.Errorf("unknown object type %T", entry)
+		}
+	}
+	return entries, nil
+}
+
+// listArchive lists the directory inner in archive, which is dir in
+// this remote
+func (f *Fs) listArchive(dir string, archive fs.Object, inner string) (entries fs.DirEntries, err error) {
+	idx, err := f.getIndex(archive)
+	if err != nil {
+		return nil, err
+	}
+	files, found := idx.dirs[inner]
+	if !found {
+		return nil, fs.ErrorDirNotFound
+	}
+	for _, e := range files {
+		remote := path.Join(dir, path.Base(e.name))
+		if e.isDir {
+			entries = append(entries, fs.NewDir(remote, e.modTime))
+		} else {
+			entries = append(entries, &Object{
+				fs:     f,
+				remote: remote,
+				o:      archive,
+				entry:  e,
+			})
+		}
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(remote string) (fs.Object, error) {
+	full := f.fullPath(remote)
+	archive, inner, found := f.findArchive(full)
+	if !found {
+		o, err := f.wrapped.NewObject(full)
+		if err != nil {
+			return nil, err
+		}
+		return &Object{
+			fs:     f,
+			remote: remote,
+			o:      o,
+		}, nil
+	}
+	if inner == "" {
+		// archives are shown as directories
+		return nil, fs.ErrorNotAFile
+	}
+	idx, err := f.getIndex(archive)
+	if err != nil {
+		return nil, err
+	}
+	e, found := idx.files[inner]
+	if !found {
+		return nil, fs.ErrorObjectNotFound
+	}
+	if e.isDir {
+		return nil, fs.ErrorNotAFile
+	}
+	return &Object{
+		fs:     f,
+		remote: remote,
+		o:      archive,
+		entry:  e,
+	}, nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return nil, errorReadOnly
+}
+
+// Mkdir makes the directory (container, bucket)
+func (f *Fs) Mkdir(dir string) error {
+	return errorReadOnly
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+func (f *Fs) Rmdir(dir string) error {
+	return errorReadOnly
+}
+
+// Object describes a file on the wrapped remote or a file in an
+// archive
+type Object struct {
+	fs     *Fs       // what this object is part of
+	remote string    // The remote path
+	o      fs.Object // the wrapped object or the archive containing entry
+	entry  *entry    // the file in the archive or nil
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the selected checksum of the file
+//
+// Hashes aren't available for files in archives.
+func (o *Object) Hash(t hash.Type) (string, error) {
+	if o.entry != nil {
+		return "", nil
+	}
+	return o.o.Hash(t)
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	if o.entry != nil {
+		return o.entry.size
+	}
+	return o.o.Size()
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime() time.Time {
+	if o.entry != nil {
+		return o.entry.modTime
+	}
+	return o.o.ModTime()
+}
+
+// SetModTime sets the modification time of the object
+func (o *Object) SetModTime(modTime time.Time) error {
+	return errorReadOnly
+}
+
+// Storable returns whether this object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+func (o *Object) Open(options ...fs.OpenOption) (io.ReadCloser, error) {
+	if o.entry == nil {
+		return o.o.Open(options...)
+	}
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.entry.size)
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	if offset > o.entry.size {
+		offset = o.entry.size
+	}
+	if limit < 0 || offset+limit > o.entry.size {
+		limit = o.entry.size - offset
+	}
+	if o.entry.zipFile != nil && o.entry.zipFile.Flags&0x1 != 0 {
+		return nil, errors.New("can't read encrypted files in zip archives")
+	}
+	o.fs.indexMu.Lock()
+	dataOffset, err := o.entry.dataOffset()
+	o.fs.indexMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	switch o.entry.method {
+	case zip.Store:
+		in, err := o.o.Open(&fs.SeekOption{Offset: dataOffset + offset})
+		if err != nil {
+			return nil, err
+		}
+		return readers.NewLimitedReadCloser(in, limit), nil
+	case zip.Deflate:
+		// compressed data has to be read from the start
+		in, err := o.o.Open(&fs.SeekOption{Offset: dataOffset})
+		if err != nil {
+			return nil, err
+		}
+		decompressor := flate.NewReader(readers.NewLimitedReadCloser(in, o.entry.compressedSize))
+		if offset > 0 {
+			_, err = io.CopyN(ioutil.Discard, decompressor, offset)
+			if err != nil {
+				_ = decompressor.Close()
+				_ = in.Close()
+				return nil, errors.Wrap(err, "failed to seek in compressed file")
+			}
+		}
+		return &entryReader{
+			Reader:       io.LimitReader(decompressor, limit),
+			decompressor: decompressor,
+			in:           in,
+		}, nil
+	}
+	return nil, errors.Errorf("can't read files compressed with method %d in zip archives", o.entry.method)
+}
+
+// entryReader reads a compressed file from an archive
+type entryReader struct {
+	io.Reader
+	decompressor io.Closer
+	in           io.Closer // the stream from the archive
+}
+
+// Close the decompressor and the stream from the archive
+func (r *entryReader) Close() error {
+	err := r.decompressor.Close()
+	closeErr := r.in.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return errorReadOnly
+}
+
+// Remove an object
+func (o *Object) Remove() error {
+	return errorReadOnly
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs     = (*Fs)(nil)
+	_ fs.Object = (*Object)(nil)
+)
@@ -0,0 +1,184 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/ncw/rclone/backend/local" // pull in test backend
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testTime = time.Date(2018, 3, 4, 5, 6, 8, 0, time.UTC)
+	testBig  = string(make([]byte, 3*readBlockSize)) + "end"
+)
+
+// writeZip makes a zip archive at p
+func writeZip(t *testing.T, p string) {
+	fd, err := os.Create(p)
+	require.NoError(t, err)
+	zw := zip.NewWriter(fd)
+	for _, file := range []struct {
+		name     string
+		contents string
+		method   uint16
+	}{
+		{"stored.txt", "stored contents", zip.Store},
+		{"dir/deflated.txt", "deflated contents", zip.Deflate},
+		{"dir/sub/big.bin", testBig, zip.Deflate},
+		{"empty/", "", zip.Store},
+	} {
+		header := &zip.FileHeader{Name: file.name, Method: file.method}
+		header.SetModTime(testTime)
+		w, err := zw.CreateHeader(header)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(file.contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, fd.Close())
+}
+
+// writeTar makes a tar archive at p
+func writeTar(t *testing.T, p string) {
+	fd, err := os.Create(p)
+	require.NoError(t, err)
+	tw := tar.NewWriter(fd)
+	for _, file := range []struct {
+		name     string
+		contents string
+		typeflag byte
+	}{
+		{"a/", "", tar.TypeDir},
+		{"a/one.txt", "one", tar.TypeReg},
+		{"a/link", "", tar.TypeSymlink},
+		{"../../two.txt", "two", tar.TypeReg},
+	} {
+		err := tw.WriteHeader(&tar.Header{
+			Name:     file.name,
+			Mode:     0600,
+			Size:     int64(len(file.contents)),
+			ModTime:  testTime,
+			Typeflag: file.typeflag,
+		})
+		require.NoError(t, err)
+		_, err = tw.Write([]byte(file.contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, fd.Close())
+}
+
+// newTestFs makes an archive Fs on a temporary directory containing
+// test.zip, test.tar and a normal file
+func newTestFs(t *testing.T, rpath string) (f *Fs, tidy func(), err error) {
+	root, err := ioutil.TempDir("", "rclone-archive-internal")
+	require.NoError(t, err)
+	writeZip(t, filepath.Join(root, "test.zip"))
+	writeTar(t, filepath.Join(root, "test.tar"))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "file.txt"), []byte("file"), 0600))
+	config.LoadConfig()
+	config.FileSet("TestArchiveInternal", "type", "archive")
+	config.FileSet("TestArchiveInternal", "remote", root)
+	fsys, err := NewFs("TestArchiveInternal", rpath)
+	return fsys.(*Fs), func() {
+		_ = os.RemoveAll(root)
+	}, err
+}
+
+// readAll reads remote with the options given
+func readAll(t *testing.T, f *Fs, remote string, options ...fs.OpenOption) string {
+	o, err := f.NewObject(remote)
+	require.NoError(t, err)
+	return fstest.ReadObject(t, o, options...)
+}
+
+func TestList(t *testing.T) {
+	f, tidy, err := newTestFs(t, "")
+	defer tidy()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"file.txt", "test.tar/", "test.zip/"}, fstest.ListNames(t, f, ""))
+	assert.Equal(t, []string{"test.zip/dir/", "test.zip/empty/", "test.zip/stored.txt"}, fstest.ListNames(t, f, "test.zip"))
+	assert.Equal(t, []string{"test.zip/dir/deflated.txt", "test.zip/dir/sub/"}, fstest.ListNames(t, f, "test.zip/dir"))
+	assert.Equal(t, []string(nil), fstest.ListNames(t, f, "test.zip/empty"))
+	assert.Equal(t, []string{"test.tar/a/", "test.tar/two.txt"}, fstest.ListNames(t, f, "test.tar"))
+	assert.Equal(t, []string{"test.tar/a/one.txt"}, fstest.ListNames(t, f, "test.tar/a"))
+
+	_, err = f.List("test.zip/potato")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+}
+
+func TestNewObject(t *testing.T) {
+	f, tidy, err := newTestFs(t, "")
+	defer tidy()
+	require.NoError(t, err)
+
+	o, err := f.NewObject("test.zip/dir/sub/big.bin")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(testBig)), o.Size())
+	assert.True(t, o.ModTime().Equal(testTime), o.ModTime())
+
+	o, err = f.NewObject("test.tar/a/one.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), o.Size())
+	assert.True(t, o.ModTime().Equal(testTime), o.ModTime())
+
+	_, err = f.NewObject("file.txt")
+	require.NoError(t, err)
+
+	for remote, want := range map[string]error{
+		"test.zip":        fs.ErrorNotAFile,
+		"test.zip/dir":    fs.ErrorNotAFile,
+		"test.zip/potato": fs.ErrorObjectNotFound,
+		"test.tar/a/link": fs.ErrorObjectNotFound,
+		"potato.txt":      fs.ErrorObjectNotFound,
+	} {
+		_, err = f.NewObject(remote)
+		assert.Equal(t, want, err, remote)
+	}
+
+	assert.Equal(t, errorReadOnly, o.Remove())
+	assert.Equal(t, errorReadOnly, f.Mkdir("dir"))
+}
+
+func TestOpen(t *testing.T) {
+	f, tidy, err := newTestFs(t, "")
+	defer tidy()
+	require.NoError(t, err)
+
+	assert.Equal(t, "stored contents", readAll(t, f, "test.zip/stored.txt"))
+	assert.Equal(t, "deflated contents", readAll(t, f, "test.zip/dir/deflated.txt"))
+	assert.Equal(t, testBig, readAll(t, f, "test.zip/dir/sub/big.bin"))
+	assert.Equal(t, "one", readAll(t, f, "test.tar/a/one.txt"))
+	assert.Equal(t, "two", readAll(t, f, "test.tar/two.txt"))
+	assert.Equal(t, "file", readAll(t, f, "file.txt"))
+
+	// Seeking and ranges
+	assert.Equal(t, "contents", readAll(t, f, "test.zip/stored.txt", &fs.SeekOption{Offset: 7}))
+	assert.Equal(t, "contents", readAll(t, f, "test.zip/dir/deflated.txt", &fs.SeekOption{Offset: 9}))
+	assert.Equal(t, "flat", readAll(t, f, "test.zip/dir/deflated.txt", &fs.RangeOption{Start: 2, End: 5}))
+	assert.Equal(t, "\x00end", readAll(t, f, "test.zip/dir/sub/big.bin", &fs.RangeOption{Start: -1, End: 4}))
+	assert.Equal(t, "ne", readAll(t, f, "test.tar/a/one.txt", &fs.RangeOption{Start: 1, End: -1}))
+}
+
+func TestRootIsFile(t *testing.T) {
+	f, tidy, err := newTestFs(t, "test.zip/dir/deflated.txt")
+	defer tidy()
+	assert.Equal(t, fs.ErrorIsFile, err)
+	assert.Equal(t, "test.zip/dir", f.Root())
+
+	f, tidy, err = newTestFs(t, "test.zip/dir")
+	defer tidy()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deflated.txt", "sub/"}, fstest.ListNames(t, f, ""))
+}
@@ -0,0 +1,267 @@
+// Read the indexes of zip and tar archives
+
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// readBlockSize is the minimum amount read from an archive at once
+// when reading its index
+const readBlockSize = 64 * 1024
+
+// archive types are recognised by their file name extensions
+const (
+	extZip = ".zip"
+	extTar = ".tar"
+)
+
+// isArchiveName returns true if the file name looks like an archive
+func isArchiveName(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == extZip || ext == extTar
+}
+
+// entry is a file or directory in an archive
+type entry struct {
+	name           string    // path in the archive
+	isDir          bool      // set if this is a directory
+	size           int64     // size of the file
+	modTime        time.Time // modification time
+	method         uint16    // zip compression method - zip.Store for tar files
+	offset         int64     // offset of the data in the archive or -1 if not known
+	compressedSize int64     // size of the data in the archive
+	zipFile        *zip.File // set for files in zip archives to find the offset
+}
+
+// dataOffset returns the offset of the data of the entry in the
+// archive
+func (e *entry) dataOffset() (int64, error) {
+	if e.offset < 0 && e.zipFile != nil {
+		offset, err := e.zipFile.DataOffset()
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to find file in zip archive")
+		}
+		e.offset = offset
+	}
+	return e.offset, nil
+}
+
+// index is the list of the files and directories in an archive
+type index struct {
+	size    int64               // size of the archive when indexed
+	modTime time.Time           // modification time of the archive when indexed
+	files   map[string]*entry   // files and directories by path
+	dirs    map[string][]*entry // contents of each directory, "" for the root
+}
+
+// newIndex makes an empty index for the archive o
+func newIndex(o fs.Object) *index {
+	return &index{
+		size:    o.Size(),
+		modTime: o.ModTime(),
+		files:   make(map[string]*entry),
+		dirs:    map[string][]*entry{"": nil},
+	}
+}
+
+// matches returns true if the index is up to date for the archive o
+func (idx *index) matches(o fs.Object) bool {
+	return idx.size == o.Size() && idx.modTime.Equal(o.ModTime())
+}
+
+// cleanName makes a name from an archive into a path relative to the
+// root of the archive.  Leading "/" and any ".." which would go above
+// the root are removed.
+func cleanName(name string) string {
+	name = path.Clean("/" + strings.Replace(name, `\`, "/", -1))
+	return strings.TrimPrefix(name, "/")
+}
+
+// addDir makes sure the directory name and its parents are in the
+// index
+func (idx *index) addDir(name string) {
+	if _, found := idx.dirs[name]; found {
+		return
+	}
+	idx.dirs[name] = nil
+	idx.add(&entry{name: name, isDir: true, modTime: idx.modTime})
+}
+
+// add puts e in the index, replacing any entry with the same name
+func (idx *index) add(e *entry) {
+	if e.name == "" {
+		return
+	}
+	parent := path.Dir(e.name)
+	if parent == "." {
+		parent = ""
+	}
+	idx.addDir(parent)
+	if old, found := idx.files[e.name]; found {
+		if old.isDir && e.isDir {
+			// keep the contents of the directory
+			old.modTime = e.modTime
+			return
+		}
+		siblings := idx.dirs[parent]
+		for i := range siblings {
+			if siblings[i] == old {
+				siblings = append(siblings[:i], siblings[i+1:]...)
+				break
+			}
+		}
+		idx.dirs[parent] = siblings
+	}
+	idx.files[e.name] = e
+	idx.dirs[parent] = append(idx.dirs[parent], e)
+	if e.isDir {
+		if _, found := idx.dirs[e.name]; !found {
+			idx.dirs[e.name] = nil
+		}
+	}
+}
+
+// readIndex reads the index of the archive o
+func readIndex(o fs.Object) (*index, error) {
+	idx := newIndex(o)
+	ra := &objectReaderAt{o: o, size: o.Size()}
+	var err error
+	switch strings.ToLower(path.Ext(o.Remote())) {
+	case extZip:
+		err = idx.readZip(ra)
+	case extTar:
+		err = idx.readTar(ra)
+	default:
+		err = errors.New("unknown archive type")
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read index of archive %q", o.Remote())
+	}
+	return idx, nil
+}
+
+// readZip reads the index of a zip archive
+func (idx *index) readZip(ra *objectReaderAt) error {
+	zr, err := zip.NewReader(ra, ra.size)
+	if err != nil {
+		return err
+	}
+	for _, zf := range zr.File {
+		name := cleanName(zf.Name)
+		idx.add(&entry{
+			name:           name,
+			isDir:          strings.HasSuffix(zf.Name, "/"),
+			size:           int64(zf.UncompressedSize64),
+			modTime:        zf.ModTime(),
+			method:         zf.Method,
+			offset:         -1,
+			compressedSize: int64(zf.CompressedSize64),
+			zipFile:        zf,
+		})
+	}
+	return nil
+}
+
+// readTar reads the index of a tar archive, skipping over the file
+// contents
+func (idx *index) readTar(ra *objectReaderAt) error {
+	in := io.NewSectionReader(ra, 0, ra.size)
+	tr := tar.NewReader(in)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		// the data of the file starts straight after the header
+		offset, err := in.Seek(0, os.SEEK_CUR)
+		if err != nil {
+			return err
+		}
+		e := &entry{
+			name:    cleanName(header.Name),
+			size:    header.Size,
+			modTime: header.ModTime,
+			method:  zip.Store,
+			offset:  offset,
+		}
+		e.compressedSize = e.size
+		switch header.Typeflag {
+		case tar.TypeDir:
+			e.isDir = true
+		case tar.TypeReg, tar.TypeRegA:
+		default:
+			// ignore links, devices and sparse files
+			continue
+		}
+		idx.add(e)
+	}
+}
+
+// objectReaderAt reads parts of an object, keeping the last block
+// read so many small reads near each other don't each need a request
+type objectReaderAt struct {
+	mu    sync.Mutex
+	o     fs.Object
+	size  int64  // size of the object
+	start int64  // offset of block in the object
+	block []byte // last data read
+}
+
+// ReadAt reads len(p) bytes from the object at off
+func (r *objectReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for len(p) > 0 {
+		if off >= r.size {
+			return n, io.EOF
+		}
+		if off < r.start || off >= r.start+int64(len(r.block)) {
+			err = r.fetch(off, len(p))
+			if err != nil {
+				return n, err
+			}
+		}
+		copied := copy(p, r.block[off-r.start:])
+		p = p[copied:]
+		off += int64(copied)
+		n += copied
+	}
+	return n, nil
+}
+
+// fetch reads at least want bytes of the object at off into the block
+func (r *objectReaderAt) fetch(off int64, want int) error {
+	size := int64(want)
+	if size < readBlockSize {
+		size = readBlockSize
+	}
+	if off+size > r.size {
+		size = r.size - off
+	}
+	in, err := r.o.Open(&fs.SeekOption{Offset: off})
+	if err != nil {
+		return err
+	}
+	block := make([]byte, size)
+	_, err = io.ReadFull(in, block)
+	// ignore errors closing a stream which hasn't been read to the end
+	_ = in.Close()
+	if err != nil {
+		return err
+	}
+	r.start, r.block = off, block
+	return nil
+}
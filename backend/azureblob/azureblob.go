@@ -36,6 +36,8 @@ import (
 
 const (
 	apiVersion         = "2017-04-17"
+	tierAPIVersion     = "2019-02-02" // first version with the rehydrate priority
+	rehydrateTier      = "Hot"        // tier archived blobs are restored to
 	minSleep           = 10 * time.Millisecond
 	maxSleep           = 10 * time.Second
 	decayConstant      = 1    // bigger for slower decay, exponential
@@ -93,6 +95,7 @@ type Fs struct {
 	containerDeleted bool                  // true if we have deleted the container
 	pacer            *pacer.Pacer          // To pace and retry the API calls
 	uploadToken      *pacer.TokenDispenser // control concurrency
+	client           *http.Client          // client for the requests the SDK can't make
 }
 
 // Object describes a azure object
@@ -206,7 +209,7 @@ func NewFs(name, root string) (fs.Fs, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to make azure storage client")
 	}
-	client.HTTPClient = fshttp.NewClient(fs.Config)
+	client.HTTPClient = fshttp.NewClient(fs.ConfigForRemote(name))
 	bc := client.GetBlobService()
 
 	f := &Fs{
@@ -220,6 +223,7 @@ func NewFs(name, root string) (fs.Fs, error) {
 		cc:          bc.GetContainerReference(container),
 		pacer:       pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant),
 		uploadToken: pacer.NewTokenDispenser(fs.Config.Transfers),
+		client:      client.HTTPClient,
 	}
 	f.features = (&fs.Features{
 		ReadMimeType:  true,
@@ -1137,6 +1141,107 @@ func (o *Object) MimeType() string {
 	return o.mimeType
 }
 
+// tierCall makes a request the SDK doesn't support, using method on
+// the blob with the comp parameter if set.  It is authorized with a
+// SAS URI which is valid for a short time.
+//
+// It returns the headers of the response.
+func (o *Object) tierCall(method, comp string, headers map[string]string) (header http.Header, err error) {
+	sasURI, err := o.getBlobReference().GetSASURI(storage.BlobSASOptions{
+		BlobServiceSASPermissions: storage.BlobServiceSASPermissions{
+			Read:  true,
+			Write: true,
+		},
+		SASOptions: storage.SASOptions{
+			Expiry: time.Now().Add(time.Hour),
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make SAS URI")
+	}
+	if comp != "" {
+		sasURI += "&comp=" + comp
+	}
+	err = o.fs.pacer.Call(func() (bool, error) {
+		req, err := http.NewRequest(method, sasURI, nil)
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("x-ms-version", tierAPIVersion)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := o.fs.client.Do(req)
+		if err != nil {
+			return o.fs.shouldRetry(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return o.fs.shouldRetry(storage.AzureStorageServiceError{
+				Code:       resp.Header.Get("x-ms-error-code"),
+				Message:    resp.Status,
+				StatusCode: resp.StatusCode,
+				RequestID:  resp.Header.Get("x-ms-request-id"),
+			})
+		}
+		header = resp.Header
+		return false, nil
+	})
+	return header, err
+}
+
+// Restore rehydrates a blob in the Archive tier by moving it to the
+// Hot tier.  The priority is Standard or High, or "" for Standard.
+//
+// Rehydrated blobs stay in the Hot tier so lifetime isn't used.
+func (o *Object) Restore(priority string, lifetime int) error {
+	headers := map[string]string{
+		"x-ms-access-tier": rehydrateTier,
+	}
+	if priority != "" {
+		rehydratePriority := strings.Title(strings.ToLower(priority))
+		if rehydratePriority != "Standard" && rehydratePriority != "High" {
+			return errors.Errorf("unknown restore priority %q - must be Standard or High", priority)
+		}
+		headers["x-ms-rehydrate-priority"] = rehydratePriority
+	}
+	_, err := o.tierCall("PUT", "tier", headers)
+	if storageErr, ok := err.(storage.AzureStorageServiceError); ok && storageErr.Code == "BlobBeingRehydrated" {
+		return nil
+	}
+	return err
+}
+
+// RestoreStatus reads the restore status of the blob from its access
+// tier and archive status
+func (o *Object) RestoreStatus() (fs.RestoreStatus, error) {
+	header, err := o.tierCall("HEAD", "", nil)
+	if storageErr, ok := err.(storage.AzureStorageServiceError); ok && storageErr.StatusCode == http.StatusNotFound {
+		return "", fs.ErrorObjectNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return restoreStatus(header.Get("x-ms-access-tier"), header.Get("x-ms-archive-status")), nil
+}
+
+// Archived returns true as the SDK doesn't read the access tier of
+// the blobs when listing so any blob may be archived
+func (o *Object) Archived() bool {
+	return true
+}
+
+// restoreStatus works out the restore status from the access tier and
+// archive status of a blob
+func restoreStatus(accessTier, archiveStatus string) fs.RestoreStatus {
+	switch {
+	case strings.HasPrefix(archiveStatus, "rehydrate-pending-to-"):
+		return fs.RestoreStatusRestoring
+	case accessTier == "Archive":
+		return fs.RestoreStatusArchived
+	}
+	return fs.RestoreStatusOnline
+}
+
 // Check the interfaces are satisfied
 var (
 	_ fs.Fs        = &Fs{}
@@ -1145,4 +1250,5 @@ var (
 	_ fs.ListRer   = &Fs{}
 	_ fs.Object    = &Object{}
 	_ fs.MimeTyper = &Object{}
+	_ fs.Restorer  = &Object{}
 )
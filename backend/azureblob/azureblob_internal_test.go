@@ -0,0 +1,33 @@
+// +build go1.7
+
+package azureblob
+
+import (
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestoreStatus(t *testing.T) {
+	for _, test := range []struct {
+		accessTier    string
+		archiveStatus string
+		want          fs.RestoreStatus
+	}{
+		{"Hot", "", fs.RestoreStatusOnline},
+		{"Cool", "", fs.RestoreStatusOnline},
+		{"Archive", "", fs.RestoreStatusArchived},
+		{"Archive", "rehydrate-pending-to-hot", fs.RestoreStatusRestoring},
+		{"Archive", "rehydrate-pending-to-cool", fs.RestoreStatusRestoring},
+	} {
+		assert.Equal(t, test.want, restoreStatus(test.accessTier, test.archiveStatus), test.accessTier+" "+test.archiveStatus)
+	}
+}
+
+func TestRestorePriority(t *testing.T) {
+	o := &Object{}
+	err := o.Restore("Expedited", 1)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Expedited")
+}
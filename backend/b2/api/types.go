@@ -299,3 +299,18 @@ type CancelLargeFileResponse struct {
 	AccountID string `json:"accountId"` // The identifier for the account.
 	BucketID  string `json:"bucketId"`  // The unique ID of the bucket.
 }
+
+// ListPartsRequest is passed to b2_list_parts
+//
+// The response is a ListPartsResponse
+type ListPartsRequest struct {
+	ID              string `json:"fileId"`                    // The ID returned by b2_start_large_file.
+	StartPartNumber int64  `json:"startPartNumber,omitempty"` // The first part to return.
+	MaxPartCount    int    `json:"maxPartCount,omitempty"`    // The maximum number of parts to return from this call. The default value is 100, and the maximum allowed is 1000.
+}
+
+// ListPartsResponse is the response to ListPartsRequest
+type ListPartsResponse struct {
+	Parts          []UploadPartResponse `json:"parts"`          // The parts uploaded so far in part number order.
+	NextPartNumber *int64               `json:"nextPartNumber"` // What to pass in to startPartNumber for the next search to continue where this one left off, or null if there are no more parts.
+}
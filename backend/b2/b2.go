@@ -259,7 +259,7 @@ func NewFs(name, root string) (fs.Fs, error) {
 		account:      account,
 		key:          key,
 		endpoint:     endpoint,
-		srv:          rest.NewClient(fshttp.NewClient(fs.Config)).SetErrorHandler(errorHandler),
+		srv:          rest.NewClient(fshttp.NewClient(fs.ConfigForRemote(name))).SetErrorHandler(errorHandler),
 		pacer:        pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant),
 		bufferTokens: make(chan []byte, fs.Config.Transfers),
 	}
@@ -1,10 +1,16 @@
 package b2
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/ncw/rclone/fs/config"
 	"github.com/ncw/rclone/fstest"
+	"github.com/ncw/rclone/lib/kv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Test b2 string encoding
@@ -168,3 +174,63 @@ func TestParseTimeString(t *testing.T) {
 	}
 
 }
+
+func TestUploadState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-b2-resume")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	oldCacheDir := config.CacheDir
+	defer func() { config.CacheDir = oldCacheDir }()
+	config.CacheDir = dir
+	const key = "account/bucket/file"
+
+	state, err := loadUploadState(key)
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	want := &uploadState{
+		ID:        "id",
+		Size:      300,
+		ModTime:   "981173110123",
+		ChunkSize: 100,
+		SHA1s:     []string{"sha1", "", ""},
+	}
+	require.NoError(t, want.save(key))
+	state, err = loadUploadState(key)
+	require.NoError(t, err)
+	assert.Equal(t, want, state)
+
+	assert.True(t, state.matches(300, "981173110123", 100))
+	assert.False(t, state.matches(301, "981173110123", 100))
+	assert.False(t, state.matches(300, "981173110124", 100))
+	assert.False(t, state.matches(300, "981173110123", 200))
+
+	removeUploadState(key)
+	state, err = loadUploadState(key)
+	require.NoError(t, err)
+	assert.Nil(t, state)
+	removeUploadState(key)
+
+	require.NoError(t, updateUploadStates(func(ns *kv.Namespace) error {
+		return ns.Put(key, []byte("potato"))
+	}))
+	_, err = loadUploadState(key)
+	assert.Error(t, err)
+}
+
+func TestResumableSHA1s(t *testing.T) {
+	state := &uploadState{SHA1s: []string{"a", "b", "c", ""}}
+	// only parts the server has with the same SHA1 can be skipped
+	uploaded := map[int64]string{1: "a", 2: "x", 4: "d"}
+	assert.Equal(t, []string{"a", "", "", ""}, state.resumableSHA1s(4, uploaded))
+	assert.Equal(t, []string{"a", "", "", "", ""}, state.resumableSHA1s(5, uploaded))
+}
+
+func TestResumeKey(t *testing.T) {
+	f := &Fs{account: "account", bucket: "bucket"}
+	key := f.resumeKey("dir/file")
+	assert.Equal(t, "account/bucket/dir/file", key)
+	assert.NotEqual(t, key, f.resumeKey("dir/file2"))
+	f.bucket = "bucket2"
+	assert.NotEqual(t, key, f.resumeKey("dir/file"))
+}
@@ -0,0 +1,180 @@
+// Save the state of large file uploads so they can be resumed
+
+package b2
+
+import (
+	"github.com/ncw/rclone/backend/b2/api"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/lib/kv"
+	"github.com/ncw/rclone/lib/rest"
+	"github.com/pkg/errors"
+)
+
+// The upload states are kept in this kv store facility and namespace
+const (
+	resumeFacility  = "b2"
+	resumeNamespace = "uploads"
+)
+
+// uploadState is what is saved about a large file upload so that it
+// can be carried on from the last part uploaded if it is interrupted
+type uploadState struct {
+	ID        string   `json:"id"`        // ID of the large file from b2_start_large_file
+	Size      int64    `json:"size"`      // size of the file being uploaded
+	ModTime   string   `json:"modTime"`   // modification time of the file from timeString
+	ChunkSize int64    `json:"chunkSize"` // size of each part
+	SHA1s     []string `json:"sha1s"`     // SHA1 of each part uploaded or "" if not uploaded
+}
+
+// resumeKey returns the key the upload state for the object called
+// name in the bucket is saved under
+func (f *Fs) resumeKey(name string) string {
+	return f.account + "/" + f.bucket + "/" + name
+}
+
+// updateUploadStates opens the kv store and calls fn with the
+// namespace the upload states are kept in.
+//
+// The store is only kept open while it is being used so other
+// rclones can use it too.
+func updateUploadStates(fn func(ns *kv.Namespace) error) error {
+	db, err := kv.Open(resumeFacility)
+	if err != nil {
+		return err
+	}
+	err = fn(db.Namespace(resumeNamespace))
+	closeErr := db.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// loadUploadState reads the upload state saved under key returning
+// nil if there isn't one
+func loadUploadState(key string) (state *uploadState, err error) {
+	err = updateUploadStates(func(ns *kv.Namespace) error {
+		state = new(uploadState)
+		return ns.GetJSON(key, state)
+	})
+	if err == kv.ErrNotFound || err == kv.ErrUnsupported {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "corrupted upload state %q", key)
+	}
+	return state, nil
+}
+
+// save writes the upload state under key replacing the old one
+func (state *uploadState) save(key string) error {
+	err := updateUploadStates(func(ns *kv.Namespace) error {
+		return ns.PutJSON(key, state)
+	})
+	if err == kv.ErrUnsupported {
+		return nil
+	}
+	return err
+}
+
+// matches returns true if the state is for an upload of the same
+// file, chunked in the same way
+func (state *uploadState) matches(size int64, modTime string, chunkSize int64) bool {
+	return state.ID != "" && state.Size == size && state.ModTime == modTime && state.ChunkSize == chunkSize
+}
+
+// resumableSHA1s returns the SHA1s of the parts in the state which
+// the server has with the same SHA1, "" for the others, for parts
+// parts
+func (state *uploadState) resumableSHA1s(parts int64, uploaded map[int64]string) []string {
+	sha1s := make([]string, parts)
+	for i := range sha1s {
+		if i < len(state.SHA1s) && state.SHA1s[i] != "" && uploaded[int64(i+1)] == state.SHA1s[i] {
+			sha1s[i] = state.SHA1s[i]
+		}
+	}
+	return sha1s
+}
+
+// listParts returns the SHA1s of the parts uploaded so far to the
+// large file with the ID given keyed by part number
+func (f *Fs) listParts(ID string) (uploaded map[int64]string, err error) {
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/b2_list_parts",
+	}
+	var request = api.ListPartsRequest{
+		ID:           ID,
+		MaxPartCount: 1000,
+	}
+	uploaded = make(map[int64]string)
+	for {
+		var response api.ListPartsResponse
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err := f.srv.CallJSON(&opts, &request, &response)
+			return f.shouldRetry(resp, err)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range response.Parts {
+			uploaded[part.PartNumber] = part.SHA1
+		}
+		if response.NextPartNumber == nil {
+			break
+		}
+		request.StartPartNumber = *response.NextPartNumber
+	}
+	return uploaded, nil
+}
+
+// resumeUpload returns the saved state of an earlier upload of o
+// which can be carried on with, or nil if there isn't one.
+//
+// An earlier upload of a different version of the file is cancelled.
+func (f *Fs) resumeUpload(o *Object, stateKey string, size int64, modTime string, parts int64) *uploadState {
+	state, err := loadUploadState(stateKey)
+	if err != nil {
+		fs.Errorf(o, "Can't resume upload: %v", err)
+		removeUploadState(stateKey)
+		return nil
+	}
+	if state == nil {
+		return nil
+	}
+	if !state.matches(size, modTime, int64(chunkSize)) {
+		fs.Debugf(o, "Cancelling unfinished upload of a different version of the file")
+		err = f.cancelLargeFile(state.ID)
+		if err != nil {
+			fs.Debugf(o, "Failed to cancel unfinished upload: %v", err)
+		}
+		removeUploadState(stateKey)
+		return nil
+	}
+	uploaded, err := f.listParts(state.ID)
+	if err != nil {
+		fs.Debugf(o, "Can't resume upload - starting again: %v", err)
+		removeUploadState(stateKey)
+		return nil
+	}
+	state.SHA1s = state.resumableSHA1s(parts, uploaded)
+	done := 0
+	for _, sum := range state.SHA1s {
+		if sum != "" {
+			done++
+		}
+	}
+	fs.Infof(o, "Resuming upload with %d of %d chunks already uploaded", done, parts)
+	return state
+}
+
+// removeUploadState removes the upload state saved under key if
+// there is one
+func removeUploadState(key string) {
+	err := updateUploadStates(func(ns *kv.Namespace) error {
+		return ns.Delete(key)
+	})
+	if err != nil && err != kv.ErrUnsupported {
+		fs.Errorf(nil, "Failed to remove upload state: %v", err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	gohash "hash"
 	"io"
+	"io/ioutil"
 	"strings"
 	"sync"
 
@@ -67,16 +68,20 @@ func newHashAppendingReader(in io.Reader, h gohash.Hash) *hashAppendingReader {
 
 // largeUpload is used to control the upload of large files which need chunking
 type largeUpload struct {
-	f        *Fs                             // parent Fs
-	o        *Object                         // object being uploaded
-	in       io.Reader                       // read the data from here
-	wrap     accounting.WrapFn               // account parts being transferred
-	id       string                          // ID of the file being uploaded
-	size     int64                           // total size
-	parts    int64                           // calculated number of parts, if known
-	sha1s    []string                        // slice of SHA1s for each part
-	uploadMu sync.Mutex                      // lock for upload variable
-	uploads  []*api.GetUploadPartURLResponse // result of get upload URL calls
+	f         *Fs                             // parent Fs
+	o         *Object                         // object being uploaded
+	in        io.Reader                       // read the data from here
+	wrap      accounting.WrapFn               // account parts being transferred
+	id        string                          // ID of the file being uploaded
+	size      int64                           // total size
+	parts     int64                           // calculated number of parts, if known
+	sha1s     []string                        // slice of SHA1s for each part
+	uploadMu  sync.Mutex                      // lock for upload variable
+	uploads   []*api.GetUploadPartURLResponse // result of get upload URL calls
+	resumed   []string                        // SHA1s of the parts uploaded before the upload was resumed
+	stateMu   sync.Mutex                      // lock for state
+	state     *uploadState                    // state saved as parts are uploaded, nil if not resumable
+	resumeKey string                          // key the state is saved under
 }
 
 // newLargeUpload starts an upload of object o from in with metadata in src
@@ -98,7 +103,31 @@ func (f *Fs) newLargeUpload(o *Object, in io.Reader, src fs.ObjectInfo) (up *lar
 		sha1SliceSize = parts
 	}
 
-	modTime := src.ModTime()
+	modTime := timeString(src.ModTime())
+	// unwrap the accounting from the input, we use wrap to put it
+	// back on after the buffering
+	in, wrap := accounting.UnWrap(in)
+	up = &largeUpload{
+		f:     f,
+		o:     o,
+		in:    in,
+		wrap:  wrap,
+		size:  size,
+		parts: parts,
+		sha1s: make([]string, sha1SliceSize),
+	}
+
+	// Uploads of known size can be resumed
+	if size >= 0 {
+		up.resumeKey = f.resumeKey(o.fs.root + remote)
+		up.state = f.resumeUpload(o, up.resumeKey, size, modTime, parts)
+		if up.state != nil {
+			up.id = up.state.ID
+			up.resumed = append([]string(nil), up.state.SHA1s...)
+			return up, nil
+		}
+	}
+
 	opts := rest.Opts{
 		Method: "POST",
 		Path:   "/b2_start_large_file",
@@ -112,7 +141,7 @@ func (f *Fs) newLargeUpload(o *Object, in io.Reader, src fs.ObjectInfo) (up *lar
 		Name:        o.fs.root + remote,
 		ContentType: fs.MimeType(src),
 		Info: map[string]string{
-			timeKey: timeString(modTime),
+			timeKey: modTime,
 		},
 	}
 	// Set the SHA1 if known
@@ -127,22 +156,58 @@ func (f *Fs) newLargeUpload(o *Object, in io.Reader, src fs.ObjectInfo) (up *lar
 	if err != nil {
 		return nil, err
 	}
-	// unwrap the accounting from the input, we use wrap to put it
-	// back on after the buffering
-	in, wrap := accounting.UnWrap(in)
-	up = &largeUpload{
-		f:     f,
-		o:     o,
-		in:    in,
-		wrap:  wrap,
-		id:    response.ID,
-		size:  size,
-		parts: parts,
-		sha1s: make([]string, sha1SliceSize),
+	up.id = response.ID
+	if up.resumeKey != "" {
+		up.state = &uploadState{
+			ID:        up.id,
+			Size:      size,
+			ModTime:   modTime,
+			ChunkSize: int64(chunkSize),
+			SHA1s:     make([]string, parts),
+		}
+		up.saveState()
 	}
 	return up, nil
 }
 
+// saveState saves the upload state so the upload can be resumed
+func (up *largeUpload) saveState() {
+	err := up.state.save(up.resumeKey)
+	if err != nil {
+		fs.Errorf(up.o, "Failed to save upload state - the upload won't be resumable: %v", err)
+	}
+}
+
+// partUploaded records that part has been uploaded in the saved state
+func (up *largeUpload) partUploaded(part int64) {
+	if up.state == nil {
+		return
+	}
+	up.stateMu.Lock()
+	defer up.stateMu.Unlock()
+	up.state.SHA1s[part-1] = up.sha1s[part-1]
+	up.saveState()
+}
+
+// skipPart returns true if part was uploaded before the upload was
+// resumed with the same contents as buf so doesn't need uploading
+func (up *largeUpload) skipPart(part int64, buf []byte) bool {
+	if up.resumed == nil || up.resumed[part-1] == "" {
+		return false
+	}
+	sum := sha1.Sum(buf)
+	hexSum := hex.EncodeToString(sum[:])
+	if hexSum != up.resumed[part-1] {
+		fs.Debugf(up.o, "Chunk %d has changed since it was uploaded - sending it again", part)
+		return false
+	}
+	up.sha1s[part-1] = hexSum
+	// Account for the part as if it had been sent
+	_, _ = io.Copy(ioutil.Discard, up.wrap(bytes.NewReader(buf)))
+	fs.Debugf(up.o, "Skipping chunk %d which was uploaded before", part)
+	return true
+}
+
 // getUploadURL returns the upload info with the UploadURL and the AuthorizationToken
 //
 // This should be returned with returnUploadURL when finished
@@ -255,6 +320,7 @@ func (up *largeUpload) transferChunk(part int64, body []byte) error {
 		fs.Debugf(up.o, "Error sending chunk %d: %v", part, err)
 	} else {
 		fs.Debugf(up.o, "Done sending chunk %d", part)
+		up.partUploaded(part)
 	}
 	return err
 }
@@ -278,26 +344,34 @@ func (up *largeUpload) finish() error {
 	if err != nil {
 		return err
 	}
+	if up.state != nil {
+		removeUploadState(up.resumeKey)
+	}
 	return up.o.decodeMetaDataFileInfo(&response)
 }
 
-// cancel aborts the large upload
-func (up *largeUpload) cancel() error {
+// cancelLargeFile aborts the large file upload with the ID given
+func (f *Fs) cancelLargeFile(ID string) error {
 	opts := rest.Opts{
 		Method: "POST",
 		Path:   "/b2_cancel_large_file",
 	}
 	var request = api.CancelLargeFileRequest{
-		ID: up.id,
+		ID: ID,
 	}
 	var response api.CancelLargeFileResponse
-	err := up.f.pacer.Call(func() (bool, error) {
-		resp, err := up.f.srv.CallJSON(&opts, &request, &response)
-		return up.f.shouldRetry(resp, err)
+	err := f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.CallJSON(&opts, &request, &response)
+		return f.shouldRetry(resp, err)
 	})
 	return err
 }
 
+// cancel aborts the large upload
+func (up *largeUpload) cancel() error {
+	return up.f.cancelLargeFile(up.id)
+}
+
 func (up *largeUpload) managedTransferChunk(wg *sync.WaitGroup, errs chan error, part int64, buf []byte) {
 	wg.Add(1)
 	go func(part int64, buf []byte) {
@@ -320,6 +394,10 @@ func (up *largeUpload) finishOrCancelOnError(err error, errs chan error) error {
 		default:
 		}
 	}
+	if err != nil && up.state != nil {
+		fs.Debugf(up.o, "Leaving large file upload to be resumed after error: %v", err)
+		return err
+	}
 	if err != nil {
 		fs.Debugf(up.o, "Cancelling large file upload due to error: %v", err)
 		cancelErr := up.cancel()
@@ -422,10 +500,16 @@ outer:
 			up.f.putUploadBlock(buf)
 			break outer
 		}
+		remaining -= reqSize
+
+		// Skip the chunk if it was uploaded before resuming
+		if up.skipPart(part, buf) {
+			up.f.putUploadBlock(buf)
+			continue
+		}
 
 		// Transfer the chunk
 		up.managedTransferChunk(&wg, errs, part, buf)
-		remaining -= reqSize
 	}
 	wg.Wait()
 
@@ -1,4 +1,4 @@
-// +build !plan9,go1.7
+// +build !plan9,!js,go1.7
 
 package cache
 
@@ -61,10 +61,11 @@ var (
 	cacheDbPath             = flags.StringP("cache-db-path", "", filepath.Join(config.CacheDir, "cache-backend"), "Directory to cache DB")
 	cacheChunkPath          = flags.StringP("cache-chunk-path", "", filepath.Join(config.CacheDir, "cache-backend"), "Directory to cached chunk files")
 	cacheDbPurge            = flags.BoolP("cache-db-purge", "", false, "Purge the cache DB before")
-	cacheChunkSize          = flags.StringP("cache-chunk-size", "", DefCacheChunkSize, "The size of a chunk")
+	_                       = flags.StringP("cache-chunk-size", "", DefCacheChunkSize, "The size of a chunk") // read with the chunk_size config
 	cacheTotalChunkSize     = flags.StringP("cache-total-chunk-size", "", DefCacheTotalChunkSize, "The total size which the chunks can take up from the disk")
 	cacheChunkCleanInterval = flags.StringP("cache-chunk-clean-interval", "", DefCacheChunkCleanInterval, "Interval at which chunk cleanup runs")
-	cacheInfoAge            = flags.StringP("cache-info-age", "", DefCacheInfoAge, "How much time should object info be stored in cache")
+	cacheChunkMaxAge        = flags.DurationP("cache-chunk-max-age", "", 0, "Remove stored chunks older than this - 0 keeps them until the total size is reached")
+	_                       = flags.StringP("cache-info-age", "", DefCacheInfoAge, "How much time should object info be stored in cache") // read with the info_age config
 	cacheReadRetries        = flags.IntP("cache-read-retries", "", DefCacheReadRetries, "How many times to retry a read from a cache storage")
 	cacheTotalWorkers       = flags.IntP("cache-workers", "", DefCacheTotalWorkers, "How many workers should run in parallel to download chunks")
 	cacheChunkNoMemory      = flags.BoolP("cache-chunk-no-memory", "", DefCacheChunkNoMemory, "Disable the in-memory cache for storing chunks during streaming")
@@ -145,6 +146,10 @@ func init() {
 				},
 			},
 			Optional: true,
+		}, {
+			Name: "plex_token",
+			Help: "The Plex token - set by rclone when it logs in to Plex.",
+			Hide: true,
 		}},
 	})
 }
@@ -163,6 +168,7 @@ type Fs struct {
 	chunkSize          int64
 	chunkTotalSize     int64
 	chunkCleanInterval time.Duration
+	chunkMaxAge        time.Duration
 	readRetries        int
 	totalWorkers       int
 	totalMaxWorkers    int
@@ -189,6 +195,19 @@ func parseRootPath(path string) (string, error) {
 	return strings.Trim(path, "/"), nil
 }
 
+// fingerprint identifies the remote being cached and how it is
+// chunked so the stored cache can be thrown away if either changes.
+//
+// It is made from the config rather than the remote itself so it is
+// the same whichever directory of the cache remote is used.
+func fingerprint(remote string, chunkSize fs.SizeSuffix) string {
+	remoteType := ""
+	if fsInfo, _, _, err := fs.ParseRemote(remote); err == nil {
+		remoteType = fsInfo.Name
+	}
+	return fmt.Sprintf("type=%s,remote=%s,chunk_size=%d", remoteType, remote, int64(chunkSize))
+}
+
 // NewFs constructs a Fs from the path, container:path
 func NewFs(name, rootPath string) (fs.Fs, error) {
 	remote := config.FileGet(name, "remote")
@@ -216,9 +235,6 @@ func NewFs(name, rootPath string) (fs.Fs, error) {
 	plexToken := config.FileGet(name, "plex_token")
 	var chunkSize fs.SizeSuffix
 	chunkSizeString := config.FileGet(name, "chunk_size", DefCacheChunkSize)
-	if *cacheChunkSize != DefCacheChunkSize {
-		chunkSizeString = *cacheChunkSize
-	}
 	err = chunkSize.Set(chunkSizeString)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to understand chunk size %v", chunkSizeString)
@@ -238,9 +254,6 @@ func NewFs(name, rootPath string) (fs.Fs, error) {
 		return nil, errors.Wrapf(err, "failed to understand duration %v", chunkCleanIntervalStr)
 	}
 	infoAge := config.FileGet(name, "info_age", DefCacheInfoAge)
-	if *cacheInfoAge != DefCacheInfoAge {
-		infoAge = *cacheInfoAge
-	}
 	infoDuration, err := time.ParseDuration(infoAge)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to understand duration %v", infoAge)
@@ -261,6 +274,7 @@ func NewFs(name, rootPath string) (fs.Fs, error) {
 		chunkSize:          int64(chunkSize),
 		chunkTotalSize:     int64(chunkTotalSize),
 		chunkCleanInterval: chunkCleanInterval,
+		chunkMaxAge:        *cacheChunkMaxAge,
 		readRetries:        *cacheReadRetries,
 		totalWorkers:       *cacheTotalWorkers,
 		totalMaxWorkers:    *cacheTotalWorkers,
@@ -328,7 +342,8 @@ func NewFs(name, rootPath string) (fs.Fs, error) {
 	fs.Infof(name, "Cache DB path: %v", dbPath)
 	fs.Infof(name, "Cache chunk path: %v", chunkPath)
 	f.cache, err = GetPersistent(dbPath, chunkPath, &Features{
-		PurgeDb: *cacheDbPurge,
+		PurgeDb:     *cacheDbPurge,
+		Fingerprint: fingerprint(remote, chunkSize),
 	})
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to start cache db")
@@ -353,6 +368,9 @@ func NewFs(name, rootPath string) (fs.Fs, error) {
 	fs.Infof(name, "Chunk Size: %v", fs.SizeSuffix(f.chunkSize))
 	fs.Infof(name, "Chunk Total Size: %v", fs.SizeSuffix(f.chunkTotalSize))
 	fs.Infof(name, "Chunk Clean Interval: %v", f.chunkCleanInterval.String())
+	if f.chunkMaxAge > 0 {
+		fs.Infof(name, "Chunk Max Age: %v", f.chunkMaxAge.String())
+	}
 	fs.Infof(name, "Workers: %v", f.totalWorkers)
 	fs.Infof(name, "File Age: %v", f.fileAge.String())
 	if f.cacheWrites {
@@ -434,7 +452,7 @@ Params:
 	return f, fsErr
 }
 
-func (f *Fs) httpExpireRemote(in rc.Params) (out rc.Params, err error) {
+func (f *Fs) httpExpireRemote(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 	out = make(rc.Params)
 	remoteInt, ok := in["remote"]
 	if !ok {
@@ -1418,6 +1436,9 @@ func (f *Fs) CleanUpCache(ignoreLastTs bool) {
 	defer f.cleanupMu.Unlock()
 
 	if ignoreLastTs || time.Now().After(f.lastChunkCleanup.Add(f.chunkCleanInterval)) {
+		if f.chunkMaxAge > 0 {
+			f.cache.CleanChunksByAge(f.chunkMaxAge)
+		}
 		f.cache.CleanChunksBySize(f.chunkTotalSize)
 		f.lastChunkCleanup = time.Now()
 	}
@@ -1,4 +1,4 @@
-// +build !plan9,go1.7
+// +build !plan9,!js,go1.7
 
 package cache_test
 
@@ -716,6 +716,55 @@ func TestInternalMaxChunkSizeRespected(t *testing.T) {
 	require.True(t, boltDb.HasChunk(co, chunkSize*5))
 }
 
+func TestInternalChunksExpired(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-cache-expire")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	boltDb, err := cache.GetPersistent(filepath.Join(dir, "expire.db"), filepath.Join(dir, "expire"), &cache.Features{})
+	require.NoError(t, err)
+	defer boltDb.Close()
+
+	require.NoError(t, boltDb.AddChunk("file", []byte("old"), 0))
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, boltDb.AddChunk("file", []byte("new"), 3))
+
+	boltDb.CleanChunksByAge(50 * time.Millisecond)
+	_, err = boltDb.GetChunkTs("file", 0)
+	require.Error(t, err)
+	_, err = os.Stat(filepath.Join(dir, "expire", "file", "0"))
+	require.True(t, os.IsNotExist(err))
+	_, err = boltDb.GetChunkTs("file", 3)
+	require.NoError(t, err)
+}
+
+func TestInternalFingerprintChanged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-cache-fingerprint")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	dbPath, chunkPath := filepath.Join(dir, "fp.db"), filepath.Join(dir, "fp")
+
+	boltDb, err := cache.GetPersistent(dbPath, chunkPath, &cache.Features{Fingerprint: "one"})
+	require.NoError(t, err)
+	require.NoError(t, boltDb.AddChunk("file", []byte("data"), 0))
+	boltDb.Close()
+
+	// the cache is kept across restarts for the same remote
+	boltDb, err = cache.GetPersistent(dbPath, chunkPath, &cache.Features{Fingerprint: "one"})
+	require.NoError(t, err)
+	_, err = boltDb.GetChunkTs("file", 0)
+	require.NoError(t, err)
+	boltDb.Close()
+
+	// but purged if the remote changes
+	boltDb, err = cache.GetPersistent(dbPath, chunkPath, &cache.Features{Fingerprint: "two"})
+	require.NoError(t, err)
+	defer boltDb.Close()
+	_, err = boltDb.GetChunkTs("file", 0)
+	require.Error(t, err)
+	_, err = os.Stat(filepath.Join(chunkPath, "file", "0"))
+	require.True(t, os.IsNotExist(err))
+}
+
 func TestInternalExpiredEntriesRemoved(t *testing.T) {
 	id := fmt.Sprintf("tieer%v", time.Now().Unix())
 	vfsflags.Opt.DirCacheTime = time.Second * 4 // needs to be lower than the defined
@@ -1,6 +1,6 @@
 // Build for cache for unsupported platforms to stop go complaining
 // about "no buildable Go source files "
 
-// +build plan9 !go1.7
+// +build plan9 js !go1.7
 
 package cache
@@ -1,4 +1,4 @@
-// +build !plan9,go1.7
+// +build !plan9,!js,go1.7
 
 package cache
 
@@ -30,11 +30,17 @@ const (
 	RootTsBucket = "rootTs"
 	DataTsBucket = "dataTs"
 	tempBucket   = "pending"
+	metaBucket   = "meta"
 )
 
+// fingerprintKey is the key in metaBucket of the fingerprint of the
+// remote the cache was made for
+const fingerprintKey = "fingerprint"
+
 // Features flags for this storage type
 type Features struct {
-	PurgeDb bool // purge the db before starting
+	PurgeDb     bool   // purge the db before starting
+	Fingerprint string // identifies the remote being cached - the db is purged if it changes
 }
 
 var boltMap = make(map[string]*Persistent)
@@ -47,6 +53,7 @@ func GetPersistent(dbPath, chunkPath string, f *Features) (*Persistent, error) {
 	defer boltMapMx.Unlock()
 	if b, ok := boltMap[dbPath]; ok {
 		if !b.open {
+			b.features = f
 			err := b.connect()
 			if err != nil {
 				return nil, err
@@ -134,14 +141,49 @@ func (b *Persistent) connect() error {
 		_, _ = tx.CreateBucketIfNotExists([]byte(RootTsBucket))
 		_, _ = tx.CreateBucketIfNotExists([]byte(DataTsBucket))
 		_, _ = tx.CreateBucketIfNotExists([]byte(tempBucket))
+		_, _ = tx.CreateBucketIfNotExists([]byte(metaBucket))
 
 		return nil
 	})
+	err = b.checkFingerprint()
+	if err != nil {
+		return errors.Wrapf(err, "failed to check the fingerprint of %q", b.dbPath)
+	}
 
 	b.open = true
 	return nil
 }
 
+// checkFingerprint purges the cache if it was made for a different
+// remote from the one in the features, then stores the fingerprint of
+// the current one.
+//
+// A DB without a fingerprint was made before they were stored so is
+// kept.
+func (b *Persistent) checkFingerprint() error {
+	if b.features.Fingerprint == "" {
+		return nil
+	}
+	var old string
+	err := b.db.View(func(tx *bolt.Tx) error {
+		old = string(tx.Bucket([]byte(metaBucket)).Get([]byte(fingerprintKey)))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if old == b.features.Fingerprint {
+		return nil
+	}
+	if old != "" {
+		fs.Logf(b, "Purging the cache as the remote it was made for has changed from %q to %q", old, b.features.Fingerprint)
+		b.Purge()
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(metaBucket)).Put([]byte(fingerprintKey), []byte(b.features.Fingerprint))
+	})
+}
+
 // getBucket prepares and cleans a specific path of the form: /var/tmp and will iterate through each path component
 // to get to the nested bucket of the final part (in this example: tmp)
 func (b *Persistent) getBucket(dir string, createIfMissing bool, tx *bolt.Tx) *bolt.Bucket {
@@ -498,9 +540,52 @@ func (b *Persistent) AddChunk(fp string, data []byte, offset int64) error {
 	})
 }
 
-// CleanChunksByAge will cleanup on a cron basis
+// CleanChunksByAge will cleanup chunks which were stored more than
+// chunkAge ago
 func (b *Persistent) CleanChunksByAge(chunkAge time.Duration) {
-	// NOOP
+	b.cleanupMux.Lock()
+	defer b.cleanupMux.Unlock()
+	var cntChunks int
+	var cleaned fs.SizeSuffix
+
+	cutoff := itob(time.Now().Add(-chunkAge).UnixNano())
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		dataTsBucket := tx.Bucket([]byte(DataTsBucket))
+		if dataTsBucket == nil {
+			return errors.Errorf("Couldn't open (%v) bucket", DataTsBucket)
+		}
+		// the keys are timestamps so iterate until the first new enough one
+		c := dataTsBucket.Cursor()
+		for k, v := c.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, v = c.Next() {
+			var ci chunkInfo
+			err := json.Unmarshal(v, &ci)
+			if err != nil {
+				continue
+			}
+			err = c.Delete()
+			if err != nil {
+				fs.Errorf(ci.Path, "failed deleting chunk ts during cleanup (%v): %v", ci.Offset, err)
+				continue
+			}
+			err = os.Remove(path.Join(b.dataPath, ci.Path, strconv.FormatInt(ci.Offset, 10)))
+			if err == nil {
+				cntChunks++
+				cleaned += fs.SizeSuffix(ci.Size)
+			}
+		}
+		if cntChunks > 0 {
+			fs.Infof("cache-cleanup", "expired chunks %v, size: %v", cntChunks, cleaned.String())
+		}
+		return nil
+	})
+
+	if err != nil {
+		if err == bolt.ErrDatabaseNotOpen {
+			// we're likely a late janitor and we need to end quietly as there's no guarantee of what exists anymore
+			return
+		}
+		fs.Errorf("cache", "cleanup failed: %v", err)
+	}
 }
 
 // CleanChunksByNeed is a noop for this implementation
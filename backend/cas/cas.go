@@ -0,0 +1,687 @@
+// Package cas provides a content addressed store on top of another remote
+package cas
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/pkg/errors"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "cas",
+		Description: "Content addressed store on top of a remote",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "remote",
+			Help: "Remote to store the contents and the index in.\nNormally should contain a ':' and a path, eg \"myremote:path/to/dir\",\n\"myremote:bucket\" or maybe \"myremote:\" (not recommended).",
+		}},
+	})
+}
+
+// NewFs contstructs an Fs from the path, container:path
+func NewFs(name, rpath string) (fs.Fs, error) {
+	remote := config.FileGet(name, "remote")
+	if remote == "" {
+		return nil, errors.New("remote not set in config file")
+	}
+	if strings.HasPrefix(remote, name+":") {
+		return nil, errors.New("can't point cas remote at itself - check the value of the remote setting")
+	}
+	wrappedFs, err := fs.NewFs(remote)
+	if err == fs.ErrorIsFile {
+		return nil, errors.Errorf("remote %q to wrap must be a directory", remote)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to make remote %q to wrap", remote)
+	}
+	idx, err := getIndex(remote, wrappedFs)
+	if err != nil {
+		return nil, err
+	}
+	f := &Fs{
+		name:    name,
+		root:    strings.Trim(rpath, "/"),
+		wrapped: wrappedFs,
+		idx:     idx,
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(f)
+	if f.root != "" {
+		idx.mu.Lock()
+		_, isFile := idx.data.Files[f.root]
+		idx.mu.Unlock()
+		if isFile {
+			f.root = parentDir(f.root)
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// Fs represents a content addressed store
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on
+	wrapped  fs.Fs        // the remote the contents are stored in
+	idx      *index       // the index of the files
+	features *fs.Features // optional features
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("Content addressed store '%s:%s'", f.name, f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	return time.Nanosecond
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.SHA1)
+}
+
+// parentDir returns the parent directory of the full path p with ""
+// for the root
+func parentDir(p string) string {
+	dir := path.Dir(p)
+	if dir == "." || dir == "/" {
+		return ""
+	}
+	return dir
+}
+
+// fullPath returns the path in the index of remote
+func (f *Fs) fullPath(remote string) string {
+	return path.Join(f.root, remote)
+}
+
+// relativePath returns the remote for the path in the index p
+func (f *Fs) relativePath(p string) string {
+	if f.root == "" {
+		return p
+	}
+	return strings.TrimPrefix(p, f.root+"/")
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+	full := f.fullPath(dir)
+	f.idx.mu.Lock()
+	defer f.idx.mu.Unlock()
+	if full != "" && !f.idx.data.Dirs[full] {
+		return nil, fs.ErrorDirNotFound
+	}
+	for p, file := range f.idx.data.Files {
+		if parentDir(p) == full {
+			entries = append(entries, &Object{
+				fs:     f,
+				remote: f.relativePath(p),
+				file:   file,
+			})
+		}
+	}
+	for p := range f.idx.data.Dirs {
+		if parentDir(p) == full {
+			entries = append(entries, fs.NewDir(f.relativePath(p), time.Time{}))
+		}
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(remote string) (fs.Object, error) {
+	full := f.fullPath(remote)
+	f.idx.mu.Lock()
+	defer f.idx.mu.Unlock()
+	if file, ok := f.idx.data.Files[full]; ok {
+		return &Object{
+			fs:     f,
+			remote: remote,
+			file:   file,
+		}, nil
+	}
+	if f.idx.data.Dirs[full] {
+		return nil, fs.ErrorNotAFile
+	}
+	return nil, fs.ErrorObjectNotFound
+}
+
+// randomID returns a random id for contents whose hash isn't known
+func randomID() (string, error) {
+	buf := make([]byte, 16)
+	_, err := io.ReadFull(rand.Reader, buf)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to make random id")
+	}
+	return "upload-" + hex.EncodeToString(buf), nil
+}
+
+// upload stores the contents read from in returning the entry for
+// the index.
+//
+// The contents are stored under their SHA-1 so identical contents
+// are only stored once.  If the SHA-1 of src isn't known in advance
+// the contents are uploaded under a random id and then renamed to
+// their SHA-1 if the wrapped remote can move objects, otherwise the
+// random id is used.
+//
+// If the contents end up stored under their SHA-1 and it wasn't known
+// in advance they are protected from removal and the caller must
+// unprotect them.
+func (f *Fs) upload(in io.Reader, src fs.ObjectInfo, options []fs.OpenOption) (file indexFile, err error) {
+	file.ModTime = src.ModTime()
+	file.SHA1, _ = src.Hash(hash.SHA1)
+	if file.SHA1 != "" {
+		file.ID = file.SHA1
+	} else {
+		file.ID, err = randomID()
+		if err != nil {
+			return file, err
+		}
+	}
+	hasher, err := hash.NewMultiHasherTypes(hash.NewHashSet(hash.SHA1))
+	if err != nil {
+		return file, err
+	}
+	in = io.TeeReader(in, hasher)
+	dataRemote := path.Join(dataDir, file.ID)
+	info := object.NewStaticObjectInfo(dataRemote, file.ModTime, src.Size(), true, nil, f.wrapped)
+	o, err := f.wrapped.Put(in, info, options...)
+	if err != nil {
+		return file, err
+	}
+	file.Size = hasher.Size()
+	sum := hasher.Sums()[hash.SHA1]
+	if file.SHA1 != "" {
+		if sum != file.SHA1 {
+			if removeErr := o.Remove(); removeErr != nil {
+				fs.Errorf(o, "Failed to remove corrupted contents: %v", removeErr)
+			}
+			return file, errors.Errorf("corrupted on upload: SHA-1 is %s but expecting %s", sum, file.SHA1)
+		}
+		return file, nil
+	}
+	file.SHA1 = sum
+	file.ID = f.rename(o, file.ID, sum)
+	return file, nil
+}
+
+// rename renames the contents o stored with id to be stored under
+// their SHA-1 if possible, returning the id to use.
+//
+// If it returns sum then the contents are protected from removal.
+func (f *Fs) rename(o fs.Object, id, sum string) (newID string) {
+	f.idx.mu.Lock()
+	f.idx.protect(sum)
+	f.idx.mu.Unlock()
+	defer func() {
+		if newID != sum {
+			f.idx.mu.Lock()
+			f.idx.unprotect(sum)
+			f.idx.mu.Unlock()
+		}
+	}()
+	dataRemote := path.Join(dataDir, sum)
+	if existing, err := f.wrapped.NewObject(dataRemote); err == nil && existing.Size() == o.Size() {
+		// The contents are stored already
+		if err = o.Remove(); err != nil {
+			fs.Debugf(o, "Failed to remove duplicate contents: %v", err)
+			return id
+		}
+		return sum
+	}
+	doMove := f.wrapped.Features().Move
+	if doMove == nil {
+		return id
+	}
+	_, err := doMove(o, dataRemote)
+	if err != nil {
+		fs.Debugf(o, "Failed to rename contents to %q: %v", sum, err)
+		return id
+	}
+	return sum
+}
+
+// removeData removes the contents with id if no file uses them any
+// more and they aren't being uploaded.
+//
+// Call with the lock held.
+func (f *Fs) removeData(id string) {
+	if f.idx.isReferenced(id) || f.idx.uploading[id] > 0 {
+		return
+	}
+	o, err := f.wrapped.NewObject(path.Join(dataDir, id))
+	if err == nil {
+		err = o.Remove()
+	}
+	if err != nil {
+		fs.Debugf(f, "Failed to remove unused contents %q: %v", id, err)
+	}
+}
+
+// setFile sets the index entry for the full path p to file and saves
+// the index along with change, removing any contents the old entry
+// used if no longer needed.
+//
+// Call with the lock held.
+func (f *Fs) setFile(p string, file indexFile, change indexChange) error {
+	old, hadOld := f.idx.data.Files[p]
+	f.idx.data.Files[p] = file
+	change.Files[p] = &file
+	f.idx.addParents(p, change)
+	err := f.idx.save(change)
+	if err != nil {
+		if hadOld {
+			f.idx.data.Files[p] = old
+		} else {
+			delete(f.idx.data.Files, p)
+		}
+		f.idx.removeDirs(change)
+		return err
+	}
+	if hadOld && old.ID != file.ID {
+		f.removeData(old.ID)
+	}
+	return nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	err := o.Update(in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(dir string) error {
+	full := f.fullPath(dir)
+	f.idx.mu.Lock()
+	defer f.idx.mu.Unlock()
+	change := newIndexChange()
+	if full != "" && !f.idx.data.Dirs[full] {
+		f.idx.data.Dirs[full] = true
+		change.Dirs[full] = true
+	}
+	f.idx.addParents(full, change)
+	if len(change.Dirs) == 0 {
+		return nil
+	}
+	err := f.idx.save(change)
+	if err != nil {
+		f.idx.removeDirs(change)
+	}
+	return err
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(dir string) error {
+	full := f.fullPath(dir)
+	f.idx.mu.Lock()
+	defer f.idx.mu.Unlock()
+	if full != "" && !f.idx.data.Dirs[full] {
+		return fs.ErrorDirNotFound
+	}
+	if !f.idx.isEmpty(full) {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	if full == "" {
+		return nil
+	}
+	delete(f.idx.data.Dirs, full)
+	change := newIndexChange()
+	change.Dirs[full] = false
+	err := f.idx.save(change)
+	if err != nil {
+		f.idx.data.Dirs[full] = true
+	}
+	return err
+}
+
+// srcObject returns src as an *Object if it is stored in the same
+// index as f
+func (f *Fs) srcObject(src fs.Object) (*Object, bool) {
+	srcObj, ok := src.(*Object)
+	if !ok || srcObj.fs.idx != f.idx {
+		return nil, false
+	}
+	return srcObj, true
+}
+
+// Copy src to this remote using server side copy operations.
+//
+// This is stored in the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := f.srcObject(src)
+	if !ok {
+		fs.Debugf(src, "Can't copy - not same remote")
+		return nil, fs.ErrorCantCopy
+	}
+	f.idx.mu.Lock()
+	defer f.idx.mu.Unlock()
+	file, ok := f.idx.data.Files[srcObj.fs.fullPath(srcObj.remote)]
+	if !ok {
+		return nil, fs.ErrorObjectNotFound
+	}
+	// Only the index needs changing as the contents are shared
+	err := f.setFile(f.fullPath(remote), file, newIndexChange())
+	if err != nil {
+		return nil, err
+	}
+	return &Object{
+		fs:     f,
+		remote: remote,
+		file:   file,
+	}, nil
+}
+
+// Move src to this remote using server side move operations.
+//
+// This is stored in the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := f.srcObject(src)
+	if !ok {
+		fs.Debugf(src, "Can't move - not same remote")
+		return nil, fs.ErrorCantMove
+	}
+	f.idx.mu.Lock()
+	defer f.idx.mu.Unlock()
+	srcPath := srcObj.fs.fullPath(srcObj.remote)
+	file, ok := f.idx.data.Files[srcPath]
+	if !ok {
+		return nil, fs.ErrorObjectNotFound
+	}
+	dstPath := f.fullPath(remote)
+	if dstPath != srcPath {
+		delete(f.idx.data.Files, srcPath)
+		change := newIndexChange()
+		change.Files[srcPath] = nil
+		err := f.setFile(dstPath, file, change)
+		if err != nil {
+			f.idx.data.Files[srcPath] = file
+			return nil, err
+		}
+	}
+	return &Object{
+		fs:     f,
+		remote: remote,
+		file:   file,
+	}, nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok || srcFs.idx != f.idx {
+		fs.Debugf(srcFs, "Can't move directory - not same remote")
+		return fs.ErrorCantDirMove
+	}
+	srcPath := srcFs.fullPath(srcRemote)
+	dstPath := f.fullPath(dstRemote)
+	if srcPath == "" || inDir(dstPath, srcPath) {
+		return fs.ErrorCantDirMove
+	}
+	f.idx.mu.Lock()
+	defer f.idx.mu.Unlock()
+	if _, isFile := f.idx.data.Files[dstPath]; isFile || dstPath == "" || f.idx.data.Dirs[dstPath] {
+		return fs.ErrorDirExists
+	}
+	if !f.idx.data.Dirs[srcPath] {
+		return fs.ErrorDirNotFound
+	}
+	files := make(map[string]indexFile, len(f.idx.data.Files))
+	for p, file := range f.idx.data.Files {
+		if inDir(p, srcPath) {
+			p = dstPath + strings.TrimPrefix(p, srcPath)
+		}
+		files[p] = file
+	}
+	dirs := make(map[string]bool, len(f.idx.data.Dirs))
+	for p := range f.idx.data.Dirs {
+		if p == srcPath || inDir(p, srcPath) {
+			p = dstPath + strings.TrimPrefix(p, srcPath)
+		}
+		dirs[p] = true
+	}
+	oldFiles, oldDirs := f.idx.data.Files, f.idx.data.Dirs
+	f.idx.data.Files, f.idx.data.Dirs = files, dirs
+	f.idx.addParents(dstPath, newIndexChange())
+	// Write a snapshot rather than journal all the changes
+	err := f.idx.snapshot()
+	if err != nil {
+		f.idx.data.Files, f.idx.data.Dirs = oldFiles, oldDirs
+	}
+	return err
+}
+
+// Object describes a file in the content addressed store
+type Object struct {
+	fs     *Fs       // what this object is part of
+	remote string    // The remote path
+	file   indexFile // the entry in the index
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the SHA-1 of the object
+func (o *Object) Hash(t hash.Type) (string, error) {
+	if t != hash.SHA1 {
+		return "", hash.ErrUnsupported
+	}
+	return o.file.SHA1, nil
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.file.Size
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime() time.Time {
+	return o.file.ModTime
+}
+
+// SetModTime sets the modification time of the object
+//
+// Only the index is changed so this works even if the wrapped remote
+// can't change the objects stored in it.
+func (o *Object) SetModTime(modTime time.Time) error {
+	idx := o.fs.idx
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	p := o.fs.fullPath(o.remote)
+	file, ok := idx.data.Files[p]
+	if !ok {
+		return fs.ErrorObjectNotFound
+	}
+	file.ModTime = modTime
+	err := o.fs.setFile(p, file, newIndexChange())
+	if err != nil {
+		return err
+	}
+	o.file = file
+	return nil
+}
+
+// Storable returns whether this object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+func (o *Object) Open(options ...fs.OpenOption) (io.ReadCloser, error) {
+	data, err := o.fs.wrapped.NewObject(path.Join(dataDir, o.file.ID))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find contents %q", o.file.ID)
+	}
+	return data.Open(options...)
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The new contents are stored and then the index is changed to point
+// to them.
+func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	idx := o.fs.idx
+	sum, _ := src.Hash(hash.SHA1)
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if sum != "" {
+		// No need to upload if the contents are stored already.
+		// This is checked with the lock held so the contents
+		// can't be removed before the index points to them.
+		if data, err := o.fs.wrapped.NewObject(path.Join(dataDir, sum)); err == nil && data.Size() == src.Size() {
+			fs.Debugf(src, "Contents already stored as %q", sum)
+			return o.setFile(indexFile{
+				ID:      sum,
+				SHA1:    sum,
+				Size:    data.Size(),
+				ModTime: src.ModTime(),
+			})
+		}
+		idx.protect(sum)
+		defer idx.unprotect(sum)
+	}
+
+	// Don't hold the lock while uploading
+	idx.mu.Unlock()
+	file, err := o.fs.upload(in, src, options)
+	idx.mu.Lock()
+	if err != nil {
+		return err
+	}
+	if sum == "" && file.ID == file.SHA1 {
+		defer idx.unprotect(file.ID)
+	}
+	return o.setFile(file)
+}
+
+// setFile points the index entry for o at file
+//
+// Call with the lock held.
+func (o *Object) setFile(file indexFile) error {
+	err := o.fs.setFile(o.fs.fullPath(o.remote), file, newIndexChange())
+	if err != nil {
+		return err
+	}
+	o.file = file
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove() error {
+	idx := o.fs.idx
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	p := o.fs.fullPath(o.remote)
+	file, ok := idx.data.Files[p]
+	if !ok {
+		return fs.ErrorObjectNotFound
+	}
+	delete(idx.data.Files, p)
+	change := newIndexChange()
+	change.Files[p] = nil
+	err := idx.save(change)
+	if err != nil {
+		idx.data.Files[p] = file
+		return err
+	}
+	o.fs.removeData(file.ID)
+	return nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs       = (*Fs)(nil)
+	_ fs.Copier   = (*Fs)(nil)
+	_ fs.Mover    = (*Fs)(nil)
+	_ fs.DirMover = (*Fs)(nil)
+	_ fs.Object   = (*Object)(nil)
+)
@@ -0,0 +1,18 @@
+package cas_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/ncw/rclone/fstest/fstests"
+)
+
+// Create the TestCas: remote
+func init() {
+	tempdir := filepath.Join(os.TempDir(), "rclone-cas-test")
+	name := "TestCas"
+	fstests.ExtraConfig = []fstests.ExtraConfigItem{
+		{Name: name, Key: "type", Value: "cas"},
+		{Name: name, Key: "remote", Value: tempdir},
+	}
+}
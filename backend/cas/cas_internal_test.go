@@ -0,0 +1,195 @@
+package cas
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	_ "github.com/ncw/rclone/backend/local" // pull in test backend
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFs makes a cas Fs in a temporary directory
+func newTestFs(t *testing.T) (f *Fs, root string, tidy func()) {
+	root, err := ioutil.TempDir("", "rclone-cas-internal")
+	require.NoError(t, err)
+	config.LoadConfig()
+	config.FileSet("TestCasInternal", "type", "cas")
+	config.FileSet("TestCasInternal", "remote", root)
+	fsys, err := NewFs("TestCasInternal", "")
+	require.NoError(t, err)
+	return fsys.(*Fs), root, func() {
+		indexesMu.Lock()
+		delete(indexes, root)
+		indexesMu.Unlock()
+		_ = os.RemoveAll(root)
+	}
+}
+
+// put stores contents at remote, with the SHA-1 given to the upload
+// if withHash is set
+func put(t *testing.T, f *Fs, remote, contents string, withHash bool) fs.Object {
+	var hashes map[hash.Type]string
+	if withHash {
+		sums, err := hash.Stream(bytes.NewBufferString(contents))
+		require.NoError(t, err)
+		hashes = map[hash.Type]string{hash.SHA1: sums[hash.SHA1]}
+	}
+	src := object.NewStaticObjectInfo(remote, time.Now(), int64(len(contents)), true, hashes, nil)
+	o, err := f.Put(bytes.NewBufferString(contents), src)
+	require.NoError(t, err)
+	return o
+}
+
+// dataNames returns the names of the stored contents
+func dataNames(t *testing.T, root string) (names []string) {
+	infos, err := ioutil.ReadDir(path.Join(root, dataDir))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	return names
+}
+
+func TestContentsStoredOnce(t *testing.T) {
+	for _, withHash := range []bool{false, true} {
+		f, root, tidy := newTestFs(t)
+
+		o1 := put(t, f, "one", "potato", withHash)
+		o2 := put(t, f, "dir/two", "potato", withHash)
+		sha1, err := o1.Hash(hash.SHA1)
+		require.NoError(t, err)
+		assert.Equal(t, []string{sha1}, dataNames(t, root), withHash)
+
+		// The contents stay until nothing uses them
+		require.NoError(t, o1.Remove())
+		assert.Equal(t, []string{sha1}, dataNames(t, root), withHash)
+		require.NoError(t, o2.Remove())
+		assert.Equal(t, []string(nil), dataNames(t, root), withHash)
+
+		tidy()
+	}
+}
+
+func TestIndexPersists(t *testing.T) {
+	f, root, tidy := newTestFs(t)
+	defer tidy()
+
+	put(t, f, "dir/file", "sausage", true)
+
+	// Read the index afresh
+	indexesMu.Lock()
+	delete(indexes, root)
+	indexesMu.Unlock()
+	fsys, err := NewFs("TestCasInternal", "dir/file")
+	assert.Equal(t, fs.ErrorIsFile, err)
+	o, err := fsys.NewObject("file")
+	require.NoError(t, err)
+	in, err := o.Open()
+	require.NoError(t, err)
+	contents, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, "sausage", string(contents))
+
+	// Only the newest index is kept
+	assert.Equal(t, 1, len(indexNames(t, root)))
+}
+
+// indexNames returns the names of the index objects
+func indexNames(t *testing.T, root string) (names []string) {
+	infos, err := ioutil.ReadDir(path.Join(root, indexDir))
+	require.NoError(t, err)
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	return names
+}
+
+// assertSameIndex checks the data in the indexes is the same once
+// saved
+func assertSameIndex(t *testing.T, want, got *index) {
+	wantJSON, err := json.Marshal(&want.data)
+	require.NoError(t, err)
+	gotJSON, err := json.Marshal(&got.data)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(wantJSON), string(gotJSON))
+}
+
+// reloadIndex reads the index for f afresh
+func reloadIndex(t *testing.T, f *Fs, root string) *index {
+	indexesMu.Lock()
+	delete(indexes, root)
+	indexesMu.Unlock()
+	idx, err := getIndex(root, f.wrapped)
+	require.NoError(t, err)
+	return idx
+}
+
+func TestIndexJournal(t *testing.T) {
+	f, root, tidy := newTestFs(t)
+	defer tidy()
+
+	// The first change writes a snapshot and the following ones
+	// are journalled
+	o1 := put(t, f, "dir/one", "one", true)
+	put(t, f, "two", "two", true)
+	require.NoError(t, o1.SetModTime(time.Date(2001, 2, 3, 4, 5, 6, 7, time.UTC)))
+	require.NoError(t, f.Mkdir("empty/sub"))
+	require.NoError(t, f.Rmdir("empty/sub"))
+	o3 := put(t, f, "three", "three", true)
+	require.NoError(t, o3.Remove())
+	assert.Equal(t, 6, len(f.idx.journal))
+	assert.Equal(t, 7, len(indexNames(t, root)))
+
+	// Reading the index applies the journal
+	idx := reloadIndex(t, f, root)
+	assertSameIndex(t, f.idx, idx)
+	assert.Equal(t, f.idx.journal, idx.journal)
+	assert.Equal(t, time.Date(2001, 2, 3, 4, 5, 6, 7, time.UTC), idx.data.Files["dir/one"].ModTime)
+	assert.Equal(t, map[string]bool{"dir": true, "empty": true}, idx.data.Dirs)
+
+	// Once the journal is big enough it is replaced by a snapshot
+	for len(f.idx.journal) > 0 {
+		require.NoError(t, o1.SetModTime(time.Now()))
+	}
+	assert.Equal(t, 1, len(indexNames(t, root)))
+	idx = reloadIndex(t, f, root)
+	assertSameIndex(t, f.idx, idx)
+}
+
+func TestRemoveDataProtected(t *testing.T) {
+	f, root, tidy := newTestFs(t)
+	defer tidy()
+
+	o := put(t, f, "file", "contents", true)
+	sha1, err := o.Hash(hash.SHA1)
+	require.NoError(t, err)
+
+	// Contents being uploaded aren't removed when the last file
+	// using them is
+	f.idx.mu.Lock()
+	f.idx.protect(sha1)
+	f.idx.mu.Unlock()
+	require.NoError(t, o.Remove())
+	assert.Equal(t, []string{sha1}, dataNames(t, root))
+
+	f.idx.mu.Lock()
+	f.idx.unprotect(sha1)
+	f.removeData(sha1)
+	f.idx.mu.Unlock()
+	assert.Equal(t, []string(nil), dataNames(t, root))
+	assert.Equal(t, 0, len(f.idx.uploading))
+}
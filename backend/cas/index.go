@@ -0,0 +1,365 @@
+// The index mapping file names to the ids of their contents
+
+package cas
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/pkg/errors"
+)
+
+const (
+	dataDir      = "data"  // directory in the wrapped remote for the contents
+	indexDir     = "index" // directory in the wrapped remote for the index
+	indexVersion = 1       // version of the index format
+	minJournal   = 100     // smallest journal to replace with a snapshot
+)
+
+// indexFile is the entry in the index for a file
+type indexFile struct {
+	ID      string    `json:"id"`      // name of the contents in dataDir
+	SHA1    string    `json:"sha1"`    // SHA-1 of the contents
+	Size    int64     `json:"size"`    // size of the contents
+	ModTime time.Time `json:"modTime"` // modification time of the file
+}
+
+// indexData is what is stored in the index snapshot objects
+type indexData struct {
+	Version int                  `json:"version"`
+	Files   map[string]indexFile `json:"files"` // full path to file
+	Dirs    map[string]bool      `json:"dirs"`  // full paths of the directories
+}
+
+// indexChange is what is stored in each journal object
+type indexChange struct {
+	Files map[string]*indexFile `json:"files,omitempty"` // files set, or removed if nil
+	Dirs  map[string]bool       `json:"dirs,omitempty"`  // directories added, or removed if false
+}
+
+// newIndexChange makes an empty indexChange
+func newIndexChange() indexChange {
+	return indexChange{
+		Files: map[string]*indexFile{},
+		Dirs:  map[string]bool{},
+	}
+}
+
+// index is the index for one wrapped remote.
+//
+// The index is stored as a snapshot of the whole index and a journal
+// of the changes made since, each change being saved as a new object
+// so no objects need to be overwritten.  When the journal gets as big
+// as the index a new snapshot is written, named after the time it
+// was made, and the old snapshot and journal are removed.  The newest
+// snapshot is the current one.
+//
+// The index is only locked within this process.  If more than one
+// process changes the index at once then some of the changes will be
+// lost.
+type index struct {
+	mu        sync.Mutex
+	wrapped   fs.Fs // the remote the index and the contents are stored in
+	data      indexData
+	current   string         // name of the current snapshot object or ""
+	seq       int64          // the number the current snapshot is named after
+	journal   []string       // names of the journal objects since the snapshot
+	uploading map[string]int // ids of contents being uploaded which mustn't be removed
+}
+
+// Indexes in use, by wrapped remote, so that all the Fs for a remote
+// in this process see the same index
+var (
+	indexesMu sync.Mutex
+	indexes   = map[string]*index{}
+)
+
+// getIndex returns the index for the wrapped remote called key,
+// reading it if this is the first time it has been used
+func getIndex(key string, wrapped fs.Fs) (*index, error) {
+	indexesMu.Lock()
+	defer indexesMu.Unlock()
+	if idx, ok := indexes[key]; ok {
+		return idx, nil
+	}
+	idx := &index{
+		wrapped: wrapped,
+		data: indexData{
+			Version: indexVersion,
+			Files:   map[string]indexFile{},
+			Dirs:    map[string]bool{},
+		},
+		uploading: map[string]int{},
+	}
+	err := idx.load()
+	if err != nil {
+		return nil, err
+	}
+	indexes[key] = idx
+	return idx, nil
+}
+
+// journalPrefix returns the prefix of the names of the journal
+// objects for the snapshot numbered seq
+func journalPrefix(seq int64) string {
+	return path.Join(indexDir, fmt.Sprintf("%020d.", seq))
+}
+
+// readObject reads the JSON object called name into v
+func (idx *index) readObject(name string, v interface{}) error {
+	o, err := idx.wrapped.NewObject(name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find index %q", name)
+	}
+	in, err := o.Open()
+	if err != nil {
+		return errors.Wrapf(err, "failed to open index %q", name)
+	}
+	buf, err := ioutil.ReadAll(in)
+	_ = in.Close()
+	if err != nil {
+		return errors.Wrapf(err, "failed to read index %q", name)
+	}
+	err = json.Unmarshal(buf, v)
+	if err != nil {
+		return errors.Wrapf(err, "failed to decode index %q", name)
+	}
+	return nil
+}
+
+// writeObject writes v as a JSON object called name
+func (idx *index) writeObject(name string, v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode index")
+	}
+	src := object.NewStaticObjectInfo(name, time.Now(), int64(len(buf)), true, nil, nil)
+	_, err = idx.wrapped.Put(bytes.NewReader(buf), src)
+	if err != nil {
+		return errors.Wrap(err, "failed to save index")
+	}
+	return nil
+}
+
+// load reads the newest snapshot if there is one and applies its
+// journal
+func (idx *index) load() error {
+	entries, err := idx.wrapped.List(indexDir)
+	if err == fs.ErrorDirNotFound {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to list index")
+	}
+	var snapshots, journal []string
+	for _, entry := range entries {
+		if o, ok := entry.(fs.Object); ok {
+			switch remote := o.Remote(); {
+			case strings.HasSuffix(remote, ".json"):
+				snapshots = append(snapshots, remote)
+			case strings.HasSuffix(remote, ".journal"):
+				journal = append(journal, remote)
+			}
+		}
+	}
+	if len(snapshots) == 0 {
+		return nil
+	}
+	sort.Strings(snapshots)
+	name := snapshots[len(snapshots)-1]
+	var data indexData
+	err = idx.readObject(name, &data)
+	if err != nil {
+		return err
+	}
+	if data.Version != indexVersion {
+		return errors.Errorf("unsupported index version %d in %q", data.Version, name)
+	}
+	if data.Files == nil {
+		data.Files = map[string]indexFile{}
+	}
+	if data.Dirs == nil {
+		data.Dirs = map[string]bool{}
+	}
+	idx.data = data
+	idx.current = name
+	_, _ = fmt.Sscanf(path.Base(name), "%d.json", &idx.seq)
+
+	// Apply the changes made since the snapshot in order
+	prefix := journalPrefix(idx.seq)
+	sort.Strings(journal)
+	for _, name := range journal {
+		if !strings.HasPrefix(name, prefix) {
+			// left over from an old snapshot
+			continue
+		}
+		var change indexChange
+		err = idx.readObject(name, &change)
+		if err != nil {
+			return err
+		}
+		idx.apply(change)
+		idx.journal = append(idx.journal, name)
+	}
+	return nil
+}
+
+// apply makes change to the index in memory
+//
+// Call with the lock held.
+func (idx *index) apply(change indexChange) {
+	for p, file := range change.Files {
+		if file == nil {
+			delete(idx.data.Files, p)
+		} else {
+			idx.data.Files[p] = *file
+		}
+	}
+	for p, isDir := range change.Dirs {
+		if isDir {
+			idx.data.Dirs[p] = true
+		} else {
+			delete(idx.data.Dirs, p)
+		}
+	}
+}
+
+// save saves change, which has already been made to the index in
+// memory, as a new journal object, or writes a new snapshot if the
+// journal is big enough to be replaced.
+//
+// Call with the lock held.
+func (idx *index) save(change indexChange) error {
+	n := len(idx.journal)
+	if idx.current == "" || (n >= minJournal && n >= len(idx.data.Files)+len(idx.data.Dirs)) {
+		return idx.snapshot()
+	}
+	name := fmt.Sprintf("%s%010d.journal", journalPrefix(idx.seq), n+1)
+	err := idx.writeObject(name, &change)
+	if err != nil {
+		return err
+	}
+	idx.journal = append(idx.journal, name)
+	return nil
+}
+
+// snapshot writes the whole index as a new snapshot object and
+// removes the previous one and its journal.
+//
+// Call with the lock held.
+func (idx *index) snapshot() error {
+	seq := time.Now().UnixNano()
+	if seq <= idx.seq {
+		// make sure the new snapshot sorts after the old one
+		seq = idx.seq + 1
+	}
+	name := path.Join(indexDir, fmt.Sprintf("%020d.json", seq))
+	err := idx.writeObject(name, &idx.data)
+	if err != nil {
+		return err
+	}
+	var old []string
+	if idx.current != "" {
+		old = append(old, idx.current)
+	}
+	old = append(old, idx.journal...)
+	for _, oldName := range old {
+		if o, err := idx.wrapped.NewObject(oldName); err == nil {
+			if err = o.Remove(); err != nil {
+				fs.Debugf(idx.wrapped, "Failed to remove old index %q: %v", oldName, err)
+			}
+		}
+	}
+	idx.current = name
+	idx.seq = seq
+	idx.journal = nil
+	return nil
+}
+
+// protect stops the contents with id being removed until unprotect
+// is called.
+//
+// Call with the lock held.
+func (idx *index) protect(id string) {
+	idx.uploading[id]++
+}
+
+// unprotect undoes protect.
+//
+// Call with the lock held.
+func (idx *index) unprotect(id string) {
+	idx.uploading[id]--
+	if idx.uploading[id] <= 0 {
+		delete(idx.uploading, id)
+	}
+}
+
+// addParents adds the directories above the full path p to the
+// index, recording them in change.
+//
+// Call with the lock held.
+func (idx *index) addParents(p string, change indexChange) {
+	for dir := path.Dir(p); dir != "." && dir != "/" && dir != ""; dir = path.Dir(dir) {
+		if idx.data.Dirs[dir] {
+			break
+		}
+		idx.data.Dirs[dir] = true
+		change.Dirs[dir] = true
+	}
+}
+
+// removeDirs removes the directories added by change from the index
+// in memory, to undo a change which couldn't be saved.
+//
+// Call with the lock held.
+func (idx *index) removeDirs(change indexChange) {
+	for dir, added := range change.Dirs {
+		if added {
+			delete(idx.data.Dirs, dir)
+		}
+	}
+}
+
+// inDir returns whether the full path p is in the full path dir or
+// any of its subdirectories
+func inDir(p, dir string) bool {
+	return dir == "" || strings.HasPrefix(p, dir+"/")
+}
+
+// isEmpty returns whether the directory dir has nothing in it.
+//
+// Call with the lock held.
+func (idx *index) isEmpty(dir string) bool {
+	for p := range idx.data.Files {
+		if inDir(p, dir) {
+			return false
+		}
+	}
+	for p := range idx.data.Dirs {
+		if inDir(p, dir) {
+			return false
+		}
+	}
+	return true
+}
+
+// isReferenced returns whether any file uses the contents with id.
+//
+// Call with the lock held.
+func (idx *index) isReferenced(id string) bool {
+	for _, file := range idx.data.Files {
+		if file.ID == id {
+			return true
+		}
+	}
+	return false
+}
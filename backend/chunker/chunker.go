@@ -0,0 +1,868 @@
+// Package chunker provides wrappers for Fs and Object which split
+// large files into chunks
+package chunker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultChunkSize = "2G"
+	chunkSuffix      = ".rclone_chunk."
+	metaVersion      = 1    // version of the metadata format written
+	maxMetaSize      = 1024 // metadata objects are never bigger than this
+)
+
+// chunkRe matches the name of a chunk, capturing the name of the
+// file it is part of and its number
+var chunkRe = regexp.MustCompile(`^(.+)` + regexp.QuoteMeta(chunkSuffix) + `([0-9]{3,})$`)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "chunker",
+		Description: "Transparently chunk/split large files",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "remote",
+			Help: "Remote to chunk/unchunk.\nNormally should contain a ':' and a path, eg \"myremote:path/to/dir\",\n\"myremote:bucket\" or maybe \"myremote:\" (not recommended).",
+		}, {
+			Name: "chunk_size",
+			Help: "Files larger than this are split into chunks of this size.\nDefault: " + defaultChunkSize,
+			Examples: []fs.OptionExample{
+				{
+					Value: "2G",
+					Help:  "2 GB",
+				}, {
+					Value: "5G",
+					Help:  "5 GB - the biggest file OpenStack Swift will take",
+				},
+			},
+			Optional: true,
+		}},
+	})
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(name, rpath string) (fs.Fs, error) {
+	remote := config.FileGet(name, "remote")
+	if strings.HasPrefix(remote, name+":") {
+		return nil, errors.New("can't point chunker remote at itself - check the value of the remote setting")
+	}
+	var chunkSize fs.SizeSuffix
+	chunkSizeString := config.FileGet(name, "chunk_size", defaultChunkSize)
+	err := chunkSize.Set(chunkSizeString)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to understand chunk size %v", chunkSizeString)
+	}
+	if chunkSize <= 0 {
+		return nil, errors.Errorf("chunk size %v must be bigger than 0", chunkSize)
+	}
+	remotePath := path.Join(remote, rpath)
+	wrappedFs, err := fs.NewFs(remotePath)
+	if err != fs.ErrorIsFile && err != nil {
+		return nil, errors.Wrapf(err, "failed to make remote %q to wrap", remotePath)
+	}
+	f := &Fs{
+		Fs:        wrappedFs,
+		name:      name,
+		root:      rpath,
+		chunkSize: int64(chunkSize),
+	}
+	// the features here are ones we could support, and they are
+	// ANDed with the ones from wrappedFs
+	f.features = (&fs.Features{
+		CaseInsensitive:         true,
+		DuplicateFiles:          false,
+		ReadMimeType:            false, // MimeTypes not supported with chunking
+		WriteMimeType:           false,
+		BucketBased:             true,
+		CanHaveEmptyDirectories: true,
+	}).Fill(f).Mask(wrappedFs).WrapsFs(f, wrappedFs)
+	return f, err
+}
+
+// Fs represents a wrapped fs.Fs
+type Fs struct {
+	fs.Fs
+	name      string
+	root      string
+	features  *fs.Features // optional features
+	chunkSize int64        // files bigger than this are chunked
+}
+
+// metadata is stored in the object with the name of a chunked file
+type metadata struct {
+	Version int               `json:"ver"`              // version of this format
+	Size    int64             `json:"size"`             // size of the whole file
+	Chunks  int               `json:"nchunks"`          // number of chunks
+	Hashes  map[string]string `json:"hashes,omitempty"` // hashes of the whole file by hash name
+}
+
+// chunkName returns the name of chunk n (counting from 1) of remote
+func chunkName(remote string, n int) string {
+	return fmt.Sprintf("%s%s%03d", remote, chunkSuffix, n)
+}
+
+// parseChunkName returns the name of the file remote is a chunk of
+// and the chunk number if remote is the name of a chunk
+func parseChunkName(remote string) (file string, n int, ok bool) {
+	match := chunkRe.FindStringSubmatch(remote)
+	if match == nil {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(match[2])
+	if err != nil || n < 1 {
+		return "", 0, false
+	}
+	return match[1], n, true
+}
+
+// parseMetadata parses the contents of a metadata object returning
+// nil if it isn't one
+func parseMetadata(data []byte) (*metadata, error) {
+	var meta metadata
+	err := json.Unmarshal(data, &meta)
+	if err != nil || meta.Version < 1 || meta.Chunks < 1 || meta.Size < 0 {
+		return nil, nil
+	}
+	if meta.Version > metaVersion {
+		return nil, errors.Errorf("chunked file metadata version %d is newer than this rclone understands - upgrade rclone", meta.Version)
+	}
+	return &meta, nil
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("Chunked '%s:%s'", f.name, f.root)
+}
+
+// Hashes returns the supported hash sets.
+//
+// These are read from the wrapped remote for files which aren't
+// chunked and calculated on upload for those which are.
+func (f *Fs) Hashes() hash.Set {
+	return f.Fs.Hashes().Overlap(hash.Supported)
+}
+
+// processEntries gathers the chunks in entries into the files they
+// are part of
+func (f *Fs) processEntries(entries fs.DirEntries) (newEntries fs.DirEntries, err error) {
+	objects := make(map[string]*Object)
+	getObject := func(remote string) *Object {
+		o := objects[remote]
+		if o == nil {
+			o = f.newObject(remote, nil, nil)
+			objects[remote] = o
+			newEntries = append(newEntries, o)
+		}
+		return o
+	}
+	for _, entry := range entries {
+		switch x := entry.(type) {
+		case fs.Object:
+			if remote, n, ok := parseChunkName(x.Remote()); ok {
+				getObject(remote).addChunk(n, x)
+			} else {
+				getObject(x.Remote()).main = x
+			}
+		case fs.Directory:
+			newEntries = append(newEntries, x)
+		default:
+			return nil, errors.Errorf("Unknown object type %T", entry)
+		}
+	}
+	for _, o := range objects {
+		// Files too big to be metadata aren't chunked - any
+		// chunks with them are left over from something else
+		if o.main != nil && o.main.Size() > maxMetaSize && o.chunks != nil {
+			fs.Debugf(o, "Ignoring chunks left over from a previous upload")
+			o.chunks = nil
+		}
+	}
+	return newEntries, nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+	entries, err = f.Fs.List(dir)
+	if err != nil {
+		return nil, err
+	}
+	return f.processEntries(entries)
+}
+
+// NewObject finds the Object at remote.
+//
+// If the object is small enough to be metadata then it is read to
+// find out whether it is a chunked file.
+func (f *Fs) NewObject(remote string) (fs.Object, error) {
+	if _, _, ok := parseChunkName(remote); ok {
+		return nil, fs.ErrorObjectNotFound
+	}
+	main, err := f.Fs.NewObject(remote)
+	if err != nil {
+		return nil, err
+	}
+	o := f.newObject(remote, main, nil)
+	if main.Size() > maxMetaSize {
+		return o, nil
+	}
+	meta, err := readMetadata(main)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return o, nil
+	}
+	o.meta = meta
+	for n := 1; n <= meta.Chunks; n++ {
+		chunk, err := f.Fs.NewObject(chunkName(remote, n))
+		if err == fs.ErrorObjectNotFound {
+			fs.Errorf(o, "Chunk %d is missing", n)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		o.addChunk(n, chunk)
+	}
+	if o.chunks == nil {
+		// make sure the object is marked as chunked
+		o.chunks = make([]fs.Object, meta.Chunks)
+	}
+	return o, nil
+}
+
+// readMetadata reads the metadata from main returning nil if it
+// isn't a metadata object
+func readMetadata(main fs.Object) (meta *metadata, err error) {
+	in, err := main.Open()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open metadata")
+	}
+	defer fs.CheckClose(in, &err)
+	data, err := ioutil.ReadAll(io.LimitReader(in, maxMetaSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read metadata")
+	}
+	return parseMetadata(data)
+}
+
+// upload the chunk or metadata in to src updating old if it exists
+func (f *Fs) upload(old fs.Object, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	if old != nil {
+		return old, old.Update(in, src, options...)
+	}
+	if src.Size() < 0 {
+		do := f.Fs.Features().PutStream
+		if do == nil {
+			return nil, errors.New("can't upload files of unknown size")
+		}
+		return do(in, src, options...)
+	}
+	return f.Fs.Put(in, src, options...)
+}
+
+// put uploads in to src.Remote() splitting it into chunks if it is
+// bigger than the chunk size.
+//
+// If old is set then its parts are updated and any chunks it has
+// left over are removed.
+func (f *Fs) put(in io.Reader, src fs.ObjectInfo, options []fs.OpenOption, old *Object) (*Object, error) {
+	remote := src.Remote()
+	if _, _, ok := parseChunkName(remote); ok {
+		return nil, errors.Errorf("can't upload %q as names ending %q are reserved for chunks", remote, chunkSuffix+"NNN")
+	}
+	var oldMain fs.Object
+	var oldChunks []fs.Object
+	if old != nil {
+		oldMain, oldChunks = old.main, old.chunks
+	}
+
+	// Upload small files as they are
+	size := src.Size()
+	if size >= 0 && size <= f.chunkSize {
+		main, err := f.upload(oldMain, in, src, options...)
+		if err != nil {
+			return nil, err
+		}
+		o := f.newObject(remote, main, nil)
+		o.removeChunks(oldChunks, 0)
+		return o, nil
+	}
+
+	// Upload the chunks, hashing the whole file as we go
+	hasher, err := hash.NewMultiHasherTypes(f.Hashes())
+	if err != nil {
+		return nil, err
+	}
+	buf := bufio.NewReader(io.TeeReader(in, hasher))
+	o := f.newObject(remote, nil, nil)
+	remaining := size
+	for n := 1; ; n++ {
+		chunkSize := f.chunkSize
+		if size >= 0 {
+			if remaining <= 0 {
+				break
+			}
+			if remaining < chunkSize {
+				chunkSize = remaining
+			}
+			remaining -= chunkSize
+		} else {
+			// Always upload the first chunk so there is one
+			// even if the stream is empty
+			if n > 1 {
+				if _, err = buf.Peek(1); err == io.EOF {
+					break
+				} else if err != nil {
+					o.removeNewChunks(oldChunks)
+					return nil, err
+				}
+			}
+			chunkSize = -1
+		}
+		var oldChunk fs.Object
+		if n <= len(oldChunks) {
+			oldChunk = oldChunks[n-1]
+		}
+		info := object.NewStaticObjectInfo(chunkName(remote, n), src.ModTime(), chunkSize, true, nil, f)
+		chunk, err := f.upload(oldChunk, io.LimitReader(buf, f.chunkSize), info, options...)
+		if err != nil {
+			o.removeNewChunks(oldChunks)
+			return nil, errors.Wrapf(err, "failed to upload chunk %d", n)
+		}
+		o.addChunk(n, chunk)
+	}
+	if size >= 0 && hasher.Size() != size {
+		o.removeNewChunks(oldChunks)
+		return nil, errors.Errorf("read %d bytes expecting %d", hasher.Size(), size)
+	}
+
+	// Upload the metadata
+	meta := &metadata{
+		Version: metaVersion,
+		Size:    hasher.Size(),
+		Chunks:  len(o.chunks),
+		Hashes:  make(map[string]string),
+	}
+	for hashType, sum := range hasher.Sums() {
+		meta.Hashes[hashType.String()] = sum
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		o.removeNewChunks(oldChunks)
+		return nil, err
+	}
+	info := object.NewStaticObjectInfo(remote, src.ModTime(), int64(len(data)), true, nil, f)
+	o.main, err = f.upload(oldMain, bytes.NewReader(data), info)
+	if err != nil {
+		o.removeNewChunks(oldChunks)
+		return nil, errors.Wrap(err, "failed to upload metadata")
+	}
+	o.meta = meta
+	o.removeChunks(oldChunks, len(o.chunks))
+	return o, nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o, err := f.put(in, src, options, nil)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(in, src, options...)
+}
+
+// Purge all files in the root and the root directory
+//
+// Implement this if you have a way of deleting all the files
+// quicker than just running Remove() on the result of List()
+//
+// Return an error if it doesn't exist
+func (f *Fs) Purge() error {
+	do := f.Fs.Features().Purge
+	if do == nil {
+		return fs.ErrorCantPurge
+	}
+	return do()
+}
+
+type copyMoveFn func(src fs.Object, remote string) (fs.Object, error)
+
+// copyOrMove does a server side copy or move of all the parts of src
+// to remote
+func (f *Fs) copyOrMove(src *Object, remote string, do copyMoveFn) (*Object, error) {
+	err := src.checkComplete()
+	if err != nil {
+		return nil, err
+	}
+	o := f.newObject(remote, nil, nil)
+	for i, chunk := range src.chunks {
+		newChunk, err := do(chunk, chunkName(remote, i+1))
+		if err != nil {
+			return o, err
+		}
+		o.addChunk(i+1, newChunk)
+	}
+	if src.main != nil {
+		o.main, err = do(src.main, remote)
+		if err != nil {
+			return o, err
+		}
+	}
+	o.meta = src.meta
+	return o, nil
+}
+
+// Copy src to this remote using server side copy operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Copy
+	if do == nil {
+		return nil, fs.ErrorCantCopy
+	}
+	srcObj, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantCopy
+	}
+	o, err := f.copyOrMove(srcObj, remote, do)
+	if err != nil {
+		// don't leave a partial copy behind
+		_ = o.Remove()
+		return nil, err
+	}
+	return o, nil
+}
+
+// Move src to this remote using server side move operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Move
+	if do == nil {
+		return nil, fs.ErrorCantMove
+	}
+	srcObj, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantMove
+	}
+	o, err := f.copyOrMove(srcObj, remote, do)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
+	do := f.Fs.Features().DirMove
+	if do == nil {
+		return fs.ErrorCantDirMove
+	}
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	return do(srcFs.Fs, srcRemote, dstRemote)
+}
+
+// CleanUp the trash in the Fs
+//
+// Implement this if you have a way of emptying the trash or
+// otherwise cleaning up old versions of files.
+func (f *Fs) CleanUp() error {
+	do := f.Fs.Features().CleanUp
+	if do == nil {
+		return errors.New("can't CleanUp")
+	}
+	return do()
+}
+
+// About gets quota information from the Fs
+func (f *Fs) About() (*fs.Usage, error) {
+	do := f.Fs.Features().About
+	if do == nil {
+		return nil, errors.New("About not supported")
+	}
+	return do()
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// Object describes a file which may be split into chunks
+type Object struct {
+	f      *Fs
+	remote string
+	main   fs.Object   // the metadata if chunked, or the whole file - may be nil if the metadata is missing
+	chunks []fs.Object // the chunks in order if chunked - missing ones are nil
+	meta   *metadata   // the metadata if read
+}
+
+func (f *Fs) newObject(remote string, main fs.Object, chunks []fs.Object) *Object {
+	return &Object{
+		f:      f,
+		remote: remote,
+		main:   main,
+		chunks: chunks,
+	}
+}
+
+// addChunk adds chunk number n (counting from 1) to the object
+func (o *Object) addChunk(n int, chunk fs.Object) {
+	for len(o.chunks) < n {
+		o.chunks = append(o.chunks, nil)
+	}
+	o.chunks[n-1] = chunk
+}
+
+// isChunked returns whether the object is split into chunks
+func (o *Object) isChunked() bool {
+	return o.chunks != nil
+}
+
+// checkComplete returns an error if any of the chunks are missing
+func (o *Object) checkComplete() error {
+	for i, chunk := range o.chunks {
+		if chunk == nil {
+			return errors.Errorf("chunked file %q is missing chunk %d", o.remote, i+1)
+		}
+	}
+	if o.main == nil && !o.isChunked() {
+		return fs.ErrorObjectNotFound
+	}
+	return nil
+}
+
+// removeChunks removes the chunks numbered from after n
+func (o *Object) removeChunks(chunks []fs.Object, n int) {
+	for i := n; i < len(chunks); i++ {
+		if chunks[i] == nil {
+			continue
+		}
+		err := chunks[i].Remove()
+		if err != nil {
+			fs.Errorf(o, "Failed to remove old chunk %d: %v", i+1, err)
+		}
+	}
+}
+
+// removeNewChunks removes the chunks uploaded by a failed upload
+// which weren't updates of the old chunks
+func (o *Object) removeNewChunks(oldChunks []fs.Object) {
+	o.removeChunks(o.chunks, len(oldChunks))
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// first returns the object holding the modification time
+func (o *Object) first() fs.Object {
+	if o.main != nil || len(o.chunks) == 0 {
+		return o.main
+	}
+	return o.chunks[0]
+}
+
+// ModTime returns the modification date of the file
+func (o *Object) ModTime() time.Time {
+	first := o.first()
+	if first == nil {
+		return time.Time{}
+	}
+	return first.ModTime()
+}
+
+// SetModTime sets the modification time of the file
+func (o *Object) SetModTime(modTime time.Time) error {
+	first := o.first()
+	if first == nil {
+		return fs.ErrorObjectNotFound
+	}
+	return first.SetModTime(modTime)
+}
+
+// Size returns the size of the file
+func (o *Object) Size() int64 {
+	if !o.isChunked() {
+		return o.main.Size()
+	}
+	var size int64
+	for _, chunk := range o.chunks {
+		if chunk != nil {
+			size += chunk.Size()
+		}
+	}
+	return size
+}
+
+// Storable returns whether object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Hash returns the selected checksum of the file
+// If no checksum is available it returns ""
+func (o *Object) Hash(ht hash.Type) (string, error) {
+	if !o.isChunked() {
+		return o.main.Hash(ht)
+	}
+	if !o.f.Hashes().Contains(ht) {
+		return "", hash.ErrUnsupported
+	}
+	if o.meta == nil && o.main != nil {
+		meta, err := readMetadata(o.main)
+		if err != nil {
+			return "", err
+		}
+		o.meta = meta
+	}
+	if o.meta == nil {
+		return "", nil
+	}
+	return o.meta.Hashes[ht.String()], nil
+}
+
+// Open opens the file for read.  Call Close() on the returned io.ReadCloser
+func (o *Object) Open(options ...fs.OpenOption) (rc io.ReadCloser, err error) {
+	if !o.isChunked() {
+		return o.main.Open(options...)
+	}
+	err = o.checkComplete()
+	if err != nil {
+		return nil, err
+	}
+	var openOptions []fs.OpenOption
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.Size())
+		default:
+			// pass on Options to underlying open if appropriate
+			openOptions = append(openOptions, option)
+		}
+	}
+	return newChunkReader(o.chunks, offset, limit, openOptions), nil
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	newO, err := o.f.put(in, src, options, o)
+	if err != nil {
+		return err
+	}
+	*o = *newO
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove() error {
+	for i, chunk := range o.chunks {
+		if chunk == nil {
+			continue
+		}
+		err := chunk.Remove()
+		if err != nil {
+			return errors.Wrapf(err, "failed to remove chunk %d", i+1)
+		}
+	}
+	if o.main != nil {
+		return o.main.Remove()
+	}
+	return nil
+}
+
+// chunkReader reads the chunks of a file in order as one stream
+type chunkReader struct {
+	chunks  []fs.Object
+	options []fs.OpenOption
+	i       int           // index of the next chunk to open
+	offset  int64         // offset to open the next chunk at
+	limit   int64         // bytes left to read or -1 to read to the end
+	in      io.ReadCloser // the chunk being read or nil
+}
+
+// newChunkReader makes a reader for the chunks reading limit bytes
+// from offset, or to the end if limit is -1
+func newChunkReader(chunks []fs.Object, offset, limit int64, options []fs.OpenOption) *chunkReader {
+	r := &chunkReader{
+		chunks:  chunks,
+		options: options,
+		offset:  offset,
+		limit:   limit,
+	}
+	// skip the chunks before offset
+	for r.i < len(chunks) && r.offset >= chunks[r.i].Size() {
+		r.offset -= chunks[r.i].Size()
+		r.i++
+	}
+	return r
+}
+
+// openChunk opens the next chunk
+func (r *chunkReader) openChunk() (err error) {
+	chunk := r.chunks[r.i]
+	options := r.options
+	end := int64(-1)
+	if r.limit >= 0 && r.offset+r.limit < chunk.Size() {
+		end = r.offset + r.limit - 1
+	}
+	if r.offset > 0 || end >= 0 {
+		options = append(options[:len(options):len(options)], &fs.RangeOption{Start: r.offset, End: end})
+	}
+	r.in, err = chunk.Open(options...)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open chunk %d", r.i+1)
+	}
+	r.i++
+	r.offset = 0
+	return nil
+}
+
+// Read bytes from the chunks
+func (r *chunkReader) Read(p []byte) (n int, err error) {
+	for {
+		if r.limit == 0 {
+			return 0, io.EOF
+		}
+		if r.in == nil {
+			if r.i >= len(r.chunks) {
+				return 0, io.EOF
+			}
+			err = r.openChunk()
+			if err != nil {
+				return 0, err
+			}
+		}
+		if r.limit >= 0 && int64(len(p)) > r.limit {
+			p = p[:r.limit]
+		}
+		n, err = r.in.Read(p)
+		if r.limit >= 0 {
+			r.limit -= int64(n)
+		}
+		if err == io.EOF {
+			err = r.in.Close()
+			r.in = nil
+			if err != nil || n > 0 {
+				return n, err
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// Close the chunk being read
+func (r *chunkReader) Close() error {
+	if r.in == nil {
+		return nil
+	}
+	err := r.in.Close()
+	r.in = nil
+	return err
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = (*Fs)(nil)
+	_ fs.Purger      = (*Fs)(nil)
+	_ fs.Copier      = (*Fs)(nil)
+	_ fs.Mover       = (*Fs)(nil)
+	_ fs.DirMover    = (*Fs)(nil)
+	_ fs.PutStreamer = (*Fs)(nil)
+	_ fs.CleanUpper  = (*Fs)(nil)
+	_ fs.UnWrapper   = (*Fs)(nil)
+	_ fs.Abouter     = (*Fs)(nil)
+	_ fs.Object      = (*Object)(nil)
+)
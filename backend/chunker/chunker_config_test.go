@@ -0,0 +1,20 @@
+package chunker_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/ncw/rclone/fstest/fstests"
+)
+
+// Create the TestChunker: remote
+func init() {
+	tempdir := filepath.Join(os.TempDir(), "rclone-chunker-test")
+	name := "TestChunker"
+	fstests.ExtraConfig = []fstests.ExtraConfigItem{
+		{Name: name, Key: "type", Value: "chunker"},
+		{Name: name, Key: "remote", Value: tempdir},
+		// small enough that the test files are split into chunks
+		{Name: name, Key: "chunk_size", Value: "40"},
+	}
+}
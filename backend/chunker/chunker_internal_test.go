@@ -0,0 +1,205 @@
+package chunker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/ncw/rclone/backend/local" // pull in test backend
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const remoteName = "TestChunkerInternal"
+
+// prepare makes a directory to chunk into with the given chunk size
+// returning the Fs, the directory and a function to clean up
+func prepare(t *testing.T, chunkSize string) (f fs.Fs, dir string, cleanup func()) {
+	config.LoadConfig()
+	dir, err := ioutil.TempDir("", "rclone-chunker")
+	require.NoError(t, err)
+	config.FileSet(remoteName, "type", "chunker")
+	config.FileSet(remoteName, "remote", dir)
+	config.FileSet(remoteName, "chunk_size", chunkSize)
+	f, err = fs.NewFs(remoteName + ":")
+	require.NoError(t, err)
+	return f, dir, func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+// files returns the names of the files in dir sorted
+func files(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestChunkNames(t *testing.T) {
+	assert.Equal(t, "dir/file.txt.rclone_chunk.001", chunkName("dir/file.txt", 1))
+	assert.Equal(t, "file.rclone_chunk.1234", chunkName("file", 1234))
+	for _, test := range []struct {
+		in   string
+		file string
+		n    int
+		ok   bool
+	}{
+		{"dir/file.txt.rclone_chunk.001", "dir/file.txt", 1, true},
+		{"file.rclone_chunk.1234", "file", 1234, true},
+		{"file.rclone_chunk.01", "", 0, false},
+		{"file.rclone_chunk.000", "", 0, false},
+		{".rclone_chunk.001", "", 0, false},
+		{"file.txt", "", 0, false},
+	} {
+		file, n, ok := parseChunkName(test.in)
+		assert.Equal(t, test.file, file, test.in)
+		assert.Equal(t, test.n, n, test.in)
+		assert.Equal(t, test.ok, ok, test.in)
+	}
+}
+
+func TestParseMetadata(t *testing.T) {
+	meta, err := parseMetadata([]byte(`{"ver":1,"size":100,"nchunks":2}`))
+	require.NoError(t, err)
+	assert.Equal(t, &metadata{Version: 1, Size: 100, Chunks: 2}, meta)
+	for _, in := range []string{"", "hello", `{"size":100}`, `{"ver":1,"size":100}`} {
+		meta, err = parseMetadata([]byte(in))
+		assert.NoError(t, err, in)
+		assert.Nil(t, meta, in)
+	}
+	_, err = parseMetadata([]byte(`{"ver":99,"size":100,"nchunks":2}`))
+	assert.Error(t, err)
+}
+
+func TestChunking(t *testing.T) {
+	f, dir, cleanup := prepare(t, "10b")
+	defer cleanup()
+
+	// Small files aren't chunked
+	fstest.PutContents(t, f, "small.txt", "0123456789", time.Now())
+	assert.Equal(t, []string{"small.txt"}, files(t, dir))
+
+	// Big files are
+	contents := "abcdefghijklmnopqrstuvwxyz"
+	o := fstest.PutContents(t, f, "big.txt", contents, time.Now())
+	assert.Equal(t, []string{
+		"big.txt",
+		"big.txt.rclone_chunk.001",
+		"big.txt.rclone_chunk.002",
+		"big.txt.rclone_chunk.003",
+		"small.txt",
+	}, files(t, dir))
+	assert.Equal(t, int64(len(contents)), o.Size())
+	assert.Equal(t, contents, fstest.ReadObject(t, o))
+	md5, err := o.Hash(hash.MD5)
+	require.NoError(t, err)
+	assert.Equal(t, "c3fcd3d76192e4007dfb496cca67e13b", md5)
+
+	// Listing shows the files and not the chunks
+	entries, err := f.List("")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(entries))
+	sort.Sort(entries)
+	assert.Equal(t, "big.txt", entries[0].Remote())
+	assert.Equal(t, int64(len(contents)), entries[0].Size())
+	assert.Equal(t, "small.txt", entries[1].Remote())
+
+	// NewObject finds the chunks
+	o, err = f.NewObject("big.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(contents)), o.Size())
+	assert.Equal(t, contents, fstest.ReadObject(t, o))
+	_, err = f.NewObject("big.txt.rclone_chunk.001")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	// Reading parts of the file
+	assert.Equal(t, contents[5:], fstest.ReadObject(t, o, &fs.SeekOption{Offset: 5}))
+	assert.Equal(t, contents[10:], fstest.ReadObject(t, o, &fs.SeekOption{Offset: 10}))
+	assert.Equal(t, contents[8:22], fstest.ReadObject(t, o, &fs.RangeOption{Start: 8, End: 21}))
+	assert.Equal(t, contents[20:], fstest.ReadObject(t, o, &fs.RangeOption{Start: -1, End: 6}))
+
+	// Updating to fewer chunks removes the old ones
+	src := object.NewStaticObjectInfo("big.txt", time.Now(), 15, true, nil, nil)
+	require.NoError(t, o.Update(strings.NewReader(contents[:15]), src))
+	assert.Equal(t, contents[:15], fstest.ReadObject(t, o))
+	assert.Equal(t, []string{
+		"big.txt",
+		"big.txt.rclone_chunk.001",
+		"big.txt.rclone_chunk.002",
+		"small.txt",
+	}, files(t, dir))
+
+	// Updating to a small file removes all the chunks
+	src = object.NewStaticObjectInfo("big.txt", time.Now(), 5, true, nil, nil)
+	require.NoError(t, o.Update(strings.NewReader("small"), src))
+	assert.Equal(t, "small", fstest.ReadObject(t, o))
+	assert.Equal(t, []string{"big.txt", "small.txt"}, files(t, dir))
+
+	// Can't upload files with chunk names
+	src = object.NewStaticObjectInfo("file.rclone_chunk.001", time.Now(), 5, true, nil, nil)
+	_, err = f.Put(strings.NewReader("hello"), src)
+	assert.Error(t, err)
+}
+
+func TestChunkingStream(t *testing.T) {
+	f, dir, cleanup := prepare(t, "10b")
+	defer cleanup()
+
+	for _, contents := range []string{"", "0123456789", "0123456789abcdefghij0"} {
+		src := object.NewStaticObjectInfo("stream.txt", time.Now(), -1, true, nil, nil)
+		o, err := f.Features().PutStream(strings.NewReader(contents), src)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(contents)), o.Size(), contents)
+		o, err = f.NewObject("stream.txt")
+		require.NoError(t, err)
+		assert.Equal(t, contents, fstest.ReadObject(t, o), contents)
+		require.NoError(t, o.Remove())
+		assert.Equal(t, []string(nil), files(t, dir))
+	}
+}
+
+func TestMove(t *testing.T) {
+	f, dir, cleanup := prepare(t, "10b")
+	defer cleanup()
+
+	contents := "abcdefghijklmnopqrstuvwxyz"
+	o := fstest.PutContents(t, f, "big.txt", contents, time.Now())
+	o, err := f.Features().Move(o, "moved.txt")
+	require.NoError(t, err)
+	assert.Equal(t, contents, fstest.ReadObject(t, o))
+	md5, err := o.Hash(hash.MD5)
+	require.NoError(t, err)
+	assert.Equal(t, "c3fcd3d76192e4007dfb496cca67e13b", md5)
+	assert.Equal(t, []string{
+		"moved.txt",
+		"moved.txt.rclone_chunk.001",
+		"moved.txt.rclone_chunk.002",
+		"moved.txt.rclone_chunk.003",
+	}, files(t, dir))
+}
+
+func TestMissingChunk(t *testing.T) {
+	f, dir, cleanup := prepare(t, "10b")
+	defer cleanup()
+
+	fstest.PutContents(t, f, "big.txt", "abcdefghijklmnopqrstuvwxyz", time.Now())
+	require.NoError(t, os.Remove(filepath.Join(dir, "big.txt.rclone_chunk.002")))
+	o, err := f.NewObject("big.txt")
+	require.NoError(t, err)
+	_, err = o.Open()
+	assert.Error(t, err)
+}
@@ -0,0 +1,634 @@
+// Package compress provides wrappers for Fs and Object which
+// compress and decompress the data
+package compress
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/pkg/errors"
+)
+
+const (
+	gzipSuffix = ".gz"
+)
+
+// compressedRe matches the name of a compressed object, capturing
+// the original name and the uncompressed size
+var compressedRe = regexp.MustCompile(`^(.+)\.([0-9]+)` + regexp.QuoteMeta(gzipSuffix) + `$`)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "compress",
+		Description: "Compress a remote",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "remote",
+			Help: "Remote to compress.\nNormally should contain a ':' and a path, eg \"myremote:path/to/dir\",\n\"myremote:bucket\" or maybe \"myremote:\" (not recommended).",
+		}, {
+			Name: "compression_level",
+			Help: "GZIP compression level (1 to 9), higher is smaller but slower.\nDefault: 6",
+			Examples: []fs.OptionExample{
+				{
+					Value: "1",
+					Help:  "Fastest",
+				}, {
+					Value: "6",
+					Help:  "Default",
+				}, {
+					Value: "9",
+					Help:  "Smallest",
+				},
+			},
+			Optional: true,
+		}},
+	})
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(name, rpath string) (fs.Fs, error) {
+	f, err := newFs(name, rpath)
+	if err != nil {
+		return nil, err
+	}
+	// The compressed object names don't match the path so see if
+	// it points to a file by looking in its parent
+	if rpath != "" {
+		parent := path.Dir(rpath)
+		if parent == "." {
+			parent = ""
+		}
+		parentFs, err := newFs(name, parent)
+		if err != nil {
+			return nil, err
+		}
+		_, err = parentFs.NewObject(path.Base(rpath))
+		if err == nil {
+			return parentFs, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// newFs constructs an Fs from the path without checking whether it
+// points to a file
+func newFs(name, rpath string) (*Fs, error) {
+	remote := config.FileGet(name, "remote")
+	if strings.HasPrefix(remote, name+":") {
+		return nil, errors.New("can't point compress remote at itself - check the value of the remote setting")
+	}
+	levelString := config.FileGet(name, "compression_level", "6")
+	level, err := strconv.Atoi(levelString)
+	if err != nil || level < gzip.BestSpeed || level > gzip.BestCompression {
+		return nil, errors.Errorf("compression_level %q must be a number from %d to %d", levelString, gzip.BestSpeed, gzip.BestCompression)
+	}
+	remotePath := path.Join(remote, rpath)
+	wrappedFs, err := fs.NewFs(remotePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to make remote %q to wrap", remotePath)
+	}
+	f := &Fs{
+		Fs:    wrappedFs,
+		name:  name,
+		root:  rpath,
+		level: level,
+	}
+	// the features here are ones we could support, and they are
+	// ANDed with the ones from wrappedFs
+	f.features = (&fs.Features{
+		CaseInsensitive:         true,
+		DuplicateFiles:          false,
+		ReadMimeType:            false, // MimeTypes not supported with compression
+		WriteMimeType:           false,
+		BucketBased:             true,
+		CanHaveEmptyDirectories: true,
+	}).Fill(f).Mask(wrappedFs).WrapsFs(f, wrappedFs)
+	return f, nil
+}
+
+// Fs represents a wrapped fs.Fs
+type Fs struct {
+	fs.Fs
+	name     string
+	root     string
+	features *fs.Features // optional features
+	level    int          // gzip compression level
+}
+
+// compressedName returns the name remote of size is stored under
+func compressedName(remote string, size int64) string {
+	return fmt.Sprintf("%s.%d%s", remote, size, gzipSuffix)
+}
+
+// parseCompressedName returns the name and uncompressed size of the
+// compressed object remote, or ok false if it isn't one
+func parseCompressedName(remote string) (name string, size int64, ok bool) {
+	match := compressedRe.FindStringSubmatch(remote)
+	if match == nil {
+		return "", 0, false
+	}
+	size, err := strconv.ParseInt(match[2], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return match[1], size, true
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("Compressed drive '%s:%s'", f.name, f.root)
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.None)
+}
+
+// processEntries turns the compressed objects in entries into
+// Objects skipping any which weren't written by this backend
+func (f *Fs) processEntries(entries fs.DirEntries) (newEntries fs.DirEntries, err error) {
+	newEntries = entries[:0] // in place filter
+	for _, entry := range entries {
+		switch x := entry.(type) {
+		case fs.Object:
+			o := f.newObject(x)
+			if o == nil {
+				fs.Debugf(x, "Skipping file not compressed by rclone")
+				continue
+			}
+			newEntries = append(newEntries, o)
+		case fs.Directory:
+			newEntries = append(newEntries, x)
+		default:
+			return nil, errors.Errorf("Unknown object type %T", entry)
+		}
+	}
+	return newEntries, nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+	entries, err = f.Fs.List(dir)
+	if err != nil {
+		return nil, err
+	}
+	return f.processEntries(entries)
+}
+
+// NewObject finds the Object at remote.
+//
+// As the uncompressed size is part of the object name this lists the
+// directory to find it.
+func (f *Fs) NewObject(remote string) (fs.Object, error) {
+	dir := path.Dir(remote)
+	if dir == "." {
+		dir = ""
+	}
+	entries, err := f.Fs.List(dir)
+	if err == fs.ErrorDirNotFound {
+		return nil, fs.ErrorObjectNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if o, ok := entry.(fs.Object); ok {
+			if name, _, ok := parseCompressedName(o.Remote()); ok && name == remote {
+				return f.newObject(o), nil
+			}
+		}
+	}
+	return nil, fs.ErrorObjectNotFound
+}
+
+// compress returns a reader of in compressed and its size, or -1
+// if the size is unknown as the compressed data is being streamed.
+//
+// If the wrapped remote can't stream uploads of unknown size then
+// the data is compressed to a temporary file first.  Call cleanup
+// when finished with the reader.
+func (f *Fs) compress(in io.Reader) (out io.Reader, size int64, cleanup func(), err error) {
+	if f.Fs.Features().PutStream != nil {
+		pipeReader, pipeWriter := io.Pipe()
+		go func() {
+			_ = pipeWriter.CloseWithError(f.compressTo(pipeWriter, in))
+		}()
+		return pipeReader, -1, func() { _ = pipeReader.Close() }, nil
+	}
+	tmp, err := ioutil.TempFile("", "rclone-compress")
+	if err != nil {
+		return nil, 0, nil, errors.Wrap(err, "failed to make temporary file")
+	}
+	cleanup = func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}
+	err = f.compressTo(tmp, in)
+	if err == nil {
+		size, err = tmp.Seek(0, 1)
+	}
+	if err == nil {
+		_, err = tmp.Seek(0, 0)
+	}
+	if err != nil {
+		cleanup()
+		return nil, 0, nil, errors.Wrap(err, "failed to compress to temporary file")
+	}
+	return tmp, size, cleanup, nil
+}
+
+// compressTo compresses in to out
+func (f *Fs) compressTo(out io.Writer, in io.Reader) error {
+	gz, err := gzip.NewWriterLevel(out, f.level)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(gz, in)
+	closeErr := gz.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// put compresses in and uploads it as src, updating old if it is set
+// and the object name hasn't changed
+func (f *Fs) put(in io.Reader, src fs.ObjectInfo, options []fs.OpenOption, old *Object) (*Object, error) {
+	if src.Size() < 0 {
+		return nil, errors.New("can't upload files of unknown size to compress remote")
+	}
+	compressed, size, cleanup, err := f.compress(in)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	info := f.newObjectInfo(src, size)
+	if old != nil && old.Object.Remote() == info.Remote() {
+		err = old.Object.Update(compressed, info, options...)
+		if err != nil {
+			return nil, err
+		}
+		return old, nil
+	}
+	var o fs.Object
+	if size < 0 {
+		o, err = f.Fs.Features().PutStream(compressed, info, options...)
+	} else {
+		o, err = f.Fs.Put(compressed, info, options...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if old != nil {
+		// the name has changed so remove the old object
+		err = old.Object.Remove()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to remove old version")
+		}
+	}
+	return f.newObject(o), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o, err := f.put(in, src, options, nil)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Purge all files in the root and the root directory
+//
+// Implement this if you have a way of deleting all the files
+// quicker than just running Remove() on the result of List()
+//
+// Return an error if it doesn't exist
+func (f *Fs) Purge() error {
+	do := f.Fs.Features().Purge
+	if do == nil {
+		return fs.ErrorCantPurge
+	}
+	return do()
+}
+
+// Copy src to this remote using server side copy operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Copy
+	if do == nil {
+		return nil, fs.ErrorCantCopy
+	}
+	o, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantCopy
+	}
+	oResult, err := do(o.Object, compressedName(remote, o.size))
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(oResult), nil
+}
+
+// Move src to this remote using server side move operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Move
+	if do == nil {
+		return nil, fs.ErrorCantMove
+	}
+	o, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantMove
+	}
+	oResult, err := do(o.Object, compressedName(remote, o.size))
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(oResult), nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
+	do := f.Fs.Features().DirMove
+	if do == nil {
+		return fs.ErrorCantDirMove
+	}
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	return do(srcFs.Fs, srcRemote, dstRemote)
+}
+
+// CleanUp the trash in the Fs
+//
+// Implement this if you have a way of emptying the trash or
+// otherwise cleaning up old versions of files.
+func (f *Fs) CleanUp() error {
+	do := f.Fs.Features().CleanUp
+	if do == nil {
+		return errors.New("can't CleanUp")
+	}
+	return do()
+}
+
+// About gets quota information from the Fs
+func (f *Fs) About() (*fs.Usage, error) {
+	do := f.Fs.Features().About
+	if do == nil {
+		return nil, errors.New("About not supported")
+	}
+	return do()
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// Object describes a wrapped object being read from the Fs
+//
+// This decompresses the data and reads the name and size from the
+// name of the compressed object
+type Object struct {
+	fs.Object
+	f      *Fs
+	remote string // the uncompressed name
+	size   int64  // the uncompressed size
+}
+
+// newObject wraps o returning nil if it isn't a compressed object
+func (f *Fs) newObject(o fs.Object) *Object {
+	remote, size, ok := parseCompressedName(o.Remote())
+	if !ok {
+		return nil
+	}
+	return &Object{
+		Object: o,
+		f:      f,
+		remote: remote,
+		size:   size,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Size returns the uncompressed size of the file
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// Hash returns the selected checksum of the file
+// If no checksum is available it returns ""
+func (o *Object) Hash(ht hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// UnWrap returns the wrapped Object
+func (o *Object) UnWrap() fs.Object {
+	return o.Object
+}
+
+// Open opens the file for read.  Call Close() on the returned io.ReadCloser
+//
+// Files are stored as a single gzip stream which can't be seeked in,
+// so ranges are read by downloading and decompressing from the start
+// of the file and discarding the data before the range.  This means
+// reading at an offset costs O(offset), and random access, eg from a
+// mount, reads the start of the file again for each seek.
+func (o *Object) Open(options ...fs.OpenOption) (rc io.ReadCloser, err error) {
+	var openOptions []fs.OpenOption
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.size)
+		default:
+			// pass on Options to underlying open if appropriate
+			openOptions = append(openOptions, option)
+		}
+	}
+	in, err := o.Object.Open(openOptions...)
+	if err != nil {
+		return nil, err
+	}
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		_ = in.Close()
+		return nil, errors.Wrap(err, "failed to read compressed data")
+	}
+	if offset > 0 {
+		_, err = io.CopyN(ioutil.Discard, gz, offset)
+		if err == io.EOF {
+			err = nil
+		}
+		if err != nil {
+			_ = in.Close()
+			return nil, errors.Wrap(err, "failed to seek in compressed data")
+		}
+	}
+	var r io.Reader = gz
+	if limit >= 0 {
+		r = io.LimitReader(gz, limit)
+	}
+	return &decompressor{Reader: r, gz: gz, in: in}, nil
+}
+
+// decompressor reads decompressed data closing the gzip reader and
+// the compressed data when closed
+type decompressor struct {
+	io.Reader
+	gz *gzip.Reader
+	in io.ReadCloser
+}
+
+// Close the decompressor
+func (d *decompressor) Close() error {
+	err := d.gz.Close()
+	inErr := d.in.Close()
+	if err == nil {
+		err = inErr
+	}
+	return err
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	newO, err := o.f.put(in, src, options, o)
+	if err != nil {
+		return err
+	}
+	*o = *o.f.newObject(newO.Object)
+	return nil
+}
+
+// ObjectInfo describes a wrapped fs.ObjectInfo for being the source
+//
+// This puts the size in the remote name and sets the compressed size
+type ObjectInfo struct {
+	fs.ObjectInfo
+	f    *Fs
+	size int64 // compressed size or -1 if unknown
+}
+
+func (f *Fs) newObjectInfo(src fs.ObjectInfo, size int64) *ObjectInfo {
+	return &ObjectInfo{
+		ObjectInfo: src,
+		f:          f,
+		size:       size,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *ObjectInfo) Fs() fs.Info {
+	return o.f
+}
+
+// Remote returns the remote path
+func (o *ObjectInfo) Remote() string {
+	return compressedName(o.ObjectInfo.Remote(), o.ObjectInfo.Size())
+}
+
+// Size returns the compressed size of the file
+func (o *ObjectInfo) Size() int64 {
+	return o.size
+}
+
+// Hash returns the selected checksum of the file
+// If no checksum is available it returns ""
+func (o *ObjectInfo) Hash(hash hash.Type) (string, error) {
+	return "", nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.Purger          = (*Fs)(nil)
+	_ fs.Copier          = (*Fs)(nil)
+	_ fs.Mover           = (*Fs)(nil)
+	_ fs.DirMover        = (*Fs)(nil)
+	_ fs.CleanUpper      = (*Fs)(nil)
+	_ fs.UnWrapper       = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.ObjectInfo      = (*ObjectInfo)(nil)
+	_ fs.Object          = (*Object)(nil)
+	_ fs.ObjectUnWrapper = (*Object)(nil)
+)
@@ -0,0 +1,18 @@
+package compress_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/ncw/rclone/fstest/fstests"
+)
+
+// Create the TestCompress: remote
+func init() {
+	tempdir := filepath.Join(os.TempDir(), "rclone-compress-test")
+	name := "TestCompress"
+	fstests.ExtraConfig = []fstests.ExtraConfigItem{
+		{Name: name, Key: "type", Value: "compress"},
+		{Name: name, Key: "remote", Value: tempdir},
+	}
+}
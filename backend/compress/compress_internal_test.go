@@ -0,0 +1,147 @@
+package compress
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/ncw/rclone/backend/local" // pull in test backend
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const remoteName = "TestCompressInternal"
+
+// prepare makes a directory to compress into returning the Fs, the
+// directory and a function to clean up
+func prepare(t *testing.T) (f *Fs, dir string, cleanup func()) {
+	config.LoadConfig()
+	dir, err := ioutil.TempDir("", "rclone-compress")
+	require.NoError(t, err)
+	config.FileSet(remoteName, "type", "compress")
+	config.FileSet(remoteName, "remote", dir)
+	newF, err := fs.NewFs(remoteName + ":")
+	require.NoError(t, err)
+	return newF.(*Fs), dir, func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+// gunzip reads the gzipped file
+func gunzip(t *testing.T, name string) string {
+	in, err := os.Open(name)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, in.Close())
+	}()
+	gz, err := gzip.NewReader(in)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(gz)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestCompressedName(t *testing.T) {
+	assert.Equal(t, "dir/file.txt.123.gz", compressedName("dir/file.txt", 123))
+	for _, test := range []struct {
+		in   string
+		name string
+		size int64
+		ok   bool
+	}{
+		{"dir/file.txt.123.gz", "dir/file.txt", 123, true},
+		{"file.0.gz.0.gz", "file.0.gz", 0, true},
+		{"file.txt.gz", "", 0, false},
+		{"file.txt.12a.gz", "", 0, false},
+		{".0.gz", "", 0, false},
+		{"file.txt", "", 0, false},
+	} {
+		name, size, ok := parseCompressedName(test.in)
+		assert.Equal(t, test.name, name, test.in)
+		assert.Equal(t, test.size, size, test.in)
+		assert.Equal(t, test.ok, ok, test.in)
+	}
+}
+
+func TestCompress(t *testing.T) {
+	f, dir, cleanup := prepare(t)
+	defer cleanup()
+
+	contents := strings.Repeat("potato ", 100)
+	o := fstest.PutContents(t, f, "potato.txt", contents, time.Now())
+	assert.Equal(t, "potato.txt", o.Remote())
+	assert.Equal(t, int64(len(contents)), o.Size())
+	assert.Equal(t, contents, fstest.ReadObject(t, o))
+
+	// The data is stored compressed under a name with the size
+	name := filepath.Join(dir, "potato.txt.700.gz")
+	info, err := os.Stat(name)
+	require.NoError(t, err)
+	assert.True(t, info.Size() < int64(len(contents)))
+	assert.Equal(t, contents, gunzip(t, name))
+
+	// Listing and finding objects shows the uncompressed name and size
+	entries, err := f.List("")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, "potato.txt", entries[0].Remote())
+	assert.Equal(t, int64(len(contents)), entries[0].Size())
+	o, err = f.NewObject("potato.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(contents)), o.Size())
+	_, err = f.NewObject("potato.txt.700.gz")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	// Reading parts of the file
+	assert.Equal(t, contents[5:], fstest.ReadObject(t, o, &fs.SeekOption{Offset: 5}))
+	assert.Equal(t, contents[8:22], fstest.ReadObject(t, o, &fs.RangeOption{Start: 8, End: 21}))
+	assert.Equal(t, contents[690:], fstest.ReadObject(t, o, &fs.RangeOption{Start: -1, End: 10}))
+
+	// Pointing at a file
+	_, err = fs.NewFs(remoteName + ":potato.txt")
+	assert.Equal(t, fs.ErrorIsFile, err)
+
+	// Updating to a different size renames the compressed object
+	src := object.NewStaticObjectInfo("potato.txt", time.Now(), 6, true, nil, nil)
+	require.NoError(t, o.Update(strings.NewReader("potato"), src))
+	assert.Equal(t, int64(6), o.Size())
+	assert.Equal(t, "potato", fstest.ReadObject(t, o))
+	assert.Equal(t, "potato", gunzip(t, filepath.Join(dir, "potato.txt.6.gz")))
+	_, err = os.Stat(name)
+	assert.True(t, os.IsNotExist(err))
+
+	// Files not written by compress are ignored
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "other.txt"), []byte("hello"), 0600))
+	entries, err = f.List("")
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
+}
+
+// noStreamFs is an Fs which can't stream uploads
+type noStreamFs struct {
+	fs.Fs
+	features *fs.Features
+}
+
+func (f *noStreamFs) Features() *fs.Features { return f.features }
+
+func TestCompressSpool(t *testing.T) {
+	f, dir, cleanup := prepare(t)
+	defer cleanup()
+	features := *f.Fs.Features()
+	features.PutStream = nil
+	f.Fs = &noStreamFs{Fs: f.Fs, features: &features}
+
+	contents := strings.Repeat("spool ", 100)
+	o := fstest.PutContents(t, f, "spool.txt", contents, time.Now())
+	assert.Equal(t, contents, fstest.ReadObject(t, o))
+	assert.Equal(t, contents, gunzip(t, filepath.Join(dir, "spool.txt.600.gz")))
+}
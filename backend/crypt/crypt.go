@@ -69,6 +69,18 @@ func init() {
 			Help:       "Password or pass phrase for salt. Optional but recommended.\nShould be different to the previous password.",
 			IsPassword: true,
 			Optional:   true,
+		}, {
+			Name:     "plain_dirs",
+			Help:     "Comma separated list of top level directories whose names, and the names of\neverything in them, aren't encrypted.  Their file contents are still encrypted.\nOptional - leave blank normally.",
+			Optional: true,
+		}, {
+			Name:     "keyfile",
+			Help:     "Path to a file of random bytes to mix in with the password.\nOptional - if set the file is needed as well as the password to decrypt.",
+			Optional: true,
+		}, {
+			Name:     "keyfile_command",
+			Help:     "Command whose output is mixed in with the password instead of a keyfile.\nOptional - leave blank normally.",
+			Optional: true,
 		}},
 	})
 }
@@ -98,10 +110,22 @@ func NewCipher(name string) (Cipher, error) {
 			return nil, errors.Wrap(err, "failed to decrypt password2")
 		}
 	}
+	keyFile, err := readKeyFile(config.FileGet(name, "keyfile", ""), config.FileGet(name, "keyfile_command", ""))
+	if err != nil {
+		return nil, err
+	}
+	password = mixKeyFile(password, keyFile)
 	cipher, err := newCipher(mode, password, salt, dirNameEncrypt)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to make cipher")
 	}
+	plainDirs, err := parsePlainDirs(config.FileGet(name, "plain_dirs", ""))
+	if err != nil {
+		return nil, err
+	}
+	if plainDirs != nil {
+		return newPlainDirsCipher(cipher, plainDirs), nil
+	}
 	return cipher, nil
 }
 
@@ -126,6 +150,14 @@ func NewFs(name, rpath string) (fs.Fs, error) {
 	if err != fs.ErrorIsFile && err != nil {
 		return nil, errors.Wrapf(err, "failed to make remote %q to wrap", remotePath)
 	}
+	if c, ok := cipher.(*plainDirsCipher); ok {
+		// names are now relative to the root of wrappedFs
+		if err == fs.ErrorIsFile {
+			c.setRoot(path.Dir(rpath))
+		} else {
+			c.setRoot(rpath)
+		}
+	}
 	f := &Fs{
 		Fs:     wrappedFs,
 		name:   name,
@@ -452,6 +484,15 @@ func (f *Fs) CleanUp() error {
 	return do()
 }
 
+// About gets quota information from the Fs
+func (f *Fs) About() (*fs.Usage, error) {
+	do := f.Fs.Features().About
+	if do == nil {
+		return nil, errors.New("About not supported")
+	}
+	return do()
+}
+
 // UnWrap returns the Fs that this Fs is wrapping
 func (f *Fs) UnWrap() fs.Fs {
 	return f.Fs
@@ -588,6 +629,69 @@ func (o *Object) UnWrap() fs.Object {
 	return o.Object
 }
 
+// GetTier returns the storage tier of the wrapped Object if known
+func (o *Object) GetTier() string {
+	do, ok := o.Object.(fs.GetTierer)
+	if !ok {
+		return ""
+	}
+	return do.GetTier()
+}
+
+// SetTier changes the storage tier of the wrapped Object
+func (o *Object) SetTier(tier string) error {
+	do, ok := o.Object.(fs.SetTierer)
+	if !ok {
+		return errors.New("crypt: underlying remote does not support SetTier")
+	}
+	return do.SetTier(tier)
+}
+
+// Restore restores the wrapped Object if it is archived
+func (o *Object) Restore(priority string, lifetime int) error {
+	do, ok := o.Object.(fs.Restorer)
+	if !ok {
+		return errors.New("crypt: underlying remote does not support Restore")
+	}
+	return do.Restore(priority, lifetime)
+}
+
+// RestoreStatus returns the restore status of the wrapped Object
+func (o *Object) RestoreStatus() (fs.RestoreStatus, error) {
+	do, ok := o.Object.(fs.Restorer)
+	if !ok {
+		return fs.RestoreStatusOnline, nil
+	}
+	return do.RestoreStatus()
+}
+
+// Archived returns whether the wrapped Object may be archived
+func (o *Object) Archived() bool {
+	do, ok := o.Object.(fs.Restorer)
+	if !ok {
+		return false
+	}
+	return do.Archived()
+}
+
+// Metadata returns the user metadata of the wrapped Object
+func (o *Object) Metadata() (map[string]string, error) {
+	do, ok := o.Object.(fs.Metadataer)
+	if !ok {
+		return nil, nil
+	}
+	return do.Metadata()
+}
+
+// SetMetadata replaces the user metadata of the wrapped Object
+func (o *Object) SetMetadata(metadata map[string]string) error {
+	do, ok := o.Object.(fs.SetMetadataer)
+	if !ok {
+		return errors.New("crypt: underlying remote does not support SetMetadata")
+	}
+	return do.SetMetadata(metadata)
+}
+
 // Open opens the file for read.  Call Close() on the returned io.ReadCloser
 func (o *Object) Open(options ...fs.OpenOption) (rc io.ReadCloser, err error) {
 	var openOptions []fs.OpenOption
@@ -699,7 +803,13 @@ var (
 	_ fs.CleanUpper      = (*Fs)(nil)
 	_ fs.UnWrapper       = (*Fs)(nil)
 	_ fs.ListRer         = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
 	_ fs.ObjectInfo      = (*ObjectInfo)(nil)
 	_ fs.Object          = (*Object)(nil)
 	_ fs.ObjectUnWrapper = (*Object)(nil)
+	_ fs.GetTierer       = (*Object)(nil)
+	_ fs.SetTierer       = (*Object)(nil)
+	_ fs.Restorer        = (*Object)(nil)
+	_ fs.Metadataer      = (*Object)(nil)
+	_ fs.SetMetadataer   = (*Object)(nil)
 )
@@ -0,0 +1,58 @@
+// Mix a keyfile in with the password
+
+package crypt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// readKeyFile reads the key material from the file at path, or if
+// command is set, from the standard output of the command.
+//
+// It returns nil if neither are set.
+func readKeyFile(path, command string) (keyFile []byte, err error) {
+	switch {
+	case path != "" && command != "":
+		return nil, errors.New("can't use keyfile and keyfile_command together")
+	case path != "":
+		keyFile, err = ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read keyfile")
+		}
+	case strings.TrimSpace(command) != "":
+		args := strings.Fields(command)
+		cmd := exec.Command(args[0], args[1:]...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		keyFile, err = cmd.Output()
+		if err != nil {
+			return nil, errors.Wrapf(err, "keyfile_command failed: %s", strings.TrimSpace(stderr.String()))
+		}
+	default:
+		return nil, nil
+	}
+	if len(keyFile) == 0 {
+		return nil, errors.New("keyfile is empty")
+	}
+	return keyFile, nil
+}
+
+// mixKeyFile combines the password with the contents of the keyfile
+// so that both are needed to derive the keys.
+//
+// The password can't contain a NUL so the result can't be the same
+// as any password on its own.
+func mixKeyFile(password string, keyFile []byte) string {
+	if keyFile == nil {
+		return password
+	}
+	sum := sha256.Sum256(keyFile)
+	return password + "\x00" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,58 @@
+package crypt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadKeyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-crypt-keyfile")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	path := filepath.Join(dir, "keyfile")
+	require.NoError(t, ioutil.WriteFile(path, []byte("potato"), 0600))
+	empty := filepath.Join(dir, "empty")
+	require.NoError(t, ioutil.WriteFile(empty, nil, 0600))
+
+	keyFile, err := readKeyFile("", "")
+	require.NoError(t, err)
+	assert.Nil(t, keyFile)
+
+	keyFile, err = readKeyFile(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("potato"), keyFile)
+
+	_, err = readKeyFile(empty, "")
+	assert.EqualError(t, err, "keyfile is empty")
+
+	_, err = readKeyFile(filepath.Join(dir, "notfound"), "")
+	assert.Error(t, err)
+
+	_, err = readKeyFile(path, "echo potato")
+	assert.Error(t, err)
+
+	if runtime.GOOS != "windows" {
+		keyFile, err = readKeyFile("", "echo potato")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("potato\n"), keyFile)
+
+		_, err = readKeyFile("", "false")
+		assert.Error(t, err)
+	}
+}
+
+func TestMixKeyFile(t *testing.T) {
+	assert.Equal(t, "potato", mixKeyFile("potato", nil))
+	mixed := mixKeyFile("potato", []byte("sausage"))
+	assert.Equal(t, "potato\x0030caae2fcb7c34ecadfddc45e0a27e9103bd7cfc87730d7818cc096b1266a683", mixed)
+	assert.NotEqual(t, mixed, mixKeyFile("potato", []byte("Sausage")))
+	assert.NotEqual(t, mixed, mixKeyFile("Potato", []byte("sausage")))
+}
@@ -0,0 +1,110 @@
+// Leave the names in some top level directories unencrypted
+
+package crypt
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// plainDirsCipher is a Cipher which doesn't encrypt the names of some
+// top level directories or the names of anything in them, as if
+// filename_encryption was off for them.  The contents of the files
+// are encrypted as normal.
+type plainDirsCipher struct {
+	Cipher                 // the cipher for everything else
+	dirs   map[string]bool // the top level directories to leave plain
+	root   string          // the root of the Fs relative to the top of the crypt remote
+}
+
+// parsePlainDirs parses the comma separated list of directories from
+// the plain_dirs config option
+func parsePlainDirs(list string) (dirs map[string]bool, err error) {
+	for _, dir := range strings.Split(list, ",") {
+		dir = strings.Trim(strings.TrimSpace(dir), "/")
+		if dir == "" {
+			continue
+		}
+		if strings.Contains(dir, "/") {
+			return nil, errors.Errorf("plain_dirs: %q must be a top level directory", dir)
+		}
+		if dirs == nil {
+			dirs = make(map[string]bool)
+		}
+		dirs[dir] = true
+	}
+	return dirs, nil
+}
+
+// newPlainDirsCipher wraps cipher so the names in dirs are left
+// unencrypted
+func newPlainDirsCipher(cipher Cipher, dirs map[string]bool) *plainDirsCipher {
+	return &plainDirsCipher{
+		Cipher: cipher,
+		dirs:   dirs,
+	}
+}
+
+// setRoot sets the root of the Fs which the names passed in are
+// relative to
+func (c *plainDirsCipher) setRoot(root string) {
+	if root == "." || root == "/" {
+		root = ""
+	}
+	c.root = root
+}
+
+// isPlain returns whether the name in should be left plain.
+//
+// Plain directories have the same name when encrypted and decrypted
+// so this works for both.
+func (c *plainDirsCipher) isPlain(in string, isDir bool) bool {
+	full := in
+	if c.root != "" {
+		full = path.Join(c.root, in)
+	}
+	i := strings.IndexRune(full, '/')
+	if i < 0 {
+		// a top level file is never plain
+		return isDir && c.dirs[full]
+	}
+	return c.dirs[full[:i]]
+}
+
+// EncryptFileName encrypts a file path
+func (c *plainDirsCipher) EncryptFileName(in string) string {
+	if c.isPlain(in, false) {
+		return in + encryptedSuffix
+	}
+	return c.Cipher.EncryptFileName(in)
+}
+
+// DecryptFileName decrypts a file path, returns error if decrypt was invalid
+func (c *plainDirsCipher) DecryptFileName(in string) (string, error) {
+	if c.isPlain(in, false) {
+		remainingLength := len(in) - len(encryptedSuffix)
+		if remainingLength > 0 && strings.HasSuffix(in, encryptedSuffix) {
+			return in[:remainingLength], nil
+		}
+		return "", ErrorNotAnEncryptedFile
+	}
+	return c.Cipher.DecryptFileName(in)
+}
+
+// EncryptDirName encrypts a directory path
+func (c *plainDirsCipher) EncryptDirName(in string) string {
+	if c.isPlain(in, true) {
+		return in
+	}
+	return c.Cipher.EncryptDirName(in)
+}
+
+// DecryptDirName decrypts a directory path, returns error if decrypt was invalid
+func (c *plainDirsCipher) DecryptDirName(in string) (string, error) {
+	if c.isPlain(in, true) {
+		return in, nil
+	}
+	return c.Cipher.DecryptDirName(in)
+}
@@ -0,0 +1,68 @@
+package crypt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlainDirs(t *testing.T) {
+	dirs, err := parsePlainDirs("")
+	require.NoError(t, err)
+	assert.Nil(t, dirs)
+
+	dirs, err = parsePlainDirs(" shared, /public/ ,,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"shared": true, "public": true}, dirs)
+
+	_, err = parsePlainDirs("shared/sub")
+	assert.Error(t, err)
+}
+
+func TestPlainDirsCipher(t *testing.T) {
+	base, err := newCipher(NameEncryptionStandard, "", "", true)
+	require.NoError(t, err)
+	c := newPlainDirsCipher(base, map[string]bool{"shared": true})
+
+	for _, test := range []struct {
+		root      string
+		in        string
+		isDir     bool
+		expected  string
+		encrypted bool
+	}{
+		{"", "shared", true, "shared", false},
+		{"", "shared/file.txt", false, "shared/file.txt.bin", false},
+		{"", "shared/sub", true, "shared/sub", false},
+		{"", "shared", false, base.EncryptFileName("shared"), true},
+		{"", "private/file.txt", false, base.EncryptFileName("private/file.txt"), true},
+		{"", "private", true, base.EncryptDirName("private"), true},
+		{"shared", "", true, "", false},
+		{"shared", "file.txt", false, "file.txt.bin", false},
+		{"shared/sub", "dir", true, "dir", false},
+		{"private", "file.txt", false, base.EncryptFileName("file.txt"), true},
+	} {
+		c.setRoot(test.root)
+		var got string
+		var back string
+		if test.isDir {
+			got = c.EncryptDirName(test.in)
+			back, err = c.DecryptDirName(got)
+		} else {
+			got = c.EncryptFileName(test.in)
+			back, err = c.DecryptFileName(got)
+		}
+		what := test.root + " + " + test.in
+		assert.Equal(t, test.expected, got, what)
+		require.NoError(t, err, what)
+		assert.Equal(t, test.in, back, what)
+		if test.encrypted {
+			assert.NotEqual(t, test.in, got, what)
+		}
+	}
+
+	c.setRoot("shared")
+	_, err = c.DecryptFileName("file.txt")
+	assert.Equal(t, ErrorNotAnEncryptedFile, err)
+}
@@ -75,6 +75,8 @@ var (
 		ClientSecret: obscure.MustReveal(rcloneEncryptedClientSecret),
 		RedirectURL:  oauthutil.TitleBarRedirectURL,
 	}
+	// where to revoke the token on disconnect
+	revokeURL           = "https://accounts.google.com/o/oauth2/revoke"
 	mimeTypeToExtension = map[string]string{
 		"application/epub+zip":                                                      "epub",
 		"application/msword":                                                        "doc",
@@ -166,6 +168,10 @@ func init() {
 		}, {
 			Name: "service_account_file",
 			Help: "Service Account Credentials JSON file path  - leave blank normally.\nNeeded only if you want use SA instead of interactive login.",
+		}, {
+			Name: "team_drive",
+			Help: "ID of the Team Drive - set by the config wizard.",
+			Hide: true,
 		}},
 	})
 	flags.VarP(&driveUploadCutoff, "drive-upload-cutoff", "", "Cutoff for switching to chunked upload")
@@ -202,6 +208,7 @@ type Object struct {
 	md5sum       string // md5sum of the object
 	bytes        int64  // size of the object
 	modifiedDate string // RFC3339 time it was last modified
+	createdDate  string // RFC3339 time it was created
 	isDocument   bool   // if set this is a Google doc
 	mimeType     string
 }
@@ -446,7 +453,7 @@ func newPacer() *pacer.Pacer {
 	return pacer.New().SetMinSleep(minSleep).SetPacer(pacer.GoogleDrivePacer)
 }
 
-func getServiceAccountClient(keyJsonfilePath string) (*http.Client, error) {
+func getServiceAccountClient(name, keyJsonfilePath string) (*http.Client, error) {
 	data, err := ioutil.ReadFile(os.ExpandEnv(keyJsonfilePath))
 	if err != nil {
 		return nil, errors.Wrap(err, "error opening credentials file")
@@ -458,7 +465,7 @@ func getServiceAccountClient(keyJsonfilePath string) (*http.Client, error) {
 	if *driveImpersonate != "" {
 		conf.Subject = *driveImpersonate
 	}
-	ctxWithSpecialClient := oauthutil.Context(fshttp.NewClient(fs.Config))
+	ctxWithSpecialClient := oauthutil.Context(fshttp.NewClient(fs.ConfigForRemote(name)))
 	return oauth2.NewClient(ctxWithSpecialClient, conf.TokenSource(ctxWithSpecialClient)), nil
 }
 
@@ -468,7 +475,7 @@ func createOAuthClient(name string) (*http.Client, error) {
 
 	serviceAccountPath := config.FileGet(name, "service_account_file")
 	if serviceAccountPath != "" {
-		oAuthClient, err = getServiceAccountClient(serviceAccountPath)
+		oAuthClient, err = getServiceAccountClient(name, serviceAccountPath)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to create oauth client from service account")
 		}
@@ -1297,6 +1304,75 @@ func (f *Fs) DirCacheFlush() {
 	f.dirCache.ResetRoot()
 }
 
+// UserInfo returns info about the connected user
+func (f *Fs) UserInfo() (userInfo map[string]string, err error) {
+	var about *drive.About
+	err = f.pacer.Call(func() (bool, error) {
+		about, err = f.svc.About.Get().Fields("user").Do()
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read user info")
+	}
+	if about.User == nil {
+		return nil, errors.New("no user info returned")
+	}
+	return map[string]string{
+		"Name":         about.User.DisplayName,
+		"Email":        about.User.EmailAddress,
+		"PermissionId": about.User.PermissionId,
+	}, nil
+}
+
+// About gets quota information
+func (f *Fs) About() (*fs.Usage, error) {
+	var about *drive.About
+	var err error
+	err = f.pacer.Call(func() (bool, error) {
+		about, err = f.svc.About.Get().Fields("storageQuota").Do()
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read quota")
+	}
+	if about.StorageQuota == nil {
+		return nil, errors.New("no quota info returned")
+	}
+	q := about.StorageQuota
+	usage := &fs.Usage{
+		Used:    fs.NewUsageValue(q.UsageInDrive),
+		Trashed: fs.NewUsageValue(q.UsageInDriveTrash),
+		Other:   fs.NewUsageValue(q.Usage - q.UsageInDrive),
+	}
+	// A limit of 0 means the quota is unlimited
+	if q.Limit > 0 {
+		usage.Total = fs.NewUsageValue(q.Limit)
+		usage.Free = fs.NewUsageValue(q.Limit - q.Usage)
+	}
+	return usage, nil
+}
+
+// Disconnect the current user by revoking the token
+func (f *Fs) Disconnect() error {
+	token, err := oauthutil.GetToken(f.name)
+	if err != nil {
+		return errors.Wrap(err, "failed to read token")
+	}
+	revoke := token.RefreshToken
+	if revoke == "" {
+		revoke = token.AccessToken
+	}
+	resp, err := fshttp.NewClient(fs.Config).PostForm(revokeURL, url.Values{"token": {revoke}})
+	if err != nil {
+		return errors.Wrap(err, "failed to revoke token")
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("failed to revoke token: %s", resp.Status)
+	}
+	return nil
+}
+
 // Hashes returns the supported hash sets.
 func (f *Fs) Hashes() hash.Set {
 	return hash.Set(hash.MD5)
@@ -1346,6 +1422,7 @@ func (o *Object) setMetaData(info *drive.File) {
 	} else {
 		o.modifiedDate = info.ModifiedTime
 	}
+	o.createdDate = info.CreatedTime
 	o.mimeType = info.MimeType
 }
 
@@ -1398,6 +1475,20 @@ func (o *Object) ModTime() time.Time {
 	return modTime
 }
 
+// CreatedTime returns the time the object was created in drive
+func (o *Object) CreatedTime() time.Time {
+	err := o.readMetaData()
+	if err != nil {
+		fs.Debugf(o, "Failed to read metadata: %v", err)
+		return time.Time{}
+	}
+	createdTime, err := time.Parse(timeFormatIn, o.createdDate)
+	if err != nil {
+		return time.Time{}
+	}
+	return createdTime
+}
+
 // SetModTime sets the modification time of the drive fs object
 func (o *Object) SetModTime(modTime time.Time) error {
 	err := o.readMetaData()
@@ -1594,6 +1685,10 @@ var (
 	_ fs.ChangeNotifier  = (*Fs)(nil)
 	_ fs.PutUncheckeder  = (*Fs)(nil)
 	_ fs.MergeDirser     = (*Fs)(nil)
+	_ fs.UserInfoer      = (*Fs)(nil)
+	_ fs.Disconnecter    = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
 	_ fs.Object          = (*Object)(nil)
 	_ fs.MimeTyper       = &Object{}
+	_ fs.CreatedTimer    = &Object{}
 )
@@ -2,12 +2,16 @@ package drive
 
 import (
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"google.golang.org/api/drive/v3"
 
+	"github.com/ncw/rclone/fs/config"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const exampleExportFormats = `{
@@ -110,3 +114,66 @@ func TestInternalFindExportFormat(t *testing.T) {
 		assert.Equal(t, test.wantMimeType, gotMimeType)
 	}
 }
+
+// newTestServer makes an Fs talking to a test server which serves
+// the user info and records the tokens revoked
+func newTestServer(t *testing.T, revoked *[]string) (*Fs, func()) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/about":
+			assert.Equal(t, "user", r.URL.Query().Get("fields"))
+			_, _ = w.Write([]byte(`{"user":{"displayName":"Jo Bloggs","emailAddress":"jo@example.com","permissionId":"1234"}}`))
+		case "/revoke":
+			*revoked = append(*revoked, r.FormValue("token"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	svc, err := drive.New(ts.Client())
+	require.NoError(t, err)
+	svc.BasePath = ts.URL + "/"
+	oldRevokeURL := revokeURL
+	revokeURL = ts.URL + "/revoke"
+	f := &Fs{
+		name:  "TestDriveInternal",
+		svc:   svc,
+		pacer: newPacer(),
+	}
+	return f, func() {
+		revokeURL = oldRevokeURL
+		ts.Close()
+	}
+}
+
+func TestInternalUserInfo(t *testing.T) {
+	f, cleanup := newTestServer(t, nil)
+	defer cleanup()
+	userInfo, err := f.UserInfo()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"Name":         "Jo Bloggs",
+		"Email":        "jo@example.com",
+		"PermissionId": "1234",
+	}, userInfo)
+}
+
+func TestInternalDisconnect(t *testing.T) {
+	var revoked []string
+	f, cleanup := newTestServer(t, &revoked)
+	defer cleanup()
+	defer config.FileDeleteKey(f.name, config.ConfigToken)
+
+	// No token
+	assert.Error(t, f.Disconnect())
+
+	// The refresh token is revoked in preference to the access token
+	config.FileSet(f.name, config.ConfigToken, `{"access_token":"access","refresh_token":"refresh"}`)
+	require.NoError(t, f.Disconnect())
+	config.FileSet(f.name, config.ConfigToken, `{"access_token":"access"}`)
+	require.NoError(t, f.Disconnect())
+	assert.Equal(t, []string{"refresh", "access"}, revoked)
+
+	// Failure to revoke
+	revokeURL += "/notfound"
+	assert.Error(t, f.Disconnect())
+}
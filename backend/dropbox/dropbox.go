@@ -35,7 +35,9 @@ import (
 	"time"
 
 	"github.com/dropbox/dropbox-sdk-go-unofficial/dropbox"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/dropbox/auth"
 	"github.com/dropbox/dropbox-sdk-go-unofficial/dropbox/files"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/dropbox/users"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/config"
 	"github.com/ncw/rclone/fs/config/flags"
@@ -130,6 +132,8 @@ type Fs struct {
 	root           string       // the path we are working on
 	features       *fs.Features // optional features
 	srv            files.Client // the connection to the dropbox server
+	users          users.Client // for reading info about the user
+	auth           auth.Client  // for revoking the token
 	slashRoot      string       // root with "/" prefix, lowercase
 	slashRootSlash string       // root with "/" prefix and postfix, lowercase
 	pacer          *pacer.Pacer // To pace the API calls
@@ -214,6 +218,8 @@ func NewFs(name, root string) (fs.Fs, error) {
 	f := &Fs{
 		name:  name,
 		srv:   srv,
+		users: users.New(config),
+		auth:  auth.New(config),
 		pacer: pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant),
 	}
 	f.features = (&fs.Features{
@@ -683,6 +689,220 @@ func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
 	return nil
 }
 
+// UserInfo returns info about the connected user
+func (f *Fs) UserInfo() (userInfo map[string]string, err error) {
+	var account *users.FullAccount
+	err = f.pacer.Call(func() (bool, error) {
+		account, err = f.users.GetCurrentAccount()
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read user info")
+	}
+	userInfo = map[string]string{
+		"AccountId": account.AccountId,
+		"Email":     account.Email,
+		"Country":   account.Country,
+	}
+	if account.Name != nil {
+		userInfo["Name"] = account.Name.DisplayName
+	}
+	return userInfo, nil
+}
+
+// About gets quota information
+func (f *Fs) About() (usage *fs.Usage, err error) {
+	var q *users.SpaceUsage
+	err = f.pacer.Call(func() (bool, error) {
+		q, err = f.users.GetSpaceUsage()
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "about failed")
+	}
+	var total uint64
+	if q.Allocation != nil {
+		if q.Allocation.Individual != nil {
+			total += q.Allocation.Individual.Allocated
+		}
+		if q.Allocation.Team != nil {
+			total += q.Allocation.Team.Allocated
+		}
+	}
+	usage = &fs.Usage{
+		Used: fs.NewUsageValue(int64(q.Used)), // bytes in use
+	}
+	if total > 0 {
+		free := int64(total) - int64(q.Used)
+		if free < 0 {
+			free = 0
+		}
+		usage.Total = fs.NewUsageValue(int64(total)) // quota of bytes that can be used
+		usage.Free = fs.NewUsageValue(free)          // bytes which can be uploaded before reaching the quota
+	}
+	return usage, nil
+}
+
+// Disconnect the current user by revoking the token
+func (f *Fs) Disconnect() (err error) {
+	err = f.pacer.Call(func() (bool, error) {
+		err = f.auth.TokenRevoke()
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to revoke token")
+	}
+	return nil
+}
+
+// ChangeNotify calls the passed function with a path that has had changes.
+// If the implementation uses polling, it should adhere to the given interval.
+//
+// Dropbox holds each poll open until there are changes, for up to
+// pollInterval, so changes are notified as soon as they happen.
+//
+// Automatically restarts itself in case of unexpected behaviour of the remote.
+//
+// Close the returned channel to stop being notified.
+func (f *Fs) ChangeNotify(notifyFunc func(string, fs.EntryType), pollInterval time.Duration) chan bool {
+	quit := make(chan bool)
+	go func() {
+		for {
+			err := f.changeNotifyRunner(notifyFunc, pollInterval, quit)
+			if err == nil {
+				return
+			}
+			fs.Debugf(f, "Notify listener service ran into issues, restarting shortly: %v", err)
+			select {
+			case <-quit:
+				return
+			case <-time.After(pollInterval):
+			}
+		}
+	}()
+	return quit
+}
+
+// changeNotifyRunner long polls for changes until quit is closed,
+// when it returns nil, or there is an error
+func (f *Fs) changeNotifyRunner(notifyFunc func(string, fs.EntryType), pollInterval time.Duration, quit chan bool) error {
+	cursor, err := f.changeNotifyCursor()
+	if err != nil {
+		return err
+	}
+	// Dropbox needs the timeout to be between 30s and 480s
+	timeout := uint64(pollInterval / time.Second)
+	if timeout < 30 {
+		timeout = 30
+	} else if timeout > 480 {
+		timeout = 480
+	}
+	for {
+		select {
+		case <-quit:
+			return nil
+		default:
+		}
+		fs.Debugf(f, "Checking for changes on remote")
+		var res *files.ListFolderLongpollResult
+		err = f.pacer.Call(func() (bool, error) {
+			res, err = f.srv.ListFolderLongpoll(&files.ListFolderLongpollArg{
+				Cursor:  cursor,
+				Timeout: timeout,
+			})
+			return shouldRetry(err)
+		})
+		if err != nil {
+			return errors.Wrap(err, "long poll")
+		}
+		if res.Changes {
+			cursor, err = f.changeNotifyChanges(cursor, notifyFunc)
+			if err != nil {
+				return err
+			}
+		}
+		if res.Backoff > 0 {
+			select {
+			case <-quit:
+				return nil
+			case <-time.After(time.Duration(res.Backoff) * time.Second):
+			}
+		}
+	}
+}
+
+// changeNotifyCursor returns a cursor for the current state of
+// everything under the root
+func (f *Fs) changeNotifyCursor() (cursor string, err error) {
+	arg := files.ListFolderArg{
+		Path:      f.slashRoot,
+		Recursive: true,
+	}
+	if arg.Path == "/" {
+		arg.Path = "" // Specify root folder as empty string
+	}
+	var res *files.ListFolderGetLatestCursorResult
+	err = f.pacer.Call(func() (bool, error) {
+		res, err = f.srv.ListFolderGetLatestCursor(&arg)
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "get latest cursor")
+	}
+	return res.Cursor, nil
+}
+
+// changeNotifyChanges calls notifyFunc for each change since cursor
+// and returns the cursor to read the next changes from
+func (f *Fs) changeNotifyChanges(cursor string, notifyFunc func(string, fs.EntryType)) (string, error) {
+	for {
+		arg := files.ListFolderContinueArg{
+			Cursor: cursor,
+		}
+		var res *files.ListFolderResult
+		var err error
+		err = f.pacer.Call(func() (bool, error) {
+			res, err = f.srv.ListFolderContinue(&arg)
+			return shouldRetry(err)
+		})
+		if err != nil {
+			return "", errors.Wrap(err, "list continue")
+		}
+		cursor = res.Cursor
+		for _, entry := range res.Entries {
+			var metadata *files.Metadata
+			entryType := fs.EntryObject
+			switch info := entry.(type) {
+			case *files.FolderMetadata:
+				metadata = &info.Metadata
+				entryType = fs.EntryDirectory
+			case *files.FileMetadata:
+				metadata = &info.Metadata
+			case *files.DeletedMetadata:
+				// We don't know if this was a file or a
+				// directory, but either way forgetting
+				// its parent removes it
+				metadata = &info.Metadata
+			default:
+				continue
+			}
+			if !strings.HasPrefix(metadata.PathLower, f.slashRootSlash) {
+				continue
+			}
+			// Use the cased path if it lines up with the
+			// lower case one
+			entryPath := metadata.PathDisplay
+			if len(entryPath) != len(metadata.PathLower) {
+				entryPath = metadata.PathLower
+			}
+			notifyFunc(entryPath[len(f.slashRootSlash):], entryType)
+		}
+		if !res.HasMore {
+			return cursor, nil
+		}
+	}
+}
+
 // Hashes returns the supported hash sets.
 func (f *Fs) Hashes() hash.Set {
 	return hash.Set(hash.Dropbox)
@@ -975,11 +1195,15 @@ func (o *Object) Remove() (err error) {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs          = (*Fs)(nil)
-	_ fs.Copier      = (*Fs)(nil)
-	_ fs.Purger      = (*Fs)(nil)
-	_ fs.PutStreamer = (*Fs)(nil)
-	_ fs.Mover       = (*Fs)(nil)
-	_ fs.DirMover    = (*Fs)(nil)
-	_ fs.Object      = (*Object)(nil)
+	_ fs.Fs             = (*Fs)(nil)
+	_ fs.Copier         = (*Fs)(nil)
+	_ fs.Purger         = (*Fs)(nil)
+	_ fs.PutStreamer    = (*Fs)(nil)
+	_ fs.Mover          = (*Fs)(nil)
+	_ fs.DirMover       = (*Fs)(nil)
+	_ fs.UserInfoer     = (*Fs)(nil)
+	_ fs.Disconnecter   = (*Fs)(nil)
+	_ fs.Abouter        = (*Fs)(nil)
+	_ fs.ChangeNotifier = (*Fs)(nil)
+	_ fs.Object         = (*Object)(nil)
 )
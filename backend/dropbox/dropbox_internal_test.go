@@ -0,0 +1,85 @@
+// +build go1.7
+
+package dropbox
+
+import (
+	"testing"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/dropbox/auth"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/dropbox/users"
+	"github.com/ncw/rclone/lib/pacer"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUsers is a users.Client which returns account
+type fakeUsers struct {
+	users.Client
+	account *users.FullAccount
+}
+
+func (u *fakeUsers) GetCurrentAccount() (*users.FullAccount, error) {
+	if u.account == nil {
+		return nil, errors.New("no account")
+	}
+	return u.account, nil
+}
+
+// fakeAuth is an auth.Client which counts the tokens revoked
+type fakeAuth struct {
+	auth.Client
+	revoked int
+	err     error
+}
+
+func (a *fakeAuth) TokenRevoke() error {
+	if a.err != nil {
+		return a.err
+	}
+	a.revoked++
+	return nil
+}
+
+func TestInternalUserInfo(t *testing.T) {
+	u := &fakeUsers{}
+	f := &Fs{
+		users: u,
+		pacer: pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant),
+	}
+	_, err := f.UserInfo()
+	assert.Error(t, err)
+
+	u.account = &users.FullAccount{
+		Account: users.Account{
+			AccountId: "dbid:1234",
+			Email:     "jo@example.com",
+		},
+		Country: "GB",
+	}
+	userInfo, err := f.UserInfo()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"AccountId": "dbid:1234",
+		"Email":     "jo@example.com",
+		"Country":   "GB",
+	}, userInfo)
+
+	u.account.Name = &users.Name{DisplayName: "Jo Bloggs"}
+	userInfo, err = f.UserInfo()
+	require.NoError(t, err)
+	assert.Equal(t, "Jo Bloggs", userInfo["Name"])
+}
+
+func TestInternalDisconnect(t *testing.T) {
+	a := &fakeAuth{}
+	f := &Fs{
+		auth:  a,
+		pacer: pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant),
+	}
+	require.NoError(t, f.Disconnect())
+	assert.Equal(t, 1, a.revoked)
+
+	a.err = errors.New("revoke failed")
+	assert.Error(t, f.Disconnect())
+}
@@ -41,6 +41,7 @@ func init() {
 				Optional: true,
 			}, {
 				Name:     "port",
+				Type:     fs.OptionTypeInt,
 				Help:     "FTP port, leave blank to use default (21) ",
 				Optional: true,
 			}, {
@@ -48,6 +49,19 @@ func init() {
 				Help:       "FTP password",
 				IsPassword: true,
 				Optional:   false,
+			}, {
+				Name: "url",
+				Help: "Old style FTP URL - replaced by host and port when the remote is used.",
+				Hide: true,
+			}, {
+				Name: "username",
+				Help: "Old style FTP username - replaced by user when the remote is used.",
+				Hide: true,
+			}, {
+				Name:       "password",
+				Help:       "Old style FTP password - replaced by pass when the remote is used.",
+				IsPassword: true,
+				Hide:       true,
 			},
 		},
 	})
@@ -229,9 +229,11 @@ type Object struct {
 	remote   string    // The remote path
 	url      string    // download path
 	md5sum   string    // The MD5Sum of the object
+	crc32c   string    // The CRC32C of the object
 	bytes    int64     // Bytes in the object
 	modTime  time.Time // Modified time of the object
 	mimeType string
+	tier     string // storage class of the object
 }
 
 // ------------------------------------------------------------
@@ -277,7 +279,7 @@ func parsePath(path string) (bucket, directory string, err error) {
 	return
 }
 
-func getServiceAccountClient(keyJsonfilePath string) (*http.Client, error) {
+func getServiceAccountClient(name, keyJsonfilePath string) (*http.Client, error) {
 	data, err := ioutil.ReadFile(os.ExpandEnv(keyJsonfilePath))
 	if err != nil {
 		return nil, errors.Wrap(err, "error opening credentials file")
@@ -286,7 +288,7 @@ func getServiceAccountClient(keyJsonfilePath string) (*http.Client, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "error processing credentials")
 	}
-	ctxWithSpecialClient := oauthutil.Context(fshttp.NewClient(fs.Config))
+	ctxWithSpecialClient := oauthutil.Context(fshttp.NewClient(fs.ConfigForRemote(name)))
 	return oauth2.NewClient(ctxWithSpecialClient, conf.TokenSource(ctxWithSpecialClient)), nil
 }
 
@@ -297,7 +299,7 @@ func NewFs(name, root string) (fs.Fs, error) {
 
 	serviceAccountPath := config.FileGet(name, "service_account_file")
 	if serviceAccountPath != "" {
-		oAuthClient, err = getServiceAccountClient(serviceAccountPath)
+		oAuthClient, err = getServiceAccountClient(name, serviceAccountPath)
 		if err != nil {
 			log.Fatalf("Failed configuring Google Cloud Storage Service Account: %v", err)
 		}
@@ -689,7 +691,7 @@ func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
 
 // Hashes returns the supported hash sets.
 func (f *Fs) Hashes() hash.Set {
-	return hash.Set(hash.MD5)
+	return hash.NewHashSet(hash.MD5, hash.CRC32C)
 }
 
 // ------------------------------------------------------------
@@ -712,12 +714,16 @@ func (o *Object) Remote() string {
 	return o.remote
 }
 
-// Hash returns the Md5sum of an object returning a lowercase hex string
+// Hash returns the MD5 or CRC32C of an object returning a lowercase
+// hex string
 func (o *Object) Hash(t hash.Type) (string, error) {
-	if t != hash.MD5 {
-		return "", hash.ErrUnsupported
+	switch t {
+	case hash.MD5:
+		return o.md5sum, nil
+	case hash.CRC32C:
+		return o.crc32c, nil
 	}
-	return o.md5sum, nil
+	return "", hash.ErrUnsupported
 }
 
 // Size returns the size of an object in bytes
@@ -730,6 +736,7 @@ func (o *Object) setMetaData(info *storage.Object) {
 	o.url = info.MediaLink
 	o.bytes = int64(info.Size)
 	o.mimeType = info.ContentType
+	o.tier = info.StorageClass
 
 	// Read md5sum
 	md5sumData, err := base64.StdEncoding.DecodeString(info.Md5Hash)
@@ -739,6 +746,14 @@ func (o *Object) setMetaData(info *storage.Object) {
 		o.md5sum = hex.EncodeToString(md5sumData)
 	}
 
+	// Read crc32c - this is big endian like the hex CRC32C hash
+	crc32cData, err := base64.StdEncoding.DecodeString(info.Crc32c)
+	if err != nil {
+		fs.Logf(o, "Bad CRC32C decode: %v", err)
+	} else {
+		o.crc32c = hex.EncodeToString(crc32cData)
+	}
+
 	// read mtime out of metadata if available
 	mtimeString, ok := info.Metadata[metaMtime]
 	if ok {
@@ -875,6 +890,36 @@ func (o *Object) MimeType() string {
 	return o.mimeType
 }
 
+// GetTier returns the storage class of the object
+func (o *Object) GetTier() string {
+	return o.tier
+}
+
+// SetTier changes the storage class of the object by rewriting it
+// in place
+func (o *Object) SetTier(tier string) error {
+	object := storage.Object{
+		StorageClass: strings.ToUpper(tier),
+	}
+	name := o.fs.root + o.remote
+	rewriteToken := ""
+	for {
+		call := o.fs.svc.Objects.Rewrite(o.fs.bucket, name, o.fs.bucket, name, &object)
+		if rewriteToken != "" {
+			call = call.RewriteToken(rewriteToken)
+		}
+		resp, err := call.Do()
+		if err != nil {
+			return err
+		}
+		if resp.Done {
+			o.setMetaData(resp.Resource)
+			return nil
+		}
+		rewriteToken = resp.RewriteToken
+	}
+}
+
 // Check the interfaces are satisfied
 var (
 	_ fs.Fs          = &Fs{}
@@ -883,4 +928,6 @@ var (
 	_ fs.ListRer     = &Fs{}
 	_ fs.Object      = &Object{}
 	_ fs.MimeTyper   = &Object{}
+	_ fs.GetTierer   = &Object{}
+	_ fs.SetTierer   = &Object{}
 )
@@ -0,0 +1,81 @@
+// Package api has type definitions for WebHDFS
+//
+// See https://hadoop.apache.org/docs/stable/hadoop-project-dist/hadoop-hdfs/WebHDFS.html
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+// Types of FileStatus
+const (
+	TypeFile      = "FILE"
+	TypeDirectory = "DIRECTORY"
+)
+
+// FileStatus describes a file or directory
+type FileStatus struct {
+	AccessTime       int64  `json:"accessTime"`
+	BlockSize        int64  `json:"blockSize"`
+	Group            string `json:"group"`
+	Length           int64  `json:"length"`
+	ModificationTime int64  `json:"modificationTime"` // milliseconds since the epoch
+	Owner            string `json:"owner"`
+	PathSuffix       string `json:"pathSuffix"`
+	Permission       string `json:"permission"`
+	Replication      int    `json:"replication"`
+	Type             string `json:"type"` // TypeFile or TypeDirectory
+}
+
+// ModTime returns the modification time of the FileStatus
+func (s *FileStatus) ModTime() time.Time {
+	return time.Unix(0, s.ModificationTime*int64(time.Millisecond))
+}
+
+// IsDir returns whether the FileStatus is a directory
+func (s *FileStatus) IsDir() bool {
+	return s.Type == TypeDirectory
+}
+
+// FileStatusResponse is returned by GETFILESTATUS
+type FileStatusResponse struct {
+	FileStatus FileStatus `json:"FileStatus"`
+}
+
+// FileStatusesResponse is returned by LISTSTATUS
+type FileStatusesResponse struct {
+	FileStatuses struct {
+		FileStatus []FileStatus `json:"FileStatus"`
+	} `json:"FileStatuses"`
+}
+
+// BooleanResponse is returned by MKDIRS, RENAME and DELETE
+type BooleanResponse struct {
+	Boolean bool `json:"boolean"`
+}
+
+// DelegationTokenResponse is returned by GETDELEGATIONTOKEN
+type DelegationTokenResponse struct {
+	Token struct {
+		URLString string `json:"urlString"`
+	} `json:"Token"`
+}
+
+// RemoteException is returned when an operation fails
+type RemoteException struct {
+	Exception     string `json:"exception"`
+	JavaClassName string `json:"javaClassName"`
+	Message       string `json:"message"`
+}
+
+// Error is the error response
+type Error struct {
+	RemoteException RemoteException `json:"RemoteException"`
+	StatusCode      int             `json:"-"`
+}
+
+// Error returns a string for the error and satisfies the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s (%d)", e.RemoteException.Exception, e.RemoteException.Message, e.StatusCode)
+}
@@ -0,0 +1,823 @@
+// Package hdfs provides an interface to the Hadoop distributed file
+// system (HDFS) using its WebHDFS REST API.
+
+// +build go1.7
+
+package hdfs
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/backend/hdfs/api"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/ncw/rclone/fs/fshttp"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/lib/pacer"
+	"github.com/ncw/rclone/lib/rest"
+	"github.com/pkg/errors"
+	krbclient "gopkg.in/jcmturner/gokrb5.v5/client"
+	krbconfig "gopkg.in/jcmturner/gokrb5.v5/config"
+	"gopkg.in/jcmturner/gokrb5.v5/credentials"
+)
+
+const (
+	minSleep      = 10 * time.Millisecond
+	maxSleep      = 2 * time.Second
+	decayConstant = 2 // bigger for slower decay, exponential
+	apiPath       = "/webhdfs/v1/"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "hdfs",
+		Description: "Hadoop distributed file system (HDFS) using WebHDFS",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "namenode",
+			Help: "URL of the WebHDFS server on the namenode",
+			Examples: []fs.OptionExample{{
+				Value: "http://namenode:9870",
+				Help:  "Connect to namenode on the default port for Hadoop 3",
+			}, {
+				Value: "http://namenode:50070",
+				Help:  "Connect to namenode on the default port for Hadoop 2",
+			}},
+		}, {
+			Name:     "user",
+			Help:     "Hadoop user name to use with simple authentication.\nLeave blank to use the delegation token or to be anonymous.",
+			Optional: true,
+		}, {
+			Name:     "delegation_token",
+			Help:     "Delegation token for clusters secured with Kerberos.\nLeave blank normally.",
+			Optional: true,
+		}, {
+			Name:     "service_principal_name",
+			Help:     "Kerberos service principal name of the namenode, eg HTTP/namenode.example.com\nSetting this fetches a delegation token using SPNEGO.\nLeave blank if not using Kerberos.",
+			Optional: true,
+		}, {
+			Name:     "kerberos_ccache",
+			Help:     "Path to the Kerberos credentials cache made by kinit.\nLeave blank to use $KRB5CCNAME or /tmp/krb5cc_<uid>.",
+			Optional: true,
+		}},
+	})
+}
+
+// Fs represents a remote HDFS
+type Fs struct {
+	name       string       // name of this remote
+	root       string       // the path we are working on
+	features   *fs.Features // optional features
+	srv        *rest.Client // the connection to the namenode
+	noRedirect *rest.Client // the connection to the namenode which doesn't follow redirects
+	pacer      *pacer.Pacer // pacer for API calls
+	user       string       // user for simple authentication
+	token      string       // delegation token for Kerberos authentication
+}
+
+// Object describes an HDFS file
+type Object struct {
+	fs          *Fs       // what this object is part of
+	remote      string    // The remote path
+	hasMetaData bool      // whether info below has been set
+	size        int64     // size of the object
+	modTime     time.Time // modification time of the object
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("hdfs root '%s'", f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+	509, // Bandwidth Limit Exceeded
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(resp *http.Response, err error) (bool, error) {
+	if apiErr, ok := err.(*api.Error); ok {
+		switch apiErr.RemoteException.Exception {
+		case "RetriableException", "StandbyException":
+			return true, err
+		}
+	}
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// errorHandler parses a non 2xx error response into an error
+func errorHandler(resp *http.Response) error {
+	// Decode error response
+	errResponse := new(api.Error)
+	err := rest.DecodeJSON(resp, &errResponse)
+	if err != nil {
+		fs.Debugf(nil, "Couldn't decode error response: %v", err)
+	}
+	if errResponse.RemoteException.Message == "" {
+		errResponse.RemoteException.Message = resp.Status
+	}
+	errResponse.StatusCode = resp.StatusCode
+	return errResponse
+}
+
+// isNotFound returns whether err is an HDFS file not found error
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*api.Error)
+	return ok && (apiErr.StatusCode == http.StatusNotFound || apiErr.RemoteException.Exception == "FileNotFoundException")
+}
+
+// filePath returns an escaped file path (f.root, file) relative to
+// the API root
+func (f *Fs) filePath(file string) string {
+	return rest.URLPathEscape(path.Join(f.root, file))
+}
+
+// absPath returns the absolute HDFS path of (f.root, file)
+func (f *Fs) absPath(file string) string {
+	return "/" + path.Join(f.root, file)
+}
+
+// params returns the parameters for op including the authentication
+func (f *Fs) params(op string) url.Values {
+	params := url.Values{}
+	params.Set("op", op)
+	if f.token != "" {
+		params.Set("delegation", f.token)
+	} else if f.user != "" {
+		params.Set("user.name", f.user)
+	}
+	return params
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(name, root string) (fs.Fs, error) {
+	namenode := config.FileGet(name, "namenode")
+	if namenode == "" {
+		return nil, errors.New("namenode not set in config file")
+	}
+	u, err := url.Parse(strings.TrimRight(namenode, "/") + apiPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse namenode URL")
+	}
+	// The namenode redirects uploads to a datanode which we need
+	// to follow by hand to send the data there
+	noRedirectClient := fshttp.NewClient(fs.ConfigForRemote(name))
+	noRedirectClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	f := &Fs{
+		name:       name,
+		root:       strings.Trim(root, "/"),
+		srv:        rest.NewClient(fshttp.NewClient(fs.ConfigForRemote(name))).SetRoot(u.String()),
+		noRedirect: rest.NewClient(noRedirectClient).SetRoot(u.String()),
+		pacer:      pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant),
+		user:       config.FileGet(name, "user"),
+		token:      config.FileGet(name, "delegation_token"),
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(f)
+	f.srv.SetErrorHandler(errorHandler)
+	f.noRedirect.SetErrorHandler(errorHandler)
+
+	spn := config.FileGet(name, "service_principal_name")
+	if spn != "" && f.token == "" {
+		f.token, err = f.getDelegationToken(spn, config.FileGet(name, "kerberos_ccache"))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get delegation token")
+		}
+	}
+
+	if f.root != "" {
+		// Check to see if the root actually an existing file
+		info, err := f.getFileStatus("")
+		if err == nil && !info.IsDir() {
+			f.root = path.Dir(f.root)
+			if f.root == "." {
+				f.root = ""
+			}
+			// return an error with an fs which points to the parent
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// kerberosCCache returns the path of the Kerberos credentials cache
+// to use if none is configured
+func kerberosCCache() string {
+	if ccache := os.Getenv("KRB5CCNAME"); ccache != "" {
+		return strings.TrimPrefix(ccache, "FILE:")
+	}
+	return fmt.Sprintf("/tmp/krb5cc_%d", os.Getuid())
+}
+
+// spnegoHeader returns the Authorization header to authenticate to
+// spn with the Kerberos tickets in ccache
+//
+// This is a variable so it can be replaced in the tests
+var spnegoHeader = func(spn, ccache string) (string, error) {
+	if ccache == "" {
+		ccache = kerberosCCache()
+	}
+	krb5conf := os.Getenv("KRB5_CONFIG")
+	if krb5conf == "" {
+		krb5conf = "/etc/krb5.conf"
+	}
+	cfg, err := krbconfig.Load(krb5conf)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to load Kerberos config %q", krb5conf)
+	}
+	cc, err := credentials.LoadCCache(ccache)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to load Kerberos credentials cache %q - did you run kinit?", ccache)
+	}
+	cl, err := krbclient.NewClientFromCCache(cc)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to make Kerberos client")
+	}
+	cl.WithConfig(cfg)
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		return "", err
+	}
+	err = cl.SetSPNEGOHeader(req, spn)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get Kerberos service ticket for %q", spn)
+	}
+	return req.Header.Get("Authorization"), nil
+}
+
+// getDelegationToken authenticates to the namenode with Kerberos and
+// returns a delegation token to use for the following requests
+func (f *Fs) getDelegationToken(spn, ccache string) (token string, err error) {
+	authorization, err := spnegoHeader(spn, ccache)
+	if err != nil {
+		return "", err
+	}
+	opts := rest.Opts{
+		Method:     "GET",
+		Parameters: url.Values{"op": {"GETDELEGATIONTOKEN"}},
+		ExtraHeaders: map[string]string{
+			"Authorization": authorization,
+		},
+	}
+	var result api.DelegationTokenResponse
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(&opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return "", err
+	}
+	if result.Token.URLString == "" {
+		return "", errors.New("no delegation token returned")
+	}
+	return result.Token.URLString, nil
+}
+
+// getFileStatus reads the status of remote
+func (f *Fs) getFileStatus(remote string) (info *api.FileStatus, err error) {
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       f.filePath(remote),
+		Parameters: f.params("GETFILESTATUS"),
+	}
+	var result api.FileStatusResponse
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(&opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result.FileStatus, nil
+}
+
+// Return an Object from a path
+//
+// If it can't be found it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) newObjectWithInfo(remote string, info *api.FileStatus) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	var err error
+	if info != nil {
+		// Set info
+		err = o.setMetaData(info)
+	} else {
+		err = o.readMetaData() // reads info and meta, returning an error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(remote string) (fs.Object, error) {
+	return f.newObjectWithInfo(remote, nil)
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       f.filePath(dir),
+		Parameters: f.params("LISTSTATUS"),
+	}
+	var result api.FileStatusesResponse
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(&opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fs.ErrorDirNotFound
+		}
+		return nil, errors.Wrap(err, "couldn't list files")
+	}
+	for i := range result.FileStatuses.FileStatus {
+		info := &result.FileStatuses.FileStatus[i]
+		if info.PathSuffix == "" {
+			// listing a file returns the file itself
+			return nil, fs.ErrorDirNotFound
+		}
+		remote := path.Join(dir, info.PathSuffix)
+		if info.IsDir() {
+			entries = append(entries, fs.NewDir(remote, info.ModTime()))
+		} else {
+			o, err := f.newObjectWithInfo(remote, info)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, o)
+		}
+	}
+	return entries, nil
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(in, src, options...)
+}
+
+// booleanCall makes a call whose result is a boolean, returning an
+// error if it is false
+func (f *Fs) booleanCall(opts *rest.Opts) (err error) {
+	var result api.BooleanResponse
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return err
+	}
+	if !result.Boolean {
+		return errors.Errorf("%s failed", opts.Parameters.Get("op"))
+	}
+	return nil
+}
+
+// mkdirs creates the directory at the absolute path dirPath and any
+// parents it needs
+func (f *Fs) mkdirs(dirPath string) error {
+	opts := rest.Opts{
+		Method:     "PUT",
+		Path:       rest.URLPathEscape(strings.TrimPrefix(dirPath, "/")),
+		Parameters: f.params("MKDIRS"),
+	}
+	err := f.booleanCall(&opts)
+	if err != nil {
+		return errors.Wrap(err, "mkdir failed")
+	}
+	return nil
+}
+
+// Mkdir creates the directory if it doesn't exist
+func (f *Fs) Mkdir(dir string) error {
+	return f.mkdirs(f.absPath(dir))
+}
+
+// delete removes the file or directory at remote
+func (f *Fs) delete(remote string, recursive bool) error {
+	opts := rest.Opts{
+		Method:     "DELETE",
+		Path:       f.filePath(remote),
+		Parameters: f.params("DELETE"),
+	}
+	opts.Parameters.Set("recursive", strconv.FormatBool(recursive))
+	return f.booleanCall(&opts)
+}
+
+// Rmdir deletes the directory
+//
+// Returns an error if it isn't empty
+func (f *Fs) Rmdir(dir string) error {
+	info, err := f.getFileStatus(dir)
+	if err != nil {
+		if isNotFound(err) {
+			return fs.ErrorDirNotFound
+		}
+		return errors.Wrap(err, "rmdir failed")
+	}
+	if !info.IsDir() {
+		return fs.ErrorIsFile
+	}
+	err = f.delete(dir, false)
+	if err != nil {
+		if apiErr, ok := err.(*api.Error); ok && apiErr.RemoteException.Exception == "PathIsNotEmptyDirectoryException" {
+			return fs.ErrorDirectoryNotEmpty
+		}
+		return errors.Wrap(err, "rmdir failed")
+	}
+	return nil
+}
+
+// Precision return the precision of this Fs
+func (f *Fs) Precision() time.Duration {
+	return time.Millisecond
+}
+
+// Purge deletes all the files and the container
+//
+// Optional interface: Only implement this if you have a way of
+// deleting all the files quicker than just running Remove() on the
+// result of List()
+func (f *Fs) Purge() error {
+	err := f.delete("", true)
+	if err != nil {
+		return errors.Wrap(err, "purge failed")
+	}
+	return nil
+}
+
+// rename renames the absolute path srcPath to the absolute path
+// dstPath making the parent directory of dstPath first
+func (f *Fs) rename(srcPath, dstPath string) error {
+	err := f.mkdirs(path.Dir(dstPath))
+	if err != nil {
+		return err
+	}
+	opts := rest.Opts{
+		Method:     "PUT",
+		Path:       rest.URLPathEscape(strings.TrimPrefix(srcPath, "/")),
+		Parameters: f.params("RENAME"),
+	}
+	opts.Parameters.Set("destination", dstPath)
+	return f.booleanCall(&opts)
+}
+
+// Move src to this remote using server side move operations.
+//
+// This is stored in the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	dstPath := f.absPath(remote)
+	// RENAME won't overwrite so remove any existing file first
+	if dstObj, err := f.NewObject(remote); err == nil {
+		err = dstObj.Remove()
+		if err != nil {
+			return nil, errors.Wrap(err, "move: failed to remove existing file")
+		}
+	}
+	err := f.rename(srcObj.fs.absPath(srcObj.remote), dstPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "move failed")
+	}
+	return f.NewObject(remote)
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	_, err := f.getFileStatus(dstRemote)
+	if err == nil {
+		return fs.ErrorDirExists
+	} else if !isNotFound(err) {
+		return errors.Wrap(err, "dirmove: failed to read destination")
+	}
+	err = f.rename(srcFs.absPath(srcRemote), f.absPath(dstRemote))
+	if err != nil {
+		return errors.Wrap(err, "dirmove failed")
+	}
+	return nil
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.None)
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the hash of an object returning a lowercase hex string
+//
+// HDFS checksums are of the blocks so aren't comparable with other
+// remotes.
+func (o *Object) Hash(t hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	err := o.readMetaData()
+	if err != nil {
+		fs.Logf(o, "Failed to read metadata: %v", err)
+		return 0
+	}
+	return o.size
+}
+
+// setMetaData sets the metadata from info
+func (o *Object) setMetaData(info *api.FileStatus) error {
+	if info.IsDir() {
+		return fs.ErrorNotAFile
+	}
+	o.hasMetaData = true
+	o.size = info.Length
+	o.modTime = info.ModTime()
+	return nil
+}
+
+// readMetaData gets the metadata if it hasn't already been fetched
+//
+// it also sets the info
+func (o *Object) readMetaData() (err error) {
+	if o.hasMetaData {
+		return nil
+	}
+	info, err := o.fs.getFileStatus(o.remote)
+	if err != nil {
+		if isNotFound(err) {
+			return fs.ErrorObjectNotFound
+		}
+		return err
+	}
+	return o.setMetaData(info)
+}
+
+// ModTime returns the modification time of the object
+//
+// It attempts to read the objects mtime and if that isn't present the
+// LastModified returned in the http headers
+func (o *Object) ModTime() time.Time {
+	err := o.readMetaData()
+	if err != nil {
+		fs.Logf(o, "Failed to read metadata: %v", err)
+		return time.Now()
+	}
+	return o.modTime
+}
+
+// SetModTime sets the modification time of the local fs object
+func (o *Object) SetModTime(modTime time.Time) error {
+	opts := rest.Opts{
+		Method:     "PUT",
+		Path:       o.fs.filePath(o.remote),
+		Parameters: o.fs.params("SETTIMES"),
+		NoResponse: true,
+	}
+	opts.Parameters.Set("modificationtime", strconv.FormatInt(modTime.UnixNano()/int64(time.Millisecond), 10))
+	var resp *http.Response
+	err := o.fs.pacer.Call(func() (bool, error) {
+		var err error
+		resp, err = o.fs.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to set modification time")
+	}
+	o.modTime = modTime.Truncate(time.Millisecond)
+	return nil
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       o.fs.filePath(o.remote),
+		Parameters: o.fs.params("OPEN"),
+	}
+	// Use WebHDFS parameters for the range as the redirect to
+	// the datanode may not keep the Range header
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			opts.Parameters.Set("offset", strconv.FormatInt(x.Offset, 10))
+		case *fs.RangeOption:
+			offset, limit := x.Decode(o.Size())
+			opts.Parameters.Set("offset", strconv.FormatInt(offset, 10))
+			if limit >= 0 {
+				opts.Parameters.Set("length", strconv.FormatInt(limit, 10))
+			}
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, err
+}
+
+// createLocation asks the namenode where to upload the object to
+func (o *Object) createLocation() (location string, err error) {
+	opts := rest.Opts{
+		Method:       "PUT",
+		Path:         o.fs.filePath(o.remote),
+		Parameters:   o.fs.params("CREATE"),
+		IgnoreStatus: true,
+		NoResponse:   true,
+	}
+	opts.Parameters.Set("overwrite", "true")
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.noRedirect.Call(&opts)
+		if err == nil && resp.StatusCode != http.StatusTemporaryRedirect {
+			err = errorHandler(resp)
+		}
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return "", err
+	}
+	location = resp.Header.Get("Location")
+	if location == "" {
+		return "", errors.New("no Location in redirect")
+	}
+	return location, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// If existing is set then it updates the object rather than creating a new one
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	err = o.fs.Mkdir(path.Dir(o.remote))
+	if err != nil {
+		return errors.Wrap(err, "Update mkdir failed")
+	}
+	location, err := o.createLocation()
+	if err != nil {
+		return errors.Wrap(err, "Update create failed")
+	}
+	size := src.Size()
+	opts := rest.Opts{
+		Method:      "PUT",
+		RootURL:     location,
+		Body:        in,
+		NoResponse:  true,
+		ContentType: "application/octet-stream",
+		Options:     options,
+	}
+	if size >= 0 {
+		opts.ContentLength = &size
+	}
+	var resp *http.Response
+	err = o.fs.pacer.CallNoRetry(func() (bool, error) {
+		resp, err = o.fs.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		// Don't leave a partial file behind
+		if removeErr := o.Remove(); removeErr != nil {
+			fs.Debugf(o, "Failed to remove partial upload: %v", removeErr)
+		}
+		return errors.Wrap(err, "Update failed")
+	}
+	err = o.SetModTime(src.ModTime())
+	if err != nil {
+		return err
+	}
+	// read metadata from remote
+	o.hasMetaData = false
+	return o.readMetaData()
+}
+
+// Remove an object
+func (o *Object) Remove() error {
+	return o.fs.delete(o.remote, false)
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = (*Fs)(nil)
+	_ fs.Purger      = (*Fs)(nil)
+	_ fs.PutStreamer = (*Fs)(nil)
+	_ fs.Mover       = (*Fs)(nil)
+	_ fs.DirMover    = (*Fs)(nil)
+	_ fs.Object      = (*Object)(nil)
+)
@@ -0,0 +1,312 @@
+// +build go1.7
+
+package hdfs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/backend/hdfs/api"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNamenode is an in memory WebHDFS server
+type fakeNamenode struct {
+	mu      sync.Mutex
+	files   map[string][]byte    // absolute path to contents
+	dirs    map[string]bool      // absolute paths of directories
+	modTime map[string]time.Time // absolute path to modification time
+	user    string               // the user.name of the last request
+	token   string               // the delegation token of the last request
+	server  *httptest.Server
+}
+
+func newFakeNamenode() *fakeNamenode {
+	n := &fakeNamenode{
+		files:   map[string][]byte{},
+		dirs:    map[string]bool{"/": true},
+		modTime: map[string]time.Time{},
+	}
+	n.server = httptest.NewServer(n)
+	return n
+}
+
+func (n *fakeNamenode) status(p string) (api.FileStatus, bool) {
+	ms := n.modTime[p].UnixNano() / int64(time.Millisecond)
+	if data, ok := n.files[p]; ok {
+		return api.FileStatus{Type: api.TypeFile, Length: int64(len(data)), ModificationTime: ms, PathSuffix: path.Base(p)}, true
+	}
+	if n.dirs[p] {
+		return api.FileStatus{Type: api.TypeDirectory, ModificationTime: ms, PathSuffix: path.Base(p)}, true
+	}
+	return api.FileStatus{}, false
+}
+
+func (n *fakeNamenode) reply(w http.ResponseWriter, code int, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+func (n *fakeNamenode) fail(w http.ResponseWriter, code int, exception string) {
+	n.reply(w, code, api.Error{RemoteException: api.RemoteException{Exception: exception, Message: exception}})
+}
+
+func (n *fakeNamenode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if strings.HasPrefix(r.URL.Path, "/data/") {
+		// the datanode
+		p := strings.TrimPrefix(r.URL.Path, "/data")
+		switch r.Method {
+		case "PUT":
+			data, _ := ioutil.ReadAll(r.Body)
+			n.files[p] = data
+			n.modTime[p] = time.Now()
+			w.WriteHeader(http.StatusCreated)
+		case "GET":
+			data := n.files[p]
+			offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+			if offset > len(data) {
+				offset = len(data)
+			}
+			data = data[offset:]
+			if length, err := strconv.Atoi(r.URL.Query().Get("length")); err == nil && length < len(data) {
+				data = data[:length]
+			}
+			_, _ = w.Write(data)
+		}
+		return
+	}
+	p := path.Clean("/" + strings.TrimPrefix(r.URL.Path, apiPath))
+	q := r.URL.Query()
+	n.user = q.Get("user.name")
+	n.token = q.Get("delegation")
+	st, exists := n.status(p)
+	switch q.Get("op") {
+	case "GETDELEGATIONTOKEN":
+		if r.Header.Get("Authorization") != "Negotiate ticket" {
+			n.fail(w, http.StatusUnauthorized, "AuthenticationException")
+			return
+		}
+		var result api.DelegationTokenResponse
+		result.Token.URLString = "TOKEN"
+		n.reply(w, http.StatusOK, result)
+	case "GETFILESTATUS":
+		if !exists {
+			n.fail(w, http.StatusNotFound, "FileNotFoundException")
+			return
+		}
+		n.reply(w, http.StatusOK, api.FileStatusResponse{FileStatus: st})
+	case "LISTSTATUS":
+		if !exists {
+			n.fail(w, http.StatusNotFound, "FileNotFoundException")
+			return
+		}
+		var result api.FileStatusesResponse
+		if !st.IsDir() {
+			st.PathSuffix = ""
+			result.FileStatuses.FileStatus = append(result.FileStatuses.FileStatus, st)
+		}
+		for child := range n.files {
+			if path.Dir(child) == p {
+				st, _ := n.status(child)
+				result.FileStatuses.FileStatus = append(result.FileStatuses.FileStatus, st)
+			}
+		}
+		for child := range n.dirs {
+			if child != "/" && path.Dir(child) == p {
+				st, _ := n.status(child)
+				result.FileStatuses.FileStatus = append(result.FileStatuses.FileStatus, st)
+			}
+		}
+		n.reply(w, http.StatusOK, result)
+	case "MKDIRS":
+		for dir := p; !n.dirs[dir]; dir = path.Dir(dir) {
+			n.dirs[dir] = true
+		}
+		n.reply(w, http.StatusOK, api.BooleanResponse{Boolean: true})
+	case "CREATE":
+		w.Header().Set("Location", n.server.URL+(&url.URL{Path: "/data" + p}).String())
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	case "OPEN":
+		if !exists {
+			n.fail(w, http.StatusNotFound, "FileNotFoundException")
+			return
+		}
+		http.Redirect(w, r, n.server.URL+(&url.URL{Path: "/data" + p, RawQuery: r.URL.RawQuery}).String(), http.StatusTemporaryRedirect)
+	case "SETTIMES":
+		ms, _ := strconv.ParseInt(q.Get("modificationtime"), 10, 64)
+		n.modTime[p] = time.Unix(0, ms*int64(time.Millisecond))
+		w.WriteHeader(http.StatusOK)
+	case "DELETE":
+		if !exists {
+			n.reply(w, http.StatusOK, api.BooleanResponse{Boolean: false})
+			return
+		}
+		if st.IsDir() {
+			for child := range n.files {
+				if isUnder(child, p) {
+					if q.Get("recursive") != "true" {
+						n.fail(w, http.StatusForbidden, "PathIsNotEmptyDirectoryException")
+						return
+					}
+					delete(n.files, child)
+				}
+			}
+			for child := range n.dirs {
+				if isUnder(child, p) {
+					if q.Get("recursive") != "true" {
+						n.fail(w, http.StatusForbidden, "PathIsNotEmptyDirectoryException")
+						return
+					}
+					delete(n.dirs, child)
+				}
+			}
+			if p != "/" {
+				delete(n.dirs, p)
+			}
+		} else {
+			delete(n.files, p)
+		}
+		n.reply(w, http.StatusOK, api.BooleanResponse{Boolean: true})
+	case "RENAME":
+		dst := q.Get("destination")
+		if _, dstExists := n.status(dst); !exists || dstExists {
+			n.reply(w, http.StatusOK, api.BooleanResponse{Boolean: false})
+			return
+		}
+		for child, data := range n.files {
+			if child == p || isUnder(child, p) {
+				delete(n.files, child)
+				n.files[dst+strings.TrimPrefix(child, p)] = data
+				n.modTime[dst+strings.TrimPrefix(child, p)] = n.modTime[child]
+			}
+		}
+		for child := range n.dirs {
+			if child == p || isUnder(child, p) {
+				delete(n.dirs, child)
+				n.dirs[dst+strings.TrimPrefix(child, p)] = true
+			}
+		}
+		n.reply(w, http.StatusOK, api.BooleanResponse{Boolean: true})
+	default:
+		n.fail(w, http.StatusBadRequest, "IllegalArgumentException")
+	}
+}
+
+// isUnder returns whether child is inside the directory p
+func isUnder(child, p string) bool {
+	return child != p && strings.HasPrefix(child, strings.TrimSuffix(p, "/")+"/")
+}
+
+func newTestFs(t *testing.T, root string) (*Fs, *fakeNamenode) {
+	n := newFakeNamenode()
+	config.LoadConfig()
+	config.FileSet("TestHdfsInternal", "type", "hdfs")
+	config.FileSet("TestHdfsInternal", "namenode", n.server.URL)
+	config.FileSet("TestHdfsInternal", "user", "hadoop")
+	f, err := NewFs("TestHdfsInternal", root)
+	require.NoError(t, err)
+	return f.(*Fs), n
+}
+
+func TestPutOpenList(t *testing.T) {
+	f, n := newTestFs(t, "root")
+	defer n.server.Close()
+
+	modTime := time.Date(2018, 6, 10, 12, 0, 0, 123000000, time.UTC)
+	o := fstest.PutContents(t, f, "dir/file.txt", "hello world", modTime)
+	assert.Equal(t, int64(11), o.Size())
+	assert.True(t, modTime.Equal(o.ModTime()))
+	assert.Equal(t, "hadoop", n.user)
+	assert.Equal(t, []byte("hello world"), n.files["/root/dir/file.txt"])
+
+	assert.Equal(t, []string{"dir/"}, fstest.ListNames(t, f, ""))
+	assert.Equal(t, []string{"dir/file.txt"}, fstest.ListNames(t, f, "dir"))
+	_, err := f.List("dir/file.txt")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+	_, err = f.List("missing")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+
+	assert.Equal(t, "wor", fstest.ReadObject(t, o, &fs.RangeOption{Start: 6, End: 8}))
+
+	// A file root gives fs.ErrorIsFile
+	_, err = NewFs("TestHdfsInternal", "root/dir/file.txt")
+	assert.Equal(t, fs.ErrorIsFile, err)
+}
+
+func TestMoveAndRemove(t *testing.T) {
+	f, n := newTestFs(t, "")
+	defer n.server.Close()
+
+	o := fstest.PutContents(t, f, "a/one", "one", time.Now())
+	fstest.PutContents(t, f, "b/two", "two", time.Now())
+
+	_, err := operations.Move(f, nil, "b/two", o)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("one"), n.files["/b/two"])
+	assert.Nil(t, n.files["/a/one"])
+
+	err = f.DirMove(f, "b", "c/d")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c/d/two"}, fstest.ListNames(t, f, "c/d"))
+	assert.Equal(t, fs.ErrorDirExists, f.DirMove(f, "a", "c"))
+
+	assert.Equal(t, fs.ErrorDirectoryNotEmpty, f.Rmdir("c"))
+	assert.Equal(t, fs.ErrorDirNotFound, f.Rmdir("missing"))
+	require.NoError(t, f.Rmdir("a"))
+	require.NoError(t, f.Purge())
+	assert.Equal(t, 0, len(n.files))
+}
+
+func TestKerberosDelegationToken(t *testing.T) {
+	oldSpnegoHeader := spnegoHeader
+	defer func() {
+		spnegoHeader = oldSpnegoHeader
+	}()
+	var gotSpn, gotCCache string
+	spnegoHeader = func(spn, ccache string) (string, error) {
+		gotSpn, gotCCache = spn, ccache
+		return "Negotiate ticket", nil
+	}
+
+	n := newFakeNamenode()
+	defer n.server.Close()
+	config.LoadConfig()
+	config.FileSet("TestHdfsKerberos", "type", "hdfs")
+	config.FileSet("TestHdfsKerberos", "namenode", n.server.URL)
+	config.FileSet("TestHdfsKerberos", "service_principal_name", "HTTP/namenode@EXAMPLE.COM")
+	config.FileSet("TestHdfsKerberos", "kerberos_ccache", "/tmp/ccache")
+	f, err := NewFs("TestHdfsKerberos", "")
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/namenode@EXAMPLE.COM", gotSpn)
+	assert.Equal(t, "/tmp/ccache", gotCCache)
+
+	// The token is used for the following requests
+	fstest.PutContents(t, f, "file.txt", "hello", time.Now())
+	assert.Equal(t, "TOKEN", n.token)
+	assert.Equal(t, "", n.user)
+
+	// A failed negotiation is returned
+	spnegoHeader = func(spn, ccache string) (string, error) {
+		return "Negotiate bad", nil
+	}
+	_, err = NewFs("TestHdfsKerberos", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "AuthenticationException")
+}
@@ -14,6 +14,7 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ncw/rclone/fs"
@@ -62,6 +63,7 @@ type Fs struct {
 type Object struct {
 	fs          *Fs
 	remote      string
+	statOnce    sync.Once // used to read the metadata lazily
 	size        int64
 	modTime     time.Time
 	contentType string
@@ -97,7 +99,7 @@ func NewFs(name, root string) (fs.Fs, error) {
 		return nil, err
 	}
 
-	client := fshttp.NewClient(fs.Config)
+	client := fshttp.NewClient(fs.ConfigForRemote(name))
 
 	var isFile = false
 	if !strings.HasSuffix(u.String(), "/") {
@@ -319,6 +321,15 @@ func (f *Fs) readDir(dir string) (names []string, err error) {
 // This should return ErrDirNotFound if the directory isn't
 // found.
 func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+	return f.ListP(dir, fs.ListOptAll)
+}
+
+// ListP lists the objects and directories in dir like List.
+//
+// Reading the size or modification time of a file takes a HEAD
+// request, so if neither is needed the files are returned without
+// them and they will be read when first asked for.
+func (f *Fs) ListP(dir string, opt fs.ListOpt) (entries fs.DirEntries, err error) {
 	if !strings.HasSuffix(dir, "/") && dir != "" {
 		dir += "/"
 	}
@@ -338,9 +349,11 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 				fs:     f,
 				remote: remote,
 			}
-			if err = file.stat(); err != nil {
-				fs.Debugf(remote, "skipping because of error: %v", err)
-				continue
+			if opt.Size || opt.ModTime {
+				if err = file.stat(); err != nil {
+					fs.Debugf(remote, "skipping because of error: %v", err)
+					continue
+				}
 			}
 			entries = append(entries, file)
 		}
@@ -387,11 +400,13 @@ func (o *Object) Hash(r hash.Type) (string, error) {
 
 // Size returns the size in bytes of the remote http file
 func (o *Object) Size() int64 {
+	o.lazyStat()
 	return o.size
 }
 
 // ModTime returns the modification time of the remote http file
 func (o *Object) ModTime() time.Time {
+	o.lazyStat()
 	return o.modTime
 }
 
@@ -400,8 +415,28 @@ func (o *Object) url() string {
 	return o.fs.url(o.remote)
 }
 
+// lazyStat reads the metadata if it hasn't been read yet
+func (o *Object) lazyStat() {
+	o.statOnce.Do(func() {
+		o.size = -1
+		o.modTime = timeUnset
+		err := o.doStat()
+		if err != nil {
+			fs.Debugf(o, "Failed to read metadata: %v", err)
+		}
+	})
+}
+
 // stat updates the info field in the Object
-func (o *Object) stat() error {
+func (o *Object) stat() (err error) {
+	o.statOnce.Do(func() {
+		err = o.doStat()
+	})
+	return err
+}
+
+// doStat reads the metadata with a HEAD request
+func (o *Object) doStat() error {
 	url := o.url()
 	res, err := o.fs.httpClient.Head(url)
 	err = statusError(res, err)
@@ -479,6 +514,7 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 
 // MimeType of an Object if known, "" otherwise
 func (o *Object) MimeType() string {
+	o.lazyStat()
 	return o.contentType
 }
 
@@ -486,6 +522,7 @@ func (o *Object) MimeType() string {
 var (
 	_ fs.Fs          = &Fs{}
 	_ fs.PutStreamer = &Fs{}
+	_ fs.ListPer     = &Fs{}
 	_ fs.Object      = &Object{}
 	_ fs.MimeTyper   = &Object{}
 )
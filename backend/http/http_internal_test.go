@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -116,6 +117,43 @@ func TestListSubDir(t *testing.T) {
 	assert.True(t, ok)
 }
 
+func TestListPLazy(t *testing.T) {
+	// Count the HEAD requests made
+	var heads int32
+	fileServer := http.FileServer(http.Dir(filesPath))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			atomic.AddInt32(&heads, 1)
+		}
+		fileServer.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	config.LoadConfig()
+	config.FileSet(remoteName, "type", "http")
+	config.FileSet(remoteName, "url", ts.URL)
+	f, err := NewFs(remoteName, "")
+	require.NoError(t, err)
+	listP := f.Features().ListP
+	require.NotNil(t, listP)
+
+	// No metadata needed so no HEAD requests
+	entries, err := listP("three", fs.ListOpt{})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&heads))
+
+	// Reading the size does a HEAD request once only
+	e := entries[0]
+	assert.Equal(t, int64(9), e.Size())
+	assert.False(t, e.ModTime().IsZero())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&heads))
+
+	// Metadata needed so HEAD requests are made
+	_, err = listP("three", fs.ListOpt{Size: true})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&heads))
+}
+
 func TestNewObject(t *testing.T) {
 	f, tidy := prepare(t)
 	defer tidy()
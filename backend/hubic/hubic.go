@@ -160,7 +160,7 @@ func NewFs(name, root string) (fs.Fs, error) {
 		Auth:           newAuth(f),
 		ConnectTimeout: 10 * fs.Config.ConnectTimeout, // Use the timeouts in the transport
 		Timeout:        10 * fs.Config.Timeout,        // Use the timeouts in the transport
-		Transport:      fshttp.NewTransport(fs.Config),
+		Transport:      fshttp.NewTransport(fs.ConfigForRemote(name)),
 	}
 	err = c.Authenticate()
 	if err != nil {
@@ -0,0 +1,92 @@
+// Package api has type definitions for the Internet Archive
+//
+// See https://archive.org/services/docs/api/ias3.html and
+// https://archive.org/services/docs/api/metadata.html
+package api
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Sources of files in an item
+const (
+	SourceOriginal   = "original"   // uploaded to the item
+	SourceDerivative = "derivative" // made from an original by the archive
+	SourceMetadata   = "metadata"   // describes the item, eg <item>_meta.xml
+)
+
+// File describes a file in an item as returned by the metadata API
+//
+// All the values are strings in the JSON.
+type File struct {
+	Name        string `json:"name"`
+	Source      string `json:"source"`
+	Format      string `json:"format"`
+	Size        string `json:"size"`
+	Mtime       string `json:"mtime"` // seconds since the epoch
+	MD5         string `json:"md5"`
+	SHA1        string `json:"sha1"`
+	CRC32       string `json:"crc32"`
+	RcloneMtime string `json:"rclone-mtime"` // RFC3339 set on upload by rclone
+}
+
+// ModTime returns the modification time of the file, preferring the
+// one rclone stored on upload as it is more accurate
+func (f *File) ModTime() time.Time {
+	if f.RcloneMtime != "" {
+		t, err := time.Parse(time.RFC3339Nano, f.RcloneMtime)
+		if err == nil {
+			return t
+		}
+	}
+	secs, err := strconv.ParseInt(f.Mtime, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(secs, 0)
+}
+
+// Length returns the size of the file or -1 if it isn't known
+func (f *File) Length() int64 {
+	size, err := strconv.ParseInt(f.Size, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return size
+}
+
+// MetadataResponse is returned by the metadata API for an item.  It
+// is empty if the item doesn't exist.
+type MetadataResponse struct {
+	Created int64  `json:"created"`
+	Files   []File `json:"files"`
+}
+
+// Bucket is an item as returned by ListAllMyBuckets
+type Bucket struct {
+	Name         string    `xml:"Name"`
+	CreationDate time.Time `xml:"CreationDate"`
+}
+
+// ListAllMyBucketsResult is returned by a GET of the S3 endpoint root
+type ListAllMyBucketsResult struct {
+	XMLName xml.Name `xml:"ListAllMyBucketsResult"`
+	Buckets []Bucket `xml:"Buckets>Bucket"`
+}
+
+// Error is the error response from the S3 endpoint
+type Error struct {
+	XMLName    xml.Name `xml:"Error"`
+	Code       string   `xml:"Code"`
+	Message    string   `xml:"Message"`
+	Resource   string   `xml:"Resource"`
+	StatusCode int      `xml:"-"`
+}
+
+// Error returns a string for the error and satisfies the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s (%d)", e.Code, e.Message, e.StatusCode)
+}
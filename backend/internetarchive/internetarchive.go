@@ -0,0 +1,797 @@
+// Package internetarchive provides an interface to the Internet
+// Archive (archive.org) using its S3 like API for uploads and its
+// metadata API for listings.
+package internetarchive
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/backend/internetarchive/api"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/ncw/rclone/fs/fshttp"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/walk"
+	"github.com/ncw/rclone/lib/pacer"
+	"github.com/ncw/rclone/lib/rest"
+	"github.com/pkg/errors"
+)
+
+const (
+	minSleep             = 10 * time.Millisecond
+	maxSleep             = 2 * time.Second
+	decayConstant        = 2 // bigger for slower decay, exponential
+	defaultEndpoint      = "https://s3.us.archive.org"
+	defaultFrontEndpoint = "https://archive.org"
+	mtimeKey             = "rclone-mtime" // file metadata key for the modification time
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "internetarchive",
+		Description: "Internet Archive (archive.org)",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "access_key_id",
+			Help: "IAS3 access key from https://archive.org/account/s3.php\nLeave blank for anonymous access (read only).",
+		}, {
+			Name: "secret_access_key",
+			Help: "IAS3 secret key from https://archive.org/account/s3.php\nLeave blank for anonymous access (read only).",
+		}, {
+			Name:     "item_metadata",
+			Help:     "Metadata for new items as comma separated key=value pairs.\nRepeat a key to give it several values.",
+			Optional: true,
+			Examples: []fs.OptionExample{{
+				Value: "mediatype=data,collection=opensource",
+				Help:  "Put new items in the community data collection",
+			}},
+		}, {
+			Name:     "include_derivatives",
+			Type:     fs.OptionTypeBool,
+			Help:     "List the files the archive derives from the uploaded ones (true or false).",
+			Optional: true,
+		}, {
+			Name:     "queue_derive",
+			Type:     fs.OptionTypeBool,
+			Help:     "Ask the archive to make derivatives after each upload (true or false, default true).",
+			Optional: true,
+		}, {
+			Name:     "endpoint",
+			Help:     "IAS3 endpoint, leave blank for " + defaultEndpoint,
+			Optional: true,
+		}, {
+			Name:     "front_endpoint",
+			Help:     "Host of the metadata API and downloads, leave blank for " + defaultFrontEndpoint,
+			Optional: true,
+		}},
+	})
+}
+
+// Fs represents a remote Internet Archive item
+type Fs struct {
+	name               string            // name of this remote
+	root               string            // the path in the item we are working on
+	features           *fs.Features      // optional features
+	srv                *rest.Client      // the connection to the IAS3 endpoint
+	front              *rest.Client      // the connection to the metadata API and downloads
+	pacer              *pacer.Pacer      // pacer for API calls
+	bucket             string            // the item we are working on
+	itemHeaders        map[string]string // metadata headers for new items
+	includeDerivatives bool              // whether to list derivative files
+	queueDerive        bool              // whether to derive after uploads
+	bucketOKMu         sync.Mutex        // mutex to protect bucket OK
+	bucketOK           bool              // true if we have created the item
+}
+
+// Object describes a file in an Internet Archive item
+type Object struct {
+	fs      *Fs       // what this object is part of
+	remote  string    // The remote path
+	size    int64     // size of the object
+	modTime time.Time // modification time of the object
+	md5     string    // MD5 hash if known
+	sha1    string    // SHA-1 hash if known
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	if f.root == "" {
+		return f.bucket
+	}
+	return f.bucket + "/" + f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	if f.root == "" {
+		return fmt.Sprintf("Internet Archive item %s", f.bucket)
+	}
+	return fmt.Sprintf("Internet Archive item %s path %s", f.bucket, f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable - the archive says SlowDown with this
+	504, // Gateway Timeout
+	509, // Bandwidth Limit Exceeded
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(resp *http.Response, err error) (bool, error) {
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// errorHandler parses a non 2xx error response into an error
+func errorHandler(resp *http.Response) error {
+	// Decode error response - this will fail for the front
+	// endpoint which returns HTML
+	errResponse := new(api.Error)
+	err := rest.DecodeXML(resp, &errResponse)
+	if err != nil {
+		fs.Debugf(nil, "Couldn't decode error response: %v", err)
+	}
+	if errResponse.Code == "" {
+		errResponse.Code = resp.Status
+	}
+	errResponse.StatusCode = resp.StatusCode
+	return errResponse
+}
+
+// isNotFound returns whether err is a not found error
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*api.Error)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Pattern to match an Internet Archive path
+var matcher = regexp.MustCompile(`^([^/]*)(.*)$`)
+
+// parsePath parses an Internet Archive path into the item and the
+// path within it
+func parsePath(p string) (bucket, directory string) {
+	parts := matcher.FindStringSubmatch(p)
+	return parts[1], strings.Trim(parts[2], "/")
+}
+
+// itemPath returns the escaped path of file in the item relative to
+// the endpoint roots
+func (f *Fs) itemPath(file string) string {
+	return rest.URLPathEscape(path.Join(f.bucket, f.root, file))
+}
+
+// headerValue encodes v for use in an x-archive-meta header, which
+// can only contain plain ASCII unless wrapped in uri()
+func headerValue(v string) string {
+	for _, c := range v {
+		if c < ' ' || c > '~' {
+			return "uri(" + rest.URLPathEscape(v) + ")"
+		}
+	}
+	return v
+}
+
+// parseItemMetadata parses the comma separated key=value pairs in s
+// into the headers needed to set them on a new item
+func parseItemMetadata(s string) (map[string]string, error) {
+	values := map[string][]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		equals := strings.IndexRune(pair, '=')
+		if equals <= 0 {
+			return nil, errors.Errorf("item_metadata: expecting key=value but got %q", pair)
+		}
+		key := strings.ToLower(strings.TrimSpace(pair[:equals]))
+		values[key] = append(values[key], strings.TrimSpace(pair[equals+1:]))
+	}
+	headers := map[string]string{}
+	for key, keyValues := range values {
+		if len(keyValues) == 1 {
+			headers["x-archive-meta-"+key] = headerValue(keyValues[0])
+			continue
+		}
+		// Number the headers to give a key several values
+		for i, value := range keyValues {
+			headers[fmt.Sprintf("x-archive-meta%02d-%s", i+1, key)] = headerValue(value)
+		}
+	}
+	return headers, nil
+}
+
+// NewFs constructs an Fs from the path, item:path
+func NewFs(name, root string) (fs.Fs, error) {
+	bucket, directory := parsePath(root)
+	itemHeaders, err := parseItemMetadata(config.FileGet(name, "item_metadata"))
+	if err != nil {
+		return nil, err
+	}
+	endpoint := strings.TrimRight(config.FileGet(name, "endpoint", defaultEndpoint), "/")
+	frontEndpoint := strings.TrimRight(config.FileGet(name, "front_endpoint", defaultFrontEndpoint), "/")
+	f := &Fs{
+		name:               name,
+		root:               directory,
+		srv:                rest.NewClient(fshttp.NewClient(fs.ConfigForRemote(name))).SetRoot(endpoint + "/"),
+		front:              rest.NewClient(fshttp.NewClient(fs.ConfigForRemote(name))).SetRoot(frontEndpoint + "/"),
+		pacer:              pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant),
+		bucket:             bucket,
+		itemHeaders:        itemHeaders,
+		includeDerivatives: config.FileGetBool(name, "include_derivatives", false),
+		queueDerive:        config.FileGetBool(name, "queue_derive", true),
+	}
+	f.features = (&fs.Features{
+		BucketBased: true,
+	}).Fill(f)
+	for _, client := range []*rest.Client{f.srv, f.front} {
+		client.SetErrorHandler(errorHandler)
+		accessKey, secretKey := config.FileGet(name, "access_key_id"), config.FileGet(name, "secret_access_key")
+		if accessKey != "" || secretKey != "" {
+			client.SetHeader("Authorization", "LOW "+accessKey+":"+secretKey)
+		}
+	}
+	if f.bucket != "" && f.root != "" {
+		// Check to see if the root is actually an existing file
+		_, err := f.NewObject("")
+		if err == nil {
+			f.root = path.Dir(f.root)
+			if f.root == "." {
+				f.root = ""
+			}
+			// return an error with an fs which points to the parent
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// readItem reads the metadata and file list of the item, returning
+// fs.ErrorDirNotFound if it doesn't exist
+func (f *Fs) readItem() (*api.MetadataResponse, error) {
+	opts := rest.Opts{
+		Method: "GET",
+		Path:   "metadata/" + rest.URLPathEscape(f.bucket),
+	}
+	var result api.MetadataResponse
+	var resp *http.Response
+	var err error
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.front.CallJSON(&opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't read item")
+	}
+	// The metadata API returns {} for items which don't exist
+	if result.Created == 0 && len(result.Files) == 0 {
+		return nil, fs.ErrorDirNotFound
+	}
+	return &result, nil
+}
+
+// listable returns whether file should be shown in listings
+func (f *Fs) listable(file *api.File) bool {
+	switch file.Source {
+	case api.SourceOriginal:
+		return true
+	case api.SourceDerivative:
+		return f.includeDerivatives
+	}
+	return false
+}
+
+// Return an Object from a path
+//
+// If it can't be found it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) newObjectWithInfo(remote string, file *api.File) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	if file == nil {
+		var err error
+		file, err = f.findFile(remote)
+		if err != nil {
+			return nil, err
+		}
+	}
+	o.setMetaData(file)
+	return o, nil
+}
+
+// findFile finds remote in the item
+func (f *Fs) findFile(remote string) (*api.File, error) {
+	if f.bucket == "" {
+		return nil, fs.ErrorObjectNotFound
+	}
+	item, err := f.readItem()
+	if err == fs.ErrorDirNotFound {
+		return nil, fs.ErrorObjectNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	name := path.Join(f.root, remote)
+	for i := range item.Files {
+		file := &item.Files[i]
+		if file.Name == name && f.listable(file) {
+			return file, nil
+		}
+	}
+	return nil, fs.ErrorObjectNotFound
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(remote string) (fs.Object, error) {
+	return f.newObjectWithInfo(remote, nil)
+}
+
+// listFn is called from list to handle an object.
+type listFn func(remote string, file *api.File, isDirectory bool) error
+
+// list the files in the item under dir calling fn for each of them
+// and, unless recurse is set, the directories directly in dir
+func (f *Fs) list(dir string, recurse bool, fn listFn) error {
+	item, err := f.readItem()
+	if err != nil {
+		return err
+	}
+	f.markBucketOK()
+	prefix := path.Join(f.root, dir)
+	if prefix != "" {
+		prefix += "/"
+	}
+	seenDirs := map[string]bool{}
+	for i := range item.Files {
+		file := &item.Files[i]
+		if !f.listable(file) || !strings.HasPrefix(file.Name, prefix) {
+			continue
+		}
+		remote := path.Join(dir, file.Name[len(prefix):])
+		if !recurse {
+			slash := strings.IndexRune(file.Name[len(prefix):], '/')
+			if slash >= 0 {
+				remote = path.Join(dir, file.Name[len(prefix):len(prefix)+slash])
+				if !seenDirs[remote] {
+					seenDirs[remote] = true
+					err = fn(remote, nil, true)
+					if err != nil {
+						return err
+					}
+				}
+				continue
+			}
+		}
+		err = fn(remote, file, false)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// itemToDirEntry converts a listed item into a fs.DirEntry
+func (f *Fs) itemToDirEntry(remote string, file *api.File, isDirectory bool) (fs.DirEntry, error) {
+	if isDirectory {
+		return fs.NewDir(remote, time.Time{}), nil
+	}
+	return f.newObjectWithInfo(remote, file)
+}
+
+// markBucketOK marks the item as existing
+func (f *Fs) markBucketOK() {
+	f.bucketOKMu.Lock()
+	f.bucketOK = true
+	f.bucketOKMu.Unlock()
+}
+
+// listBuckets lists the items belonging to the user
+func (f *Fs) listBuckets(dir string) (entries fs.DirEntries, err error) {
+	if dir != "" {
+		return nil, fs.ErrorListBucketRequired
+	}
+	opts := rest.Opts{
+		Method: "GET",
+		Path:   "",
+	}
+	var result api.ListAllMyBucketsResult
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallXML(&opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't list items")
+	}
+	for _, bucket := range result.Buckets {
+		entries = append(entries, fs.NewDir(bucket.Name, bucket.CreationDate))
+	}
+	return entries, nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+	if f.bucket == "" {
+		return f.listBuckets(dir)
+	}
+	err = f.list(dir, false, func(remote string, file *api.File, isDirectory bool) error {
+		entry, err := f.itemToDirEntry(remote, file, isDirectory)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+//
+// The metadata API returns all the files in an item at once so this
+// is much quicker than listing each directory.
+func (f *Fs) ListR(dir string, callback fs.ListRCallback) (err error) {
+	if f.bucket == "" {
+		return fs.ErrorListBucketRequired
+	}
+	list := walk.NewListRHelper(callback)
+	err = f.list(dir, true, func(remote string, file *api.File, isDirectory bool) error {
+		entry, err := f.itemToDirEntry(remote, file, isDirectory)
+		if err != nil {
+			return err
+		}
+		return list.Add(entry)
+	})
+	if err != nil {
+		return err
+	}
+	return list.Flush()
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(in, src, options...)
+}
+
+// Mkdir creates the item if it doesn't exist
+//
+// Directories inside items don't exist on their own so there is
+// nothing to do for them.
+func (f *Fs) Mkdir(dir string) error {
+	f.bucketOKMu.Lock()
+	defer f.bucketOKMu.Unlock()
+	if f.bucketOK {
+		return nil
+	}
+	_, err := f.readItem()
+	if err == nil {
+		f.bucketOK = true
+		return nil
+	} else if err != fs.ErrorDirNotFound {
+		return err
+	}
+	opts := rest.Opts{
+		Method:       "PUT",
+		Path:         rest.URLPathEscape(f.bucket),
+		ExtraHeaders: f.uploadHeaders(),
+		NoResponse:   true,
+	}
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return errors.Wrap(err, "couldn't create item")
+	}
+	f.bucketOK = true
+	return nil
+}
+
+// Rmdir checks the item is empty if the fs is at the root
+//
+// Items can't be deleted from the Internet Archive so an empty
+// item is left behind.
+func (f *Fs) Rmdir(dir string) error {
+	if f.root != "" || dir != "" {
+		return nil
+	}
+	item, err := f.readItem()
+	if err != nil {
+		return err
+	}
+	for i := range item.Files {
+		if item.Files[i].Source == api.SourceOriginal {
+			return fs.ErrorDirectoryNotEmpty
+		}
+	}
+	fs.Debugf(f, "Leaving empty item as items can't be removed")
+	return nil
+}
+
+// Precision return the precision of this Fs
+func (f *Fs) Precision() time.Duration {
+	return time.Nanosecond
+}
+
+// uploadHeaders returns the headers needed for a PUT which creates
+// an item or a file in it
+func (f *Fs) uploadHeaders() map[string]string {
+	headers := map[string]string{
+		"x-amz-auto-make-bucket": "1",
+	}
+	if !f.queueDerive {
+		headers["x-archive-queue-derive"] = "0"
+	}
+	// These are ignored if the item exists already
+	for k, v := range f.itemHeaders {
+		headers[k] = v
+	}
+	return headers
+}
+
+// Copy src to this remote using server side copy operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't copy - not same remote type")
+		return nil, fs.ErrorCantCopy
+	}
+	headers := f.uploadHeaders()
+	headers["x-amz-copy-source"] = "/" + srcObj.fs.itemPath(srcObj.remote)
+	headers["x-amz-metadata-directive"] = "COPY"
+	opts := rest.Opts{
+		Method:       "PUT",
+		Path:         f.itemPath(remote),
+		ExtraHeaders: headers,
+		NoResponse:   true,
+	}
+	var resp *http.Response
+	var err error
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "copy failed")
+	}
+	// The item's file list is updated asynchronously so make the
+	// new object from the source rather than reading it
+	dstObj := *srcObj
+	dstObj.fs = f
+	dstObj.remote = remote
+	return &dstObj, nil
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.MD5 | hash.SHA1)
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the hash of an object returning a lowercase hex string
+func (o *Object) Hash(t hash.Type) (string, error) {
+	switch t {
+	case hash.MD5:
+		return o.md5, nil
+	case hash.SHA1:
+		return o.sha1, nil
+	}
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// setMetaData sets the metadata from file
+func (o *Object) setMetaData(file *api.File) {
+	o.size = file.Length()
+	o.modTime = file.ModTime()
+	o.md5 = file.MD5
+	o.sha1 = file.SHA1
+}
+
+// ModTime returns the modification time of the object
+//
+// This is the time rclone stored on upload if present, otherwise the
+// time the archive has for the file.
+func (o *Object) ModTime() time.Time {
+	return o.modTime
+}
+
+// SetModTime sets the modification time of the object
+//
+// The metadata of a file can only be set by uploading it again.
+func (o *Object) SetModTime(modTime time.Time) error {
+	return fs.ErrorCantSetModTimeWithoutDelete
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	opts := rest.Opts{
+		Method:  "GET",
+		Path:    "download/" + o.fs.itemPath(o.remote),
+		Options: options,
+	}
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.front.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, err
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// If existing is set then it updates the object rather than creating a new one
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	modTime := src.ModTime()
+	size := src.Size()
+	headers := o.fs.uploadHeaders()
+	headers["x-archive-filemeta-"+mtimeKey] = modTime.Format(time.RFC3339Nano)
+	if size >= 0 {
+		// Lets the archive pick a server with room for the item
+		headers["x-archive-size-hint"] = fmt.Sprint(size)
+	}
+	if md5, _ := src.Hash(hash.MD5); md5 != "" {
+		if sum, err := hex.DecodeString(md5); err == nil {
+			headers["Content-MD5"] = base64.StdEncoding.EncodeToString(sum)
+		}
+	}
+	// Hash the data as we send it as the file list is updated
+	// asynchronously so can't be read back straight away
+	hasher := hash.NewMultiHasher()
+	opts := rest.Opts{
+		Method:       "PUT",
+		Path:         o.fs.itemPath(o.remote),
+		Body:         io.TeeReader(in, hasher),
+		NoResponse:   true,
+		ContentType:  fs.MimeType(src),
+		ExtraHeaders: headers,
+		Options:      options,
+	}
+	if size >= 0 {
+		opts.ContentLength = &size
+	}
+	var resp *http.Response
+	err = o.fs.pacer.CallNoRetry(func() (bool, error) {
+		resp, err = o.fs.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return errors.Wrap(err, "Update failed")
+	}
+	o.fs.markBucketOK()
+	sums := hasher.Sums()
+	o.size = hasher.Size()
+	o.modTime = modTime
+	o.md5 = sums[hash.MD5]
+	o.sha1 = sums[hash.SHA1]
+	return nil
+}
+
+// Remove an object
+//
+// This removes the files the archive derived from it too.
+func (o *Object) Remove() error {
+	opts := rest.Opts{
+		Method: "DELETE",
+		Path:   o.fs.itemPath(o.remote),
+		ExtraHeaders: map[string]string{
+			"x-archive-cascade-delete": "1",
+		},
+		NoResponse: true,
+	}
+	var resp *http.Response
+	var err error
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return fs.ErrorObjectNotFound
+		}
+		return errors.Wrap(err, "remove failed")
+	}
+	return nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = (*Fs)(nil)
+	_ fs.Copier      = (*Fs)(nil)
+	_ fs.PutStreamer = (*Fs)(nil)
+	_ fs.ListRer     = (*Fs)(nil)
+	_ fs.Object      = (*Object)(nil)
+)
@@ -0,0 +1,309 @@
+package internetarchive
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/backend/internetarchive/api"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFile is a file in a fakeItem
+type fakeFile struct {
+	data   []byte
+	source string
+	mtime  string // rclone-mtime
+}
+
+// fakeItem is an item in the fakeArchive
+type fakeItem struct {
+	created time.Time
+	meta    http.Header // x-archive-meta headers it was created with
+	files   map[string]*fakeFile
+}
+
+// fakeArchive is an in memory Internet Archive serving the IAS3,
+// metadata and download endpoints
+type fakeArchive struct {
+	mu     sync.Mutex
+	items  map[string]*fakeItem
+	auth   string // the Authorization header of the last request
+	derive string // the x-archive-queue-derive header of the last upload
+	server *httptest.Server
+}
+
+func newFakeArchive() *fakeArchive {
+	a := &fakeArchive{
+		items: map[string]*fakeItem{},
+	}
+	a.server = httptest.NewServer(a)
+	return a
+}
+
+func (a *fakeArchive) fail(w http.ResponseWriter, code int, errCode string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(code)
+	_ = xml.NewEncoder(w).Encode(api.Error{Code: errCode, Message: errCode})
+}
+
+// makeItem makes the item if it doesn't exist and the request asks
+// for it
+func (a *fakeArchive) makeItem(r *http.Request, name string) *fakeItem {
+	item := a.items[name]
+	if item == nil && r.Header.Get("x-amz-auto-make-bucket") == "1" {
+		item = &fakeItem{
+			created: time.Now(),
+			meta:    http.Header{},
+			files: map[string]*fakeFile{
+				name + "_meta.xml": {source: api.SourceMetadata},
+			},
+		}
+		for k, v := range r.Header {
+			if strings.HasPrefix(strings.ToLower(k), "x-archive-meta") {
+				item.meta[k] = v
+			}
+		}
+		a.items[name] = item
+	}
+	return item
+}
+
+func (a *fakeArchive) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.auth = r.Header.Get("Authorization")
+	p := strings.TrimPrefix(r.URL.Path, "/")
+	switch {
+	case r.Method == "GET" && strings.HasPrefix(p, "metadata/"):
+		item := a.items[strings.TrimPrefix(p, "metadata/")]
+		result := api.MetadataResponse{}
+		if item != nil {
+			result.Created = item.created.Unix()
+			for name, file := range item.files {
+				sumMD5, sumSHA1 := md5.Sum(file.data), sha1.Sum(file.data)
+				result.Files = append(result.Files, api.File{
+					Name:        name,
+					Source:      file.source,
+					Size:        strconv.Itoa(len(file.data)),
+					Mtime:       strconv.FormatInt(item.created.Unix(), 10),
+					MD5:         hex.EncodeToString(sumMD5[:]),
+					SHA1:        hex.EncodeToString(sumSHA1[:]),
+					RcloneMtime: file.mtime,
+				})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(result)
+		return
+	case r.Method == "GET" && strings.HasPrefix(p, "download/"):
+		p = strings.TrimPrefix(p, "download/")
+		slash := strings.IndexRune(p, '/')
+		if slash < 0 || a.items[p[:slash]] == nil || a.items[p[:slash]].files[p[slash+1:]] == nil {
+			http.NotFound(w, r)
+			return
+		}
+		file := a.items[p[:slash]].files[p[slash+1:]]
+		http.ServeContent(w, r, p, time.Time{}, bytes.NewReader(file.data))
+		return
+	case r.Method == "GET" && p == "":
+		var result api.ListAllMyBucketsResult
+		for name, item := range a.items {
+			result.Buckets = append(result.Buckets, api.Bucket{Name: name, CreationDate: item.created})
+		}
+		_ = xml.NewEncoder(w).Encode(result)
+		return
+	}
+	slash := strings.IndexRune(p, '/')
+	if slash < 0 {
+		if r.Method != "PUT" {
+			a.fail(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+			return
+		}
+		a.makeItem(r, p)
+		return
+	}
+	itemName, name := p[:slash], p[slash+1:]
+	switch r.Method {
+	case "PUT":
+		item := a.makeItem(r, itemName)
+		if item == nil {
+			a.fail(w, http.StatusNotFound, "NoSuchBucket")
+			return
+		}
+		file := &fakeFile{source: api.SourceOriginal, mtime: r.Header.Get("x-archive-filemeta-rclone-mtime")}
+		if source := r.Header.Get("x-amz-copy-source"); source != "" {
+			u, _ := url.Parse(source)
+			source = strings.TrimPrefix(u.Path, "/")
+			slash := strings.IndexRune(source, '/')
+			srcItem := a.items[source[:slash]]
+			if srcItem == nil || srcItem.files[source[slash+1:]] == nil {
+				a.fail(w, http.StatusNotFound, "NoSuchKey")
+				return
+			}
+			*file = *srcItem.files[source[slash+1:]]
+		} else {
+			var err error
+			file.data, err = ioutil.ReadAll(r.Body)
+			if err != nil {
+				a.fail(w, http.StatusBadRequest, "IncompleteBody")
+				return
+			}
+			if contentMD5 := r.Header.Get("Content-MD5"); contentMD5 != "" {
+				sum := md5.Sum(file.data)
+				if contentMD5 != base64.StdEncoding.EncodeToString(sum[:]) {
+					a.fail(w, http.StatusBadRequest, "BadDigest")
+					return
+				}
+			}
+		}
+		item.files[name] = file
+		a.derive = r.Header.Get("x-archive-queue-derive")
+		if a.derive != "0" && strings.HasSuffix(name, ".png") {
+			item.files[strings.TrimSuffix(name, ".png")+"_thumb.jpg"] = &fakeFile{source: api.SourceDerivative, data: []byte("thumb")}
+		}
+	case "DELETE":
+		item := a.items[itemName]
+		if item == nil || item.files[name] == nil {
+			a.fail(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		delete(item.files, name)
+		if r.Header.Get("x-archive-cascade-delete") == "1" && strings.HasSuffix(name, ".png") {
+			delete(item.files, strings.TrimSuffix(name, ".png")+"_thumb.jpg")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		a.fail(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func newTestFs(t *testing.T, a *fakeArchive, root string) *Fs {
+	config.LoadConfig()
+	config.FileSet("TestInternetArchiveInternal", "type", "internetarchive")
+	config.FileSet("TestInternetArchiveInternal", "access_key_id", "key")
+	config.FileSet("TestInternetArchiveInternal", "secret_access_key", "secret")
+	config.FileSet("TestInternetArchiveInternal", "endpoint", a.server.URL)
+	config.FileSet("TestInternetArchiveInternal", "front_endpoint", a.server.URL)
+	config.FileSet("TestInternetArchiveInternal", "item_metadata", "mediatype=data, subject=one,subject=two,title=Café")
+	f, err := NewFs("TestInternetArchiveInternal", root)
+	require.NoError(t, err)
+	return f.(*Fs)
+}
+
+func TestParseItemMetadata(t *testing.T) {
+	headers, err := parseItemMetadata("mediatype=data, Subject=one,subject=two,title=Café,empty=")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"x-archive-meta-mediatype": "data",
+		"x-archive-meta01-subject": "one",
+		"x-archive-meta02-subject": "two",
+		"x-archive-meta-title":     "uri(Caf%C3%A9)",
+		"x-archive-meta-empty":     "",
+	}, headers)
+
+	headers, err = parseItemMetadata("")
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(headers))
+
+	_, err = parseItemMetadata("mediatype")
+	assert.Error(t, err)
+	_, err = parseItemMetadata("=data")
+	assert.Error(t, err)
+}
+
+func TestPutOpenList(t *testing.T) {
+	a := newFakeArchive()
+	defer a.server.Close()
+	f := newTestFs(t, a, "item/root")
+
+	_, err := f.List("")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+
+	modTime := time.Date(2018, 6, 10, 12, 0, 0, 123456789, time.UTC)
+	o := fstest.PutContents(t, f, "dir/file.txt", "hello world", modTime)
+	assert.Equal(t, int64(11), o.Size())
+	assert.True(t, modTime.Equal(o.ModTime()))
+	md5sum, err := o.Hash(hash.MD5)
+	require.NoError(t, err)
+	assert.Equal(t, "5eb63bbbe01eeed093cb22bb8f5acdc3", md5sum)
+	assert.Equal(t, "LOW key:secret", a.auth)
+	assert.Equal(t, []byte("hello world"), a.items["item"].files["root/dir/file.txt"].data)
+	assert.Equal(t, "data", a.items["item"].meta.Get("x-archive-meta-mediatype"))
+	assert.Equal(t, "two", a.items["item"].meta.Get("x-archive-meta02-subject"))
+	assert.Equal(t, "uri(Caf%C3%A9)", a.items["item"].meta.Get("x-archive-meta-title"))
+
+	// Derivatives and the item's own files aren't listed by default
+	fstest.PutContents(t, f, "image.png", "png", modTime)
+	assert.Equal(t, []string{"dir/", "image.png"}, fstest.ListNames(t, f, ""))
+	assert.Equal(t, []string{"dir/file.txt"}, fstest.ListNames(t, f, "dir"))
+	config.FileSet("TestInternetArchiveInternal", "include_derivatives", "true")
+	fd, err := NewFs("TestInternetArchiveInternal", "item")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"root/"}, fstest.ListNames(t, fd, ""))
+	assert.Equal(t, []string{"root/dir/", "root/image.png", "root/image_thumb.jpg"}, fstest.ListNames(t, fd, "root"))
+
+	// Read back with a new Fs
+	o, err = f.NewObject("dir/file.txt")
+	require.NoError(t, err)
+	assert.True(t, modTime.Equal(o.ModTime()))
+	assert.Equal(t, "wor", fstest.ReadObject(t, o, &fs.RangeOption{Start: 6, End: 8}))
+
+	// A file root gives fs.ErrorIsFile
+	_, err = NewFs("TestInternetArchiveInternal", "item/root/dir/file.txt")
+	assert.Equal(t, fs.ErrorIsFile, err)
+
+	// The root lists the items
+	fr, err := NewFs("TestInternetArchiveInternal", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"item/"}, fstest.ListNames(t, fr, ""))
+}
+
+func TestCopyAndRemove(t *testing.T) {
+	a := newFakeArchive()
+	defer a.server.Close()
+	f := newTestFs(t, a, "item")
+	config.FileSet("TestInternetArchiveInternal", "queue_derive", "false")
+	fNoDerive, err := NewFs("TestInternetArchiveInternal", "other")
+	require.NoError(t, err)
+
+	o := fstest.PutContents(t, f, "a/one.png", "one", time.Now())
+	assert.Equal(t, "", a.derive)
+	assert.NotNil(t, a.items["item"].files["a/one_thumb.jpg"])
+
+	dst, err := operations.Copy(fNoDerive, nil, "b/two.png", o)
+	require.NoError(t, err)
+	assert.Equal(t, "0", a.derive)
+	assert.Equal(t, []byte("one"), a.items["other"].files["b/two.png"].data)
+	assert.Nil(t, a.items["other"].files["b/one_thumb.jpg"])
+	assert.Equal(t, "b/two.png", dst.Remote())
+	assert.Equal(t, int64(3), dst.Size())
+
+	assert.Equal(t, fs.ErrorDirectoryNotEmpty, f.Rmdir(""))
+	require.NoError(t, o.Remove())
+	assert.Nil(t, a.items["item"].files["a/one.png"])
+	assert.Nil(t, a.items["item"].files["a/one_thumb.jpg"])
+	assert.Equal(t, fs.ErrorObjectNotFound, o.Remove())
+
+	// Items can't be removed but Rmdir of an empty one succeeds
+	require.NoError(t, f.Rmdir(""))
+	assert.NotNil(t, a.items["item"])
+}
@@ -0,0 +1,21 @@
+// Creation time reading functions
+
+// +build darwin freebsd netbsd
+
+package local
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// readBirthTime returns the creation time of a valid os.FileInfo,
+// returning the zero time if it fails.
+func readBirthTime(fi os.FileInfo) time.Time {
+	statT, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(statT.Birthtimespec.Sec), int64(statT.Birthtimespec.Nsec))
+}
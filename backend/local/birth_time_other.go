@@ -0,0 +1,18 @@
+// Creation time reading functions
+
+// +build !darwin,!freebsd,!netbsd,!windows
+
+package local
+
+import (
+	"os"
+	"time"
+)
+
+// readBirthTime returns the creation time of a valid os.FileInfo,
+// returning the zero time if it fails.
+//
+// The stat call on this OS doesn't report the creation time.
+func readBirthTime(fi os.FileInfo) time.Time {
+	return time.Time{}
+}
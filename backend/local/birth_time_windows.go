@@ -0,0 +1,21 @@
+// Creation time reading functions
+
+// +build windows
+
+package local
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// readBirthTime returns the creation time of a valid os.FileInfo,
+// returning the zero time if it fails.
+func readBirthTime(fi os.FileInfo) time.Time {
+	attr, ok := fi.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(0, attr.CreationTime.Nanoseconds())
+}
@@ -8,7 +8,6 @@ import (
 	"os"
 	"path"
 	"path/filepath"
-	"regexp"
 	"runtime"
 	"strings"
 	"sync"
@@ -19,6 +18,7 @@ import (
 	"github.com/ncw/rclone/fs/config"
 	"github.com/ncw/rclone/fs/config/flags"
 	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/lib/file"
 	"github.com/ncw/rclone/lib/readers"
 	"github.com/pkg/errors"
 	"google.golang.org/appengine/log"
@@ -28,6 +28,10 @@ var (
 	followSymlinks = flags.BoolP("copy-links", "L", false, "Follow symlinks and copy the pointed to item.")
 	skipSymlinks   = flags.BoolP("skip-links", "", false, "Don't warn about skipped symlinks.")
 	noUTFNorm      = flags.BoolP("local-no-unicode-normalization", "", false, "Don't apply unicode normalization to paths and filenames")
+	noPreAllocate  = flags.BoolP("local-no-preallocate", "", false, "Disable preallocation of disk space for transferred files")
+	noSparse       = flags.BoolP("local-no-sparse", "", false, "Disable sparse files for multi-thread downloads")
+	atomicWrites   = flags.BoolP("local-atomic-writes", "", false, "Write files to a temporary name and rename them into place when complete")
+	fsyncWrites    = flags.BoolP("local-fsync", "", false, "Flush files to stable storage before closing them")
 )
 
 // Constants
@@ -47,6 +51,12 @@ func init() {
 				Value: "true",
 				Help:  "Disables long file names",
 			}},
+		}, {
+			Name:     "vss",
+			Help:     "Read from a Volume Shadow Copy snapshot on Windows. Only use on remotes used as a source.",
+			Optional: true,
+			Hide:     true,
+			Type:     fs.OptionTypeBool,
 		}},
 	}
 	fs.Register(fsi)
@@ -73,10 +83,11 @@ type Fs struct {
 type Object struct {
 	fs      *Fs    // The Fs this object is part of
 	remote  string // The remote path - properly UTF-8 encoded - for rclone
-	path    string // The local path - may not be properly UTF-8 encoded - for OS
+	path    string // The local path if it isn't the one made from remote - may not be properly UTF-8 encoded - for OS
 	size    int64  // file metadata - always present
 	mode    os.FileMode
 	modTime time.Time
+	btime   time.Time            // creation time if the OS reports it
 	hashes  map[hash.Type]string // Hashes
 }
 
@@ -100,6 +111,10 @@ func NewFs(name, root string) (fs.Fs, error) {
 		dirNames: newMapper(),
 	}
 	f.root = f.cleanPath(root)
+	f.root, err = vssRoot(f.root, config.FileGetBool(name, "vss"))
+	if err != nil {
+		return nil, err
+	}
 	f.features = (&fs.Features{
 		CaseInsensitive:         f.caseInsensitive(),
 		CanHaveEmptyDirectories: true,
@@ -155,18 +170,33 @@ func (f *Fs) caseInsensitive() bool {
 // newObject makes a half completed Object
 //
 // if dstPath is empty then it is made from remote
+//
+// The local path is only stored in the Object if it can't be made
+// from the remote, which is rare, as in large listings keeping both
+// would double the memory the names use.
 func (f *Fs) newObject(remote, dstPath string) *Object {
-	if dstPath == "" {
+	cleanRemote := f.cleanRemote(remote)
+	if dstPath == "" && cleanRemote != remote {
 		dstPath = f.cleanPath(filepath.Join(f.root, remote))
 	}
-	remote = f.cleanRemote(remote)
+	if dstPath != "" && dstPath == f.cleanPath(filepath.Join(f.root, cleanRemote)) {
+		dstPath = ""
+	}
 	return &Object{
 		fs:     f,
-		remote: remote,
+		remote: cleanRemote,
 		path:   dstPath,
 	}
 }
 
+// localPath returns the OS path of the object
+func (o *Object) localPath() string {
+	if o.path != "" {
+		return o.path
+	}
+	return o.fs.cleanPath(filepath.Join(o.fs.root, o.remote))
+}
+
 // Return an Object from a path
 //
 // May return nil if an error occurred
@@ -208,17 +238,36 @@ func (f *Fs) NewObject(remote string) (fs.Object, error) {
 // This should return ErrDirNotFound if the directory isn't
 // found.
 func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+	err = f.ListPages(dir, func(page fs.DirEntries) error {
+		entries = append(entries, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ListPages lists the objects and directories in dir calling callback
+// with each page of entries as it is read from the disk.
+//
+// This should return ErrDirNotFound if the directory isn't found.
+func (f *Fs) ListPages(dir string, callback fs.ListRCallback) (err error) {
 	dir = f.dirNames.Load(dir)
 	fsDirPath := f.cleanPath(filepath.Join(f.root, dir))
 	remote := f.cleanRemote(dir)
+	// On Unix the paths of the entries can be made from their
+	// remotes unless the names needed cleaning, so don't make
+	// newObject check
+	derivable := runtime.GOOS != "windows" && remote == dir
 	_, err = os.Stat(fsDirPath)
 	if err != nil {
-		return nil, fs.ErrorDirNotFound
+		return fs.ErrorDirNotFound
 	}
 
 	fd, err := os.Open(fsDirPath)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to open directory %q", dir)
+		return errors.Wrapf(err, "failed to open directory %q", dir)
 	}
 	defer func() {
 		cerr := fd.Close()
@@ -233,19 +282,20 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 			break
 		}
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to read directory %q", dir)
+			return errors.Wrapf(err, "failed to read directory %q", dir)
 		}
+		var entries fs.DirEntries
 
 		for _, fi := range fis {
 			name := fi.Name()
 			mode := fi.Mode()
-			newRemote := path.Join(remote, name)
 			newPath := filepath.Join(fsDirPath, name)
+			newRemote := path.Join(remote, name)
 			// Follow symlinks if required
 			if *followSymlinks && (mode&os.ModeSymlink) != 0 {
 				fi, err = os.Stat(newPath)
 				if err != nil {
-					return nil, err
+					return err
 				}
 				mode = fi.Mode()
 			}
@@ -257,17 +307,27 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 					entries = append(entries, d)
 				}
 			} else {
-				fso, err := f.newObjectWithInfo(newRemote, newPath, fi)
+				dstPath := newPath
+				if derivable && utf8.ValidString(name) {
+					dstPath = ""
+				}
+				fso, err := f.newObjectWithInfo(newRemote, dstPath, fi)
 				if err != nil {
-					return nil, err
+					return err
 				}
 				if fso.Storable() {
 					entries = append(entries, fso)
 				}
 			}
 		}
+		if len(entries) > 0 {
+			err = callback(entries)
+			if err != nil {
+				return err
+			}
+		}
 	}
-	return entries, nil
+	return nil
 }
 
 // cleanRemote makes string a valid UTF-8 string for remote strings.
@@ -343,11 +403,81 @@ func (f *Fs) PutStream(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption
 	return f.Put(in, src, options...)
 }
 
+// localWriterAt is the file returned by OpenWriterAt
+type localWriterAt struct {
+	*os.File
+	writePath string // the path being written to
+	path      string // the path to rename it to when closed if different
+}
+
+// Close the file, renaming it into place if required
+func (w *localWriterAt) Close() (err error) {
+	if *fsyncWrites {
+		err = w.File.Sync()
+	}
+	closeErr := w.File.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil && w.writePath != w.path {
+		err = os.Rename(w.writePath, w.path)
+	}
+	if err != nil && w.writePath != w.path {
+		_ = os.Remove(w.writePath)
+	}
+	return err
+}
+
+// OpenWriterAt opens with a handle for random access writes
+//
+// Pass in the remote desired and the size if known.
+//
+// It truncates any existing object
+func (f *Fs) OpenWriterAt(remote string, size int64) (fs.WriterAtCloser, error) {
+	// Temporary Object under construction
+	o := f.newObject(remote, "")
+
+	err := o.mkdirAll()
+	if err != nil {
+		return nil, err
+	}
+
+	localPath := o.localPath()
+	writePath := localPath
+	if *atomicWrites {
+		writePath = fmt.Sprintf("%s.rclone-partial-%d", localPath, os.Getpid())
+	}
+	out, err := os.OpenFile(writePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
+	if !*noPreAllocate {
+		// Pre-allocate the file for performance reasons
+		err = file.PreAllocate(size, out)
+		if err != nil {
+			fs.Debugf(o, "Failed to pre-allocate: %v", err)
+		}
+	}
+	if !*noSparse {
+		// Set the file to be a sparse file so the parts can
+		// be written in any order without filling the gaps
+		err = file.SetSparse(out)
+		if err != nil {
+			fs.Debugf(o, "Failed to set sparse: %v", err)
+		}
+	}
+	return &localWriterAt{
+		File:      out,
+		writePath: writePath,
+		path:      localPath,
+	}, nil
+}
+
 // Mkdir creates the directory if it doesn't exist
 func (f *Fs) Mkdir(dir string) error {
 	// FIXME: https://github.com/syncthing/syncthing/blob/master/lib/osutil/mkdirall_windows.go
 	root := f.cleanPath(filepath.Join(f.root, dir))
-	err := os.MkdirAll(root, 0777)
+	err := file.MkdirAll(root, 0777)
 	if err != nil {
 		return err
 	}
@@ -482,7 +612,7 @@ func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
 	}
 
 	// Do the move
-	err = os.Rename(srcObj.path, dstObj.path)
+	err = os.Rename(srcObj.localPath(), dstObj.localPath())
 	if os.IsNotExist(err) {
 		// race condition, source was deleted in the meantime
 		return nil, err
@@ -530,7 +660,7 @@ func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
 
 	// Create parent of destination
 	dstParentPath, _ := getDirFile(dstPath)
-	err = os.MkdirAll(dstParentPath, 0777)
+	err = file.MkdirAll(dstParentPath, 0777)
 	if err != nil {
 		return err
 	}
@@ -593,7 +723,7 @@ func (o *Object) Hash(r hash.Type) (string, error) {
 
 	if !o.modTime.Equal(oldtime) || oldsize != o.size || hashes == nil {
 		hashes = make(map[hash.Type]string)
-		in, err := os.Open(o.path)
+		in, err := os.Open(o.localPath())
 		if err != nil {
 			return "", errors.Wrap(err, "hash: failed to open")
 		}
@@ -622,9 +752,15 @@ func (o *Object) ModTime() time.Time {
 	return o.modTime
 }
 
+// CreatedTime returns the creation time of the object if the OS
+// reports it, or the zero time if not
+func (o *Object) CreatedTime() time.Time {
+	return o.btime
+}
+
 // SetModTime sets the modification time of the local fs object
 func (o *Object) SetModTime(modTime time.Time) error {
-	err := os.Chtimes(o.path, modTime, modTime)
+	err := os.Chtimes(o.localPath(), modTime, modTime)
 	if err != nil {
 		return err
 	}
@@ -722,7 +858,7 @@ func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
 		}
 	}
 
-	fd, err := os.Open(o.path)
+	fd, err := os.Open(o.localPath())
 	if err != nil {
 		return
 	}
@@ -749,8 +885,8 @@ func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
 
 // mkdirAll makes all the directories needed to store the object
 func (o *Object) mkdirAll() error {
-	dir, _ := getDirFile(o.path)
-	return os.MkdirAll(dir, 0777)
+	dir, _ := getDirFile(o.localPath())
+	return file.MkdirAll(dir, 0777)
 }
 
 // Update the object from in with modTime and size
@@ -768,10 +904,25 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 		return err
 	}
 
-	out, err := os.OpenFile(o.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	// Write to a temporary file first if required so the
+	// destination never contains a partial file
+	localPath := o.localPath()
+	writePath := localPath
+	if *atomicWrites {
+		writePath = fmt.Sprintf("%s.rclone-partial-%d", localPath, os.Getpid())
+	}
+
+	out, err := os.OpenFile(writePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
 	if err != nil {
 		return err
 	}
+	if !*noPreAllocate {
+		// Pre-allocate the file for performance reasons
+		err = file.PreAllocate(src.Size(), out)
+		if err != nil {
+			fs.Debugf(o, "Failed to pre-allocate: %v", err)
+		}
+	}
 
 	// Calculate the hash of the object we are reading as we go along
 	hash, err := hash.NewMultiHasherTypes(hashes)
@@ -781,13 +932,19 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	in = io.TeeReader(in, hash)
 
 	_, err = io.Copy(out, in)
+	if err == nil && *fsyncWrites {
+		err = out.Sync()
+	}
 	closeErr := out.Close()
 	if err == nil {
 		err = closeErr
 	}
+	if err == nil && writePath != localPath {
+		err = os.Rename(writePath, localPath)
+	}
 	if err != nil {
 		fs.Logf(o, "Removing partially written file on error: %v", err)
-		if removeErr := os.Remove(o.path); removeErr != nil {
+		if removeErr := os.Remove(writePath); removeErr != nil {
 			fs.Errorf(o, "Failed to remove partially written file: %v", removeErr)
 		}
 		return err
@@ -821,11 +978,14 @@ func (o *Object) setMetadata(info os.FileInfo) {
 	if o.mode != info.Mode() {
 		o.mode = info.Mode()
 	}
+	if btime := readBirthTime(info); !o.btime.Equal(btime) {
+		o.btime = btime
+	}
 }
 
 // Stat a Object into info
 func (o *Object) lstat() error {
-	info, err := o.fs.lstat(o.path)
+	info, err := o.fs.lstat(o.localPath())
 	if err == nil {
 		o.setMetadata(info)
 	}
@@ -834,7 +994,7 @@ func (o *Object) lstat() error {
 
 // Remove an object
 func (o *Object) Remove() error {
-	return os.Remove(o.path)
+	return os.Remove(o.localPath())
 }
 
 // Return the directory and file from an OS path. Assumes
@@ -872,7 +1032,7 @@ func cleanPathFragment(s string) string {
 func (f *Fs) cleanPath(s string) string {
 	s = cleanPathFragment(s)
 	if runtime.GOOS == "windows" {
-		if !filepath.IsAbs(s) && !strings.HasPrefix(s, "\\") {
+		if !filepath.IsAbs(s) && !strings.HasPrefix(s, `\\`) {
 			s2, err := filepath.Abs(s)
 			if err == nil {
 				s = s2
@@ -880,7 +1040,7 @@ func (f *Fs) cleanPath(s string) string {
 		}
 		if !f.nounc {
 			// Convert to UNC
-			s = uncPath(s)
+			s = file.UNCPath(s)
 		}
 		s = cleanWindowsName(f, s)
 	} else {
@@ -894,31 +1054,6 @@ func (f *Fs) cleanPath(s string) string {
 	return s
 }
 
-// Pattern to match a windows absolute path: "c:\" and similar
-var isAbsWinDrive = regexp.MustCompile(`^[a-zA-Z]\:\\`)
-
-// uncPath converts an absolute Windows path
-// to a UNC long path.
-func uncPath(s string) string {
-	// UNC can NOT use "/", so convert all to "\"
-	s = strings.Replace(s, `/`, `\`, -1)
-
-	// If prefix is "\\", we already have a UNC path or server.
-	if strings.HasPrefix(s, `\\`) {
-		// If already long path, just keep it
-		if strings.HasPrefix(s, `\\?\`) {
-			return s
-		}
-
-		// Trim "\\" from path and add UNC prefix.
-		return `\\?\UNC\` + strings.TrimPrefix(s, `\\`)
-	}
-	if isAbsWinDrive.MatchString(s) {
-		return `\\?\` + s
-	}
-	return s
-}
-
 // cleanWindowsName will clean invalid Windows characters replacing them with _
 func cleanWindowsName(f *Fs, name string) string {
 	original := name
@@ -960,10 +1095,13 @@ func cleanWindowsName(f *Fs, name string) string {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs          = &Fs{}
-	_ fs.Purger      = &Fs{}
-	_ fs.PutStreamer = &Fs{}
-	_ fs.Mover       = &Fs{}
-	_ fs.DirMover    = &Fs{}
-	_ fs.Object      = &Object{}
+	_ fs.Fs             = &Fs{}
+	_ fs.Purger         = &Fs{}
+	_ fs.PutStreamer    = &Fs{}
+	_ fs.ListPager      = &Fs{}
+	_ fs.Mover          = &Fs{}
+	_ fs.DirMover       = &Fs{}
+	_ fs.OpenWriterAter = &Fs{}
+	_ fs.Object         = &Object{}
+	_ fs.CreatedTimer   = &Object{}
 )
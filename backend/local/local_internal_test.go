@@ -1,12 +1,19 @@
 package local
 
 import (
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/object"
 	"github.com/ncw/rclone/fstest"
 	"github.com/ncw/rclone/lib/readers"
 	"github.com/stretchr/testify/assert"
@@ -60,3 +67,94 @@ func TestMapper(t *testing.T) {
 	_, err = in.Read(buf)
 	require.Errorf(t, err, "can't copy - source file is being updated")
 }
+
+func TestAtomicWrites(t *testing.T) {
+	oldAtomicWrites, oldFsyncWrites := *atomicWrites, *fsyncWrites
+	*atomicWrites, *fsyncWrites = true, true
+	defer func() {
+		*atomicWrites, *fsyncWrites = oldAtomicWrites, oldFsyncWrites
+	}()
+
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	f := r.Flocal.(*Fs)
+	filePath := "atomic/file.txt"
+	t1 := fstest.Time("2001-02-03T04:05:06.499999999Z")
+
+	src := object.NewStaticObjectInfo(filePath, t1, 7, true, nil, nil)
+	o, err := f.Put(strings.NewReader("content"), src)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Flocal, fstest.NewItem(filePath, "content", t1))
+
+	// A failed write must leave the existing file intact and
+	// not leave the temporary file behind
+	src = object.NewStaticObjectInfo(filePath, t1, 100, true, nil, nil)
+	in := io.MultiReader(strings.NewReader("partial"), iotest.TimeoutReader(strings.NewReader("x")))
+	err = o.Update(in, src)
+	require.Error(t, err)
+	fstest.CheckItems(t, r.Flocal, fstest.NewItem(filePath, "content", t1))
+}
+
+func TestObjectPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-local-path")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0777))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sub", "file.txt"), []byte("hello"), 0666))
+	f, err := NewFs("local", dir)
+	require.NoError(t, err)
+
+	// The path isn't stored when it can be made from the remote
+	entries, err := f.List("sub")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	o := entries[0].(*Object)
+	assert.Equal(t, "sub/file.txt", o.Remote())
+	assert.Equal(t, "", o.path)
+	assert.Equal(t, f.(*Fs).cleanPath(filepath.Join(dir, "sub", "file.txt")), o.localPath())
+	assert.NoError(t, o.lstat())
+	assert.Equal(t, int64(5), o.Size())
+}
+
+func TestVSSPath(t *testing.T) {
+	const device = `\\?\GLOBALROOT\Device\HarddiskVolumeShadowCopy3`
+	for _, test := range []struct {
+		root       string
+		wantVolume string
+		wantRoot   string
+	}{
+		{`\\?\C:\Users\rclone`, "C:", device + `\Users\rclone`},
+		{`C:\Users\rclone`, "C:", device + `\Users\rclone`},
+		{`\\?\D:\`, "D:", device + `\`},
+		{`\\?\UNC\server\share`, "", device + `\UNC\server\share`},
+	} {
+		volume, root := vssPath(device, test.root)
+		assert.Equal(t, test.wantVolume, volume, test.root)
+		assert.Equal(t, test.wantRoot, root, test.root)
+	}
+}
+
+// BenchmarkList shows the memory used listing a large directory
+func BenchmarkList(b *testing.B) {
+	dir, err := ioutil.TempDir("", "rclone-local-list")
+	require.NoError(b, err)
+	defer func() {
+		require.NoError(b, os.RemoveAll(dir))
+	}()
+	const files = 1000
+	for i := 0; i < files; i++ {
+		require.NoError(b, ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("file-with-a-fairly-long-name-%04d.txt", i)), nil, 0666))
+	}
+	f, err := NewFs("local", dir)
+	require.NoError(b, err)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entries, err := f.List("")
+		if err != nil || len(entries) != files {
+			b.Fatalf("list failed: %v: %d entries", err, len(entries))
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// Volume Shadow Copy path functions
+
+package local
+
+import "strings"
+
+// vssPath translates root, which should be an absolute path
+// optionally with a `\\?\` prefix, into the equivalent path inside
+// the shadow copy device deviceObject.
+//
+// It returns the volume root is on and the translated path.
+func vssPath(deviceObject, root string) (volume, shadowRoot string) {
+	p := strings.TrimPrefix(root, `\\?\`)
+	if len(p) >= 2 && p[1] == ':' {
+		volume = p[:2]
+	}
+	rest := strings.TrimLeft(p[len(volume):], `\/`)
+	shadowRoot = strings.TrimRight(deviceObject, `\`) + `\` + rest
+	return volume, shadowRoot
+}
@@ -0,0 +1,11 @@
+// Volume Shadow Copy functions
+
+// +build !windows
+
+package local
+
+// vssRoot returns root unchanged as Volume Shadow Copy is only
+// supported on Windows.
+func vssRoot(root string, useVSS bool) (string, error) {
+	return root, nil
+}
@@ -0,0 +1,107 @@
+// Volume Shadow Copy functions
+
+// +build windows
+
+package local
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/lib/atexit"
+	"github.com/pkg/errors"
+)
+
+var (
+	vssMu        sync.Mutex
+	vssSnapshots = map[string]*vssSnapshot{} // snapshots indexed by volume
+)
+
+// vssSnapshot describes a shadow copy made by rclone
+type vssSnapshot struct {
+	id           string // the ShadowID
+	deviceObject string // eg \\?\GLOBALROOT\Device\HarddiskVolumeShadowCopy3
+}
+
+// powershell runs script with powershell returning the non blank
+// lines of the output
+func powershell(script string) ([]string, error) {
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, errors.Wrapf(err, "powershell failed: %s", strings.TrimSpace(string(out)))
+	}
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// newVSSSnapshot makes a shadow copy of volume, eg "C:"
+func newVSSSnapshot(volume string) (*vssSnapshot, error) {
+	script := fmt.Sprintf(`$ErrorActionPreference = "Stop"
+$r = (Get-WmiObject -List Win32_ShadowCopy).Create("%s\", "ClientAccessible")
+if ($r.ReturnValue -ne 0) { throw "Win32_ShadowCopy.Create returned $($r.ReturnValue)" }
+$s = Get-WmiObject Win32_ShadowCopy | Where-Object { $_.ID -eq $r.ShadowID }
+Write-Output $s.ID
+Write-Output $s.DeviceObject`, volume)
+	lines, err := powershell(script)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create shadow copy of %q", volume)
+	}
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], `\\?\GLOBALROOT\`) {
+		return nil, errors.Errorf("failed to create shadow copy of %q: unexpected output %q", volume, lines)
+	}
+	return &vssSnapshot{
+		id:           lines[0],
+		deviceObject: lines[1],
+	}, nil
+}
+
+// remove deletes the shadow copy
+func (s *vssSnapshot) remove() {
+	script := fmt.Sprintf(`Get-WmiObject Win32_ShadowCopy | Where-Object { $_.ID -eq "%s" } | ForEach-Object { $_.Delete() }`, s.id)
+	if _, err := powershell(script); err != nil {
+		fs.Errorf(nil, "Failed to delete shadow copy %s: %v", s.id, err)
+		return
+	}
+	fs.Debugf(nil, "Deleted shadow copy %s", s.id)
+}
+
+// vssRoot returns the path to root inside a Volume Shadow Copy
+// snapshot of its volume if useVSS is set, otherwise it returns root
+// unchanged.
+//
+// A snapshot is made once per volume and deleted when rclone exits.
+func vssRoot(root string, useVSS bool) (string, error) {
+	if !useVSS {
+		return root, nil
+	}
+	vssMu.Lock()
+	defer vssMu.Unlock()
+	volume, _ := vssPath("", root)
+	if len(volume) != 2 || volume[1] != ':' {
+		return "", errors.Errorf("vss: can't snapshot %q as it isn't on a drive letter", root)
+	}
+	volume = strings.ToUpper(volume)
+	snapshot, ok := vssSnapshots[volume]
+	if !ok {
+		var err error
+		snapshot, err = newVSSSnapshot(volume)
+		if err != nil {
+			return "", err
+		}
+		fs.Infof(nil, "Created shadow copy %s of %s at %s", snapshot.id, volume, snapshot.deviceObject)
+		vssSnapshots[volume] = snapshot
+		atexit.Register(snapshot.remove)
+	}
+	_, shadowRoot := vssPath(snapshot.deviceObject, root)
+	return shadowRoot, nil
+}
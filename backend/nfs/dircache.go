@@ -0,0 +1,60 @@
+package nfs
+
+import (
+	"strings"
+	"sync"
+)
+
+// dirCache caches the handles of the directories in an export so
+// they don't have to be looked up from the root each time.
+//
+// All the Fs for an export in this process share a dirCache so
+// renames and removals made by one are seen by the others.
+type dirCache struct {
+	mu   sync.Mutex
+	dirs map[string][]byte // handles by path in the export
+}
+
+var (
+	dirCachesMu sync.Mutex
+	dirCaches   = map[string]*dirCache{}
+)
+
+// getDirCache returns the dirCache for export on host
+func getDirCache(host, export string) *dirCache {
+	key := host + ":" + export
+	dirCachesMu.Lock()
+	defer dirCachesMu.Unlock()
+	dc, ok := dirCaches[key]
+	if !ok {
+		dc = &dirCache{dirs: map[string][]byte{}}
+		dirCaches[key] = dc
+	}
+	return dc
+}
+
+// get returns the handle for the directory p if it is cached
+func (dc *dirCache) get(p string) ([]byte, bool) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	fh, ok := dc.dirs[p]
+	return fh, ok
+}
+
+// put caches the handle for the directory p
+func (dc *dirCache) put(p string, fh []byte) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.dirs[p] = fh
+}
+
+// forget removes the directory p and everything below it
+func (dc *dirCache) forget(p string) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	for dir := range dc.dirs {
+		if dir == p || strings.HasPrefix(dir, p+"/") {
+			delete(dc.dirs, dir)
+		}
+	}
+}
@@ -0,0 +1,874 @@
+// Package nfs provides an interface to NFS servers using a userspace
+// NFS version 3 client so it doesn't need root or kernel mounts.
+package nfs
+
+import (
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/backend/nfs/nfs3"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/fshttp"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultIOSize = 64 * 1024   // size of reads and writes if the server doesn't say
+	maxIOSize     = 1024 * 1024 // maximum size of reads and writes
+	nobody        = 65534       // uid and gid to use if there isn't one
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "nfs",
+		Description: "NFS version 3 server",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name:     "host",
+			Help:     "NFS server hostname to connect to",
+			Optional: false,
+			Examples: []fs.OptionExample{{
+				Value: "nas.example.com",
+				Help:  "Connect to nas.example.com",
+			}},
+		}, {
+			Name:     "export",
+			Help:     "Path of the export on the server, as shown by showmount -e",
+			Optional: false,
+			Examples: []fs.OptionExample{{
+				Value: "/srv/nfs",
+				Help:  "Use the /srv/nfs export",
+			}},
+		}, {
+			Name:     "uid",
+			Type:     fs.OptionTypeInt,
+			Help:     "User ID to send to the server, leave blank to use the current user's",
+			Optional: true,
+		}, {
+			Name:     "gid",
+			Type:     fs.OptionTypeInt,
+			Help:     "Group ID to send to the server, leave blank to use the current user's",
+			Optional: true,
+		}, {
+			Name:     "port",
+			Type:     fs.OptionTypeInt,
+			Help:     "NFS port, leave blank to ask the portmapper or use the default (2049)",
+			Optional: true,
+		}, {
+			Name:     "mount_port",
+			Type:     fs.OptionTypeInt,
+			Help:     "Port of the mount daemon, leave blank to ask the portmapper",
+			Optional: true,
+		}},
+	})
+}
+
+// Fs represents an export on an NFS server
+type Fs struct {
+	name     string        // name of this remote
+	root     string        // the path we are working on if any
+	features *fs.Features  // optional features
+	host     string        // the server
+	export   string        // the export on the server
+	port     uint32        // the port of the NFS server
+	auth     *nfs3.Auth    // credentials sent with each call
+	rootFh   []byte        // handle of the root of the export
+	dial     nfs3.DialFunc // for making connections to the server
+	dirs     *dirCache     // handles of the directories in the export
+	poolMu   sync.Mutex
+	pool     []*nfs3.Target
+}
+
+// Object describes an NFS file
+type Object struct {
+	fs      *Fs
+	remote  string
+	size    int64
+	modTime time.Time
+}
+
+// ------------------------------------------------------------
+
+// Name of this fs
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return "nfs://" + f.host + path.Join(f.export, f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Get a connection from the pool, or open a new one
+func (f *Fs) getTarget() (t *nfs3.Target, err error) {
+	f.poolMu.Lock()
+	if len(f.pool) > 0 {
+		t = f.pool[0]
+		f.pool = f.pool[1:]
+	}
+	f.poolMu.Unlock()
+	if t != nil {
+		return t, nil
+	}
+	fs.Debugf(f, "Connecting to NFS server")
+	t, err = nfs3.NewTarget(f.dial, f.host, f.port, f.auth, f.rootFh)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to NFS server")
+	}
+	return t, nil
+}
+
+// Return a connection to the pool
+//
+// It nils the pointed to connection out so it can't be reused
+//
+// if err is not nil and isn't an NFS error then the connection is
+// closed rather than being returned to the pool
+func (f *Fs) putTarget(pt **nfs3.Target, err error) {
+	t := *pt
+	*pt = nil
+	if err != nil {
+		if _, isRegularError := errors.Cause(err).(*nfs3.Error); !isRegularError {
+			fs.Debugf(f, "Connection failed, closing: %v", err)
+			_ = t.Close()
+			return
+		}
+	}
+	f.poolMu.Lock()
+	f.pool = append(f.pool, t)
+	f.poolMu.Unlock()
+}
+
+// withTarget calls fn with a pooled connection
+func (f *Fs) withTarget(fn func(t *nfs3.Target) error) error {
+	t, err := f.getTarget()
+	if err != nil {
+		return err
+	}
+	err = fn(t)
+	f.putTarget(&t, err)
+	return err
+}
+
+// parseID parses a uid or gid from the config
+func parseID(name, key string, current int) (uint32, error) {
+	value := config.FileGet(name, key)
+	if value == "" {
+		if current < 0 {
+			return nobody, nil
+		}
+		return uint32(current), nil
+	}
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, errors.Wrapf(err, "bad %s %q", key, value)
+	}
+	return uint32(id), nil
+}
+
+// parsePort parses a port from the config, returning 0 if not set
+func parsePort(name, key string) (uint32, error) {
+	value := config.FileGet(name, key)
+	if value == "" {
+		return 0, nil
+	}
+	port, err := strconv.ParseUint(value, 10, 16)
+	if err != nil {
+		return 0, errors.Wrapf(err, "bad %s %q", key, value)
+	}
+	return uint32(port), nil
+}
+
+// NewFs constructs an Fs from the path
+func NewFs(name, root string) (fs.Fs, error) {
+	host := config.FileGet(name, "host")
+	if host == "" {
+		return nil, errors.New("host not found in config")
+	}
+	export := config.FileGet(name, "export")
+	if export == "" {
+		return nil, errors.New("export not found in config")
+	}
+	uid, err := parseID(name, "uid", os.Getuid())
+	if err != nil {
+		return nil, err
+	}
+	gid, err := parseID(name, "gid", os.Getgid())
+	if err != nil {
+		return nil, err
+	}
+	port, err := parsePort(name, "port")
+	if err != nil {
+		return nil, err
+	}
+	mountPort, err := parsePort(name, "mount_port")
+	if err != nil {
+		return nil, err
+	}
+	machineName, _ := os.Hostname()
+	f := &Fs{
+		name:   name,
+		root:   strings.Trim(root, "/"),
+		host:   host,
+		export: export,
+		auth: &nfs3.Auth{
+			MachineName: machineName,
+			UID:         uid,
+			GID:         gid,
+		},
+		dial: fshttp.NewDialer(fs.Config).Dial,
+		dirs: getDirCache(host, export),
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(f)
+	f.rootFh, err = nfs3.Mount(f.dial, host, mountPort, f.auth, export)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewFs")
+	}
+	f.port = port
+	if f.port == 0 {
+		f.port = nfs3.NFSPort(f.dial, host)
+	}
+	// Make a connection and pool it to return errors early
+	t, err := f.getTarget()
+	if err != nil {
+		return nil, errors.Wrap(err, "NewFs")
+	}
+	f.putTarget(&t, nil)
+	if f.root != "" {
+		// Check to see if the root actually an existing file
+		_, attr, err := f.lookup(f.root)
+		if err == nil && !attr.IsDir() {
+			f.root = path.Dir(f.root)
+			if f.root == "." {
+				f.root = ""
+			}
+			// return an error with an fs which points to the parent
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// fullPath returns the path of remote in the export
+func (f *Fs) fullPath(remote string) string {
+	p := path.Join(f.root, remote)
+	if p == "." {
+		return ""
+	}
+	return p
+}
+
+// splitPath returns the parent directory and leaf of the path in the
+// export
+func splitPath(p string) (dir, leaf string) {
+	dir, leaf = path.Split(p)
+	return strings.TrimSuffix(dir, "/"), leaf
+}
+
+// dirHandle returns the handle of the directory at p in the export,
+// looking it up if it isn't cached
+func (f *Fs) dirHandle(p string) ([]byte, error) {
+	if p == "" {
+		return f.rootFh, nil
+	}
+	fh, ok := f.dirs.get(p)
+	if ok {
+		return fh, nil
+	}
+	fh, attr, err := f.lookup(p)
+	if err != nil {
+		return nil, err
+	}
+	if !attr.IsDir() {
+		return nil, &nfs3.Error{Op: "lookup", Status: nfs3.StatusNotDir}
+	}
+	f.dirs.put(p, fh)
+	return fh, nil
+}
+
+// withDir calls fn with a pooled connection and the handle of the
+// directory p, retrying once if the cached handle has gone stale
+func (f *Fs) withDir(p string, fn func(t *nfs3.Target, dirFh []byte) error) (err error) {
+	for try := 0; try < 2; try++ {
+		var dirFh []byte
+		dirFh, err = f.dirHandle(p)
+		if err != nil {
+			return err
+		}
+		err = f.withTarget(func(t *nfs3.Target) error {
+			return fn(t, dirFh)
+		})
+		if !isStale(err) {
+			break
+		}
+		fs.Debugf(f, "Handle for %q is stale - looking it up again", p)
+		f.dirs.forget(p)
+	}
+	return err
+}
+
+// lookup returns the handle and attributes of the path p in the
+// export
+func (f *Fs) lookup(p string) (fh []byte, attr *nfs3.Attr, err error) {
+	if p == "" {
+		err = f.withTarget(func(t *nfs3.Target) error {
+			attr, err = t.GetAttr(f.rootFh)
+			return err
+		})
+		return f.rootFh, attr, err
+	}
+	dir, leaf := splitPath(p)
+	err = f.withDir(dir, func(t *nfs3.Target, dirFh []byte) error {
+		fh, attr, err = t.Lookup(dirFh, leaf)
+		if err == nil && attr == nil {
+			attr, err = t.GetAttr(fh)
+		}
+		return err
+	})
+	return fh, attr, err
+}
+
+// isStale returns whether err means that a handle is no longer valid
+func isStale(err error) bool {
+	err = errors.Cause(err)
+	return nfs3.IsStatus(err, nfs3.StatusStale) || nfs3.IsStatus(err, nfs3.StatusBadHandle)
+}
+
+// isNotFound returns whether err means that the path doesn't exist
+func isNotFound(err error) bool {
+	err = errors.Cause(err)
+	return nfs3.IsStatus(err, nfs3.StatusNoEnt) || nfs3.IsStatus(err, nfs3.StatusNotDir)
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(remote string) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	_, err := o.stat()
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+	var items []nfs3.DirEntry
+	err = f.withDir(f.fullPath(dir), func(t *nfs3.Target, dirFh []byte) error {
+		items, err = t.ReadDirPlus(dirFh)
+		if err != nil {
+			return err
+		}
+		// Fill in the attributes the server didn't send
+		for i := range items {
+			item := &items[i]
+			if item.Attr != nil {
+				continue
+			}
+			if item.Handle == nil {
+				item.Handle, item.Attr, err = t.Lookup(dirFh, item.Name)
+			}
+			if err == nil && item.Attr == nil {
+				item.Attr, err = t.GetAttr(item.Handle)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fs.ErrorDirNotFound
+		}
+		return nil, errors.Wrapf(err, "error listing %q", dir)
+	}
+	for _, item := range items {
+		remote := path.Join(dir, item.Name)
+		switch item.Attr.Type {
+		case nfs3.TypeDirectory:
+			entries = append(entries, fs.NewDir(remote, item.Attr.ModTime))
+		case nfs3.TypeRegular:
+			o := &Object{
+				fs:     f,
+				remote: remote,
+			}
+			o.setMetadata(item.Attr)
+			entries = append(entries, o)
+		default:
+			fs.Debugf(remote, "Skipping file which isn't a regular file or directory")
+		}
+	}
+	return entries, nil
+}
+
+// Hashes are not supported
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.None)
+}
+
+// Precision is the granularity of the times the server stores
+func (f *Fs) Precision() (precision time.Duration) {
+	precision = time.Second
+	_ = f.withTarget(func(t *nfs3.Target) error {
+		if t.Info.TimeDelta > 0 {
+			precision = t.Info.TimeDelta
+		}
+		return nil
+	})
+	return precision
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	err := o.Update(in, src, options...)
+	return o, err
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(in, src, options...)
+}
+
+// mkdir makes the directory at p in the export and any parents,
+// returning its handle
+func (f *Fs) mkdir(p string) ([]byte, error) {
+	fh, err := f.dirHandle(p)
+	if err == nil || !nfs3.IsStatus(errors.Cause(err), nfs3.StatusNoEnt) {
+		return fh, err
+	}
+	dir, leaf := splitPath(p)
+	dirFh, err := f.mkdir(dir)
+	if err != nil {
+		return nil, err
+	}
+	mode := uint32(0755)
+	err = f.withTarget(func(t *nfs3.Target) error {
+		fh, err = t.Mkdir(dirFh, leaf, &nfs3.SetAttr{Mode: &mode})
+		if nfs3.IsStatus(err, nfs3.StatusExist) {
+			// made by someone else in the meantime
+			fh, _, err = t.Lookup(dirFh, leaf)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	f.dirs.put(p, fh)
+	return fh, nil
+}
+
+// Mkdir creates the directory if it doesn't exist
+func (f *Fs) Mkdir(dir string) error {
+	_, err := f.mkdir(f.fullPath(dir))
+	if err != nil {
+		return errors.Wrap(err, "Mkdir failed")
+	}
+	return nil
+}
+
+// Rmdir removes the directory if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(dir string) error {
+	p := f.fullPath(dir)
+	if p == "" {
+		// the root of the export can't be removed
+		return nil
+	}
+	parent, leaf := splitPath(p)
+	err := f.withDir(parent, func(t *nfs3.Target, parentFh []byte) error {
+		return t.Rmdir(parentFh, leaf)
+	})
+	f.dirs.forget(p)
+	switch {
+	case err == nil:
+		return nil
+	case isNotFound(err):
+		return fs.ErrorDirNotFound
+	case nfs3.IsStatus(errors.Cause(err), nfs3.StatusNotEmpty), nfs3.IsStatus(errors.Cause(err), nfs3.StatusExist):
+		return fs.ErrorDirectoryNotEmpty
+	}
+	return errors.Wrap(err, "Rmdir failed")
+}
+
+// rename renames srcPath in the export to dstPath making the parent
+// directory of dstPath if necessary
+func (f *Fs) rename(srcPath, dstPath string) error {
+	srcDir, srcLeaf := splitPath(srcPath)
+	dstDir, dstLeaf := splitPath(dstPath)
+	dstDirFh, err := f.mkdir(dstDir)
+	if err != nil {
+		return errors.Wrap(err, "failed to make destination directory")
+	}
+	err = f.withDir(srcDir, func(t *nfs3.Target, srcDirFh []byte) error {
+		return t.Rename(srcDirFh, srcLeaf, dstDirFh, dstLeaf)
+	})
+	if isStale(err) {
+		// the destination handle must be the stale one
+		f.dirs.forget(dstDir)
+		dstDirFh, err = f.mkdir(dstDir)
+		if err != nil {
+			return errors.Wrap(err, "failed to make destination directory")
+		}
+		err = f.withDir(srcDir, func(t *nfs3.Target, srcDirFh []byte) error {
+			return t.Rename(srcDirFh, srcLeaf, dstDirFh, dstLeaf)
+		})
+	}
+	return err
+}
+
+// sameExport returns whether src is on the same export as f
+func (f *Fs) sameExport(src *Fs) bool {
+	return f.host == src.host && f.export == src.export && f.port == src.port
+}
+
+// Move src to this remote using server side move operations.
+//
+// This is stored in the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || !f.sameExport(srcObj.fs) {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	err := f.rename(srcObj.fs.fullPath(srcObj.remote), f.fullPath(remote))
+	if err != nil {
+		return nil, errors.Wrap(err, "Move failed")
+	}
+	return f.NewObject(remote)
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok || !f.sameExport(srcFs) {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	srcPath := srcFs.fullPath(srcRemote)
+	dstPath := f.fullPath(dstRemote)
+	if srcPath == "" {
+		return errors.New("DirMove: can't move the root of the export")
+	}
+	_, _, err := f.lookup(dstPath)
+	if err == nil {
+		return fs.ErrorDirExists
+	} else if !isNotFound(err) {
+		return errors.Wrap(err, "DirMove lookup failed")
+	}
+	err = f.rename(srcPath, dstPath)
+	srcFs.dirs.forget(srcPath)
+	if err != nil {
+		return errors.Wrap(err, "DirMove failed")
+	}
+	return nil
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// String version of o
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the hash of an object returning a lowercase hex string
+func (o *Object) Hash(t hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime() time.Time {
+	return o.modTime
+}
+
+// setMetadata sets the metadata from attr
+func (o *Object) setMetadata(attr *nfs3.Attr) {
+	o.size = int64(attr.Size)
+	o.modTime = attr.ModTime
+}
+
+// stat reads the metadata from the server returning the handle of
+// the object
+func (o *Object) stat() ([]byte, error) {
+	fh, attr, err := o.fs.lookup(o.fs.fullPath(o.remote))
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fs.ErrorObjectNotFound
+		}
+		return nil, errors.Wrap(err, "stat failed")
+	}
+	if attr.IsDir() {
+		return nil, fs.ErrorNotAFile
+	}
+	o.setMetadata(attr)
+	return fh, nil
+}
+
+// SetModTime sets the modification time of the object
+func (o *Object) SetModTime(modTime time.Time) error {
+	fh, err := o.stat()
+	if err != nil {
+		return err
+	}
+	return o.setModTime(fh, modTime)
+}
+
+// setModTime sets the modification time of the file with handle fh
+// and reads its metadata
+func (o *Object) setModTime(fh []byte, modTime time.Time) error {
+	return o.fs.withTarget(func(t *nfs3.Target) error {
+		err := t.SetAttr(fh, &nfs3.SetAttr{ModTime: &modTime})
+		if err != nil {
+			return errors.Wrap(err, "SetModTime failed")
+		}
+		attr, err := t.GetAttr(fh)
+		if err != nil {
+			return errors.Wrap(err, "SetModTime failed to read attributes")
+		}
+		o.setMetadata(attr)
+		return nil
+	})
+}
+
+// Storable returns a boolean as to whether this object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// nfsReader reads an NFS file
+type nfsReader struct {
+	f      *Fs
+	t      *nfs3.Target
+	fh     []byte
+	offset uint64
+	limit  int64 // bytes left to read or -1 for all of them
+	err    error // any error from the server
+}
+
+// Read bytes into p
+func (r *nfsReader) Read(p []byte) (n int, err error) {
+	if r.t == nil {
+		return 0, errors.New("read on closed file")
+	}
+	if r.limit == 0 {
+		return 0, io.EOF
+	}
+	count := uint32(len(p))
+	if size := r.t.Info.ReadSize; size > 0 && count > size {
+		count = size
+	}
+	if count > maxIOSize {
+		count = maxIOSize
+	}
+	if r.limit > 0 && int64(count) > r.limit {
+		count = uint32(r.limit)
+	}
+	data, eof, err := r.t.Read(r.fh, r.offset, count)
+	if err != nil {
+		r.err = err
+		return 0, err
+	}
+	n = copy(p, data)
+	r.offset += uint64(n)
+	if r.limit > 0 {
+		r.limit -= int64(n)
+	}
+	if eof || n == 0 {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Close the reader and return the connection to the pool
+func (r *nfsReader) Close() error {
+	if r.t != nil {
+		r.f.putTarget(&r.t, r.err)
+	}
+	return nil
+}
+
+// Open an object for read
+func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.Size())
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	fh, err := o.stat()
+	if err != nil {
+		return nil, errors.Wrap(err, "Open failed")
+	}
+	t, err := o.fs.getTarget()
+	if err != nil {
+		return nil, errors.Wrap(err, "Open failed")
+	}
+	return &nfsReader{
+		f:      o.fs,
+		t:      t,
+		fh:     fh,
+		offset: uint64(offset),
+		limit:  limit,
+	}, nil
+}
+
+// write copies in to the file with handle fh
+func (o *Object) write(t *nfs3.Target, fh []byte, in io.Reader) error {
+	size := t.Info.WriteSize
+	if size == 0 {
+		size = defaultIOSize
+	}
+	if size > maxIOSize {
+		size = maxIOSize
+	}
+	buf := make([]byte, size)
+	var offset uint64
+	for {
+		n, readErr := io.ReadFull(in, buf)
+		for data := buf[:n]; len(data) > 0; {
+			written, err := t.Write(fh, offset, data)
+			if err != nil {
+				return err
+			}
+			if written == 0 {
+				return errors.New("server wrote no data")
+			}
+			data = data[written:]
+			offset += uint64(written)
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	p := o.fs.fullPath(o.remote)
+	dir, leaf := splitPath(p)
+	_, err = o.fs.mkdir(dir)
+	if err != nil {
+		return errors.Wrap(err, "Update mkdir failed")
+	}
+	mode := uint32(0644)
+	var size uint64
+	var fh []byte
+	err = o.fs.withDir(dir, func(t *nfs3.Target, dirFh []byte) error {
+		fh, err = t.Create(dirFh, leaf, &nfs3.SetAttr{Mode: &mode, Size: &size})
+		if err != nil {
+			return err
+		}
+		return o.write(t, fh, in)
+	})
+	if err != nil {
+		if fh != nil {
+			if removeErr := o.Remove(); removeErr != nil {
+				fs.Debugf(src, "Failed to remove: %v", removeErr)
+			} else {
+				fs.Debugf(src, "Removed after failed upload: %v", err)
+			}
+		}
+		return errors.Wrap(err, "Update failed")
+	}
+	return o.setModTime(fh, src.ModTime())
+}
+
+// Remove an object
+func (o *Object) Remove() error {
+	dir, leaf := splitPath(o.fs.fullPath(o.remote))
+	return o.fs.withDir(dir, func(t *nfs3.Target, dirFh []byte) error {
+		return t.Remove(dirFh, leaf)
+	})
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = &Fs{}
+	_ fs.PutStreamer = &Fs{}
+	_ fs.Mover       = &Fs{}
+	_ fs.DirMover    = &Fs{}
+	_ fs.Object      = &Object{}
+)
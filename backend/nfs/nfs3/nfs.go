@@ -0,0 +1,390 @@
+// Package nfs3 is a minimal userspace client for NFS version 3 (RFC
+// 1813) over TCP.
+//
+// It does what is needed to read and write files in an export
+// without using the kernel NFS client, so it doesn't need root.
+package nfs3
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DialFunc dials a network connection
+type DialFunc func(network, addr string) (net.Conn, error)
+
+// call makes a call to prog on the server at addr on a new connection
+func call(dial DialFunc, addr string, auth *Auth, prog, vers, proc uint32, args *Writer) (*Reader, error) {
+	conn, err := dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := NewClient(conn, auth)
+	defer func() {
+		_ = c.Close()
+	}()
+	return c.Call(prog, vers, proc, args)
+}
+
+// hostPort joins host and port into an address
+func hostPort(host string, port uint32) string {
+	return net.JoinHostPort(host, strconv.FormatUint(uint64(port), 10))
+}
+
+// GetPort asks the portmapper on host which TCP port vers of prog is
+// on
+func GetPort(dial DialFunc, host string, prog, vers uint32) (uint32, error) {
+	var args Writer
+	args.Uint32(prog)
+	args.Uint32(vers)
+	args.Uint32(6) // TCP
+	args.Uint32(0)
+	r, err := call(dial, hostPort(host, portmapPort), nil, portmapProgram, portmapVersion, portmapGetPort, &args)
+	if err != nil {
+		return 0, errors.Wrap(err, "portmap GETPORT failed")
+	}
+	port := r.Uint32()
+	if err = r.Err(); err != nil {
+		return 0, errors.Wrap(err, "portmap GETPORT failed")
+	}
+	if port == 0 {
+		return 0, errors.Errorf("program %d version %d isn't registered with the portmapper", prog, vers)
+	}
+	return port, nil
+}
+
+// NFSPort returns the port the NFS server on host is on, asking the
+// portmapper if possible
+func NFSPort(dial DialFunc, host string) uint32 {
+	port, err := GetPort(dial, host, nfsProgram, nfsVersion)
+	if err != nil {
+		return nfsPort
+	}
+	return port
+}
+
+// Mount asks the mount daemon on host for the file handle of export.
+//
+// If port is 0 the portmapper is asked which port the mount daemon is
+// on.
+func Mount(dial DialFunc, host string, port uint32, auth *Auth, export string) (root []byte, err error) {
+	if port == 0 {
+		port, err = GetPort(dial, host, mountProgram, mountVersion)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var args Writer
+	args.Str(export)
+	r, err := call(dial, hostPort(host, port), auth, mountProgram, mountVersion, mountMnt, &args)
+	if err != nil {
+		return nil, errors.Wrap(err, "MNT failed")
+	}
+	if err = status(r, "mount "+export); err != nil {
+		return nil, err
+	}
+	root = r.Opaque()
+	return root, r.Err()
+}
+
+// Target is a connection to the NFS server for an export
+type Target struct {
+	rpc  *Client
+	Root []byte // the handle of the root of the export
+	Info FSInfo // static information about the export
+}
+
+// NewTarget connects to the NFS server on host and port to use the
+// export whose handle is root
+func NewTarget(dial DialFunc, host string, port uint32, auth *Auth, root []byte) (*Target, error) {
+	conn, err := dial("tcp", hostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+	t := &Target{
+		rpc:  NewClient(conn, auth),
+		Root: root,
+	}
+	t.Info, err = t.FSInfo(root)
+	if err != nil {
+		_ = t.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// Close the connection to the server
+func (t *Target) Close() error {
+	return t.rpc.Close()
+}
+
+// call calls an NFS procedure
+func (t *Target) call(proc uint32, args *Writer) (*Reader, error) {
+	return t.rpc.Call(nfsProgram, nfsVersion, proc, args)
+}
+
+// status reads a status, returning an *Error if it isn't OK
+func status(r *Reader, op string) error {
+	s := r.Uint32()
+	if err := r.Err(); err != nil {
+		return err
+	}
+	if s != StatusOK {
+		return &Error{Op: op, Status: s}
+	}
+	return nil
+}
+
+// writeDirOp writes a diropargs3
+func writeDirOp(w *Writer, dir []byte, name string) {
+	w.Opaque(dir)
+	w.Str(name)
+}
+
+// FSInfo reads the static information about the file system
+func (t *Target) FSInfo(fh []byte) (info FSInfo, err error) {
+	var args Writer
+	args.Opaque(fh)
+	r, err := t.call(procFSInfo, &args)
+	if err != nil {
+		return info, err
+	}
+	if err = status(r, "fsinfo"); err != nil {
+		return info, err
+	}
+	readPostOpAttr(r)
+	r.Uint32() // rtmax
+	info.ReadSize = r.Uint32()
+	r.Uint32() // rtmult
+	r.Uint32() // wtmax
+	info.WriteSize = r.Uint32()
+	r.Uint32() // wtmult
+	info.DirSize = r.Uint32()
+	r.Uint64() // maxfilesize
+	delta := readTime(r)
+	info.TimeDelta = time.Duration(delta.Unix())*time.Second + time.Duration(delta.Nanosecond())
+	return info, r.Err()
+}
+
+// GetAttr reads the attributes of fh
+func (t *Target) GetAttr(fh []byte) (*Attr, error) {
+	var args Writer
+	args.Opaque(fh)
+	r, err := t.call(procGetAttr, &args)
+	if err != nil {
+		return nil, err
+	}
+	if err = status(r, "getattr"); err != nil {
+		return nil, err
+	}
+	attr := readAttr(r)
+	return attr, r.Err()
+}
+
+// SetAttr changes the attributes of fh
+func (t *Target) SetAttr(fh []byte, attr *SetAttr) error {
+	var args Writer
+	args.Opaque(fh)
+	attr.write(&args)
+	args.Bool(false) // no guard
+	r, err := t.call(procSetAttr, &args)
+	if err != nil {
+		return err
+	}
+	return status(r, "setattr")
+}
+
+// Lookup finds name in the directory dir
+func (t *Target) Lookup(dir []byte, name string) (fh []byte, attr *Attr, err error) {
+	var args Writer
+	writeDirOp(&args, dir, name)
+	r, err := t.call(procLookup, &args)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = status(r, "lookup"); err != nil {
+		return nil, nil, err
+	}
+	fh = r.Opaque()
+	attr = readPostOpAttr(r)
+	return fh, attr, r.Err()
+}
+
+// Read reads up to count bytes from fh at offset
+func (t *Target) Read(fh []byte, offset uint64, count uint32) (data []byte, eof bool, err error) {
+	var args Writer
+	args.Opaque(fh)
+	args.Uint64(offset)
+	args.Uint32(count)
+	r, err := t.call(procRead, &args)
+	if err != nil {
+		return nil, false, err
+	}
+	if err = status(r, "read"); err != nil {
+		return nil, false, err
+	}
+	readPostOpAttr(r)
+	r.Uint32() // count
+	eof = r.Bool()
+	data = r.Opaque()
+	return data, eof, r.Err()
+}
+
+// Write writes data to fh at offset, returning how much was written.
+//
+// The data is on stable storage when it returns.
+func (t *Target) Write(fh []byte, offset uint64, data []byte) (n uint32, err error) {
+	const fileSync = 2
+	var args Writer
+	args.Opaque(fh)
+	args.Uint64(offset)
+	args.Uint32(uint32(len(data)))
+	args.Uint32(fileSync)
+	args.Opaque(data)
+	r, err := t.call(procWrite, &args)
+	if err != nil {
+		return 0, err
+	}
+	if err = status(r, "write"); err != nil {
+		return 0, err
+	}
+	skipWcc(r)
+	n = r.Uint32()
+	return n, r.Err()
+}
+
+// readNewHandle reads the results of CREATE and MKDIR, looking the
+// new file up if the server didn't return its handle
+func (t *Target) readNewHandle(r *Reader, dir []byte, name string) ([]byte, error) {
+	var fh []byte
+	if r.Bool() {
+		fh = r.Opaque()
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	if fh == nil {
+		var err error
+		fh, _, err = t.Lookup(dir, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fh, nil
+}
+
+// Create makes the file name in dir, truncating it if it exists
+func (t *Target) Create(dir []byte, name string, attr *SetAttr) (fh []byte, err error) {
+	const unchecked = 0
+	var args Writer
+	writeDirOp(&args, dir, name)
+	args.Uint32(unchecked)
+	attr.write(&args)
+	r, err := t.call(procCreate, &args)
+	if err != nil {
+		return nil, err
+	}
+	if err = status(r, "create"); err != nil {
+		return nil, err
+	}
+	return t.readNewHandle(r, dir, name)
+}
+
+// Mkdir makes the directory name in dir
+func (t *Target) Mkdir(dir []byte, name string, attr *SetAttr) (fh []byte, err error) {
+	var args Writer
+	writeDirOp(&args, dir, name)
+	attr.write(&args)
+	r, err := t.call(procMkdir, &args)
+	if err != nil {
+		return nil, err
+	}
+	if err = status(r, "mkdir"); err != nil {
+		return nil, err
+	}
+	return t.readNewHandle(r, dir, name)
+}
+
+// Remove removes the file name from dir
+func (t *Target) Remove(dir []byte, name string) error {
+	var args Writer
+	writeDirOp(&args, dir, name)
+	r, err := t.call(procRemove, &args)
+	if err != nil {
+		return err
+	}
+	return status(r, "remove")
+}
+
+// Rmdir removes the empty directory name from dir
+func (t *Target) Rmdir(dir []byte, name string) error {
+	var args Writer
+	writeDirOp(&args, dir, name)
+	r, err := t.call(procRmdir, &args)
+	if err != nil {
+		return err
+	}
+	return status(r, "rmdir")
+}
+
+// Rename renames fromName in fromDir to toName in toDir
+func (t *Target) Rename(fromDir []byte, fromName string, toDir []byte, toName string) error {
+	var args Writer
+	writeDirOp(&args, fromDir, fromName)
+	writeDirOp(&args, toDir, toName)
+	r, err := t.call(procRename, &args)
+	if err != nil {
+		return err
+	}
+	return status(r, "rename")
+}
+
+// ReadDirPlus reads all the entries in dir except "." and ".."
+func (t *Target) ReadDirPlus(dir []byte) (entries []DirEntry, err error) {
+	var cookie uint64
+	cookieVerf := make([]byte, 8)
+	dirSize := t.Info.DirSize
+	if dirSize == 0 {
+		dirSize = 8192
+	}
+	for {
+		var args Writer
+		args.Opaque(dir)
+		args.Uint64(cookie)
+		args.Fixed(cookieVerf)
+		args.Uint32(dirSize)     // dircount
+		args.Uint32(dirSize * 8) // maxcount
+		r, err := t.call(procReadDirPlus, &args)
+		if err != nil {
+			return nil, err
+		}
+		if err = status(r, "readdirplus"); err != nil {
+			return nil, err
+		}
+		readPostOpAttr(r)
+		cookieVerf = r.Fixed(8)
+		for r.Bool() {
+			var entry DirEntry
+			r.Uint64() // fileid
+			entry.Name = r.Str()
+			cookie = r.Uint64()
+			entry.Attr = readPostOpAttr(r)
+			if r.Bool() {
+				entry.Handle = r.Opaque()
+			}
+			if entry.Name != "." && entry.Name != ".." {
+				entries = append(entries, entry)
+			}
+		}
+		eof := r.Bool()
+		if err = r.Err(); err != nil {
+			return nil, err
+		}
+		if eof {
+			return entries, nil
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package nfs3
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ONC RPC constants (RFC 5531)
+const (
+	rpcVersion = 2
+
+	msgCall  = 0
+	msgReply = 1
+
+	replyAccepted = 0
+	replyDenied   = 1
+
+	acceptSuccess      = 0
+	acceptProgUnavail  = 1
+	acceptProgMismatch = 2
+	acceptProcUnavail  = 3
+	acceptGarbageArgs  = 4
+	acceptSystemErr    = 5
+
+	rejectRPCMismatch = 0
+	rejectAuthError   = 1
+
+	authNone = 0
+	authSys  = 1
+
+	lastFragment = 0x80000000
+	maxRecord    = 64 << 20 // refuse replies bigger than this
+)
+
+// Auth is the AUTH_SYS credential sent with each call
+type Auth struct {
+	MachineName string
+	UID         uint32
+	GID         uint32
+	GIDs        []uint32
+}
+
+// encode writes the credential as an opaque_auth
+func (a *Auth) encode(w *Writer) {
+	if a == nil {
+		w.Uint32(authNone)
+		w.Opaque(nil)
+		return
+	}
+	var body Writer
+	body.Uint32(uint32(time.Now().Unix()))
+	body.Str(a.MachineName)
+	body.Uint32(a.UID)
+	body.Uint32(a.GID)
+	body.Uint32(uint32(len(a.GIDs)))
+	for _, gid := range a.GIDs {
+		body.Uint32(gid)
+	}
+	w.Uint32(authSys)
+	w.Opaque(body.Bytes())
+}
+
+// RPCError is returned when the server doesn't accept a call
+type RPCError struct {
+	Stat uint32 // the accept_stat or reject_stat
+	Text string
+}
+
+// Error satisfies the error interface
+func (e *RPCError) Error() string {
+	return "RPC error: " + e.Text
+}
+
+// Client makes RPC calls over a TCP connection.  One call is made at
+// a time.
+type Client struct {
+	mu   sync.Mutex
+	conn net.Conn
+	auth *Auth
+	xid  uint32
+}
+
+// NewClient makes a Client which uses conn, sending auth with each
+// call.
+func NewClient(conn net.Conn, auth *Auth) *Client {
+	return &Client{
+		conn: conn,
+		auth: auth,
+		xid:  uint32(time.Now().UnixNano()),
+	}
+}
+
+// Close the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Call calls proc in version vers of prog with the arguments args
+// (which may be nil) returning a Reader for the results
+func (c *Client) Call(prog, vers, proc uint32, args *Writer) (*Reader, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.xid++
+	xid := c.xid
+	var w Writer
+	w.Uint32(0) // record mark filled in below
+	w.Uint32(xid)
+	w.Uint32(msgCall)
+	w.Uint32(rpcVersion)
+	w.Uint32(prog)
+	w.Uint32(vers)
+	w.Uint32(proc)
+	c.auth.encode(&w)
+	w.Uint32(authNone) // verifier
+	w.Opaque(nil)
+	if args != nil {
+		w.Fixed(args.Bytes())
+	}
+	buf := w.Bytes()
+	binary.BigEndian.PutUint32(buf, lastFragment|uint32(len(buf)-4))
+	if _, err := c.conn.Write(buf); err != nil {
+		return nil, errors.Wrap(err, "RPC write failed")
+	}
+	for {
+		reply, err := c.readRecord()
+		if err != nil {
+			return nil, errors.Wrap(err, "RPC read failed")
+		}
+		r := NewReader(reply)
+		if r.Uint32() != xid {
+			// a reply to an earlier call which was abandoned
+			continue
+		}
+		if r.Uint32() != msgReply {
+			return nil, errors.New("RPC reply expected")
+		}
+		return r, decodeReplyHeader(r)
+	}
+}
+
+// readRecord reads all the fragments of a record
+func (c *Client) readRecord() (record []byte, err error) {
+	return ReadRecord(c.conn, maxRecord)
+}
+
+// ReadRecord reads an RPC record from in, joining its fragments as
+// described in RFC 5531 section 11.  Records bigger than max bytes
+// are refused.
+func ReadRecord(in io.Reader, max int) (record []byte, err error) {
+	for {
+		var mark [4]byte
+		_, err = io.ReadFull(in, mark[:])
+		if err != nil {
+			return nil, err
+		}
+		n := binary.BigEndian.Uint32(mark[:])
+		size := int(n &^ lastFragment)
+		if len(record)+size > max {
+			return nil, errors.Errorf("record too big (%d bytes)", len(record)+size)
+		}
+		fragment := make([]byte, size)
+		_, err = io.ReadFull(in, fragment)
+		if err != nil {
+			return nil, err
+		}
+		record = append(record, fragment...)
+		if n&lastFragment != 0 {
+			return record, nil
+		}
+	}
+}
+
+// WriteRecord writes record to out as a single fragment
+func WriteRecord(out io.Writer, record []byte) error {
+	buf := make([]byte, 4+len(record))
+	binary.BigEndian.PutUint32(buf, lastFragment|uint32(len(record)))
+	copy(buf[4:], record)
+	_, err := out.Write(buf)
+	return err
+}
+
+// decodeReplyHeader reads the reply status, returning an error if
+// the call didn't succeed
+func decodeReplyHeader(r *Reader) error {
+	switch r.Uint32() {
+	case replyAccepted:
+		r.Uint32() // verifier flavor
+		r.Opaque() // verifier body
+		stat := r.Uint32()
+		if err := r.Err(); err != nil {
+			return err
+		}
+		switch stat {
+		case acceptSuccess:
+			return nil
+		case acceptProgUnavail:
+			return &RPCError{Stat: stat, Text: "program unavailable"}
+		case acceptProgMismatch:
+			low, high := r.Uint32(), r.Uint32()
+			return &RPCError{Stat: stat, Text: fmt.Sprintf("program version mismatch: server supports %d to %d", low, high)}
+		case acceptProcUnavail:
+			return &RPCError{Stat: stat, Text: "procedure unavailable"}
+		case acceptGarbageArgs:
+			return &RPCError{Stat: stat, Text: "garbage arguments"}
+		case acceptSystemErr:
+			return &RPCError{Stat: stat, Text: "system error"}
+		}
+		return &RPCError{Stat: stat, Text: fmt.Sprintf("unknown accept status %d", stat)}
+	case replyDenied:
+		stat := r.Uint32()
+		switch stat {
+		case rejectRPCMismatch:
+			return &RPCError{Stat: stat, Text: "RPC version mismatch"}
+		case rejectAuthError:
+			return &RPCError{Stat: stat, Text: fmt.Sprintf("authentication error %d - does the export need a privileged port?", r.Uint32())}
+		}
+		return &RPCError{Stat: stat, Text: "call denied"}
+	}
+	if err := r.Err(); err != nil {
+		return err
+	}
+	return errors.New("bad RPC reply status")
+}
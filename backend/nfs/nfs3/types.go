@@ -0,0 +1,228 @@
+package nfs3
+
+import (
+	"fmt"
+	"time"
+)
+
+// Program numbers and versions
+const (
+	portmapProgram = 100000
+	portmapVersion = 2
+	portmapPort    = 111
+
+	mountProgram = 100005
+	mountVersion = 3
+
+	nfsProgram = 100003
+	nfsVersion = 3
+	nfsPort    = 2049
+)
+
+// Procedures
+const (
+	portmapGetPort = 3
+
+	mountMnt = 1
+
+	procGetAttr     = 1
+	procSetAttr     = 2
+	procLookup      = 3
+	procRead        = 6
+	procWrite       = 7
+	procCreate      = 8
+	procMkdir       = 9
+	procRemove      = 12
+	procRmdir       = 13
+	procRename      = 14
+	procReadDirPlus = 17
+	procFSInfo      = 19
+)
+
+// Status codes
+const (
+	StatusOK          = 0
+	StatusPerm        = 1
+	StatusNoEnt       = 2
+	StatusIO          = 5
+	StatusAccess      = 13
+	StatusExist       = 17
+	StatusNotDir      = 20
+	StatusIsDir       = 21
+	StatusInval       = 22
+	StatusNoSpace     = 28
+	StatusReadOnly    = 30
+	StatusNameTooLong = 63
+	StatusNotEmpty    = 66
+	StatusStale       = 70
+	StatusBadHandle   = 10001
+	StatusNotSupp     = 10004
+	StatusServerFault = 10006
+	StatusJukebox     = 10008
+)
+
+var statusText = map[uint32]string{
+	StatusPerm:        "not owner",
+	StatusNoEnt:       "no such file or directory",
+	StatusIO:          "I/O error",
+	StatusAccess:      "permission denied",
+	StatusExist:       "file exists",
+	StatusNotDir:      "not a directory",
+	StatusIsDir:       "is a directory",
+	StatusInval:       "invalid argument",
+	StatusNoSpace:     "no space left on device",
+	StatusReadOnly:    "read-only file system",
+	StatusNameTooLong: "file name too long",
+	StatusNotEmpty:    "directory not empty",
+	StatusStale:       "stale file handle",
+	StatusBadHandle:   "bad file handle",
+	StatusNotSupp:     "operation not supported",
+	StatusServerFault: "server fault",
+	StatusJukebox:     "try again later",
+}
+
+// Error is an NFS or MOUNT status other than OK
+type Error struct {
+	Op     string
+	Status uint32
+}
+
+// Error satisfies the error interface
+func (e *Error) Error() string {
+	text, ok := statusText[e.Status]
+	if !ok {
+		text = fmt.Sprintf("status %d", e.Status)
+	}
+	return e.Op + ": " + text
+}
+
+// IsStatus returns whether err is an *Error with status
+func IsStatus(err error, status uint32) bool {
+	e, ok := err.(*Error)
+	return ok && e.Status == status
+}
+
+// File types
+const (
+	TypeRegular   = 1
+	TypeDirectory = 2
+	TypeSymlink   = 5
+)
+
+// Attr are the attributes of a file (fattr3)
+type Attr struct {
+	Type    uint32
+	Mode    uint32
+	Size    uint64
+	FileID  uint64
+	ModTime time.Time
+}
+
+// IsDir returns whether the attributes are for a directory
+func (a *Attr) IsDir() bool {
+	return a.Type == TypeDirectory
+}
+
+// readTime reads an nfstime3
+func readTime(r *Reader) time.Time {
+	sec := r.Uint32()
+	nsec := r.Uint32()
+	return time.Unix(int64(sec), int64(nsec))
+}
+
+// writeTime writes an nfstime3
+func writeTime(w *Writer, t time.Time) {
+	w.Uint32(uint32(t.Unix()))
+	w.Uint32(uint32(t.Nanosecond()))
+}
+
+// readAttr reads an fattr3
+func readAttr(r *Reader) *Attr {
+	var a Attr
+	a.Type = r.Uint32()
+	a.Mode = r.Uint32()
+	r.Uint32() // nlink
+	r.Uint32() // uid
+	r.Uint32() // gid
+	a.Size = r.Uint64()
+	r.Uint64() // used
+	r.Uint64() // rdev
+	r.Uint64() // fsid
+	a.FileID = r.Uint64()
+	readTime(r) // atime
+	a.ModTime = readTime(r)
+	readTime(r) // ctime
+	return &a
+}
+
+// readPostOpAttr reads a post_op_attr returning nil if there are no
+// attributes
+func readPostOpAttr(r *Reader) *Attr {
+	if !r.Bool() {
+		return nil
+	}
+	return readAttr(r)
+}
+
+// skipWcc reads and discards a wcc_data
+func skipWcc(r *Reader) {
+	if r.Bool() {
+		r.Uint64()  // size
+		readTime(r) // mtime
+		readTime(r) // ctime
+	}
+	readPostOpAttr(r)
+}
+
+// SetAttr are the attributes to change with SetAttr, Create and
+// Mkdir.  nil fields aren't changed.
+type SetAttr struct {
+	Mode    *uint32
+	Size    *uint64
+	ModTime *time.Time
+}
+
+// Set time values for sattr3
+const (
+	dontChange      = 0
+	setToClientTime = 2
+)
+
+// write writes the attributes as a sattr3
+func (s *SetAttr) write(w *Writer) {
+	if s == nil {
+		s = &SetAttr{}
+	}
+	w.Bool(s.Mode != nil)
+	if s.Mode != nil {
+		w.Uint32(*s.Mode)
+	}
+	w.Bool(false) // uid
+	w.Bool(false) // gid
+	w.Bool(s.Size != nil)
+	if s.Size != nil {
+		w.Uint64(*s.Size)
+	}
+	w.Uint32(dontChange) // atime
+	if s.ModTime != nil {
+		w.Uint32(setToClientTime)
+		writeTime(w, *s.ModTime)
+	} else {
+		w.Uint32(dontChange)
+	}
+}
+
+// DirEntry is an entry returned by ReadDirPlus
+type DirEntry struct {
+	Name   string
+	Handle []byte // may be nil if the server didn't return it
+	Attr   *Attr  // may be nil if the server didn't return it
+}
+
+// FSInfo is the static information about the file system
+type FSInfo struct {
+	ReadSize  uint32        // preferred size of READ requests
+	WriteSize uint32        // preferred size of WRITE requests
+	DirSize   uint32        // preferred size of READDIR requests
+	TimeDelta time.Duration // granularity of the times stored
+}
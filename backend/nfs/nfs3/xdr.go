@@ -0,0 +1,174 @@
+package nfs3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Writer encodes values in XDR (RFC 4506)
+type Writer struct {
+	buf bytes.Buffer
+}
+
+// Bytes returns the encoded data
+func (w *Writer) Bytes() []byte {
+	return w.buf.Bytes()
+}
+
+// Len returns the number of bytes encoded
+func (w *Writer) Len() int {
+	return w.buf.Len()
+}
+
+// Uint32 writes an unsigned int
+func (w *Writer) Uint32(x uint32) {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], x)
+	_, _ = w.buf.Write(buf[:])
+}
+
+// Uint64 writes an unsigned hyper
+func (w *Writer) Uint64(x uint64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], x)
+	_, _ = w.buf.Write(buf[:])
+}
+
+// Bool writes a boolean
+func (w *Writer) Bool(x bool) {
+	if x {
+		w.Uint32(1)
+	} else {
+		w.Uint32(0)
+	}
+}
+
+// pad writes the zeros to round n bytes up to a multiple of 4
+func (w *Writer) pad(n int) {
+	for ; n%4 != 0; n++ {
+		_ = w.buf.WriteByte(0)
+	}
+}
+
+// Fixed writes fixed length opaque data
+func (w *Writer) Fixed(data []byte) {
+	_, _ = w.buf.Write(data)
+	w.pad(len(data))
+}
+
+// Opaque writes variable length opaque data
+func (w *Writer) Opaque(data []byte) {
+	w.Uint32(uint32(len(data)))
+	w.Fixed(data)
+}
+
+// Str writes a string
+func (w *Writer) Str(s string) {
+	w.Uint32(uint32(len(s)))
+	_, _ = w.buf.WriteString(s)
+	w.pad(len(s))
+}
+
+// Reader decodes values in XDR.
+//
+// The first error is remembered and returned by Err, after which
+// all reads return zero values.
+type Reader struct {
+	data []byte
+	err  error
+}
+
+// NewReader makes a Reader to decode data
+func NewReader(data []byte) *Reader {
+	return &Reader{data: data}
+}
+
+// Err returns the first error found while decoding
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// next returns the next n bytes of the data
+func (r *Reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data) {
+		r.err = errors.Wrap(io.ErrUnexpectedEOF, "XDR decode")
+		r.data = nil
+		return nil
+	}
+	p := r.data[:n]
+	r.data = r.data[n:]
+	return p
+}
+
+// Uint32 reads an unsigned int
+func (r *Reader) Uint32() uint32 {
+	p := r.next(4)
+	if p == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(p)
+}
+
+// Uint64 reads an unsigned hyper
+func (r *Reader) Uint64() uint64 {
+	p := r.next(8)
+	if p == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(p)
+}
+
+// Bool reads a boolean
+func (r *Reader) Bool() bool {
+	return r.Uint32() != 0
+}
+
+// Fixed reads n bytes of fixed length opaque data
+func (r *Reader) Fixed(n int) []byte {
+	p := r.next(n)
+	r.next((4 - n%4) % 4)
+	if p == nil || r.err != nil {
+		return nil
+	}
+	return append([]byte(nil), p...)
+}
+
+// Opaque reads variable length opaque data
+func (r *Reader) Opaque() []byte {
+	n := r.Uint32()
+	if r.err == nil && int64(n) > int64(len(r.data)) {
+		r.err = errors.Errorf("XDR decode: length %d too long", n)
+		return nil
+	}
+	return r.Fixed(int(n))
+}
+
+// Str reads a string
+func (r *Reader) Str() string {
+	return string(r.Opaque())
+}
+
+// OpaqueMax reads variable length opaque data of at most max bytes
+func (r *Reader) OpaqueMax(max int) []byte {
+	n := r.Uint32()
+	if r.err == nil && int64(n) > int64(max) {
+		r.err = errors.Errorf("XDR decode: length %d longer than %d", n, max)
+		return nil
+	}
+	if r.err == nil && int64(n) > int64(len(r.data)) {
+		r.err = errors.Errorf("XDR decode: length %d too long", n)
+		return nil
+	}
+	return r.Fixed(int(n))
+}
+
+// StrMax reads a string of at most max bytes
+func (r *Reader) StrMax(max int) string {
+	return string(r.OpaqueMax(max))
+}
@@ -0,0 +1,44 @@
+package nfs3
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXDR(t *testing.T) {
+	w := &Writer{}
+	w.Uint32(1)
+	w.Uint64(2)
+	w.Bool(true)
+	w.Str("hello")
+	w.Fixed([]byte{1, 2})
+	assert.Equal(t, 4+8+4+4+8+4, w.Len())
+
+	r := NewReader(w.Bytes())
+	assert.Equal(t, uint32(1), r.Uint32())
+	assert.Equal(t, uint64(2), r.Uint64())
+	assert.True(t, r.Bool())
+	assert.Equal(t, "hello", r.StrMax(5))
+	assert.Equal(t, []byte{1, 2}, r.Fixed(2))
+	assert.NoError(t, r.Err())
+	_ = r.Uint32()
+	assert.Error(t, r.Err())
+
+	r = NewReader(w.Bytes()[4+8+4:])
+	assert.Equal(t, "", r.StrMax(4))
+	assert.Error(t, r.Err())
+}
+
+func TestRecord(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteRecord(&buf, []byte("hello")))
+	require.NoError(t, WriteRecord(&buf, []byte("potato")))
+	record, err := ReadRecord(&buf, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(record))
+	_, err = ReadRecord(&buf, 5)
+	assert.Error(t, err)
+}
@@ -0,0 +1,506 @@
+package nfs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/backend/nfs/nfs3"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNode is a file or directory on the fake server
+type fakeNode struct {
+	id       uint64
+	dir      bool
+	data     []byte
+	mode     uint32
+	modTime  time.Time
+	children map[string]uint64
+}
+
+// fakeServer is an in memory NFS version 3 server which serves the
+// MOUNT and NFS programs on the same port
+type fakeServer struct {
+	mu     sync.Mutex
+	nodes  map[uint64]*fakeNode
+	nextID uint64
+	ln     net.Listener
+	port   int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeServer{
+		nodes:  map[uint64]*fakeNode{},
+		nextID: 1,
+		ln:     ln,
+		port:   ln.Addr().(*net.TCPAddr).Port,
+	}
+	s.newNode(true)
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) newNode(dir bool) *fakeNode {
+	n := &fakeNode{id: s.nextID, dir: dir, mode: 0644, modTime: time.Now()}
+	if dir {
+		n.children = map[string]uint64{}
+		n.mode = 0755
+	}
+	s.nodes[n.id] = n
+	s.nextID++
+	return n
+}
+
+func handle(n *fakeNode) []byte {
+	fh := make([]byte, 8)
+	binary.BigEndian.PutUint64(fh, n.id)
+	return fh
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *fakeServer) serveConn(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	for {
+		var mark [4]byte
+		if _, err := io.ReadFull(conn, mark[:]); err != nil {
+			return
+		}
+		call := make([]byte, binary.BigEndian.Uint32(mark[:])&^0x80000000)
+		if _, err := io.ReadFull(conn, call); err != nil {
+			return
+		}
+		r := nfs3.NewReader(call)
+		xid := r.Uint32()
+		r.Uint32() // CALL
+		r.Uint32() // RPC version
+		prog, _, proc := r.Uint32(), r.Uint32(), r.Uint32()
+		r.Uint32() // cred
+		r.Opaque()
+		r.Uint32() // verf
+		r.Opaque()
+		var w nfs3.Writer
+		w.Uint32(0) // record mark
+		w.Uint32(xid)
+		w.Uint32(1) // REPLY
+		w.Uint32(0) // MSG_ACCEPTED
+		w.Uint32(0) // verf
+		w.Opaque(nil)
+		w.Uint32(0) // SUCCESS
+		s.mu.Lock()
+		if prog == 100005 {
+			// MNT
+			w.Uint32(nfs3.StatusOK)
+			w.Opaque(handle(s.nodes[1]))
+			w.Uint32(1)
+			w.Uint32(1)
+		} else {
+			s.dispatch(proc, r, &w)
+		}
+		s.mu.Unlock()
+		reply := w.Bytes()
+		binary.BigEndian.PutUint32(reply, 0x80000000|uint32(len(reply)-4))
+		if _, err := conn.Write(reply); err != nil {
+			return
+		}
+	}
+}
+
+func writeTime(w *nfs3.Writer, t time.Time) {
+	w.Uint32(uint32(t.Unix()))
+	w.Uint32(uint32(t.Nanosecond()))
+}
+
+func readTime(r *nfs3.Reader) time.Time {
+	sec := r.Uint32()
+	return time.Unix(int64(sec), int64(r.Uint32()))
+}
+
+func writeAttr(w *nfs3.Writer, n *fakeNode) {
+	if n.dir {
+		w.Uint32(nfs3.TypeDirectory)
+	} else {
+		w.Uint32(nfs3.TypeRegular)
+	}
+	w.Uint32(n.mode)
+	w.Uint32(1) // nlink
+	w.Uint32(0) // uid
+	w.Uint32(0) // gid
+	w.Uint64(uint64(len(n.data)))
+	w.Uint64(uint64(len(n.data)))
+	w.Uint64(0) // rdev
+	w.Uint64(1) // fsid
+	w.Uint64(n.id)
+	writeTime(w, n.modTime)
+	writeTime(w, n.modTime)
+	writeTime(w, n.modTime)
+}
+
+// readSetAttr reads a sattr3 and applies it to n if not nil
+func readSetAttr(r *nfs3.Reader, n *fakeNode) {
+	var mode *uint32
+	var size *uint64
+	var modTime *time.Time
+	if r.Bool() {
+		x := r.Uint32()
+		mode = &x
+	}
+	if r.Bool() {
+		r.Uint32() // uid
+	}
+	if r.Bool() {
+		r.Uint32() // gid
+	}
+	if r.Bool() {
+		x := r.Uint64()
+		size = &x
+	}
+	if r.Uint32() == 2 {
+		readTime(r) // atime
+	}
+	if r.Uint32() == 2 {
+		x := readTime(r)
+		modTime = &x
+	}
+	if n == nil {
+		return
+	}
+	if mode != nil {
+		n.mode = *mode
+	}
+	if size != nil && !n.dir {
+		data := make([]byte, *size)
+		copy(data, n.data)
+		n.data = data
+	}
+	if modTime != nil {
+		n.modTime = *modTime
+	}
+}
+
+func writeWcc(w *nfs3.Writer) {
+	w.Bool(false)
+	w.Bool(false)
+}
+
+func (s *fakeServer) node(r *nfs3.Reader) (*fakeNode, uint32) {
+	fh := r.Opaque()
+	if len(fh) != 8 {
+		return nil, nfs3.StatusBadHandle
+	}
+	n, ok := s.nodes[binary.BigEndian.Uint64(fh)]
+	if !ok {
+		return nil, nfs3.StatusStale
+	}
+	return n, nfs3.StatusOK
+}
+
+func (s *fakeServer) dirOp(r *nfs3.Reader) (dir *fakeNode, name string, child *fakeNode, status uint32) {
+	dir, status = s.node(r)
+	name = r.Str()
+	if status != nfs3.StatusOK {
+		return nil, name, nil, status
+	}
+	if !dir.dir {
+		return nil, name, nil, nfs3.StatusNotDir
+	}
+	if id, ok := dir.children[name]; ok {
+		child = s.nodes[id]
+	}
+	return dir, name, child, nfs3.StatusOK
+}
+
+func (s *fakeServer) dispatch(proc uint32, r *nfs3.Reader, w *nfs3.Writer) {
+	switch proc {
+	case 19: // FSINFO
+		w.Uint32(nfs3.StatusOK)
+		w.Bool(false)
+		for _, x := range []uint32{65536, 1000, 1, 65536, 1000, 1, 4096} {
+			w.Uint32(x)
+		}
+		w.Uint64(1 << 40)
+		w.Uint32(0)
+		w.Uint32(1000) // time_delta 1us
+		w.Uint32(0)
+	case 1: // GETATTR
+		n, status := s.node(r)
+		w.Uint32(status)
+		if status == nfs3.StatusOK {
+			writeAttr(w, n)
+		}
+	case 2: // SETATTR
+		n, status := s.node(r)
+		readSetAttr(r, n)
+		w.Uint32(status)
+		writeWcc(w)
+	case 3: // LOOKUP
+		_, _, child, status := s.dirOp(r)
+		if status == nfs3.StatusOK && child == nil {
+			status = nfs3.StatusNoEnt
+		}
+		w.Uint32(status)
+		if status == nfs3.StatusOK {
+			w.Opaque(handle(child))
+			w.Bool(true)
+			writeAttr(w, child)
+		}
+		w.Bool(false)
+	case 6: // READ
+		n, status := s.node(r)
+		offset, count := r.Uint64(), r.Uint32()
+		w.Uint32(status)
+		w.Bool(false)
+		if status == nfs3.StatusOK {
+			data := []byte{}
+			if offset < uint64(len(n.data)) {
+				data = n.data[offset:]
+			}
+			if uint64(len(data)) > uint64(count) {
+				data = data[:count]
+			}
+			w.Uint32(uint32(len(data)))
+			w.Bool(offset+uint64(len(data)) >= uint64(len(n.data)))
+			w.Opaque(data)
+		}
+	case 7: // WRITE
+		n, status := s.node(r)
+		offset := r.Uint64()
+		r.Uint32() // count
+		r.Uint32() // stable
+		data := r.Opaque()
+		if status == nfs3.StatusOK {
+			if end := offset + uint64(len(data)); end > uint64(len(n.data)) {
+				n.data = append(n.data, make([]byte, end-uint64(len(n.data)))...)
+			}
+			copy(n.data[offset:], data)
+			n.modTime = time.Now()
+		}
+		w.Uint32(status)
+		writeWcc(w)
+		if status == nfs3.StatusOK {
+			w.Uint32(uint32(len(data)))
+			w.Uint32(2)
+			w.Fixed(make([]byte, 8))
+		}
+	case 8, 9: // CREATE, MKDIR
+		dir, name, child, status := s.dirOp(r)
+		if proc == 8 {
+			r.Uint32() // createhow
+		}
+		if status == nfs3.StatusOK {
+			switch {
+			case child != nil && (proc == 9 || child.dir):
+				status = nfs3.StatusExist
+			case child == nil:
+				child = s.newNode(proc == 9)
+				dir.children[name] = child.id
+			}
+		}
+		if status == nfs3.StatusOK {
+			readSetAttr(r, child)
+		}
+		w.Uint32(status)
+		if status == nfs3.StatusOK {
+			w.Bool(true)
+			w.Opaque(handle(child))
+			w.Bool(false)
+		}
+		writeWcc(w)
+	case 12, 13: // REMOVE, RMDIR
+		dir, name, child, status := s.dirOp(r)
+		switch {
+		case status != nfs3.StatusOK:
+		case child == nil:
+			status = nfs3.StatusNoEnt
+		case proc == 12 && child.dir:
+			status = nfs3.StatusIsDir
+		case proc == 13 && !child.dir:
+			status = nfs3.StatusNotDir
+		case proc == 13 && len(child.children) > 0:
+			status = nfs3.StatusNotEmpty
+		default:
+			delete(dir.children, name)
+			delete(s.nodes, child.id)
+		}
+		w.Uint32(status)
+		writeWcc(w)
+	case 14: // RENAME
+		fromDir, fromName, from, status := s.dirOp(r)
+		toDir, toName, to, toStatus := s.dirOp(r)
+		if status == nfs3.StatusOK {
+			status = toStatus
+		}
+		switch {
+		case status != nfs3.StatusOK:
+		case from == nil:
+			status = nfs3.StatusNoEnt
+		case to != nil && to.dir && len(to.children) > 0:
+			status = nfs3.StatusNotEmpty
+		default:
+			if to != nil {
+				delete(s.nodes, to.id)
+			}
+			delete(fromDir.children, fromName)
+			toDir.children[toName] = from.id
+		}
+		w.Uint32(status)
+		writeWcc(w)
+		writeWcc(w)
+	case 17: // READDIRPLUS
+		dir, status := s.node(r)
+		cookie := r.Uint64()
+		if status == nfs3.StatusOK && !dir.dir {
+			status = nfs3.StatusNotDir
+		}
+		w.Uint32(status)
+		w.Bool(false)
+		if status != nfs3.StatusOK {
+			return
+		}
+		var names []string
+		for name := range dir.children {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		names = append([]string{".", ".."}, names...)
+		w.Fixed(make([]byte, 8))
+		// return 3 entries at a time to exercise the cookies
+		i := int(cookie)
+		for ; i < len(names) && i < int(cookie)+3; i++ {
+			w.Bool(true)
+			w.Uint64(uint64(i))
+			w.Str(names[i])
+			w.Uint64(uint64(i + 1))
+			child := dir
+			if id, ok := dir.children[names[i]]; ok {
+				child = s.nodes[id]
+			}
+			// leave out the attributes of every other entry
+			w.Bool(i%2 == 0)
+			if i%2 == 0 {
+				writeAttr(w, child)
+			}
+			w.Bool(i%2 == 0)
+			if i%2 == 0 {
+				w.Opaque(handle(child))
+			}
+		}
+		w.Bool(false)
+		w.Bool(i >= len(names))
+	default:
+		w.Uint32(nfs3.StatusNotSupp)
+	}
+}
+
+func newTestFs(t *testing.T, root string) (*Fs, *fakeServer) {
+	s := newFakeServer(t)
+	config.LoadConfig()
+	config.FileSet("TestNfsInternal", "type", "nfs")
+	config.FileSet("TestNfsInternal", "host", "127.0.0.1")
+	config.FileSet("TestNfsInternal", "export", "/export")
+	config.FileSet("TestNfsInternal", "port", strconv.Itoa(s.port))
+	config.FileSet("TestNfsInternal", "mount_port", strconv.Itoa(s.port))
+	// each fake server needs its own directory cache
+	dirCachesMu.Lock()
+	delete(dirCaches, "127.0.0.1:/export")
+	dirCachesMu.Unlock()
+	f, err := NewFs("TestNfsInternal", root)
+	require.NoError(t, err)
+	return f.(*Fs), s
+}
+
+func TestPutOpenList(t *testing.T) {
+	f, s := newTestFs(t, "root")
+	defer func() { _ = s.ln.Close() }()
+
+	assert.Equal(t, time.Microsecond, f.Precision())
+
+	// bigger than the write size so it takes several writes
+	contents := string(bytes.Repeat([]byte("0123456789"), 250))
+	modTime := time.Date(2018, 6, 10, 12, 0, 0, 123456000, time.UTC)
+	o := fstest.PutContents(t, f, "dir/file.txt", contents, modTime)
+	assert.Equal(t, int64(len(contents)), o.Size())
+	assert.True(t, modTime.Equal(o.ModTime()))
+
+	for i := 0; i < 5; i++ {
+		fstest.PutContents(t, f, "dir/sub"+strconv.Itoa(i)+"/file", "x", modTime)
+	}
+	assert.Equal(t, []string{"dir/"}, fstest.ListNames(t, f, ""))
+	assert.Equal(t, []string{"dir/file.txt", "dir/sub0/", "dir/sub1/", "dir/sub2/", "dir/sub3/", "dir/sub4/"}, fstest.ListNames(t, f, "dir"))
+	_, err := f.List("dir/file.txt")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+	_, err = f.List("missing")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+
+	assert.Equal(t, contents, fstest.ReadObject(t, o))
+
+	assert.Equal(t, "5678901234", fstest.ReadObject(t, o, &fs.RangeOption{Start: 1995, End: 2004}))
+
+	// A file root gives fs.ErrorIsFile
+	_, err = NewFs("TestNfsInternal", "root/dir/file.txt")
+	assert.Equal(t, fs.ErrorIsFile, err)
+}
+
+func TestMoveAndRemove(t *testing.T) {
+	f, s := newTestFs(t, "")
+	defer func() { _ = s.ln.Close() }()
+
+	o := fstest.PutContents(t, f, "a/one", "one", time.Now())
+	fstest.PutContents(t, f, "b/two", "two", time.Now())
+
+	// Move overwrites the existing file
+	o, err := operations.Move(f, nil, "b/two", o)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b/two"}, fstest.ListNames(t, f, "b"))
+	assert.Equal(t, int64(3), o.Size())
+	_, err = f.NewObject("a/one")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	require.NoError(t, f.DirMove(f, "b", "c/d"))
+	assert.Equal(t, []string{"c/d/two"}, fstest.ListNames(t, f, "c/d"))
+	assert.Equal(t, fs.ErrorDirExists, f.DirMove(f, "a", "c"))
+
+	assert.Equal(t, fs.ErrorDirectoryNotEmpty, f.Rmdir("c"))
+	assert.Equal(t, fs.ErrorDirNotFound, f.Rmdir("missing"))
+	require.NoError(t, f.Rmdir("a"))
+}
+
+func TestStaleHandle(t *testing.T) {
+	f, s := newTestFs(t, "")
+	defer func() { _ = s.ln.Close() }()
+
+	fstest.PutContents(t, f, "dir/one", "one", time.Now())
+
+	// Replace dir behind rclone's back so the cached handle is stale
+	s.mu.Lock()
+	root := s.nodes[1]
+	delete(s.nodes, root.children["dir"])
+	dir := s.newNode(true)
+	root.children["dir"] = dir.id
+	s.mu.Unlock()
+
+	fstest.PutContents(t, f, "dir/two", "two", time.Now())
+	assert.Equal(t, []string{"dir/two"}, fstest.ListNames(t, f, "dir"))
+}
@@ -223,6 +223,10 @@ func init() {
 		}, {
 			Name: config.ConfigClientSecret,
 			Help: "Microsoft App Client Secret - leave blank normally.",
+		}, {
+			Name: configResourceURL,
+			Help: "The resource URL of a OneDrive for Business account - set by the config wizard.",
+			Hide: true,
 		}},
 	})
 
@@ -250,6 +254,7 @@ type Object struct {
 	hasMetaData bool      // whether info below has been set
 	size        int64     // size of the object
 	modTime     time.Time // modification time of the object
+	createdTime time.Time // time the object was created in OneDrive
 	id          string    // ID of the object
 	sha1        string    // SHA-1 of the object content
 	mimeType    string    // Content-Type of object from server (may not be as uploaded)
@@ -922,6 +927,148 @@ func (f *Fs) DirCacheFlush() {
 	f.dirCache.ResetRoot()
 }
 
+// About gets quota information
+func (f *Fs) About() (usage *fs.Usage, err error) {
+	var drive api.Drive
+	opts := rest.Opts{
+		Method: "GET",
+		Path:   "",
+	}
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(&opts, nil, &drive)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "about failed")
+	}
+	q := drive.Quota
+	usage = &fs.Usage{
+		Total:   fs.NewUsageValue(q.Total),     // quota of bytes that can be used
+		Used:    fs.NewUsageValue(q.Used),      // bytes in use
+		Trashed: fs.NewUsageValue(q.Deleted),   // bytes in trash
+		Free:    fs.NewUsageValue(q.Remaining), // bytes which can be uploaded before reaching the quota
+	}
+	return usage, nil
+}
+
+// ChangeNotify calls the passed function with a path that has had changes.
+// If the implementation uses polling, it should adhere to the given interval.
+//
+// Automatically restarts itself in case of unexpected behaviour of the remote.
+//
+// Close the returned channel to stop being notified.
+func (f *Fs) ChangeNotify(notifyFunc func(string, fs.EntryType), pollInterval time.Duration) chan bool {
+	quit := make(chan bool)
+	go func() {
+		for {
+			err := f.changeNotifyRunner(notifyFunc, pollInterval, quit)
+			if err == nil {
+				return
+			}
+			fs.Debugf(f, "Notify listener service ran into issues, restarting shortly: %v", err)
+			select {
+			case <-quit:
+				return
+			case <-time.After(pollInterval):
+			}
+		}
+	}()
+	return quit
+}
+
+// changeNotifyRunner polls the delta API for changes every
+// pollInterval until quit is closed, when it returns nil, or there is
+// an error
+func (f *Fs) changeNotifyRunner(notifyFunc func(string, fs.EntryType), pollInterval time.Duration, quit chan bool) error {
+	// Ask for a link to read the changes from now on
+	deltaLink, err := f.changeNotifyDelta(&rest.Opts{
+		Method: "GET",
+		Path:   "/root/delta?token=latest",
+	}, nil)
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-quit:
+			return nil
+		case <-time.After(pollInterval):
+		}
+		fs.Debugf(f, "Checking for changes on remote")
+		deltaLink, err = f.changeNotifyDelta(&rest.Opts{
+			Method:  "GET",
+			RootURL: deltaLink,
+		}, notifyFunc)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// changeNotifyDelta reads all the pages of changes starting with opts
+// calling notifyFunc, if set, with the paths which have changed.
+//
+// It returns the link to read the next changes from.
+func (f *Fs) changeNotifyDelta(opts *rest.Opts, notifyFunc func(string, fs.EntryType)) (deltaLink string, err error) {
+	for {
+		var result api.ViewDeltaResponse
+		var resp *http.Response
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err = f.srv.CallJSON(opts, nil, &result)
+			return shouldRetry(resp, err)
+		})
+		if err != nil {
+			return "", errors.Wrap(err, "couldn't read changes")
+		}
+		if notifyFunc != nil {
+			for i := range result.Value {
+				f.changeNotifyItem(&result.Value[i], notifyFunc)
+			}
+		}
+		if result.NextLink == "" {
+			if result.DeltaLink == "" {
+				return "", errors.New("no delta link in changes")
+			}
+			return result.DeltaLink, nil
+		}
+		opts = &rest.Opts{
+			Method:  "GET",
+			RootURL: result.NextLink,
+		}
+	}
+}
+
+// changeNotifyItem calls notifyFunc with the paths of the changed
+// item if it is in a directory which has been seen.
+//
+// The paths are notified as objects so the directories containing
+// them are forgotten.
+func (f *Fs) changeNotifyItem(item *api.Item, notifyFunc func(string, fs.EntryType)) {
+	newPath, newOK := "", false
+	if item.ParentReference != nil && item.Name != "" {
+		var parentPath string
+		parentPath, newOK = f.dirCache.GetInv(item.ParentReference.ID)
+		newPath = path.Join(parentPath, restoreReservedChars(item.Name))
+	}
+	oldPath, oldOK := f.dirCache.GetInv(item.ID)
+	if item.Folder != nil && item.Deleted == nil {
+		// Folders are in the changes whenever anything in them
+		// changes.  The changed contents are notified by
+		// themselves so only notify folders which are new or
+		// have moved.
+		if !newOK || (oldOK && oldPath == newPath) {
+			return
+		}
+	}
+	if oldOK {
+		notifyFunc(oldPath, fs.EntryObject)
+	}
+	if newOK && (!oldOK || newPath != oldPath) {
+		notifyFunc(newPath, fs.EntryObject)
+	}
+}
+
 // Hashes returns the supported hash sets.
 func (f *Fs) Hashes() hash.Set {
 	return hash.Set(hash.SHA1)
@@ -996,6 +1143,7 @@ func (o *Object) setMetaData(info *api.Item) (err error) {
 	} else {
 		o.modTime = time.Time(info.LastModifiedDateTime)
 	}
+	o.createdTime = time.Time(info.CreatedDateTime)
 	o.id = info.ID
 	return nil
 }
@@ -1053,6 +1201,19 @@ func (o *Object) setModTime(modTime time.Time) (*api.Item, error) {
 	return info, err
 }
 
+// CreatedTime returns the time the object was created in OneDrive
+//
+// This is the time the item was made on the server, not the
+// createdDateTime rclone sets in the file system info on upload.
+func (o *Object) CreatedTime() time.Time {
+	err := o.readMetaData()
+	if err != nil {
+		fs.Logf(o, "Failed to read metadata: %v", err)
+		return time.Time{}
+	}
+	return o.createdTime
+}
+
 // SetModTime sets the modification time of the local fs object
 func (o *Object) SetModTime(modTime time.Time) error {
 	info, err := o.setModTime(modTime)
@@ -1271,6 +1432,9 @@ var (
 	_ fs.Mover  = (*Fs)(nil)
 	// _ fs.DirMover = (*Fs)(nil)
 	_ fs.DirCacheFlusher = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.ChangeNotifier  = (*Fs)(nil)
 	_ fs.Object          = (*Object)(nil)
 	_ fs.MimeTyper       = &Object{}
+	_ fs.CreatedTimer    = &Object{}
 )
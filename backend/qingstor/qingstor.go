@@ -1,7 +1,7 @@
 // Package qingstor provides an interface to QingStor object storage
 // Home: https://www.qingcloud.com/
 
-// +build !plan9,go1.7
+// +build !plan9,!js,go1.7
 
 package qingstor
 
@@ -35,6 +35,7 @@ func init() {
 		NewFs:       NewFs,
 		Options: []fs.Option{{
 			Name: "env_auth",
+			Type: fs.OptionTypeBool,
 			Help: "Get QingStor credentials from runtime. Only applies if access_key_id and secret_access_key is blank.",
 			Examples: []fs.OptionExample{
 				{
@@ -217,7 +218,7 @@ func qsServiceConnection(name string) (*qs.Service, error) {
 	cf.Host = host
 	cf.Port = port
 	cf.ConnectionRetries = connectionRetries
-	cf.Connection = fshttp.NewClient(fs.Config)
+	cf.Connection = fshttp.NewClient(fs.ConfigForRemote(name))
 
 	svc, _ := qs.Init(cf)
 
@@ -0,0 +1,171 @@
+// Server side copies of large objects in parts
+
+package s3
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultCopyChunkSize   = fs.SizeSuffix(1024 * 1024 * 1024) // default size of the parts of multipart copies
+	defaultCopyConcurrency = 4                                 // default number of parts to copy at once
+)
+
+// copyPartSize returns the size of the parts to copy an object of
+// size bytes in so that there are few enough of them
+func copyPartSize(size int64, chunkSize fs.SizeSuffix) int64 {
+	partSize := int64(chunkSize)
+	if size/partSize >= s3manager.MaxUploadParts {
+		// Calculate partition size rounded up to the nearest MB
+		partSize = (((size / s3manager.MaxUploadParts) >> 20) + 1) << 20
+	}
+	return partSize
+}
+
+// copyMultipart copies srcObj to key in parts with UploadPartCopy.
+//
+// This is needed for objects bigger than 5GB which CopyObject can't
+// copy and is quicker for large objects as the parts are copied in
+// parallel.
+func (f *Fs) copyMultipart(srcObj *Object, key, source string) error {
+	err := srcObj.readMetaData()
+	if err != nil {
+		return err
+	}
+	size := srcObj.bytes
+
+	// Copy the metadata, adding the MD5 if it is only known from the
+	// ETag as the copy will have a multipart ETag
+	metadata := make(map[string]*string, len(srcObj.meta)+1)
+	for k, v := range srcObj.meta {
+		metadata[k] = v
+	}
+	if _, ok := metadata[metaMD5Hash]; !ok {
+		if md5sum, err := srcObj.md5(); err == nil && md5sum != "" {
+			md5Bytes, err := hex.DecodeString(md5sum)
+			if err == nil {
+				metadata[metaMD5Hash] = aws.String(base64.StdEncoding.EncodeToString(md5Bytes))
+			}
+		}
+	}
+
+	req := s3.CreateMultipartUploadInput{
+		Bucket:   &f.bucket,
+		ACL:      f.aclPtr(),
+		Key:      &key,
+		Metadata: metadata,
+	}
+	if srcObj.mimeType != "" {
+		req.ContentType = &srcObj.mimeType
+	}
+	req.ServerSideEncryption, req.SSEKMSKeyId = f.ssePtrs()
+	req.SSECustomerAlgorithm, req.SSECustomerKey = f.sseCustomer()
+	if f.storageClass != "" {
+		req.StorageClass = &f.storageClass
+	}
+	upload, err := f.c.CreateMultipartUpload(&req)
+	if err != nil {
+		return errors.Wrap(err, "multipart copy: failed to start")
+	}
+	uploadID := upload.UploadId
+
+	partSize := copyPartSize(size, f.copyChunkSize)
+	numParts := (size + partSize - 1) / partSize
+	fs.Debugf(srcObj, "Copying in %d parts of %v", numParts, fs.SizeSuffix(partSize))
+	parts := make([]*s3.CompletedPart, numParts)
+
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+		partNums = make(chan int64, numParts)
+	)
+	for partNum := int64(1); partNum <= numParts; partNum++ {
+		partNums <- partNum
+	}
+	close(partNums)
+	concurrency := f.copyConcurrency
+	if int64(concurrency) > numParts {
+		concurrency = int(numParts)
+	}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for partNum := range partNums {
+				errMu.Lock()
+				failed := firstErr != nil
+				errMu.Unlock()
+				if failed {
+					return
+				}
+				start := (partNum - 1) * partSize
+				end := start + partSize - 1
+				if end >= size {
+					end = size - 1
+				}
+				partNumber := partNum
+				partReq := s3.UploadPartCopyInput{
+					Bucket:          &f.bucket,
+					Key:             &key,
+					CopySource:      &source,
+					CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+					PartNumber:      &partNumber,
+					UploadId:        uploadID,
+				}
+				partReq.SSECustomerAlgorithm, partReq.SSECustomerKey = f.sseCustomer()
+				partReq.CopySourceSSECustomerAlgorithm, partReq.CopySourceSSECustomerKey = srcObj.fs.sseCustomer()
+				resp, err := f.c.UploadPartCopy(&partReq)
+				if err == nil && resp.CopyPartResult == nil {
+					err = errors.New("no ETag returned")
+				}
+				if err != nil {
+					errMu.Lock()
+					if firstErr == nil {
+						firstErr = errors.Wrapf(err, "multipart copy: failed to copy part %d", partNum)
+					}
+					errMu.Unlock()
+					return
+				}
+				parts[partNum-1] = &s3.CompletedPart{
+					ETag:       resp.CopyPartResult.ETag,
+					PartNumber: &partNumber,
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr == nil {
+		_, err = f.c.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+			Bucket:          &f.bucket,
+			Key:             &key,
+			MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+			UploadId:        uploadID,
+		})
+		if err == nil {
+			return nil
+		}
+		firstErr = errors.Wrap(err, "multipart copy: failed to finish")
+	}
+
+	// Abort the upload so the parts copied aren't left behind
+	_, err = f.c.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   &f.bucket,
+		Key:      &key,
+		UploadId: uploadID,
+	})
+	if err != nil {
+		fs.Debugf(srcObj, "Failed to abort multipart copy: %v", err)
+	}
+	return firstErr
+}
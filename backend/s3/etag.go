@@ -0,0 +1,98 @@
+// Calculate the ETag of uploads to check them
+
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	gohash "hash"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// etagHasher calculates the MD5 of the data read through it and the
+// ETag S3 gives it when it is uploaded in parts of partSize.
+//
+// The ETag of a multipart upload is the MD5 of the MD5s of the parts
+// followed by "-" and the number of parts.
+type etagHasher struct {
+	in       io.Reader
+	partSize int64
+	md5      gohash.Hash // MD5 of all the data
+	part     gohash.Hash // MD5 of the current part
+	partRead int64       // bytes read into the current part
+	partMD5s []byte      // the MD5s of the finished parts
+	parts    int         // number of finished parts
+}
+
+// newETagHasher returns an etagHasher reading from in
+func newETagHasher(in io.Reader, partSize int64) *etagHasher {
+	return &etagHasher{
+		in:       in,
+		partSize: partSize,
+		md5:      md5.New(),
+		part:     md5.New(),
+	}
+}
+
+// Read reads from the input hashing the data read
+func (h *etagHasher) Read(p []byte) (n int, err error) {
+	n, err = h.in.Read(p)
+	data := p[:n]
+	_, _ = h.md5.Write(data)
+	for len(data) > 0 {
+		chunk := data
+		if remaining := h.partSize - h.partRead; int64(len(chunk)) > remaining {
+			chunk = chunk[:remaining]
+		}
+		_, _ = h.part.Write(chunk)
+		h.partRead += int64(len(chunk))
+		data = data[len(chunk):]
+		if h.partRead == h.partSize {
+			h.endPart()
+		}
+	}
+	return n, err
+}
+
+// endPart finishes the current part
+func (h *etagHasher) endPart() {
+	h.partMD5s = h.part.Sum(h.partMD5s)
+	h.parts++
+	h.part.Reset()
+	h.partRead = 0
+}
+
+// MD5 returns the MD5 of the data read as raw bytes
+func (h *etagHasher) MD5() []byte {
+	return h.md5.Sum(nil)
+}
+
+// MultipartETag returns the ETag of the data read if it was uploaded
+// in parts
+func (h *etagHasher) MultipartETag() string {
+	partMD5s, parts := h.partMD5s, h.parts
+	if h.partRead > 0 || parts == 0 {
+		partMD5s = h.part.Sum(partMD5s)
+		parts++
+	}
+	etag := md5.Sum(partMD5s)
+	return hex.EncodeToString(etag[:]) + "-" + strconv.Itoa(parts)
+}
+
+// Check returns an error if etag, as returned by S3 for an upload of
+// the data read, doesn't match it.
+func (h *etagHasher) Check(etag string) error {
+	etag = strings.Trim(strings.ToLower(etag), `"`)
+	want := hex.EncodeToString(h.MD5())
+	if strings.Contains(etag, "-") {
+		want = h.MultipartETag()
+	}
+	if etag != want {
+		return errors.Errorf("corrupted on transfer: ETag differ %q vs %q", etag, want)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Defaults for the different S3 providers
+
+package s3
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/ncw/rclone/fs"
+)
+
+// minChunkSize is the smallest part S3 allows in a multipart upload
+const minChunkSize = fs.SizeSuffix(s3manager.MinUploadPartSize)
+
+// quirks describes the ways an S3 provider differs from the others
+type quirks struct {
+	listVersion   int           // version of ListObjects to use - 1 or 2
+	useACL        bool          // set if the provider supports canned ACLs
+	chunkSize     fs.SizeSuffix // size of the parts of multipart uploads
+	multipartETag bool          // set if the ETag of a multipart upload is the MD5 of the MD5s of its parts then "-N"
+}
+
+// providerQuirks are the quirks of each provider in the "provider"
+// config setting
+var providerQuirks = map[string]quirks{
+	"AWS": {
+		listVersion:   2,
+		useACL:        true,
+		chunkSize:     minChunkSize,
+		multipartETag: true,
+	},
+	"Alibaba": {
+		listVersion:   1,
+		useACL:        true,
+		chunkSize:     minChunkSize,
+		multipartETag: false,
+	},
+	"Ceph": {
+		listVersion:   1,
+		useACL:        true,
+		chunkSize:     minChunkSize,
+		multipartETag: true,
+	},
+	"DigitalOcean": {
+		listVersion:   1,
+		useACL:        true,
+		chunkSize:     minChunkSize,
+		multipartETag: true,
+	},
+	"Minio": {
+		listVersion:   2,
+		useACL:        false,
+		chunkSize:     minChunkSize,
+		multipartETag: true,
+	},
+	"Wasabi": {
+		listVersion:   2,
+		useACL:        true,
+		chunkSize:     minChunkSize,
+		multipartETag: true,
+	},
+	"Other": {
+		listVersion:   1,
+		useACL:        true,
+		chunkSize:     minChunkSize,
+		multipartETag: false,
+	},
+}
+
+// findQuirks returns the quirks of provider, matched case
+// insensitively.
+//
+// If provider isn't set then AWS is assumed if there is no endpoint
+// and Other if there is.  An unknown provider is treated as Other.
+func findQuirks(name, provider, endpoint string) quirks {
+	if provider == "" {
+		if endpoint == "" {
+			return providerQuirks["AWS"]
+		}
+		return providerQuirks["Other"]
+	}
+	for providerName, q := range providerQuirks {
+		if strings.EqualFold(providerName, provider) {
+			return q
+		}
+	}
+	fs.Logf(name, "Unknown S3 provider %q - using the defaults for Other", provider)
+	return providerQuirks["Other"]
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/config"
 	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/ncw/rclone/fs/config/obscure"
 	"github.com/ncw/rclone/fs/fshttp"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fs/walk"
@@ -51,11 +52,37 @@ import (
 func init() {
 	fs.Register(&fs.RegInfo{
 		Name:        "s3",
-		Description: "Amazon S3 (also Dreamhost, Ceph, Minio, IBM COS)",
+		Description: "Amazon S3 Compliant Storage Providers (AWS, Alibaba, Ceph, Digital Ocean, Minio, Wasabi, etc)",
 		NewFs:       NewFs,
 		// AWS endpoints: http://docs.amazonwebservices.com/general/latest/gr/rande.html#s3_region
 		Options: []fs.Option{{
+			Name: "provider",
+			Help: "Choose your S3 provider.  This sets the defaults for the quirks of each provider.",
+			Examples: []fs.OptionExample{{
+				Value: "AWS",
+				Help:  "Amazon Web Services (AWS) S3",
+			}, {
+				Value: "Alibaba",
+				Help:  "Alibaba Cloud Object Storage System (OSS)",
+			}, {
+				Value: "Ceph",
+				Help:  "Ceph Object Storage",
+			}, {
+				Value: "DigitalOcean",
+				Help:  "Digital Ocean Spaces",
+			}, {
+				Value: "Minio",
+				Help:  "Minio Object Storage",
+			}, {
+				Value: "Wasabi",
+				Help:  "Wasabi Object Storage",
+			}, {
+				Value: "Other",
+				Help:  "Any other S3 compatible provider",
+			}},
+		}, {
 			Name: "env_auth",
+			Type: fs.OptionTypeBool,
 			Help: "Get AWS credentials from runtime (environment variables or EC2/ECS meta data if no env vars). Only applies if access_key_id and secret_access_key is blank.",
 			Examples: []fs.OptionExample{
 				{
@@ -195,6 +222,29 @@ func init() {
 		}, {
 			Name: "server_side_encryption",
 			Help: "The server-side encryption algorithm used when storing this object in S3.",
+			Examples: []fs.OptionExample{{
+				Value: "",
+				Help:  "None",
+			}, {
+				Value: "AES256",
+				Help:  "AES256",
+			}, {
+				Value: "aws:kms",
+				Help:  "aws:kms",
+			}},
+		}, {
+			Name: "sse_kms_key_id",
+			Help: "If using KMS ID you must provide the ARN of Key.",
+			Examples: []fs.OptionExample{{
+				Value: "",
+				Help:  "None",
+			}, {
+				Value: "arn:aws:kms:us-east-1:*",
+				Help:  "arn:aws:kms:*",
+			}},
+		}, {
+			Name: "sse_customer_algorithm",
+			Help: "If using SSE-C, the server-side encryption algorithm used when storing this object in S3.",
 			Examples: []fs.OptionExample{{
 				Value: "",
 				Help:  "None",
@@ -202,6 +252,10 @@ func init() {
 				Value: "AES256",
 				Help:  "AES256",
 			}},
+		}, {
+			Name:       "sse_customer_key",
+			Help:       "If using SSE-C you must provide the secret encryption key, 32 bytes long, used to encrypt/decrypt your data.",
+			IsPassword: true,
 		}, {
 			Name: "storage_class",
 			Help: "The storage class to use when storing objects in S3.",
@@ -217,7 +271,47 @@ func init() {
 			}, {
 				Value: "STANDARD_IA",
 				Help:  "Standard Infrequent Access storage class",
+			}, {
+				Value: "ONEZONE_IA",
+				Help:  "One Zone Infrequent Access storage class",
+			}, {
+				Value: "GLACIER",
+				Help:  "Glacier storage class",
+			}, {
+				Value: "DEEP_ARCHIVE",
+				Help:  "Glacier Deep Archive storage class",
 			}},
+		}, {
+			Name: "requester_pays",
+			Help: "Enables requester pays option when interacting with S3 bucket.",
+			Type: fs.OptionTypeBool,
+			Hide: true,
+		}, {
+			Name: "session_token",
+			Help: "AWS session token for temporary credentials - optional.",
+			Hide: true,
+		}, {
+			Name: "list_version",
+			Help: "Version of ListObjects to use: 1 or 2.  Overrides the default for the provider.",
+			Type: fs.OptionTypeInt,
+			Hide: true,
+		}, {
+			Name: "chunk_size",
+			Help: "Size of the parts of multipart uploads, eg 16M.  Overrides the default for the provider.",
+			Hide: true,
+		}, {
+			Name: "copy_cutoff",
+			Help: "Server side copy objects this size or bigger in parts, eg 1G.  At most 5G which is the default.",
+			Hide: true,
+		}, {
+			Name: "copy_chunk_size",
+			Help: "Size of the parts of multipart server side copies, eg 512M.  Default 1G.",
+			Hide: true,
+		}, {
+			Name: "copy_concurrency",
+			Help: "Number of parts of a multipart server side copy to copy at once.  Default 4.",
+			Type: fs.OptionTypeInt,
+			Hide: true,
 		}},
 	})
 }
@@ -226,6 +320,7 @@ func init() {
 const (
 	metaMtime      = "Mtime"                       // the meta key to store mtime in - eg X-Amz-Meta-Mtime
 	metaMD5Hash    = "Md5chksum"                   // the meta key to store md5hash in
+	metaSHA256Hash = "Sha256chksum"                // the meta key to store the SHA-256 in
 	listChunkSize  = 1000                          // number of items to read at once
 	maxRetries     = 10                            // number of retries to make of operations
 	maxSizeForCopy = 5 * 1024 * 1024 * 1024        // The maximum size of object we can COPY
@@ -234,9 +329,11 @@ const (
 
 // Globals
 var (
-	// Flags
-	s3ACL          = flags.StringP("s3-acl", "", "", "Canned ACL used when creating buckets and/or storing objects in S3")
-	s3StorageClass = flags.StringP("s3-storage-class", "", "", "Storage class to use when uploading S3 objects (STANDARD|REDUCED_REDUNDANCY|STANDARD_IA)")
+	// Flags - these are read with the config as they override the
+	// acl and storage_class config values
+	_ = flags.StringP("s3-acl", "", "", "Canned ACL used when creating buckets and/or storing objects in S3")
+	_ = flags.StringP("s3-storage-class", "", "", "Storage class to use when uploading S3 objects (STANDARD|REDUCED_REDUNDANCY|STANDARD_IA|ONEZONE_IA|GLACIER|DEEP_ARCHIVE)")
+	_ = flags.BoolP("s3-requester-pays", "", false, "Pay for requests to buckets with requester pays turned on")
 )
 
 // Fs represents a remote s3 server
@@ -253,7 +350,14 @@ type Fs struct {
 	acl                string           // ACL for new buckets / objects
 	locationConstraint string           // location constraint of new buckets
 	sse                string           // the type of server-side encryption
+	sseKMSKeyID        string           // the KMS key to encrypt with if sse is aws:kms
+	sseCustomerAlgo    string           // the algorithm for SSE-C or "" if not using it
+	sseCustomerKey     string           // the key for SSE-C
 	storageClass       string           // storage class
+	quirks             quirks           // how the provider differs from the others
+	copyCutoff         fs.SizeSuffix    // copy objects this size or bigger in parts
+	copyChunkSize      fs.SizeSuffix    // size of the parts of multipart copies
+	copyConcurrency    int              // number of parts to copy at once
 }
 
 // Object describes a s3 object
@@ -269,6 +373,8 @@ type Object struct {
 	lastModified time.Time          // Last modified
 	meta         map[string]*string // The object metadata if known - may be nil
 	mimeType     string             // MimeType of object - may be ""
+	storageClass string             // storage class of the object - may be "" for STANDARD
+	restore      string             // the x-amz-restore header - "" if not being restored
 }
 
 // ------------------------------------------------------------
@@ -374,7 +480,7 @@ func s3Connection(name string) (*s3.S3, *session.Session, error) {
 		WithMaxRetries(maxRetries).
 		WithCredentials(cred).
 		WithEndpoint(endpoint).
-		WithHTTPClient(fshttp.NewClient(fs.Config)).
+		WithHTTPClient(fshttp.NewClient(fs.ConfigForRemote(name))).
 		WithS3ForcePathStyle(true)
 	// awsConfig.WithLogLevel(aws.LogDebugWithSigning)
 	ses := session.New()
@@ -392,6 +498,11 @@ func s3Connection(name string) (*s3.S3, *session.Session, error) {
 		c.Handlers.Sign.PushBackNamed(corehandlers.BuildContentLengthHandler)
 		c.Handlers.Sign.PushBack(signer)
 	}
+	if config.FileGetBool(name, "requester_pays", false) {
+		c.Handlers.Build.PushBack(func(req *request.Request) {
+			req.HTTPRequest.Header.Set("X-Amz-Request-Payer", "requester")
+		})
+	}
 	return c, ses, nil
 }
 
@@ -414,18 +525,74 @@ func NewFs(name, root string) (fs.Fs, error) {
 		root:               directory,
 		locationConstraint: config.FileGet(name, "location_constraint"),
 		sse:                config.FileGet(name, "server_side_encryption"),
+		sseKMSKeyID:        config.FileGet(name, "sse_kms_key_id"),
+		sseCustomerAlgo:    config.FileGet(name, "sse_customer_algorithm"),
 		storageClass:       config.FileGet(name, "storage_class"),
+		copyCutoff:         maxSizeForCopy,
+		copyChunkSize:      defaultCopyChunkSize,
+		copyConcurrency:    config.FileGetInt(name, "copy_concurrency", defaultCopyConcurrency),
 	}
 	f.features = (&fs.Features{
 		ReadMimeType:  true,
 		WriteMimeType: true,
 		BucketBased:   true,
 	}).Fill(f)
-	if *s3ACL != "" {
-		f.acl = *s3ACL
+	f.quirks = findQuirks(name, config.FileGet(name, "provider"), config.FileGet(name, "endpoint"))
+	if listVersion := config.FileGet(name, "list_version"); listVersion != "" {
+		f.quirks.listVersion = config.FileGetInt(name, "list_version", f.quirks.listVersion)
+		if f.quirks.listVersion != 1 && f.quirks.listVersion != 2 {
+			return nil, errors.Errorf("list_version must be 1 or 2, not %q", listVersion)
+		}
+	}
+	if chunkSize := config.FileGet(name, "chunk_size"); chunkSize != "" {
+		err = f.quirks.chunkSize.Set(chunkSize)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse chunk_size")
+		}
+		if f.quirks.chunkSize < minChunkSize {
+			return nil, errors.Errorf("chunk_size must be at least %v", minChunkSize)
+		}
+	}
+	if copyCutoff := config.FileGet(name, "copy_cutoff"); copyCutoff != "" {
+		err = f.copyCutoff.Set(copyCutoff)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse copy_cutoff")
+		}
+		if f.copyCutoff > maxSizeForCopy {
+			return nil, errors.Errorf("copy_cutoff must be at most %v", fs.SizeSuffix(maxSizeForCopy))
+		}
+	}
+	if copyChunkSize := config.FileGet(name, "copy_chunk_size"); copyChunkSize != "" {
+		err = f.copyChunkSize.Set(copyChunkSize)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse copy_chunk_size")
+		}
+		if f.copyChunkSize < minChunkSize || f.copyChunkSize > maxSizeForCopy {
+			return nil, errors.Errorf("copy_chunk_size must be between %v and %v", minChunkSize, fs.SizeSuffix(maxSizeForCopy))
+		}
+	}
+	if f.copyConcurrency < 1 {
+		return nil, errors.Errorf("copy_concurrency must be at least 1, not %d", f.copyConcurrency)
+	}
+	if f.sseKMSKeyID != "" && f.sse != "aws:kms" {
+		return nil, errors.New("sse_kms_key_id needs server_side_encryption set to aws:kms")
 	}
-	if *s3StorageClass != "" {
-		f.storageClass = *s3StorageClass
+	if f.sseCustomerKey, err = obscure.Reveal(config.FileGet(name, "sse_customer_key")); err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt sse_customer_key")
+	}
+	if f.sseCustomerAlgo != "" {
+		if f.sse != "" {
+			return nil, errors.New("server_side_encryption can't be used with sse_customer_algorithm")
+		}
+		if len(f.sseCustomerKey) != 32 {
+			return nil, errors.Errorf("sse_customer_key must be 32 bytes long, not %d", len(f.sseCustomerKey))
+		}
+	} else if f.sseCustomerKey != "" {
+		return nil, errors.New("sse_customer_key needs sse_customer_algorithm to be set")
+	}
+	if !f.quirks.useACL && f.acl != "" {
+		fs.Logf(f, "Ignoring acl %q as the provider doesn't support canned ACLs", f.acl)
+		f.acl = ""
 	}
 	if f.root != "" {
 		f.root += "/"
@@ -434,6 +601,7 @@ func NewFs(name, root string) (fs.Fs, error) {
 			Bucket: &f.bucket,
 			Key:    &directory,
 		}
+		req.SSECustomerAlgorithm, req.SSECustomerKey = f.sseCustomer()
 		_, err = f.c.HeadObject(&req)
 		if err == nil {
 			f.root = path.Dir(directory)
@@ -468,6 +636,7 @@ func (f *Fs) newObjectWithInfo(remote string, info *s3.Object) (fs.Object, error
 		}
 		o.etag = aws.StringValue(info.ETag)
 		o.bytes = aws.Int64Value(info.Size)
+		o.storageClass = aws.StringValue(info.StorageClass)
 	} else {
 		err := o.readMetaData() // reads info and meta, returning an error
 		if err != nil {
@@ -504,14 +673,13 @@ func (f *Fs) list(dir string, recurse bool, fn listFn) error {
 	var marker *string
 	for {
 		// FIXME need to implement ALL loop
-		req := s3.ListObjectsInput{
+		resp, err := f.listObjects(&s3.ListObjectsInput{
 			Bucket:    &f.bucket,
 			Delimiter: &delimiter,
 			Prefix:    &root,
 			MaxKeys:   &maxKeys,
 			Marker:    marker,
-		}
-		resp, err := f.c.ListObjects(&req)
+		})
 		if err != nil {
 			if awsErr, ok := err.(awserr.RequestFailure); ok {
 				if awsErr.StatusCode() == http.StatusNotFound {
@@ -559,6 +727,9 @@ func (f *Fs) list(dir string, recurse bool, fn listFn) error {
 		}
 		// Use NextMarker if set, otherwise use last Key
 		if resp.NextMarker == nil || *resp.NextMarker == "" {
+			if f.quirks.listVersion == 2 {
+				return errors.New("s3 protocol error: received listing v2 with IsTruncated set and no NextContinuationToken")
+			}
 			if len(resp.Contents) == 0 {
 				return errors.New("s3 protocol error: received listing with IsTruncated set, no NextMarker and no Contents")
 			}
@@ -570,6 +741,70 @@ func (f *Fs) list(dir string, recurse bool, fn listFn) error {
 	return nil
 }
 
+// listObjects lists the objects with ListObjects or ListObjectsV2
+// depending on the provider.
+//
+// For ListObjectsV2 the Marker of req is used as the continuation
+// token and the next continuation token is returned as the
+// NextMarker.
+func (f *Fs) listObjects(req *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
+	if f.quirks.listVersion != 2 {
+		return f.c.ListObjects(req)
+	}
+	resp, err := f.c.ListObjectsV2(&s3.ListObjectsV2Input{
+		Bucket:            req.Bucket,
+		Delimiter:         req.Delimiter,
+		Prefix:            req.Prefix,
+		MaxKeys:           req.MaxKeys,
+		ContinuationToken: req.Marker,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s3.ListObjectsOutput{
+		CommonPrefixes: resp.CommonPrefixes,
+		Contents:       resp.Contents,
+		IsTruncated:    resp.IsTruncated,
+		NextMarker:     resp.NextContinuationToken,
+	}, nil
+}
+
+// aclPtr returns the ACL to use in requests or nil to use the default
+func (f *Fs) aclPtr() *string {
+	if f.acl == "" {
+		return nil
+	}
+	return &f.acl
+}
+
+// ssePtrs returns the server side encryption and the KMS key ID to
+// set on new objects, nil if not set
+func (f *Fs) ssePtrs() (sse, kmsKeyID *string) {
+	if f.sse != "" {
+		sse = &f.sse
+	}
+	if f.sseKMSKeyID != "" {
+		kmsKeyID = &f.sseKMSKeyID
+	}
+	return sse, kmsKeyID
+}
+
+// sseCustomer returns the algorithm and key which must be sent with
+// every request to read or write objects if SSE-C is in use, nil if
+// it isn't
+func (f *Fs) sseCustomer() (algorithm, key *string) {
+	if f.sseCustomerAlgo == "" {
+		return nil, nil
+	}
+	return &f.sseCustomerAlgo, &f.sseCustomerKey
+}
+
+// etagIsMD5 returns true if the ETag of objects uploaded in one part
+// is their MD5, which isn't the case with SSE-KMS or SSE-C
+func (f *Fs) etagIsMD5() bool {
+	return f.sse != "aws:kms" && f.sseCustomerAlgo == ""
+}
+
 // Convert a list item into a DirEntry
 func (f *Fs) itemToDirEntry(remote string, object *s3.Object, isDirectory bool) (fs.DirEntry, error) {
 	if isDirectory {
@@ -739,7 +974,7 @@ func (f *Fs) Mkdir(dir string) error {
 	}
 	req := s3.CreateBucketInput{
 		Bucket: &f.bucket,
-		ACL:    &f.acl,
+		ACL:    f.aclPtr(),
 	}
 	if f.locationConstraint != "" {
 		req.CreateBucketConfiguration = &s3.CreateBucketConfiguration{
@@ -812,12 +1047,22 @@ func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
 	srcFs := srcObj.fs
 	key := f.root + remote
 	source := pathEscape(srcFs.bucket + "/" + srcFs.root + srcObj.remote)
+	if srcObj.bytes > 0 && srcObj.bytes >= int64(f.copyCutoff) {
+		err = f.copyMultipart(srcObj, key, source)
+		if err != nil {
+			return nil, err
+		}
+		return f.NewObject(remote)
+	}
 	req := s3.CopyObjectInput{
 		Bucket:            &f.bucket,
 		Key:               &key,
 		CopySource:        &source,
 		MetadataDirective: aws.String(s3.MetadataDirectiveCopy),
 	}
+	req.ServerSideEncryption, req.SSEKMSKeyId = f.ssePtrs()
+	req.SSECustomerAlgorithm, req.SSECustomerKey = f.sseCustomer()
+	req.CopySourceSSECustomerAlgorithm, req.CopySourceSSECustomerKey = srcFs.sseCustomer()
 	_, err = f.c.CopyObject(&req)
 	if err != nil {
 		return nil, err
@@ -827,7 +1072,7 @@ func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
 
 // Hashes returns the supported hash sets.
 func (f *Fs) Hashes() hash.Set {
-	return hash.Set(hash.MD5)
+	return hash.NewHashSet(hash.MD5, hash.SHA256)
 }
 
 // ------------------------------------------------------------
@@ -852,32 +1097,47 @@ func (o *Object) Remote() string {
 
 var matchMd5 = regexp.MustCompile(`^[0-9a-f]{32}$`)
 
-// Hash returns the Md5sum of an object returning a lowercase hex string
+// Hash returns the MD5 or SHA-256 of an object returning a lowercase
+// hex string
 func (o *Object) Hash(t hash.Type) (string, error) {
-	if t != hash.MD5 {
-		return "", hash.ErrUnsupported
+	switch t {
+	case hash.MD5:
+		return o.md5()
+	case hash.SHA256:
+		return o.metaHash(metaSHA256Hash)
 	}
+	return "", hash.ErrUnsupported
+}
+
+// md5 returns the MD5 of the object from the ETag, or from the
+// metadata if the ETag isn't an MD5, eg for multipart uploads
+func (o *Object) md5() (string, error) {
 	hash := strings.Trim(strings.ToLower(o.etag), `"`)
 	// Check the etag is a valid md5sum
-	if !matchMd5.MatchString(hash) {
-		err := o.readMetaData()
-		if err != nil {
-			return "", err
-		}
-
-		if md5sum, ok := o.meta[metaMD5Hash]; ok {
-			md5sumBytes, err := base64.StdEncoding.DecodeString(*md5sum)
-			if err != nil {
-				return "", err
-			}
-			hash = hex.EncodeToString(md5sumBytes)
-		} else {
-			hash = ""
-		}
+	if !o.fs.etagIsMD5() || !matchMd5.MatchString(hash) {
+		return o.metaHash(metaMD5Hash)
 	}
 	return hash, nil
 }
 
+// metaHash reads the base64 encoded hash stored in the metadata
+// under key returning a lowercase hex string, or "" if it isn't set
+func (o *Object) metaHash(key string) (string, error) {
+	err := o.readMetaData()
+	if err != nil {
+		return "", err
+	}
+	sum, ok := o.meta[key]
+	if !ok || sum == nil {
+		return "", nil
+	}
+	sumBytes, err := base64.StdEncoding.DecodeString(*sum)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sumBytes), nil
+}
+
 // Size returns the size of an object in bytes
 func (o *Object) Size() int64 {
 	return o.bytes
@@ -895,6 +1155,7 @@ func (o *Object) readMetaData() (err error) {
 		Bucket: &o.fs.bucket,
 		Key:    &key,
 	}
+	req.SSECustomerAlgorithm, req.SSECustomerKey = o.fs.sseCustomer()
 	resp, err := o.fs.c.HeadObject(&req)
 	if err != nil {
 		if awsErr, ok := err.(awserr.RequestFailure); ok {
@@ -920,6 +1181,8 @@ func (o *Object) readMetaData() (err error) {
 		o.lastModified = *resp.LastModified
 	}
 	o.mimeType = aws.StringValue(resp.ContentType)
+	o.storageClass = aws.StringValue(resp.StorageClass)
+	o.restore = aws.StringValue(resp.Restore)
 	return nil
 }
 
@@ -959,7 +1222,16 @@ func (o *Object) SetModTime(modTime time.Time) error {
 		fs.Debugf(o, "SetModTime is unsupported for objects bigger than %v bytes", fs.SizeSuffix(maxSizeForCopy))
 		return nil
 	}
+	if isArchived(o.storageClass) {
+		fs.Debugf(o, "SetModTime is unsupported for objects in %s", o.storageClass)
+		return nil
+	}
+	return o.updateMetadata()
+}
 
+// updateMetadata replaces the metadata of the object with o.meta by
+// copying the object to itself
+func (o *Object) updateMetadata() error {
 	// Guess the content type
 	mimeType := fs.MimeType(o)
 
@@ -969,17 +1241,30 @@ func (o *Object) SetModTime(modTime time.Time) error {
 	directive := s3.MetadataDirectiveReplace // replace metadata with that passed in
 	req := s3.CopyObjectInput{
 		Bucket:            &o.fs.bucket,
-		ACL:               &o.fs.acl,
+		ACL:               o.fs.aclPtr(),
 		Key:               &key,
 		ContentType:       &mimeType,
 		CopySource:        aws.String(pathEscape(sourceKey)),
 		Metadata:          o.meta,
 		MetadataDirective: &directive,
 	}
-	_, err = o.fs.c.CopyObject(&req)
+	if o.storageClass != "" {
+		// keep the storage class otherwise it is reset to STANDARD
+		req.StorageClass = &o.storageClass
+	}
+	req.ServerSideEncryption, req.SSEKMSKeyId = o.fs.ssePtrs()
+	req.SSECustomerAlgorithm, req.SSECustomerKey = o.fs.sseCustomer()
+	req.CopySourceSSECustomerAlgorithm, req.CopySourceSSECustomerKey = o.fs.sseCustomer()
+	_, err := o.fs.c.CopyObject(&req)
 	return err
 }
 
+// isArchived returns true if objects of storageClass must be restored
+// before they can be read or copied
+func isArchived(storageClass string) bool {
+	return storageClass == s3.ObjectStorageClassGlacier || storageClass == "DEEP_ARCHIVE"
+}
+
 // Storable raturns a boolean indicating if this object is storable
 func (o *Object) Storable() bool {
 	return true
@@ -992,6 +1277,7 @@ func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
 		Bucket: &o.fs.bucket,
 		Key:    &key,
 	}
+	req.SSECustomerAlgorithm, req.SSECustomerKey = o.fs.sseCustomer()
 	for _, option := range options {
 		switch option.(type) {
 		case *fs.RangeOption, *fs.SeekOption:
@@ -1028,7 +1314,7 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 		u.Concurrency = 2
 		u.LeavePartsOnError = false
 		u.S3 = o.fs.c
-		u.PartSize = s3manager.MinUploadPartSize
+		u.PartSize = int64(o.fs.quirks.chunkSize)
 
 		if size == -1 {
 			// Make parts as small as possible while still being able to upload to the
@@ -1048,7 +1334,15 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 		metaMtime: aws.String(swift.TimeToFloatString(modTime)),
 	}
 
-	if size > uploader.PartSize {
+	// Work out the MD5 and the ETag of uploads which may be multipart
+	// to check them and to store the MD5 if it isn't known
+	var hasher *etagHasher
+	if size < 0 || size > uploader.PartSize || !o.fs.etagIsMD5() {
+		hasher = newETagHasher(in, uploader.PartSize)
+		in = hasher
+	}
+
+	if size > uploader.PartSize || !o.fs.etagIsMD5() {
 		hash, err := src.Hash(hash.MD5)
 
 		if err == nil && matchMd5.MatchString(hash) {
@@ -1060,22 +1354,29 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 		}
 	}
 
+	// Store the SHA-256 if the source knows it
+	if sha256sum, err := src.Hash(hash.SHA256); err == nil && sha256sum != "" {
+		sha256Bytes, err := hex.DecodeString(sha256sum)
+		if err == nil {
+			metadata[metaSHA256Hash] = aws.String(base64.StdEncoding.EncodeToString(sha256Bytes))
+		}
+	}
+
 	// Guess the content type
 	mimeType := fs.MimeType(src)
 
 	key := o.fs.root + o.remote
 	req := s3manager.UploadInput{
 		Bucket:      &o.fs.bucket,
-		ACL:         &o.fs.acl,
+		ACL:         o.fs.aclPtr(),
 		Key:         &key,
 		Body:        in,
 		ContentType: &mimeType,
 		Metadata:    metadata,
 		//ContentLength: &size,
 	}
-	if o.fs.sse != "" {
-		req.ServerSideEncryption = &o.fs.sse
-	}
+	req.ServerSideEncryption, req.SSEKMSKeyId = o.fs.ssePtrs()
+	req.SSECustomerAlgorithm, req.SSECustomerKey = o.fs.sseCustomer()
 	if o.fs.storageClass != "" {
 		req.StorageClass = &o.fs.storageClass
 	}
@@ -1087,7 +1388,36 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	// Read the metadata from the newly created object
 	o.meta = nil // wipe old metadata
 	err = o.readMetaData()
-	return err
+	if err != nil || hasher == nil {
+		return err
+	}
+
+	// The ETag isn't the MD5 with SSE-KMS
+	if o.fs.quirks.multipartETag && o.fs.etagIsMD5() {
+		err = hasher.Check(o.etag)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Store the MD5 of multipart uploads if it wasn't known before
+	// the upload so the object has an MD5 sum
+	if _, ok := o.meta[metaMD5Hash]; !ok && (strings.Contains(o.etag, "-") || !o.fs.etagIsMD5()) {
+		if o.bytes >= maxSizeForCopy {
+			fs.Debugf(o, "Can't store MD5 for objects bigger than %v bytes", fs.SizeSuffix(maxSizeForCopy))
+			return nil
+		}
+		if isArchived(o.storageClass) {
+			fs.Debugf(o, "Can't store MD5 for objects in %s", o.storageClass)
+			return nil
+		}
+		o.meta[metaMD5Hash] = aws.String(base64.StdEncoding.EncodeToString(hasher.MD5()))
+		err = o.updateMetadata()
+		if err != nil {
+			return errors.Wrap(err, "failed to store MD5")
+		}
+	}
+	return nil
 }
 
 // Remove an object
@@ -1111,12 +1441,172 @@ func (o *Object) MimeType() string {
 	return o.mimeType
 }
 
+// GetTier returns the storage class of the object
+func (o *Object) GetTier() string {
+	if o.storageClass == "" {
+		return s3.ObjectStorageClassStandard
+	}
+	return o.storageClass
+}
+
+// SetTier changes the storage class of the object by copying it to
+// itself
+func (o *Object) SetTier(tier string) error {
+	err := o.readMetaData()
+	if err != nil {
+		return err
+	}
+	if o.bytes >= maxSizeForCopy {
+		return errors.Errorf("can't change the storage class of objects bigger than %v", fs.SizeSuffix(maxSizeForCopy))
+	}
+	tier = strings.ToUpper(tier)
+	key := o.fs.root + o.remote
+	sourceKey := o.fs.bucket + "/" + key
+	directive := s3.MetadataDirectiveCopy // keep the metadata
+	req := s3.CopyObjectInput{
+		Bucket:            &o.fs.bucket,
+		ACL:               o.fs.aclPtr(),
+		Key:               &key,
+		CopySource:        aws.String(pathEscape(sourceKey)),
+		MetadataDirective: &directive,
+		StorageClass:      &tier,
+	}
+	req.ServerSideEncryption, req.SSEKMSKeyId = o.fs.ssePtrs()
+	req.SSECustomerAlgorithm, req.SSECustomerKey = o.fs.sseCustomer()
+	req.CopySourceSSECustomerAlgorithm, req.CopySourceSSECustomerKey = o.fs.sseCustomer()
+	_, err = o.fs.c.CopyObject(&req)
+	if err != nil {
+		return err
+	}
+	o.storageClass = tier
+	return nil
+}
+
+// Restore starts restoring an object in GLACIER or DEEP_ARCHIVE so
+// it can be read for lifetime days.  The priority is the retrieval
+// tier, Standard, Bulk or Expedited, or "" for Standard.
+func (o *Object) Restore(priority string, lifetime int) error {
+	if lifetime < 1 {
+		return errors.Errorf("restore lifetime must be at least 1 day, not %d", lifetime)
+	}
+	key := o.fs.root + o.remote
+	req := s3.RestoreObjectInput{
+		Bucket: &o.fs.bucket,
+		Key:    &key,
+		RestoreRequest: &s3.RestoreRequest{
+			Days: aws.Int64(int64(lifetime)),
+		},
+	}
+	if priority != "" {
+		tier := strings.Title(strings.ToLower(priority))
+		switch tier {
+		case s3.TierStandard, s3.TierBulk, s3.TierExpedited:
+		default:
+			return errors.Errorf("unknown restore priority %q - must be Standard, Bulk or Expedited", priority)
+		}
+		req.RestoreRequest.GlacierJobParameters = &s3.GlacierJobParameters{
+			Tier: &tier,
+		}
+	}
+	_, err := o.fs.c.RestoreObject(&req)
+	if err, ok := err.(awserr.Error); ok && err.Code() == "RestoreAlreadyInProgress" {
+		return nil
+	}
+	return err
+}
+
+// RestoreStatus reads the restore status of the object from the
+// x-amz-restore header
+func (o *Object) RestoreStatus() (fs.RestoreStatus, error) {
+	o.meta = nil // read the metadata again to see the latest status
+	err := o.readMetaData()
+	if err != nil {
+		return "", err
+	}
+	return restoreStatus(o.storageClass, o.restore), nil
+}
+
+// Archived returns whether the object is in an archive storage class
+// going by the storage class it was listed with
+func (o *Object) Archived() bool {
+	return isArchived(o.storageClass)
+}
+
+// restoreStatus works out the restore status from the storage class
+// and x-amz-restore header of an object
+func restoreStatus(storageClass, restore string) fs.RestoreStatus {
+	switch {
+	case !isArchived(storageClass):
+		return fs.RestoreStatusOnline
+	case restore == "":
+		return fs.RestoreStatusArchived
+	case strings.Contains(restore, `ongoing-request="true"`):
+		return fs.RestoreStatusRestoring
+	}
+	return fs.RestoreStatusRestored
+}
+
+// isInternalMeta returns true if the metadata key k is used by
+// rclone rather than being user metadata
+func isInternalMeta(k string) bool {
+	return strings.EqualFold(k, metaMtime) || strings.EqualFold(k, metaMD5Hash) || strings.EqualFold(k, metaSHA256Hash)
+}
+
+// Metadata returns the user metadata of the object
+func (o *Object) Metadata() (map[string]string, error) {
+	err := o.readMetaData()
+	if err != nil {
+		return nil, err
+	}
+	metadata := make(map[string]string, len(o.meta))
+	for k, v := range o.meta {
+		if !isInternalMeta(k) {
+			metadata[k] = aws.StringValue(v)
+		}
+	}
+	return metadata, nil
+}
+
+// SetMetadata replaces the user metadata of the object by copying
+// it to itself
+func (o *Object) SetMetadata(metadata map[string]string) error {
+	err := o.readMetaData()
+	if err != nil {
+		return err
+	}
+	if o.bytes >= maxSizeForCopy {
+		return errors.Errorf("can't change the metadata of objects bigger than %v", fs.SizeSuffix(maxSizeForCopy))
+	}
+	meta := make(map[string]*string, len(metadata)+2)
+	for k, v := range o.meta {
+		if isInternalMeta(k) {
+			meta[k] = v
+		}
+	}
+	for k, v := range metadata {
+		meta[k] = aws.String(v)
+	}
+	oldMeta := o.meta
+	o.meta = meta
+	err = o.updateMetadata()
+	if err != nil {
+		o.meta = oldMeta
+		return err
+	}
+	return nil
+}
+
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs          = &Fs{}
-	_ fs.Copier      = &Fs{}
-	_ fs.PutStreamer = &Fs{}
-	_ fs.ListRer     = &Fs{}
-	_ fs.Object      = &Object{}
-	_ fs.MimeTyper   = &Object{}
+	_ fs.Fs            = &Fs{}
+	_ fs.Copier        = &Fs{}
+	_ fs.PutStreamer   = &Fs{}
+	_ fs.ListRer       = &Fs{}
+	_ fs.Object        = &Object{}
+	_ fs.MimeTyper     = &Object{}
+	_ fs.GetTierer     = &Object{}
+	_ fs.SetTierer     = &Object{}
+	_ fs.Restorer      = &Object{}
+	_ fs.Metadataer    = &Object{}
+	_ fs.SetMetadataer = &Object{}
 )
@@ -0,0 +1,112 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindQuirks(t *testing.T) {
+	assert.Equal(t, providerQuirks["AWS"], findQuirks("remote", "", ""))
+	assert.Equal(t, providerQuirks["Other"], findQuirks("remote", "", "https://s3.example.com"))
+	assert.Equal(t, providerQuirks["Minio"], findQuirks("remote", "minio", "http://localhost:9000"))
+	assert.Equal(t, providerQuirks["Other"], findQuirks("remote", "potato", ""))
+	assert.False(t, findQuirks("remote", "Minio", "").useACL)
+	assert.Equal(t, 1, findQuirks("remote", "Ceph", "").listVersion)
+}
+
+func TestETagHasher(t *testing.T) {
+	md5Of := func(s string) []byte {
+		sum := md5.Sum([]byte(s))
+		return sum[:]
+	}
+	multipart := md5.Sum(bytes.Join([][]byte{md5Of("abcd"), md5Of("efgh"), md5Of("ij")}, nil))
+	wantMultipart := hex.EncodeToString(multipart[:]) + "-3"
+
+	for _, in := range []io.Reader{
+		strings.NewReader("abcdefghij"),
+		iotest.OneByteReader(strings.NewReader("abcdefghij")),
+		iotest.HalfReader(strings.NewReader("abcdefghij")),
+	} {
+		h := newETagHasher(in, 4)
+		data, err := ioutil.ReadAll(h)
+		require.NoError(t, err)
+		assert.Equal(t, "abcdefghij", string(data))
+		assert.Equal(t, md5Of("abcdefghij"), h.MD5())
+		assert.Equal(t, wantMultipart, h.MultipartETag())
+		assert.NoError(t, h.Check(`"`+strings.ToUpper(wantMultipart)+`"`))
+		assert.NoError(t, h.Check(hex.EncodeToString(md5Of("abcdefghij"))))
+		assert.Error(t, h.Check("0123456789abcdef0123456789abcdef-3"))
+	}
+
+	// a whole number of parts doesn't make an empty part
+	h := newETagHasher(strings.NewReader("abcdefgh"), 4)
+	_, err := ioutil.ReadAll(h)
+	require.NoError(t, err)
+	multipart = md5.Sum(bytes.Join([][]byte{md5Of("abcd"), md5Of("efgh")}, nil))
+	assert.Equal(t, hex.EncodeToString(multipart[:])+"-2", h.MultipartETag())
+}
+
+func TestCopyPartSize(t *testing.T) {
+	const (
+		mb = 1024 * 1024
+		gb = 1024 * mb
+	)
+	assert.Equal(t, int64(gb), copyPartSize(10*gb, defaultCopyChunkSize))
+	assert.Equal(t, int64(5*mb), copyPartSize(6*mb, minChunkSize))
+	// 5TB in 5MB parts is too many so the parts are made bigger
+	partSize := copyPartSize(maxFileSize, minChunkSize)
+	assert.Equal(t, int64(525*mb), partSize)
+	assert.True(t, maxFileSize/partSize < 10000)
+}
+
+func TestETagIsMD5(t *testing.T) {
+	const etag = `"9a0364b9e99bb480dd25e1f0284c8555"`
+	for _, test := range []struct {
+		f    *Fs
+		want string
+	}{
+		{&Fs{}, "9a0364b9e99bb480dd25e1f0284c8555"},
+		{&Fs{sse: "AES256"}, "9a0364b9e99bb480dd25e1f0284c8555"},
+		{&Fs{sse: "aws:kms"}, ""},
+		{&Fs{sseCustomerAlgo: "AES256"}, ""},
+	} {
+		o := &Object{fs: test.f, etag: etag, meta: map[string]*string{}}
+		got, err := o.md5()
+		require.NoError(t, err)
+		assert.Equal(t, test.want, got)
+	}
+}
+
+func TestIsArchived(t *testing.T) {
+	assert.True(t, isArchived("GLACIER"))
+	assert.True(t, isArchived("DEEP_ARCHIVE"))
+	assert.False(t, isArchived(""))
+	assert.False(t, isArchived("STANDARD_IA"))
+	assert.False(t, isArchived("ONEZONE_IA"))
+}
+
+func TestRestoreStatus(t *testing.T) {
+	for _, test := range []struct {
+		storageClass string
+		restore      string
+		want         fs.RestoreStatus
+	}{
+		{"", "", fs.RestoreStatusOnline},
+		{"STANDARD_IA", "", fs.RestoreStatusOnline},
+		{"GLACIER", "", fs.RestoreStatusArchived},
+		{"DEEP_ARCHIVE", `ongoing-request="true"`, fs.RestoreStatusRestoring},
+		{"GLACIER", `ongoing-request="false", expiry-date="Fri, 23 Dec 2012 00:00:00 GMT"`, fs.RestoreStatusRestored},
+	} {
+		assert.Equal(t, test.want, restoreStatus(test.storageClass, test.restore), test.storageClass+" "+test.restore)
+	}
+}
@@ -61,6 +61,7 @@ func init() {
 			Optional: true,
 		}, {
 			Name:     "port",
+			Type:     fs.OptionTypeInt,
 			Help:     "SSH port, leave blank to use default (22)",
 			Optional: true,
 		}, {
@@ -74,6 +75,7 @@ func init() {
 			Optional: true,
 		}, {
 			Name:     "use_insecure_cipher",
+			Type:     fs.OptionTypeBool,
 			Help:     "Enable the user of the aes128-cbc cipher. This cipher is insecure and may allow plaintext data to be recovered by an attacker..",
 			Optional: true,
 			Examples: []fs.OptionExample{
@@ -87,8 +89,14 @@ func init() {
 			},
 		}, {
 			Name:     "disable_hashcheck",
+			Type:     fs.OptionTypeBool,
 			Help:     "Disable the exectution of SSH commands to determine if remote file hashing is available, leave blank unless you know what you are doing.",
 			Optional: true,
+		}, {
+			Name: "set_modtime",
+			Type: fs.OptionTypeBool,
+			Help: "Set the modified time on the remote if set (true or false, default true).",
+			Hide: true,
 		}},
 	}
 	fs.Register(fsi)
@@ -148,7 +156,7 @@ func readCurrentUser() (userName string) {
 // initiates the SSH handshake, and then sets up a Client.
 func Dial(network, addr string, sshConfig *ssh.ClientConfig) (*ssh.Client, error) {
 	dialer := fshttp.NewDialer(fs.Config)
-	conn, err := dialer.Dial(network, addr)
+	conn, err := dialer.Dial(fshttp.Network(fs.Config, network), addr)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,752 @@
+// Package smb provides an interface to SMB/CIFS servers such as
+// Windows shares and Samba using github.com/hirochachacha/go-smb2
+
+package smb
+
+import (
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hirochachacha/go-smb2"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/config/obscure"
+	"github.com/ncw/rclone/fs/fshttp"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/lib/readers"
+	"github.com/pkg/errors"
+)
+
+// NT status codes returned by the server which rclone needs to know
+// about
+const (
+	statusObjectNameNotFound = 0xC0000034
+	statusObjectPathNotFound = 0xC000003A
+	statusDirectoryNotEmpty  = 0xC0000101
+	statusNotADirectory      = 0xC0000103
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "smb",
+		Description: "SMB / CIFS (Windows shares, Samba)",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name:     "host",
+			Help:     "SMB server hostname to connect to",
+			Optional: false,
+			Examples: []fs.OptionExample{{
+				Value: "example.com",
+				Help:  "Connect to example.com",
+			}},
+		}, {
+			Name:     "user",
+			Help:     "SMB username, leave blank for current username, " + os.Getenv("USER"),
+			Optional: true,
+		}, {
+			Name:     "port",
+			Type:     fs.OptionTypeInt,
+			Help:     "SMB port, leave blank to use default (445)",
+			Optional: true,
+		}, {
+			Name:       "pass",
+			Help:       "SMB password",
+			IsPassword: true,
+			Optional:   true,
+		}, {
+			Name:     "domain",
+			Help:     "Domain name for NTLM authentication, leave blank for none",
+			Optional: true,
+			Examples: []fs.OptionExample{{
+				Value: "WORKGROUP",
+				Help:  "The default workgroup",
+			}},
+		}},
+	})
+}
+
+// Fs represents a remote SMB server
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on if any
+	features *fs.Features // optional features
+	dialer   *smb2.Dialer // for logging in to the server
+	host     string       // host name of the server
+	dialAddr string       // host:port of the server
+	poolMu   sync.Mutex
+	pool     []*conn
+}
+
+// Object describes an SMB file
+type Object struct {
+	fs      *Fs
+	remote  string
+	size    int64
+	modTime time.Time
+}
+
+// conn is a session with the server and the shares mounted on it
+type conn struct {
+	session *smb2.Session
+	shares  map[string]*smb2.Share // mounted shares by name
+}
+
+// ------------------------------------------------------------
+
+// Name of this fs
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return "smb://" + path.Join(f.dialAddr+"/", f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Open a new connection to the SMB server.
+func (f *Fs) smbConnection() (*conn, error) {
+	fs.Debugf(f, "Connecting to SMB server")
+	dialer := fshttp.NewDialer(fs.Config)
+	tconn, err := dialer.Dial("tcp", f.dialAddr)
+	if err != nil {
+		return nil, errors.Wrap(err, "smbConnection Dial")
+	}
+	session, err := f.dialer.Dial(tconn)
+	if err != nil {
+		_ = tconn.Close()
+		return nil, errors.Wrap(err, "smbConnection Login")
+	}
+	return &conn{
+		session: session,
+		shares:  map[string]*smb2.Share{},
+	}, nil
+}
+
+// share returns the share called name, mounting it if necessary
+func (c *conn) share(host, name string) (*smb2.Share, error) {
+	if share, ok := c.shares[name]; ok {
+		return share, nil
+	}
+	share, err := c.session.Mount(`\\` + host + `\` + name)
+	if err != nil {
+		return nil, err
+	}
+	c.shares[name] = share
+	return share, nil
+}
+
+// close unmounts the shares and logs off the session
+func (c *conn) close() {
+	for _, share := range c.shares {
+		_ = share.Umount()
+	}
+	_ = c.session.Logoff()
+}
+
+// Get an SMB connection from the pool, or open a new one
+func (f *Fs) getSmbConnection() (c *conn, err error) {
+	f.poolMu.Lock()
+	if len(f.pool) > 0 {
+		c = f.pool[0]
+		f.pool = f.pool[1:]
+	}
+	f.poolMu.Unlock()
+	if c != nil {
+		return c, nil
+	}
+	return f.smbConnection()
+}
+
+// Return an SMB connection to the pool
+//
+// It nils the pointed to connection out so it can't be reused
+//
+// if err is not nil and isn't an error from the server then the
+// connection is closed rather than being returned to the pool
+func (f *Fs) putSmbConnection(pc **conn, err error) {
+	c := *pc
+	*pc = nil
+	if err != nil {
+		if _, isRegularError := statusCode(err); !isRegularError {
+			fs.Debugf(f, "Connection failed, closing: %v", err)
+			c.close()
+			return
+		}
+	}
+	f.poolMu.Lock()
+	f.pool = append(f.pool, c)
+	f.poolMu.Unlock()
+}
+
+// statusCode returns the NT status code the server returned in err
+// and whether there was one
+func statusCode(err error) (uint32, bool) {
+	switch x := errors.Cause(err).(type) {
+	case *os.PathError:
+		err = x.Err
+	case *os.LinkError:
+		err = x.Err
+	}
+	if err == os.ErrNotExist {
+		return statusObjectNameNotFound, true
+	}
+	if respErr, ok := err.(*smb2.ResponseError); ok {
+		return respErr.Code, true
+	}
+	return 0, false
+}
+
+// isNotExist returns whether err means the path doesn't exist
+func isNotExist(err error) bool {
+	code, _ := statusCode(err)
+	return code == statusObjectNameNotFound || code == statusObjectPathNotFound || code == statusNotADirectory
+}
+
+// NewFs constructs an Fs from the path, share/path
+func NewFs(name, root string) (fs.Fs, error) {
+	host := config.FileGet(name, "host")
+	user := config.FileGet(name, "user")
+	pass := config.FileGet(name, "pass")
+	port := config.FileGet(name, "port")
+	domain := config.FileGet(name, "domain")
+	if host == "" {
+		return nil, errors.New("host not found in config")
+	}
+	pass, err := obscure.Reveal(pass)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewFs decrypt password")
+	}
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+	if port == "" {
+		port = "445"
+	}
+	root = strings.Trim(root, "/")
+	f := &Fs{
+		name: name,
+		root: root,
+		dialer: &smb2.Dialer{
+			Initiator: &smb2.NTLMInitiator{
+				User:     user,
+				Password: pass,
+				Domain:   domain,
+			},
+		},
+		host:     host,
+		dialAddr: host + ":" + port,
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(f)
+	// Make a connection and pool it to return errors early
+	c, err := f.getSmbConnection()
+	if err != nil {
+		return nil, errors.Wrap(err, "NewFs")
+	}
+	f.putSmbConnection(&c, nil)
+	if share, filePath := f.split(""); share != "" && filePath != "" {
+		// Check to see if the root actually an existing file
+		info, err := f.stat("")
+		if err == nil && !info.IsDir() {
+			f.root = path.Dir(root)
+			if f.root == "." {
+				f.root = ""
+			}
+			// return an error with an fs which points to the parent
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// split returns the share name and the path within the share of the
+// remote, with the path using SMB separators
+func (f *Fs) split(remote string) (share, filePath string) {
+	fullPath := path.Join(f.root, remote)
+	if fullPath == "." {
+		return "", ""
+	}
+	i := strings.IndexRune(fullPath, '/')
+	if i < 0 {
+		return fullPath, ""
+	}
+	return fullPath[:i], strings.Replace(fullPath[i+1:], "/", `\`, -1)
+}
+
+// withShare calls fn with the share and path for remote on a pooled
+// connection
+func (f *Fs) withShare(remote string, fn func(share *smb2.Share, filePath string) error) error {
+	shareName, filePath := f.split(remote)
+	if shareName == "" {
+		return errors.New("can't use the server root - put a share name on the end of the remote, eg remote:share/path")
+	}
+	c, err := f.getSmbConnection()
+	if err != nil {
+		return err
+	}
+	share, err := c.share(f.host, shareName)
+	if err == nil {
+		err = fn(share, filePath)
+	}
+	f.putSmbConnection(&c, err)
+	return err
+}
+
+// stat returns the info for remote
+func (f *Fs) stat(remote string) (info os.FileInfo, err error) {
+	err = f.withShare(remote, func(share *smb2.Share, filePath string) error {
+		info, err = share.Stat(filePath)
+		return err
+	})
+	return info, err
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(remote string) (fs.Object, error) {
+	info, err := f.stat(remote)
+	if err != nil {
+		if isNotExist(err) {
+			return nil, fs.ErrorObjectNotFound
+		}
+		return nil, errors.Wrap(err, "NewObject stat failed")
+	}
+	if info.IsDir() {
+		return nil, fs.ErrorNotAFile
+	}
+	o := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	o.setMetadata(info)
+	return o, nil
+}
+
+// listShares lists the shares on the server as directories
+func (f *Fs) listShares() (entries fs.DirEntries, err error) {
+	c, err := f.getSmbConnection()
+	if err != nil {
+		return nil, errors.Wrap(err, "list")
+	}
+	names, err := c.session.ListShareNames()
+	f.putSmbConnection(&c, err)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list shares")
+	}
+	for _, name := range names {
+		// Skip the administrative shares such as IPC$ and C$
+		if strings.HasSuffix(name, "$") {
+			continue
+		}
+		entries = append(entries, fs.NewDir(name, time.Time{}))
+	}
+	return entries, nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+	if shareName, _ := f.split(dir); shareName == "" {
+		return f.listShares()
+	}
+	var infos []os.FileInfo
+	err = f.withShare(dir, func(share *smb2.Share, filePath string) error {
+		infos, err = share.ReadDir(filePath)
+		return err
+	})
+	if err != nil {
+		if isNotExist(err) {
+			return nil, fs.ErrorDirNotFound
+		}
+		return nil, errors.Wrapf(err, "error listing %q", dir)
+	}
+	for _, info := range infos {
+		remote := path.Join(dir, info.Name())
+		if info.IsDir() {
+			entries = append(entries, fs.NewDir(remote, info.ModTime()))
+		} else {
+			o := &Object{
+				fs:     f,
+				remote: remote,
+			}
+			o.setMetadata(info)
+			entries = append(entries, o)
+		}
+	}
+	return entries, nil
+}
+
+// Hashes are not supported
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.None)
+}
+
+// Precision is the resolution of SMB timestamps
+func (f *Fs) Precision() time.Duration {
+	return 100 * time.Nanosecond
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	err := o.Update(in, src, options...)
+	return o, err
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(in, src, options...)
+}
+
+// mkdir makes the directory and any parents
+func (f *Fs) mkdir(dir string) error {
+	if _, filePath := f.split(dir); filePath == "" {
+		// shares can't be made over SMB
+		return nil
+	}
+	return f.withShare(dir, func(share *smb2.Share, filePath string) error {
+		return share.MkdirAll(filePath, 0755)
+	})
+}
+
+// Mkdir creates the directory if it doesn't exist
+func (f *Fs) Mkdir(dir string) error {
+	err := f.mkdir(dir)
+	if err != nil {
+		return errors.Wrap(err, "Mkdir failed")
+	}
+	return nil
+}
+
+// Rmdir removes the directory if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(dir string) error {
+	if _, filePath := f.split(dir); filePath == "" {
+		// shares can't be removed over SMB
+		return nil
+	}
+	info, err := f.stat(dir)
+	if err != nil {
+		if isNotExist(err) {
+			return fs.ErrorDirNotFound
+		}
+		return errors.Wrap(err, "Rmdir stat failed")
+	}
+	if !info.IsDir() {
+		return fs.ErrorIsFile
+	}
+	err = f.withShare(dir, func(share *smb2.Share, filePath string) error {
+		return share.Remove(filePath)
+	})
+	if code, _ := statusCode(err); code == statusDirectoryNotEmpty {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	if err != nil {
+		return errors.Wrap(err, "Rmdir failed")
+	}
+	return nil
+}
+
+// rename renames srcRemote in srcFs to dstRemote in f, making the
+// parent directory of dstRemote first.  They must be on the same
+// share.
+func (f *Fs) rename(srcFs *Fs, srcRemote, dstRemote string) error {
+	srcShare, srcPath := srcFs.split(srcRemote)
+	dstShare, _ := f.split(dstRemote)
+	if srcShare != dstShare || srcFs.host != f.host || srcPath == "" {
+		return errCrossShare
+	}
+	err := f.mkdir(path.Dir(dstRemote))
+	if err != nil {
+		return errors.Wrap(err, "failed to make destination directory")
+	}
+	return f.withShare(dstRemote, func(share *smb2.Share, dstPath string) error {
+		return share.Rename(srcPath, dstPath)
+	})
+}
+
+// errCrossShare is returned by rename if the source and destination
+// are on different shares
+var errCrossShare = errors.New("can't rename between shares")
+
+// Move src to this remote using server side move operations.
+//
+// This is stored in the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	// SMB rename won't overwrite so remove any existing file first
+	if dstObj, err := f.NewObject(remote); err == nil {
+		err = dstObj.Remove()
+		if err != nil {
+			return nil, errors.Wrap(err, "Move failed to remove existing file")
+		}
+	}
+	err := f.rename(srcObj.fs, srcObj.remote, remote)
+	if err == errCrossShare {
+		fs.Debugf(src, "Can't move - %v", err)
+		return nil, fs.ErrorCantMove
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Move failed")
+	}
+	return f.NewObject(remote)
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	_, err := f.stat(dstRemote)
+	if err == nil {
+		return fs.ErrorDirExists
+	} else if !isNotExist(err) {
+		return errors.Wrap(err, "DirMove stat failed")
+	}
+	err = f.rename(srcFs, srcRemote, dstRemote)
+	if err == errCrossShare {
+		fs.Debugf(srcFs, "Can't move directory - %v", err)
+		return fs.ErrorCantDirMove
+	}
+	if err != nil {
+		return errors.Wrap(err, "DirMove failed")
+	}
+	return nil
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// String version of o
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the hash of an object returning a lowercase hex string
+func (o *Object) Hash(t hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime() time.Time {
+	return o.modTime
+}
+
+// setMetadata sets the metadata from info
+func (o *Object) setMetadata(info os.FileInfo) {
+	o.size = info.Size()
+	o.modTime = info.ModTime()
+}
+
+// stat updates the metadata from the server
+func (o *Object) stat() error {
+	info, err := o.fs.stat(o.remote)
+	if err != nil {
+		if isNotExist(err) {
+			return fs.ErrorObjectNotFound
+		}
+		return errors.Wrap(err, "stat failed")
+	}
+	if info.IsDir() {
+		return errors.Wrapf(fs.ErrorNotAFile, "%q", o.remote)
+	}
+	o.setMetadata(info)
+	return nil
+}
+
+// SetModTime sets the modification time of the object
+func (o *Object) SetModTime(modTime time.Time) error {
+	err := o.fs.withShare(o.remote, func(share *smb2.Share, filePath string) error {
+		return share.Chtimes(filePath, modTime, modTime)
+	})
+	if err != nil {
+		return errors.Wrap(err, "SetModTime failed")
+	}
+	return o.stat()
+}
+
+// Storable returns a boolean as to whether this object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// smbReadCloser returns the connection to the pool when the file is
+// closed
+type smbReadCloser struct {
+	io.ReadCloser
+	f    *Fs
+	c    *conn
+	file *smb2.File
+}
+
+// Close the file and return the connection to the pool
+func (rc *smbReadCloser) Close() error {
+	err := rc.file.Close()
+	rc.f.putSmbConnection(&rc.c, err)
+	return err
+}
+
+// Open an object for read
+func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.Size())
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	shareName, filePath := o.fs.split(o.remote)
+	c, err := o.fs.getSmbConnection()
+	if err != nil {
+		return nil, errors.Wrap(err, "Open")
+	}
+	share, err := c.share(o.fs.host, shareName)
+	if err != nil {
+		o.fs.putSmbConnection(&c, err)
+		return nil, errors.Wrap(err, "Open")
+	}
+	file, err := share.Open(filePath)
+	if err != nil {
+		o.fs.putSmbConnection(&c, err)
+		return nil, errors.Wrap(err, "Open failed")
+	}
+	if offset > 0 {
+		_, err = file.Seek(offset, io.SeekStart)
+		if err != nil {
+			_ = file.Close()
+			o.fs.putSmbConnection(&c, err)
+			return nil, errors.Wrap(err, "Open Seek failed")
+		}
+	}
+	return &smbReadCloser{
+		ReadCloser: readers.NewLimitedReadCloser(file, limit),
+		f:          o.fs,
+		c:          c,
+		file:       file,
+	}, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	err = o.fs.mkdir(path.Dir(o.remote))
+	if err != nil {
+		return errors.Wrap(err, "Update mkdir failed")
+	}
+	// remove the file if upload failed
+	remove := func() {
+		removeErr := o.Remove()
+		if removeErr != nil {
+			fs.Debugf(src, "Failed to remove: %v", removeErr)
+		} else {
+			fs.Debugf(src, "Removed after failed upload: %v", err)
+		}
+	}
+	err = o.fs.withShare(o.remote, func(share *smb2.Share, filePath string) error {
+		file, err := share.Create(filePath)
+		if err != nil {
+			return err
+		}
+		_, err = file.ReadFrom(in)
+		closeErr := file.Close()
+		if err == nil {
+			err = closeErr
+		}
+		return err
+	})
+	if err != nil {
+		remove()
+		return errors.Wrap(err, "Update failed")
+	}
+	return o.SetModTime(src.ModTime())
+}
+
+// Remove an object
+func (o *Object) Remove() error {
+	return o.fs.withShare(o.remote, func(share *smb2.Share, filePath string) error {
+		return share.Remove(filePath)
+	})
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = &Fs{}
+	_ fs.PutStreamer = &Fs{}
+	_ fs.Mover       = &Fs{}
+	_ fs.DirMover    = &Fs{}
+	_ fs.Object      = &Object{}
+)
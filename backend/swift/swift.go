@@ -44,6 +44,7 @@ func init() {
 		NewFs:       NewFs,
 		Options: []fs.Option{{
 			Name: "env_auth",
+			Type: fs.OptionTypeBool,
 			Help: "Get swift credentials from environment variables in standard OpenStack form.",
 			Examples: []fs.OptionExample{
 				{
@@ -108,6 +109,7 @@ func init() {
 			Help: "Auth Token from alternate authentication - optional (OS_AUTH_TOKEN)",
 		}, {
 			Name: "auth_version",
+			Type: fs.OptionTypeInt,
 			Help: "AuthVersion - optional - set to (1,2,3) if your auth URL has no version (ST_AUTH_VERSION)",
 		}, {
 			Name: "endpoint_type",
@@ -213,7 +215,7 @@ func swiftConnection(name string) (*swift.Connection, error) {
 		EndpointType:   swift.EndpointType(config.FileGet(name, "endpoint_type", "public")),
 		ConnectTimeout: 10 * fs.Config.ConnectTimeout, // Use the timeouts in the transport
 		Timeout:        10 * fs.Config.Timeout,        // Use the timeouts in the transport
-		Transport:      fshttp.NewTransport(fs.Config),
+		Transport:      fshttp.NewTransport(fs.ConfigForRemote(name)),
 	}
 	if config.FileGetBool(name, "env_auth", false) {
 		err := c.ApplyEnvironment()
@@ -753,6 +755,55 @@ func (o *Object) SetModTime(modTime time.Time) error {
 	return o.fs.c.ObjectUpdate(o.fs.container, o.fs.root+o.remote, newHeaders)
 }
 
+// metaMtime is the metadata key the modification time is stored in
+const metaMtime = "mtime"
+
+// Metadata returns the user metadata of the object
+func (o *Object) Metadata() (map[string]string, error) {
+	err := o.readMetaData()
+	if err != nil {
+		return nil, err
+	}
+	metadata := o.headers.ObjectMetadata()
+	delete(metadata, metaMtime)
+	return metadata, nil
+}
+
+// SetMetadata replaces the user metadata of the object
+func (o *Object) SetMetadata(metadata map[string]string) error {
+	err := o.readMetaData()
+	if err != nil {
+		return err
+	}
+	meta := swift.Metadata{}
+	if mtime, ok := o.headers.ObjectMetadata()[metaMtime]; ok {
+		meta[metaMtime] = mtime
+	}
+	for k, v := range metadata {
+		meta[strings.ToLower(k)] = v
+	}
+	newHeaders := meta.ObjectHeaders()
+	// Include any other headers from request which aren't metadata
+	for k, v := range o.headers {
+		if strings.HasPrefix(k, "X-Object-") && !strings.HasPrefix(k, "X-Object-Meta-") {
+			newHeaders[k] = v
+		}
+	}
+	err = o.fs.c.ObjectUpdate(o.fs.container, o.fs.root+o.remote, newHeaders)
+	if err != nil {
+		return err
+	}
+	for k := range o.headers {
+		if strings.HasPrefix(k, "X-Object-Meta-") {
+			delete(o.headers, k)
+		}
+	}
+	for k, v := range newHeaders {
+		o.headers[k] = v
+	}
+	return nil
+}
+
 // Storable returns if this object is storable
 //
 // It compares the Content-Type to directoryMarkerContentType - that
@@ -953,11 +1004,13 @@ func (o *Object) MimeType() string {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs          = &Fs{}
-	_ fs.Purger      = &Fs{}
-	_ fs.PutStreamer = &Fs{}
-	_ fs.Copier      = &Fs{}
-	_ fs.ListRer     = &Fs{}
-	_ fs.Object      = &Object{}
-	_ fs.MimeTyper   = &Object{}
+	_ fs.Fs            = &Fs{}
+	_ fs.Purger        = &Fs{}
+	_ fs.PutStreamer   = &Fs{}
+	_ fs.Copier        = &Fs{}
+	_ fs.ListRer       = &Fs{}
+	_ fs.Object        = &Object{}
+	_ fs.MimeTyper     = &Object{}
+	_ fs.Metadataer    = &Object{}
+	_ fs.SetMetadataer = &Object{}
 )
@@ -0,0 +1,602 @@
+// Package union implements a backend which merges several remotes
+// into one
+package union
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/pkg/errors"
+)
+
+// Create policies choosing which remote new files and directories
+// are made on
+const (
+	policyFirstFound           = "ff"    // the first remote
+	policyExistingPathMostFree = "epmfs" // the remote with the most free space of those with the parent directory
+	policyMostFree             = "mfs"   // the remote with the most free space
+	defaultPolicy              = policyExistingPathMostFree
+	unknownFree                = -1 // free space of remotes which can't tell us
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "union",
+		Description: "Union merges the contents of several remotes",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "remotes",
+			Help: "List of space separated remotes.\nCan be 'remotea:test/dir remoteb:', '\"remotea:test/space dir\" remoteb:', etc.\nFiles in earlier remotes hide files with the same name in later ones.",
+		}, {
+			Name: "create_policy",
+			Help: "Policy to choose which remote new files and directories are made on.",
+			Examples: []fs.OptionExample{
+				{
+					Value: policyExistingPathMostFree,
+					Help:  "Existing path, most free space - the remote with the most free space of those with the parent directory.",
+				}, {
+					Value: policyMostFree,
+					Help:  "Most free space - the remote with the most free space.",
+				}, {
+					Value: policyFirstFound,
+					Help:  "First found - the first remote in the list.",
+				},
+			},
+			Optional: true,
+		}},
+	})
+}
+
+// Fs represents a union of remotes
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on
+	features *fs.Features // optional features
+	remotes  []fs.Fs      // the remotes making up the union, in priority order
+	policy   string       // create policy
+}
+
+// Object describes a union Object
+//
+// This is a wrapped object which returns the union Fs as its parent
+type Object struct {
+	fs.Object
+	fs       *Fs   // what this object is part of
+	upstream fs.Fs // the remote the object is on
+}
+
+// parseRemotes splits the remotes config value into the remotes
+func parseRemotes(value string) ([]string, error) {
+	r := csv.NewReader(strings.NewReader(value))
+	r.Comma = ' '
+	fields, err := r.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse remotes")
+	}
+	var remotes []string
+	for _, field := range fields {
+		if field != "" {
+			remotes = append(remotes, field)
+		}
+	}
+	return remotes, nil
+}
+
+// newUpstream makes the Fs for the remote at root
+func newUpstream(remote, root string) (fs.Fs, error) {
+	fsInfo, configName, fsPath, err := fs.ParseRemote(remote)
+	if err != nil {
+		return nil, err
+	}
+	return fsInfo.NewFs(configName, path.Join(fsPath, root))
+}
+
+// NewFs constructs an Fs from the path.
+//
+// The returned Fs is the actual Fs, referenced by remote in the config
+func NewFs(name, root string) (fs.Fs, error) {
+	remotes, err := parseRemotes(config.FileGet(name, "remotes"))
+	if err != nil {
+		return nil, err
+	}
+	if len(remotes) == 0 {
+		return nil, errors.New("union can't be made from no remotes - check the value of the remotes setting")
+	}
+	for _, remote := range remotes {
+		if strings.HasPrefix(remote, name+":") {
+			return nil, errors.New("can't point union remote at itself - check the value of the remotes setting")
+		}
+	}
+	policy := config.FileGet(name, "create_policy", defaultPolicy)
+	switch policy {
+	case policyFirstFound, policyExistingPathMostFree, policyMostFree:
+	default:
+		return nil, errors.Errorf("unknown create_policy %q", policy)
+	}
+
+	root = strings.Trim(filepath.ToSlash(root), "/")
+	f := &Fs{
+		name:   name,
+		root:   root,
+		policy: policy,
+	}
+	isFile := false
+	for _, remote := range remotes {
+		upstream, err := newUpstream(remote, root)
+		if err == fs.ErrorIsFile {
+			isFile = true
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "failed to make remote %q to union", remote)
+		}
+		f.remotes = append(f.remotes, upstream)
+	}
+	// If root is a file on any of the remotes then point them all
+	// at its directory
+	if isFile {
+		f.root = path.Dir(root)
+		if f.root == "." {
+			f.root = ""
+		}
+		f.remotes = nil
+		for _, remote := range remotes {
+			upstream, err := newUpstream(remote, f.root)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to make remote %q to union", remote)
+			}
+			f.remotes = append(f.remotes, upstream)
+		}
+	}
+
+	// the features here are ones we could support, and they are
+	// ANDed with the ones from the remotes
+	f.features = (&fs.Features{
+		CaseInsensitive:         true,
+		DuplicateFiles:          false,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		CanHaveEmptyDirectories: true,
+		BucketBased:             true,
+	}).Fill(f)
+	for _, upstream := range f.remotes {
+		f.features = f.features.Mask(upstream)
+	}
+	// These work, or fall back to a non server side operation, on
+	// the remote the object is on whatever the others support, and
+	// the union can still work out its free space if some of the
+	// remotes can't
+	f.features.Copy = f.Copy
+	f.features.Move = f.Move
+	f.features.About = f.About
+	f.features.DisableList(fs.Config.DisableFeatures)
+
+	if isFile {
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("union root '%s'", f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision is the least precise of the remotes
+func (f *Fs) Precision() time.Duration {
+	var precision time.Duration
+	for _, upstream := range f.remotes {
+		if p := upstream.Precision(); p > precision {
+			precision = p
+		}
+	}
+	return precision
+}
+
+// Hashes returns the hashes supported by all the remotes
+func (f *Fs) Hashes() hash.Set {
+	set := hash.Supported
+	for _, upstream := range f.remotes {
+		set = set.Overlap(upstream.Hashes())
+	}
+	return set
+}
+
+// wrapObject wraps o from upstream into a union Object
+func (f *Fs) wrapObject(o fs.Object, upstream fs.Fs) *Object {
+	return &Object{
+		Object:   o,
+		fs:       f,
+		upstream: upstream,
+	}
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+//
+// Entries from earlier remotes hide entries with the same name in
+// later ones.
+func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+	seen := make(map[string]bool)
+	found := false
+	for _, upstream := range f.remotes {
+		upstreamEntries, err := upstream.List(dir)
+		if err == fs.ErrorDirNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		for _, entry := range upstreamEntries {
+			remote := entry.Remote()
+			if seen[remote] {
+				continue
+			}
+			seen[remote] = true
+			if o, ok := entry.(fs.Object); ok {
+				entry = f.wrapObject(o, upstream)
+			}
+			entries = append(entries, entry)
+		}
+	}
+	if !found {
+		return nil, fs.ErrorDirNotFound
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote on the first remote which
+// has it.  If it can't be found it returns the error
+// fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(remote string) (fs.Object, error) {
+	for _, upstream := range f.remotes {
+		o, err := upstream.NewObject(remote)
+		if err == fs.ErrorObjectNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return f.wrapObject(o, upstream), nil
+	}
+	return nil, fs.ErrorObjectNotFound
+}
+
+// dirExists returns true if dir exists on upstream
+func dirExists(upstream fs.Fs, dir string) (bool, error) {
+	_, err := upstream.List(dir)
+	if err == fs.ErrorDirNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// free returns the free space on upstream or unknownFree if it can't
+// be found
+func free(upstream fs.Fs) int64 {
+	about := upstream.Features().About
+	if about == nil {
+		return unknownFree
+	}
+	usage, err := about()
+	if err != nil {
+		fs.Debugf(upstream, "Failed to read free space: %v", err)
+		return unknownFree
+	}
+	if usage.Free == nil {
+		return unknownFree
+	}
+	return *usage.Free
+}
+
+// mostFree returns the remote with the most free space out of
+// upstreams, or the first if none of them know their free space
+func mostFree(upstreams []fs.Fs) fs.Fs {
+	best, bestFree := upstreams[0], int64(unknownFree)
+	for _, upstream := range upstreams {
+		if free := free(upstream); free > bestFree {
+			best, bestFree = upstream, free
+		}
+	}
+	return best
+}
+
+// createUpstream chooses the remote to make a new file or directory
+// in dir on according to the create policy
+func (f *Fs) createUpstream(dir string) (fs.Fs, error) {
+	switch f.policy {
+	case policyFirstFound:
+		return f.remotes[0], nil
+	case policyMostFree:
+		return mostFree(f.remotes), nil
+	}
+	// Find the remotes with the deepest existing part of the
+	// path so the new file or directory is put with its neighbours
+	for {
+		var existing []fs.Fs
+		for _, upstream := range f.remotes {
+			ok, err := dirExists(upstream, dir)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				existing = append(existing, upstream)
+			}
+		}
+		if len(existing) > 0 {
+			return mostFree(existing), nil
+		}
+		if dir == "" {
+			return mostFree(f.remotes), nil
+		}
+		dir = path.Dir(dir)
+		if dir == "." {
+			dir = ""
+		}
+	}
+}
+
+// parentDir returns the directory remote is in
+func parentDir(remote string) string {
+	dir := path.Dir(remote)
+	if dir == "." {
+		dir = ""
+	}
+	return dir
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// If the object exists already it is updated on the remote it is on,
+// otherwise it is made on the remote chosen by the create policy.
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	existing, err := f.NewObject(src.Remote())
+	if err == nil {
+		return existing, existing.Update(in, src, options...)
+	} else if err != fs.ErrorObjectNotFound {
+		return nil, err
+	}
+	upstream, err := f.createUpstream(parentDir(src.Remote()))
+	if err != nil {
+		return nil, err
+	}
+	o, err := upstream.Put(in, src, options...)
+	if err != nil {
+		if o != nil {
+			return f.wrapObject(o, upstream), err
+		}
+		return nil, err
+	}
+	return f.wrapObject(o, upstream), nil
+}
+
+// Mkdir makes the directory on the remote chosen by the create
+// policy, or makes the root on all the remotes.
+func (f *Fs) Mkdir(dir string) error {
+	if dir == "" {
+		for _, upstream := range f.remotes {
+			err := upstream.Mkdir(dir)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, upstream := range f.remotes {
+		ok, err := dirExists(upstream, dir)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+	upstream, err := f.createUpstream(parentDir(dir))
+	if err != nil {
+		return err
+	}
+	return upstream.Mkdir(dir)
+}
+
+// Rmdir removes the directory from all the remotes it is on
+//
+// Returns an error if it isn't empty
+func (f *Fs) Rmdir(dir string) error {
+	found := false
+	for _, upstream := range f.remotes {
+		ok, err := dirExists(upstream, dir)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		err = upstream.Rmdir(dir)
+		if err != nil {
+			return err
+		}
+		found = true
+	}
+	if !found {
+		return fs.ErrorDirNotFound
+	}
+	return nil
+}
+
+// dstUpstream returns the remote of f at the same position in the
+// union as the remote src is on, or nil if the remotes of the unions
+// don't line up.
+func (f *Fs) dstUpstream(src *Object) fs.Fs {
+	if len(src.fs.remotes) != len(f.remotes) {
+		return nil
+	}
+	for i, upstream := range src.fs.remotes {
+		if upstream == src.upstream {
+			return f.remotes[i]
+		}
+	}
+	return nil
+}
+
+// Copy src to this remote using server side copy operations.
+//
+// This is only possible if src is on a remote of the union which can
+// do server side copies, and the copy is made on the same remote.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't copy - not same remote type")
+		return nil, fs.ErrorCantCopy
+	}
+	upstream := f.dstUpstream(srcObj)
+	if upstream == nil {
+		fs.Debugf(src, "Can't copy - remotes of the unions don't match")
+		return nil, fs.ErrorCantCopy
+	}
+	doCopy := upstream.Features().Copy
+	if doCopy == nil {
+		return nil, fs.ErrorCantCopy
+	}
+	o, err := doCopy(srcObj.Object, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.wrapObject(o, upstream), nil
+}
+
+// Move src to this remote using server side move operations.
+//
+// This is only possible if src is on a remote of the union which can
+// do server side moves, and the object stays on the same remote.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	upstream := f.dstUpstream(srcObj)
+	if upstream == nil {
+		fs.Debugf(src, "Can't move - remotes of the unions don't match")
+		return nil, fs.ErrorCantMove
+	}
+	doMove := upstream.Features().Move
+	if doMove == nil {
+		return nil, fs.ErrorCantMove
+	}
+	o, err := doMove(srcObj.Object, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.wrapObject(o, upstream), nil
+}
+
+// About gets quota information adding up the remotes which can
+// supply it
+func (f *Fs) About() (*fs.Usage, error) {
+	usage := &fs.Usage{}
+	add := func(total **int64, value *int64) {
+		if value == nil {
+			return
+		}
+		if *total == nil {
+			*total = fs.NewUsageValue(0)
+		}
+		**total += *value
+	}
+	for _, upstream := range f.remotes {
+		about := upstream.Features().About
+		if about == nil {
+			continue
+		}
+		upstreamUsage, err := about()
+		if err != nil {
+			return nil, err
+		}
+		add(&usage.Total, upstreamUsage.Total)
+		add(&usage.Used, upstreamUsage.Used)
+		add(&usage.Trashed, upstreamUsage.Trashed)
+		add(&usage.Other, upstreamUsage.Other)
+		add(&usage.Free, upstreamUsage.Free)
+	}
+	return usage, nil
+}
+
+// String returns a description of the Object
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Object.String()
+}
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// MimeType returns the content type of the Object from the remote it
+// is on if it knows it, or from its name if not
+func (o *Object) MimeType() string {
+	return fs.MimeType(o.Object)
+}
+
+// UnWrap returns the Object that this Object is wrapping
+func (o *Object) UnWrap() fs.Object {
+	return o.Object
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.Copier          = (*Fs)(nil)
+	_ fs.Mover           = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.Object          = (*Object)(nil)
+	_ fs.MimeTyper       = (*Object)(nil)
+	_ fs.ObjectUnWrapper = (*Object)(nil)
+)
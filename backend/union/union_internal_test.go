@@ -0,0 +1,255 @@
+package union
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/ncw/rclone/backend/local" // pull in test backend
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const remoteName = "TestUnionInternal"
+
+// prepare makes count directories to union with files in, returning
+// them and a function to clean up
+func prepare(t *testing.T, policy string, count int) (dirs []string, cleanup func()) {
+	config.LoadConfig()
+	for i := 0; i < count; i++ {
+		dir, err := ioutil.TempDir("", "rclone-union")
+		require.NoError(t, err)
+		dirs = append(dirs, dir)
+	}
+	config.FileSet(remoteName, "type", "union")
+	config.FileSet(remoteName, "remotes", `"`+strings.Join(dirs, `" "`)+`"`)
+	config.FileSet(remoteName, "create_policy", policy)
+	return dirs, func() {
+		for _, dir := range dirs {
+			require.NoError(t, os.RemoveAll(dir))
+		}
+	}
+}
+
+// writeFile writes contents to name in dir making the directories
+func writeFile(t *testing.T, dir, name, contents string) {
+	name = filepath.Join(dir, filepath.FromSlash(name))
+	require.NoError(t, os.MkdirAll(filepath.Dir(name), 0777))
+	require.NoError(t, ioutil.WriteFile(name, []byte(contents), 0666))
+}
+
+// readFile returns the contents of name in dir or "" if it doesn't
+// exist
+func readFile(t *testing.T, dir, name string) string {
+	data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return ""
+	}
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestParseRemotes(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"a: b:dir", []string{"a:", "b:dir"}},
+		{"a:  /local/dir ", []string{"a:", "/local/dir"}},
+		{`"a:space dir" b:`, []string{"a:space dir", "b:"}},
+	} {
+		got, err := parseRemotes(test.in)
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+	_, err := parseRemotes(`"a: b:`)
+	assert.Error(t, err)
+}
+
+func TestNewFsErrors(t *testing.T) {
+	_, cleanup := prepare(t, "potato", 1)
+	defer cleanup()
+	_, err := fs.NewFs(remoteName + ":")
+	assert.Error(t, err)
+
+	config.FileSet(remoteName, "create_policy", "ff")
+	config.FileSet(remoteName, "remotes", remoteName+":dir")
+	_, err = fs.NewFs(remoteName + ":")
+	assert.Error(t, err)
+
+	config.FileSet(remoteName, "remotes", "")
+	_, err = fs.NewFs(remoteName + ":")
+	assert.Error(t, err)
+}
+
+func TestList(t *testing.T) {
+	dirs, cleanup := prepare(t, "ff", 2)
+	defer cleanup()
+	writeFile(t, dirs[0], "both.txt", "first")
+	writeFile(t, dirs[1], "both.txt", "second")
+	writeFile(t, dirs[0], "dir/a.txt", "a")
+	writeFile(t, dirs[1], "dir/b.txt", "b")
+	writeFile(t, dirs[1], "only/c.txt", "c")
+
+	f, err := fs.NewFs(remoteName + ":")
+	require.NoError(t, err)
+
+	entries, err := f.List("")
+	require.NoError(t, err)
+	sort.Sort(entries)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Remote())
+		if o, ok := entry.(fs.Object); ok {
+			assert.Equal(t, f, o.Fs())
+			assert.Equal(t, int64(len("first")), o.Size())
+		}
+	}
+	assert.Equal(t, []string{"both.txt", "dir", "only"}, names)
+
+	entries, err = f.List("dir")
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+
+	_, err = f.List("potato")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+
+	o, err := f.NewObject("only/c.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), o.Size())
+	_, err = f.NewObject("potato.txt")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	// Pointing at a file
+	_, err = fs.NewFs(remoteName + ":only/c.txt")
+	assert.Equal(t, fs.ErrorIsFile, err)
+}
+
+func TestCreatePolicies(t *testing.T) {
+	dirs, cleanup := prepare(t, "ff", 2)
+	defer cleanup()
+	writeFile(t, dirs[1], "existing/file.txt", "existing")
+	writeFile(t, dirs[1], "update.txt", "old")
+
+	// ff puts everything on the first remote
+	f, err := fs.NewFs(remoteName + ":")
+	require.NoError(t, err)
+	fstest.PutContents(t, f, "ff/ff.txt", "ff", time.Now())
+	assert.Equal(t, "ff", readFile(t, dirs[0], "ff/ff.txt"))
+
+	// epmfs puts files with their existing directory
+	config.FileSet(remoteName, "create_policy", "epmfs")
+	f, err = fs.NewFs(remoteName + ":")
+	require.NoError(t, err)
+	fstest.PutContents(t, f, "existing/epmfs.txt", "epmfs", time.Now())
+	assert.Equal(t, "epmfs", readFile(t, dirs[1], "existing/epmfs.txt"))
+	fstest.PutContents(t, f, "existing/new/deeper.txt", "deeper", time.Now())
+	assert.Equal(t, "deeper", readFile(t, dirs[1], "existing/new/deeper.txt"))
+	require.NoError(t, f.Mkdir("existing/newdir"))
+	_, err = os.Stat(filepath.Join(dirs[1], "existing", "newdir"))
+	assert.NoError(t, err)
+
+	// Existing files are updated where they are
+	o := fstest.PutContents(t, f, "update.txt", "new", time.Now())
+	assert.Equal(t, "new", readFile(t, dirs[1], "update.txt"))
+	assert.Equal(t, "", readFile(t, dirs[0], "update.txt"))
+
+	// Removing an object removes it from its remote
+	require.NoError(t, o.Remove())
+	assert.Equal(t, "", readFile(t, dirs[1], "update.txt"))
+
+	// Rmdir removes the directory from all the remotes
+	require.NoError(t, os.MkdirAll(filepath.Join(dirs[0], "empty"), 0777))
+	require.NoError(t, os.MkdirAll(filepath.Join(dirs[1], "empty"), 0777))
+	require.NoError(t, f.Rmdir("empty"))
+	for _, dir := range dirs {
+		_, err = os.Stat(filepath.Join(dir, "empty"))
+		assert.True(t, os.IsNotExist(err))
+	}
+	assert.Equal(t, fs.ErrorDirNotFound, f.Rmdir("empty"))
+}
+
+func TestMoveBetweenRoots(t *testing.T) {
+	dirs, cleanup := prepare(t, "ff", 2)
+	defer cleanup()
+	writeFile(t, dirs[1], "src/file.txt", "contents")
+
+	fsrc, err := fs.NewFs(remoteName + ":src")
+	require.NoError(t, err)
+	fdst, err := fs.NewFs(remoteName + ":dst")
+	require.NoError(t, err)
+	udst := fdst.(*Fs)
+
+	// The move is done on the same remote under the destination root
+	src, err := fsrc.NewObject("file.txt")
+	require.NoError(t, err)
+	o, err := udst.Move(src, "moved.txt")
+	require.NoError(t, err)
+	assert.Equal(t, fdst, o.Fs())
+	assert.Equal(t, "contents", readFile(t, dirs[1], "dst/moved.txt"))
+	assert.Equal(t, "", readFile(t, dirs[1], "src/file.txt"))
+	assert.Equal(t, "", readFile(t, dirs[1], "src/moved.txt"))
+	assert.Equal(t, "", readFile(t, dirs[0], "dst/moved.txt"))
+
+	// Unions whose remotes don't line up can't copy or move
+	src, err = fdst.NewObject("moved.txt")
+	require.NoError(t, err)
+	usrc := fsrc.(*Fs)
+	usrc.remotes = usrc.remotes[:1]
+	_, err = usrc.Copy(src, "copied.txt")
+	assert.Equal(t, fs.ErrorCantCopy, err)
+	_, err = usrc.Move(src, "moved.txt")
+	assert.Equal(t, fs.ErrorCantMove, err)
+}
+
+// freeFs is an Fs with a given amount of free space
+type freeFs struct {
+	fs.Fs
+	free     int64
+	features *fs.Features
+}
+
+func newFreeFs(f fs.Fs, free int64) *freeFs {
+	ff := &freeFs{Fs: f, free: free}
+	ff.features = &fs.Features{About: ff.About}
+	return ff
+}
+
+func (f *freeFs) Features() *fs.Features { return f.features }
+
+func (f *freeFs) About() (*fs.Usage, error) {
+	return &fs.Usage{Free: fs.NewUsageValue(f.free)}, nil
+}
+
+func TestMostFree(t *testing.T) {
+	dirs, cleanup := prepare(t, "mfs", 3)
+	defer cleanup()
+	var upstreams []fs.Fs
+	for i, free := range []int64{10, 30, 20} {
+		f, err := fs.NewFs(dirs[i])
+		require.NoError(t, err)
+		upstreams = append(upstreams, newFreeFs(f, free))
+	}
+	assert.Equal(t, upstreams[1], mostFree(upstreams))
+
+	f, err := fs.NewFs(remoteName + ":")
+	require.NoError(t, err)
+	u := f.(*Fs)
+	u.remotes = upstreams
+	fstest.PutContents(t, u, "mfs.txt", "mfs", time.Now())
+	assert.Equal(t, "mfs", readFile(t, dirs[1], "mfs.txt"))
+
+	usage, err := u.About()
+	require.NoError(t, err)
+	require.NotNil(t, usage.Free)
+	assert.Equal(t, int64(60), *usage.Free)
+	assert.Nil(t, usage.Total)
+}
@@ -132,7 +132,7 @@ func NewFs(name, root string) (fs.Fs, error) {
 	}
 
 	//create new client
-	yandexDisk := yandex.NewClient(token.AccessToken, fshttp.NewClient(fs.Config))
+	yandexDisk := yandex.NewClient(token.AccessToken, fshttp.NewClient(fs.ConfigForRemote(name)))
 
 	f := &Fs{
 		name: name,
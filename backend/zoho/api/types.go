@@ -0,0 +1,166 @@
+// Package api has type definitions for Zoho WorkDrive
+//
+// The API follows the JSON:API conventions - see
+// https://workdrive.zoho.com/apidocs/v1/overview
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Types of resources
+const (
+	TypeFiles     = "files"
+	TypeWorkspace = "workspaces"
+)
+
+// Values of the status attribute of files
+const (
+	StatusActive  = "1"
+	StatusTrashed = "51"
+)
+
+// User is returned by /users/me
+type User struct {
+	Data struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+// Team is a WorkDrive team
+type Team struct {
+	ID         string `json:"id"`
+	Attributes struct {
+		Name string `json:"name"`
+	} `json:"attributes"`
+}
+
+// TeamList is returned when listing the teams of a user
+type TeamList struct {
+	Teams []Team `json:"data"`
+}
+
+// Workspace is a team folder
+type Workspace struct {
+	ID         string `json:"id"`
+	Attributes struct {
+		Name string `json:"name"`
+	} `json:"attributes"`
+}
+
+// WorkspaceList is returned when listing the team folders of a team
+type WorkspaceList struct {
+	Workspaces []Workspace `json:"data"`
+}
+
+// CurrentUser is returned by /teams/{id}/currentuser and has the ID
+// of the user in the team
+type CurrentUser struct {
+	Data struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+// PrivateSpaceList is returned when listing the My Folders of a team
+// member
+type PrivateSpaceList struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+// Item is a file or folder
+type Item struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Attributes struct {
+		Name        string `json:"name"`
+		IsFolder    bool   `json:"is_folder"`
+		ParentID    string `json:"parent_id"`
+		Status      string `json:"status"`
+		StorageInfo struct {
+			Size int64 `json:"size_in_bytes"`
+		} `json:"storage_info"`
+		ModifiedTime int64 `json:"modified_time_in_millisecond"`
+	} `json:"attributes"`
+}
+
+// ModTime returns the modification time of the item
+func (i *Item) ModTime() time.Time {
+	return time.Unix(0, i.Attributes.ModifiedTime*int64(time.Millisecond))
+}
+
+// ItemInfo is returned when reading a single item
+type ItemInfo struct {
+	Item Item `json:"data"`
+}
+
+// ItemList is returned when listing a folder
+type ItemList struct {
+	Items []Item `json:"data"`
+}
+
+// WriteAttributes are the attributes which can be set on an item
+type WriteAttributes struct {
+	Name       string `json:"name,omitempty"`
+	ParentID   string `json:"parent_id,omitempty"`
+	ResourceID string `json:"resource_id,omitempty"`
+	Status     string `json:"status,omitempty"`
+}
+
+// WriteMetadata is a resource to create or update
+type WriteMetadata struct {
+	Attributes WriteAttributes `json:"attributes"`
+	ID         string          `json:"id,omitempty"`
+	Type       string          `json:"type"`
+}
+
+// WriteMetadataRequest creates or updates a single resource
+type WriteMetadataRequest struct {
+	Data WriteMetadata `json:"data"`
+}
+
+// WriteMultiMetadataRequest creates or updates several resources
+type WriteMultiMetadataRequest struct {
+	Data []WriteMetadata `json:"data"`
+}
+
+// UploadResult is one of the files returned by an upload
+type UploadResult struct {
+	Attributes struct {
+		ResourceID string `json:"resource_id"`
+		ParentID   string `json:"parent_id"`
+		FileName   string `json:"FileName"`
+	} `json:"attributes"`
+}
+
+// UploadResponse is returned by the upload endpoints
+type UploadResponse struct {
+	Uploads []UploadResult `json:"data"`
+}
+
+// ErrorDetail is one of the errors in an Error
+type ErrorDetail struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// Error is returned from Zoho when things go wrong
+type Error struct {
+	Errors     []ErrorDetail `json:"errors"`
+	StatusCode int           `json:"-"`
+}
+
+// Error returns a string for the error and satisfies the error interface
+func (e *Error) Error() string {
+	var titles []string
+	for _, detail := range e.Errors {
+		titles = append(titles, fmt.Sprintf("%s (%s)", detail.Title, detail.ID))
+	}
+	return fmt.Sprintf("HTTP error %d: %s", e.StatusCode, strings.Join(titles, ", "))
+}
+
+// Check Error satisfies the error interface
+var _ error = (*Error)(nil)
@@ -0,0 +1,59 @@
+// upload sessions for zoho
+
+package zoho
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/ncw/rclone/backend/zoho/api"
+	"github.com/ncw/rclone/lib/rest"
+	"github.com/pkg/errors"
+)
+
+// uploadSessions counts the upload sessions started
+var uploadSessions int64
+
+// newUploadID makes an ID to identify an upload session with
+func newUploadID() string {
+	return "rclone" + strconv.FormatInt(atomic.AddInt64(&uploadSessions, 1), 10)
+}
+
+// uploadSession uploads the object in a single stream to the upload
+// server
+//
+// Zoho recommends this for files over 10 MB.  The upload server
+// reads the data as it arrives rather than as a multipart form, so
+// it copes with large and unknown size files.  size may be -1 if
+// unknown.
+func (o *Object) uploadSession(in io.Reader, leaf, directoryID string, size int64) (result *api.UploadResponse, err error) {
+	opts := rest.Opts{
+		Method:      "POST",
+		Path:        "/stream/upload",
+		RootURL:     o.fs.uploadURL,
+		Body:        in,
+		ContentType: "application/octet-stream",
+		ExtraHeaders: map[string]string{
+			"x-filename":          url.QueryEscape(leaf),
+			"x-parent_id":         directoryID,
+			"upload-id":           newUploadID(),
+			"x-streammode":        "1",
+			"override-name-exist": "true",
+		},
+	}
+	if size >= 0 {
+		opts.ContentLength = &size
+	}
+	var resp *http.Response
+	err = o.fs.pacer.CallNoRetry(func() (bool, error) {
+		resp, err = o.fs.srv.CallJSON(&opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "upload session failed")
+	}
+	return result, nil
+}
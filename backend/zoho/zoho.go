@@ -0,0 +1,1169 @@
+// Package zoho provides an interface to the Zoho WorkDrive
+// object storage system.
+package zoho
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/backend/zoho/api"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/lib/dircache"
+	"github.com/ncw/rclone/lib/oauthutil"
+	"github.com/ncw/rclone/lib/pacer"
+	"github.com/ncw/rclone/lib/rest"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+const (
+	minSleep        = 10 * time.Millisecond
+	maxSleep        = 2 * time.Second
+	decayConstant   = 2 // bigger for slower decay, exponential
+	listChunks      = 50
+	defaultRegion   = "com"
+	configRegion    = "region"
+	configRootID    = "root_folder_id"
+	accountsPrefix  = "https://accounts.zoho."
+	minUploadCutoff = 1024 * 1024 // upload cutoff can be no lower than this
+)
+
+// Globals
+var (
+	// Endpoints for a region - these are variables so the tests can
+	// point them at a fake server
+	apiURLFormat      = "https://www.zohoapis.%s/workdrive/api/v1"
+	uploadURLFormat   = "https://upload.zoho.%s/workdrive-api/v1"
+	accountsURLFormat = accountsPrefix + "%s"
+
+	uploadCutoff = fs.SizeSuffix(10 * 1024 * 1024)
+)
+
+// newOauthConfig returns the config to authorize with for region
+//
+// Zoho doesn't have an rclone app so the client ID and secret always
+// come from the config file.
+func newOauthConfig(region string) *oauth2.Config {
+	accountsURL := fmt.Sprintf(accountsURLFormat, region)
+	return &oauth2.Config{
+		// Zoho wants the scopes separated by commas
+		Scopes: []string{"aaaserver.profile.read,WorkDrive.team.READ,WorkDrive.workspace.READ,WorkDrive.files.ALL"},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  accountsURL + "/oauth/v2/auth",
+			TokenURL: accountsURL + "/oauth/v2/token",
+		},
+		RedirectURL: oauthutil.RedirectLocalhostURL,
+	}
+}
+
+// Register with Fs
+func init() {
+	// Zoho wants the client ID and secret in the body of token
+	// requests
+	oauth2.RegisterBrokenAuthHeaderProvider(accountsPrefix)
+	fs.Register(&fs.RegInfo{
+		Name:        "zoho",
+		Description: "Zoho WorkDrive",
+		NewFs:       NewFs,
+		Config: func(name string) {
+			oauthConfig := newOauthConfig(config.FileGet(name, configRegion, defaultRegion))
+			// prompt=consent is needed to get a refresh token
+			err := oauthutil.Config("zoho", name, oauthConfig, oauth2.SetAuthURLParam("prompt", "consent"))
+			if err != nil {
+				log.Fatalf("Failed to configure token: %v", err)
+			}
+			// Are we running headless?
+			if config.FileGet(name, config.ConfigAutomatic) != "" {
+				// Yes, okay we are done
+				return
+			}
+			err = configRoot(name, oauthConfig)
+			if err != nil {
+				log.Fatalf("Failed to configure the team folder: %v", err)
+			}
+		},
+		Options: []fs.Option{{
+			Name: config.ConfigClientID,
+			Help: "Zoho App Client Id from https://api-console.zoho.com/",
+		}, {
+			Name: config.ConfigClientSecret,
+			Help: "Zoho App Client Secret from https://api-console.zoho.com/",
+		}, {
+			Name: configRegion,
+			Help: "Zoho region of your account - the end of the domain you log in to.",
+			Examples: []fs.OptionExample{{
+				Value: "com",
+				Help:  "United States / Global",
+			}, {
+				Value: "eu",
+				Help:  "Europe",
+			}, {
+				Value: "in",
+				Help:  "India",
+			}, {
+				Value: "com.au",
+				Help:  "Australia",
+			}, {
+				Value: "jp",
+				Help:  "Japan",
+			}, {
+				Value: "com.cn",
+				Help:  "China",
+			}},
+		}, {
+			Name: configRootID,
+			Help: "ID of the folder to use as the root - set by the config wizard.",
+			Hide: true,
+		}},
+	})
+	flags.VarP(&uploadCutoff, "zoho-upload-cutoff", "", "Cutoff for switching to upload sessions")
+}
+
+// configRoot asks the user for the team and team folder to use and
+// stores its ID in the config
+func configRoot(name string, oauthConfig *oauth2.Config) error {
+	oAuthClient, _, err := oauthutil.NewClient(name, oauthConfig)
+	if err != nil {
+		return err
+	}
+	srv := rest.NewClient(oAuthClient).SetRoot(fmt.Sprintf(apiURLFormat, config.FileGet(name, configRegion, defaultRegion)))
+	srv.SetErrorHandler(errorHandler)
+	srv.SetHeader("Accept", "application/vnd.api+json")
+	get := func(path string, result interface{}) error {
+		opts := rest.Opts{
+			Method: "GET",
+			Path:   path,
+		}
+		_, err := srv.CallJSON(&opts, nil, result)
+		return err
+	}
+
+	var user api.User
+	if err = get("/users/me", &user); err != nil {
+		return errors.Wrap(err, "couldn't read user")
+	}
+	var teams api.TeamList
+	if err = get("/users/"+user.Data.ID+"/teams", &teams); err != nil {
+		return errors.Wrap(err, "couldn't list teams")
+	}
+	var teamIDs, teamNames []string
+	for _, team := range teams.Teams {
+		teamIDs = append(teamIDs, team.ID)
+		teamNames = append(teamNames, team.Attributes.Name)
+	}
+	var teamID string
+	switch len(teamIDs) {
+	case 0:
+		return errors.New("no teams found")
+	case 1:
+		teamID = teamIDs[0]
+	default:
+		teamID = config.Choose("Team", teamIDs, teamNames, false)
+	}
+
+	// Offer My Folders and the team folders
+	var rootIDs, rootNames []string
+	var member api.CurrentUser
+	if err = get("/teams/"+teamID+"/currentuser", &member); err != nil {
+		return errors.Wrap(err, "couldn't read team member")
+	}
+	var privateSpaces api.PrivateSpaceList
+	if err = get("/users/"+member.Data.ID+"/privatespace", &privateSpaces); err != nil {
+		return errors.Wrap(err, "couldn't read My Folders")
+	}
+	for _, privateSpace := range privateSpaces.Data {
+		rootIDs = append(rootIDs, privateSpace.ID)
+		rootNames = append(rootNames, "My Folders")
+	}
+	var workspaces api.WorkspaceList
+	if err = get("/teams/"+teamID+"/workspaces", &workspaces); err != nil {
+		return errors.Wrap(err, "couldn't list team folders")
+	}
+	for _, workspace := range workspaces.Workspaces {
+		rootIDs = append(rootIDs, workspace.ID)
+		rootNames = append(rootNames, "Team folder "+workspace.Attributes.Name)
+	}
+	if len(rootIDs) == 0 {
+		return errors.New("no folders found")
+	}
+	config.FileSet(name, configRootID, config.Choose("Root folder", rootIDs, rootNames, false))
+	return nil
+}
+
+// Fs represents a remote zoho
+type Fs struct {
+	name         string             // name of this remote
+	root         string             // the path we are working on
+	features     *fs.Features       // optional features
+	srv          *rest.Client       // the connection to the server
+	uploadURL    string             // the root of the upload API
+	dirCache     *dircache.DirCache // Map of directory path to directory id
+	pacer        *pacer.Pacer       // pacer for API calls
+	tokenRenewer *oauthutil.Renew   // renew the token on expiry
+}
+
+// Object describes a zoho object
+//
+// Will definitely have info but maybe not meta
+type Object struct {
+	fs          *Fs       // what this object is part of
+	remote      string    // The remote path
+	hasMetaData bool      // whether info below has been set
+	size        int64     // size of the object
+	modTime     time.Time // modification time of the object
+	id          string    // ID of the object
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("zoho root '%s'", f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+	509, // Bandwidth Limit Exceeded
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(resp *http.Response, err error) (bool, error) {
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// errorHandler parses a non 2xx error response into an error
+func errorHandler(resp *http.Response) error {
+	// Decode error response
+	errResponse := new(api.Error)
+	err := rest.DecodeJSON(resp, &errResponse)
+	if err != nil {
+		fs.Debugf(nil, "Couldn't decode error response: %v", err)
+	}
+	errResponse.StatusCode = resp.StatusCode
+	return errResponse
+}
+
+// isNotFound returns whether err is a not found error
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*api.Error)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// readMetaDataForPath reads the metadata from the path
+func (f *Fs) readMetaDataForPath(path string) (info *api.Item, err error) {
+	leaf, directoryID, err := f.dirCache.FindRootAndPath(path, false)
+	if err != nil {
+		if err == fs.ErrorDirNotFound {
+			return nil, fs.ErrorObjectNotFound
+		}
+		return nil, err
+	}
+
+	found, err := f.listAll(directoryID, false, true, func(item *api.Item) bool {
+		if strings.EqualFold(item.Attributes.Name, leaf) {
+			info = item
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return info, nil
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(name, root string) (fs.Fs, error) {
+	if uploadCutoff < minUploadCutoff {
+		return nil, errors.Errorf("zoho: upload cutoff (%v) must be greater than equal to %v", uploadCutoff, fs.SizeSuffix(minUploadCutoff))
+	}
+	if config.FileGet(name, config.ConfigClientID) == "" || config.FileGet(name, config.ConfigClientSecret) == "" {
+		return nil, errors.New("client_id and client_secret must be set - register an app at https://api-console.zoho.com/")
+	}
+	rootID := config.FileGet(name, configRootID)
+	if rootID == "" {
+		return nil, errors.New("no root folder set - run rclone config to choose a team folder")
+	}
+	region := config.FileGet(name, configRegion, defaultRegion)
+
+	root = strings.Trim(root, "/")
+	oAuthClient, ts, err := oauthutil.NewClient(name, newOauthConfig(region))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to configure Zoho")
+	}
+
+	f := &Fs{
+		name:      name,
+		root:      root,
+		srv:       rest.NewClient(oAuthClient).SetRoot(fmt.Sprintf(apiURLFormat, region)),
+		uploadURL: fmt.Sprintf(uploadURLFormat, region),
+		pacer:     pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant),
+	}
+	f.features = (&fs.Features{
+		CaseInsensitive:         true,
+		CanHaveEmptyDirectories: true,
+	}).Fill(f)
+	f.srv.SetErrorHandler(errorHandler)
+	f.srv.SetHeader("Accept", "application/vnd.api+json")
+
+	// Renew the token in the background
+	f.tokenRenewer = oauthutil.NewRenew(f.String(), ts, func() error {
+		_, err := f.readMetaDataForPath("")
+		return err
+	})
+
+	f.dirCache = dircache.New(root, rootID, f)
+
+	// Find the current root
+	err = f.dirCache.FindRoot(false)
+	if err != nil {
+		// Assume it is a file
+		newRoot, remote := dircache.SplitPath(root)
+		newF := *f
+		newF.dirCache = dircache.New(newRoot, rootID, &newF)
+		newF.root = newRoot
+		// Make new Fs which is the parent
+		err = newF.dirCache.FindRoot(false)
+		if err != nil {
+			// No root so return old f
+			return f, nil
+		}
+		_, err := newF.newObjectWithInfo(remote, nil)
+		if err != nil {
+			if err == fs.ErrorObjectNotFound {
+				// File doesn't exist so return old f
+				return f, nil
+			}
+			return nil, err
+		}
+		// return an error with an fs which points to the parent
+		return &newF, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// Return an Object from a path
+//
+// If it can't be found it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) newObjectWithInfo(remote string, info *api.Item) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	var err error
+	if info != nil {
+		// Set info
+		err = o.setMetaData(info)
+	} else {
+		err = o.readMetaData() // reads info and meta, returning an error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(remote string) (fs.Object, error) {
+	return f.newObjectWithInfo(remote, nil)
+}
+
+// FindLeaf finds a directory of name leaf in the folder with ID pathID
+func (f *Fs) FindLeaf(pathID, leaf string) (pathIDOut string, found bool, err error) {
+	// Find the leaf in pathID
+	found, err = f.listAll(pathID, true, false, func(item *api.Item) bool {
+		if strings.EqualFold(item.Attributes.Name, leaf) {
+			pathIDOut = item.ID
+			return true
+		}
+		return false
+	})
+	return pathIDOut, found, err
+}
+
+// CreateDir makes a directory with pathID as parent and name leaf
+func (f *Fs) CreateDir(pathID, leaf string) (newID string, err error) {
+	var resp *http.Response
+	var info api.ItemInfo
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/files",
+	}
+	mkdir := api.WriteMetadataRequest{
+		Data: api.WriteMetadata{
+			Attributes: api.WriteAttributes{
+				Name:     leaf,
+				ParentID: pathID,
+			},
+			Type: api.TypeFiles,
+		},
+	}
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(&opts, &mkdir, &info)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return "", err
+	}
+	return info.Item.ID, nil
+}
+
+// list the objects into the function supplied
+//
+// If directories is set it only sends directories
+// User function to process a File item from listAll
+//
+// Should return true to finish processing
+type listAllFn func(*api.Item) bool
+
+// Lists the directory required calling the user function on each item found
+//
+// If the user fn ever returns true then it early exits with found = true
+func (f *Fs) listAll(dirID string, directoriesOnly bool, filesOnly bool, fn listAllFn) (found bool, err error) {
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       "/files/" + dirID + "/files",
+		Parameters: url.Values{},
+	}
+	opts.Parameters.Set("page[limit]", strconv.Itoa(listChunks))
+	offset := 0
+OUTER:
+	for {
+		opts.Parameters.Set("page[offset]", strconv.Itoa(offset))
+
+		var result api.ItemList
+		var resp *http.Response
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err = f.srv.CallJSON(&opts, nil, &result)
+			return shouldRetry(resp, err)
+		})
+		if err != nil {
+			return found, errors.Wrap(err, "couldn't list files")
+		}
+		for i := range result.Items {
+			item := &result.Items[i]
+			if item.Attributes.Status == api.StatusTrashed {
+				continue
+			}
+			if item.Attributes.IsFolder {
+				if filesOnly {
+					continue
+				}
+			} else {
+				if directoriesOnly {
+					continue
+				}
+			}
+			if fn(item) {
+				found = true
+				break OUTER
+			}
+		}
+		if len(result.Items) < listChunks {
+			break
+		}
+		offset += len(result.Items)
+	}
+	return
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+	err = f.dirCache.FindRoot(false)
+	if err != nil {
+		return nil, err
+	}
+	directoryID, err := f.dirCache.FindDir(dir, false)
+	if err != nil {
+		return nil, err
+	}
+	var iErr error
+	_, err = f.listAll(directoryID, false, false, func(info *api.Item) bool {
+		remote := path.Join(dir, info.Attributes.Name)
+		if info.Attributes.IsFolder {
+			// cache the directory ID for later lookups
+			f.dirCache.Put(remote, info.ID)
+			d := fs.NewDir(remote, info.ModTime()).SetID(info.ID)
+			entries = append(entries, d)
+		} else {
+			o, err := f.newObjectWithInfo(remote, info)
+			if err != nil {
+				iErr = err
+				return true
+			}
+			entries = append(entries, o)
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if iErr != nil {
+		return nil, iErr
+	}
+	return entries, nil
+}
+
+// Creates from the parameters passed in a half finished Object which
+// must have setMetaData called on it
+//
+// Returns the object, leaf, directoryID and error
+//
+// Used to create new objects
+func (f *Fs) createObject(remote string) (o *Object, leaf string, directoryID string, err error) {
+	// Create the directory for the object if it doesn't exist
+	leaf, directoryID, err = f.dirCache.FindRootAndPath(remote, true)
+	if err != nil {
+		return
+	}
+	// Temporary Object under construction
+	o = &Object{
+		fs:     f,
+		remote: remote,
+	}
+	return o, leaf, directoryID, nil
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	existingObj, err := f.newObjectWithInfo(src.Remote(), nil)
+	switch err {
+	case nil:
+		return existingObj, existingObj.Update(in, src, options...)
+	case fs.ErrorObjectNotFound:
+		// Not found so create it
+		return f.PutUnchecked(in, src)
+	default:
+		return nil, err
+	}
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(in, src, options...)
+}
+
+// PutUnchecked the object into the container
+//
+// This will produce an error if the object already exists
+//
+// Copy the reader in to the new object which is returned
+//
+// The new object may have been created if an error is returned
+func (f *Fs) PutUnchecked(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o, _, _, err := f.createObject(src.Remote())
+	if err != nil {
+		return nil, err
+	}
+	return o, o.Update(in, src, options...)
+}
+
+// Mkdir creates the container if it doesn't exist
+func (f *Fs) Mkdir(dir string) error {
+	err := f.dirCache.FindRoot(true)
+	if err != nil {
+		return err
+	}
+	if dir != "" {
+		_, err = f.dirCache.FindDir(dir, true)
+	}
+	return err
+}
+
+// updateItem changes the attributes of the item with id returning
+// the new item
+func (f *Fs) updateItem(id string, attributes api.WriteAttributes) (info *api.Item, err error) {
+	opts := rest.Opts{
+		Method: "PATCH",
+		Path:   "/files/" + id,
+	}
+	update := api.WriteMetadataRequest{
+		Data: api.WriteMetadata{
+			Attributes: attributes,
+			ID:         id,
+			Type:       api.TypeFiles,
+		},
+	}
+	var result api.ItemInfo
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(&opts, &update, &result)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result.Item, nil
+}
+
+// trash moves the item with id to the trash
+func (f *Fs) trash(id string) error {
+	_, err := f.updateItem(id, api.WriteAttributes{Status: api.StatusTrashed})
+	return err
+}
+
+// purgeCheck removes the root directory, if check is set then it
+// refuses to do so if it has anything in
+func (f *Fs) purgeCheck(dir string, check bool) error {
+	root := path.Join(f.root, dir)
+	if root == "" {
+		return errors.New("can't purge root directory")
+	}
+	dc := f.dirCache
+	err := dc.FindRoot(false)
+	if err != nil {
+		return err
+	}
+	rootID, err := dc.FindDir(dir, false)
+	if err != nil {
+		return err
+	}
+	if check {
+		found, err := f.listAll(rootID, false, false, func(item *api.Item) bool {
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		if found {
+			return fs.ErrorDirectoryNotEmpty
+		}
+	}
+	err = f.trash(rootID)
+	if err != nil {
+		return errors.Wrap(err, "rmdir failed")
+	}
+	f.dirCache.FlushDir(dir)
+	return nil
+}
+
+// Rmdir deletes the root folder
+//
+// Returns an error if it isn't empty
+func (f *Fs) Rmdir(dir string) error {
+	return f.purgeCheck(dir, true)
+}
+
+// Precision return the precision of this Fs
+func (f *Fs) Precision() time.Duration {
+	return fs.ModTimeNotSupported
+}
+
+// rename renames the item with id to leaf and moves it to
+// directoryID if it isn't there already
+func (f *Fs) rename(info *api.Item, leaf, directoryID string) (*api.Item, error) {
+	var err error
+	if info.Attributes.ParentID != directoryID {
+		info, err = f.updateItem(info.ID, api.WriteAttributes{ParentID: directoryID})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if info.Attributes.Name != leaf {
+		info, err = f.updateItem(info.ID, api.WriteAttributes{Name: leaf})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// readItem reads the item with id
+func (f *Fs) readItem(id string) (*api.Item, error) {
+	opts := rest.Opts{
+		Method: "GET",
+		Path:   "/files/" + id,
+	}
+	var result api.ItemInfo
+	var resp *http.Response
+	var err error
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(&opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result.Item, nil
+}
+
+// Copy src to this remote using server side copy operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't copy - not same remote type")
+		return nil, fs.ErrorCantCopy
+	}
+	err := srcObj.readMetaData()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create temporary object
+	dstObj, leaf, directoryID, err := f.createObject(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	// The copy keeps the name of the source so it can't be to
+	// the same directory
+	srcLeaf, srcDirectoryID, err := srcObj.fs.dirCache.FindPath(srcObj.remote, false)
+	if err != nil {
+		return nil, err
+	}
+	if srcDirectoryID == directoryID {
+		fs.Debugf(src, "Can't copy - same directory")
+		return nil, fs.ErrorCantCopy
+	}
+	// Check nothing with the source's name is in the way
+	if srcLeaf != leaf {
+		_, found, err := f.findFile(directoryID, srcLeaf)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			fs.Debugf(src, "Can't copy - %q exists in destination directory", srcLeaf)
+			return nil, fs.ErrorCantCopy
+		}
+	}
+
+	// Copy the object
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/files/" + directoryID + "/copy",
+	}
+	copyFile := api.WriteMultiMetadataRequest{
+		Data: []api.WriteMetadata{{
+			Attributes: api.WriteAttributes{
+				ResourceID: srcObj.id,
+			},
+			Type: api.TypeFiles,
+		}},
+	}
+	var resp *http.Response
+	var result api.ItemList
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(&opts, &copyFile, &result)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Items) != 1 {
+		return nil, errors.Errorf("copy returned %d items", len(result.Items))
+	}
+	info, err := f.rename(&result.Items[0], leaf, directoryID)
+	if err != nil {
+		return nil, errors.Wrap(err, "copy failed to rename")
+	}
+	err = dstObj.setMetaData(info)
+	if err != nil {
+		return nil, err
+	}
+	return dstObj, nil
+}
+
+// findFile finds the file leaf in directoryID
+func (f *Fs) findFile(directoryID, leaf string) (info *api.Item, found bool, err error) {
+	found, err = f.listAll(directoryID, false, true, func(item *api.Item) bool {
+		if strings.EqualFold(item.Attributes.Name, leaf) {
+			info = item
+			return true
+		}
+		return false
+	})
+	return info, found, err
+}
+
+// Purge deletes all the files and the container
+//
+// Optional interface: Only implement this if you have a way of
+// deleting all the files quicker than just running Remove() on the
+// result of List()
+func (f *Fs) Purge() error {
+	return f.purgeCheck("", false)
+}
+
+// Move src to this remote using server side move operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	srcInfo, err := srcObj.fs.readItem(srcObj.id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create temporary object
+	dstObj, leaf, directoryID, err := f.createObject(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	// Do the move
+	info, err := f.rename(srcInfo, leaf, directoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dstObj.setMetaData(info)
+	if err != nil {
+		return nil, err
+	}
+	return dstObj, nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	srcPath := path.Join(srcFs.root, srcRemote)
+	dstPath := path.Join(f.root, dstRemote)
+
+	// Refuse to move to or from the root
+	if srcPath == "" || dstPath == "" {
+		fs.Debugf(src, "DirMove error: Can't move root")
+		return errors.New("can't move root directory")
+	}
+
+	// find the root src directory
+	err := srcFs.dirCache.FindRoot(false)
+	if err != nil {
+		return err
+	}
+
+	// find the root dst directory
+	if dstRemote != "" {
+		err = f.dirCache.FindRoot(true)
+		if err != nil {
+			return err
+		}
+	} else {
+		if f.dirCache.FoundRoot() {
+			return fs.ErrorDirExists
+		}
+	}
+
+	// Find ID of dst parent, creating subdirs if necessary
+	var leaf, directoryID string
+	findPath := dstRemote
+	if dstRemote == "" {
+		findPath = f.root
+	}
+	leaf, directoryID, err = f.dirCache.FindPath(findPath, true)
+	if err != nil {
+		return err
+	}
+
+	// Check destination does not exist
+	if dstRemote != "" {
+		_, err = f.dirCache.FindDir(dstRemote, false)
+		if err == fs.ErrorDirNotFound {
+			// OK
+		} else if err != nil {
+			return err
+		} else {
+			return fs.ErrorDirExists
+		}
+	}
+
+	// Find ID of src
+	srcID, err := srcFs.dirCache.FindDir(srcRemote, false)
+	if err != nil {
+		return err
+	}
+	srcInfo, err := srcFs.readItem(srcID)
+	if err != nil {
+		return err
+	}
+
+	// Do the move
+	_, err = f.rename(srcInfo, leaf, directoryID)
+	if err != nil {
+		return err
+	}
+	srcFs.dirCache.FlushDir(srcRemote)
+	return nil
+}
+
+// DirCacheFlush resets the directory cache - used in testing as an
+// optional interface
+func (f *Fs) DirCacheFlush() {
+	f.dirCache.ResetRoot()
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.None)
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the hash of an object returning a lowercase hex string
+//
+// Zoho doesn't expose any hashes.
+func (o *Object) Hash(t hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	err := o.readMetaData()
+	if err != nil {
+		fs.Logf(o, "Failed to read metadata: %v", err)
+		return 0
+	}
+	return o.size
+}
+
+// setMetaData sets the metadata from info
+func (o *Object) setMetaData(info *api.Item) (err error) {
+	if info.Attributes.IsFolder {
+		return errors.Wrapf(fs.ErrorNotAFile, "%q is a folder", o.remote)
+	}
+	o.hasMetaData = true
+	o.size = info.Attributes.StorageInfo.Size
+	o.modTime = info.ModTime()
+	o.id = info.ID
+	return nil
+}
+
+// readMetaData gets the metadata if it hasn't already been fetched
+//
+// it also sets the info
+func (o *Object) readMetaData() (err error) {
+	if o.hasMetaData {
+		return nil
+	}
+	info, err := o.fs.readMetaDataForPath(o.remote)
+	if err != nil {
+		if isNotFound(err) {
+			return fs.ErrorObjectNotFound
+		}
+		return err
+	}
+	return o.setMetaData(info)
+}
+
+// ModTime returns the modification time of the object
+//
+// This is the time the object was last uploaded as Zoho doesn't
+// allow it to be set.
+func (o *Object) ModTime() time.Time {
+	err := o.readMetaData()
+	if err != nil {
+		fs.Logf(o, "Failed to read metadata: %v", err)
+		return time.Now()
+	}
+	return o.modTime
+}
+
+// SetModTime sets the modification time of the local fs object
+func (o *Object) SetModTime(modTime time.Time) error {
+	return fs.ErrorCantSetModTime
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	if o.id == "" {
+		return nil, errors.New("can't download - no id")
+	}
+	fs.FixRangeOption(options, o.size)
+	var resp *http.Response
+	opts := rest.Opts{
+		Method:  "GET",
+		Path:    "/download/" + o.id,
+		Options: options,
+	}
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, err
+}
+
+// upload does a single multipart form upload
+//
+// This is recommended for small files only
+func (o *Object) upload(in io.Reader, leaf, directoryID string) (result *api.UploadResponse, err error) {
+	params := url.Values{}
+	params.Set("filename", leaf)
+	params.Set("parent_id", directoryID)
+	params.Set("override-name-exist", "true")
+	opts := rest.Opts{
+		Method:               "POST",
+		Path:                 "/upload",
+		Body:                 in,
+		MultipartParams:      params,
+		MultipartContentName: "content",
+		MultipartFileName:    leaf,
+	}
+	var resp *http.Response
+	err = o.fs.pacer.CallNoRetry(func() (bool, error) {
+		resp, err = o.fs.srv.CallJSON(&opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	return result, err
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// If existing is set then it updates the object rather than creating a new one
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	o.fs.tokenRenewer.Start()
+	defer o.fs.tokenRenewer.Stop()
+
+	size := src.Size()
+	remote := o.Remote()
+
+	// Create the directory for the object if it doesn't exist
+	leaf, directoryID, err := o.fs.dirCache.FindRootAndPath(remote, true)
+	if err != nil {
+		return err
+	}
+
+	// Upload with simple or upload session
+	var result *api.UploadResponse
+	if size >= 0 && size <= int64(uploadCutoff) {
+		result, err = o.upload(in, leaf, directoryID)
+	} else {
+		result, err = o.uploadSession(in, leaf, directoryID, size)
+	}
+	if err != nil {
+		return err
+	}
+	if len(result.Uploads) != 1 {
+		return errors.Errorf("failed to upload %v - not sure why", o)
+	}
+	info, err := o.fs.readItem(result.Uploads[0].Attributes.ResourceID)
+	if err != nil {
+		return errors.Wrap(err, "failed to read uploaded file")
+	}
+	return o.setMetaData(info)
+}
+
+// Remove an object
+func (o *Object) Remove() error {
+	return o.fs.trash(o.id)
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	return o.id
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.Purger          = (*Fs)(nil)
+	_ fs.PutStreamer     = (*Fs)(nil)
+	_ fs.Copier          = (*Fs)(nil)
+	_ fs.Mover           = (*Fs)(nil)
+	_ fs.DirMover        = (*Fs)(nil)
+	_ fs.DirCacheFlusher = (*Fs)(nil)
+	_ fs.Object          = (*Object)(nil)
+)
@@ -0,0 +1,338 @@
+package zoho
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/backend/zoho/api"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeItem is a file or folder in the fakeDrive
+type fakeItem struct {
+	id       string
+	name     string
+	parentID string
+	isFolder bool
+	status   string
+	data     []byte
+	modTime  time.Time
+}
+
+// fakeDrive is an in memory Zoho WorkDrive serving the API, download
+// and upload endpoints under /com/api and /com/upload
+type fakeDrive struct {
+	mu       sync.Mutex
+	items    map[string]*fakeItem
+	nextID   int
+	auth     string // the Authorization header of the last request
+	sessions int    // number of upload sessions
+	server   *httptest.Server
+}
+
+func newFakeDrive() *fakeDrive {
+	d := &fakeDrive{
+		items: map[string]*fakeItem{
+			"root": {id: "root", isFolder: true, status: api.StatusActive},
+		},
+	}
+	d.server = httptest.NewServer(d)
+	return d
+}
+
+func (d *fakeDrive) fail(w http.ResponseWriter, code int, id string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(api.Error{Errors: []api.ErrorDetail{{ID: id, Title: id}}})
+}
+
+func (d *fakeDrive) reply(w http.ResponseWriter, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+func (d *fakeDrive) toAPI(item *fakeItem) (out api.Item) {
+	out.ID = item.id
+	out.Type = api.TypeFiles
+	out.Attributes.Name = item.name
+	out.Attributes.IsFolder = item.isFolder
+	out.Attributes.ParentID = item.parentID
+	out.Attributes.Status = item.status
+	out.Attributes.StorageInfo.Size = int64(len(item.data))
+	out.Attributes.ModifiedTime = item.modTime.UnixNano() / int64(time.Millisecond)
+	return out
+}
+
+// add adds a new item returning it, replacing any file of the same
+// name in the parent
+func (d *fakeDrive) add(name, parentID string, isFolder bool, data []byte) *fakeItem {
+	for _, item := range d.items {
+		if item.parentID == parentID && item.status == api.StatusActive && !item.isFolder && !isFolder && item.name == name {
+			item.data = data
+			item.modTime = time.Now()
+			return item
+		}
+	}
+	d.nextID++
+	item := &fakeItem{
+		id:       "id" + strconv.Itoa(d.nextID),
+		name:     name,
+		parentID: parentID,
+		isFolder: isFolder,
+		status:   api.StatusActive,
+		data:     data,
+		modTime:  time.Now(),
+	}
+	d.items[item.id] = item
+	return item
+}
+
+func (d *fakeDrive) uploaded(w http.ResponseWriter, item *fakeItem) {
+	var result api.UploadResponse
+	result.Uploads = make([]api.UploadResult, 1)
+	result.Uploads[0].Attributes.ResourceID = item.id
+	result.Uploads[0].Attributes.ParentID = item.parentID
+	result.Uploads[0].Attributes.FileName = item.name
+	d.reply(w, &result)
+}
+
+func (d *fakeDrive) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.auth = r.Header.Get("Authorization")
+
+	if r.URL.Path == "/com/upload/stream/upload" && r.Method == "POST" {
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			d.fail(w, http.StatusBadRequest, "R001")
+			return
+		}
+		name, err := url.QueryUnescape(r.Header.Get("x-filename"))
+		parent := d.items[r.Header.Get("x-parent_id")]
+		if err != nil || parent == nil || r.Header.Get("upload-id") == "" {
+			d.fail(w, http.StatusBadRequest, "R002")
+			return
+		}
+		d.sessions++
+		d.uploaded(w, d.add(name, parent.id, false, data))
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, "/com/api/") {
+		d.fail(w, http.StatusNotFound, "R404")
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/com/api/"), "/")
+	var item *fakeItem
+	if len(parts) >= 2 {
+		item = d.items[parts[1]]
+	}
+	switch {
+	case r.Method == "POST" && len(parts) == 1 && parts[0] == "upload":
+		file, _, err := r.FormFile("content")
+		if err != nil {
+			d.fail(w, http.StatusBadRequest, "R001")
+			return
+		}
+		data, err := ioutil.ReadAll(file)
+		parent := d.items[r.FormValue("parent_id")]
+		if err != nil || parent == nil {
+			d.fail(w, http.StatusBadRequest, "R002")
+			return
+		}
+		d.uploaded(w, d.add(r.FormValue("filename"), parent.id, false, data))
+	case r.Method == "POST" && len(parts) == 1 && parts[0] == "files":
+		d.mkdir(w, r)
+	case item == nil:
+		d.fail(w, http.StatusNotFound, "R404")
+	case r.Method == "GET" && len(parts) == 2 && parts[0] == "download":
+		http.ServeContent(w, r, item.name, item.modTime, bytes.NewReader(item.data))
+	case r.Method == "GET" && len(parts) == 3 && parts[2] == "files":
+		var ids []string
+		for _, child := range d.items {
+			if child.parentID == item.id && child.status == api.StatusActive {
+				ids = append(ids, child.id)
+			}
+		}
+		sort.Strings(ids)
+		limit, _ := strconv.Atoi(r.URL.Query().Get("page[limit]"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("page[offset]"))
+		var result api.ItemList
+		result.Items = []api.Item{}
+		for i := offset; i < len(ids) && i < offset+limit; i++ {
+			result.Items = append(result.Items, d.toAPI(d.items[ids[i]]))
+		}
+		d.reply(w, &result)
+	case r.Method == "GET" && len(parts) == 2:
+		d.reply(w, &api.ItemInfo{Item: d.toAPI(item)})
+	case r.Method == "POST" && len(parts) == 3 && parts[2] == "copy":
+		var request api.WriteMultiMetadataRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || len(request.Data) != 1 {
+			d.fail(w, http.StatusBadRequest, "R003")
+			return
+		}
+		src := d.items[request.Data[0].Attributes.ResourceID]
+		if src == nil {
+			d.fail(w, http.StatusNotFound, "R404")
+			return
+		}
+		copied := d.add(src.name, item.id, false, append([]byte(nil), src.data...))
+		d.reply(w, &api.ItemList{Items: []api.Item{d.toAPI(copied)}})
+	case r.Method == "PATCH" && len(parts) == 2:
+		var request api.WriteMetadataRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Data.ID != item.id {
+			d.fail(w, http.StatusBadRequest, "R003")
+			return
+		}
+		attributes := request.Data.Attributes
+		if attributes.Name != "" {
+			item.name = attributes.Name
+		}
+		if attributes.ParentID != "" {
+			item.parentID = attributes.ParentID
+		}
+		if attributes.Status != "" {
+			item.status = attributes.Status
+		}
+		d.reply(w, &api.ItemInfo{Item: d.toAPI(item)})
+	default:
+		d.fail(w, http.StatusMethodNotAllowed, "R405")
+	}
+}
+
+// mkdir handles creating a folder
+func (d *fakeDrive) mkdir(w http.ResponseWriter, r *http.Request) {
+	var request api.WriteMetadataRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		d.fail(w, http.StatusBadRequest, "R003")
+		return
+	}
+	if d.items[request.Data.Attributes.ParentID] == nil {
+		d.fail(w, http.StatusNotFound, "R404")
+		return
+	}
+	folder := d.add(request.Data.Attributes.Name, request.Data.Attributes.ParentID, true, nil)
+	d.reply(w, &api.ItemInfo{Item: d.toAPI(folder)})
+}
+
+func newTestFs(t *testing.T, d *fakeDrive, root string) *Fs {
+	apiURLFormat = d.server.URL + "/%s/api"
+	uploadURLFormat = d.server.URL + "/%s/upload"
+	config.LoadConfig()
+	config.FileSet("TestZohoInternal", "type", "zoho")
+	config.FileSet("TestZohoInternal", config.ConfigClientID, "id")
+	config.FileSet("TestZohoInternal", config.ConfigClientSecret, "secret")
+	config.FileSet("TestZohoInternal", config.ConfigToken, `{"access_token":"token","token_type":"Bearer","expiry":"2099-01-01T00:00:00Z"}`)
+	config.FileSet("TestZohoInternal", configRootID, "root")
+	f, err := NewFs("TestZohoInternal", root)
+	require.NoError(t, err)
+	return f.(*Fs)
+}
+
+func TestNewFsConfig(t *testing.T) {
+	config.LoadConfig()
+	config.FileSet("TestZohoInternalConfig", "type", "zoho")
+	_, err := NewFs("TestZohoInternalConfig", "")
+	assert.Contains(t, fmt.Sprint(err), "client_id")
+
+	config.FileSet("TestZohoInternalConfig", config.ConfigClientID, "id")
+	config.FileSet("TestZohoInternalConfig", config.ConfigClientSecret, "secret")
+	_, err = NewFs("TestZohoInternalConfig", "")
+	assert.Contains(t, fmt.Sprint(err), "no root folder")
+}
+
+func TestPutOpenList(t *testing.T) {
+	d := newFakeDrive()
+	defer d.server.Close()
+	f := newTestFs(t, d, "sub")
+
+	_, err := f.List("")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+
+	o := fstest.PutContents(t, f, "dir/file.txt", "hello world", time.Now())
+	assert.Equal(t, int64(11), o.Size())
+	assert.Equal(t, "Bearer token", d.auth)
+	assert.Equal(t, 0, d.sessions)
+	assert.Equal(t, []string{"dir/"}, fstest.ListNames(t, f, ""))
+	assert.Equal(t, []string{"dir/file.txt"}, fstest.ListNames(t, f, "dir"))
+
+	// Updating replaces the file
+	o = fstest.PutContents(t, f, "dir/file.txt", "hello", time.Now())
+	assert.Equal(t, int64(5), o.Size())
+	assert.Equal(t, []string{"dir/file.txt"}, fstest.ListNames(t, f, "dir"))
+
+	// Names are case insensitive
+	o, err = f.NewObject("DIR/FILE.TXT")
+	require.NoError(t, err)
+	assert.Equal(t, "ell", fstest.ReadObject(t, o, &fs.RangeOption{Start: 1, End: 3}))
+
+	// Big files use an upload session
+	big := strings.Repeat("x", minUploadCutoff+1)
+	oldCutoff := uploadCutoff
+	uploadCutoff = minUploadCutoff
+	defer func() { uploadCutoff = oldCutoff }()
+	o = fstest.PutContents(t, f, "big name.bin", big, time.Now())
+	assert.Equal(t, 1, d.sessions)
+	assert.Equal(t, int64(len(big)), o.Size())
+	assert.Equal(t, []string{"big name.bin", "dir/"}, fstest.ListNames(t, f, ""))
+
+	// Listings are paged
+	for i := 0; i < listChunks+1; i++ {
+		fstest.PutContents(t, f, fmt.Sprintf("many/%03d", i), "", time.Now())
+	}
+	assert.Equal(t, listChunks+1, len(fstest.ListNames(t, f, "many")))
+
+	// A file root gives fs.ErrorIsFile
+	_, err = NewFs("TestZohoInternal", "sub/dir/file.txt")
+	assert.Equal(t, fs.ErrorIsFile, err)
+}
+
+func TestCopyMoveRemove(t *testing.T) {
+	d := newFakeDrive()
+	defer d.server.Close()
+	f := newTestFs(t, d, "")
+
+	o := fstest.PutContents(t, f, "a/one.txt", "one", time.Now())
+
+	dst, err := operations.Copy(f, nil, "b/two.txt", o)
+	require.NoError(t, err)
+	assert.Equal(t, "b/two.txt", dst.Remote())
+	assert.Equal(t, int64(3), dst.Size())
+	assert.Equal(t, []string{"b/two.txt"}, fstest.ListNames(t, f, "b"))
+
+	// Copies within a directory aren't possible server side
+	_, err = f.Copy(o, "a/three.txt")
+	assert.Equal(t, fs.ErrorCantCopy, err)
+
+	moved, err := f.Move(dst, "c/three.txt")
+	require.NoError(t, err)
+	assert.Equal(t, dst.(*Object).id, moved.(*Object).id)
+	assert.Equal(t, []string{"c/three.txt"}, fstest.ListNames(t, f, "c"))
+	assert.Equal(t, []string(nil), fstest.ListNames(t, f, "b"))
+
+	require.NoError(t, f.DirMove(f, "c", "d"))
+	assert.Equal(t, []string{"a/", "b/", "d/"}, fstest.ListNames(t, f, ""))
+	assert.Equal(t, []string{"d/three.txt"}, fstest.ListNames(t, f, "d"))
+
+	// Removing moves to the trash
+	assert.Equal(t, fs.ErrorDirectoryNotEmpty, f.Rmdir("a"))
+	require.NoError(t, o.Remove())
+	assert.Equal(t, api.StatusTrashed, d.items[o.(*Object).id].status)
+	require.NoError(t, f.Rmdir("a"))
+	assert.Equal(t, []string{"b/", "d/"}, fstest.ListNames(t, f, ""))
+}
@@ -25,24 +25,34 @@ docs = [
     "alias.md",
     "amazonclouddrive.md",
     "s3.md",
+    "archive.md",
     "b2.md",
     "box.md",
     "cache.md",
+    "chunker.md",
+    "compress.md",
+    "cas.md",
     "crypt.md",
     "dropbox.md",
     "ftp.md",
     "googlecloudstorage.md",
     "drive.md",
+    "hdfs.md",
     "http.md",
     "hubic.md",
+    "internetarchive.md",
     "azureblob.md",
     "onedrive.md",
+    "nfs.md",
     "qingstor.md",
     "swift.md",
     "pcloud.md",
     "sftp.md",
+    "smb.md",
+    "union.md",
     "webdav.md",
     "yandex.md",
+    "zoho.md",
 
     "local.md",
     "changelog.md",
@@ -5,6 +5,7 @@ import (
 	// Active commands
 	_ "github.com/ncw/rclone/cmd"
 	_ "github.com/ncw/rclone/cmd/authorize"
+	_ "github.com/ncw/rclone/cmd/backend"
 	_ "github.com/ncw/rclone/cmd/cachestats"
 	_ "github.com/ncw/rclone/cmd/cat"
 	_ "github.com/ncw/rclone/cmd/check"
@@ -34,17 +35,22 @@ import (
 	_ "github.com/ncw/rclone/cmd/move"
 	_ "github.com/ncw/rclone/cmd/moveto"
 	_ "github.com/ncw/rclone/cmd/ncdu"
+	_ "github.com/ncw/rclone/cmd/nfsmount"
 	_ "github.com/ncw/rclone/cmd/obscure"
 	_ "github.com/ncw/rclone/cmd/purge"
 	_ "github.com/ncw/rclone/cmd/rc"
 	_ "github.com/ncw/rclone/cmd/rcat"
+	_ "github.com/ncw/rclone/cmd/rcd"
 	_ "github.com/ncw/rclone/cmd/rmdir"
 	_ "github.com/ncw/rclone/cmd/rmdirs"
+	_ "github.com/ncw/rclone/cmd/selfupdate"
 	_ "github.com/ncw/rclone/cmd/serve"
+	_ "github.com/ncw/rclone/cmd/settier"
 	_ "github.com/ncw/rclone/cmd/sha1sum"
 	_ "github.com/ncw/rclone/cmd/size"
 	_ "github.com/ncw/rclone/cmd/sync"
 	_ "github.com/ncw/rclone/cmd/touch"
 	_ "github.com/ncw/rclone/cmd/tree"
+	_ "github.com/ncw/rclone/cmd/unmount"
 	_ "github.com/ncw/rclone/cmd/version"
 )
@@ -0,0 +1,138 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Flags for the restore command
+var (
+	priority = ""
+	lifetime = 1
+	wait     time.Duration
+)
+
+func init() {
+	Command.AddCommand(featuresCommand)
+	Command.AddCommand(restoreCommand)
+	flags.StringVarP(restoreCommand.Flags(), &priority, "priority", "", priority, "How quickly to restore, eg Standard, Bulk or Expedited for S3")
+	flags.IntVarP(restoreCommand.Flags(), &lifetime, "lifetime", "", lifetime, "Number of days to keep the restored copy")
+	flags.DurationVarP(restoreCommand.Flags(), &wait, "wait", "", wait, "If set, check every this long until all the objects are restored")
+	cmd.Root.AddCommand(Command)
+}
+
+// Command definition for cobra
+var Command = &cobra.Command{
+	Use:   "backend <command> remote:path",
+	Short: `Show information about the backend of a remote.`,
+	Long: `rclone backend shows information about the backend of a remote.
+This command requires the use of a subcommand, eg
+
+    rclone backend features remote:
+
+Each subcommand has its own options which you can see in their help.
+`,
+	RunE: func(command *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("backend requires a command, eg 'rclone backend features remote:'")
+		}
+		return errors.New("unknown command")
+	},
+}
+
+var featuresCommand = &cobra.Command{
+	Use:   "features remote:path",
+	Short: `Show the optional features a remote supports.`,
+	Long: `
+Shows which optional features, such as server side Copy, Move and
+DirMove, PutStream for streaming uploads or ListR for fast recursive
+listings, remote:path supports, along with the hashes it supports and
+the precision of its modification times.  This shows what rclone will
+be able to do when syncing to or from the remote.
+
+    rclone backend features remote:path
+
+The output is JSON, eg
+
+    {
+    	"Name": "remote",
+    	"Root": "path",
+    	"String": "S3 bucket remote path path",
+    	"Precision": 1000000000,
+    	"Hashes": [
+    		"MD5"
+    	],
+    	"Features": {
+    		"BucketBased": true,
+    		"Copy": true,
+    		"Move": false,
+    		...
+    	}
+    }
+
+The Precision is in nanoseconds.
+
+Features are shown after any wrapping remotes such as crypt or cache
+have disabled the features the remotes they wrap don't support, and
+after any features disabled with ` + "`--disable`" + ` have been turned off.
+Use ` + "`-vv`" + ` to see which features were disabled and why.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		fsrc := cmd.NewFsSrc(args)
+		cmd.Run(false, false, command, func() error {
+			out, err := json.MarshalIndent(operations.GetFsInfo(fsrc), "", "\t")
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal features")
+			}
+			_, err = fmt.Printf("%s\n", out)
+			return err
+		})
+	},
+}
+
+var restoreCommand = &cobra.Command{
+	Use:   "restore remote:path",
+	Short: `Restore archived objects so they can be read.`,
+	Long: `
+Objects in archive storage classes, such as S3 GLACIER and
+DEEP_ARCHIVE or the Azure Archive tier, can't be read until they have
+been restored.  This asks for every archived object in remote:path to be
+restored, eg
+
+    rclone backend restore --lifetime 7 remote:bucket/path
+
+Objects which aren't archived, or which are already restored or being
+restored, are left alone.  Use filters such as ` + "`--include`" + ` to
+choose which objects to restore and ` + "`--dry-run`" + ` to see which would
+be without restoring them.
+
+` + "`--lifetime`" + ` is the number of days the restored copy is kept for
+before it is removed again.  Azure moves the blobs to the Hot tier
+instead so doesn't use this.  ` + "`--priority`" + ` chooses how quickly the
+objects are restored and so what it costs - Standard, Bulk or
+Expedited for S3 and Standard or High for Azure.  If it isn't set the
+provider's default is used.
+
+Restoring usually takes hours.  With ` + "`--wait`" + ` set to a duration,
+eg ` + "`--wait 15m`" + `, rclone checks the objects every this long and
+only returns when they have all been restored.
+
+Currently only the S3 and Azure Blob backends, and crypt remotes
+wrapping them, support this.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		fsrc := cmd.NewFsSrc(args)
+		cmd.Run(true, false, command, func() error {
+			return operations.Restore(fsrc, priority, lifetime, wait)
+		})
+	},
+}
@@ -1,19 +1,35 @@
 package check
 
 import (
+	"io"
+	"os"
+
 	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/operations"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 // Globals
 var (
-	download = false
+	download     = false
+	combined     = ""
+	missingOnSrc = ""
+	missingOnDst = ""
+	match        = ""
+	differ       = ""
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
-	commandDefintion.Flags().BoolVarP(&download, "download", "", download, "Check by downloading rather than with hash.")
+	flags := commandDefintion.Flags()
+	flags.BoolVarP(&download, "download", "", download, "Check by downloading rather than with hash.")
+	flags.StringVarP(&combined, "combined", "", combined, "Make a combined report of changes to this file.")
+	flags.StringVarP(&missingOnSrc, "missing-on-src", "", missingOnSrc, "Report all files missing from the source to this file.")
+	flags.StringVarP(&missingOnDst, "missing-on-dst", "", missingOnDst, "Report all files missing from the destination to this file.")
+	flags.StringVarP(&match, "match", "", match, "Report all matching files to this file.")
+	flags.StringVarP(&differ, "differ", "", differ, "Report all non-matching files to this file.")
 }
 
 var commandDefintion = &cobra.Command{
@@ -31,15 +47,97 @@ If you supply the --download flag, it will download the data from
 both remotes and check them against each other on the fly.  This can
 be useful for remotes that don't support hashes or if you really want
 to check all the data.
+
+The paths of the files checked can be written to files so they can
+be acted on by other programs.  Each of these flags takes the name of
+a file to write to, or "-" to write to standard output.
+
+  * --missing-on-src - files which are only in the destination
+  * --missing-on-dst - files which are only in the source
+  * --match - files which are identical in the source and destination
+  * --differ - files which are in both but are different
+  * --combined - all the files with a symbol and a space before them
+
+The symbols used in the --combined output are
+
+  * "= path" - path was in the source and destination and was identical
+  * "- path" - path was missing on the source, so only in the destination
+  * "+ path" - path was missing on the destination, so only in the source
+  * "* path" - path was in the source and destination but was different
+
+The paths are relative to source:path and dest:path and are written
+one per line in no particular order.
 `,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(2, 2, command, args)
 		fsrc, fdst := cmd.NewFsSrcDst(args)
 		cmd.Run(false, false, command, func() error {
+			opt, closeOutputs, err := newCheckOpt(fdst, fsrc)
+			if err != nil {
+				return err
+			}
 			if download {
-				return operations.CheckDownload(fdst, fsrc)
+				err = operations.CheckDownload(opt)
+			} else {
+				err = operations.Check(opt)
+			}
+			closeErr := closeOutputs()
+			if err == nil {
+				err = closeErr
 			}
-			return operations.Check(fdst, fsrc)
+			return err
 		})
 	},
 }
+
+// newCheckOpt makes the options for the check opening the report
+// files in the flags.  Call closeOutputs to close them when done.
+func newCheckOpt(fdst, fsrc fs.Fs) (opt *operations.CheckOpt, closeOutputs func() error, err error) {
+	var closers []io.Closer
+	closeOutputs = func() (err error) {
+		for _, closer := range closers {
+			closeErr := closer.Close()
+			if err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}
+	opt = &operations.CheckOpt{
+		Fdst: fdst,
+		Fsrc: fsrc,
+	}
+	open := func(name string, pout *io.Writer) error {
+		if name == "" {
+			return nil
+		}
+		if name == "-" {
+			*pout = os.Stdout
+			return nil
+		}
+		out, err := os.Create(name)
+		if err != nil {
+			return errors.Wrap(err, "failed to open report file")
+		}
+		*pout = out
+		closers = append(closers, out)
+		return nil
+	}
+	for _, output := range []struct {
+		name string
+		pout *io.Writer
+	}{
+		{combined, &opt.Combined},
+		{missingOnSrc, &opt.MissingOnSrc},
+		{missingOnDst, &opt.MissingOnDst},
+		{match, &opt.Match},
+		{differ, &opt.Differ},
+	} {
+		err = open(output.name, output.pout)
+		if err != nil {
+			_ = closeOutputs()
+			return nil, nil, err
+		}
+	}
+	return opt, closeOutputs, nil
+}
@@ -29,8 +29,10 @@ import (
 	"github.com/ncw/rclone/fs/fserrors"
 	"github.com/ncw/rclone/fs/fspath"
 	fslog "github.com/ncw/rclone/fs/log"
+	"github.com/ncw/rclone/fs/notify"
 	"github.com/ncw/rclone/fs/rc"
 	"github.com/ncw/rclone/fs/rc/rcflags"
+	"github.com/ncw/rclone/fs/rc/schedule"
 	"github.com/ncw/rclone/lib/atexit"
 )
 
@@ -141,10 +143,25 @@ func ShowVersion() {
 	fmt.Printf("- go version: %s\n", runtime.Version())
 }
 
+// setRemoteDefaults sets the flag defaults from the config of the
+// remote in the path, if any
+func setRemoteDefaults(remote string) {
+	configName, _ := fs.SplitRemote(remote)
+	if configName == "" {
+		return
+	}
+	err := configflags.SetRemoteDefaults(configName)
+	if err != nil {
+		fs.CountError(err)
+		log.Fatalf("Failed to set defaults from config for %q: %v", remote, err)
+	}
+}
+
 // newFsFile creates a dst Fs from a name but may point to a file.
 //
 // It returns a string with the file name if points to a file
 func newFsFile(remote string) (fs.Fs, string) {
+	setRemoteDefaults(remote)
 	fsInfo, configName, fsPath, err := fs.ParseRemote(remote)
 	if err != nil {
 		fs.CountError(err)
@@ -190,6 +207,7 @@ func newFsSrc(remote string) (fs.Fs, string) {
 //
 // This must point to a directory
 func newFsDst(remote string) fs.Fs {
+	setRemoteDefaults(remote)
 	f, err := fs.NewFs(remote)
 	if err != nil {
 		fs.CountError(err)
@@ -223,6 +241,7 @@ func NewFsSrcDstFiles(args []string) (fsrc fs.Fs, srcFileName string, fdst fs.Fs
 			log.Fatalf("%q is a directory", args[1])
 		}
 	}
+	setRemoteDefaults(dstRemote)
 	fdst, err := fs.NewFs(dstRemote)
 	switch err {
 	case fs.ErrorIsFile:
@@ -244,6 +263,16 @@ func NewFsSrc(args []string) fs.Fs {
 	return fsrc
 }
 
+// NewFsSrcMaybeFile creates a new src fs from the arguments
+//
+// If the source points to a single file then the fs is limited to it
+// and srcFileName is its name, otherwise srcFileName is ""
+func NewFsSrcMaybeFile(args []string) (fsrc fs.Fs, srcFileName string) {
+	fsrc, srcFileName = newFsSrc(args[0])
+	fs.CalculateModifyWindow(fsrc)
+	return fsrc, srcFileName
+}
+
 // NewFsDst creates a new dst fs from the arguments
 //
 // Dst fs-es can't point to single files
@@ -253,6 +282,18 @@ func NewFsDst(args []string) fs.Fs {
 	return fdst
 }
 
+// NewFsSrcFile creates a new src fs with a source file name from the arguments
+//
+// The source must point to a single file
+func NewFsSrcFile(args []string) (fsrc fs.Fs, srcFileName string) {
+	fsrc, srcFileName = newFsSrc(args[0])
+	if srcFileName == "" {
+		log.Fatalf("%q is not a file", args[0])
+	}
+	fs.CalculateModifyWindow(fsrc)
+	return
+}
+
 // NewFsDstFile creates a new dst fs with a destination file name from the arguments
 func NewFsDstFile(args []string) (fdst fs.Fs, dstFileName string) {
 	dstRemote, dstFileName := fspath.RemoteSplit(args[0])
@@ -280,6 +321,7 @@ func ShowStats() bool {
 func Run(Retry bool, showStats bool, cmd *cobra.Command, f func() error) {
 	var err error
 	var stopStats chan struct{}
+	start := time.Now()
 	if !showStats && ShowStats() {
 		showStats = true
 	}
@@ -314,6 +356,7 @@ func Run(Retry bool, showStats bool, cmd *cobra.Command, f func() error) {
 	if showStats {
 		close(stopStats)
 	}
+	notify.Send(notify.NewSummary(cmd.Name(), cmd.Flags().Args(), start, err))
 	if err != nil {
 		log.Printf("Failed to %s: %v", cmd.Name(), err)
 		resolveExitCode(err)
@@ -384,6 +427,12 @@ func initConfig() {
 
 	// Start the remote control if configured
 	rc.Start(&rcflags.Opt)
+	if rcflags.Opt.Enabled {
+		err = schedule.Start()
+		if err != nil {
+			log.Fatalf("Failed to start scheduler: %v", err)
+		}
+	}
 
 	// Setup CPU profiling if desired
 	if *cpuProfile != "" {
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/billziss-gh/cgofuse/fuse"
+	"github.com/ncw/rclone/cmd/mountlib"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/log"
 	"github.com/ncw/rclone/vfs"
@@ -33,8 +34,13 @@ type FS struct {
 
 // NewFS makes a new FS
 func NewFS(f fs.Fs) *FS {
+	return newFS(vfs.New(f, &vfsflags.Opt), f)
+}
+
+// newFS makes a new FS using an existing VFS
+func newFS(VFS *vfs.VFS, f fs.Fs) *FS {
 	fsys := &FS{
-		VFS:   vfs.New(f, &vfsflags.Opt),
+		VFS:   VFS,
 		f:     f,
 		ready: make(chan (struct{})),
 	}
@@ -159,7 +165,7 @@ func (fsys *FS) stat(node vfs.Node, stat *fuse.Stat_t) (errc int) {
 		Mode |= fuse.S_IFREG
 	}
 	//stat.Dev = 1
-	stat.Ino = node.Inode() // FIXME do we need to set the inode number?
+	stat.Ino = node.Inode()
 	stat.Mode = uint32(Mode)
 	stat.Nlink = 1
 	stat.Uid = fsys.VFS.Opt.UID
@@ -167,12 +173,13 @@ func (fsys *FS) stat(node vfs.Node, stat *fuse.Stat_t) (errc int) {
 	//stat.Rdev
 	stat.Size = int64(Size)
 	t := fuse.NewTimespec(modTime)
+	ctime, btime := mountlib.StatTimes(node, modTime)
 	stat.Atim = t
 	stat.Mtim = t
-	stat.Ctim = t
+	stat.Ctim = fuse.NewTimespec(ctime)
 	stat.Blksize = 512
 	stat.Blocks = int64(Blocks)
-	stat.Birthtim = t
+	stat.Birthtim = fuse.NewTimespec(btime)
 	// fs.Debugf(nil, "stat = %+v", *stat)
 	return 0
 }
@@ -267,9 +274,22 @@ func (fsys *FS) Statfs(path string, stat *fuse.Statfs_t) (errc int) {
 	if runtime.GOOS == "windows" {
 		fsBlocks = (1 << 43) - 1
 	}
+	total, used, free := fsys.VFS.Statfs()
+	if total >= 0 && uint64(total)/blockSize < fsBlocks {
+		fsBlocks = uint64(total) / blockSize
+	}
+	bfree := fsBlocks
+	if free >= 0 {
+		bfree = uint64(free) / blockSize
+		if bfree > fsBlocks {
+			bfree = fsBlocks
+		}
+	} else if used >= 0 && uint64(used)/blockSize < fsBlocks {
+		bfree = fsBlocks - uint64(used)/blockSize
+	}
 	stat.Blocks = fsBlocks  // Total data blocks in file system.
-	stat.Bfree = fsBlocks   // Free blocks in file system.
-	stat.Bavail = fsBlocks  // Free blocks in file system if you're not root.
+	stat.Bfree = bfree      // Free blocks in file system.
+	stat.Bavail = bfree     // Free blocks in file system if you're not root.
 	stat.Files = 1E9        // Total files in file system.
 	stat.Ffree = 1E9        // Free files in file system.
 	stat.Bsize = blockSize  // Block size
@@ -449,9 +469,14 @@ func (fsys *FS) Fsync(path string, datasync bool, fh uint64) (errc int) {
 }
 
 // Link creates a hard link to a file.
+//
+// This is emulated with a server side copy if --link-copy is set.
 func (fsys *FS) Link(oldpath string, newpath string) (errc int) {
 	defer log.Trace(oldpath, "newpath=%q", newpath)("errc=%d", &errc)
-	return -fuse.ENOSYS
+	if !mountlib.LinkCopy {
+		return -fuse.ENOSYS
+	}
+	return translateError(fsys.VFS.Link(oldpath, newpath))
 }
 
 // Symlink creates a symbolic link.
@@ -496,22 +521,68 @@ func (fsys *FS) Fsyncdir(path string, datasync bool, fh uint64) (errc int) {
 
 // Setxattr sets extended attributes.
 func (fsys *FS) Setxattr(path string, name string, value []byte, flags int) (errc int) {
-	return -fuse.ENOSYS
+	defer log.Trace(path, "name=%q, flags=0x%X", name, flags)("errc=%d", &errc)
+	node, errc := fsys.lookupNode(path)
+	if errc != 0 {
+		return errc
+	}
+	file, ok := node.(*vfs.File)
+	if !ok {
+		return -fuse.ENOTSUP
+	}
+	return translateError(file.SetXattr(name, value))
 }
 
 // Getxattr gets extended attributes.
 func (fsys *FS) Getxattr(path string, name string) (errc int, value []byte) {
-	return -fuse.ENOSYS, nil
+	defer log.Trace(path, "name=%q", name)("errc=%d", &errc)
+	node, errc := fsys.lookupNode(path)
+	if errc != 0 {
+		return errc, nil
+	}
+	file, ok := node.(*vfs.File)
+	if !ok {
+		return -fuse.ENOATTR, nil
+	}
+	value, err := file.GetXattr(name)
+	return translateError(err), value
 }
 
 // Removexattr removes extended attributes.
 func (fsys *FS) Removexattr(path string, name string) (errc int) {
-	return -fuse.ENOSYS
+	defer log.Trace(path, "name=%q", name)("errc=%d", &errc)
+	node, errc := fsys.lookupNode(path)
+	if errc != 0 {
+		return errc
+	}
+	file, ok := node.(*vfs.File)
+	if !ok {
+		return -fuse.ENOATTR
+	}
+	return translateError(file.RemoveXattr(name))
 }
 
 // Listxattr lists extended attributes.
 func (fsys *FS) Listxattr(path string, fill func(name string) bool) (errc int) {
-	return -fuse.ENOSYS
+	defer log.Trace(path, "")("errc=%d", &errc)
+	node, errc := fsys.lookupNode(path)
+	if errc != 0 {
+		return errc
+	}
+	file, ok := node.(*vfs.File)
+	if !ok {
+		return 0
+	}
+	names, err := file.ListXattr()
+	if err != nil {
+		return translateError(err)
+	}
+	for _, name := range names {
+		if !fill(name) {
+			return -fuse.ERANGE
+		}
+	}
+	return 0
 }
 
 // Translate errors from mountlib
@@ -540,6 +611,10 @@ func translateError(err error) (errc int) {
 		return -fuse.EROFS
 	case vfs.ENOSYS:
 		return -fuse.ENOSYS
+	case vfs.ENOATTR:
+		return -fuse.ENOATTR
+	case vfs.ENOTSUP:
+		return -fuse.ENOTSUP
 	case vfs.EINVAL:
 		return -fuse.EINVAL
 	}
@@ -30,7 +30,7 @@ func init() {
 	if runtime.GOOS == "windows" {
 		name = "mount"
 	}
-	mountlib.NewMountCommand(name, Mount)
+	mountlib.NewFuseMountCommand(name, "cgofuse", Mount)
 }
 
 // mountOptions configures the options from the command line flags
@@ -51,11 +51,27 @@ func mountOptions(device string, mountpoint string) (options []string) {
 		options = append(options, "-o", "debug")
 	}
 
+	volumeName := mountlib.MakeVolumeName(device)
+
 	// OSX options
 	if runtime.GOOS == "darwin" {
-		options = append(options, "-o", "volname="+device)
+		options = append(options, "-o", "volname="+volumeName)
 		options = append(options, "-o", "noappledouble")
-		options = append(options, "-o", "noapplexattr")
+		if !mountlib.AppleXattr {
+			options = append(options, "-o", "noapplexattr")
+		}
+	}
+
+	// Use the inode numbers from the VFS rather than ones made up
+	// by libfuse so they are the same each mount
+	if runtime.GOOS != "windows" {
+		options = append(options, "-o", "use_ino")
+	}
+
+	// Kernel lookup caching - WinFsp doesn't have these
+	if runtime.GOOS != "windows" {
+		options = append(options, "-o", fmt.Sprintf("entry_timeout=%g", mountlib.EntryTimeout.Seconds()))
+		options = append(options, "-o", fmt.Sprintf("negative_timeout=%g", mountlib.NegativeTimeout.Seconds()))
 	}
 
 	// Windows options
@@ -64,6 +80,11 @@ func mountOptions(device string, mountpoint string) (options []string) {
 		options = append(options, "-o", "uid=-1")
 		options = append(options, "-o", "gid=-1")
 		options = append(options, "--FileSystemName=rclone")
+		if mountlib.NetworkMode {
+			// This makes the drive a network share \\rclone\volumeName
+			options = append(options, "--VolumePrefix=\\rclone\\"+volumeName)
+		}
+		options = append(options, "-o", "volname="+volumeName)
 	}
 
 	if mountlib.AllowNonEmpty {
@@ -114,6 +135,12 @@ func waitFor(fn func() bool) (ok bool) {
 // returns an error, and an error channel for the serve process to
 // report an error when fusermount is called.
 func mount(f fs.Fs, mountpoint string) (*vfs.VFS, <-chan error, func() error, error) {
+	return mountVFS(vfs.New(f, &vfsflags.Opt), f, mountpoint)
+}
+
+// mountVFS mounts VFS at mountpoint as mount does.  The VFS is passed
+// in so it can be reused when remounting.
+func mountVFS(VFS *vfs.VFS, f fs.Fs, mountpoint string) (*vfs.VFS, <-chan error, func() error, error) {
 	fs.Debugf(f, "Mounting on %q", mountpoint)
 
 	// Check the mountpoint - in Windows the mountpoint musn't exist before the mount
@@ -128,7 +155,7 @@ func mount(f fs.Fs, mountpoint string) (*vfs.VFS, <-chan error, func() error, er
 	}
 
 	// Create underlying FS
-	fsys := NewFS(f)
+	fsys := newFS(VFS, f)
 	host := fuse.NewFileSystemHost(fsys)
 
 	// Create options
@@ -196,9 +223,9 @@ func mount(f fs.Fs, mountpoint string) (*vfs.VFS, <-chan error, func() error, er
 // If noModTime is set then it
 func Mount(f fs.Fs, mountpoint string) error {
 	// Mount it
-	FS, errChan, _, err := mount(f, mountpoint)
+	FS, errChan, unmount, err := mount(f, mountpoint)
 	if err != nil {
-		return errors.Wrap(err, "failed to mount FUSE fs")
+		return mountlib.InitError(errors.Wrap(err, "failed to mount FUSE fs"))
 	}
 
 	// Note cgofuse unmounts the fs on SIGINT etc
@@ -206,6 +233,9 @@ func Mount(f fs.Fs, mountpoint string) error {
 	sigHup := make(chan os.Signal, 1)
 	signal.Notify(sigHup, syscall.SIGHUP)
 
+	unmountRequests, removeLiveMount := mountlib.AddLiveMount(mountpoint, FS)
+	defer removeLiveMount()
+
 	if err := sdnotify.SdNotifyReady(); err != nil && err != sdnotify.SdNotifyNoSocket {
 		return errors.Wrap(err, "failed to notify systemd")
 	}
@@ -215,6 +245,33 @@ waitloop:
 		select {
 		// umount triggered outside the app
 		case err = <-errChan:
+			if !mountlib.AutoRemount || !mountlib.SessionLost(mountpoint) {
+				break waitloop
+			}
+			if err != nil {
+				fs.Errorf(f, "Mount failed: %v", err)
+			}
+			// remount with the same VFS to keep its caches - cgofuse
+			// isn't handling SIGINT etc while nothing is mounted
+			sigInt := make(chan os.Signal, 1)
+			signal.Notify(sigInt, syscall.SIGINT, syscall.SIGTERM)
+			errChan, err = mountlib.Remount(mountpoint, sigInt, func() (<-chan error, error) {
+				_, newErrChan, newUnmount, err := mountVFS(FS, f, mountpoint)
+				if err == nil {
+					unmount = newUnmount
+				}
+				return newErrChan, err
+			})
+			signal.Stop(sigInt)
+			if err != nil {
+				// stopped by a signal with nothing mounted
+				err = nil
+				break waitloop
+			}
+		// unmount requested with the remote control
+		case request := <-unmountRequests:
+			err = unmount()
+			request.Reply <- err
 			break waitloop
 		// user sent SIGHUP to clear the cache
 		case <-sigHup:
@@ -1,11 +1,25 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
 	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
+var (
+	jsonOutput = false
+	resolved   = false
+)
+
 func init() {
 	cmd.Root.AddCommand(configCommand)
 	configCommand.AddCommand(configEditCommand)
@@ -17,6 +31,12 @@ func init() {
 	configCommand.AddCommand(configUpdateCommand)
 	configCommand.AddCommand(configDeleteCommand)
 	configCommand.AddCommand(configPasswordCommand)
+	configCommand.AddCommand(configReconnectCommand)
+	configCommand.AddCommand(configDisconnectCommand)
+	configCommand.AddCommand(configUserInfoCommand)
+	configCommand.AddCommand(configValidateCommand)
+	flags.BoolVarP(configUserInfoCommand.Flags(), &jsonOutput, "json", "", false, "Format output as JSON")
+	flags.BoolVarP(configShowCommand.Flags(), &resolved, "resolved", "", false, "Show the values in use and where they came from")
 }
 
 var configCommand = &cobra.Command{
@@ -51,11 +71,33 @@ var configFileCommand = &cobra.Command{
 var configShowCommand = &cobra.Command{
 	Use:   "show [<remote>]",
 	Short: `Print (decrypted) config file, or the config for a single remote.`,
+	Long: `
+Print the (decrypted) config file, or the config for a single remote.
+
+With --resolved, show the values each remote will actually use and
+where each one came from, as config values can be overridden by
+connection strings, flags and environment variables.  The remote may
+be given as a connection string, eg
+
+    rclone config show --resolved "mys3,acl=private:"
+`,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(0, 1, command, args)
-		if len(args) == 0 {
+		switch {
+		case resolved && len(args) == 0:
+			remotes := config.FileSections()
+			sort.Strings(remotes)
+			for i, remote := range remotes {
+				if i > 0 && remote == remotes[i-1] {
+					continue // defined in the config file and the environment
+				}
+				config.ShowRemoteResolved(remote)
+			}
+		case resolved:
+			config.ShowRemoteResolved(strings.TrimSuffix(args[0], ":"))
+		case len(args) == 0:
 			config.ShowConfig()
-		} else {
+		default:
 			config.ShowRemote(args[0])
 		}
 	},
@@ -139,3 +181,142 @@ For example to set password of a remote of name myremote you would do:
 		return config.PasswordRemote(args[0], args[1:])
 	},
 }
+
+var configReconnectCommand = &cobra.Command{
+	Use:   "reconnect remote:",
+	Short: `Re-authenticates user with remote.`,
+	Long: `
+This reconnects remote: passed in to the cloud storage system.
+
+To disconnect the remote use "rclone config disconnect".
+
+This normally means going through the interactive oauth flow again.
+`,
+	RunE: func(command *cobra.Command, args []string) error {
+		cmd.CheckArgs(1, 1, command, args)
+		fsInfo, configName, _, err := fs.ParseRemote(args[0])
+		if err != nil {
+			return err
+		}
+		if fsInfo.Config == nil {
+			return errors.Errorf("%s: doesn't support reconnect", configName)
+		}
+		fsInfo.Config(configName)
+		return nil
+	},
+}
+
+var configDisconnectCommand = &cobra.Command{
+	Use:   "disconnect remote:",
+	Short: `Disconnects user from remote`,
+	Long: `
+This disconnects the remote: passed in to the cloud storage system.
+
+This normally means revoking the oauth token.
+
+To reconnect use "rclone config reconnect".
+`,
+	RunE: func(command *cobra.Command, args []string) error {
+		cmd.CheckArgs(1, 1, command, args)
+		f := cmd.NewFsSrc(args)
+		doDisconnect := f.Features().Disconnect
+		if doDisconnect == nil {
+			return errors.Errorf("%v doesn't support disconnect", f)
+		}
+		err := doDisconnect()
+		if err != nil {
+			return errors.Wrap(err, "disconnect call failed")
+		}
+		config.FileDeleteKey(f.Name(), config.ConfigToken)
+		config.SaveConfig()
+		return nil
+	},
+}
+
+var configUserInfoCommand = &cobra.Command{
+	Use:   "userinfo remote:",
+	Short: `Prints info about logged in user of remote.`,
+	Long: `
+This prints the details of the person logged in to the cloud storage
+system.
+`,
+	RunE: func(command *cobra.Command, args []string) error {
+		cmd.CheckArgs(1, 1, command, args)
+		f := cmd.NewFsSrc(args)
+		doUserInfo := f.Features().UserInfo
+		if doUserInfo == nil {
+			return errors.Errorf("%v doesn't support UserInfo", f)
+		}
+		u, err := doUserInfo()
+		if err != nil {
+			return errors.Wrap(err, "UserInfo call failed")
+		}
+		if jsonOutput {
+			raw, err := json.MarshalIndent(u, "", "\t")
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s\n", raw)
+			return nil
+		}
+		var keys []string
+		var maxKeyLen int
+		for key := range u {
+			keys = append(keys, key)
+			if len(key) > maxKeyLen {
+				maxKeyLen = len(key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Printf("%*s: %s\n", maxKeyLen, key, u[key])
+		}
+		return nil
+	},
+}
+
+var configValidateCommand = &cobra.Command{
+	Use:   "validate [<remote>]",
+	Short: `Check the config file for mistakes.`,
+	Long: `
+Check the config of all the remotes, or of just the remote given, for
+mistakes.  This reports
+
+* remotes with a missing or unknown type
+* keys which the backend doesn't use, with a suggestion if it looks
+  like a typo, eg ` + "`chunk_sise`" + `
+* values which should be true or false or a whole number but aren't
+
+It exits with an error if any problems were found.  The same problems
+are logged as warnings whenever rclone loads the config file.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(0, 1, command, args)
+		remoteProblems := map[string][]string{}
+		if len(args) == 0 {
+			remoteProblems = config.ValidateConfig()
+		} else {
+			name := strings.TrimSuffix(args[0], ":")
+			if problems := config.ValidateRemote(name); len(problems) > 0 {
+				remoteProblems[name] = problems
+			}
+		}
+		if len(remoteProblems) == 0 {
+			fmt.Println("No problems found")
+			return
+		}
+		var names []string
+		for name := range remoteProblems {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		count := 0
+		for _, name := range names {
+			for _, problem := range remoteProblems[name] {
+				fmt.Printf("%s: %s\n", name, problem)
+				count++
+			}
+		}
+		log.Fatalf("Found %d problems in the config", count)
+	},
+}
@@ -1,7 +1,13 @@
 package copyto
 
 import (
+	"io"
+	"log"
+	"os"
+	"time"
+
 	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/ncw/rclone/fs/sync"
 	"github.com/spf13/cobra"
@@ -40,9 +46,36 @@ This will:
 This doesn't transfer unchanged files, testing by size and
 modification time or MD5SUM.  It doesn't delete files from the
 destination.
+
+Either src or dst (but not both) may be ` + "`-`" + `, meaning standard
+input or standard output.  This lets rclone be used in the middle of
+a shell pipeline, eg
+
+    tar cz dir | rclone copyto - remote:backup.tgz
+    rclone copyto remote:backup.tgz - | tar xz
+
+When reading from standard input the upload is streamed in the same
+way as the rcat command so it can't be retried.  When writing to
+standard output src must be a file.
 `,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(2, 2, command, args)
+		switch {
+		case args[0] == "-" && args[1] == "-":
+			log.Fatalf("Can't copy from standard input to standard output")
+		case args[0] == "-":
+			fdst, dstFileName := cmd.NewFsDstFile(args[1:])
+			cmd.Run(false, false, command, func() error {
+				return copyFromStdin(fdst, dstFileName, os.Stdin)
+			})
+			return
+		case args[1] == "-":
+			fsrc, _ := cmd.NewFsSrcFile(args[0:1])
+			cmd.Run(false, false, command, func() error {
+				return copyToStdout(fsrc, os.Stdout)
+			})
+			return
+		}
 		fsrc, srcFileName, fdst, dstFileName := cmd.NewFsSrcDstFiles(args)
 		cmd.Run(true, true, command, func() error {
 			if srcFileName == "" {
@@ -52,3 +85,16 @@ destination.
 		})
 	},
 }
+
+// copyFromStdin streams in, which is standard input, to dstFileName
+// in fdst
+func copyFromStdin(fdst fs.Fs, dstFileName string, in io.ReadCloser) error {
+	_, err := operations.Rcat(fdst, dstFileName, in, time.Now())
+	return err
+}
+
+// copyToStdout writes the file fsrc points to to out, which is
+// standard output
+func copyToStdout(fsrc fs.Fs, out io.Writer) error {
+	return operations.Cat(fsrc, out, 0, -1)
+}
@@ -0,0 +1,59 @@
+package copyto
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/ncw/rclone/backend/local"
+)
+
+// TestMain drives the tests
+func TestMain(m *testing.M) {
+	fstest.TestMain(m)
+}
+
+func TestCopyFromStdin(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	// Stream through a pipe so the size isn't known in advance
+	contents := fstest.RandomString(100 * 1024)
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := io.Copy(pw, strings.NewReader(contents))
+		_ = pw.CloseWithError(err)
+	}()
+	require.NoError(t, copyFromStdin(r.Fremote, "dir/stdin.txt", pr))
+
+	o, err := r.Fremote.NewObject("dir/stdin.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(contents)), o.Size())
+	in, err := o.Open()
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, contents, string(data))
+}
+
+func TestCopyToStdout(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	file1 := r.WriteObject("file1.txt", "hello world", fstest.Time("2001-02-03T04:05:06.499999999Z"))
+	fstest.CheckItems(t, r.Fremote, file1)
+	fsrc, err := fs.NewFs(r.FremoteName + "/file1.txt")
+	require.Equal(t, fs.ErrorIsFile, err)
+
+	var out bytes.Buffer
+	require.NoError(t, copyToStdout(fsrc, &out))
+	assert.Equal(t, "hello world", out.String())
+}
@@ -100,5 +100,10 @@ func cryptCheck(fdst, fsrc fs.Fs) error {
 		return false, false
 	}
 
-	return operations.CheckFn(fcrypt, fsrc, checkIdentical)
+	opt := operations.CheckOpt{
+		Fdst:  fcrypt,
+		Fsrc:  fsrc,
+		Check: checkIdentical,
+	}
+	return operations.CheckFn(&opt)
 }
@@ -10,10 +10,16 @@ func init() {
 }
 
 var completionDefinition = &cobra.Command{
-	Use:   "genautocomplete [shell]",
-	Short: `Output completion script for a given shell.`,
+	Use:     "genautocomplete [shell]",
+	Aliases: []string{"completion"},
+	Short:   `Output completion script for a given shell.`,
 	Long: `
 Generates a shell completion script for rclone.
 Run with --help to list the supported shells.
+
+As well as the commands and flags, the scripts complete the names of
+the configured remotes and the files and directories on them.  Only
+the directory being completed is listed so this is quick, but it
+needs network access for remotes on the network.
 `,
 }
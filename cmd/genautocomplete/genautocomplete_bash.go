@@ -9,8 +9,39 @@ import (
 
 func init() {
 	completionDefinition.AddCommand(bashCommandDefinition)
+	cmd.Root.BashCompletionFunction = bashCompletionFunction
 }
 
+// bashCompletionFunction is called by the generated bash completion
+// when there are no commands or flags to complete.  It completes the
+// remote names and the paths on remotes.
+//
+// ":" splits words in bash so the whole of the word being completed
+// is fetched again here.
+const bashCompletionFunction = `
+__custom_func() {
+    local cur prev words cword
+    if declare -F _init_completion >/dev/null 2>&1; then
+        _init_completion -n : || return
+    else
+        __rclone_init_completion -n : || return
+    fi
+    local line
+    while IFS= read -r line; do
+        COMPREPLY+=("$line")
+    done < <(command rclone genautocomplete remote-path -- "$cur" 2>/dev/null)
+    if [[ $cur != *:* ]] && declare -F _filedir >/dev/null 2>&1; then
+        _filedir
+    fi
+    if [[ ${#COMPREPLY[@]} -eq 1 && ${COMPREPLY[0]} == *[:/] ]] && [[ $(type -t compopt) = "builtin" ]]; then
+        compopt -o nospace
+    fi
+    if declare -F __ltrim_colon_completions >/dev/null; then
+        __ltrim_colon_completions "$cur"
+    fi
+}
+`
+
 var bashCommandDefinition = &cobra.Command{
 	Use:   "bash [output_file]",
 	Short: `Output bash completion script for rclone.`,
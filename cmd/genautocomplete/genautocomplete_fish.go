@@ -0,0 +1,140 @@
+package genautocomplete
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	completionDefinition.AddCommand(fishCommandDefinition)
+}
+
+var fishCommandDefinition = &cobra.Command{
+	Use:   "fish [output_file]",
+	Short: `Output fish completion script for rclone.`,
+	Long: `
+Generates a fish autocompletion script for rclone.
+
+This writes to /etc/fish/completions/rclone.fish by default so will
+probably need to be run with sudo or as root, eg
+
+    sudo rclone genautocomplete fish
+
+Logout and login again to use the autocompletion scripts, or source
+them directly
+
+    . /etc/fish/completions/rclone.fish
+
+If you supply a command line argument the script will be written
+there.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(0, 1, command, args)
+		out := "/etc/fish/completions/rclone.fish"
+		if len(args) > 0 {
+			out = args[0]
+		}
+		outFile, err := os.Create(out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer func() { _ = outFile.Close() }()
+		err = genFishCompletion(outFile, cmd.Root)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// fishPreamble completes the remote names and the paths on remotes as
+// well as local files
+const fishPreamble = `# fish completion for %[1]s
+
+function __%[1]s_paths
+    set -l cur (commandline -ct)
+    command %[1]s genautocomplete remote-path -- $cur 2>/dev/null
+    if not string match -q -- '*:*' $cur
+        __fish_complete_path $cur
+    end
+end
+
+complete -c %[1]s -f
+complete -c %[1]s -n 'not __fish_use_subcommand' -a '(__%[1]s_paths)'
+`
+
+// fishQuote quotes s for use in a single quoted fish string
+func fishQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `'`, `\'`, -1)
+	return "'" + s + "'"
+}
+
+// genFishCompletion writes a fish completion script for root to w
+func genFishCompletion(w io.Writer, root *cobra.Command) error {
+	buf := new(bytes.Buffer)
+	name := root.Name()
+	fmt.Fprintf(buf, fishPreamble, name)
+	writeFishFlags(buf, name, "", root.PersistentFlags())
+	writeFishCommands(buf, name, "__fish_use_subcommand", root)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// writeFishCommands writes the completions for the sub commands of
+// parent which are available when condition is true
+func writeFishCommands(w io.Writer, name, condition string, parent *cobra.Command) {
+	var subNames []string
+	for _, c := range parent.Commands() {
+		if c.IsAvailableCommand() {
+			subNames = append(subNames, c.Name())
+		}
+	}
+	for _, c := range parent.Commands() {
+		if !c.IsAvailableCommand() {
+			continue
+		}
+		fmt.Fprintf(w, "complete -c %s -n %s -a %s -d %s\n", name, fishQuote(condition), c.Name(), fishQuote(c.Short))
+		seen := "__fish_seen_subcommand_from " + c.Name()
+		if parent != parent.Root() {
+			seen = "__fish_seen_subcommand_from " + parent.Name() + "; and " + seen
+		}
+		writeFishFlags(w, name, seen, c.LocalNonPersistentFlags())
+		if c.HasAvailableSubCommands() {
+			var cNames []string
+			for _, sub := range c.Commands() {
+				cNames = append(cNames, sub.Name())
+			}
+			writeFishCommands(w, name, seen+"; and not __fish_seen_subcommand_from "+strings.Join(cNames, " "), c)
+		}
+	}
+}
+
+// writeFishFlags writes the completions for flags which are available
+// when condition is true, or always if condition is empty
+func writeFishFlags(w io.Writer, name, condition string, flags *pflag.FlagSet) {
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden || flag.Deprecated != "" {
+			return
+		}
+		fmt.Fprintf(w, "complete -c %s", name)
+		if condition != "" {
+			fmt.Fprintf(w, " -n %s", fishQuote(condition))
+		}
+		fmt.Fprintf(w, " -l %s", flag.Name)
+		if flag.Shorthand != "" {
+			fmt.Fprintf(w, " -s %s", flag.Shorthand)
+		}
+		if flag.Value.Type() != "bool" {
+			fmt.Fprintf(w, " -r")
+		}
+		fmt.Fprintf(w, " -d %s\n", fishQuote(flag.Usage))
+	})
+}
@@ -0,0 +1,101 @@
+package genautocomplete
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	completionDefinition.AddCommand(pathCommandDefinition)
+}
+
+// pathCommandDefinition is called by the completion scripts to
+// complete remote names and remote paths
+var pathCommandDefinition = &cobra.Command{
+	Use:    "remote-path [prefix]",
+	Short:  `Output the remotes and remote paths which start with prefix.`,
+	Hidden: true,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(0, 1, command, args)
+		prefix := ""
+		if len(args) > 0 {
+			prefix = args[0]
+		}
+		// Give up quickly rather than holding up the shell
+		fs.Config.LowLevelRetries = 1
+		completions, err := completeRemotePath(prefix)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, completion := range completions {
+			fmt.Println(completion)
+		}
+	},
+}
+
+// completeRemotePath returns the completions for prefix.
+//
+// If prefix doesn't contain a ":" then the names of the configured
+// remotes which start with prefix are returned with a ":" on the end.
+//
+// Otherwise the directory of prefix is listed and the entries which
+// start with the leaf of prefix are returned, with a "/" on the end of
+// directories.  Only the one directory is listed so this is quick.
+func completeRemotePath(prefix string) (completions []string, err error) {
+	colon := strings.IndexRune(prefix, ':')
+	if colon < 0 {
+		for _, remote := range config.FileSections() {
+			if strings.HasPrefix(remote, prefix) {
+				completions = append(completions, remote+":")
+			}
+		}
+		sort.Strings(completions)
+		return completions, nil
+	}
+	remote, remotePath := prefix[:colon+1], prefix[colon+1:]
+	if strings.ContainsRune(remote, '/') {
+		// a local path - leave it to the shell
+		return nil, nil
+	}
+	dir, leaf := "", remotePath
+	if slash := strings.LastIndex(remotePath, "/"); slash >= 0 {
+		dir, leaf = remotePath[:slash+1], remotePath[slash+1:]
+	}
+	// list dir from an Fs rooted at the top of the remote, or at / if
+	// dir is absolute
+	root := remote
+	listDir := strings.TrimSuffix(dir, "/")
+	if strings.HasPrefix(dir, "/") {
+		root += "/"
+		listDir = strings.TrimPrefix(listDir, "/")
+	}
+	f, err := fs.NewFs(root)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := f.List(listDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := path.Base(entry.Remote())
+		if !strings.HasPrefix(name, leaf) {
+			continue
+		}
+		completion := remote + dir + name
+		if _, isDir := entry.(fs.Directory); isDir {
+			completion += "/"
+		}
+		completions = append(completions, completion)
+	}
+	sort.Strings(completions)
+	return completions, nil
+}
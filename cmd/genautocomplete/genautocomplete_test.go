@@ -3,9 +3,13 @@ package genautocomplete
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCompletionBash(t *testing.T) {
@@ -18,7 +22,7 @@ func TestCompletionBash(t *testing.T) {
 
 	bs, err := ioutil.ReadFile(tempFile.Name())
 	assert.NoError(t, err)
-	assert.NotEmpty(t, string(bs))
+	assert.Contains(t, string(bs), "__custom_func")
 }
 
 func TestCompletionZsh(t *testing.T) {
@@ -31,5 +35,53 @@ func TestCompletionZsh(t *testing.T) {
 
 	bs, err := ioutil.ReadFile(tempFile.Name())
 	assert.NoError(t, err)
-	assert.NotEmpty(t, string(bs))
+	assert.Contains(t, string(bs), "_rclone_paths")
+	assert.NotContains(t, string(bs), ":_files'")
+}
+
+func TestCompletionFish(t *testing.T) {
+	tempFile, err := ioutil.TempFile("", "completion_fish")
+	assert.NoError(t, err)
+	defer func() { _ = tempFile.Close() }()
+	defer func() { _ = os.Remove(tempFile.Name()) }()
+
+	fishCommandDefinition.Run(fishCommandDefinition, []string{tempFile.Name()})
+
+	bs, err := ioutil.ReadFile(tempFile.Name())
+	assert.NoError(t, err)
+	assert.Contains(t, string(bs), "complete -c rclone -n '__fish_use_subcommand' -a genautocomplete")
+	assert.Contains(t, string(bs), "(__rclone_paths)")
+}
+
+func TestCompleteRemotePath(t *testing.T) {
+	config.LoadConfig()
+	config.FileSet("completetest", "type", "local")
+	config.FileSet("completetestother", "type", "local")
+
+	dir, err := ioutil.TempDir("", "completion_path")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "apple"), 0777))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "apricot.txt"), []byte("hello"), 0666))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "banana.txt"), []byte("hello"), 0666))
+	dir = filepath.ToSlash(dir)
+
+	completions, err := completeRemotePath("completete")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"completetest:", "completetestother:"}, completions)
+
+	completions, err = completeRemotePath("completetest:" + dir + "/ap")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"completetest:" + dir + "/apple/",
+		"completetest:" + dir + "/apricot.txt",
+	}, completions)
+
+	completions, err = completeRemotePath("completetest:" + dir + "/")
+	require.NoError(t, err)
+	assert.Len(t, completions, 3)
+
+	completions, err = completeRemotePath("./local:path")
+	require.NoError(t, err)
+	assert.Nil(t, completions)
 }
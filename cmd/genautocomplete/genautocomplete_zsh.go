@@ -1,8 +1,10 @@
 package genautocomplete
 
 import (
+	"bytes"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/ncw/rclone/cmd"
 	"github.com/spf13/cobra"
@@ -42,9 +44,34 @@ there.
 			log.Fatal(err)
 		}
 		defer func() { _ = outFile.Close() }()
-		err = cmd.Root.GenZshCompletion(outFile)
+		var buf bytes.Buffer
+		err = cmd.Root.GenZshCompletion(&buf)
+		if err != nil {
+			log.Fatal(err)
+		}
+		_, err = outFile.WriteString(addZshPathCompletion(buf.String()))
 		if err != nil {
 			log.Fatal(err)
 		}
 	},
 }
+
+// zshPathFunction completes the remote names and the paths on remotes
+// as well as local files
+const zshPathFunction = `_rclone_paths() {
+  local -a paths
+  paths=(${(f)"$(rclone genautocomplete remote-path -- "$PREFIX" 2>/dev/null)"})
+  compadd -S '' -- ${(M)paths:#*[:/]}
+  compadd -- ${paths:#*[:/]}
+  [[ $PREFIX == *:* ]] || _files
+}
+
+`
+
+// addZshPathCompletion makes the zsh completion script generated by
+// cobra complete remote paths where it would complete files
+func addZshPathCompletion(script string) string {
+	script = strings.Replace(script, ":_files'", ":_rclone_paths'", -1)
+	header := "#compdef " + cmd.Root.Name() + "\n\n"
+	return header + zshPathFunction + strings.TrimPrefix(script, header)
+}
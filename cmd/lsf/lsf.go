@@ -31,7 +31,7 @@ func init() {
 	flags.StringVarP(&format, "format", "F", "p", "Output format - see  help for details")
 	flags.StringVarP(&separator, "separator", "s", ";", "Separator for the items in the format.")
 	flags.BoolVarP(&dirSlash, "dir-slash", "d", true, "Append a slash to directory names.")
-	flags.VarP(&hashType, "hash", "", "Use this hash when `h` is used in the format MD5|SHA-1|DropboxHash")
+	flags.VarP(&hashType, "hash", "", "Use this hash when `h` is used in the format MD5|SHA-1|DropboxHash|SHA-256|CRC32C")
 	flags.BoolVarP(&filesOnly, "files-only", "", false, "Only list files.")
 	flags.BoolVarP(&dirsOnly, "dirs-only", "", false, "Only list directories.")
 	commandDefintion.Flags().BoolVarP(&recurse, "recursive", "R", false, "Recurse into the listing.")
@@ -54,6 +54,7 @@ output:
     s - size
     t - modification time
     h - hash
+    T - storage tier or class, eg STANDARD_IA on S3
 
 So if you wanted the path, size and modification time, you would use
 --format "pst", or maybe --format "tsp" to put the path last.
@@ -101,6 +102,8 @@ func Lsf(fsrc fs.Fs, out io.Writer) error {
 			list.AddSize()
 		case 'h':
 			list.AddHash(hashType)
+		case 'T':
+			list.AddTier()
 		default:
 			return errors.Errorf("Unknown format character %q", char)
 		}
@@ -42,6 +42,7 @@ type lsJSON struct {
 	ModTime   Timestamp //`json:",omitempty"`
 	IsDir     bool
 	Hashes    map[string]string `json:",omitempty"`
+	Tier      string            `json:",omitempty"`
 }
 
 // Timestamp a time in RFC3339 format with Nanosecond precision secongs
@@ -74,7 +75,8 @@ The output is an array of Items, where each Item looks like this
       "Name" : "file.txt",
       "Encrypted" : "v0qpsdq8anpci8n929v3uu9338",
       "Path" : "full/path/goes/here/file.txt",
-      "Size" : 6
+      "Size" : 6,
+      "Tier" : "STANDARD"
    }
 
 If --hash is not specified the Hashes property won't be emitted.
@@ -83,6 +85,9 @@ If --no-modtime is specified then ModTime will be blank.
 
 If --encrypted is not specified the Encrypted won't be emitted.
 
+Tier is the storage tier or class of the object and is only emitted
+if the remote supports tiers, eg S3 and Google Cloud Storage.
+
 The Path field will only show folders below the remote path being listed.
 If "remote:path" contains the file "subfolder/file.txt", the Path for "file.txt"
 will be "subfolder/file.txt", not "remote:path/subfolder/file.txt".
@@ -143,6 +148,7 @@ can be processed line by line as each item is written one to a line.
 						item.IsDir = true
 					case fs.Object:
 						item.IsDir = false
+						item.Tier = operations.GetTier(x)
 						if showHash {
 							item.Hashes = make(map[string]string)
 							for _, hashType := range x.Fs().Hashes().Array() {
@@ -30,6 +30,7 @@ func (d *Dir) Attr(ctx context.Context, a *fuse.Attr) (err error) {
 	a.Gid = d.VFS().Opt.GID
 	a.Uid = d.VFS().Opt.UID
 	a.Mode = os.ModeDir | d.VFS().Opt.DirPerms
+	a.Inode = d.Inode()
 	modTime := d.ModTime()
 	a.Atime = modTime
 	a.Mtime = modTime
@@ -74,7 +75,7 @@ func (d *Dir) Lookup(ctx context.Context, req *fuse.LookupRequest, resp *fuse.Lo
 	if err != nil {
 		return nil, translateError(err)
 	}
-	resp.EntryValid = mountlib.AttrTimeout
+	resp.EntryValid = mountlib.EntryTimeout
 	switch x := mnode.(type) {
 	case *vfs.File:
 		return &File{x}, nil
@@ -85,29 +86,13 @@ func (d *Dir) Lookup(ctx context.Context, req *fuse.LookupRequest, resp *fuse.Lo
 }
 
 // Check interface satisfied
-var _ fusefs.HandleReadDirAller = (*Dir)(nil)
+var _ fusefs.NodeOpener = (*Dir)(nil)
 
-// ReadDirAll reads the contents of the directory
-func (d *Dir) ReadDirAll(ctx context.Context) (dirents []fuse.Dirent, err error) {
-	itemsRead := -1
-	defer log.Trace(d, "")("item=%d, err=%v", &itemsRead, &err)
-	items, err := d.Dir.ReadDirAll()
-	if err != nil {
-		return nil, translateError(err)
-	}
-	for _, node := range items {
-		var dirent = fuse.Dirent{
-			// Inode FIXME ???
-			Type: fuse.DT_File,
-			Name: node.Name(),
-		}
-		if node.IsDir() {
-			dirent.Type = fuse.DT_Dir
-		}
-		dirents = append(dirents, dirent)
-	}
-	itemsRead = len(dirents)
-	return dirents, nil
+// Open the directory for reading returning a handle which passes the
+// entries to the kernel as they are listed
+func (d *Dir) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenResponse) (handle fusefs.Handle, err error) {
+	defer log.Trace(d, "flags=%v", req.Flags)("handle=%v, err=%v", &handle, &err)
+	return newDirHandle(d.Dir), nil
 }
 
 var _ fusefs.NodeCreater = (*Dir)(nil)
@@ -189,7 +174,20 @@ var _ fusefs.NodeLinker = (*Dir)(nil)
 
 // Link creates a new directory entry in the receiver based on an
 // existing Node. Receiver must be a directory.
+//
+// This is emulated with a server side copy if --link-copy is set.
 func (d *Dir) Link(ctx context.Context, req *fuse.LinkRequest, old fusefs.Node) (new fusefs.Node, err error) {
 	defer log.Trace(d, "req=%v, old=%v", req, old)("new=%v, err=%v", &new, &err)
-	return nil, fuse.ENOSYS
+	if !mountlib.LinkCopy {
+		return nil, fuse.ENOSYS
+	}
+	oldFile, ok := old.(*File)
+	if !ok {
+		return nil, fuse.EPERM
+	}
+	file, err := d.Dir.Link(oldFile.File, req.NewName)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &File{file}, nil
 }
@@ -0,0 +1,126 @@
+// +build linux darwin freebsd
+
+package mount
+
+import (
+	"sync"
+
+	"bazil.org/fuse"
+	fusefs "bazil.org/fuse/fs"
+	"bazil.org/fuse/fuseutil"
+	"github.com/ncw/rclone/fs/log"
+	"github.com/ncw/rclone/vfs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// errDirHandleReleased is returned to stop the listing when the
+// handle is released before it is complete
+var errDirHandleReleased = errors.New("directory handle released")
+
+// DirHandle is an open directory which passes the entries to the
+// kernel as they are listed rather than waiting for the whole
+// listing.
+type DirHandle struct {
+	d        *vfs.Dir
+	mu       sync.Mutex // protects the following
+	cond     *sync.Cond // signalled when data, done or released change
+	data     []byte     // dirents listed so far
+	started  bool       // set when the listing has been started
+	done     bool       // set when the listing has finished
+	err      error      // error from the listing if any
+	released bool       // set when the handle has been released
+}
+
+// newDirHandle makes a new DirHandle for d
+func newDirHandle(d *vfs.Dir) *DirHandle {
+	dh := &DirHandle{
+		d: d,
+	}
+	dh.cond = sync.NewCond(&dh.mu)
+	return dh
+}
+
+// String converts it to printable
+func (dh *DirHandle) String() string {
+	return dh.d.String()
+}
+
+// list reads the directory appending the dirents to dh.data as each
+// page arrives
+func (dh *DirHandle) list() {
+	err := dh.d.ReadDirPages(func(items vfs.Nodes) error {
+		dh.mu.Lock()
+		defer dh.mu.Unlock()
+		if dh.released {
+			return errDirHandleReleased
+		}
+		for _, node := range items {
+			var dirent = fuse.Dirent{
+				Inode: node.Inode(),
+				Type:  fuse.DT_File,
+				Name:  node.Name(),
+			}
+			if node.IsDir() {
+				dirent.Type = fuse.DT_Dir
+			}
+			dh.data = fuse.AppendDirent(dh.data, dirent)
+		}
+		dh.cond.Broadcast()
+		return nil
+	})
+	dh.mu.Lock()
+	defer dh.mu.Unlock()
+	if err != errDirHandleReleased {
+		dh.err = err
+	}
+	dh.done = true
+	dh.cond.Broadcast()
+}
+
+// Check interface satisfied
+var _ fusefs.HandleReader = (*DirHandle)(nil)
+
+// Read the directory entries
+//
+// This waits until enough entries have been listed to fill the
+// request, or the listing is complete, so the kernel gets the start
+// of a big directory straight away.
+func (dh *DirHandle) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.ReadResponse) (err error) {
+	var n int
+	defer log.Trace(dh, "len=%d, offset=%d", req.Size, req.Offset)("read=%d, err=%v", &n, &err)
+	dh.mu.Lock()
+	defer dh.mu.Unlock()
+	if req.Offset == 0 && dh.done {
+		// rewinddir(3) or similar so read the directory again
+		dh.data, dh.started, dh.done, dh.err = nil, false, false, nil
+	}
+	if !dh.started {
+		dh.started = true
+		go dh.list()
+	}
+	end := req.Offset + int64(req.Size)
+	for !dh.done && int64(len(dh.data)) < end {
+		dh.cond.Wait()
+	}
+	if dh.err != nil {
+		return translateError(dh.err)
+	}
+	fuseutil.HandleRead(req, resp, dh.data)
+	n = len(resp.Data)
+	return nil
+}
+
+// Check interface satisfied
+var _ fusefs.HandleReleaser = (*DirHandle)(nil)
+
+// Release is called when we are finished with the directory handle
+// and stops the listing if it is still running
+func (dh *DirHandle) Release(ctx context.Context, req *fuse.ReleaseRequest) (err error) {
+	defer log.Trace(dh, "")("err=%v", &err)
+	dh.mu.Lock()
+	defer dh.mu.Unlock()
+	dh.released = true
+	dh.cond.Broadcast()
+	return nil
+}
@@ -31,11 +31,11 @@ func (f *File) Attr(ctx context.Context, a *fuse.Attr) (err error) {
 	a.Gid = f.VFS().Opt.GID
 	a.Uid = f.VFS().Opt.UID
 	a.Mode = f.VFS().Opt.FilePerms
+	a.Inode = f.Inode()
 	a.Size = Size
 	a.Atime = modTime
 	a.Mtime = modTime
-	a.Ctime = modTime
-	a.Crtime = modTime
+	a.Ctime, a.Crtime = mountlib.StatTimes(f.File, modTime)
 	a.Blocks = Blocks
 	return nil
 }
@@ -81,6 +81,54 @@ func (f *File) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenR
 	return &FileHandle{handle}, nil
 }
 
+// Check interface satisfied
+var _ fusefs.NodeGetxattrer = (*File)(nil)
+
+// Getxattr gets an extended attribute by the given name from the
+// node.
+//
+// If there is no xattr by that name, returns fuse.ErrNoXattr.
+func (f *File) Getxattr(ctx context.Context, req *fuse.GetxattrRequest, resp *fuse.GetxattrResponse) (err error) {
+	defer log.Trace(f, "name=%q", req.Name)("err=%v", &err)
+	resp.Xattr, err = f.File.GetXattr(req.Name)
+	return translateError(err)
+}
+
+// Check interface satisfied
+var _ fusefs.NodeListxattrer = (*File)(nil)
+
+// Listxattr lists the extended attributes recorded for the node.
+func (f *File) Listxattr(ctx context.Context, req *fuse.ListxattrRequest, resp *fuse.ListxattrResponse) (err error) {
+	defer log.Trace(f, "")("err=%v", &err)
+	names, err := f.File.ListXattr()
+	if err != nil {
+		return translateError(err)
+	}
+	resp.Append(names...)
+	return nil
+}
+
+// Check interface satisfied
+var _ fusefs.NodeSetxattrer = (*File)(nil)
+
+// Setxattr sets an extended attribute with the given name and
+// value for the node.
+func (f *File) Setxattr(ctx context.Context, req *fuse.SetxattrRequest) (err error) {
+	defer log.Trace(f, "name=%q", req.Name)("err=%v", &err)
+	return translateError(f.File.SetXattr(req.Name, req.Xattr))
+}
+
+// Check interface satisfied
+var _ fusefs.NodeRemovexattrer = (*File)(nil)
+
+// Removexattr removes an extended attribute for the name.
+//
+// If there is no xattr by that name, returns fuse.ErrNoXattr.
+func (f *File) Removexattr(ctx context.Context, req *fuse.RemovexattrRequest) (err error) {
+	defer log.Trace(f, "name=%q", req.Name)("err=%v", &err)
+	return translateError(f.File.RemoveXattr(req.Name))
+}
+
 // Check interface satisfied
 var _ fusefs.NodeFsyncer = (*File)(nil)
 
@@ -28,8 +28,13 @@ var _ fusefs.FS = (*FS)(nil)
 
 // NewFS makes a new FS
 func NewFS(f fs.Fs) *FS {
+	return newFS(vfs.New(f, &vfsflags.Opt), f)
+}
+
+// newFS makes a new FS using an existing VFS
+func newFS(VFS *vfs.VFS, f fs.Fs) *FS {
 	fsys := &FS{
-		VFS: vfs.New(f, &vfsflags.Opt),
+		VFS: VFS,
 		f:   f,
 	}
 	return fsys
@@ -53,10 +58,23 @@ var _ fusefs.FSStatfser = (*FS)(nil)
 func (f *FS) Statfs(ctx context.Context, req *fuse.StatfsRequest, resp *fuse.StatfsResponse) (err error) {
 	defer log.Trace("", "")("stat=%+v, err=%v", resp, &err)
 	const blockSize = 4096
-	const fsBlocks = (1 << 50) / blockSize
+	var fsBlocks uint64 = (1 << 50) / blockSize
+	total, used, free := f.VFS.Statfs()
+	if total >= 0 {
+		fsBlocks = uint64(total) / blockSize
+	}
+	bfree := fsBlocks
+	if free >= 0 {
+		bfree = uint64(free) / blockSize
+		if bfree > fsBlocks {
+			bfree = fsBlocks
+		}
+	} else if used >= 0 && uint64(used)/blockSize < fsBlocks {
+		bfree = fsBlocks - uint64(used)/blockSize
+	}
 	resp.Blocks = fsBlocks  // Total data blocks in file system.
-	resp.Bfree = fsBlocks   // Free blocks in file system.
-	resp.Bavail = fsBlocks  // Free blocks in file system if you're not root.
+	resp.Bfree = bfree      // Free blocks in file system.
+	resp.Bavail = bfree     // Free blocks in file system if you're not root.
 	resp.Files = 1E9        // Total files in file system.
 	resp.Ffree = 1E9        // Free files in file system.
 	resp.Bsize = blockSize  // Block size
@@ -91,6 +109,10 @@ func translateError(err error) error {
 		return fuse.Errno(syscall.EROFS)
 	case vfs.ENOSYS:
 		return fuse.ENOSYS
+	case vfs.ENOATTR:
+		return fuse.ErrNoXattr
+	case vfs.ENOTSUP:
+		return fuse.ENOTSUP
 	case vfs.EINVAL:
 		return fuse.Errno(syscall.EINVAL)
 	}
@@ -20,7 +20,7 @@ import (
 )
 
 func init() {
-	mountlib.NewMountCommand("mount", Mount)
+	mountlib.NewFuseMountCommand("mount", "bazil", Mount)
 }
 
 // mountOptions configures the options from the command line flags
@@ -28,9 +28,8 @@ func mountOptions(device string) (options []fuse.MountOption) {
 	options = []fuse.MountOption{
 		fuse.MaxReadahead(uint32(mountlib.MaxReadAhead)),
 		fuse.Subtype("rclone"),
-		fuse.FSName(device), fuse.VolumeName(device),
+		fuse.FSName(device), fuse.VolumeName(mountlib.MakeVolumeName(device)),
 		fuse.NoAppleDouble(),
-		fuse.NoAppleXattr(),
 
 		// Options from benchmarking in the fuse module
 		//fuse.MaxReadahead(64 * 1024 * 1024),
@@ -39,6 +38,9 @@ func mountOptions(device string) (options []fuse.MountOption) {
 		// which is probably related to errors people are having
 		//fuse.WritebackCache(),
 	}
+	if !mountlib.AppleXattr {
+		options = append(options, fuse.NoAppleXattr())
+	}
 	if mountlib.AllowNonEmpty {
 		options = append(options, fuse.AllowNonEmptyMount())
 	}
@@ -57,6 +59,9 @@ func mountOptions(device string) (options []fuse.MountOption) {
 	if mountlib.WritebackCache {
 		options = append(options, fuse.WritebackCache())
 	}
+	if mountlib.NegativeTimeout != 0 {
+		fs.Errorf(nil, "--negative-timeout not supported with this FUSE backend")
+	}
 	if len(mountlib.ExtraOptions) > 0 {
 		fs.Errorf(nil, "-o/--option not supported with this FUSE backend")
 	}
@@ -73,13 +78,19 @@ func mountOptions(device string) (options []fuse.MountOption) {
 // returns an error, and an error channel for the serve process to
 // report an error when fusermount is called.
 func mount(f fs.Fs, mountpoint string) (*vfs.VFS, <-chan error, func() error, error) {
+	return mountVFS(vfs.New(f, &vfsflags.Opt), f, mountpoint)
+}
+
+// mountVFS mounts VFS at mountpoint as mount does.  The VFS is passed
+// in so it can be reused when remounting.
+func mountVFS(VFS *vfs.VFS, f fs.Fs, mountpoint string) (*vfs.VFS, <-chan error, func() error, error) {
 	fs.Debugf(f, "Mounting on %q", mountpoint)
 	c, err := fuse.Mount(mountpoint, mountOptions(f.Name()+":"+f.Root())...)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	filesys := NewFS(f)
+	filesys := newFS(VFS, f)
 	server := fusefs.New(c, nil)
 
 	// Serve the mount point in the background returning error to errChan
@@ -121,7 +132,7 @@ func Mount(f fs.Fs, mountpoint string) error {
 	// Mount it
 	FS, errChan, unmount, err := mount(f, mountpoint)
 	if err != nil {
-		return errors.Wrap(err, "failed to mount FUSE fs")
+		return mountlib.InitError(errors.Wrap(err, "failed to mount FUSE fs"))
 	}
 
 	sigInt := make(chan os.Signal, 1)
@@ -129,6 +140,9 @@ func Mount(f fs.Fs, mountpoint string) error {
 	sigHup := make(chan os.Signal, 1)
 	signal.Notify(sigHup, syscall.SIGHUP)
 
+	unmountRequests, removeLiveMount := mountlib.AddLiveMount(mountpoint, FS)
+	defer removeLiveMount()
+
 	if err := sdnotify.SdNotifyReady(); err != nil && err != sdnotify.SdNotifyNoSocket {
 		return errors.Wrap(err, "failed to notify systemd")
 	}
@@ -138,11 +152,34 @@ waitloop:
 		select {
 		// umount triggered outside the app
 		case err = <-errChan:
-			break waitloop
+			if !mountlib.AutoRemount || !mountlib.SessionLost(mountpoint) {
+				break waitloop
+			}
+			if err != nil {
+				fs.Errorf(f, "Mount failed: %v", err)
+			}
+			// remount with the same VFS to keep its caches
+			errChan, err = mountlib.Remount(mountpoint, sigInt, func() (<-chan error, error) {
+				_, newErrChan, newUnmount, err := mountVFS(FS, f, mountpoint)
+				if err == nil {
+					unmount = newUnmount
+				}
+				return newErrChan, err
+			})
+			if err != nil {
+				// stopped by a signal with nothing mounted
+				err = nil
+				break waitloop
+			}
 		// Program abort: umount
 		case <-sigInt:
 			err = unmount()
 			break waitloop
+		// unmount requested with the remote control
+		case request := <-unmountRequests:
+			err = unmount()
+			request.Reply <- err
+			break waitloop
 		// user sent SIGHUP to clear the cache
 		case <-sigHup:
 			root, err := FS.Root()
@@ -6,10 +6,22 @@ package mountlib
 
 import (
 	"log"
+	"os"
 	"runtime"
+	"time"
 )
 
-func startBackgroundMode() bool {
+func startBackgroundMode() *os.Process {
 	log.Fatalf("background mode not supported on %s platform", runtime.GOOS)
-	return false
+	return nil
+}
+
+func stopBackgroundMode() {}
+
+func mountpointDevice(mountpoint string) (uint64, error) {
+	return 0, nil
+}
+
+func waitBackgroundMode(child *os.Process, mountpoint string, startDevice uint64, timeout time.Duration) error {
+	return nil
 }
@@ -7,26 +7,92 @@ package mountlib
 
 import (
 	"log"
+	"os"
+	"syscall"
+	"time"
 
+	"github.com/ncw/rclone/fs"
+	rclog "github.com/ncw/rclone/fs/log"
+	"github.com/pkg/errors"
 	"github.com/sevlyar/go-daemon"
 )
 
-func startBackgroundMode() bool {
-	cntxt := &daemon.Context{}
-	d, err := cntxt.Reborn()
+// daemonContext is kept so the daemon can remove its PID file when it
+// exits
+var daemonContext *daemon.Context
+
+// startBackgroundMode starts a copy of rclone in the background.
+//
+// It returns the background process in the parent and nil in the
+// background process.
+func startBackgroundMode() *os.Process {
+	// Send the stdout and stderr of the daemon to the --log-file so
+	// nothing written before the logging starts is lost
+	daemonContext = &daemon.Context{
+		LogFileName: rclog.LogFile(),
+		LogFilePerm: 0640,
+		PidFileName: DaemonPidFile,
+		PidFilePerm: 0644,
+	}
+	child, err := daemonContext.Reborn()
 	if err != nil {
 		log.Fatalln(err)
 	}
+	return child
+}
 
-	if d != nil {
-		return true
+// stopBackgroundMode removes the PID file of the background process
+// if there is one
+func stopBackgroundMode() {
+	if err := daemonContext.Release(); err != nil {
+		log.Printf("error encountered while killing daemon: %v", err)
 	}
+}
 
-	defer func() {
-		if err := cntxt.Release(); err != nil {
-			log.Printf("error encountered while killing daemon: %v", err)
-		}
-	}()
+// mountpointDevice returns the device of the file system mountpoint
+// is on
+func mountpointDevice(mountpoint string) (uint64, error) {
+	fi, err := os.Stat(mountpoint)
+	if err != nil {
+		return 0, err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, errors.New("can't read device of mountpoint")
+	}
+	return uint64(st.Dev), nil
+}
 
-	return false
+// waitBackgroundMode waits for the background process child to mount
+// mountpoint, which is on device startDevice before it is mounted.
+//
+// It returns an error if child exits first or the mount doesn't
+// appear within timeout, in which case child is killed.
+func waitBackgroundMode(child *os.Process, mountpoint string, startDevice uint64, timeout time.Duration) error {
+	exited := make(chan *os.ProcessState, 1)
+	go func() {
+		state, _ := child.Wait()
+		exited <- state
+	}()
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case state := <-exited:
+			return errors.Errorf("daemon exited before mounting %q: %v", mountpoint, state)
+		case <-deadline:
+			_ = child.Kill()
+			return errors.Errorf("daemon didn't mount %q within %v", mountpoint, timeout)
+		case <-ticker.C:
+			device, err := mountpointDevice(mountpoint)
+			if err != nil {
+				fs.Debugf(nil, "Waiting for mount: %v", err)
+				continue
+			}
+			if device != startDevice {
+				return nil
+			}
+		}
+	}
 }
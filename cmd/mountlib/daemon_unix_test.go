@@ -0,0 +1,101 @@
+// +build !windows
+// +build !darwin cgo
+
+package mountlib
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sevlyar/go-daemon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// environment variable used to pass the PID file to the test daemon
+const testPidFileEnv = "RCLONE_TEST_DAEMON_PIDFILE"
+
+func TestMain(m *testing.M) {
+	if daemon.WasReborn() {
+		os.Exit(runTestDaemon())
+	}
+	os.Exit(m.Run())
+}
+
+// runTestDaemon is run in the background process started by
+// TestDaemonPidFile.  It checks the PID file is written and removed
+// and returns the exit code.
+func runTestDaemon() int {
+	DaemonPidFile = os.Getenv(testPidFileEnv)
+	if startBackgroundMode() != nil {
+		return 2
+	}
+	pid, err := daemon.ReadPidFile(DaemonPidFile)
+	if err != nil || pid != os.Getpid() {
+		return 3
+	}
+	stopBackgroundMode()
+	if _, err := os.Stat(DaemonPidFile); !os.IsNotExist(err) {
+		return 4
+	}
+	return 0
+}
+
+func TestDaemonPidFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-daemon-test")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	oldPidFile := DaemonPidFile
+	defer func() { DaemonPidFile = oldPidFile }()
+	DaemonPidFile = filepath.Join(dir, "rclone.pid")
+	require.NoError(t, os.Setenv(testPidFileEnv, DaemonPidFile))
+	defer func() { _ = os.Unsetenv(testPidFileEnv) }()
+
+	child := startBackgroundMode()
+	require.NotNil(t, child)
+	state, err := child.Wait()
+	require.NoError(t, err)
+	assert.True(t, state.Success(), state.String())
+	_, err = os.Stat(DaemonPidFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+// startChild starts a process to stand in for the daemon
+func startChild(t *testing.T, args ...string) *os.Process {
+	cmd := exec.Command(args[0], args[1:]...)
+	require.NoError(t, cmd.Start())
+	return cmd.Process
+}
+
+func TestDaemonWait(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-daemon-test")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	device, err := mountpointDevice(dir)
+	require.NoError(t, err)
+
+	// mounted - the device of the mountpoint changes
+	child := startChild(t, "sleep", "10")
+	err = waitBackgroundMode(child, dir, device+1, 10*time.Second)
+	assert.NoError(t, err)
+	_ = child.Kill()
+
+	// the daemon exits without mounting
+	child = startChild(t, "true")
+	err = waitBackgroundMode(child, dir, device, 10*time.Second)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exited before mounting")
+
+	// the daemon doesn't mount in time
+	child = startChild(t, "sleep", "10")
+	start := time.Now()
+	err = waitBackgroundMode(child, dir, device, 300*time.Millisecond)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "didn't mount")
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
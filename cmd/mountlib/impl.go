@@ -0,0 +1,131 @@
+// Choose between the FUSE implementations compiled in
+
+package mountlib
+
+import (
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// MountImpl is the FUSE implementation chosen with --mount-impl
+var MountImpl = "auto"
+
+// MountFn mounts the remote f at mountpoint and serves it until it
+// is unmounted.
+type MountFn func(f fs.Fs, mountpoint string) error
+
+// implementation is a FUSE library which can mount remotes
+type implementation struct {
+	name  string
+	mount MountFn
+}
+
+// implementations which are compiled in in the order they were added
+var implementations []implementation
+
+// initError is an error from an implementation which happened before
+// the mount was ready.
+type initError struct {
+	error
+}
+
+// Cause returns the underlying error
+func (e initError) Cause() error {
+	return e.error
+}
+
+// InitError marks err as having happened while the FUSE
+// implementation was starting, before anything was mounted, so
+// another implementation can be tried in its place.
+func InitError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return initError{err}
+}
+
+// isInitError returns true if err was marked with InitError
+func isInitError(err error) bool {
+	_, ok := err.(initError)
+	return ok
+}
+
+// implementationNames returns the names of the implementations
+func implementationNames() string {
+	var names []string
+	for _, impl := range implementations {
+		names = append(names, impl.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// chooseImplementations returns the implementations to try in order
+// for a mount command whose own implementation is defaultName.
+//
+// With "auto" this is defaultName then the others, otherwise just the
+// one asked for.
+func chooseImplementations(impl, defaultName string) ([]implementation, error) {
+	var chosen []implementation
+	if impl == "" || impl == "auto" {
+		for _, candidate := range implementations {
+			if candidate.name == defaultName {
+				chosen = append([]implementation{candidate}, chosen...)
+			} else {
+				chosen = append(chosen, candidate)
+			}
+		}
+		return chosen, nil
+	}
+	for _, candidate := range implementations {
+		if candidate.name == impl {
+			return append(chosen, candidate), nil
+		}
+	}
+	return nil, errors.Errorf("mount implementation %q isn't available - this rclone has: auto, %s", impl, implementationNames())
+}
+
+// mountWith mounts f at mountpoint with the implementation chosen by
+// --mount-impl.
+//
+// If an implementation fails to start then the next one is tried.
+func mountWith(defaultName string, f fs.Fs, mountpoint string) error {
+	chosen, err := chooseImplementations(MountImpl, defaultName)
+	if err != nil {
+		return err
+	}
+	var failures []string
+	for i, impl := range chosen {
+		fs.Debugf(nil, "Mounting with %s", impl.name)
+		err = impl.mount(f, mountpoint)
+		if !isInitError(err) {
+			return err
+		}
+		failures = append(failures, impl.name+": "+err.Error())
+		if i < len(chosen)-1 {
+			fs.Errorf(nil, "Failed to start %s - trying %s: %v", impl.name, chosen[i+1].name, err)
+		}
+	}
+	if len(failures) == 1 {
+		return err
+	}
+	return errors.Errorf("no mount implementation could start: %s", strings.Join(failures, "; "))
+}
+
+// NewFuseMountCommand makes a mount command with the given name which
+// mounts with the FUSE implementation implName by default.
+//
+// Mount should wrap errors from before the file system was mounted
+// with InitError so that another implementation can be tried if
+// implName fails to start.
+func NewFuseMountCommand(commandName, implName string, Mount MountFn) *cobra.Command {
+	implementations = append(implementations, implementation{name: implName, mount: Mount})
+	command := NewMountCommand(commandName, func(f fs.Fs, mountpoint string) error {
+		return mountWith(implName, f, mountpoint)
+	})
+	flags.StringVarP(command.Flags(), &MountImpl, "mount-impl", "", MountImpl, "FUSE implementation to use: auto, bazil or cgofuse.")
+	return command
+}
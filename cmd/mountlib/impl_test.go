@@ -0,0 +1,93 @@
+package mountlib
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeImplementations replaces the implementations with ones which
+// record their use in calls and return the errors in results,
+// returning a function to restore them.
+func fakeImplementations(calls *[]string, results map[string]error) func() {
+	oldImplementations, oldMountImpl := implementations, MountImpl
+	implementations = nil
+	for _, name := range []string{"cgofuse", "bazil"} {
+		name := name
+		implementations = append(implementations, implementation{
+			name: name,
+			mount: func(f fs.Fs, mountpoint string) error {
+				*calls = append(*calls, name)
+				return results[name]
+			},
+		})
+	}
+	return func() {
+		implementations, MountImpl = oldImplementations, oldMountImpl
+	}
+}
+
+func TestChooseImplementations(t *testing.T) {
+	var calls []string
+	defer fakeImplementations(&calls, nil)()
+
+	names := func(impls []implementation) (names []string) {
+		for _, impl := range impls {
+			names = append(names, impl.name)
+		}
+		return names
+	}
+	chosen, err := chooseImplementations("auto", "bazil")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bazil", "cgofuse"}, names(chosen))
+	chosen, err = chooseImplementations("", "cgofuse")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cgofuse", "bazil"}, names(chosen))
+	chosen, err = chooseImplementations("cgofuse", "bazil")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cgofuse"}, names(chosen))
+	_, err = chooseImplementations("potato", "bazil")
+	assert.EqualError(t, err, `mount implementation "potato" isn't available - this rclone has: auto, cgofuse, bazil`)
+}
+
+func TestMountWith(t *testing.T) {
+	errStart := errors.New("no fuse")
+	errServe := errors.New("serve failed")
+	for _, test := range []struct {
+		impl    string
+		results map[string]error
+		calls   []string
+		err     string
+	}{
+		{"auto", nil, []string{"bazil"}, ""},
+		{"auto", map[string]error{"bazil": errServe}, []string{"bazil"}, "serve failed"},
+		{"auto", map[string]error{"bazil": InitError(errStart)}, []string{"bazil", "cgofuse"}, ""},
+		{"auto", map[string]error{"bazil": InitError(errStart), "cgofuse": InitError(errStart)}, []string{"bazil", "cgofuse"}, "no mount implementation could start: bazil: no fuse; cgofuse: no fuse"},
+		{"bazil", map[string]error{"bazil": InitError(errStart)}, []string{"bazil"}, "no fuse"},
+		{"cgofuse", nil, []string{"cgofuse"}, ""},
+		{"potato", nil, nil, `mount implementation "potato" isn't available - this rclone has: auto, cgofuse, bazil`},
+	} {
+		var calls []string
+		restore := fakeImplementations(&calls, test.results)
+		MountImpl = test.impl
+		err := mountWith("bazil", nil, "/mnt/test")
+		restore()
+		assert.Equal(t, test.calls, calls, test.impl)
+		if test.err == "" {
+			assert.NoError(t, err, test.impl)
+		} else {
+			assert.EqualError(t, err, test.err, test.impl)
+		}
+	}
+}
+
+func TestInitError(t *testing.T) {
+	assert.Nil(t, InitError(nil))
+	err := errors.New("potato")
+	assert.True(t, isInitError(InitError(err)))
+	assert.False(t, isInitError(err))
+	assert.Equal(t, "potato", InitError(err).Error())
+}
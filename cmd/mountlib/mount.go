@@ -5,11 +5,14 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/config/flags"
+	rclog "github.com/ncw/rclone/fs/log"
+	"github.com/ncw/rclone/fs/notify"
 	"github.com/ncw/rclone/vfs"
 	"github.com/ncw/rclone/vfs/vfsflags"
 	"github.com/pkg/errors"
@@ -24,13 +27,105 @@ var (
 	AllowOther                       = false
 	DefaultPermissions               = false
 	WritebackCache                   = false
+	AppleXattr                       = false
+	VolumeName                       = ""
+	NetworkMode                      = false // mount as a network drive on Windows
 	Daemon                           = false
+	DaemonWait                       = 0 * time.Second // how long to wait for the daemon to mount
+	DaemonPidFile                    = ""              // file to write the PID of the daemon to
+	AutoRemount                      = false
+	LinkCopy                         = false // emulate hard links with server side copies
 	MaxReadAhead       fs.SizeSuffix = 128 * 1024
 	ExtraOptions       []string
 	ExtraFlags         []string
 	AttrTimeout        = 0 * time.Second // how long the kernel caches attribute for
+	EntryTimeout       = 0 * time.Second // how long the kernel caches directory entries for
+	NegativeTimeout    = 0 * time.Second // how long the kernel caches failed lookups for
+	CreatedTime        = ""              // comma separated list of stat times to set to the creation time
+	createdCtime       = false           // set if the ctime should be the creation time
+	createdBtime       = false           // set if the btime should be the creation time
 )
 
+// parseCreatedTime parses the value of --created-time
+func parseCreatedTime(value string) (ctime, btime bool, err error) {
+	for _, field := range strings.Split(value, ",") {
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "":
+		case "ctime":
+			ctime = true
+		case "btime":
+			btime = true
+		default:
+			return false, false, errors.Errorf("unknown stat time %q in --created-time - use ctime or btime", field)
+		}
+	}
+	return ctime, btime, nil
+}
+
+// StatTimes returns the times to report as the ctime and the btime
+// (birth time) of node which has the modification time modTime.
+//
+// These are modTime unless --created-time asks for the creation time
+// and the backend reports it.
+func StatTimes(node vfs.Node, modTime time.Time) (ctime, btime time.Time) {
+	ctime, btime = modTime, modTime
+	if !createdCtime && !createdBtime {
+		return ctime, btime
+	}
+	file, ok := node.(*vfs.File)
+	if !ok {
+		return ctime, btime
+	}
+	createdTime := file.CreatedTime()
+	if createdTime.IsZero() {
+		return ctime, btime
+	}
+	if createdCtime {
+		ctime = createdTime
+	}
+	if createdBtime {
+		btime = createdTime
+	}
+	return ctime, btime
+}
+
+// MakeVolumeName returns the volume name for a mount of the remote
+// device, which is --volname if set.
+//
+// On Windows the characters which can't be used in a volume label or
+// a network share name are replaced.
+func MakeVolumeName(device string) string {
+	name := VolumeName
+	if name == "" {
+		name = device
+	}
+	if runtime.GOOS == "windows" {
+		name = windowsVolumeName(name)
+	}
+	return name
+}
+
+// windowsVolumeName makes name usable as a volume label or a network
+// share name on Windows, replacing the characters which can't be used
+// with spaces and shortening it to the 32 characters a volume label
+// can have.
+func windowsVolumeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`\/:*?"<>|`, r) {
+			return ' '
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if runes := []rune(name); len(runes) > 32 {
+		name = strings.TrimSpace(string(runes[:32]))
+	}
+	if name == "" {
+		name = "rclone"
+	}
+	return name
+}
+
 // Check is folder is empty
 func checkMountEmpty(mountpoint string) error {
 	fp, fpErr := os.Open(mountpoint)
@@ -118,6 +213,61 @@ infrastructure](https://github.com/billziss-gh/winfsp/wiki/WinFsp-Service-Archit
 which creates drives accessible for everyone on the system or
 alternatively using [the nssm service manager](https://nssm.cc/usage).
 
+#### Network mode and volume names
+
+By default the mount appears as a fixed disk drive.  Use
+` + "`--network-mode`" + ` to make it appear as a network drive instead,
+shared as ` + "`\\\\rclone\\volume name`" + `.  Explorer then shows it
+with the network drives, which stops it misreporting the free space
+on the drive.
+
+The volume name is the name of the remote, eg ` + "`remote path`" + `
+for ` + "`remote:path`" + `.  Use ` + "`--volname`" + ` to set it, eg to
+tell several mounts apart.  On Windows any characters which can't be
+used in volume labels are replaced by spaces and the name is
+shortened to 32 characters.  ` + "`--volname`" + ` sets the name of the
+volume on macOS too.
+
+### Mounting on macOS without FUSE
+
+Mounting with FUSE on macOS needs the macFUSE kernel extension.  If
+that can't be installed, for example because the machine is managed
+with a policy forbidding kernel extensions, use ` + "`rclone nfsmount`" + `
+instead.  This takes the same arguments but serves the remote over NFS
+on localhost and mounts it with the NFS client built into macOS.
+
+### FUSE implementations
+
+rclone can mount with two FUSE libraries: bazil, which talks to the
+kernel directly on Linux, FreeBSD and macOS, and cgofuse, which uses
+libfuse or WinFsp and is only in builds made with the ` + "`cmount`" + `
+tag.  ` + "`rclone mount`" + ` uses bazil and ` + "`rclone cmount`" + ` uses
+cgofuse.
+
+By default (` + "`--mount-impl auto`" + `) if the library for the command
+fails to start, eg because libfuse isn't installed, the error is
+logged and the other library is tried if it is compiled in.  Use
+` + "`--mount-impl bazil`" + ` or ` + "`--mount-impl cgofuse`" + ` to use only
+that library.
+
+### Inode numbers
+
+The inode number of each file and directory is made from a hash of
+its path, so it is the same each time the remote is mounted.  This
+helps programs which use inode numbers to recognise files, eg backup
+programs looking for hard links.  A file which is renamed gets the
+inode number of its new path.
+
+### Hard links
+
+Remotes don't support hard links so making one on the mount fails
+with "Function not implemented".  Some programs, eg some download
+managers, insist on making hard links.  For these use ` + "`--link-copy`" + `
+which makes a server side copy of the file instead.  The copy is a
+separate file, so changes to one aren't seen in the other, and it has
+its own inode number.  This only works if the remote can do server
+side copies.
+
 ### Limitations
 
 Without the use of "--vfs-cache-mode" this can only write files
@@ -136,6 +286,13 @@ the directory cache.
 
 Only supported on Linux, FreeBSD, OS X and Windows at the moment.
 
+### Disk usage
+
+Tools like ` + "`df`" + ` show the quota of the remote as the size and free
+space of the mount if the remote can report it (eg Google Drive,
+Dropbox and OneDrive).  This is read at most once every
+` + "`--dir-cache-time`" + `.  Other remotes show a very large fake size.
+
 ### rclone ` + commandName + ` vs rclone sync/copy
 
 File systems expect things to be 100% reliable, whereas cloud storage
@@ -159,6 +316,71 @@ strange effects when files change on the remote.
 
 This is the same as setting the attr_timeout option in mount.fuse.
 
+The kernel also caches the results of looking up names in directories
+and these are controlled separately.  Use --entry-timeout to set the
+time the kernel remembers that a name exists in a directory, which is
+the same as the entry_timeout option in mount.fuse, and
+--negative-timeout to set the time it remembers that a name doesn't
+exist, which is the same as the negative_timeout option.  Both default
+to 0s - no caching.  A longer --negative-timeout saves calls to rclone
+when programs look for files which aren't there, but files created on
+the remote won't be seen until it expires.  --negative-timeout is
+only supported by cmount and neither flag has any effect on Windows.
+
+These timeouts are separate from the directory cache kept by rclone
+itself which is controlled by --dir-cache-time and
+--dir-cache-negative-time - see the Directory Cache section below.
+Changes on the remote can only be seen once both have expired.
+
+### Large directories
+
+When a directory isn't in the directory cache rclone ` + commandName + `
+passes its entries to the kernel as they are listed from the remote
+where the remote supports it (currently only local disks), so ` + "`ls`" + `
+on a directory with hundreds of thousands of entries starts producing
+output straight away.  The entries come out in pages which are each
+sorted rather than in one sorted list.  This isn't supported by
+cmount yet.
+
+### Creation times
+
+By default the change time (ctime) and the creation or birth time
+(btime) of files in the mount are the same as their modification
+time.  Some programs, eg media scanners, sort by these times, so use
+` + "`--created-time`" + ` to set them to the time the file was created
+where the remote reports it.  Use ` + "`--created-time btime`" + ` for
+the birth time, ` + "`--created-time ctime`" + ` for the change time or
+` + "`--created-time ctime,btime`" + ` for both.
+
+The local filesystem reports creation times on macOS, FreeBSD, NetBSD
+and Windows, as do Google Drive and OneDrive.  Files on other remotes
+keep the modification time.  Note that Linux doesn't show the birth
+time of files in FUSE file systems.
+
+### Extended attributes
+
+On remotes which store user metadata with files (S3 and Swift) the
+metadata of files can be read and changed as extended attributes,
+eg with ` + "`getfattr`" + `, ` + "`setfattr`" + ` or ` + "`rsync -X`" + `.
+
+On Linux only extended attributes in the ` + "`user.`" + ` namespace are
+supported, and the namespace is left out of the metadata key, so the
+S3 metadata ` + "`X-Amz-Meta-Colour`" + ` is the extended attribute
+` + "`user.colour`" + `.  Metadata keys are case insensitive so upper case
+letters in names, and any characters which can't be used in HTTP
+headers, are stored as ` + "`%xx`" + `.  Values which aren't printable
+ASCII are stored base64 encoded with a ` + "`base64:`" + ` prefix.
+
+Changing the extended attributes of a file copies the object to itself
+on S3 so it can't be done for objects bigger than 5GB.  The extended
+attributes are lost if the file is overwritten.  Directories don't
+have extended attributes.
+
+On macOS extended attributes starting with ` + "`com.apple.`" + ` are
+turned off by default to stop the Finder storing its own information
+in the metadata of every file.  Use ` + "`--apple-xattr`" + ` to allow
+them, eg to use Finder tags.
+
 ### Filters
 
 Note that all the rclone filters can be used to select a subset of the
@@ -171,9 +393,59 @@ to use Type=notify. In this case the service will enter the started state
 after the mountpoint has been successfully set up.
 Units having the rclone ` + commandName + ` service specified as a requirement
 will see all files and folders immediately in this mode.
+
+### Auto remount
+
+If the mount is lost while rclone is still running, eg because the
+FUSE connection was aborted, the mountpoint is left in a broken
+"Transport endpoint is not connected" state until it is unmounted by
+hand.  With ` + "`--auto-remount`" + ` rclone notices this, cleans up the
+broken mountpoint and mounts it again.  If that fails it retries,
+waiting 1s between the first tries and doubling the wait each time up
+to a maximum of 1m.  The remount uses the same flags and keeps the
+directory cache and the ` + "`--vfs-cache-mode`" + ` cache of the original
+mount.
+
+Unmounting the mountpoint with ` + "`fusermount -u`" + ` or ` + "`umount`" + ` still
+makes rclone exit.
+
+### Running in the background
+
+With ` + "`--daemon`" + ` the mount command returns as soon as the mount
+has been started in the background, so it exits successfully even if
+the mount then fails.  Use ` + "`--daemon-wait 1m`" + ` to make it wait up
+to a minute until the mountpoint is actually mounted.  It then exits
+with a non-zero exit code if the mount failed, or if it didn't appear
+in time in which case the background process is killed.
+
+Use ` + "`--daemon-pidfile /path/to/file`" + ` to write the process ID of
+the background process to a file.  The file is locked while the mount
+is running so a second mount can't use the same file, and it is
+removed when the mount exits.
+
+To unmount a mount started with ` + "`--rc`" + ` without losing files
+which are still being written use ` + "`rclone unmount /path/to/mountpoint`" + `.
+
+### Logging
+
+When run with ` + "`--daemon`" + ` the mount runs in the background without
+a terminal so its output is lost unless you use ` + "`--log-file`" + ` or
+` + "`--syslog`" + `.  These and ` + "`--log-level`" + ` apply to each
+mount command separately so each mount can log to its own file.
+
+To rotate the log file with an external program like logrotate, move
+the file then either send the mount SIGHUP, which clears the directory
+cache too, or run ` + "`rclone rc core/reopen-log`" + ` if the mount was
+started with ` + "`--rc`" + `.  The log level of a running mount can be
+changed with ` + "`rclone rc core/log-level level=DEBUG`" + `.
 ` + vfs.Help,
 		Run: func(command *cobra.Command, args []string) {
 			cmd.CheckArgs(2, 2, command, args)
+			var err error
+			createdCtime, createdBtime, err = parseCreatedTime(CreatedTime)
+			if err != nil {
+				log.Fatalf("Fatal error: %v", err)
+			}
 			fdst := cmd.NewFsDst(args)
 
 			// Show stats if the user has specifically requested them
@@ -193,13 +465,39 @@ will see all files and folders immediately in this mode.
 
 			// Start background task if --background is specified
 			if Daemon {
-				daemonized := startBackgroundMode()
-				if daemonized {
+				if !rclog.Redirected() {
+					fs.Logf(nil, "Logs from the daemon will be lost - use --log-file or --syslog to keep them")
+				}
+				// Read the device of the mountpoint before mounting so
+				// the mount can be detected by it changing
+				wait := DaemonWait > 0
+				var startDevice uint64
+				if wait {
+					startDevice, err = mountpointDevice(args[1])
+					if err != nil {
+						fs.Errorf(nil, "Not waiting for the daemon to mount: %v", err)
+						wait = false
+					}
+				}
+				child := startBackgroundMode()
+				if child != nil {
+					if !wait {
+						return
+					}
+					err = waitBackgroundMode(child, args[1], startDevice, DaemonWait)
+					if err != nil {
+						log.Fatalf("Fatal error: %v", err)
+					}
 					return
 				}
 			}
 
-			err := Mount(fdst, args[1])
+			start := time.Now()
+			err = Mount(fdst, args[1])
+			notify.Send(notify.NewSummary(commandName, args, start, err))
+			if Daemon {
+				stopBackgroundMode()
+			}
 			if err != nil {
 				log.Fatalf("Fatal error: %v", err)
 			}
@@ -218,11 +516,21 @@ will see all files and folders immediately in this mode.
 	flags.BoolVarP(flagSet, &AllowOther, "allow-other", "", AllowOther, "Allow access to other users.")
 	flags.BoolVarP(flagSet, &DefaultPermissions, "default-permissions", "", DefaultPermissions, "Makes kernel enforce access control based on the file mode.")
 	flags.BoolVarP(flagSet, &WritebackCache, "write-back-cache", "", WritebackCache, "Makes kernel buffer writes before sending them to rclone. Without this, writethrough caching is used.")
+	flags.BoolVarP(flagSet, &AppleXattr, "apple-xattr", "", AppleXattr, "Allow extended attributes starting with com.apple., eg Finder tags. (macOS only)")
+	flags.StringVarP(flagSet, &VolumeName, "volname", "", VolumeName, "Set the volume name (not supported by all OSes).")
+	flags.BoolVarP(flagSet, &NetworkMode, "network-mode", "", NetworkMode, "Mount as a network drive, instead of a fixed disk drive. (Windows only)")
 	flags.FVarP(flagSet, &MaxReadAhead, "max-read-ahead", "", "The number of bytes that can be prefetched for sequential reads.")
 	flags.DurationVarP(flagSet, &AttrTimeout, "attr-timeout", "", AttrTimeout, "Time for which file/directory attributes are cached.")
+	flags.DurationVarP(flagSet, &EntryTimeout, "entry-timeout", "", EntryTimeout, "Time for which the kernel caches directory entries.")
+	flags.DurationVarP(flagSet, &NegativeTimeout, "negative-timeout", "", NegativeTimeout, "Time for which the kernel caches names which don't exist. (cmount only)")
+	flags.StringVarP(flagSet, &CreatedTime, "created-time", "", CreatedTime, "Stat times to set to the file creation time if known: ctime, btime or ctime,btime.")
 	flags.StringArrayVarP(flagSet, &ExtraOptions, "option", "o", []string{}, "Option for libfuse/WinFsp. Repeat if required.")
 	flags.StringArrayVarP(flagSet, &ExtraFlags, "fuse-flag", "", []string{}, "Flags or arguments to be passed direct to libfuse/WinFsp. Repeat if required.")
 	flags.BoolVarP(flagSet, &Daemon, "daemon", "", Daemon, "Run mount as a daemon (background mode).")
+	flags.DurationVarP(flagSet, &DaemonWait, "daemon-wait", "", DaemonWait, "Time to wait for the daemon to mount before exiting with an error. 0 to not wait.")
+	flags.StringVarP(flagSet, &DaemonPidFile, "daemon-pidfile", "", DaemonPidFile, "File to write the PID of the daemon to.")
+	flags.BoolVarP(flagSet, &AutoRemount, "auto-remount", "", AutoRemount, "Remount automatically if the mount is lost.")
+	flags.BoolVarP(flagSet, &LinkCopy, "link-copy", "", LinkCopy, "Emulate hard links by making a server side copy of the file.")
 
 	// Add in the generic flags
 	vfsflags.AddFlags(flagSet)
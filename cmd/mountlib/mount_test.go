@@ -0,0 +1,65 @@
+package mountlib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCreatedTime(t *testing.T) {
+	for _, test := range []struct {
+		in     string
+		ctime  bool
+		btime  bool
+		hasErr bool
+	}{
+		{"", false, false, false},
+		{"ctime", true, false, false},
+		{"BTime", false, true, false},
+		{"ctime, btime", true, true, false},
+		{"atime", false, false, true},
+	} {
+		ctime, btime, err := parseCreatedTime(test.in)
+		assert.Equal(t, test.hasErr, err != nil, test.in)
+		assert.Equal(t, test.ctime, ctime, test.in)
+		assert.Equal(t, test.btime, btime, test.in)
+	}
+}
+
+func TestStatTimesNotFile(t *testing.T) {
+	oldCtime, oldBtime := createdCtime, createdBtime
+	defer func() { createdCtime, createdBtime = oldCtime, oldBtime }()
+	createdCtime, createdBtime = true, true
+
+	modTime := time.Date(2018, 7, 1, 12, 0, 0, 0, time.UTC)
+	ctime, btime := StatTimes(nil, modTime)
+	assert.Equal(t, modTime, ctime)
+	assert.Equal(t, modTime, btime)
+}
+
+func TestWindowsVolumeName(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want string
+	}{
+		{"remote:", "remote"},
+		{"remote:path/to/dir", "remote path to dir"},
+		{`a\b*c?d"e<f>g|h`, "a b c d e f g h"},
+		{"a very long remote name:with a long path", "a very long remote name with a l"},
+		{"é:", "é"},
+		{":", "rclone"},
+	} {
+		assert.Equal(t, test.want, windowsVolumeName(test.in), test.in)
+	}
+}
+
+func TestMakeVolumeName(t *testing.T) {
+	oldVolumeName := VolumeName
+	defer func() { VolumeName = oldVolumeName }()
+
+	VolumeName = ""
+	assert.Equal(t, "remote", MakeVolumeName("remote"))
+	VolumeName = "backups"
+	assert.Equal(t, "backups", MakeVolumeName("remote"))
+}
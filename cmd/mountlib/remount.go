@@ -0,0 +1,68 @@
+// Remount the file system when the mount session is lost
+
+package mountlib
+
+import (
+	"os"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// Backoff used between remount attempts
+const (
+	remountMinSleep = time.Second
+	remountMaxSleep = time.Minute
+)
+
+// These are variables so they can be replaced in the tests
+var (
+	remountAfter   = time.After
+	cleanupBroken  = unmountBroken
+	isDisconnected = mountpointDisconnected
+)
+
+// ErrRemountStopped is returned by Remount if it was stopped before
+// the file system could be mounted again
+var ErrRemountStopped = errors.New("remount stopped")
+
+// SessionLost returns true if the mount session at mountpoint ended
+// unexpectedly, leaving the mountpoint disconnected ("transport
+// endpoint is not connected"), rather than being unmounted.
+func SessionLost(mountpoint string) bool {
+	return isDisconnected(mountpoint)
+}
+
+// Remount mounts the file system at mountpoint again with mountFn
+// after the session was lost, retrying with exponential backoff
+// until it succeeds or a signal is received on stop.
+//
+// mountFn should reuse the VFS from the original mount so the
+// directory and file caches are kept.  It returns the error channel
+// of the new mount.
+//
+// If stopped it returns ErrRemountStopped.
+func Remount(mountpoint string, stop <-chan os.Signal, mountFn func() (<-chan error, error)) (<-chan error, error) {
+	sleepTime := remountMinSleep
+	for try := 1; ; try++ {
+		fs.Logf(nil, "Mount at %q lost - remounting (try %d)", mountpoint, try)
+		cleanupBroken(mountpoint)
+		errChan, err := mountFn()
+		if err == nil {
+			fs.Logf(nil, "Remounted %q", mountpoint)
+			return errChan, nil
+		}
+		fs.Errorf(nil, "Failed to remount %q - retrying in %v: %v", mountpoint, sleepTime, err)
+		select {
+		case <-stop:
+			fs.Logf(nil, "Stopped remounting %q", mountpoint)
+			return nil, ErrRemountStopped
+		case <-remountAfter(sleepTime):
+		}
+		sleepTime *= 2
+		if sleepTime > remountMaxSleep {
+			sleepTime = remountMaxSleep
+		}
+	}
+}
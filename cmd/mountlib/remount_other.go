@@ -0,0 +1,15 @@
+// Detect and clean up lost mounts - for oses which don't have
+// disconnected mountpoints
+
+// +build windows plan9
+
+package mountlib
+
+// mountpointDisconnected returns false as this OS doesn't leave
+// disconnected mountpoints
+func mountpointDisconnected(mountpoint string) bool {
+	return false
+}
+
+// unmountBroken does nothing on this OS
+func unmountBroken(mountpoint string) {}
@@ -0,0 +1,87 @@
+package mountlib
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionLost(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-remount-test")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	// a clean unmount leaves a working directory
+	assert.False(t, SessionLost(dir))
+
+	oldIsDisconnected := isDisconnected
+	defer func() { isDisconnected = oldIsDisconnected }()
+	isDisconnected = func(mountpoint string) bool { return mountpoint == dir }
+	assert.True(t, SessionLost(dir))
+}
+
+func TestRemount(t *testing.T) {
+	oldAfter, oldCleanup := remountAfter, cleanupBroken
+	defer func() { remountAfter, cleanupBroken = oldAfter, oldCleanup }()
+	var sleeps []time.Duration
+	remountAfter = func(d time.Duration) <-chan time.Time {
+		sleeps = append(sleeps, d)
+		c := make(chan time.Time, 1)
+		c <- time.Now()
+		return c
+	}
+	cleanups := 0
+	cleanupBroken = func(mountpoint string) {
+		assert.Equal(t, "/mnt/point", mountpoint)
+		cleanups++
+	}
+
+	wantErrChan := make(chan error)
+	tries := 0
+	errChan, err := Remount("/mnt/point", nil, func() (<-chan error, error) {
+		tries++
+		if tries < 9 {
+			return nil, errors.New("not yet")
+		}
+		return wantErrChan, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, (<-chan error)(wantErrChan), errChan)
+	assert.Equal(t, 9, tries)
+	assert.Equal(t, 9, cleanups)
+	assert.Equal(t, []time.Duration{
+		1 * time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		16 * time.Second,
+		32 * time.Second,
+		time.Minute,
+		time.Minute,
+	}, sleeps)
+}
+
+func TestRemountStop(t *testing.T) {
+	oldAfter, oldCleanup := remountAfter, cleanupBroken
+	defer func() { remountAfter, cleanupBroken = oldAfter, oldCleanup }()
+	cleanupBroken = func(mountpoint string) {}
+	// never finish sleeping so only the signal can end the wait
+	remountAfter = func(d time.Duration) <-chan time.Time { return nil }
+
+	stop := make(chan os.Signal, 1)
+	stop <- syscall.SIGINT
+	tries := 0
+	errChan, err := Remount("/mnt/point", stop, func() (<-chan error, error) {
+		tries++
+		return nil, errors.New("not yet")
+	})
+	assert.Equal(t, ErrRemountStopped, err)
+	assert.Nil(t, errChan)
+	assert.Equal(t, 1, tries)
+}
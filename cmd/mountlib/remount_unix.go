@@ -0,0 +1,42 @@
+// Detect and clean up lost mounts under unix
+
+// +build !windows,!plan9
+
+package mountlib
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+
+	"github.com/ncw/rclone/fs"
+)
+
+// mountpointDisconnected returns true if the mountpoint is a mount
+// whose FUSE session has gone away
+func mountpointDisconnected(mountpoint string) bool {
+	_, err := os.Stat(mountpoint)
+	if pathErr, ok := err.(*os.PathError); ok {
+		return pathErr.Err == syscall.ENOTCONN
+	}
+	return false
+}
+
+// unmountBroken lazily unmounts the disconnected mount at mountpoint
+// so it can be mounted again.  Errors are logged as the mountpoint
+// may already have been cleaned up.
+func unmountBroken(mountpoint string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux":
+		cmd = exec.Command("fusermount", "-uz", mountpoint)
+	case "darwin", "freebsd":
+		cmd = exec.Command("umount", "-f", mountpoint)
+	default:
+		return
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		fs.Debugf(nil, "Unmounting broken mount %q: %v: %s", mountpoint, err, out)
+	}
+}
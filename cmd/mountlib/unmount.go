@@ -0,0 +1,141 @@
+// Unmount running mounts with the remote control
+
+package mountlib
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/ncw/rclone/vfs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// DefaultUnmountTimeout is how long to wait for files being written
+// to be closed before unmounting if no timeout is given
+const DefaultUnmountTimeout = time.Minute
+
+// UnmountRequest asks a mount to unmount.  The mount sends the result
+// of unmounting on Reply.
+type UnmountRequest struct {
+	Reply chan<- error
+}
+
+// liveMount is a running mount which can be unmounted with the
+// remote control
+type liveMount struct {
+	mountpoint string
+	VFS        *vfs.VFS
+	requests   chan UnmountRequest
+	done       chan struct{} // closed when the mount has finished
+}
+
+var (
+	liveMountsMu sync.Mutex
+	liveMounts   = map[string]*liveMount{}
+)
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "mount/unmount",
+		Fn:    rcUnmount,
+		Title: "Unmount a running mount",
+		Help: `
+This flushes the files being written to the mount then unmounts it.
+
+Parameters
+
+- mountPoint - the mountpoint to unmount - optional if there is only one mount
+- timeout - how long to wait for open files to be closed, eg "30s" - default "1m"
+
+This is the call made by "rclone unmount".
+`,
+	})
+}
+
+// cleanMountpoint returns mountpoint as an absolute clean path so it
+// can be used as a key of liveMounts
+func cleanMountpoint(mountpoint string) string {
+	abs, err := filepath.Abs(mountpoint)
+	if err != nil {
+		return filepath.Clean(mountpoint)
+	}
+	return abs
+}
+
+// AddLiveMount registers the mount of VFS at mountpoint so it can be
+// unmounted with the mount/unmount remote control call.
+//
+// The mount should unmount when it receives an UnmountRequest on the
+// channel returned and call the remove function returned when it has
+// finished.
+func AddLiveMount(mountpoint string, VFS *vfs.VFS) (requests <-chan UnmountRequest, remove func()) {
+	key := cleanMountpoint(mountpoint)
+	m := &liveMount{
+		mountpoint: mountpoint,
+		VFS:        VFS,
+		requests:   make(chan UnmountRequest),
+		done:       make(chan struct{}),
+	}
+	liveMountsMu.Lock()
+	liveMounts[key] = m
+	liveMountsMu.Unlock()
+	return m.requests, func() {
+		liveMountsMu.Lock()
+		if liveMounts[key] == m {
+			delete(liveMounts, key)
+		}
+		liveMountsMu.Unlock()
+		close(m.done)
+	}
+}
+
+// findLiveMount returns the mount at mountpoint, or the only mount if
+// mountpoint is ""
+func findLiveMount(mountpoint string) (*liveMount, error) {
+	liveMountsMu.Lock()
+	defer liveMountsMu.Unlock()
+	if mountpoint != "" {
+		m, ok := liveMounts[cleanMountpoint(mountpoint)]
+		if !ok {
+			return nil, errors.Errorf("nothing mounted at %q", mountpoint)
+		}
+		return m, nil
+	}
+	if len(liveMounts) != 1 {
+		return nil, errors.Errorf("mountPoint must be given as there are %d mounts", len(liveMounts))
+	}
+	for _, m := range liveMounts {
+		return m, nil
+	}
+	panic("unreachable")
+}
+
+// rcUnmount waits for the files being written to the mount to be
+// closed then asks it to unmount
+func rcUnmount(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	mountpoint, _ := in.GetString("mountPoint")
+	timeout := DefaultUnmountTimeout
+	if timeoutString, err := in.GetString("timeout"); err == nil {
+		timeout, err = time.ParseDuration(timeoutString)
+		if err != nil {
+			return nil, errors.Wrap(err, "bad timeout")
+		}
+	}
+	m, err := findLiveMount(mountpoint)
+	if err != nil {
+		return nil, err
+	}
+	fs.Logf(nil, "Unmounting %q on request", m.mountpoint)
+	m.VFS.WaitForWriters(timeout)
+	reply := make(chan error, 1)
+	select {
+	case m.requests <- UnmountRequest{Reply: reply}:
+	case <-m.done:
+		return nil, errors.Errorf("%q was unmounted already", m.mountpoint)
+	}
+	return nil, <-reply
+}
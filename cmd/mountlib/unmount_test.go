@@ -0,0 +1,48 @@
+package mountlib
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/ncw/rclone/vfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestRcUnmount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-unmount-test")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+	VFS := vfs.New(f, nil)
+
+	_, err = rcUnmount(context.Background(), rc.Params{})
+	assert.EqualError(t, err, "mountPoint must be given as there are 0 mounts")
+
+	requests, remove := AddLiveMount("/mnt/test", VFS)
+	_, err = rcUnmount(context.Background(), rc.Params{"mountPoint": "/mnt/other"})
+	assert.EqualError(t, err, `nothing mounted at "/mnt/other"`)
+	_, err = rcUnmount(context.Background(), rc.Params{"timeout": "potato"})
+	assert.Error(t, err)
+
+	// the mount unmounts when asked and returns the result
+	errUnmount := errors.New("unmount failed")
+	go func() {
+		request := <-requests
+		request.Reply <- errUnmount
+	}()
+	_, err = rcUnmount(context.Background(), rc.Params{"mountPoint": "/mnt/test/", "timeout": "1s"})
+	assert.Equal(t, errUnmount, err)
+
+	// a mount which has finished isn't waited for
+	remove()
+	_, err = rcUnmount(context.Background(), rc.Params{})
+	assert.EqualError(t, err, "mountPoint must be given as there are 0 mounts")
+}
@@ -0,0 +1,178 @@
+// Package nfsmount mounts rclone remotes with the operating system's
+// NFS client talking to an NFS server run by rclone.
+
+// +build linux darwin freebsd
+
+package nfsmount
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/ncw/rclone/cmd/mountlib"
+	"github.com/ncw/rclone/cmd/serve/nfs"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/vfs"
+	"github.com/ncw/rclone/vfs/vfsflags"
+	"github.com/okzk/sdnotify"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	command := mountlib.NewMountCommand("nfsmount", Mount)
+	command.Short = `Mount the remote as a mountpoint using NFS. **EXPERIMENTAL**`
+	command.Long = `
+rclone nfsmount mounts the remote without needing FUSE.  It runs an NFS
+server on localhost on a random port and mounts it with the operating
+system's NFS client.
+
+This is useful on macOS where installing the macFUSE kernel extension
+isn't possible, for example on machines managed with an MDM policy
+which forbids kernel extensions.  It works on Linux and FreeBSD too
+but mounting NFS file systems needs root there.
+
+The NFS client writes files in pieces which may arrive out of order
+so ` + "`--vfs-cache-mode writes`" + ` or above is strongly recommended.
+Files are uploaded when they are closed.
+
+The FUSE specific flags are ignored.  Options given with ` + "`-o`" + ` are
+passed to the NFS mount command and ` + "`--attr-timeout`" + ` sets how
+long the NFS client caches attributes for.
+
+See ` + "`rclone serve nfs`" + ` to serve NFS to other machines.
+` + command.Long
+}
+
+// mountOptions returns the options for the NFS mount command for a
+// server listening on port
+func mountOptions(port int) []string {
+	options := []string{
+		fmt.Sprintf("port=%d", port),
+		fmt.Sprintf("mountport=%d", port),
+		"vers=3",
+		"tcp",
+		fmt.Sprintf("actimeo=%d", int(mountlib.AttrTimeout.Seconds())),
+	}
+	if runtime.GOOS == "darwin" {
+		options = append(options, "nolocks", "locallocks")
+	} else {
+		options = append(options, "mountproto=tcp", "nolock")
+	}
+	if vfsflags.Opt.ReadOnly {
+		options = append(options, "ro")
+	}
+	options = append(options, mountlib.ExtraOptions...)
+	return options
+}
+
+// mountCommand returns the command to mount the NFS server listening
+// on port at mountpoint
+func mountCommand(port int, mountpoint string) *exec.Cmd {
+	options := strings.Join(mountOptions(port), ",")
+	if runtime.GOOS == "darwin" {
+		return exec.Command("mount_nfs", "-o", options, "localhost:/", mountpoint)
+	}
+	return exec.Command("mount", "-t", "nfs", "-o", options, "localhost:/", mountpoint)
+}
+
+// mount the file system
+//
+// The mount point will be ready when this returns.
+//
+// returns an error, and an error channel for the serve process to
+// report an error if the NFS server stops.
+func mount(f fs.Fs, mountpoint string) (*vfs.VFS, <-chan error, func() error, error) {
+	fs.Debugf(f, "Mounting on %q", mountpoint)
+	VFS := vfs.New(f, &vfsflags.Opt)
+	server, err := nfs.NewServer(VFS, "localhost:0")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Serve NFS in the background returning error to errChan
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.Serve()
+	}()
+
+	mountCmd := mountCommand(server.Port(), mountpoint)
+	fs.Debugf(f, "Mounting with %q", mountCmd.Args)
+	out, err := mountCmd.CombinedOutput()
+	if err != nil {
+		_ = server.Close()
+		VFS.Shutdown()
+		return nil, nil, nil, errors.Wrapf(err, "%s failed: %s", mountCmd.Args[0], strings.TrimSpace(string(out)))
+	}
+
+	unmount := func() error {
+		out, err := exec.Command("umount", mountpoint).CombinedOutput()
+		if err != nil {
+			return errors.Wrapf(err, "umount failed: %s", strings.TrimSpace(string(out)))
+		}
+		// Close the server once unmounted so the files are uploaded
+		err = server.Close()
+		VFS.Shutdown()
+		return err
+	}
+
+	return VFS, errChan, unmount, nil
+}
+
+// Mount mounts the remote at mountpoint.
+func Mount(f fs.Fs, mountpoint string) error {
+	// Mount it
+	FS, errChan, unmount, err := mount(f, mountpoint)
+	if err != nil {
+		return errors.Wrap(err, "failed to mount NFS fs")
+	}
+
+	sigInt := make(chan os.Signal, 1)
+	signal.Notify(sigInt, syscall.SIGINT, syscall.SIGTERM)
+	sigHup := make(chan os.Signal, 1)
+	signal.Notify(sigHup, syscall.SIGHUP)
+
+	unmountRequests, removeLiveMount := mountlib.AddLiveMount(mountpoint, FS)
+	defer removeLiveMount()
+
+	if err := sdnotify.SdNotifyReady(); err != nil && err != sdnotify.SdNotifyNoSocket {
+		return errors.Wrap(err, "failed to notify systemd")
+	}
+
+waitloop:
+	for {
+		select {
+		// NFS server stopped
+		case err = <-errChan:
+			break waitloop
+		// Program abort: umount
+		case <-sigInt:
+			err = unmount()
+			break waitloop
+		// unmount requested with the remote control
+		case request := <-unmountRequests:
+			err = unmount()
+			request.Reply <- err
+			break waitloop
+		// user sent SIGHUP to clear the cache
+		case <-sigHup:
+			root, err := FS.Root()
+			if err != nil {
+				fs.Errorf(f, "Error reading root: %v", err)
+			} else {
+				root.ForgetAll()
+			}
+		}
+	}
+
+	_ = sdnotify.SdNotifyStopping()
+	if err != nil {
+		return errors.Wrap(err, "failed to umount NFS fs")
+	}
+
+	return nil
+}
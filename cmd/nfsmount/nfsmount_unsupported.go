@@ -0,0 +1,6 @@
+// Build for nfsmount for unsupported platforms to stop go complaining
+// about "no buildable Go source files "
+
+// +build !linux,!darwin,!freebsd
+
+package nfsmount
@@ -17,6 +17,13 @@ var commandDefintion = &cobra.Command{
 Remove the path and all of its contents.  Note that this does not obey
 include/exclude filters - everything will be removed.  Use ` + "`" + `delete` + "`" + ` if
 you want to selectively delete files.
+
+If the remote can't remove a directory and its contents in one go
+then rclone lists the files and deletes them one at a time, running
+` + "`--delete-workers`" + ` (or ` + "`--checkers`" + ` if not set) deletions at
+once, and then removes the
+directories.  Use ` + "`-v`" + ` to see each file as it is deleted or
+` + "`--stats`" + ` to see how many have been deleted so far.
 `,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
@@ -54,9 +54,16 @@ Use "rclone rc list" to see a list of all possible commands.`,
 //
 // if err is set, out may be a valid error return or it may be nil
 func doCall(path string, in rc.Params) (out rc.Params, err error) {
+	return Call(url, path, in)
+}
+
+// Call does a call from (path, in) to (out, err) with the remote
+// control of the rclone running at url.
+//
+// if err is set, out may be a valid error return or it may be nil
+func Call(url, path string, in rc.Params) (out rc.Params, err error) {
 	// Do HTTP request
 	client := fshttp.NewClient(fs.Config)
-	url := url
 	if !strings.HasSuffix(url, "/") {
 		url += "/"
 	}
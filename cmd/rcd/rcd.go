@@ -0,0 +1,47 @@
+package rcd
+
+import (
+	"log"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/ncw/rclone/fs/rc/rcflags"
+	"github.com/ncw/rclone/fs/rc/schedule"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "rcd",
+	Short: `Run rclone listening to remote control commands only.`,
+	Long: `
+This runs rclone so that it only listens to remote control commands.
+
+This is useful if you are controlling rclone via the rc API, for
+example to start syncs with sync/sync or to watch their progress with
+core/stats, without running a mount or sync at the same time.
+
+All the --rc-* flags can be used to configure the remote control
+server, eg --rc-addr to choose where it listens.
+
+See the [rc documentation](/rc/) for more info on the rc flags and
+the commands it supports.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(0, 0, command, args)
+		if rcflags.Opt.Enabled {
+			log.Fatalf("Don't supply --rc flag when using rcd")
+		}
+
+		err := schedule.Start()
+		if err != nil {
+			log.Fatalf("Failed to start scheduler: %v", err)
+		}
+
+		// Run the remote control server until killed
+		rc.Serve(&rcflags.Opt)
+	},
+}
@@ -0,0 +1,19 @@
+// Finding the running binary go1.8+
+
+//+build go1.8
+
+package selfupdate
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// executable returns the path of the running binary
+func executable() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(exe)
+}
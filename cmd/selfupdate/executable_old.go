@@ -0,0 +1,24 @@
+// Finding the running binary pre go1.8
+
+//+build !go1.8
+
+package selfupdate
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// executable returns the path of the running binary
+func executable() (string, error) {
+	exe, err := exec.LookPath(os.Args[0])
+	if err != nil {
+		return "", err
+	}
+	exe, err = filepath.Abs(exe)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(exe)
+}
@@ -0,0 +1,297 @@
+// Package selfupdate implements the selfupdate command
+package selfupdate
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/cmd/version"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/fshttp"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/clearsign"
+)
+
+// downloadURL is where the releases are published
+var downloadURL = "https://downloads.rclone.org/"
+
+var (
+	installVersion   = ""
+	output           = ""
+	keyring          = ""
+	noCheckSignature = false
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+	flags := commandDefinition.Flags()
+	flags.StringVarP(&installVersion, "version", "", "", "Install this version, eg v1.41, instead of the latest.")
+	flags.StringVarP(&output, "output", "", "", "Write the new binary here instead of replacing the running one.")
+	flags.StringVarP(&keyring, "keyring", "", "", "File with the release signing public key to check the download with.")
+	flags.BoolVarP(&noCheckSignature, "no-check-signature", "", false, "Don't check the signature on the download.")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "selfupdate",
+	Short: `Update the rclone binary to the latest release.`,
+	Long: `
+This downloads the latest release of rclone for the running OS and
+architecture from https://downloads.rclone.org/ and replaces the
+running rclone binary with it.  This is useful on machines where
+rclone wasn't installed with a package manager.
+
+If you are running the latest release already, this does nothing.
+Use --version to install a particular release instead, eg --version
+v1.41.  Use "rclone version --check" to see what the latest release
+is.
+
+The download is checked against the SHA256 checksum in the SHA256SUMS
+file for the release.  That file is signed with the rclone release
+signing key, and the signature is checked with the public key in the
+file given with --keyring.  This should be an ASCII armored or binary
+public key, eg as exported with "gpg --armor --export", obtained from
+a source you trust.  If you don't want to check the signature (not
+recommended) then use --no-check-signature instead.
+
+Use --output to write the new binary to a different file rather than
+replacing the running one, eg to install it somewhere else.  The user
+running rclone needs permission to write the binary - if rclone was
+installed into a system directory you may need to run this with sudo.
+
+On Windows the running binary can't be deleted, so it is renamed to
+rclone.exe.old which can be deleted after rclone has finished.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(0, 0, command, args)
+		cmd.Run(false, false, command, selfUpdate)
+	},
+}
+
+// selfUpdate downloads and installs the release
+func selfUpdate() error {
+	if keyring == "" && !noCheckSignature {
+		return errors.New("need --keyring with the release signing key to check the download with, or --no-check-signature")
+	}
+	newVersion := installVersion
+	if newVersion == "" {
+		latest, vs, err := version.GetVersion(downloadURL + "version.txt")
+		if err != nil {
+			return errors.Wrap(err, "failed to find latest release")
+		}
+		yours, err := version.ParseVersion(fs.Version)
+		if err == nil && yours.Cmp(latest) >= 0 && output == "" {
+			fs.Logf(nil, "rclone %s is up to date with the latest release %s", fs.Version, vs)
+			return nil
+		}
+		newVersion = vs
+	}
+	if !strings.HasPrefix(newVersion, "v") {
+		newVersion = "v" + newVersion
+	}
+
+	targetFile := output
+	if targetFile == "" {
+		var err error
+		targetFile, err = executable()
+		if err != nil {
+			return errors.Wrap(err, "failed to find the rclone binary - use --output")
+		}
+	}
+
+	zipName := releaseZipName(newVersion, runtime.GOOS, runtime.GOARCH)
+	releaseURL := downloadURL + newVersion + "/"
+	fs.Infof(nil, "Downloading %s", releaseURL+zipName)
+	zipData, err := fetch(releaseURL + zipName)
+	if err != nil {
+		return err
+	}
+
+	// Check the download
+	sums, err := fetch(releaseURL + "SHA256SUMS")
+	if err != nil {
+		return err
+	}
+	if noCheckSignature {
+		fs.Logf(nil, "Not checking the signature on SHA256SUMS")
+	} else {
+		keys, err := loadKeyring(keyring)
+		if err != nil {
+			return err
+		}
+		sums, err = verifySums(sums, keys)
+		if err != nil {
+			return err
+		}
+		fs.Infof(nil, "Signature on SHA256SUMS is OK")
+	}
+	wantHash, err := findHash(sums, zipName)
+	if err != nil {
+		return err
+	}
+	gotHash := sha256.Sum256(zipData)
+	if hex.EncodeToString(gotHash[:]) != wantHash {
+		return errors.Errorf("SHA256 of %s doesn't match SHA256SUMS - download corrupted?", zipName)
+	}
+
+	binary, err := extractBinary(zipData)
+	if err != nil {
+		return err
+	}
+	err = installBinary(targetFile, binary)
+	if err != nil {
+		return err
+	}
+	fs.Logf(nil, "Installed rclone %s as %q", newVersion, targetFile)
+	return nil
+}
+
+// releaseZipName returns the name of the release zip file for the os
+// and architecture given
+func releaseZipName(version, goos, goarch string) string {
+	if goos == "darwin" {
+		goos = "osx"
+	}
+	return "rclone-" + version + "-" + goos + "-" + goarch + ".zip"
+}
+
+// fetch reads the contents of url
+func fetch(url string) (data []byte, err error) {
+	resp, err := fshttp.NewClient(fs.Config).Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(resp.Body, &err)
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to fetch %q: %s", url, resp.Status)
+	}
+	data, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch %q", url)
+	}
+	return data, nil
+}
+
+// loadKeyring reads the public keys in the ASCII armored or binary
+// file
+func loadKeyring(file string) (openpgp.EntityList, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read keyring")
+	}
+	keys, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		keys, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse keyring %q", file)
+	}
+	return keys, nil
+}
+
+// verifySums checks the signature on the clear signed sums, returning
+// the signed text.
+func verifySums(sums []byte, keys openpgp.EntityList) ([]byte, error) {
+	block, _ := clearsign.Decode(sums)
+	if block == nil {
+		return nil, errors.New("SHA256SUMS isn't signed")
+	}
+	_, err := openpgp.CheckDetachedSignature(keys, bytes.NewReader(block.Bytes), block.ArmoredSignature.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "bad signature on SHA256SUMS")
+	}
+	return block.Plaintext, nil
+}
+
+// findHash finds the hash of the file name in the sums which are in
+// the format output by sha256sum
+func findHash(sums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.Errorf("no SHA256 for %q in SHA256SUMS", name)
+}
+
+// extractBinary returns the rclone binary from the release zip
+func extractBinary(zipData []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read release zip")
+	}
+	for _, file := range zr.File {
+		leaf := path.Base(file.Name)
+		if leaf != "rclone" && leaf != "rclone.exe" {
+			continue
+		}
+		in, err := file.Open()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read release zip")
+		}
+		data, err := ioutil.ReadAll(in)
+		closeErr := in.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read release zip")
+		}
+		return data, nil
+	}
+	return nil, errors.New("no rclone binary in release zip")
+}
+
+// installBinary replaces targetFile with the binary.
+//
+// The new binary is written next to targetFile then renamed over it
+// so targetFile is never left half written.  The old file is moved
+// out of the way first as Windows won't replace a running binary.
+func installBinary(targetFile string, binary []byte) error {
+	newFile := targetFile + ".new"
+	oldFile := targetFile + ".old"
+	err := ioutil.WriteFile(newFile, binary, 0755)
+	if err != nil {
+		return errors.Wrap(err, "failed to write new binary")
+	}
+	_ = os.Remove(oldFile)
+	hadOld := true
+	err = os.Rename(targetFile, oldFile)
+	if os.IsNotExist(err) {
+		hadOld = false
+	} else if err != nil {
+		_ = os.Remove(newFile)
+		return errors.Wrap(err, "failed to move old binary out of the way")
+	}
+	err = os.Rename(newFile, targetFile)
+	if err != nil {
+		if hadOld {
+			_ = os.Rename(oldFile, targetFile)
+		}
+		_ = os.Remove(newFile)
+		return errors.Wrap(err, "failed to install new binary")
+	}
+	if hadOld {
+		err = os.Remove(oldFile)
+		if err != nil {
+			fs.Logf(nil, "Couldn't remove old binary %q - remove it when rclone isn't running: %v", oldFile, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package selfupdate
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/clearsign"
+)
+
+// makeZip makes a release zip with the binary in
+func makeZip(t *testing.T, binary string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, contents := range map[string]string{
+		"rclone-v1.99-os-arch/README.txt": "readme",
+		"rclone-v1.99-os-arch/rclone":     binary,
+	} {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// makeKey makes a new signing key returning it and its public key
+// ASCII armored
+func makeKey(t *testing.T) (*openpgp.Entity, []byte) {
+	entity, err := openpgp.NewEntity("Test", "", "test@example.com", nil)
+	require.NoError(t, err)
+	// Serializing the private key signs the identities and subkeys
+	// which is needed before the public key can be serialized
+	require.NoError(t, entity.SerializePrivate(ioutil.Discard, nil))
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+	return entity, buf.Bytes()
+}
+
+// sign clear signs text with entity
+func sign(t *testing.T, entity *openpgp.Entity, text string) []byte {
+	var buf bytes.Buffer
+	w, err := clearsign.Encode(&buf, entity.PrivateKey, nil)
+	require.NoError(t, err)
+	_, err = w.Write([]byte(text))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestReleaseZipName(t *testing.T) {
+	assert.Equal(t, "rclone-v1.41-linux-amd64.zip", releaseZipName("v1.41", "linux", "amd64"))
+	assert.Equal(t, "rclone-v1.41-osx-386.zip", releaseZipName("v1.41", "darwin", "386"))
+}
+
+func TestFindHash(t *testing.T) {
+	sums := []byte("0123abcd  rclone-v1.41-linux-amd64.zip\nABCDEF01 *rclone-v1.41-osx-amd64.zip\n")
+	hash, err := findHash(sums, "rclone-v1.41-linux-amd64.zip")
+	require.NoError(t, err)
+	assert.Equal(t, "0123abcd", hash)
+	hash, err = findHash(sums, "rclone-v1.41-osx-amd64.zip")
+	require.NoError(t, err)
+	assert.Equal(t, "abcdef01", hash)
+	_, err = findHash(sums, "rclone-v1.41-linux-arm.zip")
+	assert.Error(t, err)
+}
+
+func TestVerifySums(t *testing.T) {
+	entity, _ := makeKey(t)
+	other, _ := makeKey(t)
+	signed := sign(t, entity, "0123abcd  rclone.zip\n")
+
+	text, err := verifySums(signed, openpgp.EntityList{entity})
+	require.NoError(t, err)
+	assert.Equal(t, "0123abcd  rclone.zip\n", string(text))
+
+	_, err = verifySums(signed, openpgp.EntityList{other})
+	assert.Error(t, err)
+
+	tampered := bytes.Replace(signed, []byte("0123abcd"), []byte("3210abcd"), 1)
+	_, err = verifySums(tampered, openpgp.EntityList{entity})
+	assert.Error(t, err)
+
+	_, err = verifySums([]byte("0123abcd  rclone.zip\n"), openpgp.EntityList{entity})
+	assert.Error(t, err)
+}
+
+func TestSelfUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-selfupdate")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	entity, publicKey := makeKey(t)
+	keyFile := filepath.Join(dir, "key.asc")
+	require.NoError(t, ioutil.WriteFile(keyFile, publicKey, 0600))
+
+	zipName := releaseZipName("v1.99", runtime.GOOS, runtime.GOARCH)
+	zipData := makeZip(t, "new binary")
+	zipHash := sha256.Sum256(zipData)
+	sums := sign(t, entity, fmt.Sprintf("%s  %s\n", hex.EncodeToString(zipHash[:]), zipName))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/version.txt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("rclone v1.99\n"))
+	})
+	mux.HandleFunc("/v1.99/"+zipName, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(zipData)
+	})
+	mux.HandleFunc("/v1.99/SHA256SUMS", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(sums)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	oldDownloadURL, oldOutput, oldKeyring := downloadURL, output, keyring
+	defer func() {
+		downloadURL, output, keyring = oldDownloadURL, oldOutput, oldKeyring
+	}()
+	downloadURL = server.URL + "/"
+	output = filepath.Join(dir, "rclone")
+	require.NoError(t, ioutil.WriteFile(output, []byte("old binary"), 0755))
+
+	// Needs a keyring
+	keyring = ""
+	assert.Error(t, selfUpdate())
+
+	keyring = keyFile
+	require.NoError(t, selfUpdate())
+	got, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "new binary", string(got))
+	_, err = os.Stat(output + ".old")
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(output + ".new")
+	assert.True(t, os.IsNotExist(err))
+
+	// A corrupted download is rejected and the binary left alone
+	require.NoError(t, ioutil.WriteFile(output, []byte("old binary"), 0755))
+	zipData = makeZip(t, "corrupted binary")
+	assert.Error(t, selfUpdate())
+	got, err = ioutil.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "old binary", string(got))
+}
@@ -0,0 +1,185 @@
+package dlna
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/vfs"
+)
+
+// dlnaContentFeatures are the DLNA flags for the resources - they
+// can be fetched with range requests and streamed
+const dlnaContentFeatures = "DLNA.ORG_OP=01;DLNA.ORG_CI=0;DLNA.ORG_FLAGS=01700000000000000000000000000000"
+
+const didlStart = `<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/" xmlns:dlna="urn:schemas-dlna-org:metadata-1-0/">`
+
+const didlEnd = `</DIDL-Lite>`
+
+// mediaMimeTypes are mime types for media files which aren't in
+// every system's mime type table
+var mediaMimeTypes = map[string]string{
+	".3gp":  "video/3gpp",
+	".aac":  "audio/aac",
+	".avi":  "video/x-msvideo",
+	".flac": "audio/flac",
+	".flv":  "video/x-flv",
+	".m4a":  "audio/mp4",
+	".m4v":  "video/mp4",
+	".mkv":  "video/x-matroska",
+	".mov":  "video/quicktime",
+	".mp3":  "audio/mpeg",
+	".mp4":  "video/mp4",
+	".mpeg": "video/mpeg",
+	".mpg":  "video/mpeg",
+	".oga":  "audio/ogg",
+	".ogg":  "audio/ogg",
+	".ogv":  "video/ogg",
+	".opus": "audio/ogg",
+	".ts":   "video/mp2t",
+	".wav":  "audio/wav",
+	".webm": "video/webm",
+	".wma":  "audio/x-ms-wma",
+	".wmv":  "video/x-ms-wmv",
+}
+
+// mimeTypeFromName returns the mime type of the file called remote
+func mimeTypeFromName(remote string) string {
+	if mimeType, ok := mediaMimeTypes[strings.ToLower(path.Ext(remote))]; ok {
+		return mimeType
+	}
+	return fs.MimeTypeFromName(remote)
+}
+
+// upnpClass returns the UPnP class for mimeType or "" if it isn't a
+// media type
+func upnpClass(mimeType string) string {
+	switch {
+	case strings.HasPrefix(mimeType, "video/"):
+		return "object.item.videoItem"
+	case strings.HasPrefix(mimeType, "audio/"):
+		return "object.item.audioItem.musicTrack"
+	case strings.HasPrefix(mimeType, "image/"):
+		return "object.item.imageItem.photo"
+	}
+	return ""
+}
+
+// objectID returns the ContentDirectory object ID of the node.
+//
+// The root is "0" as required and everything else is its path with a
+// leading "/".
+func objectID(node vfs.Node) string {
+	if node.Path() == "" {
+		return "0"
+	}
+	return "/" + node.Path()
+}
+
+// parentID returns the object ID of the parent of the node
+func parentID(node vfs.Node) string {
+	if node.Path() == "" {
+		return "-1"
+	}
+	dir := path.Dir(node.Path())
+	if dir == "." {
+		return "0"
+	}
+	return "/" + dir
+}
+
+// objectPath returns the path of the object with the ID given
+func objectPath(id string) string {
+	if id == "0" {
+		return ""
+	}
+	return strings.TrimPrefix(id, "/")
+}
+
+// isMedia returns true if the node should be shown to clients
+func isMedia(node vfs.Node) bool {
+	return node.IsDir() || upnpClass(mimeTypeFromName(node.Name())) != ""
+}
+
+// writeObject writes the DIDL-Lite for node to buf, linking to the
+// resources on the server at host
+func writeObject(buf *bytes.Buffer, host string, node vfs.Node) {
+	id, parent, title := xmlEscape(objectID(node)), xmlEscape(parentID(node)), xmlEscape(node.Name())
+	if node.Path() == "" {
+		title = "root"
+	}
+	if node.IsDir() {
+		fmt.Fprintf(buf, `<container id="%s" parentID="%s" restricted="1"><dc:title>%s</dc:title><upnp:class>object.container.storageFolder</upnp:class></container>`, id, parent, title)
+		return
+	}
+	mimeType := mimeTypeFromName(node.Name())
+	resURL := "http://" + host + (&url.URL{Path: resPath + node.Path()}).String()
+	fmt.Fprintf(buf, `<item id="%s" parentID="%s" restricted="1"><dc:title>%s</dc:title><upnp:class>%s</upnp:class>`, id, parent, title, upnpClass(mimeType))
+	fmt.Fprintf(buf, `<dc:date>%s</dc:date>`, node.ModTime().UTC().Format("2006-01-02T15:04:05"))
+	fmt.Fprintf(buf, `<res protocolInfo="http-get:*:%s:%s" size="%d">%s</res>`, xmlEscape(mimeType), dlnaContentFeatures, node.Size(), xmlEscape(resURL))
+	buf.WriteString(`</item>`)
+}
+
+// browse runs the ContentDirectory Browse action
+func (s *server) browse(r *http.Request, args map[string]string) ([]soapArg, error) {
+	node, err := s.vfs.Stat(objectPath(args["ObjectID"]))
+	if err != nil {
+		return nil, errNoSuchObject
+	}
+	var nodes vfs.Nodes
+	totalMatches := 1
+	switch args["BrowseFlag"] {
+	case "BrowseMetadata":
+		nodes = vfs.Nodes{node}
+	case "BrowseDirectChildren":
+		dir, ok := node.(*vfs.Dir)
+		if !ok {
+			return nil, errNoSuchContainer
+		}
+		items, err := dir.ReadDirAll()
+		if err != nil {
+			fs.Errorf(dir, "Failed to list directory: %v", err)
+			return nil, errActionFailed
+		}
+		for _, item := range items {
+			if isMedia(item) {
+				nodes = append(nodes, item)
+			}
+		}
+		totalMatches = len(nodes)
+		start, err := strconv.Atoi(args["StartingIndex"])
+		if err != nil || start < 0 {
+			return nil, errInvalidArgs
+		}
+		count, err := strconv.Atoi(args["RequestedCount"])
+		if err != nil || count < 0 {
+			return nil, errInvalidArgs
+		}
+		if start > len(nodes) {
+			start = len(nodes)
+		}
+		nodes = nodes[start:]
+		if count > 0 && count < len(nodes) {
+			nodes = nodes[:count]
+		}
+	default:
+		return nil, errInvalidArgs
+	}
+	var buf bytes.Buffer
+	buf.WriteString(didlStart)
+	for _, node := range nodes {
+		writeObject(&buf, r.Host, node)
+	}
+	buf.WriteString(didlEnd)
+	return []soapArg{
+		{"Result", buf.String()},
+		{"NumberReturned", strconv.Itoa(len(nodes))},
+		{"TotalMatches", strconv.Itoa(totalMatches)},
+		{"UpdateID", strconv.FormatUint(uint64(s.updateID), 10)},
+	}, nil
+}
@@ -0,0 +1,92 @@
+// Package dlna implements a DLNA media server to serve an rclone VFS
+package dlna
+
+import (
+	"time"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/ncw/rclone/vfs"
+	"github.com/ncw/rclone/vfs/vfsflags"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Options contains options for the DLNA server
+type Options struct {
+	ListenAddr       string        // Port to listen on
+	FriendlyName     string        // name shown to clients - defaults to "rclone (hostname)"
+	AnnounceInterval time.Duration // how often to advertise the server with SSDP
+}
+
+// DefaultOpt is the default values used for Options
+var DefaultOpt = Options{
+	ListenAddr:       ":7879",
+	AnnounceInterval: 12 * time.Minute,
+}
+
+// Opt is options set by command line flags
+var Opt = DefaultOpt
+
+// AddFlags adds flags for the dlna server
+func AddFlags(flagSet *pflag.FlagSet, Opt *Options) {
+	flags.StringVarP(flagSet, &Opt.ListenAddr, "addr", "", Opt.ListenAddr, "IPaddress:Port or :Port to bind the DLNA http server to.")
+	flags.StringVarP(flagSet, &Opt.FriendlyName, "name", "", Opt.FriendlyName, "Name of DLNA server shown to clients (default \"rclone (hostname)\").")
+	flags.DurationVarP(flagSet, &Opt.AnnounceInterval, "announce-interval", "", Opt.AnnounceInterval, "Interval between SSDP announcements.")
+}
+
+func init() {
+	vfsflags.AddFlags(Command.Flags())
+	AddFlags(Command.Flags(), &Opt)
+}
+
+// Command definition for cobra
+var Command = &cobra.Command{
+	Use:   "dlna remote:path",
+	Short: `Serve remote:path over DLNA`,
+	Long: `rclone serve dlna is a DLNA media server for media stored in a
+remote.  Many devices, such as smart TVs, games consoles and media
+players, can browse the server and stream videos, music and pictures
+straight from the remote.
+
+The server advertises itself on the local network with SSDP (the
+discovery part of UPnP) so it should appear in the devices' list of
+media sources without any configuration.  Only directories and files
+which look like videos, music or pictures are shown.  The files are
+served over HTTP with support for range requests so the devices can
+seek within them.
+
+Note that DLNA has no authentication so anyone on the local network
+will be able to read the media served.
+
+### Server options
+
+Use --addr to specify which IP address and port the server should
+listen on, eg --addr 1.2.3.4:8000 or --addr :8080 to listen to all
+IPs.  It listens on all IPs on port 7879 by default, as the devices
+need to be able to reach it.
+
+Use --name to choose the friendly name the devices show for the
+server.  It defaults to "rclone (hostname)".
+
+The server is announced every --announce-interval (12 minutes by
+default) and answers searches from devices at any time.  If the
+devices can't find the server check that the firewall allows UDP
+multicast on port 1900 as well as TCP connections to --addr.
+` + vfs.Help,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		f := cmd.NewFsSrc(args)
+		cmd.Run(false, false, command, func() error {
+			s := newServer(f, &Opt)
+			err := s.Serve()
+			if err != nil {
+				return err
+			}
+			fs.Logf(f, "DLNA server %q listening on %v", s.friendlyName, s.Addr())
+			s.Wait()
+			return nil
+		})
+	},
+}
@@ -0,0 +1,223 @@
+package dlna
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startServer starts a server serving a temporary directory with some
+// media in, returning it and a function to clean up
+func startServer(t *testing.T) (s *server, cleanup func()) {
+	dir, err := ioutil.TempDir("", "rclone-serve-dlna")
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "films"), 0777))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "video.mp4"), []byte("0123456789"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not media"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "films", "a & b.mkv"), []byte("film"), 0600))
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+
+	opt := DefaultOpt
+	opt.ListenAddr = "localhost:0"
+	opt.FriendlyName = "rclone test"
+	s = newServer(f, &opt)
+	require.NoError(t, s.Serve())
+	return s, func() {
+		s.Close()
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+// soapCall calls the action on the ContentDirectory returning the
+// status and the response
+func soapCall(t *testing.T, s *server, action string, args string) (int, string) {
+	body := `<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>` +
+		fmt.Sprintf(`<u:%s xmlns:u="urn:schemas-upnp-org:service:ContentDirectory:1">%s</u:%s>`, action, args, action) +
+		`</s:Body></s:Envelope>`
+	req, err := http.NewRequest("POST", "http://"+s.Addr()+controlPath, bytes.NewBufferString(body))
+	require.NoError(t, err)
+	req.Header.Set("SOAPACTION", `"urn:schemas-upnp-org:service:ContentDirectory:1#`+action+`"`)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	data, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(data)
+}
+
+// browseResponse is the response to a Browse action
+type browseResponse struct {
+	Body struct {
+		Response struct {
+			Result         string `xml:"Result"`
+			NumberReturned int    `xml:"NumberReturned"`
+			TotalMatches   int    `xml:"TotalMatches"`
+		} `xml:"BrowseResponse"`
+	} `xml:"Body"`
+}
+
+// browse does a Browse action returning the response
+func browse(t *testing.T, s *server, objectID, flag string, start, count int) browseResponse {
+	status, body := soapCall(t, s, "Browse", fmt.Sprintf("<ObjectID>%s</ObjectID><BrowseFlag>%s</BrowseFlag><Filter>*</Filter><StartingIndex>%d</StartingIndex><RequestedCount>%d</RequestedCount><SortCriteria></SortCriteria>", xmlEscape(objectID), flag, start, count))
+	require.Equal(t, http.StatusOK, status, body)
+	var resp browseResponse
+	require.NoError(t, xml.Unmarshal([]byte(body), &resp))
+	return resp
+}
+
+func TestDescriptions(t *testing.T) {
+	s, cleanup := startServer(t)
+	defer cleanup()
+
+	resp, err := http.Get("http://" + s.Addr() + rootDescPath)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	var desc rootDesc
+	require.NoError(t, xml.Unmarshal(data, &desc))
+	assert.Equal(t, "rclone test", desc.Device.FriendlyName)
+	assert.Equal(t, mediaServerDeviceType, desc.Device.DeviceType)
+	assert.Equal(t, "uuid:"+makeDeviceUUID("rclone test"), desc.Device.UDN)
+	require.Equal(t, len(services), len(desc.Device.Services))
+
+	// Check all the services can be described
+	for _, service := range desc.Device.Services {
+		resp, err := http.Get("http://" + s.Addr() + service.SCPDURL)
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var desc scpd
+		require.NoError(t, xml.Unmarshal(data, &desc), service.SCPDURL)
+		assert.NotEqual(t, 0, len(desc.Actions), service.SCPDURL)
+	}
+}
+
+func TestBrowse(t *testing.T) {
+	s, cleanup := startServer(t)
+	defer cleanup()
+
+	// The root should have the directory and the video but not the
+	// text file
+	resp := browse(t, s, "0", "BrowseDirectChildren", 0, 0)
+	assert.Equal(t, 2, resp.Body.Response.NumberReturned)
+	assert.Equal(t, 2, resp.Body.Response.TotalMatches)
+	result := resp.Body.Response.Result
+	assert.Contains(t, result, `<container id="/films" parentID="0"`)
+	assert.Contains(t, result, `<item id="/video.mp4" parentID="0"`)
+	assert.Contains(t, result, `<upnp:class>object.item.videoItem</upnp:class>`)
+	assert.Contains(t, result, `protocolInfo="http-get:*:video/mp4:`)
+	assert.Contains(t, result, `size="10">http://`+s.Addr()+`/r/video.mp4</res>`)
+	assert.NotContains(t, result, "notes.txt")
+
+	// Paging
+	resp = browse(t, s, "0", "BrowseDirectChildren", 1, 1)
+	assert.Equal(t, 1, resp.Body.Response.NumberReturned)
+	assert.Equal(t, 2, resp.Body.Response.TotalMatches)
+	assert.Contains(t, resp.Body.Response.Result, "video.mp4")
+	assert.NotContains(t, resp.Body.Response.Result, "films")
+
+	// Names which need escaping
+	resp = browse(t, s, "/films", "BrowseDirectChildren", 0, 0)
+	assert.Equal(t, 1, resp.Body.Response.NumberReturned)
+	assert.Contains(t, resp.Body.Response.Result, `<item id="/films/a &amp; b.mkv" parentID="/films"`)
+	assert.Contains(t, resp.Body.Response.Result, `/r/films/a%20&amp;%20b.mkv</res>`)
+
+	resp = browse(t, s, "/video.mp4", "BrowseMetadata", 0, 0)
+	assert.Equal(t, 1, resp.Body.Response.NumberReturned)
+	assert.Contains(t, resp.Body.Response.Result, `<item id="/video.mp4" parentID="0"`)
+
+	resp = browse(t, s, "0", "BrowseMetadata", 0, 0)
+	assert.Contains(t, resp.Body.Response.Result, `<container id="0" parentID="-1"`)
+
+	// Errors
+	status, body := soapCall(t, s, "Browse", "<ObjectID>/potato</ObjectID><BrowseFlag>BrowseMetadata</BrowseFlag>")
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Contains(t, body, "<errorCode>701</errorCode>")
+	status, body = soapCall(t, s, "Browse", "<ObjectID>/video.mp4</ObjectID><BrowseFlag>BrowseDirectChildren</BrowseFlag><StartingIndex>0</StartingIndex><RequestedCount>0</RequestedCount>")
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Contains(t, body, "<errorCode>710</errorCode>")
+	status, body = soapCall(t, s, "Potato", "")
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Contains(t, body, "<errorCode>401</errorCode>")
+
+	status, body = soapCall(t, s, "GetSortCapabilities", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "<SortCaps>dc:title</SortCaps>")
+}
+
+func TestServeResource(t *testing.T) {
+	s, cleanup := startServer(t)
+	defer cleanup()
+
+	req, err := http.NewRequest("GET", "http://"+s.Addr()+"/r/video.mp4", nil)
+	require.NoError(t, err)
+	req.Header.Set("Range", "bytes=2-5")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t, "2345", string(data))
+	assert.Equal(t, "video/mp4", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "Streaming", resp.Header.Get("transferMode.dlna.org"))
+	assert.True(t, strings.HasPrefix(resp.Header.Get("contentFeatures.dlna.org"), "DLNA.ORG_OP=01"))
+
+	resp, err = http.Head("http://" + s.Addr() + "/r/films/a%20&%20b.mkv")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "4", resp.Header.Get("Content-Length"))
+
+	for _, path := range []string{"/r/films", "/r/potato.mp4"} {
+		resp, err = http.Get("http://" + s.Addr() + path)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, path)
+	}
+}
+
+func TestSSDPMessages(t *testing.T) {
+	s := &server{uuid: "1234"}
+	ssdp := &ssdpServer{s: s, location: "http://1.2.3.4:7879/rootDesc.xml"}
+
+	assert.Equal(t, []string{mediaServerDeviceType}, s.searchTargets(mediaServerDeviceType))
+	assert.Equal(t, []string{"uuid:1234"}, s.searchTargets("uuid:1234"))
+	assert.Equal(t, s.notificationTypes(), s.searchTargets("ssdp:all"))
+	assert.Nil(t, s.searchTargets("urn:schemas-upnp-org:device:MediaRenderer:1"))
+
+	msg := string(ssdp.makeSearchResponse("upnp:rootdevice"))
+	assert.True(t, strings.HasPrefix(msg, "HTTP/1.1 200 OK\r\n"))
+	assert.Contains(t, msg, "\r\nLOCATION: http://1.2.3.4:7879/rootDesc.xml\r\n")
+	assert.Contains(t, msg, "\r\nST: upnp:rootdevice\r\n")
+	assert.Contains(t, msg, "\r\nUSN: uuid:1234::upnp:rootdevice\r\n")
+	assert.True(t, strings.HasSuffix(msg, "\r\n\r\n"))
+
+	msg = string(ssdp.makeNotify("ssdp:alive", "uuid:1234"))
+	assert.True(t, strings.HasPrefix(msg, "NOTIFY * HTTP/1.1\r\n"))
+	assert.Contains(t, msg, "\r\nNTS: ssdp:alive\r\n")
+	assert.Contains(t, msg, "\r\nUSN: uuid:1234\r\n")
+	assert.Contains(t, msg, "\r\nLOCATION: ")
+
+	msg = string(ssdp.makeNotify("ssdp:byebye", "upnp:rootdevice"))
+	assert.Contains(t, msg, "\r\nNTS: ssdp:byebye\r\n")
+	assert.NotContains(t, msg, "LOCATION")
+}
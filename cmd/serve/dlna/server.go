@@ -0,0 +1,338 @@
+package dlna
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/vfs"
+	"github.com/ncw/rclone/vfs/vfsflags"
+	"github.com/pkg/errors"
+)
+
+const (
+	rootDescPath = "/rootDesc.xml"
+	scpdPath     = "/scpd/"
+	controlPath  = "/ctl"
+	eventPath    = "/evt/"
+	resPath      = "/r/"
+
+	mediaServerDeviceType = "urn:schemas-upnp-org:device:MediaServer:1"
+)
+
+// server contains everything to run the server
+type server struct {
+	f            fs.Fs
+	opt          Options
+	vfs          *vfs.VFS
+	friendlyName string
+	uuid         string // of the root device
+	updateID     uint32 // SystemUpdateID of the ContentDirectory
+	rootDesc     []byte // root device description
+	listener     net.Listener
+	ssdp         []*ssdpServer
+	waitChan     chan struct{} // for waiting on the listener to close
+	wg           sync.WaitGroup
+}
+
+// newServer makes a new DLNA server for f
+func newServer(f fs.Fs, opt *Options) *server {
+	s := &server{
+		f:            f,
+		vfs:          vfs.New(f, &vfsflags.Opt),
+		opt:          *opt,
+		friendlyName: opt.FriendlyName,
+		updateID:     uint32(time.Now().Unix()),
+		waitChan:     make(chan struct{}),
+	}
+	if s.friendlyName == "" {
+		s.friendlyName = makeDefaultFriendlyName()
+	}
+	s.uuid = makeDeviceUUID(s.friendlyName)
+	return s
+}
+
+// makeDefaultFriendlyName returns "rclone (hostname)"
+func makeDefaultFriendlyName() string {
+	hostName, err := os.Hostname()
+	if err != nil {
+		hostName = ""
+	} else {
+		hostName = " (" + hostName + ")"
+	}
+	return "rclone" + hostName
+}
+
+// makeDeviceUUID makes a UUID from the friendly name so that it stays
+// the same across restarts and devices remember the server.
+func makeDeviceUUID(unique string) string {
+	h := md5.New()
+	_, _ = h.Write([]byte(unique))
+	buf := h.Sum(nil)
+	return fmt.Sprintf("%x-%x-%x-%x-%x", buf[:4], buf[4:6], buf[6:8], buf[8:10], buf[10:16])
+}
+
+// Serve starts the server listening in the background
+func (s *server) Serve() (err error) {
+	s.rootDesc, err = s.makeRootDesc()
+	if err != nil {
+		return err
+	}
+	s.listener, err = net.Listen("tcp", s.opt.ListenAddr)
+	if err != nil {
+		return errors.Wrap(err, "failed to listen for connection")
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(rootDescPath, s.serveRootDesc)
+	mux.HandleFunc(scpdPath, s.serveSCPD)
+	mux.HandleFunc(controlPath, s.serveControl)
+	mux.HandleFunc(eventPath, s.serveEvent)
+	mux.HandleFunc(resPath, s.serveResource)
+	go func() {
+		defer close(s.waitChan)
+		err := http.Serve(s.listener, logRequests(mux))
+		if err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
+			fs.Errorf(nil, "Error while serving DLNA: %v", err)
+		}
+	}()
+	s.startSSDP()
+	return nil
+}
+
+// Addr returns the address the server is listening on
+func (s *server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Wait blocks until the server is closed
+func (s *server) Wait() {
+	<-s.waitChan
+}
+
+// Close stops the SSDP announcements and the server listening
+func (s *server) Close() {
+	for _, ssdp := range s.ssdp {
+		ssdp.Close()
+	}
+	s.wg.Wait()
+	err := s.listener.Close()
+	if err != nil {
+		fs.Errorf(nil, "Error on closing DLNA server: %v", err)
+		return
+	}
+	s.Wait()
+}
+
+// logRequests logs the http requests at debug level
+func logRequests(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fs.Debugf(nil, "DLNA %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// writeXML writes an XML document with the xml header
+func writeXML(w http.ResponseWriter, data []byte) {
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(data)
+}
+
+// specVersion is the UPnP version used by the device and services
+type specVersion struct {
+	Major int `xml:"major"`
+	Minor int `xml:"minor"`
+}
+
+// deviceService describes a service in the root device description
+type deviceService struct {
+	ServiceType string `xml:"serviceType"`
+	ServiceID   string `xml:"serviceId"`
+	SCPDURL     string `xml:"SCPDURL"`
+	ControlURL  string `xml:"controlURL"`
+	EventSubURL string `xml:"eventSubURL"`
+}
+
+// deviceDesc is the description of the device
+type deviceDesc struct {
+	DeviceType       string          `xml:"deviceType"`
+	FriendlyName     string          `xml:"friendlyName"`
+	Manufacturer     string          `xml:"manufacturer"`
+	ManufacturerURL  string          `xml:"manufacturerURL"`
+	ModelDescription string          `xml:"modelDescription"`
+	ModelName        string          `xml:"modelName"`
+	ModelNumber      string          `xml:"modelNumber"`
+	ModelURL         string          `xml:"modelURL"`
+	UDN              string          `xml:"UDN"`
+	DLNADoc          string          `xml:"urn:schemas-dlna-org:device-1-0 X_DLNADOC"`
+	Services         []deviceService `xml:"serviceList>service"`
+}
+
+// rootDesc is the root device description fetched from rootDescPath
+type rootDesc struct {
+	XMLName     xml.Name    `xml:"urn:schemas-upnp-org:device-1-0 root"`
+	SpecVersion specVersion `xml:"specVersion"`
+	Device      deviceDesc  `xml:"device"`
+}
+
+// makeRootDesc makes the root device description
+func (s *server) makeRootDesc() ([]byte, error) {
+	desc := rootDesc{
+		SpecVersion: specVersion{Major: 1, Minor: 0},
+		Device: deviceDesc{
+			DeviceType:       mediaServerDeviceType,
+			FriendlyName:     s.friendlyName,
+			Manufacturer:     "rclone (rclone.org)",
+			ManufacturerURL:  "https://rclone.org/",
+			ModelDescription: "rclone",
+			ModelName:        "rclone",
+			ModelNumber:      fs.Version,
+			ModelURL:         "https://rclone.org/",
+			UDN:              "uuid:" + s.uuid,
+			DLNADoc:          "DMS-1.50",
+		},
+	}
+	for _, service := range services {
+		desc.Device.Services = append(desc.Device.Services, deviceService{
+			ServiceType: service.serviceType,
+			ServiceID:   service.serviceID,
+			SCPDURL:     scpdPath + service.name + ".xml",
+			ControlURL:  controlPath,
+			EventSubURL: eventPath + service.name,
+		})
+	}
+	data, err := xml.MarshalIndent(desc, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make root device description")
+	}
+	return data, nil
+}
+
+// serveRootDesc serves the root device description
+func (s *server) serveRootDesc(w http.ResponseWriter, r *http.Request) {
+	writeXML(w, s.rootDesc)
+}
+
+// serveSCPD serves the service descriptions
+func (s *server) serveSCPD(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, scpdPath), ".xml")
+	service := findService(name)
+	if service == nil {
+		http.NotFound(w, r)
+		return
+	}
+	data, err := xml.MarshalIndent(service.scpd, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeXML(w, data)
+}
+
+// serveEvent accepts event subscriptions.
+//
+// Nothing is evented but some devices won't use the server unless
+// their subscriptions succeed.
+func (s *server) serveEvent(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "SUBSCRIBE":
+		sid := r.Header.Get("SID")
+		if sid == "" {
+			sid = "uuid:" + makeDeviceUUID(fmt.Sprintf("%s %s %d", s.uuid, r.RemoteAddr, time.Now().UnixNano()))
+		}
+		w.Header().Set("SID", sid)
+		w.Header().Set("TIMEOUT", "Second-1800")
+	case "UNSUBSCRIBE":
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// serveControl runs the SOAP actions on the services
+func (s *server) serveControl(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var env soapEnvelope
+	err := xml.NewDecoder(r.Body).Decode(&env)
+	if err != nil {
+		http.Error(w, "Bad SOAP request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	action := env.Body.Action.XMLName
+	args := map[string]string{}
+	for _, arg := range env.Body.Action.Args {
+		args[arg.XMLName.Local] = arg.Value
+	}
+	fs.Debugf(nil, "DLNA action %s#%s %v", action.Space, action.Local, args)
+	var out []soapArg
+	service := findServiceType(action.Space)
+	if service == nil {
+		err = errInvalidAction
+	} else {
+		out, err = service.action(s, r, action.Local, args)
+	}
+	w.Header().Set("EXT", "")
+	if err != nil {
+		fs.Debugf(nil, "DLNA action %s failed: %v", action.Local, err)
+		w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write(soapFault(err))
+		return
+	}
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	_, _ = w.Write(soapResponse(action.Space, action.Local, out))
+}
+
+// serveResource serves the media files with support for range
+// requests
+func (s *server) serveResource(w http.ResponseWriter, r *http.Request) {
+	remote := strings.TrimPrefix(r.URL.Path, resPath)
+	node, err := s.vfs.Stat(remote)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	file, ok := node.(*vfs.File)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	mimeType := mimeTypeFromName(remote)
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("contentFeatures.dlna.org", dlnaContentFeatures)
+	if strings.HasPrefix(mimeType, "image/") {
+		w.Header().Set("transferMode.dlna.org", "Interactive")
+	} else {
+		w.Header().Set("transferMode.dlna.org", "Streaming")
+	}
+	in, err := file.Open(os.O_RDONLY)
+	if err != nil {
+		fs.Errorf(remote, "Failed to open file: %v", err)
+		http.Error(w, "Failed to open file", http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		err := in.Close()
+		if err != nil {
+			fs.Errorf(remote, "Failed to close file: %v", err)
+		}
+	}()
+	http.ServeContent(w, r, remote, node.ModTime(), in)
+}
+
+// xmlEscape escapes s for use in XML text or attributes
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
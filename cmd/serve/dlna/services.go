@@ -0,0 +1,244 @@
+package dlna
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strconv"
+)
+
+// scpdArgument is an argument of an action in a service description
+type scpdArgument struct {
+	Name                 string `xml:"name"`
+	Direction            string `xml:"direction"`
+	RelatedStateVariable string `xml:"relatedStateVariable"`
+}
+
+// scpdAction is an action in a service description
+type scpdAction struct {
+	Name      string         `xml:"name"`
+	Arguments []scpdArgument `xml:"argumentList>argument"`
+}
+
+// scpdStateVariable is a state variable in a service description
+type scpdStateVariable struct {
+	SendEvents    string   `xml:"sendEvents,attr"`
+	Name          string   `xml:"name"`
+	DataType      string   `xml:"dataType"`
+	AllowedValues []string `xml:"allowedValueList>allowedValue,omitempty"`
+}
+
+// scpd is a service description
+type scpd struct {
+	XMLName        xml.Name            `xml:"urn:schemas-upnp-org:service-1-0 scpd"`
+	SpecVersion    specVersion         `xml:"specVersion"`
+	Actions        []scpdAction        `xml:"actionList>action"`
+	StateVariables []scpdStateVariable `xml:"serviceStateTable>stateVariable"`
+}
+
+// in makes an input argument
+func in(name, stateVariable string) scpdArgument {
+	return scpdArgument{Name: name, Direction: "in", RelatedStateVariable: stateVariable}
+}
+
+// out makes an output argument
+func out(name, stateVariable string) scpdArgument {
+	return scpdArgument{Name: name, Direction: "out", RelatedStateVariable: stateVariable}
+}
+
+// stateVariable makes a state variable which isn't evented
+func stateVariable(name, dataType string, allowedValues ...string) scpdStateVariable {
+	return scpdStateVariable{SendEvents: "no", Name: name, DataType: dataType, AllowedValues: allowedValues}
+}
+
+// service is a UPnP service implemented by the server
+type service struct {
+	name        string // used in the URLs
+	serviceType string
+	serviceID   string
+	scpd        scpd
+	action      func(s *server, r *http.Request, action string, args map[string]string) ([]soapArg, error)
+}
+
+// services are the services implemented by the media server
+var services = []*service{
+	{
+		name:        "ContentDirectory",
+		serviceType: "urn:schemas-upnp-org:service:ContentDirectory:1",
+		serviceID:   "urn:upnp-org:serviceId:ContentDirectory",
+		scpd: scpd{
+			SpecVersion: specVersion{Major: 1, Minor: 0},
+			Actions: []scpdAction{
+				{Name: "GetSearchCapabilities", Arguments: []scpdArgument{out("SearchCaps", "SearchCapabilities")}},
+				{Name: "GetSortCapabilities", Arguments: []scpdArgument{out("SortCaps", "SortCapabilities")}},
+				{Name: "GetSystemUpdateID", Arguments: []scpdArgument{out("Id", "SystemUpdateID")}},
+				{Name: "Browse", Arguments: []scpdArgument{
+					in("ObjectID", "A_ARG_TYPE_ObjectID"),
+					in("BrowseFlag", "A_ARG_TYPE_BrowseFlag"),
+					in("Filter", "A_ARG_TYPE_Filter"),
+					in("StartingIndex", "A_ARG_TYPE_Index"),
+					in("RequestedCount", "A_ARG_TYPE_Count"),
+					in("SortCriteria", "A_ARG_TYPE_SortCriteria"),
+					out("Result", "A_ARG_TYPE_Result"),
+					out("NumberReturned", "A_ARG_TYPE_Count"),
+					out("TotalMatches", "A_ARG_TYPE_Count"),
+					out("UpdateID", "A_ARG_TYPE_UpdateID"),
+				}},
+			},
+			StateVariables: []scpdStateVariable{
+				stateVariable("SearchCapabilities", "string"),
+				stateVariable("SortCapabilities", "string"),
+				{SendEvents: "yes", Name: "SystemUpdateID", DataType: "ui4"},
+				stateVariable("A_ARG_TYPE_ObjectID", "string"),
+				stateVariable("A_ARG_TYPE_BrowseFlag", "string", "BrowseMetadata", "BrowseDirectChildren"),
+				stateVariable("A_ARG_TYPE_Filter", "string"),
+				stateVariable("A_ARG_TYPE_Index", "ui4"),
+				stateVariable("A_ARG_TYPE_Count", "ui4"),
+				stateVariable("A_ARG_TYPE_SortCriteria", "string"),
+				stateVariable("A_ARG_TYPE_Result", "string"),
+				stateVariable("A_ARG_TYPE_UpdateID", "ui4"),
+			},
+		},
+		action: (*server).contentDirectoryAction,
+	},
+	{
+		name:        "ConnectionManager",
+		serviceType: "urn:schemas-upnp-org:service:ConnectionManager:1",
+		serviceID:   "urn:upnp-org:serviceId:ConnectionManager",
+		scpd: scpd{
+			SpecVersion: specVersion{Major: 1, Minor: 0},
+			Actions: []scpdAction{
+				{Name: "GetProtocolInfo", Arguments: []scpdArgument{
+					out("Source", "SourceProtocolInfo"),
+					out("Sink", "SinkProtocolInfo"),
+				}},
+				{Name: "GetCurrentConnectionIDs", Arguments: []scpdArgument{
+					out("ConnectionIDs", "CurrentConnectionIDs"),
+				}},
+				{Name: "GetCurrentConnectionInfo", Arguments: []scpdArgument{
+					in("ConnectionID", "A_ARG_TYPE_ConnectionID"),
+					out("RcsID", "A_ARG_TYPE_RcsID"),
+					out("AVTransportID", "A_ARG_TYPE_AVTransportID"),
+					out("ProtocolInfo", "A_ARG_TYPE_ProtocolInfo"),
+					out("PeerConnectionManager", "A_ARG_TYPE_ConnectionManager"),
+					out("PeerConnectionID", "A_ARG_TYPE_ConnectionID"),
+					out("Direction", "A_ARG_TYPE_Direction"),
+					out("Status", "A_ARG_TYPE_ConnectionStatus"),
+				}},
+			},
+			StateVariables: []scpdStateVariable{
+				{SendEvents: "yes", Name: "SourceProtocolInfo", DataType: "string"},
+				{SendEvents: "yes", Name: "SinkProtocolInfo", DataType: "string"},
+				{SendEvents: "yes", Name: "CurrentConnectionIDs", DataType: "string"},
+				stateVariable("A_ARG_TYPE_ConnectionStatus", "string", "OK", "ContentFormatMismatch", "InsufficientBandwidth", "UnreliableChannel", "Unknown"),
+				stateVariable("A_ARG_TYPE_ConnectionManager", "string"),
+				stateVariable("A_ARG_TYPE_Direction", "string", "Input", "Output"),
+				stateVariable("A_ARG_TYPE_ProtocolInfo", "string"),
+				stateVariable("A_ARG_TYPE_ConnectionID", "i4"),
+				stateVariable("A_ARG_TYPE_AVTransportID", "i4"),
+				stateVariable("A_ARG_TYPE_RcsID", "i4"),
+			},
+		},
+		action: (*server).connectionManagerAction,
+	},
+	{
+		// Needed by Xbox consoles
+		name:        "X_MS_MediaReceiverRegistrar",
+		serviceType: "urn:microsoft.com:service:X_MS_MediaReceiverRegistrar:1",
+		serviceID:   "urn:microsoft.com:serviceId:X_MS_MediaReceiverRegistrar",
+		scpd: scpd{
+			SpecVersion: specVersion{Major: 1, Minor: 0},
+			Actions: []scpdAction{
+				{Name: "IsAuthorized", Arguments: []scpdArgument{
+					in("DeviceID", "A_ARG_TYPE_DeviceID"),
+					out("Result", "A_ARG_TYPE_Result"),
+				}},
+				{Name: "IsValidated", Arguments: []scpdArgument{
+					in("DeviceID", "A_ARG_TYPE_DeviceID"),
+					out("Result", "A_ARG_TYPE_Result"),
+				}},
+				{Name: "RegisterDevice", Arguments: []scpdArgument{
+					in("RegistrationReqMsg", "A_ARG_TYPE_RegistrationReqMsg"),
+					out("RegistrationRespMsg", "A_ARG_TYPE_RegistrationRespMsg"),
+				}},
+			},
+			StateVariables: []scpdStateVariable{
+				stateVariable("A_ARG_TYPE_DeviceID", "string"),
+				stateVariable("A_ARG_TYPE_Result", "int"),
+				stateVariable("A_ARG_TYPE_RegistrationReqMsg", "bin.base64"),
+				stateVariable("A_ARG_TYPE_RegistrationRespMsg", "bin.base64"),
+			},
+		},
+		action: (*server).mediaReceiverRegistrarAction,
+	},
+}
+
+// findService finds the service with the name given or nil
+func findService(name string) *service {
+	for _, service := range services {
+		if service.name == name {
+			return service
+		}
+	}
+	return nil
+}
+
+// findServiceType finds the service with the serviceType given or nil
+func findServiceType(serviceType string) *service {
+	for _, service := range services {
+		if service.serviceType == serviceType {
+			return service
+		}
+	}
+	return nil
+}
+
+// contentDirectoryAction runs the ContentDirectory actions
+func (s *server) contentDirectoryAction(r *http.Request, action string, args map[string]string) ([]soapArg, error) {
+	switch action {
+	case "GetSearchCapabilities":
+		return []soapArg{{"SearchCaps", ""}}, nil
+	case "GetSortCapabilities":
+		return []soapArg{{"SortCaps", "dc:title"}}, nil
+	case "GetSystemUpdateID":
+		return []soapArg{{"Id", strconv.FormatUint(uint64(s.updateID), 10)}}, nil
+	case "Browse":
+		return s.browse(r, args)
+	}
+	return nil, errInvalidAction
+}
+
+// connectionManagerAction runs the ConnectionManager actions
+func (s *server) connectionManagerAction(r *http.Request, action string, args map[string]string) ([]soapArg, error) {
+	switch action {
+	case "GetProtocolInfo":
+		return []soapArg{{"Source", "http-get:*:*:*"}, {"Sink", ""}}, nil
+	case "GetCurrentConnectionIDs":
+		return []soapArg{{"ConnectionIDs", "0"}}, nil
+	case "GetCurrentConnectionInfo":
+		if args["ConnectionID"] != "0" {
+			return nil, &upnpError{706, "Invalid connection reference"}
+		}
+		return []soapArg{
+			{"RcsID", "-1"},
+			{"AVTransportID", "-1"},
+			{"ProtocolInfo", ""},
+			{"PeerConnectionManager", ""},
+			{"PeerConnectionID", "-1"},
+			{"Direction", "Output"},
+			{"Status", "OK"},
+		}, nil
+	}
+	return nil, errInvalidAction
+}
+
+// mediaReceiverRegistrarAction runs the X_MS_MediaReceiverRegistrar
+// actions, allowing all devices
+func (s *server) mediaReceiverRegistrarAction(r *http.Request, action string, args map[string]string) ([]soapArg, error) {
+	switch action {
+	case "IsAuthorized", "IsValidated":
+		return []soapArg{{"Result", "1"}}, nil
+	case "RegisterDevice":
+		return []soapArg{{"RegistrationRespMsg", ""}}, nil
+	}
+	return nil, errInvalidAction
+}
@@ -0,0 +1,79 @@
+package dlna
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+// soapEnvelope is a SOAP request for a UPnP action
+type soapEnvelope struct {
+	Body struct {
+		Action struct {
+			XMLName xml.Name
+			Args    []struct {
+				XMLName xml.Name
+				Value   string `xml:",chardata"`
+			} `xml:",any"`
+		} `xml:",any"`
+	} `xml:"Body"`
+}
+
+// soapArg is an output argument of an action
+type soapArg struct {
+	name  string
+	value string
+}
+
+// upnpError is an error returned from an action
+type upnpError struct {
+	code int
+	desc string
+}
+
+// Error satisfies the error interface
+func (e *upnpError) Error() string {
+	return fmt.Sprintf("UPnP error %d: %s", e.code, e.desc)
+}
+
+// UPnP errors returned from the actions
+var (
+	errInvalidAction   = &upnpError{401, "Invalid Action"}
+	errInvalidArgs     = &upnpError{402, "Invalid Args"}
+	errActionFailed    = &upnpError{501, "Action Failed"}
+	errNoSuchObject    = &upnpError{701, "No such object"}
+	errNoSuchContainer = &upnpError{710, "No such container"}
+)
+
+const soapEnvelopeStart = `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`
+
+const soapEnvelopeEnd = `</s:Body></s:Envelope>`
+
+// soapResponse makes the response to action on serviceType
+func soapResponse(serviceType, action string, args []soapArg) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(soapEnvelopeStart)
+	fmt.Fprintf(&buf, `<u:%sResponse xmlns:u="%s">`, action, xmlEscape(serviceType))
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "<%s>%s</%s>", arg.name, xmlEscape(arg.value), arg.name)
+	}
+	fmt.Fprintf(&buf, `</u:%sResponse>`, action)
+	buf.WriteString(soapEnvelopeEnd)
+	return buf.Bytes()
+}
+
+// soapFault makes the response to an action which returned err
+func soapFault(err error) []byte {
+	upnpErr, ok := err.(*upnpError)
+	if !ok {
+		upnpErr = &upnpError{errActionFailed.code, err.Error()}
+	}
+	var buf bytes.Buffer
+	buf.WriteString(soapEnvelopeStart)
+	buf.WriteString(`<s:Fault><faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring><detail>`)
+	fmt.Fprintf(&buf, `<UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>%d</errorCode><errorDescription>%s</errorDescription></UPnPError>`, upnpErr.code, xmlEscape(upnpErr.desc))
+	buf.WriteString(`</detail></s:Fault>`)
+	buf.WriteString(soapEnvelopeEnd)
+	return buf.Bytes()
+}
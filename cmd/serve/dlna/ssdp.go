@@ -0,0 +1,276 @@
+package dlna
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+const (
+	ssdpAddr   = "239.255.255.250:1900"
+	ssdpMaxAge = 1800 // seconds devices may cache the announcements for
+)
+
+// ssdpServer advertises the server with SSDP on one network interface
+// and answers searches for it
+type ssdpServer struct {
+	s         *server
+	iface     net.Interface
+	nets      []*net.IPNet // networks the interface is on
+	location  string       // URL of the root device description
+	conn      *net.UDPConn
+	groupAddr *net.UDPAddr
+	closing   chan struct{}
+	wg        sync.WaitGroup
+}
+
+// startSSDP starts advertising the server on all the multicast
+// network interfaces.
+//
+// Errors are logged rather than returned as the server can still be
+// used by devices which are told its address.
+func (s *server) startSSDP() {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		fs.Errorf(nil, "DLNA: failed to list network interfaces: %v", err)
+		return
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		ssdp, err := newSSDPServer(s, iface)
+		if err != nil {
+			fs.Debugf(nil, "DLNA: not advertising on %q: %v", iface.Name, err)
+			continue
+		}
+		fs.Debugf(nil, "DLNA: advertising %s on %q", ssdp.location, iface.Name)
+		s.ssdp = append(s.ssdp, ssdp)
+	}
+	if len(s.ssdp) == 0 {
+		fs.Errorf(nil, "DLNA: couldn't advertise on any network interfaces so devices won't be able to find the server")
+	}
+}
+
+// newSSDPServer starts advertising s on iface
+func newSSDPServer(s *server, iface net.Interface) (ssdp *ssdpServer, err error) {
+	ssdp = &ssdpServer{
+		s:       s,
+		iface:   iface,
+		closing: make(chan struct{}),
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	var ip net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.To4() == nil {
+			continue
+		}
+		if ip == nil {
+			ip = ipNet.IP
+		}
+		ssdp.nets = append(ssdp.nets, ipNet)
+	}
+	if ip == nil {
+		return nil, errors.New("no IPv4 address")
+	}
+	host, port, err := net.SplitHostPort(s.Addr())
+	if err != nil {
+		return nil, err
+	}
+	if listenIP := net.ParseIP(host); listenIP != nil && !listenIP.IsUnspecified() {
+		ip = listenIP
+	}
+	ssdp.location = "http://" + net.JoinHostPort(ip.String(), port) + rootDescPath
+	ssdp.groupAddr, err = net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return nil, err
+	}
+	ssdp.conn, err = net.ListenMulticastUDP("udp4", &iface, ssdp.groupAddr)
+	if err != nil {
+		return nil, err
+	}
+	ssdp.wg.Add(2)
+	go ssdp.serve()
+	go ssdp.announce()
+	return ssdp, nil
+}
+
+// Close says goodbye to the devices and stops the server
+func (ssdp *ssdpServer) Close() {
+	close(ssdp.closing)
+	ssdp.notifyAll("ssdp:byebye")
+	_ = ssdp.conn.Close()
+	ssdp.wg.Wait()
+}
+
+// notificationTypes returns the types the server is advertised as
+func (s *server) notificationTypes() []string {
+	nts := []string{"upnp:rootdevice", "uuid:" + s.uuid, mediaServerDeviceType}
+	for _, service := range services {
+		nts = append(nts, service.serviceType)
+	}
+	return nts
+}
+
+// usn returns the unique service name for the notification type nt
+func (s *server) usn(nt string) string {
+	if nt == "uuid:"+s.uuid {
+		return nt
+	}
+	return "uuid:" + s.uuid + "::" + nt
+}
+
+// serverHeader is the SERVER header sent with the SSDP messages
+var serverHeader = fmt.Sprintf("%s/1.0 UPnP/1.0 rclone/%s", runtime.GOOS, fs.Version)
+
+// makeNotify makes a NOTIFY message with the nts (ssdp:alive or
+// ssdp:byebye) given for the notification type nt
+func (ssdp *ssdpServer) makeNotify(nts, nt string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("NOTIFY * HTTP/1.1\r\n")
+	fmt.Fprintf(&buf, "HOST: %s\r\n", ssdpAddr)
+	fmt.Fprintf(&buf, "NT: %s\r\n", nt)
+	fmt.Fprintf(&buf, "NTS: %s\r\n", nts)
+	fmt.Fprintf(&buf, "USN: %s\r\n", ssdp.s.usn(nt))
+	if nts == "ssdp:alive" {
+		fmt.Fprintf(&buf, "CACHE-CONTROL: max-age=%d\r\n", ssdpMaxAge)
+		fmt.Fprintf(&buf, "LOCATION: %s\r\n", ssdp.location)
+		fmt.Fprintf(&buf, "SERVER: %s\r\n", serverHeader)
+	}
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// makeSearchResponse makes the response to an M-SEARCH for st
+func (ssdp *ssdpServer) makeSearchResponse(st string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("HTTP/1.1 200 OK\r\n")
+	fmt.Fprintf(&buf, "CACHE-CONTROL: max-age=%d\r\n", ssdpMaxAge)
+	fmt.Fprintf(&buf, "DATE: %s\r\n", time.Now().UTC().Format(http.TimeFormat))
+	buf.WriteString("EXT:\r\n")
+	fmt.Fprintf(&buf, "LOCATION: %s\r\n", ssdp.location)
+	fmt.Fprintf(&buf, "SERVER: %s\r\n", serverHeader)
+	fmt.Fprintf(&buf, "ST: %s\r\n", st)
+	fmt.Fprintf(&buf, "USN: %s\r\n", ssdp.s.usn(st))
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// searchTargets returns the notification types which match the
+// search target st
+func (s *server) searchTargets(st string) []string {
+	nts := s.notificationTypes()
+	if st == "ssdp:all" {
+		return nts
+	}
+	for _, nt := range nts {
+		if nt == st {
+			return []string{nt}
+		}
+	}
+	return nil
+}
+
+// notifyAll sends a NOTIFY with nts for each notification type
+func (ssdp *ssdpServer) notifyAll(nts string) {
+	for _, nt := range ssdp.s.notificationTypes() {
+		_, err := ssdp.conn.WriteToUDP(ssdp.makeNotify(nts, nt), ssdp.groupAddr)
+		if err != nil {
+			fs.Debugf(nil, "DLNA: failed to send %s on %q: %v", nts, ssdp.iface.Name, err)
+			return
+		}
+	}
+}
+
+// announce sends the alive notifications every AnnounceInterval
+func (ssdp *ssdpServer) announce() {
+	defer ssdp.wg.Done()
+	ticker := time.NewTicker(ssdp.s.opt.AnnounceInterval)
+	defer ticker.Stop()
+	for {
+		ssdp.notifyAll("ssdp:alive")
+		select {
+		case <-ssdp.closing:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// onNetwork returns true if ip is on one of the interface's networks
+func (ssdp *ssdpServer) onNetwork(ip net.IP) bool {
+	for _, ipNet := range ssdp.nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// serve answers M-SEARCH requests until the server is closed
+func (ssdp *ssdpServer) serve() {
+	defer ssdp.wg.Done()
+	buf := make([]byte, 2048)
+	for {
+		n, addr, err := ssdp.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-ssdp.closing:
+			default:
+				fs.Errorf(nil, "DLNA: failed to read SSDP on %q: %v", ssdp.iface.Name, err)
+			}
+			return
+		}
+		// The socket receives the searches from all the interfaces
+		// so only answer the ones from this interface's networks
+		if !ssdp.onNetwork(addr.IP) {
+			continue
+		}
+		req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(buf[:n])))
+		if err != nil || req.Method != "M-SEARCH" || req.Header.Get("MAN") != `"ssdp:discover"` {
+			continue
+		}
+		sts := ssdp.s.searchTargets(req.Header.Get("ST"))
+		if len(sts) == 0 {
+			continue
+		}
+		// Reply after a random delay up to MX seconds as requested
+		mx, err := strconv.Atoi(req.Header.Get("MX"))
+		if err != nil || mx < 1 {
+			mx = 1
+		} else if mx > 5 {
+			mx = 5
+		}
+		delay := time.Duration(rand.Int63n(int64(mx) * int64(time.Second)))
+		ssdp.wg.Add(1)
+		go func() {
+			defer ssdp.wg.Done()
+			select {
+			case <-ssdp.closing:
+				return
+			case <-time.After(delay):
+			}
+			for _, st := range sts {
+				_, err := ssdp.conn.WriteToUDP(ssdp.makeSearchResponse(st), addr)
+				if err != nil {
+					fs.Debugf(nil, "DLNA: failed to answer search from %v: %v", addr, err)
+					return
+				}
+			}
+		}()
+	}
+}
@@ -0,0 +1,893 @@
+package ftp
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/vfs"
+	"github.com/pkg/errors"
+)
+
+const (
+	maxLineLength   = 4096             // longest command line accepted
+	idleTimeout     = 15 * time.Minute // close control connections idle this long
+	dataConnTimeout = 30 * time.Second // how long to wait for a data connection
+	mlstTimeFormat  = "20060102150405" // time format used by MDTM and MLST
+)
+
+// conn is a single FTP control connection
+type conn struct {
+	s          *server
+	ctrl       net.Conn      // the control connection
+	r          *bufio.Reader // reading from ctrl
+	what       string        // description for logging
+	user       string        // user name sent with USER
+	loggedIn   bool          // set when logged in
+	cwd        string        // current working directory - always absolute
+	renameFrom string        // path from RNFR
+	restOffset int64         // offset from REST for the next transfer
+	tls        bool          // set if the control connection is using TLS
+	protP      bool          // set if the data connections should use TLS
+	passive    net.Listener  // listener for the passive data connection
+	activeAddr string        // address for the active data connection
+	quit       bool          // set to close the connection
+}
+
+// newConn makes a conn to serve the control connection nConn
+func newConn(s *server, nConn net.Conn) *conn {
+	c := &conn{
+		s:    s,
+		ctrl: nConn,
+		r:    bufio.NewReaderSize(nConn, maxLineLength),
+		what: fmt.Sprintf("serve ftp %s->%s", nConn.RemoteAddr(), nConn.LocalAddr()),
+		cwd:  "/",
+	}
+	return c
+}
+
+// command describes an FTP command
+type command struct {
+	fn        func(c *conn, arg string)
+	needLogin bool // command may only be used when logged in
+	needArg   bool // command needs an argument
+}
+
+// commands is the table of the supported FTP commands
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"ABOR": {fn: (*conn).handleABOR},
+		"ALLO": {fn: (*conn).handleALLO},
+		"APPE": {fn: (*conn).handleAPPE, needLogin: true, needArg: true},
+		"AUTH": {fn: (*conn).handleAUTH, needArg: true},
+		"CDUP": {fn: (*conn).handleCDUP, needLogin: true},
+		"CWD":  {fn: (*conn).handleCWD, needLogin: true, needArg: true},
+		"DELE": {fn: (*conn).handleDELE, needLogin: true, needArg: true},
+		"EPRT": {fn: (*conn).handleEPRT, needLogin: true, needArg: true},
+		"EPSV": {fn: (*conn).handleEPSV, needLogin: true},
+		"FEAT": {fn: (*conn).handleFEAT},
+		"LIST": {fn: (*conn).handleLIST, needLogin: true},
+		"MDTM": {fn: (*conn).handleMDTM, needLogin: true, needArg: true},
+		"MFMT": {fn: (*conn).handleMFMT, needLogin: true, needArg: true},
+		"MKD":  {fn: (*conn).handleMKD, needLogin: true, needArg: true},
+		"MLSD": {fn: (*conn).handleMLSD, needLogin: true},
+		"MLST": {fn: (*conn).handleMLST, needLogin: true},
+		"MODE": {fn: (*conn).handleMODE, needArg: true},
+		"NLST": {fn: (*conn).handleNLST, needLogin: true},
+		"NOOP": {fn: (*conn).handleNOOP},
+		"OPTS": {fn: (*conn).handleOPTS, needArg: true},
+		"PASS": {fn: (*conn).handlePASS},
+		"PASV": {fn: (*conn).handlePASV, needLogin: true},
+		"PBSZ": {fn: (*conn).handlePBSZ, needArg: true},
+		"PORT": {fn: (*conn).handlePORT, needLogin: true, needArg: true},
+		"PROT": {fn: (*conn).handlePROT, needArg: true},
+		"PWD":  {fn: (*conn).handlePWD, needLogin: true},
+		"QUIT": {fn: (*conn).handleQUIT},
+		"REST": {fn: (*conn).handleREST, needLogin: true, needArg: true},
+		"RETR": {fn: (*conn).handleRETR, needLogin: true, needArg: true},
+		"RMD":  {fn: (*conn).handleRMD, needLogin: true, needArg: true},
+		"RNFR": {fn: (*conn).handleRNFR, needLogin: true, needArg: true},
+		"RNTO": {fn: (*conn).handleRNTO, needLogin: true, needArg: true},
+		"SIZE": {fn: (*conn).handleSIZE, needLogin: true, needArg: true},
+		"STOR": {fn: (*conn).handleSTOR, needLogin: true, needArg: true},
+		"STRU": {fn: (*conn).handleSTRU, needArg: true},
+		"SYST": {fn: (*conn).handleSYST},
+		"TYPE": {fn: (*conn).handleTYPE, needArg: true},
+		"USER": {fn: (*conn).handleUSER, needArg: true},
+	}
+	// Obsolete aliases still sent by some clients
+	commands["XCUP"] = commands["CDUP"]
+	commands["XCWD"] = commands["CWD"]
+	commands["XMKD"] = commands["MKD"]
+	commands["XPWD"] = commands["PWD"]
+	commands["XRMD"] = commands["RMD"]
+}
+
+// serve reads commands from the control connection and runs them
+// until the client quits or disconnects
+func (c *conn) serve() {
+	fs.Infof(c.what, "FTP connection opened")
+	defer func() {
+		c.closeDataConn()
+		_ = c.ctrl.Close()
+		fs.Infof(c.what, "FTP connection closed")
+	}()
+	c.reply(220, "rclone FTP server ready")
+	for !c.quit {
+		_ = c.ctrl.SetReadDeadline(time.Now().Add(idleTimeout))
+		line, err := c.r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			// Skip the rest of the line
+			for err == bufio.ErrBufferFull {
+				_, err = c.r.ReadSlice('\n')
+			}
+			c.reply(500, "Command line too long")
+			continue
+		}
+		if err != nil {
+			if err != io.EOF {
+				fs.Debugf(c.what, "Failed to read command: %v", err)
+			}
+			return
+		}
+		verb, arg := parseCommand(string(line))
+		if verb == "" {
+			continue
+		}
+		if verb == "PASS" {
+			fs.Debugf(c.what, "< PASS ****")
+		} else {
+			fs.Debugf(c.what, "< %s %s", verb, arg)
+		}
+		cmd, ok := commands[verb]
+		switch {
+		case !ok:
+			c.reply(502, fmt.Sprintf("Command %q not implemented", verb))
+		case cmd.needLogin && !c.loggedIn:
+			c.reply(530, "Please login with USER and PASS")
+		case cmd.needArg && arg == "":
+			c.reply(501, fmt.Sprintf("%s needs an argument", verb))
+		default:
+			cmd.fn(c, arg)
+		}
+	}
+}
+
+// parseCommand splits a command line into its upper case verb and
+// its argument
+func parseCommand(line string) (verb, arg string) {
+	line = strings.TrimRight(line, "\r\n")
+	if i := strings.IndexByte(line, ' '); i >= 0 {
+		verb, arg = line[:i], line[i+1:]
+	} else {
+		verb = line
+	}
+	return strings.ToUpper(verb), arg
+}
+
+// reply sends a single line reply to the client
+func (c *conn) reply(code int, message string) {
+	c.replyLines(code, message)
+}
+
+// replyLines sends a reply to the client, using the multi-line form
+// if there is more than one line
+func (c *conn) replyLines(code int, lines ...string) {
+	var buf bytes.Buffer
+	for i, line := range lines {
+		switch {
+		case len(lines) == 1:
+			fmt.Fprintf(&buf, "%d %s\r\n", code, line)
+		case i == 0:
+			fmt.Fprintf(&buf, "%d-%s\r\n", code, line)
+		case i == len(lines)-1:
+			fmt.Fprintf(&buf, "%d %s\r\n", code, line)
+		default:
+			fmt.Fprintf(&buf, " %s\r\n", line)
+		}
+	}
+	fs.Debugf(c.what, "> %d %s", code, lines[len(lines)-1])
+	_, err := c.ctrl.Write(buf.Bytes())
+	if err != nil {
+		fs.Debugf(c.what, "Failed to send reply: %v", err)
+		c.quit = true
+	}
+}
+
+// replyError sends the error as a reply with code
+func (c *conn) replyError(code int, err error) {
+	fs.Debugf(c.what, "Error: %v", err)
+	c.reply(code, strings.Replace(err.Error(), "\n", " ", -1))
+}
+
+// buildPath returns the absolute path of p relative to the current
+// working directory
+func (c *conn) buildPath(p string) string {
+	if !strings.HasPrefix(p, "/") {
+		p = path.Join(c.cwd, p)
+	}
+	return path.Clean("/" + p)
+}
+
+// quote quotes p for use in a 257 reply
+func quote(p string) string {
+	return `"` + strings.Replace(p, `"`, `""`, -1) + `"`
+}
+
+// stat returns the node at the path p relative to the current
+// working directory
+func (c *conn) stat(p string) (vfs.Node, error) {
+	return c.s.vfs.Stat(c.buildPath(p))
+}
+
+func (c *conn) handleUSER(arg string) {
+	if c.s.tlsConfig != nil && !c.tls {
+		fs.Debugf(c.what, "Logging in without TLS")
+	}
+	c.user = arg
+	c.loggedIn = false
+	c.reply(331, "User name okay, need password")
+}
+
+func (c *conn) handlePASS(arg string) {
+	if c.user == "" {
+		c.reply(503, "Login with USER first")
+		return
+	}
+	if !c.s.checkLogin(c.user, arg) {
+		fs.Infof(c.what, "Login failed for user %q", c.user)
+		c.reply(530, "Incorrect user name or password")
+		return
+	}
+	fs.Infof(c.what, "Logged in as %q", c.user)
+	c.loggedIn = true
+	c.reply(230, "User logged in, proceed")
+}
+
+func (c *conn) handleAUTH(arg string) {
+	if c.s.tlsConfig == nil {
+		c.reply(502, "TLS is not configured on this server")
+		return
+	}
+	if c.tls {
+		c.reply(503, "Already using TLS")
+		return
+	}
+	switch strings.ToUpper(arg) {
+	case "TLS", "TLS-C", "SSL":
+	default:
+		c.reply(504, fmt.Sprintf("Unsupported AUTH type %q", arg))
+		return
+	}
+	c.reply(234, "AUTH command OK, starting TLS")
+	tlsConn := tls.Server(c.ctrl, c.s.tlsConfig)
+	err := tlsConn.Handshake()
+	if err != nil {
+		fs.Errorf(c.what, "TLS handshake failed: %v", err)
+		c.quit = true
+		return
+	}
+	c.ctrl = tlsConn
+	c.r = bufio.NewReaderSize(tlsConn, maxLineLength)
+	c.tls = true
+}
+
+func (c *conn) handlePBSZ(arg string) {
+	if !c.tls {
+		c.reply(503, "PBSZ needs AUTH first")
+		return
+	}
+	c.reply(200, "PBSZ=0")
+}
+
+func (c *conn) handlePROT(arg string) {
+	switch strings.ToUpper(arg) {
+	case "C":
+		c.protP = false
+	case "P":
+		if !c.tls {
+			c.reply(503, "PROT P needs AUTH first")
+			return
+		}
+		c.protP = true
+	default:
+		c.reply(504, fmt.Sprintf("Unsupported protection level %q", arg))
+		return
+	}
+	c.reply(200, "Protection level set")
+}
+
+func (c *conn) handleFEAT(arg string) {
+	features := []string{
+		"Extensions supported:",
+		"EPRT",
+		"EPSV",
+		"MDTM",
+		"MFMT",
+		"MLST type*;size*;modify*;",
+		"PASV",
+		"REST STREAM",
+		"SIZE",
+		"UTF8",
+	}
+	if c.s.tlsConfig != nil {
+		features = append(features, "AUTH TLS", "PBSZ", "PROT")
+	}
+	features = append(features, "End")
+	c.replyLines(211, features...)
+}
+
+func (c *conn) handleOPTS(arg string) {
+	if strings.ToUpper(arg) == "UTF8 ON" || strings.ToUpper(arg) == "UTF8" {
+		c.reply(200, "UTF8 mode is always enabled")
+		return
+	}
+	c.reply(501, fmt.Sprintf("Unsupported option %q", arg))
+}
+
+func (c *conn) handleSYST(arg string) {
+	c.reply(215, "UNIX Type: L8")
+}
+
+func (c *conn) handleNOOP(arg string) {
+	c.reply(200, "OK")
+}
+
+func (c *conn) handleALLO(arg string) {
+	c.reply(202, "No storage allocation necessary")
+}
+
+func (c *conn) handleABOR(arg string) {
+	// Transfers are finished before the next command is read so
+	// there is never anything to abort
+	c.closeDataConn()
+	c.reply(226, "No transfer to abort")
+}
+
+func (c *conn) handleQUIT(arg string) {
+	c.reply(221, "Goodbye")
+	c.quit = true
+}
+
+func (c *conn) handleTYPE(arg string) {
+	// All transfers are done as binary
+	fields := strings.Fields(strings.ToUpper(arg))
+	if len(fields) == 0 {
+		c.reply(501, "TYPE needs an argument")
+		return
+	}
+	switch fields[0] {
+	case "A", "I", "L":
+		c.reply(200, "Type set")
+	default:
+		c.reply(504, fmt.Sprintf("Unsupported type %q", arg))
+	}
+}
+
+func (c *conn) handleMODE(arg string) {
+	if strings.ToUpper(arg) != "S" {
+		c.reply(504, "Only stream mode is supported")
+		return
+	}
+	c.reply(200, "Mode set to stream")
+}
+
+func (c *conn) handleSTRU(arg string) {
+	if strings.ToUpper(arg) != "F" {
+		c.reply(504, "Only file structure is supported")
+		return
+	}
+	c.reply(200, "Structure set to file")
+}
+
+func (c *conn) handlePWD(arg string) {
+	c.reply(257, quote(c.cwd)+" is the current directory")
+}
+
+func (c *conn) handleCWD(arg string) {
+	p := c.buildPath(arg)
+	node, err := c.s.vfs.Stat(p)
+	if err != nil {
+		c.replyError(550, err)
+		return
+	}
+	if node.IsFile() {
+		c.reply(550, fmt.Sprintf("%q is not a directory", p))
+		return
+	}
+	c.cwd = p
+	c.reply(250, "Directory changed to "+p)
+}
+
+func (c *conn) handleCDUP(arg string) {
+	c.handleCWD("..")
+}
+
+func (c *conn) handleMKD(arg string) {
+	p := c.buildPath(arg)
+	dir, leaf, err := c.s.vfs.StatParent(p)
+	if err != nil {
+		c.replyError(550, err)
+		return
+	}
+	_, err = dir.Mkdir(leaf)
+	if err != nil {
+		c.replyError(550, err)
+		return
+	}
+	c.reply(257, quote(p)+" created")
+}
+
+func (c *conn) handleRMD(arg string) {
+	node, err := c.stat(arg)
+	if err != nil {
+		c.replyError(550, err)
+		return
+	}
+	if node.IsFile() {
+		c.reply(550, fmt.Sprintf("%q is not a directory", arg))
+		return
+	}
+	err = node.Remove()
+	if err != nil {
+		c.replyError(550, err)
+		return
+	}
+	c.reply(250, "Directory removed")
+}
+
+func (c *conn) handleDELE(arg string) {
+	node, err := c.stat(arg)
+	if err != nil {
+		c.replyError(550, err)
+		return
+	}
+	if !node.IsFile() {
+		c.reply(550, fmt.Sprintf("%q is a directory", arg))
+		return
+	}
+	err = node.Remove()
+	if err != nil {
+		c.replyError(550, err)
+		return
+	}
+	c.reply(250, "File removed")
+}
+
+func (c *conn) handleRNFR(arg string) {
+	_, err := c.stat(arg)
+	if err != nil {
+		c.replyError(550, err)
+		return
+	}
+	c.renameFrom = c.buildPath(arg)
+	c.reply(350, "Ready for RNTO")
+}
+
+func (c *conn) handleRNTO(arg string) {
+	if c.renameFrom == "" {
+		c.reply(503, "RNFR needed first")
+		return
+	}
+	from := c.renameFrom
+	c.renameFrom = ""
+	err := c.s.vfs.Rename(from, c.buildPath(arg))
+	if err != nil {
+		c.replyError(550, err)
+		return
+	}
+	c.reply(250, "Renamed")
+}
+
+func (c *conn) handleSIZE(arg string) {
+	node, err := c.stat(arg)
+	if err != nil {
+		c.replyError(550, err)
+		return
+	}
+	if !node.IsFile() {
+		c.reply(550, fmt.Sprintf("%q is not a file", arg))
+		return
+	}
+	c.reply(213, strconv.FormatInt(node.Size(), 10))
+}
+
+func (c *conn) handleMDTM(arg string) {
+	node, err := c.stat(arg)
+	if err != nil {
+		c.replyError(550, err)
+		return
+	}
+	c.reply(213, node.ModTime().UTC().Format(mlstTimeFormat))
+}
+
+func (c *conn) handleMFMT(arg string) {
+	fields := strings.SplitN(arg, " ", 2)
+	if len(fields) != 2 {
+		c.reply(501, "MFMT needs a time and a path")
+		return
+	}
+	modTime, err := time.Parse(mlstTimeFormat, fields[0])
+	if err != nil {
+		c.replyError(501, err)
+		return
+	}
+	node, err := c.stat(fields[1])
+	if err != nil {
+		c.replyError(550, err)
+		return
+	}
+	err = node.SetModTime(modTime)
+	if err != nil {
+		c.replyError(550, err)
+		return
+	}
+	c.reply(213, fmt.Sprintf("Modify=%s; %s", fields[0], fields[1]))
+}
+
+func (c *conn) handleREST(arg string) {
+	offset, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || offset < 0 {
+		c.reply(501, fmt.Sprintf("Bad offset %q", arg))
+		return
+	}
+	c.restOffset = offset
+	c.reply(350, fmt.Sprintf("Restarting at %d", offset))
+}
+
+// closeDataConn forgets any data connection set up with PASV, EPSV,
+// PORT or EPRT
+func (c *conn) closeDataConn() {
+	if c.passive != nil {
+		_ = c.passive.Close()
+		c.passive = nil
+	}
+	c.activeAddr = ""
+}
+
+// remoteIP returns the IP address of the client
+func (c *conn) remoteIP() net.IP {
+	if addr, ok := c.ctrl.RemoteAddr().(*net.TCPAddr); ok {
+		return addr.IP
+	}
+	return nil
+}
+
+// listenPassive starts listening for a passive data connection
+func (c *conn) listenPassive() (port int, ok bool) {
+	c.closeDataConn()
+	host, _, err := net.SplitHostPort(c.ctrl.LocalAddr().String())
+	if err != nil {
+		c.replyError(425, err)
+		return 0, false
+	}
+	l, err := c.s.listenPassive(host)
+	if err != nil {
+		c.replyError(425, err)
+		return 0, false
+	}
+	c.passive = l
+	return l.Addr().(*net.TCPAddr).Port, true
+}
+
+func (c *conn) handlePASV(arg string) {
+	var ip net.IP
+	if c.s.opt.PublicIP != "" {
+		ip = net.ParseIP(c.s.opt.PublicIP).To4()
+	} else if addr, ok := c.ctrl.LocalAddr().(*net.TCPAddr); ok {
+		ip = addr.IP.To4()
+	}
+	if ip == nil {
+		c.reply(425, "PASV needs IPv4 - use EPSV")
+		return
+	}
+	port, ok := c.listenPassive()
+	if !ok {
+		return
+	}
+	c.reply(227, fmt.Sprintf("Entering Passive Mode (%d,%d,%d,%d,%d,%d)", ip[0], ip[1], ip[2], ip[3], port>>8, port&0xFF))
+}
+
+func (c *conn) handleEPSV(arg string) {
+	if strings.ToUpper(arg) == "ALL" {
+		c.reply(200, "EPSV ALL OK")
+		return
+	}
+	port, ok := c.listenPassive()
+	if !ok {
+		return
+	}
+	c.reply(229, fmt.Sprintf("Entering Extended Passive Mode (|||%d|)", port))
+}
+
+// setActive sets the address for an active data connection checking
+// it is on the client
+func (c *conn) setActive(ip net.IP, port int) {
+	c.closeDataConn()
+	if !ip.Equal(c.remoteIP()) {
+		c.reply(504, "Data connections may only be made to the client")
+		return
+	}
+	if port <= 0 || port > 65535 {
+		c.reply(501, "Bad port")
+		return
+	}
+	c.activeAddr = net.JoinHostPort(ip.String(), strconv.Itoa(port))
+	c.reply(200, "Data connection address set")
+}
+
+func (c *conn) handlePORT(arg string) {
+	parts := strings.Split(arg, ",")
+	if len(parts) != 6 {
+		c.reply(501, "Bad PORT argument")
+		return
+	}
+	var nums [6]byte
+	for i, part := range parts {
+		n, err := strconv.ParseUint(strings.TrimSpace(part), 10, 8)
+		if err != nil {
+			c.reply(501, "Bad PORT argument")
+			return
+		}
+		nums[i] = byte(n)
+	}
+	ip := net.IPv4(nums[0], nums[1], nums[2], nums[3])
+	c.setActive(ip, int(nums[4])<<8|int(nums[5]))
+}
+
+func (c *conn) handleEPRT(arg string) {
+	// Format is <d><proto><d><address><d><port><d>
+	if len(arg) < 1 {
+		c.reply(501, "Bad EPRT argument")
+		return
+	}
+	parts := strings.Split(arg, arg[:1])
+	if len(parts) != 5 {
+		c.reply(501, "Bad EPRT argument")
+		return
+	}
+	ip := net.ParseIP(parts[2])
+	port, err := strconv.Atoi(parts[3])
+	if ip == nil || err != nil {
+		c.reply(501, "Bad EPRT argument")
+		return
+	}
+	c.setActive(ip, port)
+}
+
+// openDataConn opens the data connection set up with PASV, EPSV,
+// PORT or EPRT, replying with an error if it fails
+func (c *conn) openDataConn() (dataConn net.Conn, ok bool) {
+	var err error
+	switch {
+	case c.passive != nil:
+		l := c.passive.(*net.TCPListener)
+		c.passive = nil
+		defer func() {
+			_ = l.Close()
+		}()
+		_ = l.SetDeadline(time.Now().Add(dataConnTimeout))
+		for {
+			dataConn, err = l.Accept()
+			if err != nil {
+				c.replyError(425, errors.Wrap(err, "failed to accept data connection"))
+				return nil, false
+			}
+			// Only accept data connections from the client
+			if addr, ok := dataConn.RemoteAddr().(*net.TCPAddr); ok && addr.IP.Equal(c.remoteIP()) {
+				break
+			}
+			fs.Errorf(c.what, "Rejecting data connection from %v", dataConn.RemoteAddr())
+			_ = dataConn.Close()
+		}
+	case c.activeAddr != "":
+		addr := c.activeAddr
+		c.activeAddr = ""
+		dataConn, err = net.DialTimeout("tcp", addr, dataConnTimeout)
+		if err != nil {
+			c.replyError(425, errors.Wrap(err, "failed to open data connection"))
+			return nil, false
+		}
+	default:
+		c.reply(425, "Use PASV, EPSV, PORT or EPRT first")
+		return nil, false
+	}
+	if c.protP {
+		tlsConn := tls.Server(dataConn, c.s.tlsConfig)
+		err = tlsConn.Handshake()
+		if err != nil {
+			_ = dataConn.Close()
+			c.replyError(425, errors.Wrap(err, "TLS handshake on data connection failed"))
+			return nil, false
+		}
+		dataConn = tlsConn
+	}
+	return dataConn, true
+}
+
+// transfer opens the data connection, calls fn with it then closes
+// it and sends the final reply
+func (c *conn) transfer(fn func(dataConn net.Conn) error) {
+	c.reply(150, "Opening data connection")
+	dataConn, ok := c.openDataConn()
+	if !ok {
+		return
+	}
+	err := fn(dataConn)
+	closeErr := dataConn.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		c.replyError(426, err)
+		return
+	}
+	c.reply(226, "Transfer complete")
+}
+
+func (c *conn) handleRETR(arg string) {
+	offset := c.restOffset
+	c.restOffset = 0
+	handle, err := c.s.vfs.OpenFile(c.buildPath(arg), os.O_RDONLY, 0)
+	if err != nil {
+		c.replyError(550, err)
+		return
+	}
+	if offset > 0 {
+		_, err = handle.Seek(offset, os.SEEK_SET)
+		if err != nil {
+			_ = handle.Close()
+			c.replyError(550, err)
+			return
+		}
+	}
+	c.transfer(func(dataConn net.Conn) error {
+		_, err := io.Copy(dataConn, handle)
+		closeErr := handle.Close()
+		if err == nil {
+			err = closeErr
+		}
+		return err
+	})
+}
+
+// store receives a file to p opened with flags
+func (c *conn) store(arg string, flags int) {
+	offset := c.restOffset
+	c.restOffset = 0
+	if offset == 0 && flags&os.O_APPEND == 0 {
+		flags |= os.O_TRUNC
+	}
+	handle, err := c.s.vfs.OpenFile(c.buildPath(arg), flags, 0777)
+	if err != nil {
+		c.replyError(550, err)
+		return
+	}
+	if offset > 0 {
+		_, err = handle.Seek(offset, os.SEEK_SET)
+		if err != nil {
+			_ = handle.Close()
+			c.replyError(550, err)
+			return
+		}
+	}
+	c.transfer(func(dataConn net.Conn) error {
+		_, err := io.Copy(handle, dataConn)
+		closeErr := handle.Close()
+		if err == nil {
+			err = closeErr
+		}
+		return err
+	})
+}
+
+func (c *conn) handleSTOR(arg string) {
+	c.store(arg, os.O_WRONLY|os.O_CREATE)
+}
+
+func (c *conn) handleAPPE(arg string) {
+	c.store(arg, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+}
+
+// listArg returns the path from the argument of a list command,
+// ignoring any ls style flags
+func listArg(arg string) string {
+	for _, field := range strings.Fields(arg) {
+		if !strings.HasPrefix(field, "-") {
+			return arg[strings.Index(arg, field):]
+		}
+	}
+	return ""
+}
+
+// list lists the directory in arg, or the file if it isn't a
+// directory, formatting each entry with format
+func (c *conn) list(arg string, format func(fi os.FileInfo) string) {
+	node, err := c.stat(listArg(arg))
+	if err != nil {
+		c.replyError(550, err)
+		return
+	}
+	var nodes []os.FileInfo
+	if dir, ok := node.(*vfs.Dir); ok {
+		entries, err := dir.ReadDirAll()
+		if err != nil {
+			c.replyError(550, err)
+			return
+		}
+		for _, entry := range entries {
+			nodes = append(nodes, entry)
+		}
+	} else {
+		nodes = append(nodes, node)
+	}
+	c.transfer(func(dataConn net.Conn) error {
+		w := bufio.NewWriter(dataConn)
+		for _, fi := range nodes {
+			_, err := w.WriteString(format(fi) + "\r\n")
+			if err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	})
+}
+
+// lsLine formats fi like ls -l
+func lsLine(fi os.FileInfo) string {
+	modTime := fi.ModTime()
+	timeFormat := "Jan _2 15:04"
+	if age := time.Since(modTime); age > 180*24*time.Hour || age < -time.Hour {
+		timeFormat = "Jan _2  2006"
+	}
+	return fmt.Sprintf("%s 1 ftp ftp %12d %s %s", fi.Mode().String(), fi.Size(), modTime.Format(timeFormat), fi.Name())
+}
+
+// mlstFacts formats fi as RFC 3659 facts
+func mlstFacts(fi os.FileInfo) string {
+	fileType := "file"
+	if fi.IsDir() {
+		fileType = "dir"
+	}
+	return fmt.Sprintf("type=%s;size=%d;modify=%s; %s", fileType, fi.Size(), fi.ModTime().UTC().Format(mlstTimeFormat), fi.Name())
+}
+
+func (c *conn) handleLIST(arg string) {
+	c.list(arg, lsLine)
+}
+
+func (c *conn) handleNLST(arg string) {
+	c.list(arg, func(fi os.FileInfo) string { return fi.Name() })
+}
+
+func (c *conn) handleMLSD(arg string) {
+	node, err := c.stat(arg)
+	if err == nil && node.IsFile() {
+		c.reply(501, fmt.Sprintf("%q is not a directory", arg))
+		return
+	}
+	c.list(arg, mlstFacts)
+}
+
+func (c *conn) handleMLST(arg string) {
+	p := c.buildPath(arg)
+	node, err := c.s.vfs.Stat(p)
+	if err != nil {
+		c.replyError(550, err)
+		return
+	}
+	facts := mlstFacts(node)
+	facts = facts[:strings.Index(facts, " ")+1] + p
+	c.replyLines(250, "Listing "+p, facts, "End")
+}
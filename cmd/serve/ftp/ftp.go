@@ -0,0 +1,114 @@
+// Package ftp implements an FTP server to serve an rclone VFS
+package ftp
+
+import (
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/ncw/rclone/vfs"
+	"github.com/ncw/rclone/vfs/vfsflags"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Options contains options for the FTP server
+type Options struct {
+	ListenAddr   string // Port to listen on
+	PublicIP     string // IP to tell the client to connect to in passive mode
+	PassivePorts string // Range of ports to use for passive data connections
+	User         string // single username
+	Pass         string // password for user - any password if empty
+	TLSCert      string // path to TLS PEM certificate to enable FTPS
+	TLSKey       string // path to TLS PEM private key
+}
+
+// DefaultOpt is the default values used for Options
+var DefaultOpt = Options{
+	ListenAddr:   "localhost:2121",
+	PassivePorts: "30000-32000",
+	User:         "anonymous",
+}
+
+// Opt is options set by command line flags
+var Opt = DefaultOpt
+
+// AddFlags adds flags for the ftp server
+func AddFlags(flagSet *pflag.FlagSet, Opt *Options) {
+	flags.StringVarP(flagSet, &Opt.ListenAddr, "addr", "", Opt.ListenAddr, "IPaddress:Port or :Port to bind server to.")
+	flags.StringVarP(flagSet, &Opt.PublicIP, "public-ip", "", Opt.PublicIP, "Public IP address to advertise for passive connections.")
+	flags.StringVarP(flagSet, &Opt.PassivePorts, "passive-port", "", Opt.PassivePorts, "Passive port range to use.")
+	flags.StringVarP(flagSet, &Opt.User, "user", "", Opt.User, "User name for authentication.")
+	flags.StringVarP(flagSet, &Opt.Pass, "pass", "", Opt.Pass, "Password for authentication. (empty value allow every password)")
+	flags.StringVarP(flagSet, &Opt.TLSCert, "cert", "", Opt.TLSCert, "TLS PEM key (concatenation of certificate and CA certificate)")
+	flags.StringVarP(flagSet, &Opt.TLSKey, "key", "", Opt.TLSKey, "TLS PEM Private key")
+}
+
+func init() {
+	vfsflags.AddFlags(Command.Flags())
+	AddFlags(Command.Flags(), &Opt)
+}
+
+// Command definition for cobra
+var Command = &cobra.Command{
+	Use:   "ftp remote:path",
+	Short: `Serve remote:path over FTP.`,
+	Long: `
+rclone serve ftp implements a basic FTP server to serve the
+remote over FTP protocol. This can be viewed with an FTP client
+or you can make a remote of type ftp to read and write it.
+
+This is useful for devices such as cameras, scanners and other
+embedded devices which can only upload files with FTP.
+
+### Server options
+
+Use --addr to specify which IP address and port the server should
+listen on, eg --addr 1.2.3.4:8000 or --addr :8080 to listen to all
+IPs.  By default it only listens on localhost.  You can use port
+:0 to let the OS choose an available port.
+
+If you set --addr to listen on a public or LAN accessible IP address
+then using Authentication is advised - see the next section for info.
+
+The data connections use passive mode by default, with the server
+listening on a port from the range set with --passive-port, which is
+"30000-32000" by default.  If the server is behind NAT then open these
+ports in the firewall and set --public-ip to the address the clients
+should connect to.  Active mode (PORT and EPRT) is also supported but
+only to the address the client connected from.
+
+#### Authentication
+
+By default this will serve files to the user "anonymous" with any
+password.  You can set a single username and password with the --user
+and --pass flags.
+
+#### FTPS
+
+If you set --cert and --key then the server will allow clients to
+upgrade the connection to TLS with AUTH TLS (explicit FTPS) and to
+encrypt the data connections with PROT P.
+
+--cert should be a either a PEM encoded certificate or a concatenation
+of that with the CA certificate.  --key should be the PEM encoded
+private key.
+
+Files are uploaded in sequence so the default "--vfs-cache-mode off"
+works for normal uploads, but appending or resuming uploads with REST
+needs "--vfs-cache-mode writes".
+` + vfs.Help,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		f := cmd.NewFsSrc(args)
+		cmd.Run(false, false, command, func() error {
+			s := newServer(f, &Opt)
+			err := s.Serve()
+			if err != nil {
+				return err
+			}
+			fs.Logf(f, "FTP server listening on %v", s.Addr())
+			s.Wait()
+			return nil
+		})
+	},
+}
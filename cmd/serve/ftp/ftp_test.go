@@ -0,0 +1,265 @@
+package ftp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	ftpclient "github.com/jlaffaye/ftp"
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testUser = "testuser"
+	testPass = "testpass"
+)
+
+// startServer starts a server serving a new temporary directory,
+// returning it and a function to clean up
+func startServer(t *testing.T, opt Options) (s *server, dir string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "rclone-serve-ftp")
+	require.NoError(t, err)
+	root := filepath.Join(dir, "root")
+	require.NoError(t, os.Mkdir(root, 0777))
+	f, err := fs.NewFs(root)
+	require.NoError(t, err)
+
+	opt.ListenAddr = "localhost:0"
+	opt.User = testUser
+	opt.Pass = testPass
+	s = newServer(f, &opt)
+	require.NoError(t, s.Serve())
+	return s, dir, func() {
+		s.Close()
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+func TestFTP(t *testing.T) {
+	s, _, cleanup := startServer(t, DefaultOpt)
+	defer cleanup()
+
+	c, err := ftpclient.Dial(s.Addr())
+	require.NoError(t, err)
+	defer func() {
+		_ = c.Quit()
+	}()
+
+	// Check commands are refused before login
+	assert.Error(t, c.MakeDir("/dir"))
+	assert.Error(t, c.Login(testUser, "wrong"))
+	require.NoError(t, c.Login(testUser, testPass))
+
+	// Make a directory and write a file into it
+	require.NoError(t, c.MakeDir("/dir"))
+	require.NoError(t, c.ChangeDir("dir"))
+	cwd, err := c.CurrentDir()
+	require.NoError(t, err)
+	assert.Equal(t, "/dir", cwd)
+	require.NoError(t, c.Stor("file.txt", bytes.NewBufferString("hello world")))
+
+	size, err := c.FileSize("/dir/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), size)
+
+	// Read it back, whole and from an offset
+	r, err := c.Retr("file.txt")
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, "hello world", string(data))
+
+	r, err = c.RetrFrom("file.txt", 6)
+	require.NoError(t, err)
+	data, err = ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, "world", string(data))
+
+	// List with MLSD
+	entries, err := c.List("/dir")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, "file.txt", entries[0].Name)
+	assert.Equal(t, uint64(11), entries[0].Size)
+	assert.Equal(t, ftpclient.EntryTypeFile, entries[0].Type)
+
+	// Rename, then list the names with PASV rather than EPSV
+	require.NoError(t, c.Rename("file.txt", "/dir/file2.txt"))
+	c.DisableEPSV = true
+	names, err := c.NameList("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"file2.txt"}, names)
+
+	// Remove the file and the directory
+	require.NoError(t, c.ChangeDirToParent())
+	assert.Error(t, c.RemoveDir("/dir/file2.txt"))
+	assert.Error(t, c.Delete("/dir"))
+	require.NoError(t, c.Delete("/dir/file2.txt"))
+	require.NoError(t, c.RemoveDir("dir"))
+	entries, err = c.List("/")
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(entries))
+}
+
+func TestPassivePorts(t *testing.T) {
+	// Find a free port to use as the only passive port
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+
+	opt := DefaultOpt
+	opt.PassivePorts = strconv.Itoa(port)
+	opt.PublicIP = "1.2.3.4"
+	s, _, cleanup := startServer(t, opt)
+	defer cleanup()
+
+	c, err := textproto.Dial("tcp", s.Addr())
+	require.NoError(t, err)
+	defer func() {
+		_ = c.Close()
+	}()
+	_, _, err = c.ReadResponse(220)
+	require.NoError(t, err)
+	login(t, c)
+
+	wantPASV := "Entering Passive Mode (1,2,3,4," + strconv.Itoa(port>>8) + "," + strconv.Itoa(port&0xFF) + ")"
+	_, msg, err := sendCmd(c, 227, "PASV")
+	require.NoError(t, err)
+	assert.Equal(t, wantPASV, msg)
+	_, msg, err = sendCmd(c, 229, "EPSV")
+	require.NoError(t, err)
+	assert.Equal(t, "Entering Extended Passive Mode (|||"+strconv.Itoa(port)+"|)", msg)
+}
+
+func TestParsePortRange(t *testing.T) {
+	for _, test := range []struct {
+		in         string
+		start, end int
+		err        bool
+	}{
+		{"30000-32000", 30000, 32000, false},
+		{"2121", 2121, 2121, false},
+		{"32000-30000", 0, 0, true},
+		{"1-70000", 0, 0, true},
+		{"potato", 0, 0, true},
+	} {
+		start, end, err := parsePortRange(test.in)
+		assert.Equal(t, test.err, err != nil, test.in)
+		assert.Equal(t, test.start, start, test.in)
+		assert.Equal(t, test.end, end, test.in)
+	}
+}
+
+// sendCmd sends a command and reads the response
+func sendCmd(c *textproto.Conn, expectCode int, format string, args ...interface{}) (int, string, error) {
+	_, err := c.Cmd(format, args...)
+	if err != nil {
+		return 0, "", err
+	}
+	return c.ReadResponse(expectCode)
+}
+
+// login logs in to the server
+func login(t *testing.T, c *textproto.Conn) {
+	_, _, err := sendCmd(c, 331, "USER %s", testUser)
+	require.NoError(t, err)
+	_, _, err = sendCmd(c, 230, "PASS %s", testPass)
+	require.NoError(t, err)
+}
+
+// makeCert makes a self signed certificate and key for localhost in
+// dir returning their paths
+func makeCert(t *testing.T, dir string) (certPath, keyPath string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")},
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certPath = filepath.Join(dir, "cert.pem")
+	keyPath = filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600))
+	return certPath, keyPath
+}
+
+func TestFTPS(t *testing.T) {
+	certDir, err := ioutil.TempDir("", "rclone-serve-ftp-cert")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(certDir))
+	}()
+	opt := DefaultOpt
+	opt.TLSCert, opt.TLSKey = makeCert(t, certDir)
+	s, dir, cleanup := startServer(t, opt)
+	defer cleanup()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "root", "file.txt"), []byte("hello"), 0600))
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	nConn, err := net.Dial("tcp", s.Addr())
+	require.NoError(t, err)
+	c := textproto.NewConn(nConn)
+	_, _, err = c.ReadResponse(220)
+	require.NoError(t, err)
+	_, msg, err := sendCmd(c, 211, "FEAT")
+	require.NoError(t, err)
+	assert.Contains(t, msg, "AUTH TLS")
+
+	// Upgrade the control connection
+	_, _, err = sendCmd(c, 234, "AUTH TLS")
+	require.NoError(t, err)
+	tlsConn := tls.Client(nConn, tlsConfig)
+	require.NoError(t, tlsConn.Handshake())
+	c = textproto.NewConn(tlsConn)
+	defer func() {
+		_ = c.Close()
+	}()
+	login(t, c)
+	_, _, err = sendCmd(c, 200, "PBSZ 0")
+	require.NoError(t, err)
+	_, _, err = sendCmd(c, 200, "PROT P")
+	require.NoError(t, err)
+
+	// Read a file over an encrypted data connection
+	_, msg, err = sendCmd(c, 229, "EPSV")
+	require.NoError(t, err)
+	port := msg[strings.Index(msg, "|||")+3 : strings.LastIndex(msg, "|")]
+	host, _, err := net.SplitHostPort(s.Addr())
+	require.NoError(t, err)
+	_, _, err = sendCmd(c, 150, "RETR file.txt")
+	require.NoError(t, err)
+	dataConn, err := tls.Dial("tcp", net.JoinHostPort(host, port), tlsConfig)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(dataConn)
+	require.NoError(t, err)
+	require.NoError(t, dataConn.Close())
+	assert.Equal(t, "hello", string(data))
+	_, _, err = c.ReadResponse(226)
+	require.NoError(t, err)
+}
@@ -0,0 +1,151 @@
+package ftp
+
+import (
+	"crypto/tls"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/vfs"
+	"github.com/ncw/rclone/vfs/vfsflags"
+	"github.com/pkg/errors"
+)
+
+// server contains everything to run the server
+type server struct {
+	f         fs.Fs
+	opt       Options
+	vfs       *vfs.VFS
+	tlsConfig *tls.Config // set if FTPS is enabled
+	pasvStart int         // first port in the passive range
+	pasvEnd   int         // last port in the passive range
+	listener  net.Listener
+	waitChan  chan struct{} // for waiting on the listener to close
+	wg        sync.WaitGroup
+}
+
+// newServer makes a new FTP server for f
+func newServer(f fs.Fs, opt *Options) *server {
+	s := &server{
+		f:        f,
+		vfs:      vfs.New(f, &vfsflags.Opt),
+		opt:      *opt,
+		waitChan: make(chan struct{}),
+	}
+	return s
+}
+
+// parsePortRange parses a port range like "30000-32000"
+func parsePortRange(portRange string) (start, end int, err error) {
+	parts := strings.SplitN(portRange, "-", 2)
+	start, err = strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, errors.Errorf("bad passive port range %q", portRange)
+	}
+	end = start
+	if len(parts) == 2 {
+		end, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return 0, 0, errors.Errorf("bad passive port range %q", portRange)
+		}
+	}
+	if start < 0 || end > 65535 || start > end {
+		return 0, 0, errors.Errorf("bad passive port range %q", portRange)
+	}
+	return start, end, nil
+}
+
+// Serve starts the server listening in the background
+func (s *server) Serve() (err error) {
+	s.pasvStart, s.pasvEnd, err = parsePortRange(s.opt.PassivePorts)
+	if err != nil {
+		return err
+	}
+	if s.opt.PublicIP != "" {
+		ip := net.ParseIP(s.opt.PublicIP)
+		if ip == nil || ip.To4() == nil {
+			return errors.Errorf("--public-ip must be an IPv4 address, not %q", s.opt.PublicIP)
+		}
+	}
+	if s.opt.TLSCert != "" || s.opt.TLSKey != "" {
+		cert, err := tls.LoadX509KeyPair(s.opt.TLSCert, s.opt.TLSKey)
+		if err != nil {
+			return errors.Wrap(err, "failed to load TLS certificate")
+		}
+		s.tlsConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+		}
+	}
+	s.listener, err = net.Listen("tcp", s.opt.ListenAddr)
+	if err != nil {
+		return errors.Wrap(err, "failed to listen for connection")
+	}
+	go s.acceptConnections()
+	return nil
+}
+
+// Addr returns the address the server is listening on
+func (s *server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Wait blocks until the server is closed
+func (s *server) Wait() {
+	<-s.waitChan
+}
+
+// Close stops the server listening and waits for the connections to
+// finish
+func (s *server) Close() {
+	err := s.listener.Close()
+	if err != nil {
+		fs.Errorf(nil, "Error on closing FTP server: %v", err)
+		return
+	}
+	s.wg.Wait()
+}
+
+// acceptConnections accepts connections until the listener is closed
+func (s *server) acceptConnections() {
+	defer close(s.waitChan)
+	for {
+		nConn, err := s.listener.Accept()
+		if err != nil {
+			if strings.Contains(err.Error(), "use of closed network connection") {
+				return
+			}
+			fs.Errorf(nil, "Failed to accept incoming connection: %v", err)
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			newConn(s, nConn).serve()
+		}()
+	}
+}
+
+// checkLogin returns true if user and pass may log in
+func (s *server) checkLogin(user, pass string) bool {
+	if user != s.opt.User {
+		return false
+	}
+	return s.opt.Pass == "" || pass == s.opt.Pass
+}
+
+// listenPassive listens on a free port in the passive range on host
+func (s *server) listenPassive(host string) (*net.TCPListener, error) {
+	n := s.pasvEnd - s.pasvStart + 1
+	offset := rand.Intn(n)
+	for i := 0; i < n; i++ {
+		port := s.pasvStart + (offset+i)%n
+		l, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+		if err == nil {
+			return l.(*net.TCPListener), nil
+		}
+	}
+	return nil, errors.Errorf("no free ports in passive port range %d-%d", s.pasvStart, s.pasvEnd)
+}
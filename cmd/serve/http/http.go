@@ -1,8 +1,10 @@
 package http
 
 import (
+	"archive/zip"
 	"fmt"
 	"html/template"
+	"io"
 	"net/http"
 	"os"
 	"path"
@@ -20,9 +22,14 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	zipDirs bool
+)
+
 func init() {
 	httpflags.AddFlags(Command.Flags())
 	vfsflags.AddFlags(Command.Flags())
+	Command.Flags().BoolVar(&zipDirs, "zip", false, "Allow directories to be downloaded as zip files.")
 }
 
 // Command definition for cobra
@@ -40,12 +47,32 @@ The server will log errors.  Use -v to see access logs.
 
 --bwlimit will be respected for file transfers.  Use --stats to
 control the stats printing.
+
+If remote:path points to a single file then only that file is served
+and it is returned for requests to the root of the server, so the URL
+of the server can be given out as a download link for the file.  It
+is also served at its own name, and everything else returns a 404
+error, including the --zip downloads.
+
+### Downloading directories as zip files
+
+Use --zip to allow whole directories to be downloaded as zip files.
+Each directory listing then has a link to download the directory, and
+everything in it, as a zip file.  This can also be done by adding
+` + "`?download=zip`" + ` to the URL of any directory.
+
+The zip file is made as it is downloaded, reading the files from the
+remote one after another, so downloads start straight away whatever
+the size of the directory.  The files are stored in the zip file
+uncompressed.  As the size of the zip file isn't known in advance, the
+download can't be resumed if it is interrupted.
 ` + httplib.Help + vfs.Help,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
-		f := cmd.NewFsSrc(args)
+		f, fileName := cmd.NewFsSrcMaybeFile(args)
 		cmd.Run(false, true, command, func() error {
 			s := newServer(f, &httpflags.Opt)
+			s.fileName = fileName
 			s.serve()
 			return nil
 		})
@@ -54,9 +81,10 @@ control the stats printing.
 
 // server contains everything to run the server
 type server struct {
-	f   fs.Fs
-	vfs *vfs.VFS
-	srv *httplib.Server
+	f        fs.Fs
+	vfs      *vfs.VFS
+	srv      *httplib.Server
+	fileName string // if set serve just this file at the root
 }
 
 func newServer(f fs.Fs, opt *httplib.Options) *server {
@@ -88,7 +116,17 @@ func (s *server) handler(w http.ResponseWriter, r *http.Request) {
 	urlPath := r.URL.Path
 	isDir := strings.HasSuffix(urlPath, "/")
 	remote := strings.Trim(urlPath, "/")
-	if isDir {
+	if s.fileName != "" {
+		// Serve only the file - the VFS has all the files in its
+		// directory
+		if remote != "" && (isDir || remote != s.fileName) {
+			http.Error(w, "File not found", http.StatusNotFound)
+			return
+		}
+		s.serveFile(w, r, s.fileName)
+	} else if isDir && zipDirs && r.URL.Query().Get("download") == "zip" {
+		s.serveZip(w, r, remote)
+	} else if isDir {
 		s.serveDir(w, r, remote)
 	} else {
 		s.serveFile(w, r, remote)
@@ -130,7 +168,8 @@ var indexPage = `<!DOCTYPE html>
 </head>
 <body>
 <h1>{{ .Title }}</h1>
-{{ range $i := .Entries }}<a href="{{ $i.URL }}">{{ $i.Leaf }}</a><br />
+{{ if .Zip }}<a href="?download=zip">Download as zip</a><br />
+{{ end }}{{ range $i := .Entries }}<a href="{{ $i.URL }}">{{ $i.Leaf }}</a><br />
 {{ end }}</body>
 </html>
 `
@@ -142,6 +181,7 @@ var indexTemplate = template.Must(template.New("index").Parse(indexPage))
 type indexData struct {
 	Title   string
 	Entries entries
+	Zip     bool
 }
 
 // error returns an http.StatusInternalServerError and logs the error
@@ -151,22 +191,31 @@ func internalError(what interface{}, w http.ResponseWriter, text string, err err
 	http.Error(w, text+".", http.StatusInternalServerError)
 }
 
-// serveDir serves a directory index at dirRemote
-func (s *server) serveDir(w http.ResponseWriter, r *http.Request, dirRemote string) {
-	// List the directory
+// findDir finds the directory at dirRemote, writing an error and
+// returning nil if it can't
+func (s *server) findDir(w http.ResponseWriter, dirRemote string) *vfs.Dir {
 	node, err := s.vfs.Stat(dirRemote)
 	if err == vfs.ENOENT {
 		http.Error(w, "Directory not found", http.StatusNotFound)
-		return
+		return nil
 	} else if err != nil {
 		internalError(dirRemote, w, "Failed to list directory", err)
-		return
+		return nil
 	}
 	if !node.IsDir() {
 		http.Error(w, "Not a directory", http.StatusNotFound)
+		return nil
+	}
+	return node.(*vfs.Dir)
+}
+
+// serveDir serves a directory index at dirRemote
+func (s *server) serveDir(w http.ResponseWriter, r *http.Request, dirRemote string) {
+	// List the directory
+	dir := s.findDir(w, dirRemote)
+	if dir == nil {
 		return
 	}
-	dir := node.(*vfs.Dir)
 	dirEntries, err := dir.ReadDirAll()
 	if err != nil {
 		internalError(dirRemote, w, "Failed to list directory", err)
@@ -186,6 +235,7 @@ func (s *server) serveDir(w http.ResponseWriter, r *http.Request, dirRemote stri
 	err = indexTemplate.Execute(w, indexData{
 		Entries: out,
 		Title:   fmt.Sprintf("Directory listing of /%s", dirRemote),
+		Zip:     zipDirs,
 	})
 	if err != nil {
 		internalError(dirRemote, w, "Failed to render template", err)
@@ -253,3 +303,88 @@ func (s *server) serveFile(w http.ResponseWriter, r *http.Request, remote string
 	// Serve the file
 	http.ServeContent(w, r, remote, node.ModTime(), in)
 }
+
+// serveZip serves the directory at dirRemote and everything in it as
+// a zip file made as it is sent
+func (s *server) serveZip(w http.ResponseWriter, r *http.Request, dirRemote string) {
+	dir := s.findDir(w, dirRemote)
+	if dir == nil {
+		return
+	}
+	zipName := path.Base(path.Join(s.f.Root(), dirRemote))
+	if zipName == "." || zipName == "/" || zipName == "" {
+		zipName = s.f.Name()
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", zipName+".zip"))
+
+	// If HEAD no need to make the zip file
+	if r.Method == "HEAD" {
+		return
+	}
+
+	fs.Infof(dirRemote, "%s: Serving directory as zip", r.RemoteAddr)
+	zw := zip.NewWriter(w)
+	err := s.zipDir(zw, dir, "")
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		// the headers have been sent so all we can do is log
+		// the error and stop sending
+		fs.CountError(err)
+		fs.Errorf(dirRemote, "Failed to send zip file: %v", err)
+	}
+}
+
+// zipDir adds the contents of dir to zw with names starting with
+// prefix
+func (s *server) zipDir(zw *zip.Writer, dir *vfs.Dir, prefix string) error {
+	nodes, err := dir.ReadDirAll()
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		name := prefix + node.Name()
+		header := &zip.FileHeader{
+			Name:   name,
+			Method: zip.Store,
+		}
+		header.SetModTime(node.ModTime())
+		if node.IsDir() {
+			header.Name += "/"
+			header.SetMode(os.ModeDir | 0755)
+			_, err = zw.CreateHeader(header)
+			if err == nil {
+				err = s.zipDir(zw, node.(*vfs.Dir), header.Name)
+			}
+		} else {
+			header.SetMode(0644)
+			err = s.zipFile(zw, header, node.(*vfs.File))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zipFile adds the contents of file to zw
+func (s *server) zipFile(zw *zip.Writer, header *zip.FileHeader, file *vfs.File) (err error) {
+	remote := file.Path()
+	out, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	in, err := file.Open(os.O_RDONLY)
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(in, &err)
+
+	// Account the transfer
+	accounting.Stats.Transferring(remote)
+	_, err = io.Copy(out, in)
+	accounting.Stats.DoneTransferring(remote, err == nil)
+	return err
+}
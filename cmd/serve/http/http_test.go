@@ -3,10 +3,13 @@
 package http
 
 import (
+	"archive/zip"
+	"bytes"
 	"flag"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"path"
 	"strings"
 	"testing"
@@ -17,6 +20,7 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/config"
 	"github.com/ncw/rclone/fs/filter"
+	"github.com/ncw/rclone/vfs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -202,6 +206,126 @@ func TestGET(t *testing.T) {
 	}
 }
 
+func TestGETZip(t *testing.T) {
+	get := func(URL string) *http.Response {
+		resp, err := http.Get(testURL + URL)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// not allowed without --zip so the listing is returned
+	resp := get("three/?download=zip")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	require.NoError(t, resp.Body.Close())
+
+	zipDirs = true
+	defer func() { zipDirs = false }()
+
+	for _, test := range []struct {
+		URL      string
+		fileName string
+		want     map[string]string
+	}{
+		{
+			URL:      "three/?download=zip",
+			fileName: "three.zip",
+			want: map[string]string{
+				"a.txt": "testdata/files/three/a.txt",
+				"b.txt": "testdata/files/three/b.txt",
+			},
+		},
+		{
+			URL:      "?download=zip",
+			fileName: "files.zip",
+			want: map[string]string{
+				"one%.txt":    "testdata/files/one%.txt",
+				"three/":      "",
+				"three/a.txt": "testdata/files/three/a.txt",
+				"three/b.txt": "testdata/files/three/b.txt",
+				"two.txt":     "testdata/files/two.txt",
+			},
+		},
+	} {
+		resp := get(test.URL)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/zip", resp.Header.Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="`+test.fileName+`"`, resp.Header.Get("Content-Disposition"))
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		require.NoError(t, err)
+		got := map[string]string{}
+		for _, file := range zr.File {
+			in, err := file.Open()
+			require.NoError(t, err)
+			contents, err := ioutil.ReadAll(in)
+			require.NoError(t, err)
+			require.NoError(t, in.Close())
+			got[file.Name] = string(contents)
+		}
+		want := map[string]string{}
+		for name, fileName := range test.want {
+			want[name] = ""
+			if fileName != "" {
+				contents, err := ioutil.ReadFile(fileName)
+				require.NoError(t, err)
+				want[name] = string(contents)
+			}
+		}
+		assert.Equal(t, want, got, test.URL)
+	}
+
+	// the listing links to the zip file
+	resp = get("three/")
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Contains(t, string(body), `<a href="?download=zip">Download as zip</a>`)
+}
+
+func TestServeSingleFile(t *testing.T) {
+	f, err := fs.NewFs("testdata/files")
+	require.NoError(t, err)
+	s := &server{
+		f:        f,
+		vfs:      vfs.New(f, nil),
+		fileName: "two.txt",
+	}
+	defer s.vfs.Shutdown()
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	s.handler(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	want, err := ioutil.ReadFile("testdata/files/two.txt")
+	require.NoError(t, err)
+	assert.Equal(t, string(want), w.Body.String())
+
+	req = httptest.NewRequest("GET", "/two.txt", nil)
+	w = httptest.NewRecorder()
+	s.handler(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, string(want), w.Body.String())
+
+	// Nothing else in the directory is served
+	oldZipDirs := zipDirs
+	zipDirs = true
+	defer func() { zipDirs = oldZipDirs }()
+	for _, url := range []string{"/hidden.txt", "/two.txt/", "/three/", "/three/?download=zip"} {
+		req = httptest.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+		s.handler(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code, url)
+	}
+	req = httptest.NewRequest("GET", "/?download=zip", nil)
+	w = httptest.NewRecorder()
+	s.handler(w, req)
+	assert.Equal(t, string(want), w.Body.String())
+}
+
 type mockNode struct {
 	path  string
 	isdir bool
@@ -8,6 +8,8 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	auth "github.com/abbot/go-http-auth"
@@ -36,6 +38,14 @@ for a transfer.
 --max-header-bytes controls the maximum number of bytes the server will
 accept in the HTTP header.
 
+--baseurl controls the URL prefix that rclone serves from.  By default
+rclone will serve from the root.  If you used --baseurl "/rclone" then
+rclone would serve from a URL starting with "/rclone/".  This is
+useful if you wish to proxy rclone serve.  Rclone automatically
+inserts leading and trailing "/" on --baseurl, so --baseurl "rclone",
+--baseurl "/rclone" and --baseurl "/rclone/" are all treated
+identically.  Requests for URLs outside the prefix get a 404 error.
+
 #### Authentication
 
 By default this will serve files without needing a login.
@@ -83,6 +93,7 @@ type Options struct {
 	Realm              string        // realm for authentication
 	BasicUser          string        // single username for basic auth if not using Htpasswd
 	BasicPass          string        // password for BasicUser
+	BaseURL            string        // prefix to strip from URLs
 }
 
 // DefaultOpt is the default values used for Options
@@ -111,6 +122,15 @@ func (s *Server) singleUserProvider(user, realm string) string {
 	return ""
 }
 
+// BaseURLHandler is an http.Handler which serves from the base URL
+// itself, for example because it needs to put it in the URLs it
+// returns.  NewServer passes it the requests unchanged and tells it
+// the base URL with SetBaseURL.
+type BaseURLHandler interface {
+	http.Handler
+	SetBaseURL(baseURL string)
+}
+
 // NewServer creates an http server.  The opt can be nil in which case
 // the default options will be used.
 func NewServer(handler http.Handler, opt *Options) *Server {
@@ -125,6 +145,14 @@ func NewServer(handler http.Handler, opt *Options) *Server {
 		s.Opt = DefaultOpt
 	}
 
+	// Serve from the base URL if required
+	s.Opt.BaseURL = cleanBaseURL(s.Opt.BaseURL)
+	if baseURLHandler, ok := handler.(BaseURLHandler); ok {
+		baseURLHandler.SetBaseURL(s.Opt.BaseURL)
+	} else if s.Opt.BaseURL != "" {
+		handler = stripBaseURL(s.Opt.BaseURL, handler)
+	}
+
 	// Use htpasswd if required on everything
 	if s.Opt.HtPasswd != "" || s.Opt.BasicUser != "" {
 		var secretProvider auth.SecretProvider
@@ -203,5 +231,43 @@ func (s *Server) URL() string {
 	if s.useSSL {
 		proto = "https"
 	}
-	return fmt.Sprintf("%s://%s/", proto, s.Opt.ListenAddr)
+	return fmt.Sprintf("%s://%s%s/", proto, s.Opt.ListenAddr, s.Opt.BaseURL)
+}
+
+// cleanBaseURL makes baseURL start with a "/" and not end with one,
+// returning "" for the root.
+func cleanBaseURL(baseURL string) string {
+	baseURL = strings.Trim(baseURL, "/")
+	if baseURL == "" {
+		return ""
+	}
+	return "/" + baseURL
+}
+
+// stripBaseURL returns a handler which serves requests for URLs
+// starting with baseURL by removing it and calling handler.  The
+// baseURL itself is redirected to baseURL + "/" and requests outside
+// it get a 404 error.
+func stripBaseURL(baseURL string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == baseURL {
+			target := baseURL + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, baseURL+"/") {
+			http.NotFound(w, r)
+			return
+		}
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = r.URL.Path[len(baseURL):]
+		r2.URL.RawPath = ""
+		handler.ServeHTTP(w, r2)
+	})
 }
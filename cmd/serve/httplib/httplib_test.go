@@ -0,0 +1,62 @@
+package httplib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanBaseURL(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"/", ""},
+		{"rclone", "/rclone"},
+		{"/rclone", "/rclone"},
+		{"/rclone/", "/rclone"},
+		{"rclone/sub/", "/rclone/sub"},
+	} {
+		assert.Equal(t, test.want, cleanBaseURL(test.in), test.in)
+	}
+}
+
+func TestStripBaseURL(t *testing.T) {
+	var gotPath string
+	handler := stripBaseURL("/rclone", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+	}))
+	for _, test := range []struct {
+		URL      string
+		status   int
+		path     string
+		location string
+	}{
+		{"/rclone/", http.StatusOK, "/", ""},
+		{"/rclone/dir/file%20name.txt", http.StatusOK, "/dir/file name.txt", ""},
+		{"/rclone", http.StatusMovedPermanently, "", "/rclone/"},
+		{"/rclone?download=zip", http.StatusMovedPermanently, "", "/rclone/?download=zip"},
+		{"/", http.StatusNotFound, "", ""},
+		{"/rclonepotato/", http.StatusNotFound, "", ""},
+	} {
+		gotPath = ""
+		req, err := http.NewRequest("GET", "http://localhost"+test.URL, nil)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, test.status, w.Code, test.URL)
+		assert.Equal(t, test.path, gotPath, test.URL)
+		assert.Equal(t, test.location, w.Header().Get("Location"), test.URL)
+	}
+}
+
+func TestURL(t *testing.T) {
+	opt := DefaultOpt
+	opt.BaseURL = "rclone/"
+	s := NewServer(http.NewServeMux(), &opt)
+	assert.Equal(t, "http://localhost:8080/rclone/", s.URL())
+}
@@ -0,0 +1,103 @@
+// MOUNT version 3 procedures as described in RFC 1813 appendix I
+
+package nfs
+
+import (
+	"strings"
+
+	"github.com/ncw/rclone/backend/nfs/nfs3"
+	"github.com/ncw/rclone/fs/log"
+	"github.com/ncw/rclone/vfs"
+)
+
+// MOUNT constants
+const (
+	mountProgram = 100005
+	mountVersion = 3
+
+	// longest path
+	mountPathLen = 1024
+
+	// mountstat3
+	mountOK        = 0
+	mountErrNoEnt  = 2
+	mountErrNotDir = 20
+)
+
+func init() {
+	programs[mountProgram] = &program{
+		name:    "MOUNT",
+		version: mountVersion,
+		procs: map[uint32]procedure{
+			0: mountNull,
+			1: mountMnt,
+			2: mountDump,
+			3: mountUmnt,
+			4: mountUmntall,
+			5: mountExport,
+		},
+	}
+}
+
+// NULL - do nothing
+func mountNull(s *Server, args *nfs3.Reader, res *nfs3.Writer) error {
+	return nil
+}
+
+// MNT - return the file handle of a directory to mount
+//
+// Any directory in the VFS can be mounted.
+func mountMnt(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	dirPath := args.StrMax(mountPathLen)
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	var status uint32 = mountOK
+	defer log.Trace(dirPath, "")("status=%d", &status)
+	name := strings.Trim(dirPath, "/")
+	node, err := s.vfs.Stat(name)
+	if err != nil {
+		status = mountErrNoEnt
+	} else if _, ok := node.(*vfs.Dir); !ok {
+		status = mountErrNotDir
+	}
+	res.Uint32(status)
+	if status != mountOK {
+		return nil
+	}
+	res.Opaque(s.fileHandle(name))
+	// Clients should use AUTH_UNIX but it is ignored
+	res.Uint32(2)
+	res.Uint32(authUnix)
+	res.Uint32(authNone)
+	return nil
+}
+
+// DUMP - return the mounts, which aren't recorded
+func mountDump(s *Server, args *nfs3.Reader, res *nfs3.Writer) error {
+	res.Bool(false)
+	return nil
+}
+
+// UMNT - remove a mount, which needs no action
+func mountUmnt(s *Server, args *nfs3.Reader, res *nfs3.Writer) error {
+	_ = args.StrMax(mountPathLen)
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	return nil
+}
+
+// UMNTALL - remove all the mounts, which needs no action
+func mountUmntall(s *Server, args *nfs3.Reader, res *nfs3.Writer) error {
+	return nil
+}
+
+// EXPORT - return the export list which is just the root
+func mountExport(s *Server, args *nfs3.Reader, res *nfs3.Writer) error {
+	res.Bool(true)
+	res.Str("/")
+	res.Bool(false) // no groups
+	res.Bool(false) // end of list
+	return nil
+}
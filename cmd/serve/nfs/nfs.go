@@ -0,0 +1,462 @@
+// Package nfs serves a remote over NFS version 3
+package nfs
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/vfs"
+	"github.com/ncw/rclone/vfs/vfsflags"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Options set by command line flags
+var (
+	Addr = "localhost:2049"
+)
+
+func init() {
+	Command.Flags().StringVarP(&Addr, "addr", "", Addr, "IPaddress:Port or :Port to bind server to.")
+	vfsflags.AddFlags(Command.Flags())
+}
+
+// Command definition for cobra
+var Command = &cobra.Command{
+	Use:   "nfs remote:path",
+	Short: `Serve remote:path over NFS.`,
+	Long: `
+rclone serve nfs implements a basic NFS version 3 server to serve the
+remote.  This is mainly so the remote can be mounted by the operating
+system's NFS client, which is what ` + "`rclone nfsmount`" + ` does.
+
+Use --addr to set the address to listen on, "localhost:2049" by
+default.  The MOUNT protocol is served on the same port so there is no
+need for a portmapper, but the client must be told the port, eg on
+macOS
+
+    mount -t nfs -o port=2049,mountport=2049,vers=3,tcp,nolocks,locallocks localhost:/ /path/to/mountpoint
+
+and on Linux
+
+    mount -t nfs -o port=2049,mountport=2049,vers=3,tcp,mountproto=tcp,nolock localhost:/ /path/to/mountpoint
+
+The server has no authentication so only serve it on localhost or on
+a trusted network.
+
+NFS writes files in pieces which may arrive out of order and files can
+be read and written at the same time, so using
+` + "`--vfs-cache-mode writes`" + ` or above is strongly recommended.
+Files are uploaded when the NFS client commits them, which it does when
+the file is closed, or when they haven't been used for a few seconds.
+` + vfs.Help,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		f := cmd.NewFsSrc(args)
+		cmd.Run(false, false, command, func() error {
+			s, err := NewServer(vfs.New(f, &vfsflags.Opt), Addr)
+			if err != nil {
+				return err
+			}
+			fs.Logf(f, "NFS Server started on %s", s.Addr())
+			return s.Serve()
+		})
+	},
+}
+
+// Server is an NFS server serving a VFS
+type Server struct {
+	vfs      *vfs.VFS
+	listener net.Listener
+	handles  *handleTable
+	files    *openFiles
+	verifier [8]byte // changes each time the server is started
+	wg       sync.WaitGroup
+	mu       sync.Mutex // protects closed
+	closed   bool
+}
+
+// NewServer makes an NFS server for VFS listening on addr
+//
+// Call Serve to start serving it.
+func NewServer(VFS *vfs.VFS, addr string) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start NFS server")
+	}
+	s := &Server{
+		vfs:      VFS,
+		listener: listener,
+		handles:  newHandleTable(),
+		files:    newOpenFiles(VFS),
+	}
+	binary.BigEndian.PutUint64(s.verifier[:], uint64(time.Now().UnixNano()))
+	return s, nil
+}
+
+// Addr returns the address the server is listening on
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Port returns the TCP port the server is listening on
+func (s *Server) Port() int {
+	if addr, ok := s.listener.Addr().(*net.TCPAddr); ok {
+		return addr.Port
+	}
+	return 0
+}
+
+// Serve serves NFS clients until the server is closed
+func (s *Server) Serve() error {
+	go s.files.closeIdle()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return nil
+			}
+			return errors.Wrap(err, "NFS server failed to accept connection")
+		}
+		s.wg.Add(1)
+		go s.serveConn(conn)
+	}
+}
+
+// isClosed returns whether Close has been called
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// Close stops the server and closes any open files, uploading any
+// which have been written to
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	err := s.listener.Close()
+	s.files.closeAll()
+	return err
+}
+
+// handleTable maps NFS file handles to paths in the VFS
+//
+// File handles are numbers handed out in order which are never
+// reused, so they stay valid while the server is running as long as
+// the file isn't removed.  The number is used as the file id too.
+type handleTable struct {
+	mu     sync.Mutex
+	byPath map[string]uint64
+	byID   map[uint64]string
+	nextID uint64
+}
+
+// rootID is the handle of the root directory
+const rootID = 1
+
+// newHandleTable makes a new handleTable containing the root
+func newHandleTable() *handleTable {
+	return &handleTable{
+		byPath: map[string]uint64{"": rootID},
+		byID:   map[uint64]string{rootID: ""},
+		nextID: rootID + 1,
+	}
+}
+
+// id returns the handle for name, making one if necessary
+func (t *handleTable) id(name string) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	id, found := t.byPath[name]
+	if !found {
+		id = t.nextID
+		t.nextID++
+		t.byPath[name] = id
+		t.byID[id] = name
+	}
+	return id
+}
+
+// path returns the path for the handle id
+func (t *handleTable) path(id uint64) (name string, found bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	name, found = t.byID[id]
+	return name, found
+}
+
+// remove forgets the handle for name
+func (t *handleTable) remove(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if id, found := t.byPath[name]; found {
+		delete(t.byPath, name)
+		delete(t.byID, id)
+	}
+}
+
+// rename moves the handles for oldName and anything under it to
+// newName so they stay valid
+func (t *handleTable) rename(oldName, newName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if id, found := t.byPath[newName]; found {
+		delete(t.byPath, newName)
+		delete(t.byID, id)
+	}
+	for name, id := range t.byPath {
+		var renamed string
+		switch {
+		case name == oldName:
+			renamed = newName
+		case strings.HasPrefix(name, oldName+"/"):
+			renamed = newName + name[len(oldName):]
+		default:
+			continue
+		}
+		delete(t.byPath, name)
+		t.byPath[renamed] = id
+		t.byID[id] = renamed
+	}
+}
+
+// joinPath joins the leaf name onto the directory path dir
+//
+// name must have been checked by readDirOp so it can't escape dir
+func joinPath(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return dir + "/" + name
+}
+
+// How long a file is left open after it was last used
+const openFileTimeout = 5 * time.Second
+
+// openFile is a VFS file handle kept open between NFS calls
+type openFile struct {
+	mu       sync.Mutex // held while the handle is in use
+	handle   vfs.Handle
+	write    bool      // set if handle can be written to
+	read     bool      // set if handle can be read from
+	closed   bool      // set if handle has been closed
+	lastUsed time.Time // when the handle was last used - protected by openFiles.mu
+}
+
+// close closes the file once any call using it has finished
+func (file *openFile) close(name string) error {
+	file.mu.Lock()
+	defer file.mu.Unlock()
+	if file.closed {
+		return nil
+	}
+	file.closed = true
+	err := file.handle.Close()
+	if err != nil {
+		fs.Errorf(name, "NFS failed to close file: %v", err)
+	}
+	return err
+}
+
+// openFiles keeps the VFS handles open between NFS calls
+//
+// NFS is stateless so has no open or close calls.  Instead the file
+// is opened when it is first read or written and closed when the
+// client commits it or when it hasn't been used for openFileTimeout.
+//
+// The lock only protects the map of files - each file has its own
+// lock so calls on different files don't wait for each other.
+type openFiles struct {
+	vfs   *vfs.VFS
+	mu    sync.Mutex
+	files map[string]*openFile
+	quit  chan struct{}
+}
+
+// newOpenFiles makes a new openFiles for VFS
+func newOpenFiles(VFS *vfs.VFS) *openFiles {
+	return &openFiles{
+		vfs:   VFS,
+		files: make(map[string]*openFile),
+		quit:  make(chan struct{}),
+	}
+}
+
+// get returns the file for name which can be written to if write is
+// set or read from otherwise, opening it if necessary
+//
+// The file is returned locked - unlock file.mu when done with it
+func (o *openFiles) get(name string, write bool) (*openFile, error) {
+	for {
+		o.mu.Lock()
+		file, found := o.files[name]
+		if !found {
+			o.mu.Unlock()
+			return o.open(name, write, false)
+		}
+		if (write && file.write) || (!write && file.read) {
+			file.lastUsed = time.Now()
+			o.mu.Unlock()
+			file.mu.Lock()
+			if !file.closed {
+				return file, nil
+			}
+			// closed while we were waiting so try again
+			file.mu.Unlock()
+			continue
+		}
+		delete(o.files, name)
+		o.mu.Unlock()
+		err := file.close(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// open opens name for writing if write is set or reading otherwise,
+// creating or truncating it if create is set
+//
+// The file is returned locked - unlock file.mu when done with it
+func (o *openFiles) open(name string, write, create bool) (*openFile, error) {
+	file := &openFile{
+		read:     !write,
+		write:    write,
+		lastUsed: time.Now(),
+	}
+	flags, perm := os.O_RDONLY, os.FileMode(0)
+	if write {
+		flags = os.O_WRONLY
+		if o.vfs.Opt.CacheMode >= vfs.CacheModeWrites {
+			flags = os.O_RDWR
+			file.read = true
+		}
+	}
+	if create {
+		flags |= os.O_CREATE | os.O_TRUNC
+		perm = 0777
+	}
+	// Put the file in the map locked so other calls wait for it to
+	// be opened without holding up calls on other files
+	file.mu.Lock()
+	o.mu.Lock()
+	old := o.files[name]
+	o.files[name] = file
+	o.mu.Unlock()
+	var err error
+	if old != nil {
+		err = old.close(name)
+	}
+	if err == nil {
+		file.handle, err = o.vfs.OpenFile(name, flags, perm)
+	}
+	if err != nil {
+		file.closed = true
+		o.mu.Lock()
+		if o.files[name] == file {
+			delete(o.files, name)
+		}
+		o.mu.Unlock()
+		file.mu.Unlock()
+		return nil, err
+	}
+	return file, nil
+}
+
+// create opens name for writing, creating or truncating it
+func (o *openFiles) create(name string) error {
+	file, err := o.open(name, true, true)
+	if err != nil {
+		return err
+	}
+	file.mu.Unlock()
+	return nil
+}
+
+// readAt reads from name at off
+func (o *openFiles) readAt(name string, p []byte, off int64) (n int, err error) {
+	file, err := o.get(name, false)
+	if err != nil {
+		return 0, err
+	}
+	defer file.mu.Unlock()
+	return file.handle.ReadAt(p, off)
+}
+
+// writeAt writes to name at off
+func (o *openFiles) writeAt(name string, p []byte, off int64) (n int, err error) {
+	file, err := o.get(name, true)
+	if err != nil {
+		return 0, err
+	}
+	defer file.mu.Unlock()
+	return file.handle.WriteAt(p, off)
+}
+
+// close closes name if it is open
+func (o *openFiles) close(name string) error {
+	o.mu.Lock()
+	file, found := o.files[name]
+	delete(o.files, name)
+	o.mu.Unlock()
+	if !found {
+		return nil
+	}
+	return file.close(name)
+}
+
+// commit closes name if it is open so any data written to it is
+// uploaded
+func (o *openFiles) commit(name string) error {
+	return o.close(name)
+}
+
+// closeIdle closes the files which haven't been used for
+// openFileTimeout until closeAll is called
+func (o *openFiles) closeIdle() {
+	ticker := time.NewTicker(openFileTimeout / 5)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-o.quit:
+			return
+		case <-ticker.C:
+		}
+		idle := map[string]*openFile{}
+		o.mu.Lock()
+		for name, file := range o.files {
+			if time.Since(file.lastUsed) > openFileTimeout {
+				idle[name] = file
+				delete(o.files, name)
+			}
+		}
+		o.mu.Unlock()
+		for name, file := range idle {
+			_ = file.close(name)
+		}
+	}
+}
+
+// closeAll closes all the open files and stops closeIdle
+func (o *openFiles) closeAll() {
+	o.mu.Lock()
+	select {
+	case <-o.quit:
+	default:
+		close(o.quit)
+	}
+	files := o.files
+	o.files = make(map[string]*openFile)
+	o.mu.Unlock()
+	for name, file := range files {
+		_ = file.close(name)
+	}
+}
@@ -0,0 +1,939 @@
+// NFS version 3 procedures as described in RFC 1813
+
+package nfs
+
+import (
+	"encoding/binary"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/backend/nfs/nfs3"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/log"
+	"github.com/ncw/rclone/vfs"
+	"github.com/pkg/errors"
+)
+
+// NFS constants
+const (
+	nfsProgram = 100003
+	nfsVersion = 3
+
+	// largest file handle
+	nfsFhSize = 64
+
+	// largest read or write
+	nfsMaxData = 1024 * 1024
+
+	// nfsstat3
+	nfsOK             = 0
+	nfsErrPerm        = 1
+	nfsErrNoEnt       = 2
+	nfsErrIO          = 5
+	nfsErrExist       = 17
+	nfsErrNotDir      = 20
+	nfsErrIsDir       = 21
+	nfsErrInval       = 22
+	nfsErrROFS        = 30
+	nfsErrNameTooLong = 63
+	nfsErrNotEmpty    = 66
+	nfsErrStale       = 70
+	nfsErrBadHandle   = 10001
+	nfsErrNotSupp     = 10004
+	nfsErrTooSmall    = 10005
+
+	// ftype3
+	nfsReg = 1
+	nfsDir = 2
+
+	// stable_how
+	nfsUnstable = 0
+	nfsFileSync = 2
+
+	// createmode3
+	nfsUnchecked = 0
+	nfsGuarded   = 1
+	nfsExclusive = 2
+
+	// time_how
+	nfsSetToServerTime = 1
+	nfsSetToClientTime = 2
+
+	// ACCESS bits
+	nfsAccessRead    = 0x0001
+	nfsAccessLookup  = 0x0002
+	nfsAccessModify  = 0x0004
+	nfsAccessExtend  = 0x0008
+	nfsAccessDelete  = 0x0010
+	nfsAccessExecute = 0x0020
+
+	// FSINFO properties
+	nfsFSFHomogeneous = 0x0008
+	nfsFSFCanSetTime  = 0x0010
+
+	// longest file name
+	nfsNameMax = 255
+)
+
+func init() {
+	programs[nfsProgram] = &program{
+		name:    "NFS",
+		version: nfsVersion,
+		procs: map[uint32]procedure{
+			0:  nfsNull,
+			1:  nfsGetattr,
+			2:  nfsSetattr,
+			3:  nfsLookup,
+			4:  nfsAccess,
+			5:  nfsReadlink,
+			6:  nfsRead,
+			7:  nfsWrite,
+			8:  nfsCreate,
+			9:  nfsMkdir,
+			10: nfsSymlink,
+			11: nfsMknod,
+			12: nfsRemove,
+			13: nfsRmdir,
+			14: nfsRename,
+			15: nfsLink,
+			16: nfsReaddir,
+			17: nfsReaddirplus,
+			18: nfsFsstat,
+			19: nfsFsinfo,
+			20: nfsPathconf,
+			21: nfsCommit,
+		},
+	}
+}
+
+// nfsStatus translates errors from the VFS into an nfsstat3
+func nfsStatus(err error) uint32 {
+	if err == nil {
+		return nfsOK
+	}
+	switch errors.Cause(err) {
+	case vfs.OK:
+		return nfsOK
+	case vfs.ENOENT:
+		return nfsErrNoEnt
+	case vfs.EEXIST:
+		return nfsErrExist
+	case vfs.EPERM:
+		return nfsErrPerm
+	case vfs.ENOTEMPTY:
+		return nfsErrNotEmpty
+	case vfs.EROFS:
+		return nfsErrROFS
+	case vfs.ENOSYS, vfs.ENOTSUP:
+		return nfsErrNotSupp
+	case vfs.EINVAL:
+		return nfsErrInval
+	}
+	return nfsErrIO
+}
+
+// fileHandle encodes the file handle for name
+func (s *Server) fileHandle(name string) []byte {
+	fh := make([]byte, 8)
+	binary.BigEndian.PutUint64(fh, s.handles.id(name))
+	return fh
+}
+
+// readHandle decodes a file handle from args returning the path it
+// refers to
+func (s *Server) readHandle(args *nfs3.Reader) (name string, status uint32) {
+	fh := args.OpaqueMax(nfsFhSize)
+	if args.Err() != nil {
+		return "", nfsOK
+	}
+	if len(fh) != 8 {
+		return "", nfsErrBadHandle
+	}
+	name, found := s.handles.path(binary.BigEndian.Uint64(fh))
+	if !found {
+		return "", nfsErrStale
+	}
+	return name, nfsOK
+}
+
+// stat finds the node for the handle name
+func (s *Server) stat(name string) (vfs.Node, uint32) {
+	node, err := s.vfs.Stat(name)
+	if err == vfs.ENOENT {
+		return nil, nfsErrStale
+	} else if err != nil {
+		return nil, nfsStatus(err)
+	}
+	return node, nfsOK
+}
+
+// readDirOp decodes diropargs3 from args returning the directory and
+// the leaf name in it
+func (s *Server) readDirOp(args *nfs3.Reader) (dirPath string, dir *vfs.Dir, leaf string, status uint32) {
+	dirPath, status = s.readHandle(args)
+	leaf = args.StrMax(nfsNameMax + 1)
+	if args.Err() != nil || status != nfsOK {
+		return "", nil, "", status
+	}
+	if len(leaf) > nfsNameMax {
+		return "", nil, "", nfsErrNameTooLong
+	}
+	if leaf == "" || strings.Contains(leaf, "/") {
+		return "", nil, "", nfsErrInval
+	}
+	node, status := s.stat(dirPath)
+	if status != nfsOK {
+		return "", nil, "", status
+	}
+	dir, ok := node.(*vfs.Dir)
+	if !ok {
+		return "", nil, "", nfsErrNotDir
+	}
+	return dirPath, dir, leaf, nfsOK
+}
+
+// readNewDirOp decodes diropargs3 from args like readDirOp for the
+// calls which make or remove the leaf so can't be given "." or ".."
+func (s *Server) readNewDirOp(args *nfs3.Reader) (dirPath string, dir *vfs.Dir, leaf string, status uint32) {
+	dirPath, dir, leaf, status = s.readDirOp(args)
+	if status == nfsOK && (leaf == "." || leaf == "..") {
+		return "", nil, "", nfsErrInval
+	}
+	return dirPath, dir, leaf, status
+}
+
+// writeTime encodes t as an nfstime3
+func writeTime(w *nfs3.Writer, t time.Time) {
+	sec := t.Unix()
+	if sec < 0 {
+		sec = 0
+	}
+	w.Uint32(uint32(sec))
+	w.Uint32(uint32(t.Nanosecond()))
+}
+
+// readTime decodes an nfstime3
+func readTime(args *nfs3.Reader) time.Time {
+	sec := args.Uint32()
+	nsec := args.Uint32()
+	return time.Unix(int64(sec), int64(nsec))
+}
+
+// writeAttr encodes the fattr3 for node which is at name
+func (s *Server) writeAttr(w *nfs3.Writer, name string, node vfs.Node) {
+	modTime := node.ModTime()
+	size := uint64(node.Size())
+	if node.IsDir() {
+		w.Uint32(nfsDir)
+		w.Uint32(uint32(s.vfs.Opt.DirPerms.Perm()))
+		w.Uint32(2)
+	} else {
+		w.Uint32(nfsReg)
+		w.Uint32(uint32(s.vfs.Opt.FilePerms.Perm()))
+		w.Uint32(1)
+	}
+	w.Uint32(s.vfs.Opt.UID)
+	w.Uint32(s.vfs.Opt.GID)
+	w.Uint64(size)               // size
+	w.Uint64(size)               // used
+	w.Uint64(0)                  // rdev
+	w.Uint64(1)                  // fsid
+	w.Uint64(s.handles.id(name)) // fileid
+	writeTime(w, modTime)        // atime
+	writeTime(w, modTime)        // mtime
+	writeTime(w, modTime)        // ctime
+}
+
+// writePostOpAttr encodes the post_op_attr for name
+func (s *Server) writePostOpAttr(w *nfs3.Writer, name string) {
+	node, err := s.vfs.Stat(name)
+	if err != nil {
+		w.Bool(false)
+		return
+	}
+	w.Bool(true)
+	s.writeAttr(w, name, node)
+}
+
+// writeWcc encodes the wcc_data for name
+//
+// The attributes from before the operation aren't sent so the client
+// will fetch them again.
+func (s *Server) writeWcc(w *nfs3.Writer, name string) {
+	w.Bool(false)
+	s.writePostOpAttr(w, name)
+}
+
+// writeNewObject encodes the results of creating name in dirPath
+func (s *Server) writeNewObject(w *nfs3.Writer, name, dirPath string) {
+	w.Uint32(nfsOK)
+	w.Bool(true)
+	w.Opaque(s.fileHandle(name))
+	s.writePostOpAttr(w, name)
+	s.writeWcc(w, dirPath)
+}
+
+// sattr is a decoded sattr3
+type sattr struct {
+	setSize    bool
+	size       uint64
+	setModTime bool
+	modTime    time.Time
+}
+
+// readSattr decodes a sattr3 ignoring the mode and owner
+func readSattr(args *nfs3.Reader) (attr sattr) {
+	if args.Bool() {
+		_ = args.Uint32() // mode
+	}
+	if args.Bool() {
+		_ = args.Uint32() // uid
+	}
+	if args.Bool() {
+		_ = args.Uint32() // gid
+	}
+	if args.Bool() {
+		attr.setSize = true
+		attr.size = args.Uint64()
+	}
+	if args.Uint32() == nfsSetToClientTime {
+		_ = readTime(args) // atime
+	}
+	switch args.Uint32() {
+	case nfsSetToServerTime:
+		attr.setModTime = true
+		attr.modTime = time.Now()
+	case nfsSetToClientTime:
+		attr.setModTime = true
+		attr.modTime = readTime(args)
+	}
+	return attr
+}
+
+// setAttr applies attr to the file name
+func (s *Server) setAttr(name string, attr sattr) error {
+	if attr.setSize {
+		node, err := s.vfs.Stat(name)
+		if err != nil {
+			return err
+		}
+		if node.IsDir() {
+			return vfs.EINVAL
+		}
+		if attr.size == 0 {
+			err = s.files.create(name)
+		} else {
+			err = node.Truncate(int64(attr.size))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if attr.setModTime {
+		node, err := s.vfs.Stat(name)
+		if err != nil {
+			return err
+		}
+		err = node.SetModTime(attr.modTime)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NULL - do nothing
+func nfsNull(s *Server, args *nfs3.Reader, res *nfs3.Writer) error {
+	return nil
+}
+
+// GETATTR - get file attributes
+func nfsGetattr(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	name, status := s.readHandle(args)
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	defer log.Trace(name, "")("status=%d", &status)
+	var node vfs.Node
+	if status == nfsOK {
+		node, status = s.stat(name)
+	}
+	res.Uint32(status)
+	if status == nfsOK {
+		s.writeAttr(res, name, node)
+	}
+	return nil
+}
+
+// SETATTR - set file attributes
+func nfsSetattr(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	name, status := s.readHandle(args)
+	attr := readSattr(args)
+	if args.Bool() {
+		_ = readTime(args) // guard ctime
+	}
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	defer log.Trace(name, "attr=%+v", attr)("status=%d", &status)
+	if status == nfsOK {
+		status = nfsStatus(s.setAttr(name, attr))
+	}
+	res.Uint32(status)
+	s.writeWcc(res, name)
+	return nil
+}
+
+// LOOKUP - look up a file name in a directory
+func nfsLookup(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	dirPath, dir, leaf, status := s.readDirOp(args)
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	defer log.Trace(dirPath, "leaf=%q", leaf)("status=%d", &status)
+	var name string
+	if status == nfsOK {
+		switch leaf {
+		case ".":
+			name = dirPath
+		case "..":
+			name = path.Dir(dirPath)
+			if name == "." {
+				name = ""
+			}
+		default:
+			name = joinPath(dirPath, leaf)
+			_, err := dir.Stat(leaf)
+			status = nfsStatus(err)
+		}
+	}
+	res.Uint32(status)
+	if status != nfsOK {
+		s.writePostOpAttr(res, dirPath)
+		return nil
+	}
+	res.Opaque(s.fileHandle(name))
+	s.writePostOpAttr(res, name)
+	s.writePostOpAttr(res, dirPath)
+	return nil
+}
+
+// ACCESS - check access permission
+func nfsAccess(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	name, status := s.readHandle(args)
+	access := args.Uint32()
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	defer log.Trace(name, "access=0x%X", access)("status=%d", &status)
+	if status == nfsOK {
+		_, status = s.stat(name)
+	}
+	res.Uint32(status)
+	s.writePostOpAttr(res, name)
+	if status == nfsOK {
+		allowed := uint32(nfsAccessRead | nfsAccessLookup | nfsAccessExecute)
+		if !s.vfs.Opt.ReadOnly {
+			allowed |= nfsAccessModify | nfsAccessExtend | nfsAccessDelete
+		}
+		res.Uint32(access & allowed)
+	}
+	return nil
+}
+
+// READLINK - symbolic links aren't supported
+func nfsReadlink(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	name, _ := s.readHandle(args)
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	res.Uint32(nfsErrNotSupp)
+	s.writePostOpAttr(res, name)
+	return nil
+}
+
+// READ - read from a file
+func nfsRead(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	name, status := s.readHandle(args)
+	offset := args.Uint64()
+	count := args.Uint32()
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	var n int
+	defer log.Trace(name, "offset=%d, count=%d", offset, count)("n=%d, status=%d", &n, &status)
+	var node vfs.Node
+	if status == nfsOK {
+		node, status = s.stat(name)
+	}
+	if status == nfsOK && node.IsDir() {
+		status = nfsErrIsDir
+	}
+	var data []byte
+	eof := false
+	if status == nfsOK {
+		if count > nfsMaxData {
+			count = nfsMaxData
+		}
+		data = make([]byte, count)
+		n, err = s.files.readAt(name, data, int64(offset))
+		if err == io.EOF {
+			err = nil
+			eof = true
+		}
+		status = nfsStatus(err)
+		data = data[:n]
+		if int64(offset)+int64(n) >= node.Size() {
+			eof = true
+		}
+	}
+	res.Uint32(status)
+	s.writePostOpAttr(res, name)
+	if status == nfsOK {
+		res.Uint32(uint32(n))
+		res.Bool(eof)
+		res.Opaque(data)
+	}
+	return nil
+}
+
+// WRITE - write to a file
+func nfsWrite(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	name, status := s.readHandle(args)
+	offset := args.Uint64()
+	_ = args.Uint32() // count - the same as len(data)
+	stable := args.Uint32()
+	data := args.OpaqueMax(maxRecordSize)
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	var n int
+	defer log.Trace(name, "offset=%d, len=%d, stable=%d", offset, len(data), stable)("n=%d, status=%d", &n, &status)
+	var node vfs.Node
+	if status == nfsOK {
+		node, status = s.stat(name)
+	}
+	if status == nfsOK && node.IsDir() {
+		status = nfsErrIsDir
+	}
+	if status == nfsOK {
+		n, err = s.files.writeAt(name, data, int64(offset))
+		if err == nil && stable != nfsUnstable {
+			err = s.files.commit(name)
+		}
+		status = nfsStatus(err)
+	}
+	res.Uint32(status)
+	s.writeWcc(res, name)
+	if status == nfsOK {
+		res.Uint32(uint32(n))
+		if stable != nfsUnstable {
+			res.Uint32(nfsFileSync)
+		} else {
+			res.Uint32(nfsUnstable)
+		}
+		res.Fixed(s.verifier[:])
+	}
+	return nil
+}
+
+// CREATE - create a file
+func nfsCreate(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	dirPath, dir, leaf, status := s.readNewDirOp(args)
+	mode := args.Uint32()
+	var attr sattr
+	if mode == nfsExclusive {
+		_ = args.Fixed(8) // verifier
+	} else {
+		attr = readSattr(args)
+	}
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	defer log.Trace(dirPath, "leaf=%q, mode=%d", leaf, mode)("status=%d", &status)
+	name := joinPath(dirPath, leaf)
+	if status == nfsOK {
+		node, err := dir.Stat(leaf)
+		switch {
+		case err == vfs.ENOENT:
+			err = s.files.create(name)
+			attr.setSize = false
+		case err != nil:
+		case node.IsDir():
+			err = vfs.EEXIST
+		case mode == nfsGuarded:
+			err = vfs.EEXIST
+		}
+		if err == nil {
+			err = s.setAttr(name, attr)
+		}
+		status = nfsStatus(err)
+	}
+	if status != nfsOK {
+		res.Uint32(status)
+		s.writeWcc(res, dirPath)
+		return nil
+	}
+	s.writeNewObject(res, name, dirPath)
+	return nil
+}
+
+// MKDIR - create a directory
+func nfsMkdir(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	dirPath, dir, leaf, status := s.readNewDirOp(args)
+	attr := readSattr(args)
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	defer log.Trace(dirPath, "leaf=%q", leaf)("status=%d", &status)
+	name := joinPath(dirPath, leaf)
+	if status == nfsOK {
+		_, err = dir.Stat(leaf)
+		if err == nil {
+			err = vfs.EEXIST
+		} else if err == vfs.ENOENT {
+			_, err = dir.Mkdir(leaf)
+			if err == nil && attr.setModTime {
+				err = s.setAttr(name, attr)
+			}
+		}
+		status = nfsStatus(err)
+	}
+	if status != nfsOK {
+		res.Uint32(status)
+		s.writeWcc(res, dirPath)
+		return nil
+	}
+	s.writeNewObject(res, name, dirPath)
+	return nil
+}
+
+// SYMLINK - symbolic links aren't supported
+func nfsSymlink(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	dirPath, _, _, _ := s.readDirOp(args)
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	res.Uint32(nfsErrNotSupp)
+	s.writeWcc(res, dirPath)
+	return nil
+}
+
+// MKNOD - special files aren't supported
+func nfsMknod(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	dirPath, _, _, _ := s.readDirOp(args)
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	res.Uint32(nfsErrNotSupp)
+	s.writeWcc(res, dirPath)
+	return nil
+}
+
+// remove removes the file or directory leaf from dir
+func (s *Server) remove(dirPath string, dir *vfs.Dir, leaf string, isDir bool) uint32 {
+	name := joinPath(dirPath, leaf)
+	node, err := dir.Stat(leaf)
+	if err != nil {
+		return nfsStatus(err)
+	}
+	if node.IsDir() != isDir {
+		if isDir {
+			return nfsErrNotDir
+		}
+		return nfsErrIsDir
+	}
+	if !isDir {
+		_ = s.files.commit(name)
+	}
+	err = node.Remove()
+	if err != nil {
+		return nfsStatus(err)
+	}
+	s.handles.remove(name)
+	return nfsOK
+}
+
+// REMOVE - remove a file
+func nfsRemove(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	dirPath, dir, leaf, status := s.readNewDirOp(args)
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	defer log.Trace(dirPath, "leaf=%q", leaf)("status=%d", &status)
+	if status == nfsOK {
+		status = s.remove(dirPath, dir, leaf, false)
+	}
+	res.Uint32(status)
+	s.writeWcc(res, dirPath)
+	return nil
+}
+
+// RMDIR - remove a directory
+func nfsRmdir(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	dirPath, dir, leaf, status := s.readNewDirOp(args)
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	defer log.Trace(dirPath, "leaf=%q", leaf)("status=%d", &status)
+	if status == nfsOK {
+		status = s.remove(dirPath, dir, leaf, true)
+	}
+	res.Uint32(status)
+	s.writeWcc(res, dirPath)
+	return nil
+}
+
+// RENAME - rename a file or directory
+func nfsRename(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	fromPath, fromDir, fromLeaf, status := s.readNewDirOp(args)
+	toPath, toDir, toLeaf, toStatus := s.readNewDirOp(args)
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	defer log.Trace(fromPath, "leaf=%q, to=%q, toLeaf=%q", fromLeaf, toPath, toLeaf)("status=%d", &status)
+	if status == nfsOK {
+		status = toStatus
+	}
+	if status == nfsOK {
+		oldName := joinPath(fromPath, fromLeaf)
+		newName := joinPath(toPath, toLeaf)
+		// Files can't be renamed while they are open
+		_ = s.files.commit(oldName)
+		_ = s.files.commit(newName)
+		err = fromDir.Rename(fromLeaf, toLeaf, toDir)
+		if err == nil {
+			s.handles.rename(oldName, newName)
+		}
+		status = nfsStatus(err)
+	}
+	res.Uint32(status)
+	s.writeWcc(res, fromPath)
+	s.writeWcc(res, toPath)
+	return nil
+}
+
+// LINK - hard links aren't supported
+func nfsLink(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	name, _ := s.readHandle(args)
+	dirPath, _, _, _ := s.readDirOp(args)
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	res.Uint32(nfsErrNotSupp)
+	s.writePostOpAttr(res, name)
+	s.writeWcc(res, dirPath)
+	return nil
+}
+
+// readdir encodes the entries of the directory name starting after
+// cookie.  If plus is set the attributes and handles of the entries
+// are sent too.
+//
+// dirCount limits the size of the names and maxCount limits the size
+// of the whole reply.
+func (s *Server) readdir(res *nfs3.Writer, name string, cookie uint64, dirCount, maxCount uint32, plus bool) (status uint32) {
+	defer log.Trace(name, "cookie=%d, dirCount=%d, maxCount=%d, plus=%v", cookie, dirCount, maxCount, plus)("status=%d", &status)
+	node, status := s.stat(name)
+	if status != nfsOK {
+		res.Uint32(status)
+		s.writePostOpAttr(res, name)
+		return status
+	}
+	dir, ok := node.(*vfs.Dir)
+	if !ok {
+		res.Uint32(nfsErrNotDir)
+		s.writePostOpAttr(res, name)
+		return nfsErrNotDir
+	}
+	items, err := dir.ReadDirAll()
+	if err != nil {
+		status = nfsStatus(err)
+		res.Uint32(status)
+		s.writePostOpAttr(res, name)
+		return status
+	}
+	// Encode the entries which fit
+	entries := &nfs3.Writer{}
+	const overhead = 4 + 4 + 84 + 8 + 4 + 4 // status, attr, verf, end of list and eof
+	size, names := uint32(overhead), uint32(0)
+	i := cookie
+	for ; i < uint64(len(items)); i++ {
+		item := items[i]
+		itemPath := joinPath(name, item.Name())
+		entry := &nfs3.Writer{}
+		entry.Bool(true)
+		entry.Uint64(s.handles.id(itemPath))
+		entry.Str(item.Name())
+		entry.Uint64(i + 1)
+		names += uint32(entry.Len())
+		if plus {
+			entry.Bool(true)
+			s.writeAttr(entry, itemPath, item)
+			entry.Bool(true)
+			entry.Opaque(s.fileHandle(itemPath))
+		}
+		size += uint32(entry.Len())
+		if size > maxCount || names > dirCount {
+			break
+		}
+		entries.Fixed(entry.Bytes())
+	}
+	if i == cookie && i < uint64(len(items)) {
+		res.Uint32(nfsErrTooSmall)
+		s.writePostOpAttr(res, name)
+		return nfsErrTooSmall
+	}
+	res.Uint32(nfsOK)
+	s.writePostOpAttr(res, name)
+	res.Uint64(0) // cookie verifier
+	res.Fixed(entries.Bytes())
+	res.Bool(false)
+	res.Bool(i >= uint64(len(items)))
+	return nfsOK
+}
+
+// READDIR - read from a directory
+func nfsReaddir(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	name, status := s.readHandle(args)
+	cookie := args.Uint64()
+	_ = args.Fixed(8) // cookie verifier
+	count := args.Uint32()
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	if status != nfsOK {
+		res.Uint32(status)
+		res.Bool(false)
+		return nil
+	}
+	s.readdir(res, name, cookie, count, count, false)
+	return nil
+}
+
+// READDIRPLUS - read from a directory with attributes
+func nfsReaddirplus(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	name, status := s.readHandle(args)
+	cookie := args.Uint64()
+	_ = args.Fixed(8) // cookie verifier
+	dirCount := args.Uint32()
+	maxCount := args.Uint32()
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	if status != nfsOK {
+		res.Uint32(status)
+		res.Bool(false)
+		return nil
+	}
+	s.readdir(res, name, cookie, dirCount, maxCount, true)
+	return nil
+}
+
+// FSSTAT - get dynamic file system information
+func nfsFsstat(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	name, status := s.readHandle(args)
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	res.Uint32(status)
+	s.writePostOpAttr(res, name)
+	if status != nfsOK {
+		return nil
+	}
+	var totalBytes uint64 = 1 << 50
+	total, used, free := s.vfs.Statfs()
+	if total >= 0 {
+		totalBytes = uint64(total)
+	}
+	freeBytes := totalBytes
+	if free >= 0 {
+		freeBytes = uint64(free)
+		if freeBytes > totalBytes {
+			freeBytes = totalBytes
+		}
+	} else if used >= 0 && uint64(used) < totalBytes {
+		freeBytes = totalBytes - uint64(used)
+	}
+	res.Uint64(totalBytes) // tbytes
+	res.Uint64(freeBytes)  // fbytes
+	res.Uint64(freeBytes)  // abytes
+	res.Uint64(1e9)        // tfiles
+	res.Uint64(1e9)        // ffiles
+	res.Uint64(1e9)        // afiles
+	res.Uint32(0)          // invarsec
+	return nil
+}
+
+// FSINFO - get static file system information
+func nfsFsinfo(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	name, status := s.readHandle(args)
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	res.Uint32(status)
+	s.writePostOpAttr(res, name)
+	if status != nfsOK {
+		return nil
+	}
+	res.Uint32(nfsMaxData) // rtmax
+	res.Uint32(nfsMaxData) // rtpref
+	res.Uint32(4096)       // rtmult
+	res.Uint32(nfsMaxData) // wtmax
+	res.Uint32(nfsMaxData) // wtpref
+	res.Uint32(4096)       // wtmult
+	res.Uint32(64 * 1024)  // dtpref
+	res.Uint64(1 << 62)    // maxfilesize
+	res.Uint32(0)          // time_delta seconds
+	res.Uint32(1)          // time_delta nanoseconds
+	res.Uint32(nfsFSFHomogeneous | nfsFSFCanSetTime)
+	return nil
+}
+
+// PATHCONF - retrieve POSIX information
+func nfsPathconf(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	name, status := s.readHandle(args)
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	res.Uint32(status)
+	s.writePostOpAttr(res, name)
+	if status != nfsOK {
+		return nil
+	}
+	res.Uint32(1)          // linkmax
+	res.Uint32(nfsNameMax) // name_max
+	res.Bool(true)         // no_trunc
+	res.Bool(true)         // chown_restricted
+	res.Bool(false)        // case_insensitive
+	res.Bool(true)         // case_preserving
+	return nil
+}
+
+// COMMIT - commit cached data to stable storage
+//
+// This closes the file so it is uploaded.
+func nfsCommit(s *Server, args *nfs3.Reader, res *nfs3.Writer) (err error) {
+	name, status := s.readHandle(args)
+	_ = args.Uint64() // offset
+	_ = args.Uint32() // count
+	if args.Err() != nil {
+		return errGarbageArgs
+	}
+	defer log.Trace(name, "")("status=%d", &status)
+	if status == nfsOK {
+		err = s.files.commit(name)
+		if err != nil {
+			fs.Errorf(name, "NFS commit failed: %v", err)
+		}
+		status = nfsStatus(err)
+	}
+	res.Uint32(status)
+	s.writeWcc(res, name)
+	if status == nfsOK {
+		res.Fixed(s.verifier[:])
+	}
+	return nil
+}
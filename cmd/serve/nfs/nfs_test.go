@@ -0,0 +1,330 @@
+package nfs
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/backend/nfs/nfs3"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/vfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClient makes RPC calls to the server
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	xid  uint32
+}
+
+// call calls proc in prog with the encoded args returning a reader
+// for the results
+func (c *testClient) call(prog, proc uint32, args *nfs3.Writer) *nfs3.Reader {
+	c.xid++
+	w := &nfs3.Writer{}
+	w.Uint32(c.xid)
+	w.Uint32(rpcCall)
+	w.Uint32(rpcVersion)
+	w.Uint32(prog)
+	w.Uint32(programs[prog].version)
+	w.Uint32(proc)
+	w.Uint32(authUnix)
+	w.Opaque(make([]byte, 20))
+	w.Uint32(authNone)
+	w.Opaque(nil)
+	w.Fixed(args.Bytes())
+	require.NoError(c.t, nfs3.WriteRecord(c.conn, w.Bytes()))
+	record, err := nfs3.ReadRecord(c.conn, maxRecordSize)
+	require.NoError(c.t, err)
+	r := nfs3.NewReader(record)
+	assert.Equal(c.t, c.xid, r.Uint32())
+	assert.Equal(c.t, uint32(rpcReply), r.Uint32())
+	assert.Equal(c.t, uint32(rpcMsgAccepted), r.Uint32())
+	_ = r.Uint32()
+	_ = r.OpaqueMax(maxAuthBytes)
+	require.Equal(c.t, uint32(rpcSuccess), r.Uint32())
+	require.NoError(c.t, r.Err())
+	return r
+}
+
+// dirOp encodes diropargs3
+func dirOp(fh []byte, name string) *nfs3.Writer {
+	w := &nfs3.Writer{}
+	w.Opaque(fh)
+	w.Str(name)
+	return w
+}
+
+// skipPostOpAttr skips a post_op_attr returning whether it was there
+func skipPostOpAttr(r *nfs3.Reader) bool {
+	if !r.Bool() {
+		return false
+	}
+	_ = r.Fixed(84)
+	return true
+}
+
+func TestNFS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-serve-nfs")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+
+	opt := vfs.DefaultOpt
+	opt.CacheMode = vfs.CacheModeWrites
+	VFS := vfs.New(f, &opt)
+	defer VFS.Shutdown()
+	s, err := NewServer(VFS, "localhost:0")
+	require.NoError(t, err)
+	go func() {
+		assert.NoError(t, s.Serve())
+	}()
+	defer func() {
+		assert.NoError(t, s.Close())
+	}()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer func() {
+		_ = conn.Close()
+	}()
+	c := &testClient{t: t, conn: conn}
+
+	// NULL
+	c.call(nfsProgram, 0, &nfs3.Writer{})
+
+	// MNT the root
+	args := &nfs3.Writer{}
+	args.Str("/")
+	r := c.call(mountProgram, 1, args)
+	require.Equal(t, uint32(mountOK), r.Uint32())
+	root := r.OpaqueMax(nfsFhSize)
+	require.NoError(t, r.Err())
+
+	// MKDIR dir
+	args = dirOp(root, "dir")
+	args.Fixed(make([]byte, 6*4)) // empty sattr3
+	r = c.call(nfsProgram, 9, args)
+	require.Equal(t, uint32(nfsOK), r.Uint32())
+	require.True(t, r.Bool())
+	subdir := r.OpaqueMax(nfsFhSize)
+	fi, err := os.Stat(dir + "/dir")
+	require.NoError(t, err)
+	assert.True(t, fi.IsDir())
+
+	// CREATE dir/file
+	args = dirOp(subdir, "file")
+	args.Uint32(nfsGuarded)
+	args.Fixed(make([]byte, 6*4)) // empty sattr3
+	r = c.call(nfsProgram, 8, args)
+	require.Equal(t, uint32(nfsOK), r.Uint32())
+	require.True(t, r.Bool())
+	file := r.OpaqueMax(nfsFhSize)
+
+	// CREATE it again fails
+	args = dirOp(subdir, "file")
+	args.Uint32(nfsGuarded)
+	args.Fixed(make([]byte, 6*4)) // empty sattr3
+	r = c.call(nfsProgram, 8, args)
+	assert.Equal(t, uint32(nfsErrExist), r.Uint32())
+
+	// WRITE to it out of order
+	for _, write := range []struct {
+		offset uint64
+		data   string
+	}{
+		{5, " world"},
+		{0, "hello"},
+	} {
+		args = &nfs3.Writer{}
+		args.Opaque(file)
+		args.Uint64(write.offset)
+		args.Uint32(uint32(len(write.data)))
+		args.Uint32(nfsUnstable)
+		args.Str(write.data)
+		r = c.call(nfsProgram, 7, args)
+		require.Equal(t, uint32(nfsOK), r.Uint32())
+		_ = r.Bool()
+		skipPostOpAttr(r)
+		assert.Equal(t, uint32(len(write.data)), r.Uint32())
+	}
+
+	// COMMIT uploads it
+	args = &nfs3.Writer{}
+	args.Opaque(file)
+	args.Uint64(0)
+	args.Uint32(0)
+	r = c.call(nfsProgram, 21, args)
+	require.Equal(t, uint32(nfsOK), r.Uint32())
+	data, err := ioutil.ReadFile(dir + "/dir/file")
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+
+	// LOOKUP gives the same handle
+	r = c.call(nfsProgram, 3, dirOp(subdir, "file"))
+	require.Equal(t, uint32(nfsOK), r.Uint32())
+	assert.Equal(t, file, r.OpaqueMax(nfsFhSize))
+	r = c.call(nfsProgram, 3, dirOp(subdir, "potato"))
+	assert.Equal(t, uint32(nfsErrNoEnt), r.Uint32())
+
+	// GETATTR
+	args = &nfs3.Writer{}
+	args.Opaque(file)
+	r = c.call(nfsProgram, 1, args)
+	require.Equal(t, uint32(nfsOK), r.Uint32())
+	assert.Equal(t, uint32(nfsReg), r.Uint32())
+	_ = r.Fixed(4 * 4)
+	assert.Equal(t, uint64(11), r.Uint64())
+
+	// READ part of it
+	args = &nfs3.Writer{}
+	args.Opaque(file)
+	args.Uint64(6)
+	args.Uint32(100)
+	r = c.call(nfsProgram, 6, args)
+	require.Equal(t, uint32(nfsOK), r.Uint32())
+	skipPostOpAttr(r)
+	assert.Equal(t, uint32(5), r.Uint32())
+	assert.True(t, r.Bool())
+	assert.Equal(t, "world", string(r.OpaqueMax(nfsMaxData)))
+
+	// READDIRPLUS
+	args = &nfs3.Writer{}
+	args.Opaque(subdir)
+	args.Uint64(0)
+	args.Uint64(0)
+	args.Uint32(4096)
+	args.Uint32(4096)
+	r = c.call(nfsProgram, 17, args)
+	require.Equal(t, uint32(nfsOK), r.Uint32())
+	skipPostOpAttr(r)
+	_ = r.Uint64()
+	require.True(t, r.Bool())
+	_ = r.Uint64()
+	assert.Equal(t, "file", r.StrMax(nfsNameMax))
+	_ = r.Uint64()
+	require.True(t, skipPostOpAttr(r))
+	require.True(t, r.Bool())
+	assert.Equal(t, file, r.OpaqueMax(nfsFhSize))
+	assert.False(t, r.Bool())
+	assert.True(t, r.Bool())
+	require.NoError(t, r.Err())
+
+	// RENAME dir/file to file2 keeps the handle
+	args = dirOp(subdir, "file")
+	args.Fixed(dirOp(root, "file2").Bytes())
+	r = c.call(nfsProgram, 14, args)
+	require.Equal(t, uint32(nfsOK), r.Uint32())
+	_, err = os.Stat(dir + "/file2")
+	require.NoError(t, err)
+	args = &nfs3.Writer{}
+	args.Opaque(file)
+	r = c.call(nfsProgram, 1, args)
+	assert.Equal(t, uint32(nfsOK), r.Uint32())
+
+	// Bad leaf names are rejected
+	for _, leaf := range []string{"", ".", "..", "a/b", "../file2"} {
+		args = dirOp(root, leaf)
+		args.Fixed(make([]byte, 6*4)) // empty sattr3
+		r = c.call(nfsProgram, 9, args)
+		assert.Equal(t, uint32(nfsErrInval), r.Uint32(), "MKDIR %q", leaf)
+		args = dirOp(root, leaf)
+		args.Uint32(nfsUnchecked)
+		args.Fixed(make([]byte, 6*4)) // empty sattr3
+		r = c.call(nfsProgram, 8, args)
+		assert.Equal(t, uint32(nfsErrInval), r.Uint32(), "CREATE %q", leaf)
+		args = dirOp(root, "file2")
+		args.Fixed(dirOp(subdir, leaf).Bytes())
+		r = c.call(nfsProgram, 14, args)
+		assert.Equal(t, uint32(nfsErrInval), r.Uint32(), "RENAME %q", leaf)
+	}
+	_, err = os.Stat(dir + "/file2")
+	require.NoError(t, err)
+
+	// RMDIR the now empty dir but not a file
+	args = dirOp(root, "dir")
+	r = c.call(nfsProgram, 13, args)
+	assert.Equal(t, uint32(nfsOK), r.Uint32())
+	r = c.call(nfsProgram, 13, dirOp(root, "file2"))
+	assert.Equal(t, uint32(nfsErrNotDir), r.Uint32())
+
+	// REMOVE file2 and its handle goes stale
+	r = c.call(nfsProgram, 12, dirOp(root, "file2"))
+	require.Equal(t, uint32(nfsOK), r.Uint32())
+	_, err = os.Stat(dir + "/file2")
+	assert.True(t, os.IsNotExist(err))
+	args = &nfs3.Writer{}
+	args.Opaque(file)
+	r = c.call(nfsProgram, 1, args)
+	assert.Equal(t, uint32(nfsErrStale), r.Uint32())
+
+	// Unknown programs are rejected
+	w := &nfs3.Writer{}
+	w.Uint32(99)
+	w.Uint32(rpcCall)
+	w.Uint32(rpcVersion)
+	w.Uint32(1234)
+	w.Uint32(1)
+	w.Uint32(0)
+	w.Fixed(make([]byte, 4*4))
+	require.NoError(t, nfs3.WriteRecord(conn, w.Bytes()))
+	record, err := nfs3.ReadRecord(conn, maxRecordSize)
+	require.NoError(t, err)
+	r = nfs3.NewReader(record[4*3:])
+	_ = r.Uint32()
+	_ = r.OpaqueMax(maxAuthBytes)
+	assert.Equal(t, uint32(rpcProgUnavail), r.Uint32())
+}
+
+func TestOpenFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-serve-nfs")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	require.NoError(t, ioutil.WriteFile(dir+"/a", []byte("aaa"), 0600))
+	require.NoError(t, ioutil.WriteFile(dir+"/b", []byte("bbb"), 0600))
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+	VFS := vfs.New(f, nil)
+	defer VFS.Shutdown()
+	o := newOpenFiles(VFS)
+	defer o.closeAll()
+
+	// While a is in use b can still be read
+	a, err := o.get("a", false)
+	require.NoError(t, err)
+	buf := make([]byte, 3)
+	n, err := o.readAt("b", buf, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "bbb", string(buf[:n]))
+
+	// but a can't be read until it isn't in use
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, 3)
+		n, err := o.readAt("a", buf, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, "aaa", string(buf[:n]))
+	}()
+	select {
+	case <-done:
+		t.Fatal("read a while it was in use")
+	case <-time.After(100 * time.Millisecond):
+	}
+	a.mu.Unlock()
+	<-done
+
+	// Committing closes the file
+	require.NoError(t, o.commit("a"))
+	assert.True(t, a.closed)
+}
@@ -0,0 +1,175 @@
+// ONC RPC as described in RFC 5531
+
+package nfs
+
+import (
+	"io"
+	"net"
+
+	"github.com/ncw/rclone/backend/nfs/nfs3"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// RPC constants
+const (
+	rpcVersion = 2
+
+	// msg_type
+	rpcCall  = 0
+	rpcReply = 1
+
+	// reply_stat
+	rpcMsgAccepted = 0
+	rpcMsgDenied   = 1
+
+	// reject_stat
+	rpcMismatch = 0
+
+	// accept_stat
+	rpcSuccess      = 0
+	rpcProgUnavail  = 1
+	rpcProgMismatch = 2
+	rpcProcUnavail  = 3
+	rpcGarbageArgs  = 4
+	rpcSystemErr    = 5
+
+	// auth_flavor
+	authNone = 0
+	authUnix = 1
+
+	// largest credential or verifier body
+	maxAuthBytes = 400
+
+	// largest RPC record which will be accepted
+	maxRecordSize = 4 * 1024 * 1024
+)
+
+// errGarbageArgs is returned by a procedure when its arguments can't
+// be decoded
+var errGarbageArgs = errors.New("couldn't decode RPC arguments")
+
+// procedure is a remote procedure which decodes its arguments from
+// args and encodes its results into res
+type procedure func(s *Server, args *nfs3.Reader, res *nfs3.Writer) error
+
+// program is a set of procedures with the same program number and
+// version
+type program struct {
+	name    string
+	version uint32
+	procs   map[uint32]procedure
+}
+
+// The programs served keyed on program number
+var programs = map[uint32]*program{}
+
+// call is a decoded RPC call
+type call struct {
+	xid     uint32
+	rpcvers uint32
+	prog    uint32
+	vers    uint32
+	proc    uint32
+	args    *nfs3.Reader
+}
+
+// parseCall decodes an RPC call message from record
+func parseCall(record []byte) (*call, error) {
+	r := nfs3.NewReader(record)
+	c := &call{
+		xid: r.Uint32(),
+	}
+	if msgType := r.Uint32(); r.Err() == nil && msgType != rpcCall {
+		return nil, errors.Errorf("expecting RPC call but got message type %d", msgType)
+	}
+	c.rpcvers = r.Uint32()
+	c.prog = r.Uint32()
+	c.vers = r.Uint32()
+	c.proc = r.Uint32()
+	// Credentials and verifier are ignored
+	for i := 0; i < 2; i++ {
+		_ = r.Uint32()
+		_ = r.OpaqueMax(maxAuthBytes)
+	}
+	if r.Err() != nil {
+		return nil, errors.Wrap(r.Err(), "failed to decode RPC call")
+	}
+	c.args = r
+	return c, nil
+}
+
+// dispatch runs the procedure asked for by c returning the encoded
+// reply message
+func (s *Server) dispatch(c *call) []byte {
+	w := &nfs3.Writer{}
+	w.Uint32(c.xid)
+	w.Uint32(rpcReply)
+	if c.rpcvers != rpcVersion {
+		w.Uint32(rpcMsgDenied)
+		w.Uint32(rpcMismatch)
+		w.Uint32(rpcVersion)
+		w.Uint32(rpcVersion)
+		return w.Bytes()
+	}
+	w.Uint32(rpcMsgAccepted)
+	w.Uint32(authNone)
+	w.Opaque(nil)
+	prog, found := programs[c.prog]
+	if !found {
+		w.Uint32(rpcProgUnavail)
+		return w.Bytes()
+	}
+	if c.vers != prog.version {
+		w.Uint32(rpcProgMismatch)
+		w.Uint32(prog.version)
+		w.Uint32(prog.version)
+		return w.Bytes()
+	}
+	proc, found := prog.procs[c.proc]
+	if !found {
+		w.Uint32(rpcProcUnavail)
+		return w.Bytes()
+	}
+	res := &nfs3.Writer{}
+	err := proc(s, c.args, res)
+	switch {
+	case err == errGarbageArgs:
+		fs.Debugf(nil, "%s proc %d: %v", prog.name, c.proc, err)
+		w.Uint32(rpcGarbageArgs)
+	case err != nil:
+		fs.Errorf(nil, "%s proc %d failed: %v", prog.name, c.proc, err)
+		w.Uint32(rpcSystemErr)
+	default:
+		w.Uint32(rpcSuccess)
+		w.Fixed(res.Bytes())
+	}
+	return w.Bytes()
+}
+
+// serveConn reads calls from conn and replies to them in turn until
+// the connection is closed
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer fs.CheckClose(conn, new(error))
+	fs.Debugf(nil, "NFS connection from %s", conn.RemoteAddr())
+	for {
+		record, err := nfs3.ReadRecord(conn, maxRecordSize)
+		if err != nil {
+			if err != io.EOF && !s.isClosed() {
+				fs.Errorf(nil, "NFS connection from %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		c, err := parseCall(record)
+		if err != nil {
+			fs.Errorf(nil, "NFS connection from %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+		err = nfs3.WriteRecord(conn, s.dispatch(c))
+		if err != nil {
+			fs.Errorf(nil, "NFS connection from %s: failed to write reply: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
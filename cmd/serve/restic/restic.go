@@ -30,12 +30,14 @@ import (
 )
 
 var (
-	stdio bool
+	stdio      bool
+	appendOnly bool
 )
 
 func init() {
 	httpflags.AddFlags(Command.Flags())
 	Command.Flags().BoolVar(&stdio, "stdio", false, "run an HTTP2 server on stdin/stdout")
+	Command.Flags().BoolVar(&appendOnly, "append-only", false, "disallow deletion of repository data")
 }
 
 // Command definition for cobra
@@ -104,6 +106,18 @@ For example:
     duration: 0:00
     snapshot 45c8fdd8 saved
 
+#### Append only mode ####
+
+Use --append-only to stop the repository being changed other than by
+adding to it, which protects the backups from a compromised client,
+eg one infected with ransomware, as it can't delete or overwrite the
+existing backups.
+
+In append only mode the only files which may be deleted are restic's
+lock files and files which already exist can't be overwritten.  This
+means that "restic forget" and "restic prune" won't work, so run
+those with a separate server, or directly, from a trusted machine.
+
 #### Multiple repositories ####
 
 Note that you can use the endpoint to host multiple repositories.  Do
@@ -312,8 +326,23 @@ func (s *server) getObject(w http.ResponseWriter, r *http.Request, remote string
 	}
 }
 
+// isLock returns true if remote is one of restic's lock files
+func isLock(remote string) bool {
+	return path.Base(path.Dir(remote)) == "locks"
+}
+
 // postObject posts an object to the repository
 func (s *server) postObject(w http.ResponseWriter, r *http.Request, remote string) {
+	if appendOnly {
+		// make sure the file does not exist yet
+		_, err := s.f.NewObject(remote)
+		if err == nil {
+			fs.Errorf(remote, "Post request: file already exists")
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+	}
+
 	// fs.Debugf(s.f, "content length = %d", r.ContentLength)
 	if r.ContentLength >= 0 {
 		// Size known use Put
@@ -355,6 +384,12 @@ func (s *server) postObject(w http.ResponseWriter, r *http.Request, remote strin
 
 // delete the remote
 func (s *server) deleteObject(w http.ResponseWriter, r *http.Request, remote string) {
+	if appendOnly && !isLock(remote) {
+		fs.Errorf(remote, "Delete request: not allowed in append only mode")
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
 	o, err := s.f.NewObject(remote)
 	if err != nil {
 		fs.Debugf(remote, "Delete request error: %v", err)
@@ -3,14 +3,20 @@
 package restic
 
 import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"testing"
 
 	_ "github.com/ncw/rclone/backend/all"
 	"github.com/ncw/rclone/cmd/serve/httplib"
+	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fstest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -93,3 +99,46 @@ func TestMakeRemote(t *testing.T) {
 		assert.Equal(t, test.want, got, test.in)
 	}
 }
+
+func TestAppendOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-serve-restic")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+	s := newServer(f, &httplib.DefaultOpt)
+
+	appendOnly = true
+	defer func() { appendOnly = false }()
+
+	do := func(method, path, body string) int {
+		r, err := http.NewRequest(method, "http://localhost"+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		s.handler(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do("POST", "/?create=true", ""))
+	assert.Equal(t, http.StatusOK, do("POST", "/config", "config"))
+	assert.Equal(t, http.StatusOK, do("POST", "/data/123456", "data"))
+	assert.Equal(t, http.StatusOK, do("POST", "/locks/abcdef", "lock"))
+
+	// Existing files can't be overwritten or deleted except locks
+	assert.Equal(t, http.StatusForbidden, do("POST", "/config", "potato"))
+	assert.Equal(t, http.StatusForbidden, do("POST", "/data/123456", "potato"))
+	assert.Equal(t, http.StatusForbidden, do("DELETE", "/data/123456", ""))
+	assert.Equal(t, http.StatusForbidden, do("DELETE", "/config", ""))
+	assert.Equal(t, http.StatusOK, do("DELETE", "/locks/abcdef", ""))
+
+	data, err := ioutil.ReadFile(dir + "/data/12/123456")
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+
+	// Check everything is allowed without --append-only
+	appendOnly = false
+	assert.Equal(t, http.StatusOK, do("POST", "/data/123456", "potato"))
+	assert.Equal(t, http.StatusOK, do("DELETE", "/data/123456", ""))
+}
@@ -0,0 +1,173 @@
+package sftp
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/ncw/rclone/fs/log"
+	"github.com/ncw/rclone/vfs"
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+)
+
+// vfsHandler converts the VFS to be served by SFTP
+type vfsHandler struct {
+	*vfs.VFS
+}
+
+// newVFSHandler returns the Handlers to serve VFS over SFTP
+func newVFSHandler(VFS *vfs.VFS) sftp.Handlers {
+	v := vfsHandler{VFS: VFS}
+	return sftp.Handlers{
+		FileGet:  v,
+		FilePut:  v,
+		FileCmd:  v,
+		FileList: v,
+	}
+}
+
+// Fileread opens the file for reading
+func (v vfsHandler) Fileread(r *sftp.Request) (readerAt io.ReaderAt, err error) {
+	defer log.Trace(r.Filepath, "")("err = %v", &err)
+	file, err := v.OpenFile(r.Filepath, os.O_RDONLY, 0777)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Filewrite opens the file for writing with the flags from the
+// open request
+func (v vfsHandler) Filewrite(r *sftp.Request) (writerAt io.WriterAt, err error) {
+	defer log.Trace(r.Filepath, "")("err = %v", &err)
+	pflags := r.Pflags()
+	flags := os.O_WRONLY
+	if pflags.Read {
+		flags = os.O_RDWR
+	}
+	if pflags.Creat {
+		flags |= os.O_CREATE
+	}
+	if pflags.Trunc {
+		flags |= os.O_TRUNC
+	}
+	if pflags.Append {
+		flags |= os.O_APPEND
+	}
+	if pflags.Excl {
+		flags |= os.O_EXCL
+	}
+	file, err := v.OpenFile(r.Filepath, flags, 0777)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Filecmd runs the commands which don't need a file handle
+func (v vfsHandler) Filecmd(r *sftp.Request) (err error) {
+	defer log.Trace(r.Filepath, "method=%s, target=%q", r.Method, r.Target)("err = %v", &err)
+	switch r.Method {
+	case "Setstat":
+		node, err := v.Stat(r.Filepath)
+		if err != nil {
+			return err
+		}
+		attr := r.Attributes()
+		if r.AttrFlags(r.Flags).Size {
+			err = node.Truncate(int64(attr.Size))
+			if err != nil {
+				return err
+			}
+		}
+		if r.AttrFlags(r.Flags).Acmodtime {
+			modTime := time.Unix(int64(attr.Mtime), 0)
+			err = node.SetModTime(modTime)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case "Rename":
+		return v.Rename(r.Filepath, r.Target)
+	case "Rmdir":
+		node, err := v.Stat(r.Filepath)
+		if err != nil {
+			return err
+		}
+		if node.IsFile() {
+			return errors.Errorf("%q is not a directory", r.Filepath)
+		}
+		return node.Remove()
+	case "Remove":
+		node, err := v.Stat(r.Filepath)
+		if err != nil {
+			return err
+		}
+		if !node.IsFile() {
+			return errors.Errorf("%q is a directory", r.Filepath)
+		}
+		return node.Remove()
+	case "Mkdir":
+		dir, leaf, err := v.StatParent(r.Filepath)
+		if err != nil {
+			return err
+		}
+		_, err = dir.Mkdir(leaf)
+		return err
+	}
+	// Symlink isn't supported by the VFS
+	return sftp.ErrSshFxOpUnsupported
+}
+
+// listerat is a ListerAt for a static list of files
+type listerat []os.FileInfo
+
+// ListAt copies the files from offset into f, returning io.EOF when
+// there are no more to copy.
+func (f listerat) ListAt(ls []os.FileInfo, offset int64) (int, error) {
+	var n int
+	if offset >= int64(len(f)) {
+		return 0, io.EOF
+	}
+	n = copy(ls, f[offset:])
+	if n < len(ls) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Filelist lists directories and stats files
+func (v vfsHandler) Filelist(r *sftp.Request) (l sftp.ListerAt, err error) {
+	defer log.Trace(r.Filepath, "method=%s", r.Method)("err = %v", &err)
+	var node vfs.Node
+	switch r.Method {
+	case "List":
+		node, err = v.Stat(r.Filepath)
+		if err != nil {
+			return nil, err
+		}
+		if node.IsFile() {
+			return nil, errors.Errorf("%q is not a directory", r.Filepath)
+		}
+		dir := node.(*vfs.Dir)
+		dirEntries, err := dir.ReadDirAll()
+		if err != nil {
+			return nil, err
+		}
+		files := make(listerat, len(dirEntries))
+		for i, dirEntry := range dirEntries {
+			files[i] = dirEntry
+		}
+		return files, nil
+	case "Stat":
+		node, err = v.Stat(r.Filepath)
+		if err != nil {
+			return nil, err
+		}
+		return listerat{node}, nil
+	}
+	// Readlink isn't supported by the VFS
+	return nil, sftp.ErrSshFxOpUnsupported
+}
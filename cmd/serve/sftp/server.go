@@ -0,0 +1,429 @@
+package sftp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/vfs"
+	"github.com/ncw/rclone/vfs/vfsflags"
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// server contains everything to run the server
+type server struct {
+	f        fs.Fs
+	opt      Options
+	vfs      *vfs.VFS
+	config   *ssh.ServerConfig
+	listener net.Listener
+	waitChan chan struct{} // for waiting on the listener to close
+	wg       sync.WaitGroup
+}
+
+// newServer makes a new SFTP server for f
+func newServer(f fs.Fs, opt *Options) *server {
+	s := &server{
+		f:        f,
+		vfs:      vfs.New(f, &vfsflags.Opt),
+		opt:      *opt,
+		waitChan: make(chan struct{}),
+	}
+	return s
+}
+
+// expandHome replaces a leading ~ in path with the home directory
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home := os.Getenv("HOME"); home != "" {
+			return home + path[1:]
+		}
+	}
+	return path
+}
+
+// loadAuthorizedKeys reads the public keys in the authorized keys
+// file keyed by their wire format
+func loadAuthorizedKeys(path string) (map[string]struct{}, error) {
+	authorizedKeysBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	authorizedKeysMap := map[string]struct{}{}
+	for len(authorizedKeysBytes) > 0 {
+		pubKey, _, _, rest, err := ssh.ParseAuthorizedKey(authorizedKeysBytes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse authorized keys %q", path)
+		}
+		authorizedKeysMap[string(pubKey.Marshal())] = struct{}{}
+		authorizedKeysBytes = bytes.TrimSpace(rest)
+	}
+	return authorizedKeysMap, nil
+}
+
+// makeConfig makes the ssh configuration checking the users against
+// the options
+func (s *server) makeConfig() error {
+	s.config = &ssh.ServerConfig{
+		ServerVersion: "SSH-2.0-" + fs.Config.UserAgent,
+		NoClientAuth:  s.opt.NoAuth,
+	}
+	authMethods := 0
+
+	// Allow the user in the options to log in with their password
+	if s.opt.User != "" && s.opt.Pass != "" {
+		authMethods++
+		s.config.PasswordCallback = func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			fs.Debugf(nil, "Password login attempt for %s from %s", c.User(), c.RemoteAddr())
+			userOK := subtle.ConstantTimeCompare([]byte(c.User()), []byte(s.opt.User))
+			passOK := subtle.ConstantTimeCompare(pass, []byte(s.opt.Pass))
+			if userOK&passOK == 1 {
+				return nil, nil
+			}
+			return nil, errors.Errorf("password rejected for %q", c.User())
+		}
+	}
+
+	// Allow anyone with a key in the authorized keys file to log in
+	if s.opt.AuthorizedKeys != "" {
+		authorizedKeysPath := expandHome(s.opt.AuthorizedKeys)
+		authorizedKeysMap, err := loadAuthorizedKeys(authorizedKeysPath)
+		if err == nil {
+			authMethods++
+			fs.Debugf(nil, "Loaded %d authorized keys from %q", len(authorizedKeysMap), authorizedKeysPath)
+			s.config.PublicKeyCallback = func(c ssh.ConnMetadata, pubKey ssh.PublicKey) (*ssh.Permissions, error) {
+				fs.Debugf(nil, "Public key login attempt for %s from %s", c.User(), c.RemoteAddr())
+				if _, ok := authorizedKeysMap[string(pubKey.Marshal())]; ok {
+					return &ssh.Permissions{
+						// Record the public key used for authentication.
+						Extensions: map[string]string{
+							"pubkey-fp": ssh.FingerprintSHA256(pubKey),
+						},
+					}, nil
+				}
+				return nil, errors.Errorf("unknown public key for %q", c.User())
+			}
+		} else if s.opt.AuthorizedKeys != DefaultOpt.AuthorizedKeys || !os.IsNotExist(err) {
+			return errors.Wrap(err, "failed to load authorized keys")
+		}
+	}
+
+	if authMethods == 0 && !s.opt.NoAuth {
+		return errors.New("no authorization found, use --user/--pass or --authorized-keys or --no-auth")
+	}
+
+	// Load the private host keys, generating one if needed
+	keyPaths := s.opt.Keys
+	if len(keyPaths) == 0 {
+		keyPath := filepath.Join(config.CacheDir, "serve-sftp", "id_rsa")
+		err := makeRSAKey(keyPath)
+		if err != nil {
+			return err
+		}
+		keyPaths = []string{keyPath}
+	}
+	for _, keyPath := range keyPaths {
+		privateBytes, err := ioutil.ReadFile(keyPath)
+		if err != nil {
+			return errors.Wrap(err, "failed to load private key")
+		}
+		private, err := ssh.ParsePrivateKey(privateBytes)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse private key")
+		}
+		s.config.AddHostKey(private)
+	}
+	return nil
+}
+
+// makeRSAKey makes an RSA host key at keyPath if it doesn't exist
+func makeRSAKey(keyPath string) error {
+	if _, err := os.Stat(keyPath); err == nil {
+		return nil
+	}
+	fs.Logf(nil, "Generating host key %q", keyPath)
+	err := os.MkdirAll(filepath.Dir(keyPath), 0700)
+	if err != nil {
+		return errors.Wrap(err, "failed to create host key directory")
+	}
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate host key")
+	}
+	privateKeyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	})
+	err = ioutil.WriteFile(keyPath, privateKeyPEM, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to save host key")
+	}
+	return nil
+}
+
+// Serve starts the server listening in the background
+func (s *server) Serve() (err error) {
+	err = s.makeConfig()
+	if err != nil {
+		return err
+	}
+	s.listener, err = net.Listen("tcp", s.opt.ListenAddr)
+	if err != nil {
+		return errors.Wrap(err, "failed to listen for connection")
+	}
+	go s.acceptConnections()
+	return nil
+}
+
+// Addr returns the address the server is listening on
+func (s *server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Wait blocks until the server is closed
+func (s *server) Wait() {
+	<-s.waitChan
+}
+
+// Close stops the server listening and waits for the connections to
+// finish
+func (s *server) Close() {
+	err := s.listener.Close()
+	if err != nil {
+		fs.Errorf(nil, "Error on closing SFTP server: %v", err)
+		return
+	}
+	s.wg.Wait()
+}
+
+// acceptConnections accepts connections until the listener is closed
+func (s *server) acceptConnections() {
+	defer close(s.waitChan)
+	for {
+		nConn, err := s.listener.Accept()
+		if err != nil {
+			if strings.Contains(err.Error(), "use of closed network connection") {
+				return
+			}
+			fs.Errorf(nil, "Failed to accept incoming connection: %v", err)
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.acceptConnection(nConn)
+		}()
+	}
+}
+
+// acceptConnection does the ssh handshake on nConn then serves its
+// channels
+func (s *server) acceptConnection(nConn net.Conn) {
+	what := describeConn(nConn)
+
+	// Before use, a handshake must be performed on the incoming net.Conn.
+	sshConn, chans, reqs, err := ssh.NewServerConn(nConn, s.config)
+	if err != nil {
+		fs.Errorf(what, "SSH login failed: %v", err)
+		return
+	}
+	fs.Infof(what, "SSH login from %s using %s", sshConn.User(), sshConn.ClientVersion())
+
+	// Discard all global out-of-band Requests
+	go ssh.DiscardRequests(reqs)
+
+	// Service the incoming Channel channel.
+	for newChannel := range chans {
+		// Channels have a type, depending on the application level
+		// protocol intended. In the case of an SFTP session, this is "subsystem"
+		// with a payload string of "<length=4>sftp"
+		fs.Debugf(what, "Incoming channel: %s", newChannel.ChannelType())
+		if newChannel.ChannelType() != "session" {
+			err := newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			if err != nil {
+				fs.Errorf(what, "Failed to reject unknown channel: %v", err)
+			}
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			fs.Errorf(what, "could not accept channel: %v", err)
+			continue
+		}
+		go s.serveSession(what, channel, requests)
+	}
+	fs.Debugf(what, "SSH connection closed")
+}
+
+// serveSession serves the requests on an ssh session channel
+//
+// Only the sftp subsystem and the exec requests in commands are
+// allowed.
+func (s *server) serveSession(what string, channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer func() {
+		_ = channel.Close()
+	}()
+	for req := range requests {
+		fs.Debugf(what, "Incoming request: %s", req.Type)
+		ok := false
+		var run func()
+		switch req.Type {
+		case "subsystem":
+			// The payload is the name as an ssh string
+			if len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp" {
+				ok = true
+				run = func() { s.serveSFTP(what, channel) }
+			}
+		case "exec":
+			var command struct {
+				Command string
+			}
+			if err := ssh.Unmarshal(req.Payload, &command); err == nil {
+				ok = true
+				run = func() { s.serveCommand(what, channel, command.Command) }
+			}
+		}
+		fs.Debugf(what, " - accepted: %v", ok)
+		err := req.Reply(ok, nil)
+		if err != nil {
+			fs.Errorf(what, "Failed to reply to request: %v", err)
+			return
+		}
+		if run != nil {
+			run()
+			return
+		}
+	}
+}
+
+// describeConn returns a string describing the connection
+func describeConn(c interface {
+	RemoteAddr() net.Addr
+	LocalAddr() net.Addr
+}) string {
+	return fmt.Sprintf("serve sftp %s->%s", c.RemoteAddr(), c.LocalAddr())
+}
+
+// serveSFTP runs the sftp subsystem on channel until the client
+// disconnects
+func (s *server) serveSFTP(what string, channel ssh.Channel) {
+	fs.Debugf(what, "Starting SFTP server")
+	server := sftp.NewRequestServer(channel, newVFSHandler(s.vfs))
+	err := server.Serve()
+	if err != nil && err != io.EOF {
+		fs.Errorf(what, "Completed SFTP serve: %v", err)
+		return
+	}
+	fs.Debugf(what, "Completed SFTP serve")
+}
+
+// serveCommand runs the shell command on channel and sends its exit
+// status
+func (s *server) serveCommand(what string, channel ssh.Channel, command string) {
+	fs.Debugf(what, "Running command %q", command)
+	exitStatus := uint32(0)
+	err := s.runCommand(channel, command)
+	if err != nil {
+		fs.Errorf(what, "Command %q failed: %v", command, err)
+		_, _ = fmt.Fprintf(channel.Stderr(), "%v\n", err)
+		exitStatus = 1
+	}
+	_, err = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{exitStatus}))
+	if err != nil {
+		fs.Errorf(what, "Failed to send exit status: %v", err)
+	}
+}
+
+// runCommand runs the shell commands that the rclone sftp backend
+// uses to read hashes, writing the output to out
+//
+// These are "md5sum path" and "sha1sum path" and the "echo 'abc' |
+// md5sum" style probes used to see which hashes work.
+func (s *server) runCommand(out io.Writer, command string) error {
+	binary, args := command, ""
+	if i := strings.IndexRune(command, ' '); i >= 0 {
+		binary, args = command[:i], command[i+1:]
+	}
+	var ht hash.Type
+	switch binary {
+	case "md5sum":
+		ht = hash.MD5
+	case "sha1sum":
+		ht = hash.SHA1
+	case "echo":
+		switch args {
+		case "'abc' | md5sum":
+			ht = hash.MD5
+		case "'abc' | sha1sum":
+			ht = hash.SHA1
+		default:
+			return errors.Errorf("unsupported command %q", command)
+		}
+		if !s.f.Hashes().Contains(ht) {
+			return errors.Errorf("%v hash not supported", ht)
+		}
+		hasher, err := hash.NewMultiHasherTypes(hash.NewHashSet(ht))
+		if err != nil {
+			return err
+		}
+		_, _ = hasher.Write([]byte("abc\n"))
+		_, err = fmt.Fprintf(out, "%s  -\n", hasher.Sums()[ht])
+		return err
+	default:
+		return errors.Errorf("unsupported command %q", command)
+	}
+	if !s.f.Hashes().Contains(ht) {
+		return errors.Errorf("%v hash not supported", ht)
+	}
+	if args == "" {
+		return errors.Errorf("%s needs a file name", binary)
+	}
+	filePath := shellUnescape(args)
+	node, err := s.vfs.Stat(filePath)
+	if err != nil {
+		return errors.Wrapf(err, "%s: %s", binary, filePath)
+	}
+	if !node.IsFile() {
+		return errors.Errorf("%s: %s: Is a directory", binary, filePath)
+	}
+	o, ok := node.DirEntry().(fs.Object)
+	if !ok {
+		return errors.Errorf("%s: %s: file not uploaded yet", binary, filePath)
+	}
+	sum, err := o.Hash(ht)
+	if err != nil {
+		return errors.Wrapf(err, "%s: %s", binary, filePath)
+	}
+	_, err = fmt.Fprintf(out, "%s  %s\n", sum, filePath)
+	return err
+}
+
+// shellUnescape reverses the escaping the rclone sftp backend does
+// on file names before passing them to the shell
+func shellUnescape(str string) string {
+	str = strings.Replace(str, "'\n'", "\n", -1)
+	var out []byte
+	for i := 0; i < len(str); i++ {
+		if str[i] == '\\' && i+1 < len(str) {
+			i++
+		}
+		out = append(out, str[i])
+	}
+	return string(out)
+}
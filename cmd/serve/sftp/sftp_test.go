@@ -0,0 +1,154 @@
+package sftp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	testUser = "testuser"
+	testPass = "testpass"
+)
+
+// TestSFTP runs the server against a local directory and checks the
+// file operations and the hash commands work
+func TestSFTP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-serve-sftp")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	root := filepath.Join(dir, "root")
+	require.NoError(t, os.Mkdir(root, 0777))
+	f, err := fs.NewFs(root)
+	require.NoError(t, err)
+
+	keyPath := filepath.Join(dir, "keys", "id_rsa")
+	require.NoError(t, makeRSAKey(keyPath))
+
+	opt := DefaultOpt
+	opt.ListenAddr = "localhost:0"
+	opt.Keys = []string{keyPath}
+	opt.AuthorizedKeys = ""
+	opt.User = testUser
+	opt.Pass = testPass
+	s := newServer(f, &opt)
+	require.NoError(t, s.Serve())
+	defer s.Close()
+
+	// Check a bad password is rejected
+	_, err = ssh.Dial("tcp", s.Addr(), &ssh.ClientConfig{
+		User:            testUser,
+		Auth:            []ssh.AuthMethod{ssh.Password("wrong")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	require.Error(t, err)
+
+	sshClient, err := ssh.Dial("tcp", s.Addr(), &ssh.ClientConfig{
+		User:            testUser,
+		Auth:            []ssh.AuthMethod{ssh.Password(testPass)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	require.NoError(t, err)
+	defer func() {
+		_ = sshClient.Close()
+	}()
+	client, err := sftp.NewClient(sshClient)
+	require.NoError(t, err)
+	defer func() {
+		_ = client.Close()
+	}()
+
+	// Make a directory and write a file into it
+	require.NoError(t, client.Mkdir("/dir"))
+	out, err := client.Create("/dir/file.txt")
+	require.NoError(t, err)
+	_, err = out.Write([]byte("hello world"))
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+
+	fi, err := client.Stat("/dir/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), fi.Size())
+	assert.False(t, fi.IsDir())
+
+	modTime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	require.NoError(t, client.Chtimes("/dir/file.txt", modTime, modTime))
+	fi, err = client.Stat("/dir/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, modTime.Unix(), fi.ModTime().Unix())
+
+	// Read it back
+	in, err := client.Open("/dir/file.txt")
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, "hello world", string(data))
+
+	// Check the hash commands
+	for _, test := range []struct {
+		command string
+		want    string
+	}{
+		{"md5sum /dir/file.txt", "5eb63bbbe01eeed093cb22bb8f5acdc3  /dir/file.txt\n"},
+		{"sha1sum /dir/file.txt", "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed  /dir/file.txt\n"},
+		{"echo 'abc' | md5sum", "0bee89b07a248e27c83fc3d5951213c1  -\n"},
+		{"echo 'abc' | sha1sum", "03cfd743661f07975fa2f1220c5194cbaff48451  -\n"},
+	} {
+		session, err := sshClient.NewSession()
+		require.NoError(t, err)
+		got, err := session.Output(test.command)
+		_ = session.Close()
+		require.NoError(t, err, test.command)
+		assert.Equal(t, test.want, string(got), test.command)
+	}
+	session, err := sshClient.NewSession()
+	require.NoError(t, err)
+	_, err = session.Output("ls /")
+	_ = session.Close()
+	assert.Error(t, err)
+
+	// Rename and list
+	require.NoError(t, client.Rename("/dir/file.txt", "/dir/file2.txt"))
+	fis, err := client.ReadDir("/dir")
+	require.NoError(t, err)
+	var names []string
+	for _, fi := range fis {
+		names = append(names, fi.Name())
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"file2.txt"}, names)
+
+	// Remove the file and the directory
+	assert.Error(t, client.RemoveDirectory("/dir/file2.txt"))
+	require.NoError(t, client.Remove("/dir/file2.txt"))
+	require.NoError(t, client.RemoveDirectory("/dir"))
+	_, err = client.Stat("/dir")
+	assert.True(t, os.IsNotExist(err), err)
+}
+
+func TestShellUnescape(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want string
+	}{
+		{"/path/to/file.txt", "/path/to/file.txt"},
+		{`/path/with\ space`, "/path/with space"},
+		{`/it\'s\ \$5`, `/it's $5`},
+		{"/new'\n'line", "/new\nline"},
+	} {
+		assert.Equal(t, test.want, shellUnescape(test.in), test.in)
+	}
+}
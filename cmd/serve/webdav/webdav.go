@@ -34,6 +34,14 @@ remote over HTTP via the webdav protocol. This can be viewed with a
 webdav client or you can make a remote of type webdav to read and
 write it.
 
+This is useful for devices which can't mount the remote with FUSE,
+eg NAS appliances or Windows without WinFsp, as most operating
+systems can mount a webdav server directly.  For example Windows
+Explorer can map it as a network drive with "Map network drive" and
+the address "http://localhost:8080/", and macOS Finder can connect to
+it with "Go -> Connect to Server".  Use --read-only to stop the
+remote being changed.
+
 NB at the moment each directory listing reads the start of each file
 which is undesirable: see https://github.com/golang/go/issues/22577
 ` + httplib.Help + vfs.Help,
@@ -61,13 +69,17 @@ which is undesirable: see https://github.com/golang/go/issues/22577
 // might apply". In particular, whether or not renaming a file or directory
 // overwriting another existing file or directory is an error is OS-dependent.
 type WebDAV struct {
-	f   fs.Fs
-	vfs *vfs.VFS
-	srv *httplib.Server
+	f       fs.Fs
+	vfs     *vfs.VFS
+	srv     *httplib.Server
+	handler *webdav.Handler
 }
 
-// check interface
-var _ webdav.FileSystem = (*WebDAV)(nil)
+// check interfaces
+var (
+	_ webdav.FileSystem      = (*WebDAV)(nil)
+	_ httplib.BaseURLHandler = (*WebDAV)(nil)
+)
 
 // Make a new WebDAV to serve the remote
 func newWebDAV(f fs.Fs, opt *httplib.Options) *WebDAV {
@@ -76,16 +88,27 @@ func newWebDAV(f fs.Fs, opt *httplib.Options) *WebDAV {
 		vfs: vfs.New(f, &vfsflags.Opt),
 	}
 
-	handler := &webdav.Handler{
+	w.handler = &webdav.Handler{
 		FileSystem: w,
 		LockSystem: webdav.NewMemLS(),
 		Logger:     w.logRequest, // FIXME
 	}
 
-	w.srv = httplib.NewServer(handler, opt)
+	w.srv = httplib.NewServer(w, opt)
 	return w
 }
 
+// ServeHTTP serves the webdav requests
+func (w *WebDAV) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w.handler.ServeHTTP(rw, r)
+}
+
+// SetBaseURL sets the prefix the webdav handler strips from the
+// request paths and adds to the paths it returns
+func (w *WebDAV) SetBaseURL(baseURL string) {
+	w.handler.Prefix = baseURL
+}
+
 // serve runs the http server - doesn't return
 func (w *WebDAV) serve() {
 	fs.Logf(w.f, "WebDav Server started on %s", w.srv.URL())
@@ -8,14 +8,18 @@
 package webdav
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
+	"strings"
 	"testing"
 
 	_ "github.com/ncw/rclone/backend/local"
 	"github.com/ncw/rclone/cmd/serve/httplib"
 	"github.com/ncw/rclone/fstest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -68,3 +72,34 @@ func TestWebDav(t *testing.T) {
 	}
 	assert.NoError(t, err, "Running webdav integration tests")
 }
+
+// TestWebDavBaseURL checks the webdav handler serves from --baseurl
+// and returns paths including it
+func TestWebDavBaseURL(t *testing.T) {
+	opt := httplib.DefaultOpt
+	opt.BaseURL = "rclone/"
+
+	fstest.Initialise()
+
+	fremote, _, clean, err := fstest.RandomRemote(*fstest.RemoteName, *fstest.SubDir)
+	require.NoError(t, err)
+	defer clean()
+	require.NoError(t, fremote.Mkdir("dir"))
+
+	w := newWebDAV(fremote, &opt)
+	assert.Equal(t, "/rclone", w.handler.Prefix)
+
+	r, err := http.NewRequest("PROPFIND", "/rclone/", strings.NewReader(""))
+	require.NoError(t, err)
+	r.Header.Set("Depth", "1")
+	rw := httptest.NewRecorder()
+	w.ServeHTTP(rw, r)
+	assert.Equal(t, http.StatusMultiStatus, rw.Code)
+	assert.Contains(t, rw.Body.String(), "<D:href>/rclone/dir</D:href>")
+
+	r, err = http.NewRequest("PROPFIND", "/dir/", strings.NewReader(""))
+	require.NoError(t, err)
+	rw = httptest.NewRecorder()
+	w.ServeHTTP(rw, r)
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+}
@@ -0,0 +1,38 @@
+package settier
+
+import (
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "settier tier remote:path",
+	Short: `Changes the storage tier or class of the objects in remote:path.`,
+	Long: `
+Changes the storage tier or class of the objects in remote:path, eg
+to move data which is rarely read to a cheaper storage class.  This
+is supported by S3, where the tier is the storage class such as
+STANDARD, STANDARD_IA or REDUCED_REDUNDANCY, and by Google Cloud
+Storage, where it is the storage class such as MULTI_REGIONAL,
+NEARLINE or COLDLINE.
+
+    rclone settier STANDARD_IA s3:bucket/path/to/old/files
+
+The change is made on the server by copying each object to itself so
+the data isn't downloaded.  Use the filters to pick the objects to
+change and ` + "`rclone lsf --format pT`" + ` to see their tiers.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(2, 2, command, args)
+		tier := args[0]
+		fsrc := cmd.NewFsSrc(args[1:])
+		cmd.Run(true, false, command, func() error {
+			return operations.SetTier(fsrc, tier)
+		})
+	},
+}
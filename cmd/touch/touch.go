@@ -7,6 +7,7 @@ import (
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/object"
+	"github.com/ncw/rclone/fs/operations"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -55,6 +56,9 @@ func Touch(fsrc fs.Fs, srcFileName string) error {
 	file, err := fsrc.NewObject(srcFileName)
 	if err != nil {
 		if !notCreateNewFile {
+			if operations.SkipDestructive(srcFileName, "create file") {
+				return nil
+			}
 			var buffer []byte
 			src := object.NewStaticObjectInfo(srcFileName, timeAtr, int64(len(buffer)), true, nil, fsrc)
 			_, err = fsrc.Put(bytes.NewBuffer(buffer), src)
@@ -64,6 +68,9 @@ func Touch(fsrc fs.Fs, srcFileName string) error {
 		}
 		return nil
 	}
+	if operations.SkipDestructive(file, "touch") {
+		return nil
+	}
 	err = file.SetModTime(timeAtr)
 	if err != nil {
 		return errors.Wrap(err, "touch: couldn't set mod time")
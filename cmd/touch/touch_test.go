@@ -117,3 +117,15 @@ func TestTouchCreateMultipleDirAndFile(t *testing.T) {
 	file1 := fstest.NewItem("a/b/c.txt", "", t1)
 	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1}, []string{"a", "a/b"}, fs.ModTimeNotSupported)
 }
+
+func TestTouchDryRun(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	fs.Config.DryRun = true
+	defer func() { fs.Config.DryRun = false }()
+	err := Touch(r.Fremote, "newFile")
+	require.NoError(t, err)
+	_, err = r.Fremote.NewObject("newFile")
+	require.Error(t, err)
+}
@@ -0,0 +1,61 @@
+package unmount
+
+import (
+	"path/filepath"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/cmd/mountlib"
+	rccmd "github.com/ncw/rclone/cmd/rc"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	url     = "http://localhost:5572/"
+	timeout = mountlib.DefaultUnmountTimeout
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+	commandDefintion.Flags().StringVarP(&url, "url", "", url, "URL to connect to the remote control of the mount.")
+	commandDefintion.Flags().DurationVarP(&timeout, "timeout", "", timeout, "Time to wait for open files to be closed before unmounting.")
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "unmount /path/to/mountpoint",
+	Short: `Unmount a running rclone mount.`,
+	Long: `
+This asks the rclone mount at the mountpoint to unmount itself.  It
+waits for the files being written through the mount to be closed and
+uploaded before unmounting so no writes are lost, which unmounting
+with ` + "`fusermount -u`" + ` or ` + "`umount`" + ` doesn't guarantee.
+
+It uses the remote control so the mount must have been started with
+` + "`--rc`" + `.  If it was started with ` + "`--rc-addr`" + ` then pass the
+address with ` + "`--url`" + `, eg
+
+    rclone mount remote: /path/to/mountpoint --rc --rc-addr localhost:5573
+    rclone unmount /path/to/mountpoint --url http://localhost:5573/
+
+Files which are still open after ` + "`--timeout`" + ` are abandoned.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		cmd.Run(false, false, command, func() error {
+			// the mount may be running in a different directory
+			mountpoint, err := filepath.Abs(args[0])
+			if err != nil {
+				return err
+			}
+			_, err = rccmd.Call(url, "mount/unmount", rc.Params{
+				"mountPoint": mountpoint,
+				"timeout":    timeout.String(),
+			})
+			if err != nil {
+				return errors.Wrap(err, "failed to unmount - is the mount running with --rc?")
+			}
+			return nil
+		})
+	},
+}
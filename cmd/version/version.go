@@ -1,19 +1,163 @@
 package version
 
 import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
 	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/fshttp"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
+// URLs of the files containing the latest versions of rclone
+const (
+	StableVersionURL = "https://downloads.rclone.org/version.txt"
+	BetaVersionURL   = "https://beta.rclone.org/version.txt"
+)
+
+var (
+	check = false
+)
+
 func init() {
 	cmd.Root.AddCommand(commandDefinition)
+	flags := commandDefinition.Flags()
+	flags.BoolVarP(&check, "check", "", false, "Check for new version.")
 }
 
 var commandDefinition = &cobra.Command{
 	Use:   "version",
 	Short: `Show the version number.`,
+	Long: `
+Show the version number, the go version and the architecture.
+
+Eg
+
+    $ rclone version
+    rclone v1.41
+    - os/arch: linux/amd64
+    - go version: go1.10
+
+If you supply the --check flag, then it will do an online check to
+compare your version with the latest release and the latest beta.
+
+    $ rclone version --check
+    yours:  1.40-DEV
+    latest: 1.41
+    beta:   1.41-012-gabcdef12β
+    upgrade: https://rclone.org/downloads/
+
+Use "rclone selfupdate" to install the latest release.
+`,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(0, 0, command, args)
+		if check {
+			cmd.Run(false, false, command, checkVersion)
+			return
+		}
 		cmd.ShowVersion()
 	},
 }
+
+// Version is a parsed rclone version, eg [1, 41] for v1.41 or
+// v1.41-012-gabcdef12β
+type Version []int
+
+// versionMatch matches the numeric part of a version
+var versionMatch = regexp.MustCompile(`^v?(\d+(?:\.\d+)*)`)
+
+// ParseVersion parses a version string like "v1.41" or "rclone
+// v1.41-012-gabcdef12β" into its numeric parts.
+func ParseVersion(s string) (v Version, err error) {
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "rclone"))
+	match := versionMatch.FindStringSubmatch(s)
+	if match == nil {
+		return nil, errors.Errorf("couldn't parse version %q", s)
+	}
+	for _, part := range strings.Split(match[1], ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't parse version %q", s)
+		}
+		v = append(v, n)
+	}
+	return v, nil
+}
+
+// String converts v to a string, eg "1.41"
+func (v Version) String() string {
+	parts := make([]string, len(v))
+	for i, n := range v {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ".")
+}
+
+// Cmp compares v with o returning <0 if v is older than o, 0 if they
+// are the same and >0 if v is newer.  Missing parts count as 0.
+func (v Version) Cmp(o Version) int {
+	for i := 0; i < len(v) || i < len(o); i++ {
+		var a, b int
+		if i < len(v) {
+			a = v[i]
+		}
+		if i < len(o) {
+			b = o[i]
+		}
+		if a != b {
+			return a - b
+		}
+	}
+	return 0
+}
+
+// GetVersion fetches the version file from url returning the version
+// string in it, eg "v1.41", and its parsed form.
+func GetVersion(url string) (v Version, vs string, err error) {
+	resp, err := fshttp.NewClient(fs.Config).Get(url)
+	if err != nil {
+		return v, vs, err
+	}
+	defer fs.CheckClose(resp.Body, &err)
+	if resp.StatusCode != http.StatusOK {
+		return v, vs, errors.Errorf("failed to fetch %q: %s", url, resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return v, vs, err
+	}
+	vs = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(body)), "rclone"))
+	v, err = ParseVersion(vs)
+	return v, vs, err
+}
+
+// checkVersion prints the running version and the latest release and
+// beta versions
+func checkVersion() error {
+	fmt.Printf("yours:  %s\n", strings.TrimPrefix(fs.Version, "v"))
+	yours, err := ParseVersion(fs.Version)
+	if err != nil {
+		fs.Errorf(nil, "Failed to parse version: %v", err)
+	}
+	printVersion := func(what, url string) Version {
+		v, vs, err := GetVersion(url)
+		if err != nil {
+			fs.Errorf(nil, "Failed to get %s version: %v", what, err)
+			return nil
+		}
+		fmt.Printf("%-8s%s\n", what+":", strings.TrimPrefix(vs, "v"))
+		return v
+	}
+	latest := printVersion("latest", StableVersionURL)
+	printVersion("beta", BetaVersionURL)
+	if yours != nil && latest != nil && yours.Cmp(latest) < 0 {
+		fmt.Println("upgrade: https://rclone.org/downloads/")
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/fs/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestVersionWorksWithoutAccessibleConfigFile(t *testing.T) {
@@ -41,3 +42,45 @@ func TestVersionWorksWithoutAccessibleConfigFile(t *testing.T) {
 		assert.NoError(t, cmd.Root.Execute())
 	})
 }
+
+func TestParseVersion(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want Version
+	}{
+		{"v1.41", Version{1, 41}},
+		{"rclone v1.41\n", Version{1, 41}},
+		{"v1.40-DEV", Version{1, 40}},
+		{"v1.41-012-gabcdef12β", Version{1, 41}},
+		{"1.41.1", Version{1, 41, 1}},
+	} {
+		got, err := ParseVersion(test.in)
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+	_, err := ParseVersion("potato")
+	assert.Error(t, err)
+}
+
+func TestVersionCmp(t *testing.T) {
+	for _, test := range []struct {
+		a, b Version
+		want int
+	}{
+		{Version{1, 41}, Version{1, 41}, 0},
+		{Version{1, 41}, Version{1, 41, 0}, 0},
+		{Version{1, 40}, Version{1, 41}, -1},
+		{Version{1, 41, 1}, Version{1, 41}, 1},
+		{Version{2, 0}, Version{1, 99}, 1},
+	} {
+		got := test.a.Cmp(test.b)
+		switch {
+		case got < 0:
+			got = -1
+		case got > 0:
+			got = 1
+		}
+		assert.Equal(t, test.want, got, "%v cmp %v", test.a, test.b)
+	}
+	assert.Equal(t, "1.41.1", Version{1, 41, 1}.String())
+}
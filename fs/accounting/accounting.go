@@ -10,6 +10,9 @@ import (
 	"github.com/VividCortex/ewma"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/asyncreader"
+	"github.com/ncw/rclone/fs/rc"
+	"golang.org/x/net/context" // switch to "context" when we stop supporting go1.6
+	"golang.org/x/time/rate"
 )
 
 // Account limits and accounts for one transfer
@@ -34,11 +37,20 @@ type Account struct {
 	closed  bool               // set if the file is closed
 	exit    chan struct{}      // channel that will be closed when transfer is finished
 	withBuf bool               // is using a buffered in
+	counted bool               // set if the buffer counts the bytes downloaded
+	limiter *rate.Limiter      // per transfer bandwidth limit or nil
+	stats   *StatsInfo         // stats the transfer is accounted to
 }
 
 // NewAccountSizeName makes a Account reader for an io.ReadCloser of
 // the given size and name
 func NewAccountSizeName(in io.ReadCloser, size int64, name string) *Account {
+	return newAccount(in, size, name, Stats)
+}
+
+// newAccount makes a Account reader for an io.ReadCloser of the
+// given size and name which is accounted to stats
+func newAccount(in io.ReadCloser, size int64, name string, stats *StatsInfo) *Account {
 	acc := &Account{
 		in:     in,
 		close:  in,
@@ -48,6 +60,10 @@ func NewAccountSizeName(in io.ReadCloser, size int64, name string) *Account {
 		exit:   make(chan struct{}),
 		avg:    ewma.NewMovingAverage(),
 		lpTime: time.Now(),
+		stats:  stats,
+	}
+	if fs.Config.BwLimitFile > 0 {
+		acc.limiter = newTokenBucket(fs.Config.BwLimitFile)
 	}
 	go acc.averageLoop()
 	Stats.inProgress.set(acc.name, acc)
@@ -56,7 +72,7 @@ func NewAccountSizeName(in io.ReadCloser, size int64, name string) *Account {
 
 // NewAccount makes a Account reader for an object
 func NewAccount(in io.ReadCloser, obj fs.Object) *Account {
-	return NewAccountSizeName(in, obj.Size(), obj.Remote())
+	return newAccount(in, obj.Size(), obj.Remote(), StatsFor(obj.Fs()))
 }
 
 // WithBuffer - If the file is above a certain size it adds an Async reader
@@ -70,12 +86,13 @@ func (acc *Account) WithBuffer() *Account {
 	}
 	// On big files add a buffer
 	if buffers > 0 {
-		rc, err := asyncreader.New(acc.origIn, buffers)
+		rc, err := asyncreader.New(&downloadCounter{acc.origIn, acc.stats}, buffers)
 		if err != nil {
 			fs.Errorf(acc.name, "Failed to make buffer: %v", err)
 		} else {
 			acc.in = rc
 			acc.close = rc
+			acc.counted = true
 		}
 	}
 	return acc
@@ -103,10 +120,28 @@ func (acc *Account) UpdateReader(in io.ReadCloser) {
 	acc.in = in
 	acc.close = in
 	acc.origIn = in
+	acc.counted = false
 	acc.WithBuffer()
 	acc.mu.Unlock()
 }
 
+// downloadCounter counts the bytes read from the source of a
+// transfer into the buffer.
+//
+// These are counted separately from the bytes read from the Account
+// as the buffer may read ahead of the upload.
+type downloadCounter struct {
+	io.ReadCloser
+	stats *StatsInfo
+}
+
+// Read bytes from the source and count them
+func (d *downloadCounter) Read(p []byte) (n int, err error) {
+	n, err = d.ReadCloser.Read(p)
+	d.stats.Downloaded(int64(n))
+	return n, err
+}
+
 // averageLoop calculates averages for the stats in the background
 func (acc *Account) averageLoop() {
 	tick := time.NewTicker(time.Second)
@@ -146,12 +181,29 @@ func (acc *Account) read(in io.Reader, p []byte) (n int, err error) {
 	acc.bytes += int64(n)
 	acc.statmu.Unlock()
 
-	Stats.Bytes(int64(n))
+	acc.stats.Bytes(int64(n))
+	if !acc.counted {
+		// without a buffer bytes are downloaded as they are read
+		acc.stats.Downloaded(int64(n))
+	}
 
 	limitBandwidth(n)
+	acc.limitPerTransfer(n)
 	return
 }
 
+// limitPerTransfer sleeps for the correct amount of time for the
+// passage of n bytes according to the --bwlimit-file limit
+func (acc *Account) limitPerTransfer(n int) {
+	if acc.limiter == nil {
+		return
+	}
+	err := acc.limiter.WaitN(context.Background(), n)
+	if err != nil {
+		fs.Errorf(acc.name, "Per file token bucket error: %v", err)
+	}
+}
+
 // Read bytes from the object - see io.Reader
 func (acc *Account) Read(p []byte) (n int, err error) {
 	acc.mu.Lock()
@@ -268,6 +320,28 @@ func (acc *Account) String() string {
 	)
 }
 
+// RemoteStats produces stats for this file for the remote control
+func (acc *Account) RemoteStats() rc.Params {
+	bytes, size := acc.progress()
+	speedAvg, speed := acc.speed()
+	out := rc.Params{
+		"name":     acc.name,
+		"size":     size,
+		"bytes":    bytes,
+		"speed":    speed,
+		"speedAvg": speedAvg,
+	}
+	percentage := 0
+	if size > 0 {
+		percentage = int(100 * float64(bytes) / float64(size))
+	}
+	out["percentage"] = percentage
+	if eta, ok := acc.eta(); ok {
+		out["eta"] = eta.Seconds()
+	}
+	return out
+}
+
 // OldStream returns the top io.Reader
 func (acc *Account) OldStream() io.Reader {
 	acc.mu.Lock()
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/asyncreader"
 	"github.com/ncw/rclone/fstest/mockobject"
 	"github.com/stretchr/testify/assert"
@@ -42,6 +43,21 @@ func TestNewAccount(t *testing.T) {
 	assert.Nil(t, Stats.inProgress.get("test"))
 }
 
+func TestNewAccountBwLimitFile(t *testing.T) {
+	in := ioutil.NopCloser(bytes.NewBuffer([]byte{1}))
+	acc := NewAccountSizeName(in, 1, "test")
+	assert.Nil(t, acc.limiter)
+	assert.NoError(t, acc.Close())
+
+	oldBwLimitFile := fs.Config.BwLimitFile
+	fs.Config.BwLimitFile = fs.SizeSuffix(1 << 20)
+	defer func() { fs.Config.BwLimitFile = oldBwLimitFile }()
+	acc = NewAccountSizeName(in, 1, "test")
+	require.NotNil(t, acc.limiter)
+	assert.Equal(t, float64(1<<20), float64(acc.limiter.Limit()))
+	assert.NoError(t, acc.Close())
+}
+
 func TestAccountWithBuffer(t *testing.T) {
 	in := ioutil.NopCloser(bytes.NewBuffer([]byte{1}))
 
@@ -106,6 +122,35 @@ func TestAccountRead(t *testing.T) {
 	assert.NoError(t, acc.Close())
 }
 
+func TestAccountDownloaded(t *testing.T) {
+	Stats.ResetCounters()
+	in := ioutil.NopCloser(bytes.NewBuffer(make([]byte, 3*asyncreader.BufferSize)))
+	acc := NewAccountSizeName(in, 3*asyncreader.BufferSize, "test").WithBuffer()
+
+	// the buffer reads ahead of what is uploaded
+	var buf = make([]byte, 2)
+	_, err := io.ReadFull(acc, buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), Stats.GetBytes())
+	assert.True(t, Stats.GetDownloaded() >= 2)
+
+	_, err = io.Copy(ioutil.Discard, acc)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3*asyncreader.BufferSize), Stats.GetBytes())
+	assert.Equal(t, int64(3*asyncreader.BufferSize), Stats.GetDownloaded())
+	assert.NoError(t, acc.Close())
+
+	// without a buffer they are the same
+	Stats.ResetCounters()
+	in = ioutil.NopCloser(bytes.NewBuffer([]byte{1, 2, 3}))
+	acc = NewAccountSizeName(in, 3, "test")
+	_, err = io.Copy(ioutil.Discard, acc)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), Stats.GetBytes())
+	assert.Equal(t, int64(3), Stats.GetDownloaded())
+	assert.NoError(t, acc.Close())
+}
+
 func TestAccountString(t *testing.T) {
 	in := ioutil.NopCloser(bytes.NewBuffer([]byte{1, 2, 3}))
 	acc := NewAccountSizeName(in, 3, "test")
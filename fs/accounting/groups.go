@@ -0,0 +1,113 @@
+// Named groups of stats so concurrent operations can be told apart
+
+package accounting
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+)
+
+// statsGroups holds the named groups of stats and which group each
+// Fs is accounted to
+type statsGroups struct {
+	mu     sync.Mutex
+	groups map[string]*StatsInfo
+	byFs   map[fs.Info]*StatsInfo
+}
+
+// The global stats groups
+var groups = newStatsGroups()
+
+// newStatsGroups makes a new empty statsGroups
+func newStatsGroups() *statsGroups {
+	return &statsGroups{
+		groups: make(map[string]*StatsInfo),
+		byFs:   make(map[fs.Info]*StatsInfo),
+	}
+}
+
+// NewStatsGroup returns the stats group called name, making it if
+// necessary, and accounts everything done to the Fs passed in to it.
+//
+// Everything counted in a group is counted in the global Stats too.
+//
+// Call StopStatsGroup when the operations on the Fs are finished.
+func NewStatsGroup(name string, fss ...fs.Info) *StatsInfo {
+	groups.mu.Lock()
+	defer groups.mu.Unlock()
+	s, found := groups.groups[name]
+	if !found {
+		s = NewStats()
+		s.inProgress = Stats.inProgress
+		s.parent = Stats
+		groups.groups[name] = s
+	}
+	for _, f := range fss {
+		groups.byFs[f] = s
+	}
+	return s
+}
+
+// StopStatsGroup stops accounting anything more to the group called
+// name.  The group is kept so its stats can still be read.
+func StopStatsGroup(name string) {
+	groups.mu.Lock()
+	defer groups.mu.Unlock()
+	s := groups.groups[name]
+	for f, group := range groups.byFs {
+		if group == s {
+			delete(groups.byFs, f)
+		}
+	}
+}
+
+// RemoveStatsGroup stops and removes the group called name
+func RemoveStatsGroup(name string) {
+	StopStatsGroup(name)
+	groups.mu.Lock()
+	defer groups.mu.Unlock()
+	delete(groups.groups, name)
+}
+
+// StatsGroup returns the stats group called name or nil if it
+// doesn't exist
+func StatsGroup(name string) *StatsInfo {
+	groups.mu.Lock()
+	defer groups.mu.Unlock()
+	return groups.groups[name]
+}
+
+// StatsGroupNames returns the sorted names of the stats groups
+func StatsGroupNames() []string {
+	groups.mu.Lock()
+	defer groups.mu.Unlock()
+	names := make([]string, 0, len(groups.groups))
+	for name := range groups.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StatsFor returns the stats which operations on f should be
+// accounted to.  This is the group f was added to if any, otherwise
+// the global Stats.
+func StatsFor(f fs.Info) *StatsInfo {
+	if f == nil {
+		return Stats
+	}
+	groups.mu.Lock()
+	defer groups.mu.Unlock()
+	if s, found := groups.byFs[f]; found {
+		return s
+	}
+	return Stats
+}
+
+// CountError counts err in the stats which operations on f are
+// accounted to, and so in the global Stats too.
+func CountError(f fs.Info, err error) {
+	StatsFor(f).Error(err)
+}
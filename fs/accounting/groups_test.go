@@ -0,0 +1,111 @@
+package accounting
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/ncw/rclone/fstest/mockobject"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// testFs is an fs.Info which can be told apart from other ones
+type testFs struct {
+	fs.Info
+	name string
+}
+
+func TestStatsGroups(t *testing.T) {
+	f1, f2, other := &testFs{name: "f1"}, &testFs{name: "f2"}, &testFs{name: "other"}
+	assert.Equal(t, Stats, StatsFor(f1))
+	assert.Equal(t, Stats, StatsFor(nil))
+
+	group := NewStatsGroup("test-group", f1, f2)
+	defer RemoveStatsGroup("test-group")
+	assert.Equal(t, group, StatsFor(f1))
+	assert.Equal(t, group, StatsFor(f2))
+	assert.Equal(t, Stats, StatsFor(other))
+	assert.Equal(t, group, StatsGroup("test-group"))
+	assert.Equal(t, group, NewStatsGroup("test-group"))
+	assert.Nil(t, StatsGroup("potato"))
+	assert.Contains(t, StatsGroupNames(), "test-group")
+
+	// Things counted in the group are counted globally too
+	bytesBefore, checksBefore := Stats.GetBytes(), Stats.GetChecks()
+	group.Bytes(10)
+	group.Checking("file")
+	group.DoneChecking("file")
+	assert.Equal(t, int64(10), group.GetBytes())
+	assert.Equal(t, int64(1), group.GetChecks())
+	assert.Equal(t, bytesBefore+10, Stats.GetBytes())
+	assert.Equal(t, checksBefore+1, Stats.GetChecks())
+
+	// Stopped groups account nothing more but can still be read
+	StopStatsGroup("test-group")
+	assert.Equal(t, Stats, StatsFor(f1))
+	assert.Equal(t, group, StatsGroup("test-group"))
+
+	out, err := rcStats(context.Background(), rc.Params{"group": "test-group"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), out["bytes"])
+	_, err = rcStats(context.Background(), rc.Params{"group": "potato"})
+	assert.Error(t, err)
+
+	out, err = rcGroupList(context.Background(), rc.Params{})
+	require.NoError(t, err)
+	assert.Contains(t, out["groups"], "test-group")
+
+	_, err = rcStatsDelete(context.Background(), rc.Params{"group": "test-group"})
+	require.NoError(t, err)
+	assert.Nil(t, StatsGroup("test-group"))
+	_, err = rcStatsDelete(context.Background(), rc.Params{"group": "test-group"})
+	assert.Error(t, err)
+}
+
+// groupObject is an object on an Fs which can be in a stats group
+type groupObject struct {
+	fs.Object
+	f fs.Info
+}
+
+// Fs returns the Fs the object is on
+func (o groupObject) Fs() fs.Info {
+	return o.f
+}
+
+func TestAccountStatsGroup(t *testing.T) {
+	f := &testFs{name: "f"}
+	group := NewStatsGroup("test-account", f)
+	defer RemoveStatsGroup("test-account")
+
+	in := ioutil.NopCloser(bytes.NewBuffer([]byte{1, 2, 3}))
+	obj := groupObject{Object: mockobject.Object("test"), f: f}
+	acc := NewAccount(in, obj)
+	_, err := ioutil.ReadAll(acc)
+	require.NoError(t, err)
+	require.NoError(t, acc.Close())
+	assert.Equal(t, int64(3), group.GetBytes())
+	assert.Equal(t, int64(3), group.GetDownloaded())
+}
+
+func TestCountErrorStatsGroup(t *testing.T) {
+	f, other := &testFs{name: "f"}, &testFs{name: "other"}
+	group := NewStatsGroup("test-error", f)
+	defer RemoveStatsGroup("test-error")
+
+	errorsBefore := Stats.GetErrors()
+	CountError(other, errors.New("other error"))
+	assert.False(t, group.Errored())
+	assert.Equal(t, errorsBefore+1, Stats.GetErrors())
+
+	CountError(f, errors.New("group error"))
+	assert.True(t, group.Errored())
+	assert.Equal(t, int64(1), group.GetErrors())
+	assert.Equal(t, "group error", group.GetLastError().Error())
+	assert.Equal(t, errorsBefore+2, Stats.GetErrors())
+}
@@ -3,11 +3,15 @@ package accounting
 import (
 	"bytes"
 	"fmt"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -15,24 +19,125 @@ var (
 	Stats = NewStats()
 )
 
+// maxRecentErrors is the number of errors remembered so they can be
+// uncounted
+const maxRecentErrors = 100
+
 func init() {
 	// Set the function pointer up in fs
 	fs.CountError = Stats.Error
+	fs.CountLowLevelRetry = Stats.LowLevelRetry
+
+	rc.Add(rc.Call{
+		Path:  "core/stats",
+		Fn:    rcStats,
+		Title: "Returns stats about current transfers.",
+		Help: `
+This returns all available stats.  If the group parameter is set then
+it returns the stats for that stats group only, otherwise it returns
+the stats for everything.
+
+- bytes - bytes uploaded so far
+- downloaded - bytes read from the source of transfers so far
+- speed - average speed in bytes/sec since the start of the process
+- elapsedTime - time in seconds since the start of the process
+- errors - number of errors
+- lastError - last error string, if any
+- checks - number of checked files
+- transfers - number of transferred files
+- deletes - number of deleted files
+- serverSideCopies, serverSideCopyBytes - server side copies and the bytes they copied
+- serverSideMoves, serverSideMoveBytes - server side moves and the bytes they moved
+- checking - names of the files currently being checked
+- transferring - the files currently being transferred, each with its
+  name, size, bytes, percentage, speed, speedAvg and eta (in seconds,
+  if known)`,
+	})
+	rc.Add(rc.Call{
+		Path:  "core/group-list",
+		Fn:    rcGroupList,
+		Title: "Returns the names of the stats groups.",
+		Help: `
+This returns the names of the stats groups as a list in the groups
+response.
+
+Each job started with _async=true is accounted in its own stats group
+called "job/ID" so its progress can be read separately from anything
+else running.`,
+	})
+	rc.Add(rc.Call{
+		Path:  "core/stats-delete",
+		Fn:    rcStatsDelete,
+		Title: "Removes a stats group.",
+		Help: `
+This takes the name of the stats group to remove in the group
+parameter.  The stats it counted remain in the global stats.`,
+	})
+}
+
+// rcStats returns the stats for the rc
+func rcStats(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	if _, found := in["group"]; !found {
+		return Stats.RemoteStats(), nil
+	}
+	group, err := in.GetString("group")
+	if err != nil {
+		return nil, err
+	}
+	s := StatsGroup(group)
+	if s == nil {
+		return nil, errors.Errorf("stats group %q not found", group)
+	}
+	return s.RemoteStats(), nil
+}
+
+// rcGroupList returns the names of the stats groups
+func rcGroupList(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	return rc.Params{"groups": StatsGroupNames()}, nil
+}
+
+// rcStatsDelete removes a stats group
+func rcStatsDelete(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	group, err := in.GetString("group")
+	if err != nil {
+		return nil, err
+	}
+	if StatsGroup(group) == nil {
+		return nil, errors.Errorf("stats group %q not found", group)
+	}
+	RemoveStatsGroup(group)
+	return nil, nil
 }
 
 // StatsInfo accounts all transfers
 type StatsInfo struct {
 	lock         sync.RWMutex
 	bytes        int64
+	downloaded   int64
 	errors       int64
 	lastError    error
+	recentErrors []error // errors most recently counted, newest last
+	retries      int64
 	checks       int64
 	checking     stringSet
 	transfers    int64
 	transferring stringSet
 	deletes      int64
+	serverSide   serverSideStats
 	start        time.Time
 	inProgress   *inProgress
+	parent       *StatsInfo // if set all updates are passed on to this too
+}
+
+// serverSideStats counts the server side copies and moves.
+//
+// The bytes these move aren't counted in the bytes transferred as
+// they don't pass through rclone.
+type serverSideStats struct {
+	copies    int64
+	copyBytes int64
+	moves     int64
+	moveBytes int64
 }
 
 // NewStats cretates an initialised StatsInfo
@@ -74,6 +179,18 @@ Elapsed time:  %10v
 		s.checks,
 		s.transfers,
 		dtRounded)
+	if s.downloaded != s.bytes {
+		fmt.Fprintf(buf, "Downloaded:    %10s\n", fs.SizeSuffix(s.downloaded).Unit("Bytes"))
+	}
+	if s.serverSide.copies > 0 {
+		fmt.Fprintf(buf, "Server side copies: %d (%s)\n", s.serverSide.copies, fs.SizeSuffix(s.serverSide.copyBytes).Unit("Bytes"))
+	}
+	if s.serverSide.moves > 0 {
+		fmt.Fprintf(buf, "Server side moves: %d (%s)\n", s.serverSide.moves, fs.SizeSuffix(s.serverSide.moveBytes).Unit("Bytes"))
+	}
+	if s.deletes > 0 {
+		fmt.Fprintf(buf, "Deleted:       %10d\n", s.deletes)
+	}
 	if len(s.checking) > 0 {
 		fmt.Fprintf(buf, "Checking:\n%s\n", s.checking)
 	}
@@ -83,6 +200,45 @@ Elapsed time:  %10v
 	return buf.String()
 }
 
+// RemoteStats returns the stats for the remote control
+func (s *StatsInfo) RemoteStats() rc.Params {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	dt := time.Now().Sub(s.start)
+	speed := 0.0
+	if dt > 0 {
+		speed = float64(s.bytes) / dt.Seconds()
+	}
+	out := rc.Params{
+		"bytes":               s.bytes,
+		"downloaded":          s.downloaded,
+		"speed":               speed,
+		"elapsedTime":         dt.Seconds(),
+		"errors":              s.errors,
+		"checks":              s.checks,
+		"transfers":           s.transfers,
+		"deletes":             s.deletes,
+		"serverSideCopies":    s.serverSide.copies,
+		"serverSideCopyBytes": s.serverSide.copyBytes,
+		"serverSideMoves":     s.serverSide.moves,
+		"serverSideMoveBytes": s.serverSide.moveBytes,
+		"checking":            s.checking.names(),
+	}
+	if s.lastError != nil {
+		out["lastError"] = s.lastError.Error()
+	}
+	transferring := []rc.Params{}
+	for _, name := range s.transferring.names() {
+		if acc := s.inProgress.get(name); acc != nil {
+			transferring = append(transferring, acc.RemoteStats())
+		} else {
+			transferring = append(transferring, rc.Params{"name": name})
+		}
+	}
+	out["transferring"] = transferring
+	return out
+}
+
 // Log outputs the StatsInfo to the log
 func (s *StatsInfo) Log() {
 	fs.LogLevelPrintf(fs.Config.StatsLogLevel, nil, "%v\n", s)
@@ -90,13 +246,62 @@ func (s *StatsInfo) Log() {
 
 // Bytes updates the stats for bytes bytes
 func (s *StatsInfo) Bytes(bytes int64) {
+	if s.parent != nil {
+		s.parent.Bytes(bytes)
+	}
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	s.bytes += bytes
 }
 
+// Downloaded updates the stats for bytes read from the source of
+// transfers
+func (s *StatsInfo) Downloaded(bytes int64) {
+	if s.parent != nil {
+		s.parent.Downloaded(bytes)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.downloaded += bytes
+}
+
+// GetDownloaded returns the number of bytes read from the source of
+// transfers so far.
+//
+// This can be larger than GetBytes when transfers are buffered as
+// GetBytes only counts the bytes which have been uploaded.
+func (s *StatsInfo) GetDownloaded() int64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.downloaded
+}
+
+// GetBytes returns the number of bytes transferred so far
+func (s *StatsInfo) GetBytes() int64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.bytes
+}
+
+// GetChecks returns the number of checks done so far
+func (s *StatsInfo) GetChecks() int64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.checks
+}
+
+// GetDeletes returns the number of deletes done so far
+func (s *StatsInfo) GetDeletes() int64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.deletes
+}
+
 // Errors updates the stats for errors
 func (s *StatsInfo) Errors(errors int64) {
+	if s.parent != nil {
+		s.parent.Errors(errors)
+	}
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	s.errors += errors
@@ -116,23 +321,89 @@ func (s *StatsInfo) GetLastError() error {
 	return s.lastError
 }
 
+// LowLevelRetry counts a low level retry
+func (s *StatsInfo) LowLevelRetry() {
+	if s.parent != nil {
+		s.parent.LowLevelRetry()
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.retries++
+}
+
+// GetLowLevelRetries reads the number of low level retries
+func (s *StatsInfo) GetLowLevelRetries() int64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.retries
+}
+
 // Deletes updates the stats for deletes
 func (s *StatsInfo) Deletes(deletes int64) int64 {
+	if s.parent != nil {
+		s.parent.Deletes(deletes)
+	}
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	s.deletes += deletes
 	return s.deletes
 }
 
+// ServerSideCopy counts a server side copy of size bytes
+func (s *StatsInfo) ServerSideCopy(size int64) {
+	if s.parent != nil {
+		s.parent.ServerSideCopy(size)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.serverSide.copies++
+	if size > 0 {
+		s.serverSide.copyBytes += size
+	}
+}
+
+// ServerSideMove counts a server side move of size bytes
+func (s *StatsInfo) ServerSideMove(size int64) {
+	if s.parent != nil {
+		s.parent.ServerSideMove(size)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.serverSide.moves++
+	if size > 0 {
+		s.serverSide.moveBytes += size
+	}
+}
+
+// GetServerSideCopies returns the number of server side copies and
+// the bytes they copied
+func (s *StatsInfo) GetServerSideCopies() (copies, bytes int64) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.serverSide.copies, s.serverSide.copyBytes
+}
+
+// GetServerSideMoves returns the number of server side moves and the
+// bytes they moved
+func (s *StatsInfo) GetServerSideMoves() (moves, bytes int64) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.serverSide.moves, s.serverSide.moveBytes
+}
+
 // ResetCounters sets the counters (bytes, checks, errors, transfers) to 0
 func (s *StatsInfo) ResetCounters() {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 	s.bytes = 0
+	s.downloaded = 0
 	s.errors = 0
+	s.recentErrors = nil
+	s.retries = 0
 	s.checks = 0
 	s.transfers = 0
 	s.deletes = 0
+	s.serverSide = serverSideStats{}
 }
 
 // ResetErrors sets the errors count to 0
@@ -140,6 +411,7 @@ func (s *StatsInfo) ResetErrors() {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 	s.errors = 0
+	s.recentErrors = nil
 }
 
 // Errored returns whether there have been any errors
@@ -151,14 +423,78 @@ func (s *StatsInfo) Errored() bool {
 
 // Error adds a single error into the stats and assigns lastError
 func (s *StatsInfo) Error(err error) {
+	if s.parent != nil {
+		s.parent.Error(err)
+	}
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	s.errors++
 	s.lastError = err
+	// Only errors which can be compared can be uncounted
+	if err != nil && reflect.TypeOf(err).Comparable() {
+		if len(s.recentErrors) >= maxRecentErrors {
+			s.recentErrors = s.recentErrors[1:]
+		}
+		s.recentErrors = append(s.recentErrors, err)
+	}
+}
+
+// Uncount removes the counts of err made by Error, returning how many
+// were removed.  This is for operations which failed with err but are
+// going to be retried.
+//
+// If err is a pointer then every time it was counted is removed.
+// Other errors, such as the ones made by fserrors.RetryErrorf, may be
+// equal to the errors of other operations, so only the most recent
+// count of err is removed.  Errors which can't be compared can't be
+// uncounted.
+//
+// Only recently counted errors can be uncounted.  If err was the last
+// error then the last error becomes the most recent one remaining.
+func (s *StatsInfo) Uncount(err error) (n int64) {
+	if s.parent != nil {
+		s.parent.Uncount(err)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err == nil || !reflect.TypeOf(err).Comparable() {
+		return 0
+	}
+	recentErrors := s.recentErrors[:0]
+	if reflect.TypeOf(err).Kind() == reflect.Ptr {
+		for _, recentErr := range s.recentErrors {
+			if recentErr == err {
+				n++
+			} else {
+				recentErrors = append(recentErrors, recentErr)
+			}
+		}
+	} else {
+		recentErrors = s.recentErrors
+		for i := len(recentErrors) - 1; i >= 0; i-- {
+			if recentErrors[i] == err {
+				n++
+				recentErrors = append(recentErrors[:i], recentErrors[i+1:]...)
+				break
+			}
+		}
+	}
+	s.recentErrors = recentErrors
+	s.errors -= n
+	if n > 0 && s.lastError == err {
+		s.lastError = nil
+		if len(recentErrors) > 0 {
+			s.lastError = recentErrors[len(recentErrors)-1]
+		}
+	}
+	return n
 }
 
 // Checking adds a check into the stats
 func (s *StatsInfo) Checking(remote string) {
+	if s.parent != nil {
+		s.parent.Checking(remote)
+	}
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	s.checking[remote] = struct{}{}
@@ -166,6 +502,9 @@ func (s *StatsInfo) Checking(remote string) {
 
 // DoneChecking removes a check from the stats
 func (s *StatsInfo) DoneChecking(remote string) {
+	if s.parent != nil {
+		s.parent.DoneChecking(remote)
+	}
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	delete(s.checking, remote)
@@ -181,6 +520,9 @@ func (s *StatsInfo) GetTransfers() int64 {
 
 // Transferring adds a transfer into the stats
 func (s *StatsInfo) Transferring(remote string) {
+	if s.parent != nil {
+		s.parent.Transferring(remote)
+	}
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	s.transferring[remote] = struct{}{}
@@ -190,6 +532,9 @@ func (s *StatsInfo) Transferring(remote string) {
 //
 // if ok is true then it increments the transfers count
 func (s *StatsInfo) DoneTransferring(remote string, ok bool) {
+	if s.parent != nil {
+		s.parent.DoneTransferring(remote, ok)
+	}
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	delete(s.transferring, remote)
@@ -0,0 +1,75 @@
+package accounting
+
+import (
+	"testing"
+
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// sliceError is an error which can't be compared
+type sliceError []string
+
+func (e sliceError) Error() string { return e[0] }
+
+func TestStatsUncount(t *testing.T) {
+	s := NewStats()
+	err1 := errors.New("one")
+	err2 := errors.New("two")
+	s.Error(err1)
+	s.Error(err2)
+	s.Error(err2)
+	assert.Equal(t, int64(3), s.GetErrors())
+	assert.Equal(t, err2, s.GetLastError())
+
+	// Every time the error was counted is removed and the last
+	// error goes back to the previous one
+	assert.Equal(t, int64(2), s.Uncount(err2))
+	assert.Equal(t, int64(1), s.GetErrors())
+	assert.Equal(t, err1, s.GetLastError())
+
+	// Errors not counted aren't removed
+	assert.Equal(t, int64(0), s.Uncount(err2))
+	assert.Equal(t, int64(0), s.Uncount(errors.New("three")))
+	assert.Equal(t, int64(1), s.GetErrors())
+
+	// Errors which aren't pointers may be equal to the errors of
+	// other transfers so only the most recent is removed
+	retryErr := fserrors.RetryErrorf("retry")
+	s.Error(retryErr)
+	s.Error(fserrors.RetryErrorf("retry"))
+	assert.Equal(t, int64(3), s.GetErrors())
+	assert.Equal(t, int64(1), s.Uncount(retryErr))
+	assert.Equal(t, int64(2), s.GetErrors())
+
+	// Errors which can't be compared aren't remembered
+	s.Error(sliceError{"slice"})
+	assert.Equal(t, int64(0), s.Uncount(sliceError{"slice"}))
+	assert.Equal(t, int64(3), s.GetErrors())
+	s.ResetErrors()
+	s.Error(err1)
+	s.Error(retryErr)
+
+	assert.Equal(t, int64(1), s.Uncount(err1))
+	assert.Equal(t, int64(1), s.GetErrors())
+	assert.Equal(t, retryErr, s.GetLastError())
+
+	// The parent is uncounted too
+	child := NewStats()
+	child.parent = s
+	child.Error(err1)
+	assert.Equal(t, int64(2), s.GetErrors())
+	assert.Equal(t, int64(1), child.Uncount(err1))
+	assert.Equal(t, int64(0), child.GetErrors())
+	assert.Equal(t, int64(1), s.GetErrors())
+
+	// Only the most recent errors are remembered
+	for i := 0; i <= maxRecentErrors; i++ {
+		s.Error(err2)
+	}
+	s.Error(err1)
+	assert.Equal(t, int64(maxRecentErrors-1), s.Uncount(err2))
+	s.ResetErrors()
+	assert.Equal(t, int64(0), s.Uncount(err1))
+}
@@ -8,6 +8,16 @@ import (
 // stringSet holds a set of strings
 type stringSet map[string]struct{}
 
+// names returns the sorted names in the stringSet
+func (ss stringSet) names() []string {
+	names := make([]string, 0, len(ss))
+	for name := range ss {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // Strings returns all the strings in the stringSet
 func (ss stringSet) Strings() []string {
 	strings := make([]string, 0, len(ss))
@@ -19,6 +19,7 @@ var (
 	bwLimitToggledOff = false
 	currLimitMu       sync.Mutex // protects changes to the timeslot
 	currLimit         fs.BwTimeSlot
+	signalHandlerOnce sync.Once
 )
 
 const maxBurstSize = 1 * 1024 * 1024 // must be bigger than the biggest request
@@ -41,12 +42,15 @@ func StartTokenBucket() {
 	currLimitMu.Unlock()
 
 	if currLimit.Bandwidth > 0 {
-		tokenBucket = newTokenBucket(currLimit.Bandwidth)
+		newBucket := newTokenBucket(currLimit.Bandwidth)
+		tokenBucketMu.Lock()
+		tokenBucket = newBucket
+		tokenBucketMu.Unlock()
 		fs.Infof(nil, "Starting bandwidth limiter at %vBytes/s", &currLimit.Bandwidth)
 
 		// Start the SIGUSR2 signal handler to toggle bandwidth.
 		// This function does nothing in windows systems.
-		startSignalHandler()
+		signalHandlerOnce.Do(startSignalHandler)
 	}
 }
 
@@ -119,7 +123,7 @@ func limitBandwidth(n int) {
 func init() {
 	rc.Add(rc.Call{
 		Path: "core/bwlimit",
-		Fn: func(in rc.Params) (out rc.Params, err error) {
+		Fn: func(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 			ibwlimit, ok := in["rate"]
 			if !ok {
 				return out, errors.Errorf("parameter rate not found")
@@ -0,0 +1,109 @@
+// Package cache implements a cache of constructed Fs objects so that
+// callers which use the same remote many times in one process don't
+// have to initialise the backend each time.
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+)
+
+var (
+	mu            sync.Mutex
+	entries       = map[string]*cacheEntry{}
+	expireRunning = false
+	fsNewFs       = fs.NewFs // for tests
+
+	// How long an unused entry stays in the cache
+	expireDuration = 300 * time.Second
+
+	// How often the cache is checked for unused entries
+	expireInterval = 60 * time.Second
+)
+
+// cacheEntry is a constructed Fs
+type cacheEntry struct {
+	f        fs.Fs     // the cached fs
+	err      error     // nil or fs.ErrorIsFile
+	lastUsed time.Time // time used for expiry
+}
+
+// Get gets an fs.Fs named fsString either from the cache or creates
+// it afresh.
+//
+// Like fs.NewFs it may return fs.ErrorIsFile along with the Fs.
+// Other errors aren't cached.
+func Get(fsString string) (f fs.Fs, err error) {
+	mu.Lock()
+	entry, ok := entries[fsString]
+	if ok {
+		entry.lastUsed = time.Now()
+		mu.Unlock()
+		return entry.f, entry.err
+	}
+	mu.Unlock()
+
+	// Create the Fs without the lock held as it may take a while
+	f, err = fsNewFs(fsString)
+	if err != nil && err != fs.ErrorIsFile {
+		return f, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if entry, ok := entries[fsString]; ok {
+		// someone else made it while we were - use theirs
+		entry.lastUsed = time.Now()
+		return entry.f, entry.err
+	}
+	entries[fsString] = &cacheEntry{
+		f:        f,
+		err:      err,
+		lastUsed: time.Now(),
+	}
+	if !expireRunning {
+		time.AfterFunc(expireInterval, expire)
+		expireRunning = true
+	}
+	return f, err
+}
+
+// Put puts an fs.Fs named fsString into the cache
+func Put(fsString string, f fs.Fs) {
+	mu.Lock()
+	defer mu.Unlock()
+	entries[fsString] = &cacheEntry{
+		f:        f,
+		lastUsed: time.Now(),
+	}
+	if !expireRunning {
+		time.AfterFunc(expireInterval, expire)
+		expireRunning = true
+	}
+}
+
+// Clear removes everything from the cache
+func Clear() {
+	mu.Lock()
+	defer mu.Unlock()
+	entries = map[string]*cacheEntry{}
+}
+
+// expire removes any entries which haven't been used recently
+func expire() {
+	mu.Lock()
+	defer mu.Unlock()
+	now := time.Now()
+	for fsString, entry := range entries {
+		if now.Sub(entry.lastUsed) > expireDuration {
+			delete(entries, fsString)
+		}
+	}
+	if len(entries) != 0 {
+		time.AfterFunc(expireInterval, expire)
+	} else {
+		expireRunning = false
+	}
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var called = 0
+
+// newLocalFs makes a local Fs to put in the cache
+func newLocalFs(t *testing.T, root string) fs.Fs {
+	f, err := local.NewFs("local", root)
+	require.NoError(t, err)
+	return f
+}
+
+func mockNewFs(t *testing.T) func() {
+	called = 0
+	oldFsNewFs := fsNewFs
+	fsNewFs = func(path string) (fs.Fs, error) {
+		called++
+		switch path {
+		case "/":
+			return newLocalFs(t, "/"), nil
+		case "/file.txt":
+			return newLocalFs(t, "/"), fs.ErrorIsFile
+		case "/error":
+			return nil, errors.New("potato")
+		}
+		panic("unknown path " + path)
+	}
+	return func() {
+		fsNewFs = oldFsNewFs
+		Clear()
+	}
+}
+
+func TestGet(t *testing.T) {
+	defer mockNewFs(t)()
+
+	assert.Equal(t, 0, len(entries))
+
+	f, err := Get("/")
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
+
+	f2, err := Get("/")
+	require.NoError(t, err)
+	assert.Equal(t, f, f2)
+	assert.Equal(t, 1, called)
+}
+
+func TestGetFile(t *testing.T) {
+	defer mockNewFs(t)()
+
+	f, err := Get("/file.txt")
+	require.Equal(t, fs.ErrorIsFile, err)
+	assert.Equal(t, 1, len(entries))
+
+	f2, err := Get("/file.txt")
+	require.Equal(t, fs.ErrorIsFile, err)
+	assert.Equal(t, f, f2)
+	assert.Equal(t, 1, called)
+}
+
+func TestGetError(t *testing.T) {
+	defer mockNewFs(t)()
+
+	f, err := Get("/error")
+	require.Equal(t, "potato", err.Error())
+	require.Equal(t, nil, f)
+	assert.Equal(t, 0, len(entries))
+
+	_, _ = Get("/error")
+	assert.Equal(t, 2, called)
+}
+
+func TestPut(t *testing.T) {
+	defer mockNewFs(t)()
+
+	f := newLocalFs(t, "/alien")
+	Put("/alien", f)
+	assert.Equal(t, 1, len(entries))
+
+	fNew, err := Get("/alien")
+	require.NoError(t, err)
+	require.Equal(t, f, fNew)
+	assert.Equal(t, 0, called)
+}
+
+func TestExpire(t *testing.T) {
+	defer mockNewFs(t)()
+
+	_, err := Get("/")
+	require.NoError(t, err)
+
+	mu.Lock()
+	entries["/"].lastUsed = time.Now().Add(-expireDuration - time.Second)
+	mu.Unlock()
+	expire()
+
+	mu.Lock()
+	assert.Equal(t, 0, len(entries))
+	assert.False(t, expireRunning)
+	mu.Unlock()
+}
@@ -21,6 +21,10 @@ type ChunkedReader struct {
 	initialChunkSize int64
 	chunkGrowth      bool
 	doSeek           bool
+	window           int64  // coalesce reads this close together - 0 for off
+	history          []byte // up to window bytes read ending at offset
+	replay           []byte // bytes of history to return before reading more
+	skip             int64  // bytes to discard from rc before reading
 }
 
 // New returns a ChunkedReader for the Object.
@@ -41,11 +45,40 @@ func New(o fs.Object, initialChunkSize int64, chunkGrowth bool) *ChunkedReader {
 	}
 }
 
+// WithCoalesceWindow makes reads which are within window bytes of
+// each other share one request to the remote instead of seeking.
+//
+// Reads a little way ahead of the last one read and discard the data
+// in between, and the last window bytes read are kept so reads a
+// little way behind it are returned from memory.  Seeks which can't be
+// coalesced open ranges of at least window bytes.
+func (cr *ChunkedReader) WithCoalesceWindow(window int64) *ChunkedReader {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if window < 0 {
+		window = 0
+	}
+	cr.window = window
+	return cr
+}
+
 // Read from the file - for details see io.Reader
 func (cr *ChunkedReader) Read(p []byte) (n int, err error) {
 	cr.mu.Lock()
 	defer cr.mu.Unlock()
 
+	if len(cr.replay) > 0 {
+		n = copy(p, cr.replay)
+		cr.replay = cr.replay[n:]
+		p = p[n:]
+	}
+	if cr.skip > 0 && len(p) > 0 {
+		err = cr.discard()
+		if err != nil {
+			return n, err
+		}
+	}
+
 	for reqSize := int64(len(p)); reqSize > 0; reqSize = int64(len(p)) {
 		chunkEnd := cr.chunkOffset + cr.chunkSize
 
@@ -78,13 +111,86 @@ func (cr *ChunkedReader) Read(p []byte) (n int, err error) {
 		rn, err = io.ReadFull(cr.rc, buf)
 		n += rn
 		cr.offset += int64(rn)
+		cr.remember(buf[:rn])
 		if err != nil {
+			if err == io.ErrUnexpectedEOF {
+				// short read at the end of the object
+				err = io.EOF
+			}
 			return
 		}
 	}
 	return n, nil
 }
 
+// remember adds data just read to the history
+func (cr *ChunkedReader) remember(data []byte) {
+	if cr.window <= 0 {
+		return
+	}
+	cr.history = append(cr.history, data...)
+	if extra := int64(len(cr.history)) - cr.window; extra > 0 {
+		cr.history = cr.history[extra:]
+	}
+}
+
+// discard reads and throws away the bytes skipped by a coalesced
+// seek.  If that fails then the seek is done by opening a new range.
+func (cr *ChunkedReader) discard() error {
+	skip := cr.skip
+	cr.skip = 0
+	buf := make([]byte, skip)
+	rn, err := io.ReadFull(cr.rc, buf)
+	cr.offset += int64(rn)
+	cr.remember(buf[:rn])
+	if err == nil {
+		return nil
+	}
+	fs.Debugf(cr.o, "ChunkedReader.Read failed to skip %d bytes - reopening: %v", skip, err)
+	cr.chunkOffset = cr.offset + skip - int64(rn)
+	cr.offset = -1
+	cr.doSeek = true
+	cr.chunkSize = cr.seekChunkSize(-1)
+	return nil
+}
+
+// coalesce tries to seek to offset by using the history or skipping
+// forward in the open range, returning false if it can't
+func (cr *ChunkedReader) coalesce(offset int64) bool {
+	if cr.window <= 0 || cr.rc == nil || cr.offset < 0 {
+		return false
+	}
+	switch {
+	case offset < cr.offset:
+		back := cr.offset - offset
+		if back > int64(len(cr.history)) {
+			return false
+		}
+		cr.replay = cr.history[int64(len(cr.history))-back:]
+	case offset > cr.offset:
+		ahead := offset - cr.offset
+		if ahead > cr.window || (cr.chunkSize > 0 && offset >= cr.chunkOffset+cr.chunkSize) {
+			return false
+		}
+		cr.skip = ahead
+	}
+	fs.Debugf(cr.o, "ChunkedReader.RangeSeek coalesced from %d to %d", cr.offset, offset)
+	return true
+}
+
+// seekChunkSize returns the size of the range to open after a seek
+// asking for length bytes
+func (cr *ChunkedReader) seekChunkSize(length int64) int64 {
+	chunkSize := cr.initialChunkSize
+	if length > 0 {
+		chunkSize = length
+	}
+	if chunkSize > 0 && chunkSize < cr.window {
+		chunkSize = cr.window
+	}
+	return chunkSize
+}
+
 // Close the file - for details see io.Closer
 func (cr *ChunkedReader) Close() error {
 	cr.mu.Lock()
@@ -105,21 +211,26 @@ func (cr *ChunkedReader) RangeSeek(offset int64, whence int, length int64) (int6
 
 	fs.Debugf(cr.o, "ChunkedReader.RangeSeek from %d to %d", cr.offset, offset)
 
+	// the position Read would carry on from
+	pos := cr.offset - int64(len(cr.replay)) + cr.skip
+	if cr.offset < 0 {
+		pos = cr.chunkOffset
+	}
+	cr.replay, cr.skip = nil, 0
 	size := cr.o.Size()
 	switch whence {
 	case 0:
-		cr.offset = 0
+		pos = 0
 	case 2:
-		cr.offset = size
+		pos = size
 	}
-	cr.chunkOffset = cr.offset + offset
+	if cr.coalesce(pos + offset) {
+		return pos + offset, nil
+	}
+	cr.chunkOffset = pos + offset
 	cr.offset = -1
 	cr.doSeek = true
-	if length > 0 {
-		cr.chunkSize = length
-	} else {
-		cr.chunkSize = cr.initialChunkSize
-	}
+	cr.chunkSize = cr.seekChunkSize(length)
 	return cr.offset, nil
 }
 
@@ -164,6 +275,7 @@ func (cr *ChunkedReader) resetReader(rc io.ReadCloser, offset int64) error {
 	}
 	cr.rc = rc
 	cr.offset = offset
+	cr.history, cr.replay, cr.skip = nil, nil, 0
 	return nil
 }
 
@@ -0,0 +1,127 @@
+package chunkedreader
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest/mockobject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testObject is an fs.Object with contents which counts its opens
+type testObject struct {
+	mockobject.Object
+	contents []byte
+	opens    int
+}
+
+func (o *testObject) Size() int64 {
+	return int64(len(o.contents))
+}
+
+func (o *testObject) Open(options ...fs.OpenOption) (io.ReadCloser, error) {
+	o.opens++
+	start, end := int64(0), int64(len(o.contents))
+	for _, option := range options {
+		if r, ok := option.(*fs.RangeOption); ok {
+			start = r.Start
+			if r.End >= 0 && r.End+1 < end {
+				end = r.End + 1
+			}
+		}
+	}
+	return ioutil.NopCloser(bytes.NewReader(o.contents[start:end])), nil
+}
+
+func newTestObject(size int) *testObject {
+	o := &testObject{Object: "test", contents: make([]byte, size)}
+	for i := range o.contents {
+		o.contents[i] = byte(i % 251)
+	}
+	return o
+}
+
+// readAt seeks cr to offset and reads size bytes checking them
+func readAt(t *testing.T, o *testObject, cr *ChunkedReader, offset int64, size int) {
+	_, err := cr.Seek(offset, 0)
+	require.NoError(t, err)
+	buf := make([]byte, size)
+	_, err = io.ReadFull(cr, buf)
+	require.NoError(t, err)
+	assert.Equal(t, o.contents[offset:offset+int64(size)], buf, "at %d", offset)
+}
+
+func TestChunkedReaderRead(t *testing.T) {
+	o := newTestObject(10000)
+	for _, chunkSize := range []int64{0, 100, 1000} {
+		for _, growth := range []bool{false, true} {
+			cr := New(o, chunkSize, growth)
+			data, err := ioutil.ReadAll(cr)
+			require.NoError(t, err)
+			assert.Equal(t, o.contents, data)
+			require.NoError(t, cr.Close())
+		}
+	}
+}
+
+func TestChunkedReaderNoCoalesce(t *testing.T) {
+	o := newTestObject(10000)
+	cr := New(o, 0, false)
+	for offset := int64(0); offset < 8000; offset += 200 {
+		readAt(t, o, cr, offset, 100)
+	}
+	assert.Equal(t, 40, o.opens)
+	require.NoError(t, cr.Close())
+}
+
+func TestChunkedReaderCoalesce(t *testing.T) {
+	o := newTestObject(10000)
+	cr := New(o, 0, false).WithCoalesceWindow(1000)
+
+	// reads going forwards with gaps use one request
+	for offset := int64(0); offset < 8000; offset += 200 {
+		readAt(t, o, cr, offset, 100)
+	}
+	assert.Equal(t, 1, o.opens)
+
+	// reads going backwards within the window are from memory
+	readAt(t, o, cr, 7500, 100)
+	readAt(t, o, cr, 7000, 600)
+	assert.Equal(t, 1, o.opens)
+
+	// seeks further away open a new range
+	readAt(t, o, cr, 100, 100)
+	assert.Equal(t, 2, o.opens)
+	readAt(t, o, cr, 5000, 100)
+	assert.Equal(t, 3, o.opens)
+
+	// relative seeks and reads to the end
+	_, err := cr.Seek(-50, 1)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(cr)
+	require.NoError(t, err)
+	assert.Equal(t, o.contents[5050:], data)
+	assert.Equal(t, 3, o.opens)
+	require.NoError(t, cr.Close())
+}
+
+func TestChunkedReaderCoalesceChunks(t *testing.T) {
+	o := newTestObject(10000)
+	cr := New(o, 100, false).WithCoalesceWindow(1000)
+
+	// small ranges are made as big as the window after a seek
+	readAt(t, o, cr, 3000, 10)
+	assert.Equal(t, 1, o.opens)
+	readAt(t, o, cr, 3500, 10)
+	readAt(t, o, cr, 3990, 10)
+	assert.Equal(t, 1, o.opens)
+
+	// skipping past the end of the range opens a new one
+	readAt(t, o, cr, 4010, 10)
+	assert.Equal(t, 2, o.opens)
+	require.NoError(t, cr.Close())
+}
@@ -2,6 +2,7 @@ package fs
 
 import (
 	"net"
+	"strings"
 	"time"
 )
 
@@ -22,6 +23,12 @@ var (
 	// This is a function pointer to decouple the config
 	// implementation from the fs
 	CountError = func(err error) {}
+
+	// CountLowLevelRetry counts a low level retry.
+	//
+	// This is a function pointer to decouple the accounting
+	// implementation from the fs
+	CountLowLevelRetry = func() {}
 )
 
 // ConfigInfo is filesystem config options
@@ -29,6 +36,7 @@ type ConfigInfo struct {
 	LogLevel              LogLevel
 	StatsLogLevel         LogLevel
 	DryRun                bool
+	Interactive           bool // Ask before each operation which changes the destination
 	CheckSum              bool
 	SizeOnly              bool
 	IgnoreTimes           bool
@@ -42,8 +50,14 @@ type ConfigInfo struct {
 	InsecureSkipVerify    bool // Skip server certificate verification
 	DeleteMode            DeleteMode
 	MaxDelete             int64
-	TrackRenames          bool // Track file renames.
+	DeleteWorkers         int    // Number of deletions to run at once, 0 for the default
+	TrackRenames          bool   // Track file renames.
+	CheckFirst            bool   // Do all the checks before starting transfers
+	ResumeState           string // File to save the state of a sync in so it can be resumed
+	IgnoreErrors          bool   // Delete files on the destination even if there were IO errors
 	LowLevelRetries       int
+	FileRetries           int  // Number of times to retry a failed transfer at the end of the run
+	AutoTune              bool // Adjust the active checkers and transfers to the errors and throughput
 	UpdateOlder           bool // Skip files that are newer on the destination
 	NoGzip                bool // Disable compression
 	MaxDepth              int
@@ -56,14 +70,19 @@ type ConfigInfo struct {
 	UseListR              bool
 	BufferSize            SizeSuffix
 	BwLimit               BwTimetable
+	BwLimitFile           SizeSuffix // bandwidth limit for each individual transfer
 	TPSLimit              float64
 	TPSLimitBurst         int
 	BindAddr              net.IP
+	IPv4Only              bool // only use IPv4 for outgoing connections
+	IPv6Only              bool // only use IPv6 for outgoing connections
 	DisableFeatures       []string
 	UserAgent             string
 	Immutable             bool
 	AutoConfirm           bool
 	StreamingUploadCutoff SizeSuffix
+	MultiThreadCutoff     SizeSuffix // use multi-thread downloads for files bigger than this
+	MultiThreadStreams    int        // number of streams for multi-thread downloads
 	StatsFileNameLength   int
 	AskPassword           bool
 }
@@ -90,9 +109,30 @@ func NewConfig() *ConfigInfo {
 	c.BufferSize = SizeSuffix(16 << 20)
 	c.UserAgent = "rclone/" + Version
 	c.StreamingUploadCutoff = SizeSuffix(100 * 1024)
+	c.MultiThreadCutoff = SizeSuffix(250 * 1024 * 1024)
+	c.MultiThreadStreams = 4
 	c.StatsFileNameLength = 40
 	c.AskPassword = true
 	c.TPSLimitBurst = 1
 
 	return c
 }
+
+// ConfigForRemote returns a copy of the global config with any
+// overrides read from the config file section for the remote name
+// applied.
+//
+// The user_agent key overrides --user-agent for that remote only and
+// the features in the disable key are disabled as well as those in
+// --disable.
+func ConfigForRemote(name string) *ConfigInfo {
+	ci := new(ConfigInfo)
+	*ci = *Config
+	if userAgent := ConfigFileGet(name, "user_agent"); userAgent != "" {
+		ci.UserAgent = userAgent
+	}
+	if disable := ConfigFileGet(name, "disable"); disable != "" {
+		ci.DisableFeatures = append(append([]string(nil), Config.DisableFeatures...), strings.Split(disable, ",")...)
+	}
+	return ci
+}
@@ -28,7 +28,6 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/config/obscure"
-	"github.com/ncw/rclone/fs/driveletter"
 	"github.com/ncw/rclone/fs/fshttp"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/nacl/secretbox"
@@ -56,6 +55,11 @@ const (
 
 	// ConfigAutomatic indicates that we want non-interactive configuration
 	ConfigAutomatic = "config_automatic"
+
+	// ConfigDefaultPrefix is the prefix of the config keys which set
+	// the default of a command line flag when the remote is used,
+	// eg "default.transfers"
+	ConfigDefaultPrefix = "default."
 )
 
 // Global
@@ -161,7 +165,7 @@ func LoadConfig() {
 	// Load configuration file.
 	var err error
 	configFile, err = loadConfigFile()
-	if err == errorConfigFileNotFound {
+	if err == ErrorConfigFileNotFound {
 		fs.Logf(nil, "Config file %q not found - using defaults", ConfigPath)
 		configFile, _ = goconfig.LoadFromReader(&bytes.Buffer{})
 	} else if err != nil {
@@ -170,6 +174,9 @@ func LoadConfig() {
 		fs.Debugf(nil, "Using config file from %q", ConfigPath)
 	}
 
+	// Warn about typos and bad values in the config file
+	warnConfigProblems()
+
 	// Start the token bucket limiter
 	accounting.StartTokenBucket()
 
@@ -180,16 +187,15 @@ func LoadConfig() {
 	fshttp.StartHTTPTokenBucket()
 }
 
-var errorConfigFileNotFound = errors.New("config file not found")
+// ErrorConfigFileNotFound is returned when the config file isn't
+// found, or by a Storage which has no config saved yet
+var ErrorConfigFileNotFound = errors.New("config file not found")
 
 // loadConfigFile will load a config file, and
 // automatically decrypt it.
 func loadConfigFile() (*goconfig.ConfigFile, error) {
-	b, err := ioutil.ReadFile(ConfigPath)
+	b, err := getStorage().Load()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, errorConfigFileNotFound
-		}
 		return nil, err
 	}
 
@@ -360,86 +366,44 @@ func changeConfigPassword() {
 // saveConfig saves configuration file.
 // if configKey has been set, the file will be encrypted.
 func saveConfig() error {
-	dir, name := filepath.Split(ConfigPath)
-	f, err := ioutil.TempFile(dir, name)
-	if err != nil {
-		return errors.Errorf("Failed to create temp file for new config: %v", err)
-	}
-	defer func() {
-		if err := os.Remove(f.Name()); err != nil && !os.IsNotExist(err) {
-			fs.Errorf(nil, "Failed to remove temp config file: %v", err)
-		}
-	}()
-
 	var buf bytes.Buffer
-	err = goconfig.SaveConfigData(getConfigData(), &buf)
+	err := goconfig.SaveConfigData(getConfigData(), &buf)
 	if err != nil {
 		return errors.Errorf("Failed to save config file: %v", err)
 	}
 
 	if len(configKey) == 0 {
-		if _, err := buf.WriteTo(f); err != nil {
-			return errors.Errorf("Failed to write temp config file: %v", err)
-		}
-	} else {
-		fmt.Fprintln(f, "# Encrypted rclone configuration File")
-		fmt.Fprintln(f, "")
-		fmt.Fprintln(f, "RCLONE_ENCRYPT_V0:")
-
-		// Generate new nonce and write it to the start of the ciphertext
-		var nonce [24]byte
-		n, _ := rand.Read(nonce[:])
-		if n != 24 {
-			return errors.Errorf("nonce short read: %d", n)
-		}
-		enc := base64.NewEncoder(base64.StdEncoding, f)
-		_, err = enc.Write(nonce[:])
-		if err != nil {
-			return errors.Errorf("Failed to write temp config file: %v", err)
-		}
-
-		var key [32]byte
-		copy(key[:], configKey[:32])
-
-		b := secretbox.Seal(nil, buf.Bytes(), &nonce, &key)
-		_, err = enc.Write(b)
-		if err != nil {
-			return errors.Errorf("Failed to write temp config file: %v", err)
-		}
-		_ = enc.Close()
+		return getStorage().Save(buf.Bytes())
 	}
 
-	err = f.Close()
-	if err != nil {
-		return errors.Errorf("Failed to close config file: %v", err)
-	}
+	var out bytes.Buffer
+	fmt.Fprintln(&out, "# Encrypted rclone configuration File")
+	fmt.Fprintln(&out, "")
+	fmt.Fprintln(&out, "RCLONE_ENCRYPT_V0:")
 
-	var fileMode os.FileMode = 0600
-	info, err := os.Stat(ConfigPath)
+	// Generate new nonce and write it to the start of the ciphertext
+	var nonce [24]byte
+	n, _ := rand.Read(nonce[:])
+	if n != 24 {
+		return errors.Errorf("nonce short read: %d", n)
+	}
+	enc := base64.NewEncoder(base64.StdEncoding, &out)
+	_, err = enc.Write(nonce[:])
 	if err != nil {
-		fs.Debugf(nil, "Using default permissions for config file: %v", fileMode)
-	} else if info.Mode() != fileMode {
-		fs.Debugf(nil, "Keeping previous permissions for config file: %v", info.Mode())
-		fileMode = info.Mode()
+		return errors.Errorf("Failed to write temp config file: %v", err)
 	}
 
-	attemptCopyGroup(ConfigPath, f.Name())
+	var key [32]byte
+	copy(key[:], configKey[:32])
 
-	err = os.Chmod(f.Name(), fileMode)
+	b := secretbox.Seal(nil, buf.Bytes(), &nonce, &key)
+	_, err = enc.Write(b)
 	if err != nil {
-		fs.Errorf(nil, "Failed to set permissions on config file: %v", err)
+		return errors.Errorf("Failed to write temp config file: %v", err)
 	}
+	_ = enc.Close()
 
-	if err = os.Rename(ConfigPath, ConfigPath+".old"); err != nil && !os.IsNotExist(err) {
-		return errors.Errorf("Failed to move previous config to backup location: %v", err)
-	}
-	if err = os.Rename(f.Name(), ConfigPath); err != nil {
-		return errors.Errorf("Failed to move newly written config from %s to final location: %v", f.Name(), err)
-	}
-	if err := os.Remove(ConfigPath + ".old"); err != nil && !os.IsNotExist(err) {
-		fs.Errorf(nil, "Failed to remove backup config file: %v", err)
-	}
-	return nil
+	return getStorage().Save(out.Bytes())
 }
 
 // SaveConfig calling function which saves configuration file.
@@ -461,13 +425,18 @@ func SaveConfig() {
 // SetValueAndSave sets the key to the value and saves just that
 // value in the config file.  It loads the old config file in from
 // disk first and overwrites the given value only.
+//
+// Any functions registered with OnChange are called once the value
+// is set.
 func SetValueAndSave(name, key, value string) (err error) {
+	name, _ = splitConnectionString(name)
 	// Set the value in config in case we fail to reload it
 	getConfigData().SetValue(name, key, value)
 	// Reload the config file
 	reloadedConfigFile, err := loadConfigFile()
-	if err == errorConfigFileNotFound {
+	if err == ErrorConfigFileNotFound {
 		// Config file not written yet so ignore reload
+		notifyChange(name, key, value)
 		return nil
 	} else if err != nil {
 		return err
@@ -483,6 +452,7 @@ func SetValueAndSave(name, key, value string) (err error) {
 	reloadedConfigFile.SetValue(name, key, value)
 	// Save it again
 	SaveConfig()
+	notifyChange(name, key, value)
 	return nil
 }
 
@@ -637,7 +607,7 @@ func ShowRemote(name string) {
 				break
 			}
 		}
-		value := FileGet(name, key)
+		value := getConfigData().MustValue(name, key, "")
 		if isPassword && value != "" {
 			fmt.Printf("%s = *** ENCRYPTED ***\n", key)
 		} else {
@@ -647,6 +617,30 @@ func ShowRemote(name string) {
 	fmt.Printf("--------------------\n")
 }
 
+// ShowRemoteResolved shows the values the remote in section will use
+// and where each came from.  section may be a connection string.
+func ShowRemoteResolved(section string) {
+	fmt.Printf("--------------------\n")
+	fmt.Printf("[%s]\n", section)
+	fsInfo, _ := fs.Find(FileGet(section, "type"))
+	for _, key := range resolvedKeys(section, fsInfo) {
+		value, source, found := FileGetSource(section, key)
+		if !found {
+			continue
+		}
+		if fsInfo != nil && value != "" {
+			for _, option := range fsInfo.Options {
+				if option.Name == key && option.IsPassword {
+					value = "*** ENCRYPTED ***"
+					break
+				}
+			}
+		}
+		fmt.Printf("%s = %s ; from %s\n", key, value, source)
+	}
+	fmt.Printf("--------------------\n")
+}
+
 // OkRemote prints the contents of the remote and ask if it is OK
 func OkRemote(name string) bool {
 	ShowRemote(name)
@@ -755,6 +749,9 @@ func UpdateRemote(name string, keyValues []string) error {
 // parameters which are key, value pairs.  If update is set then it
 // adds the new keys rather than replacing all of them.
 func CreateRemote(name string, provider string, keyValues []string) error {
+	if err := fs.CheckConfigName(name); err != nil {
+		return err
+	}
 	// Suppress Confirm
 	fs.Config.AutoConfirm = true
 	// Delete the old config if it exists
@@ -820,17 +817,14 @@ func NewRemoteName() (name string) {
 	for {
 		fmt.Printf("name> ")
 		name = ReadLine()
-		parts := fs.Matcher.FindStringSubmatch(name + ":")
-		switch {
-		case name == "":
-			fmt.Printf("Can't use empty name.\n")
-		case driveletter.IsDriveLetter(name):
-			fmt.Printf("Can't use %q as it can be confused a drive letter.\n", name)
-		case parts == nil:
-			fmt.Printf("Can't use %q as it has invalid characters in it.\n", name)
-		default:
-			return name
+		if err := fs.CheckConfigName(name); err != nil {
+			fmt.Printf("Bad name: %v.\n", err)
+			continue
+		}
+		if quoted := fs.QuoteRemoteName(name); quoted != name {
+			fmt.Printf("Note that the name will need quoting when used, eg %s:path\n", quoted)
 		}
+		return name
 	}
 }
 
@@ -840,6 +834,9 @@ func NewRemote(name string) {
 	getConfigData().SetValue(name, "type", newType)
 	fs := fs.MustFind(newType)
 	for _, option := range fs.Options {
+		if option.Hide {
+			continue
+		}
 		getConfigData().SetValue(name, option.Name, ChooseOption(&option))
 	}
 	RemoteConfig(name)
@@ -856,8 +853,11 @@ func EditRemote(fs *fs.RegInfo, name string) {
 	fmt.Printf("Edit remote\n")
 	for {
 		for _, option := range fs.Options {
+			if option.Hide {
+				continue
+			}
 			key := option.Name
-			value := FileGet(name, key)
+			value := getConfigData().MustValue(name, key, "")
 			fmt.Printf("Value %q = %q\n", key, value)
 			fmt.Printf("Edit? (y/n)>\n")
 			if Confirm() {
@@ -1048,63 +1048,89 @@ func configToEnv(section, name string) string {
 // FileGet gets the config key under section returning the
 // default or empty string if not set.
 //
-// It looks up defaults in the environment if they are present
+// section may be a connection string and the value is looked up in
+// the order described in FileGetSource.
 func FileGet(section, key string, defaultVal ...string) string {
-	envKey := configToEnv(section, key)
-	newValue, found := os.LookupEnv(envKey)
-	if found {
-		defaultVal = []string{newValue}
+	value, _, _ := FileGetSource(section, key)
+	if value == "" && len(defaultVal) > 0 {
+		return defaultVal[0]
 	}
-	return getConfigData().MustValue(section, key, defaultVal...)
+	return value
 }
 
 // FileGetBool gets the config key under section returning the
 // default or false if not set.
 //
-// It looks up defaults in the environment if they are present
+// section may be a connection string and the value is looked up in
+// the order described in FileGetSource.
 func FileGetBool(section, key string, defaultVal ...bool) bool {
-	envKey := configToEnv(section, key)
-	newValue, found := os.LookupEnv(envKey)
-	if found {
-		newBool, err := strconv.ParseBool(newValue)
-		if err != nil {
-			fs.Errorf(nil, "Couldn't parse %q into bool - ignoring: %v", envKey, err)
-		} else {
-			defaultVal = []bool{newBool}
+	value, source, _ := FileGetSource(section, key)
+	if value != "" {
+		newBool, err := strconv.ParseBool(value)
+		if err == nil {
+			return newBool
 		}
+		fs.Errorf(nil, "Couldn't parse %s from %s into bool - ignoring: %v", key, source, err)
+	}
+	if len(defaultVal) > 0 {
+		return defaultVal[0]
 	}
-	return getConfigData().MustBool(section, key, defaultVal...)
+	return false
 }
 
 // FileGetInt gets the config key under section returning the
 // default or 0 if not set.
 //
-// It looks up defaults in the environment if they are present
+// section may be a connection string and the value is looked up in
+// the order described in FileGetSource.
 func FileGetInt(section, key string, defaultVal ...int) int {
-	envKey := configToEnv(section, key)
-	newValue, found := os.LookupEnv(envKey)
-	if found {
-		newInt, err := strconv.Atoi(newValue)
-		if err != nil {
-			fs.Errorf(nil, "Couldn't parse %q into int - ignoring: %v", envKey, err)
-		} else {
-			defaultVal = []int{newInt}
+	value, source, _ := FileGetSource(section, key)
+	if value != "" {
+		newInt, err := strconv.Atoi(value)
+		if err == nil {
+			return newInt
 		}
+		fs.Errorf(nil, "Couldn't parse %s from %s into int - ignoring: %v", key, source, err)
 	}
-	return getConfigData().MustInt(section, key, defaultVal...)
+	if len(defaultVal) > 0 {
+		return defaultVal[0]
+	}
+	return 0
 }
 
 // FileSet sets the key in section to value.  It doesn't save
 // the config file.
+//
+// If section is a connection string the value is set in the remote
+// it refers to.
 func FileSet(section, key, value string) {
-	getConfigData().SetValue(section, key, value)
+	name, _ := splitConnectionString(section)
+	getConfigData().SetValue(name, key, value)
 }
 
 // FileDeleteKey deletes the config key in the config file.
 // It returns true if the key was deleted,
 // or returns false if the section or key didn't exist.
 func FileDeleteKey(section, key string) bool {
-	return getConfigData().DeleteKey(section, key)
+	name, _ := splitConnectionString(section)
+	return getConfigData().DeleteKey(name, key)
+}
+
+// RemoteDefaults returns the command line flag defaults set in the
+// config of the remote called name, indexed by flag name.
+//
+// These are set with keys like "default.transfers = 8".
+func RemoteDefaults(name string) map[string]string {
+	name, _ = splitConnectionString(name)
+	defaults := map[string]string{}
+	for _, key := range getConfigData().GetKeyList(name) {
+		if !strings.HasPrefix(key, ConfigDefaultPrefix) {
+			continue
+		}
+		flagName := strings.Replace(key[len(ConfigDefaultPrefix):], "_", "-", -1)
+		defaults[flagName] = getConfigData().MustValue(name, key, "")
+	}
+	return defaults
 }
 
 var matchEnv = regexp.MustCompile(`^RCLONE_CONFIG_(.*?)_TYPE=.*$`)
@@ -1128,7 +1154,7 @@ func Dump() error {
 	for _, name := range getConfigData().GetSectionList() {
 		params := make(map[string]string)
 		for _, key := range getConfigData().GetKeyList(name) {
-			params[key] = FileGet(name, key)
+			params[key] = getConfigData().MustValue(name, key, "")
 		}
 		dump[name] = params
 	}
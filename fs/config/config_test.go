@@ -162,7 +162,7 @@ func TestConfigLoadEncryptedFailures(t *testing.T) {
 	// This file does not exist.
 	ConfigPath = "./testdata/filenotfound.conf"
 	c, err := loadConfigFile()
-	assert.Equal(t, errorConfigFileNotFound, err)
+	assert.Equal(t, ErrorConfigFileNotFound, err)
 	assert.Nil(t, c)
 }
 
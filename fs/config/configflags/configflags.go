@@ -7,11 +7,15 @@ import (
 	"log"
 	"net"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/config"
 	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/ncw/rclone/fs/fshttp"
+	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
 
@@ -44,6 +48,7 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.BoolVarP(flagSet, &fs.Config.IgnoreTimes, "ignore-times", "I", fs.Config.IgnoreTimes, "Don't skip files that match size and time - transfer all files")
 	flags.BoolVarP(flagSet, &fs.Config.IgnoreExisting, "ignore-existing", "", fs.Config.IgnoreExisting, "Skip all files that exist on destination")
 	flags.BoolVarP(flagSet, &fs.Config.DryRun, "dry-run", "n", fs.Config.DryRun, "Do a trial run with no permanent changes")
+	flags.BoolVarP(flagSet, &fs.Config.Interactive, "interactive", "", fs.Config.Interactive, "Ask before making each change to the destination")
 	flags.DurationVarP(flagSet, &fs.Config.ConnectTimeout, "contimeout", "", fs.Config.ConnectTimeout, "Connect timeout")
 	flags.DurationVarP(flagSet, &fs.Config.Timeout, "timeout", "", fs.Config.Timeout, "IO idle timeout")
 	flags.BoolVarP(flagSet, &dumpHeaders, "dump-headers", "", false, "Dump HTTP bodies - may contain sensitive info")
@@ -54,8 +59,14 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.BoolVarP(flagSet, &deleteDuring, "delete-during", "", false, "When synchronizing, delete files during transfer (default)")
 	flags.BoolVarP(flagSet, &deleteAfter, "delete-after", "", false, "When synchronizing, delete files on destination after transfering")
 	flags.IntVar64P(flagSet, &fs.Config.MaxDelete, "max-delete", "", -1, "When synchronizing, limit the number of deletes")
+	flags.IntVarP(flagSet, &fs.Config.DeleteWorkers, "delete-workers", "", fs.Config.DeleteWorkers, "Number of deletes to run in parallel (default --transfers, or --checkers for purge)")
 	flags.BoolVarP(flagSet, &fs.Config.TrackRenames, "track-renames", "", fs.Config.TrackRenames, "When synchronizing, track file renames and do a server side move if possible")
+	flags.BoolVarP(flagSet, &fs.Config.CheckFirst, "check-first", "", fs.Config.CheckFirst, "Do all the checks before starting transfers and show what will be transferred")
+	flags.BoolVarP(flagSet, &fs.Config.IgnoreErrors, "ignore-errors", "", fs.Config.IgnoreErrors, "Delete even if there are I/O errors")
+	flags.StringVarP(flagSet, &fs.Config.ResumeState, "resume-state", "", fs.Config.ResumeState, "Save the state of a sync, copy or move to this file so it can be resumed if interrupted")
 	flags.IntVarP(flagSet, &fs.Config.LowLevelRetries, "low-level-retries", "", fs.Config.LowLevelRetries, "Number of low level retries to do.")
+	flags.BoolVarP(flagSet, &fs.Config.AutoTune, "auto-tune", "", fs.Config.AutoTune, "Adjust the number of checkers and transfers running up to --checkers and --transfers.")
+	flags.IntVarP(flagSet, &fs.Config.FileRetries, "file-retries", "", fs.Config.FileRetries, "Number of times to retry each failed transfer at the end of the run.")
 	flags.BoolVarP(flagSet, &fs.Config.UpdateOlder, "update", "u", fs.Config.UpdateOlder, "Skip files that are newer on the destination.")
 	flags.BoolVarP(flagSet, &fs.Config.NoGzip, "no-gzip-encoding", "", fs.Config.NoGzip, "Don't set Accept-Encoding: gzip.")
 	flags.IntVarP(flagSet, &fs.Config.MaxDepth, "max-depth", "", fs.Config.MaxDepth, "If set limits the recursion depth to this.")
@@ -68,7 +79,9 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.BoolVarP(flagSet, &fs.Config.UseListR, "fast-list", "", fs.Config.UseListR, "Use recursive list if available. Uses more memory but fewer transactions.")
 	flags.Float64VarP(flagSet, &fs.Config.TPSLimit, "tpslimit", "", fs.Config.TPSLimit, "Limit HTTP transactions per second to this.")
 	flags.IntVarP(flagSet, &fs.Config.TPSLimitBurst, "tpslimit-burst", "", fs.Config.TPSLimitBurst, "Max burst of transactions for --tpslimit.")
-	flags.StringVarP(flagSet, &bindAddr, "bind", "", "", "Local address to bind to for outgoing connections, IPv4, IPv6 or name.")
+	flags.StringVarP(flagSet, &bindAddr, "bind", "", "", "Local address to bind to for outgoing connections, IPv4, IPv6, name or interface.")
+	flags.BoolVarP(flagSet, &fs.Config.IPv4Only, "ipv4", "", fs.Config.IPv4Only, "Only use IPv4 for outgoing connections.")
+	flags.BoolVarP(flagSet, &fs.Config.IPv6Only, "ipv6", "", fs.Config.IPv6Only, "Only use IPv6 for outgoing connections.")
 	flags.StringVarP(flagSet, &disableFeatures, "disable", "", "", "Disable a comma separated list of features.  Use help to see a list.")
 	flags.StringVarP(flagSet, &fs.Config.UserAgent, "user-agent", "", fs.Config.UserAgent, "Set the user-agent to a specified string. The default is rclone/ version")
 	flags.BoolVarP(flagSet, &fs.Config.Immutable, "immutable", "", fs.Config.Immutable, "Do not modify files. Fail if existing files have been modified.")
@@ -77,8 +90,11 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.FVarP(flagSet, &fs.Config.LogLevel, "log-level", "", "Log level DEBUG|INFO|NOTICE|ERROR")
 	flags.FVarP(flagSet, &fs.Config.StatsLogLevel, "stats-log-level", "", "Log level to show --stats output DEBUG|INFO|NOTICE|ERROR")
 	flags.FVarP(flagSet, &fs.Config.BwLimit, "bwlimit", "", "Bandwidth limit in kBytes/s, or use suffix b|k|M|G or a full timetable.")
+	flags.FVarP(flagSet, &fs.Config.BwLimitFile, "bwlimit-file", "", "Bandwidth limit per file in kBytes/s, or use suffix b|k|M|G.")
 	flags.FVarP(flagSet, &fs.Config.BufferSize, "buffer-size", "", "Buffer size when copying files.")
-	flags.FVarP(flagSet, &fs.Config.StreamingUploadCutoff, "streaming-upload-cutoff", "", "Cutoff for switching to chunked upload if file size is unknown. Upload starts after reaching cutoff or when file ends.")
+	flags.FVarP(flagSet, &fs.Config.MultiThreadCutoff, "multi-thread-cutoff", "", "Use multi-thread downloads for files above this size.")
+	flags.IntVarP(flagSet, &fs.Config.MultiThreadStreams, "multi-thread-streams", "", fs.Config.MultiThreadStreams, "Max number of streams to use for multi-thread downloads.")
+	flags.FVarP(flagSet, &fs.Config.StreamingUploadCutoff, "streaming-upload-cutoff", "", "Cutoff for switching to chunked upload if file size is unknown. Upload starts after reaching cutoff or when file ends. Used by rcat and when copying files of unknown size.")
 	flags.FVarP(flagSet, &fs.Config.Dump, "dump", "", "List of items to dump from: "+fs.DumpFlagsList)
 
 }
@@ -141,15 +157,20 @@ func SetFlags() {
 		log.Fatalf(`Can only use --suffix with --backup-dir.`)
 	}
 
+	if fs.Config.IPv4Only && fs.Config.IPv6Only {
+		log.Fatalf(`Can't use --ipv4 and --ipv6 together.`)
+	}
+
 	if bindAddr != "" {
-		addrs, err := net.LookupIP(bindAddr)
+		addrs, err := lookupBindAddr(bindAddr)
 		if err != nil {
 			log.Fatalf("--bind: Failed to parse %q as IP address: %v", bindAddr, err)
 		}
-		if len(addrs) != 1 {
-			log.Fatalf("--bind: Expecting 1 IP address for %q but got %d", bindAddr, len(addrs))
+		addr := chooseAddr(addrs)
+		if addr == nil {
+			log.Fatalf("--bind: No usable IP address for %q in %v", bindAddr, addrs)
 		}
-		fs.Config.BindAddr = addrs[0]
+		fs.Config.BindAddr = addr
 	}
 
 	if disableFeatures != "" {
@@ -165,3 +186,111 @@ func SetFlags() {
 		config.ConfigPath = configPath
 	}
 }
+
+// lookupBindAddr returns the IP addresses for the --bind parameter
+// which may be an IP address, a host name or a network interface name.
+func lookupBindAddr(name string) (addrs []net.IP, err error) {
+	if iface, ifaceErr := net.InterfaceByName(name); ifaceErr == nil {
+		ifaceAddrs, err := iface.Addrs()
+		if err != nil {
+			return nil, err
+		}
+		for _, ifaceAddr := range ifaceAddrs {
+			if ipNet, ok := ifaceAddr.(*net.IPNet); ok && !ipNet.IP.IsLinkLocalUnicast() {
+				addrs = append(addrs, ipNet.IP)
+			}
+		}
+		return addrs, nil
+	}
+	return net.LookupIP(name)
+}
+
+// chooseAddr returns the first of addrs allowed by --ipv4 or --ipv6,
+// preferring IPv4 if neither is set, or nil if there isn't one.
+func chooseAddr(addrs []net.IP) net.IP {
+	var ipv4, ipv6 net.IP
+	for _, addr := range addrs {
+		if addr.To4() != nil {
+			if ipv4 == nil {
+				ipv4 = addr
+			}
+		} else if ipv6 == nil {
+			ipv6 = addr
+		}
+	}
+	switch {
+	case fs.Config.IPv4Only:
+		return ipv4
+	case fs.Config.IPv6Only:
+		return ipv6
+	case ipv4 != nil:
+		return ipv4
+	}
+	return ipv6
+}
+
+// notPerRemote are the flags which can't be set in a remote's
+// defaults as they are used before any remotes are made
+var notPerRemote = map[string]bool{
+	"config":       true,
+	"cache-dir":    true,
+	"verbose":      true,
+	"quiet":        true,
+	"log-level":    true,
+	"log-file":     true,
+	"syslog":       true,
+	"ask-password": true,
+}
+
+// SetRemoteDefaults sets the flags from the defaults in the config
+// of the remote called name, eg "default.transfers = 8".
+//
+// Flags set on the command line or in the environment take
+// precedence over the remote's defaults.  If more than one remote
+// sets the same default then the last one set wins.
+func SetRemoteDefaults(name string) error {
+	defaults := config.RemoteDefaults(name)
+	if len(defaults) == 0 {
+		return nil
+	}
+	var flagNames []string
+	for flagName := range defaults {
+		flagNames = append(flagNames, flagName)
+	}
+	sort.Strings(flagNames)
+	changed := map[string]bool{}
+	for _, flagName := range flagNames {
+		value := defaults[flagName]
+		if notPerRemote[flagName] {
+			return errors.Errorf("--%s can't be set in the config of a remote", flagName)
+		}
+		flag := pflag.Lookup(flagName)
+		if flag == nil {
+			return errors.Errorf("unknown flag --%s in %s%s", flagName, config.ConfigDefaultPrefix, flagName)
+		}
+		if flag.Changed || flags.InEnvironment(flagName) {
+			fs.Debugf(name, "Ignoring default --%s=%s from config as set on command line or in environment", flagName, value)
+			continue
+		}
+		// Set the Value directly so flag.Changed stays false
+		err := flag.Value.Set(value)
+		if err != nil {
+			return errors.Wrapf(err, "invalid default for --%s", flagName)
+		}
+		fs.Debugf(name, "Set --%s=%s from config", flagName, value)
+		changed[flagName] = true
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	SetFlags()
+	// Restart the limiters which were started with the config
+	if changed["bwlimit"] {
+		accounting.StartTokenBucket()
+		accounting.StartTokenTicker()
+	}
+	if changed["tpslimit"] || changed["tpslimit-burst"] {
+		fshttp.StartHTTPTokenBucket()
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package configflags
+
+import (
+	"net"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// emptyStorage is a config.Storage with nothing in it
+type emptyStorage struct{}
+
+func (emptyStorage) Load() ([]byte, error)  { return nil, config.ErrorConfigFileNotFound }
+func (emptyStorage) Save(data []byte) error { return nil }
+
+func TestSetRemoteDefaults(t *testing.T) {
+	config.SetStorage(emptyStorage{})
+	defer config.SetStorage(nil)
+	config.LoadConfig()
+
+	flagSet := pflag.CommandLine
+	if flagSet.Lookup("transfers") == nil {
+		AddFlags(flagSet)
+	}
+	oldConfig := *fs.Config
+	defer func() {
+		*fs.Config = oldConfig
+		_ = flagSet.Set("transfers", "4")
+		flagSet.Lookup("transfers").Changed = false
+		flagSet.Lookup("checkers").Changed = false
+	}()
+
+	for _, keyValue := range [][2]string{
+		{"type", "local"},
+		{"default.transfers", "9"},
+		{"default.checkers", "17"},
+		{"default.size_only", "true"},
+	} {
+		config.FileSet("remotedefaults", keyValue[0], keyValue[1])
+	}
+	config.FileSet("badflag", "type", "local")
+	config.FileSet("badflag", "default.potato", "1")
+	config.FileSet("notperremote", "type", "local")
+	config.FileSet("notperremote", "default.config", "/tmp/rclone.conf")
+
+	// Flags set on the command line take precedence
+	require.NoError(t, flagSet.Set("checkers", "3"))
+
+	require.NoError(t, SetRemoteDefaults("remotedefaults"))
+	assert.Equal(t, 9, fs.Config.Transfers)
+	assert.Equal(t, 3, fs.Config.Checkers)
+	assert.True(t, fs.Config.SizeOnly)
+	assert.False(t, flagSet.Lookup("transfers").Changed)
+
+	// A remote with no defaults changes nothing
+	require.NoError(t, SetRemoteDefaults("notfound"))
+	assert.Equal(t, 9, fs.Config.Transfers)
+
+	err := SetRemoteDefaults("badflag")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown flag --potato")
+
+	err = SetRemoteDefaults("notperremote")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "can't be set")
+}
+
+func TestChooseAddr(t *testing.T) {
+	oldConfig := *fs.Config
+	defer func() {
+		*fs.Config = oldConfig
+	}()
+	ipv4a, ipv4b := net.ParseIP("192.168.1.2"), net.ParseIP("10.0.0.1")
+	ipv6a, ipv6b := net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::2")
+	for _, test := range []struct {
+		ipv4Only bool
+		ipv6Only bool
+		addrs    []net.IP
+		want     net.IP
+	}{
+		{false, false, nil, nil},
+		{false, false, []net.IP{ipv4a}, ipv4a},
+		{false, false, []net.IP{ipv6a, ipv4a, ipv4b}, ipv4a},
+		{false, false, []net.IP{ipv6a, ipv6b}, ipv6a},
+		{true, false, []net.IP{ipv6a, ipv4b, ipv4a}, ipv4b},
+		{true, false, []net.IP{ipv6a}, nil},
+		{false, true, []net.IP{ipv4a, ipv6b, ipv6a}, ipv6b},
+		{false, true, []net.IP{ipv4a}, nil},
+	} {
+		fs.Config.IPv4Only, fs.Config.IPv6Only = test.ipv4Only, test.ipv6Only
+		assert.Equal(t, test.want, chooseAddr(test.addrs), "%v", test.addrs)
+	}
+}
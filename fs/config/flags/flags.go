@@ -18,6 +18,13 @@ func optionToEnv(name string) string {
 	return "RCLONE_" + strings.ToUpper(strings.Replace(name, "-", "_", -1))
 }
 
+// InEnvironment returns true if the flag called name has its default
+// set by an environment variable
+func InEnvironment(name string) bool {
+	_, found := os.LookupEnv(optionToEnv(name))
+	return found
+}
+
 // setDefaultFromEnv constructs a name from the flag passed in and
 // sets the default from the environment if possible.
 func setDefaultFromEnv(name string) {
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/spf13/pflag"
+)
+
+// The places a config value can come from.  When a value is set in
+// more than one of them, the first in this list is used.
+const (
+	SourceConnectionString = "connection string" // eg remote,acl=private:
+	SourceFlag             = "flag"              // eg --s3-acl private
+	SourceEnvironment      = "environment"       // eg RCLONE_CONFIG_REMOTE_ACL=private or RCLONE_S3_ACL=private
+	SourceConfigFile       = "config file"       // eg acl = private
+)
+
+var (
+	connectionStringParams = regexp.MustCompile(`^(?:` + fs.ConnectionStringParam + `)+$`)
+	connectionStringParam  = regexp.MustCompile(`,([\w-]+)=("[^"]*"|'[^']*'|[^,:"']*)`)
+)
+
+// sectionExists returns true if the remote called name is in the
+// config file or defined in the environment
+func sectionExists(name string) bool {
+	if _, err := getConfigData().GetSection(name); err == nil {
+		return true
+	}
+	_, found := os.LookupEnv(configToEnv(name, "type"))
+	return found
+}
+
+// splitConnectionString splits section, which may be a connection
+// string like remote,acl=private, into the name of the remote and the
+// parameters which override its config.
+//
+// The parameter keys may use "-" or "_" and the values may be quoted
+// with " or ' if they contain "," or ":".
+func splitConnectionString(section string) (name string, params map[string]string) {
+	if !strings.Contains(section, "=") || sectionExists(section) {
+		return section, nil
+	}
+	// Split on the first "," which starts the parameters, unless a
+	// later one gives the name of an existing remote, as quoted
+	// names may contain "," and "="
+	split := -1
+	for i := 1; i < len(section); i++ {
+		if section[i] != ',' || !connectionStringParams.MatchString(section[i:]) {
+			continue
+		}
+		if sectionExists(section[:i]) {
+			split = i
+			break
+		}
+		if split < 0 {
+			split = i
+		}
+	}
+	if split < 0 {
+		return section, nil
+	}
+	params = map[string]string{}
+	for _, match := range connectionStringParam.FindAllStringSubmatch(section[split:], -1) {
+		key, value := strings.Replace(match[1], "-", "_", -1), match[2]
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+			value = value[1 : len(value)-1]
+		}
+		params[key] = value
+	}
+	return section[:split], params
+}
+
+// backendFlag returns the command line flag for key in the remote
+// in section, eg --s3-acl for the key acl of an s3 remote, or nil if
+// there isn't one.
+func backendFlag(section, key string) *pflag.Flag {
+	if key == "type" {
+		return nil
+	}
+	fsType, _, found := FileGetSource(section, "type")
+	if !found || fsType == "" {
+		return nil
+	}
+	return pflag.Lookup(fsType + "-" + strings.Replace(key, "_", "-", -1))
+}
+
+// FileGetSource gets the config key under section returning its
+// value and the source it came from.  found is false if it isn't set
+// anywhere.  FileGet and friends treat an empty value as not set.
+//
+// section may be a connection string, eg remote,acl=private.  The
+// sources are used in the order
+//
+//   - the connection string parameters
+//   - the backend flag, eg --s3-acl, if set on the command line
+//   - the remote's environment variable, eg RCLONE_CONFIG_REMOTE_ACL
+//   - the backend flag's environment variable, eg RCLONE_S3_ACL
+//   - the config file
+func FileGetSource(section, key string) (value, source string, found bool) {
+	name, params := splitConnectionString(section)
+	if value, found = params[key]; found {
+		return value, SourceConnectionString, true
+	}
+	flag := backendFlag(section, key)
+	if flag != nil && flag.Changed {
+		return flag.Value.String(), SourceFlag, true
+	}
+	if value, found = os.LookupEnv(configToEnv(name, key)); found {
+		return value, SourceEnvironment, true
+	}
+	if flag != nil && flags.InEnvironment(flag.Name) {
+		return flag.Value.String(), SourceEnvironment, true
+	}
+	value, err := getConfigData().GetValue(name, key)
+	if err == nil {
+		return value, SourceConfigFile, true
+	}
+	return "", "", false
+}
+
+// resolvedKeys returns the keys which may be set for the remote in
+// section, with "type" first and the rest sorted.
+func resolvedKeys(section string, fsInfo *fs.RegInfo) []string {
+	name, params := splitConnectionString(section)
+	seen := map[string]bool{"type": true}
+	var keys []string
+	add := func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range getConfigData().GetKeyList(name) {
+		add(key)
+	}
+	for key := range params {
+		add(key)
+	}
+	if fsInfo != nil {
+		for _, option := range fsInfo.Options {
+			add(option.Name)
+		}
+	}
+	envPrefix := configToEnv(name, "")
+	for _, keyValue := range os.Environ() {
+		if strings.HasPrefix(keyValue, envPrefix) {
+			if i := strings.IndexRune(keyValue, '='); i > len(envPrefix) {
+				add(strings.ToLower(keyValue[len(envPrefix):i]))
+			}
+		}
+	}
+	sort.Strings(keys)
+	return append([]string{"type"}, keys...)
+}
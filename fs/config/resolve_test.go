@@ -0,0 +1,127 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/Unknwon/goconfig"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitConnectionString(t *testing.T) {
+	oldConfigFile := configFile
+	defer func() { configFile = oldConfigFile }()
+	var err error
+	configFile, err = goconfig.LoadFromReader(bytes.NewBufferString(`
+[remote]
+type = local
+
+[my,remote]
+type = local
+`))
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		section string
+		name    string
+		params  map[string]string
+	}{
+		{"remote", "remote", nil},
+		{"remote,acl=private", "remote", map[string]string{"acl": "private"}},
+		{`remote,a='x,y',b="c:d",chunk-size=`, "remote", map[string]string{"a": "x,y", "b": "c:d", "chunk_size": ""}},
+		{"my,remote", "my,remote", nil},
+		{"my,remote,acl=private", "my,remote", map[string]string{"acl": "private"}},
+		{"notfound,acl=private", "notfound", map[string]string{"acl": "private"}},
+		{"remote,acl", "remote,acl", nil},
+	} {
+		name, params := splitConnectionString(test.section)
+		assert.Equal(t, test.name, name, test.section)
+		assert.Equal(t, test.params, params, test.section)
+	}
+}
+
+func TestFileGetSource(t *testing.T) {
+	oldConfigFile := configFile
+	defer func() { configFile = oldConfigFile }()
+	var err error
+	configFile, err = goconfig.LoadFromReader(bytes.NewBufferString(`
+[resolve]
+type = resolvetest
+acl = from-config
+region = from-config
+endpoint = from-config
+class = from-config
+size = from-config
+empty =
+`))
+	require.NoError(t, err)
+
+	if pflag.Lookup("resolvetest-acl") == nil {
+		pflag.String("resolvetest-acl", "", "Flag for testing")
+		pflag.String("resolvetest-region", "", "Flag for testing")
+		pflag.String("resolvetest-class", "", "Flag for testing")
+	}
+	require.NoError(t, pflag.Set("resolvetest-acl", "from-flag"))
+	require.NoError(t, pflag.Set("resolvetest-region", "from-flag"))
+	defer func() {
+		for _, name := range []string{"resolvetest-acl", "resolvetest-region"} {
+			flag := pflag.Lookup(name)
+			_ = flag.Value.Set("")
+			flag.Changed = false
+		}
+	}()
+	for key, value := range map[string]string{
+		"RCLONE_CONFIG_RESOLVE_REGION":   "from-env",
+		"RCLONE_CONFIG_RESOLVE_ENDPOINT": "from-env",
+		"RCLONE_RESOLVETEST_CLASS":       "from-flag-env",
+	} {
+		require.NoError(t, os.Setenv(key, value))
+		defer func(key string) {
+			_ = os.Unsetenv(key)
+		}(key)
+	}
+	// The flag default is read from the environment when the flag is
+	// made so set it here as the flag already exists
+	require.NoError(t, pflag.Lookup("resolvetest-class").Value.Set("from-flag-env"))
+	defer func() {
+		_ = pflag.Lookup("resolvetest-class").Value.Set("")
+	}()
+
+	for _, test := range []struct {
+		section string
+		key     string
+		value   string
+		source  string
+		found   bool
+	}{
+		{"resolve", "acl", "from-flag", SourceFlag, true},
+		{"resolve,acl=from-connection", "acl", "from-connection", SourceConnectionString, true},
+		{"resolve", "region", "from-flag", SourceFlag, true},
+		{"resolve", "endpoint", "from-env", SourceEnvironment, true},
+		{"resolve", "class", "from-flag-env", SourceEnvironment, true},
+		{"resolve", "size", "from-config", SourceConfigFile, true},
+		{"resolve,size=from-connection", "size", "from-connection", SourceConnectionString, true},
+		{"resolve", "empty", "", SourceConfigFile, true},
+		{"resolve", "missing", "", "", false},
+		{"resolve", "type", "resolvetest", SourceConfigFile, true},
+	} {
+		value, source, found := FileGetSource(test.section, test.key)
+		what := test.section + " " + test.key
+		assert.Equal(t, test.value, value, what)
+		assert.Equal(t, test.source, source, what)
+		assert.Equal(t, test.found, found, what)
+	}
+
+	assert.Equal(t, "default", FileGet("resolve", "empty", "default"))
+	assert.Equal(t, "default", FileGet("resolve", "missing", "default"))
+	assert.Equal(t, 3, FileGetInt("resolve,size=3", "size", 1))
+	assert.Equal(t, true, FileGetBool("resolve,flag=true", "flag", false))
+
+	// Setting a value through a connection string sets it in the remote
+	FileSet("resolve,size=1", "newkey", "potato")
+	assert.Equal(t, "potato", configFile.MustValue("resolve", "newkey"))
+	assert.True(t, FileDeleteKey("resolve,size=1", "newkey"))
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// Storage is used to load and save the config data.
+//
+// By default the config is stored in the file at ConfigPath.
+// Programs embedding rclone, for example mobile apps, can use
+// SetStorage to keep it somewhere else, such as the platform's
+// secure storage.
+//
+// The data passed to Save is exactly what would be written to the
+// config file, so it is encrypted if a config password is set.
+type Storage interface {
+	// Load returns the config data previously saved.  It should
+	// return ErrorConfigFileNotFound if there isn't any yet.
+	Load() ([]byte, error)
+
+	// Save stores the config data, replacing any previous data.
+	Save(data []byte) error
+}
+
+// ChangeFunc is called with the section, key and new value when a
+// value is changed and saved in the config while rclone is running,
+// for example when an oauth token is refreshed.
+type ChangeFunc func(section, key, value string)
+
+var (
+	storageMu   sync.Mutex
+	storage     Storage = fileStorage{}
+	changeFuncs []ChangeFunc
+)
+
+// SetStorage sets the Storage used to load and save the config.
+//
+// This should be called before LoadConfig.  Passing nil restores the
+// default of using the file at ConfigPath.
+func SetStorage(s Storage) {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+	if s == nil {
+		s = fileStorage{}
+	}
+	storage = s
+}
+
+// getStorage returns the Storage in use
+func getStorage() Storage {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+	return storage
+}
+
+// OnChange registers fn to be called whenever a value is changed
+// and saved with SetValueAndSave.
+//
+// fn may be called from any goroutine so should not block.
+func OnChange(fn ChangeFunc) {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+	changeFuncs = append(changeFuncs, fn)
+}
+
+// notifyChange calls the registered ChangeFuncs
+func notifyChange(section, key, value string) {
+	storageMu.Lock()
+	fns := append([]ChangeFunc(nil), changeFuncs...)
+	storageMu.Unlock()
+	for _, fn := range fns {
+		fn(section, key, value)
+	}
+}
+
+// fileStorage stores the config in the file at ConfigPath
+type fileStorage struct{}
+
+// Load reads the config file
+func (fileStorage) Load() ([]byte, error) {
+	b, err := ioutil.ReadFile(ConfigPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrorConfigFileNotFound
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// Save writes the config file atomically, keeping the permissions
+// of any existing file
+func (fileStorage) Save(data []byte) error {
+	dir, name := filepath.Split(ConfigPath)
+	f, err := ioutil.TempFile(dir, name)
+	if err != nil {
+		return errors.Errorf("Failed to create temp file for new config: %v", err)
+	}
+	defer func() {
+		if err := os.Remove(f.Name()); err != nil && !os.IsNotExist(err) {
+			fs.Errorf(nil, "Failed to remove temp config file: %v", err)
+		}
+	}()
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return errors.Errorf("Failed to write temp config file: %v", err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return errors.Errorf("Failed to close config file: %v", err)
+	}
+
+	var fileMode os.FileMode = 0600
+	info, err := os.Stat(ConfigPath)
+	if err != nil {
+		fs.Debugf(nil, "Using default permissions for config file: %v", fileMode)
+	} else if info.Mode() != fileMode {
+		fs.Debugf(nil, "Keeping previous permissions for config file: %v", info.Mode())
+		fileMode = info.Mode()
+	}
+
+	attemptCopyGroup(ConfigPath, f.Name())
+
+	err = os.Chmod(f.Name(), fileMode)
+	if err != nil {
+		fs.Errorf(nil, "Failed to set permissions on config file: %v", err)
+	}
+
+	if err = os.Rename(ConfigPath, ConfigPath+".old"); err != nil && !os.IsNotExist(err) {
+		return errors.Errorf("Failed to move previous config to backup location: %v", err)
+	}
+	if err = os.Rename(f.Name(), ConfigPath); err != nil {
+		return errors.Errorf("Failed to move newly written config from %s to final location: %v", f.Name(), err)
+	}
+	if err := os.Remove(ConfigPath + ".old"); err != nil && !os.IsNotExist(err) {
+		fs.Errorf(nil, "Failed to remove backup config file: %v", err)
+	}
+	return nil
+}
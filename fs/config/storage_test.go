@@ -0,0 +1,80 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStorage is a Storage which keeps the config in memory
+type memoryStorage struct {
+	data  []byte
+	saves int
+}
+
+func (m *memoryStorage) Load() ([]byte, error) {
+	if m.data == nil {
+		return nil, ErrorConfigFileNotFound
+	}
+	return m.data, nil
+}
+
+func (m *memoryStorage) Save(data []byte) error {
+	m.data = append([]byte(nil), data...)
+	m.saves++
+	return nil
+}
+
+func TestStorage(t *testing.T) {
+	configKey = nil // reset password
+	oldConfig := fs.Config
+	oldConfigFile := configFile
+	oldChangeFuncs := changeFuncs
+	fs.Config = &fs.ConfigInfo{}
+	configFile = nil
+	m := &memoryStorage{}
+	SetStorage(m)
+	defer func() {
+		SetStorage(nil)
+		fs.Config = oldConfig
+		configFile = oldConfigFile
+		changeFuncs = oldChangeFuncs
+		configKey = nil
+	}()
+
+	var changes []string
+	OnChange(func(section, key, value string) {
+		changes = append(changes, section+"."+key+"="+value)
+	})
+
+	// Nothing saved yet so starts empty
+	LoadConfig()
+	assert.Equal(t, []string{}, getConfigData().GetSectionList())
+
+	// Create a remote and check it is saved in the storage
+	FileSet("remote", "type", "local")
+	SaveConfig()
+	assert.Equal(t, 1, m.saves)
+	assert.Contains(t, string(m.data), "[remote]")
+
+	// Change a value, eg a token refresh
+	require.NoError(t, SetValueAndSave("remote", ConfigToken, `{"access_token":"x"}`))
+	assert.Equal(t, 2, m.saves)
+	assert.Contains(t, string(m.data), `{"access_token":"x"}`)
+	assert.Equal(t, []string{`remote.token={"access_token":"x"}`}, changes)
+
+	// Check it loads back from the storage
+	configFile = nil
+	assert.Equal(t, `{"access_token":"x"}`, FileGet("remote", ConfigToken))
+
+	// Check encrypted configs are passed to the storage encrypted
+	require.NoError(t, setConfigPassword("potato"))
+	SaveConfig()
+	assert.True(t, strings.Contains(string(m.data), "RCLONE_ENCRYPT_V0:"))
+	assert.False(t, strings.Contains(string(m.data), "access_token"))
+	configFile = nil
+	assert.Equal(t, `{"access_token":"x"}`, FileGet("remote", ConfigToken))
+}
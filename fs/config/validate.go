@@ -0,0 +1,159 @@
+// Validate the config of the remotes
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/spf13/pflag"
+)
+
+// commonKeys are the config keys which any remote may use as well as
+// the Options of its backend
+var commonKeys = map[string]bool{
+	"type":          true,
+	"user_agent":    true,
+	"disable":       true,
+	ConfigToken:     true,
+	ConfigAuthURL:   true,
+	ConfigTokenURL:  true,
+	ConfigAutomatic: true,
+}
+
+// ValidateRemote checks the config of the remote called name and
+// returns a description of each problem found.
+//
+// It checks the type of the remote is known, that each key is used by
+// the backend and that the values of bool and int options parse.
+func ValidateRemote(name string) (problems []string) {
+	remoteType := FileGet(name, "type")
+	if remoteType == "" {
+		return []string{"type not set"}
+	}
+	ri, err := fs.Find(remoteType)
+	if err != nil {
+		return []string{fmt.Sprintf("unknown type %q", remoteType)}
+	}
+	options := map[string]*fs.Option{}
+	for i := range ri.Options {
+		options[ri.Options[i].Name] = &ri.Options[i]
+	}
+	for _, key := range getConfigData().GetKeyList(name) {
+		if commonKeys[key] {
+			continue
+		}
+		if strings.HasPrefix(key, ConfigDefaultPrefix) {
+			flagName := strings.Replace(key[len(ConfigDefaultPrefix):], "_", "-", -1)
+			if pflag.CommandLine.HasFlags() && pflag.Lookup(flagName) == nil {
+				problems = append(problems, fmt.Sprintf("unknown flag --%s in %q", flagName, key))
+			}
+			continue
+		}
+		option, ok := options[key]
+		if !ok {
+			problem := fmt.Sprintf("unknown key %q", key)
+			if suggestion := closestKey(key, options); suggestion != "" {
+				problem += fmt.Sprintf(" - did you mean %q?", suggestion)
+			}
+			problems = append(problems, problem)
+			continue
+		}
+		value := getConfigData().MustValue(name, key, "")
+		if value == "" {
+			continue
+		}
+		switch option.Type {
+		case fs.OptionTypeBool:
+			if _, err := strconv.ParseBool(value); err != nil {
+				problems = append(problems, fmt.Sprintf("%s = %q should be true or false", key, value))
+			}
+		case fs.OptionTypeInt:
+			if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+				problems = append(problems, fmt.Sprintf("%s = %q should be a whole number", key, value))
+			}
+		}
+	}
+	return problems
+}
+
+// ValidateConfig checks the config of all the remotes in the config
+// file and returns the problems found indexed by remote name.
+func ValidateConfig() map[string][]string {
+	remoteProblems := map[string][]string{}
+	for _, name := range getConfigData().GetSectionList() {
+		if problems := ValidateRemote(name); len(problems) > 0 {
+			remoteProblems[name] = problems
+		}
+	}
+	return remoteProblems
+}
+
+// warnConfigProblems logs the problems found in the config file when
+// it is loaded.
+//
+// Remotes whose backend isn't compiled in are skipped as they can't be
+// checked.
+func warnConfigProblems() {
+	remoteProblems := ValidateConfig()
+	var names []string
+	for name := range remoteProblems {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := fs.Find(FileGet(name, "type")); err != nil {
+			continue
+		}
+		for _, problem := range remoteProblems[name] {
+			fs.Logf(nil, "Config for remote %q: %s", name, problem)
+		}
+	}
+}
+
+// closestKey returns the name of the option closest to key if it is
+// near enough to be a typo, or "" if there isn't one.
+func closestKey(key string, options map[string]*fs.Option) string {
+	best, bestDistance := "", 3
+	for name := range options {
+		distance := editDistance(key, name)
+		if distance < bestDistance || (distance == bestDistance && name < best) {
+			best, bestDistance = name, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// min returns the smallest of its arguments
+func min(x int, ys ...int) int {
+	for _, y := range ys {
+		if y < x {
+			x = y
+		}
+	}
+	return x
+}
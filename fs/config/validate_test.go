@@ -0,0 +1,119 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Unknwon/goconfig"
+	"github.com/ncw/rclone/fs"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRemote(t *testing.T) {
+	fs.Register(&fs.RegInfo{
+		Name: "validate_test_remote",
+		Options: []fs.Option{{
+			Name: "chunk_size",
+		}, {
+			Name: "env_auth",
+			Type: fs.OptionTypeBool,
+		}, {
+			Name: "port",
+			Type: fs.OptionTypeInt,
+		}, {
+			Name: "root_id",
+			Hide: true,
+		}},
+	})
+
+	if pflag.Lookup("validate-test-flag") == nil {
+		pflag.Int("validate-test-flag", 0, "Flag for testing")
+	}
+
+	oldConfigFile := configFile
+	defer func() { configFile = oldConfigFile }()
+	var err error
+	configFile, err = goconfig.LoadFromReader(bytes.NewBufferString(`
+[good]
+type = validate_test_remote
+chunk_size = 10M
+env_auth = true
+port = 22
+root_id = 1234
+token = {}
+default.validate-test-flag = 3
+
+[bad]
+type = validate_test_remote
+chunk_sise = 10M
+env_auth = maybe
+port = twenty
+potato = yes
+default.potato-flag = 1
+
+[notype]
+chunk_size = 10M
+
+[unknown]
+type = validate_test_unknown
+`))
+	require.NoError(t, err)
+
+	assert.Nil(t, ValidateRemote("good"))
+	assert.Equal(t, []string{
+		`unknown key "chunk_sise" - did you mean "chunk_size"?`,
+		`env_auth = "maybe" should be true or false`,
+		`port = "twenty" should be a whole number`,
+		`unknown key "potato"`,
+		`unknown flag --potato-flag in "default.potato-flag"`,
+	}, ValidateRemote("bad"))
+	assert.Equal(t, []string{"type not set"}, ValidateRemote("notype"))
+	assert.Equal(t, []string{`unknown type "validate_test_unknown"`}, ValidateRemote("unknown"))
+
+	remoteProblems := ValidateConfig()
+	assert.Len(t, remoteProblems, 3)
+	assert.Len(t, remoteProblems["bad"], 5)
+}
+
+func TestRemoteDefaults(t *testing.T) {
+	oldConfigFile := configFile
+	defer func() { configFile = oldConfigFile }()
+	var err error
+	configFile, err = goconfig.LoadFromReader(bytes.NewBufferString(`
+[withdefaults]
+type = local
+default.transfers = 8
+default.drive_chunk_size = 64M
+nodefault = 1
+
+[nodefaults]
+type = local
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"transfers":        "8",
+		"drive-chunk-size": "64M",
+	}, RemoteDefaults("withdefaults"))
+	assert.Equal(t, map[string]string{}, RemoteDefaults("nodefaults"))
+	assert.Equal(t, map[string]string{}, RemoteDefaults("notfound"))
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"chunk_size", "chunk_size", 0},
+		{"chunk_sise", "chunk_size", 1},
+		{"chunksize", "chunk_size", 1},
+		{"kitten", "sitting", 3},
+	} {
+		assert.Equal(t, test.want, editDistance(test.a, test.b), test.a+" "+test.b)
+	}
+}
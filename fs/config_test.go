@@ -0,0 +1,36 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigForRemote(t *testing.T) {
+	oldConfigFileGet := ConfigFileGet
+	defer func() { ConfigFileGet = oldConfigFileGet }()
+	ConfigFileGet = func(section, key string, defaultVal ...string) string {
+		if section == "special" && key == "user_agent" {
+			return "special-agent/1.0"
+		}
+		if section == "special" && key == "disable" {
+			return "copy,Move"
+		}
+		return ""
+	}
+
+	ci := ConfigForRemote("normal")
+	assert.Equal(t, Config.UserAgent, ci.UserAgent)
+	assert.False(t, ci == Config)
+
+	ci = ConfigForRemote("special")
+	assert.Equal(t, "special-agent/1.0", ci.UserAgent)
+	assert.NotEqual(t, "special-agent/1.0", Config.UserAgent)
+
+	assert.Equal(t, Config.DisableFeatures, ConfigForRemote("normal").DisableFeatures)
+	oldDisableFeatures := Config.DisableFeatures
+	defer func() { Config.DisableFeatures = oldDisableFeatures }()
+	Config.DisableFeatures = []string{"purge"}
+	assert.Equal(t, []string{"purge", "copy", "Move"}, ConfigForRemote("special").DisableFeatures)
+	assert.Equal(t, []string{"purge"}, Config.DisableFeatures)
+}
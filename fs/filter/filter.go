@@ -4,7 +4,6 @@ package filter
 import (
 	"bufio"
 	"fmt"
-	"log"
 	"os"
 	"path"
 	"regexp"
@@ -88,6 +87,7 @@ type Opt struct {
 	FilesFrom      []string
 	MinAge         fs.Duration
 	MaxAge         fs.Duration
+	ReferenceTime  fs.Time
 	MinSize        fs.SizeSuffix
 	MaxSize        fs.SizeSuffix
 }
@@ -124,14 +124,19 @@ func NewFilter(opt *Opt) (f *Filter, err error) {
 	}
 
 	// Filter flags
+	now := time.Now()
+	if f.Opt.ReferenceTime.IsSet() {
+		now = time.Time(f.Opt.ReferenceTime)
+		fs.Debugf(nil, "--reference-time %v", f.Opt.ReferenceTime)
+	}
 	if f.Opt.MinAge.IsSet() {
-		f.ModTimeTo = time.Now().Add(-time.Duration(f.Opt.MinAge))
+		f.ModTimeTo = now.Add(-time.Duration(f.Opt.MinAge))
 		fs.Debugf(nil, "--min-age %v to %v", f.Opt.MinAge, f.ModTimeTo)
 	}
 	if f.Opt.MaxAge.IsSet() {
-		f.ModTimeFrom = time.Now().Add(-time.Duration(f.Opt.MaxAge))
-		if !f.ModTimeTo.IsZero() && f.ModTimeFrom.Before(f.ModTimeTo) {
-			log.Fatal("filter: --min-age can't be larger than --max-age")
+		f.ModTimeFrom = now.Add(-time.Duration(f.Opt.MaxAge))
+		if !f.ModTimeTo.IsZero() && f.ModTimeTo.Before(f.ModTimeFrom) {
+			return nil, errors.New("filter: --min-age can't be larger than --max-age")
 		}
 		fs.Debugf(nil, "--max-age %v to %v", f.Opt.MaxAge, f.ModTimeFrom)
 	}
@@ -341,14 +346,18 @@ func (f *Filter) InActive() bool {
 		len(f.Opt.ExcludeFile) == 0)
 }
 
-// includeRemote returns whether this remote passes the filter rules.
-func (f *Filter) includeRemote(remote string) bool {
+// includeRemote returns whether this remote passes the filter rules
+// along with the rule which excluded it.
+func (f *Filter) includeRemote(remote string) (include bool, reason string) {
 	for _, rule := range f.fileRules.rules {
 		if rule.Match(remote) {
-			return rule.Include
+			if !rule.Include {
+				return false, fmt.Sprintf("matched filter rule %q", rule.String())
+			}
+			return true, ""
 		}
 	}
-	return true
+	return true, ""
 }
 
 // ListContainsExcludeFile checks if exclude file is present in the list.
@@ -382,21 +391,29 @@ func (f *Filter) IncludeDirectory(fs fs.Fs) func(string) (bool, error) {
 		if excl {
 			return false, nil
 		}
+		return f.IncludeDirectoryRules(remote), nil
+	}
+}
 
-		// filesFrom takes precedence
-		if f.files != nil {
-			_, include := f.dirs[remote]
-			return include, nil
-		}
-		remote += "/"
-		for _, rule := range f.dirRules.rules {
-			if rule.Match(remote) {
-				return rule.Include, nil
-			}
+// IncludeDirectoryRules returns whether the directory remote passes
+// --files-from and the directory filter rules.
+//
+// Unlike IncludeDirectory it doesn't look for --exclude-if-present
+// files so it never needs to query the remote.
+func (f *Filter) IncludeDirectoryRules(remote string) bool {
+	remote = strings.Trim(remote, "/")
+	// filesFrom takes precedence
+	if f.files != nil {
+		_, include := f.dirs[remote]
+		return include
+	}
+	remote += "/"
+	for _, rule := range f.dirRules.rules {
+		if rule.Match(remote) {
+			return rule.Include
 		}
-
-		return true, nil
 	}
+	return true
 }
 
 // DirContainsExcludeFile checks if exclude file is present in a
@@ -418,22 +435,32 @@ func (f *Filter) DirContainsExcludeFile(fremote fs.Fs, remote string) (bool, err
 // Include returns whether this object should be included into the
 // sync or not
 func (f *Filter) Include(remote string, size int64, modTime time.Time) bool {
+	include, _ := f.includeReason(remote, size, modTime)
+	return include
+}
+
+// includeReason returns whether this object should be included into
+// the sync or not along with the reason it was excluded
+func (f *Filter) includeReason(remote string, size int64, modTime time.Time) (include bool, reason string) {
 	// filesFrom takes precedence
 	if f.files != nil {
 		_, include := f.files[remote]
-		return include
+		if !include {
+			return false, "not in --files-from"
+		}
+		return true, ""
 	}
 	if !f.ModTimeFrom.IsZero() && modTime.Before(f.ModTimeFrom) {
-		return false
+		return false, fmt.Sprintf("modified before %v (--max-age)", f.ModTimeFrom)
 	}
 	if !f.ModTimeTo.IsZero() && modTime.After(f.ModTimeTo) {
-		return false
+		return false, fmt.Sprintf("modified after %v (--min-age)", f.ModTimeTo)
 	}
 	if f.Opt.MinSize >= 0 && size < int64(f.Opt.MinSize) {
-		return false
+		return false, fmt.Sprintf("size %d smaller than --min-size %v", size, f.Opt.MinSize)
 	}
 	if f.Opt.MaxSize >= 0 && size > int64(f.Opt.MaxSize) {
-		return false
+		return false, fmt.Sprintf("size %d larger than --max-size %v", size, f.Opt.MaxSize)
 	}
 	return f.includeRemote(remote)
 }
@@ -442,6 +469,13 @@ func (f *Filter) Include(remote string, size int64, modTime time.Time) bool {
 // the sync or not. This is a convenience function to avoid calling
 // o.ModTime(), which is an expensive operation.
 func (f *Filter) IncludeObject(o fs.Object) bool {
+	include, _ := f.IncludeObjectReason(o)
+	return include
+}
+
+// IncludeObjectReason is like IncludeObject but it also returns the
+// reason the object was excluded for logging.
+func (f *Filter) IncludeObjectReason(o fs.Object) (include bool, reason string) {
 	var modTime time.Time
 
 	if !f.ModTimeFrom.IsZero() || !f.ModTimeTo.IsZero() {
@@ -450,7 +484,7 @@ func (f *Filter) IncludeObject(o fs.Object) bool {
 		modTime = time.Unix(0, 0)
 	}
 
-	return f.Include(o.Remote(), o.Size(), modTime)
+	return f.includeReason(o.Remote(), o.Size(), modTime)
 }
 
 // forEachLine calls fn on every line in the file pointed to by path
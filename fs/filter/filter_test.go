@@ -250,6 +250,39 @@ func TestNewFilterMaxAge(t *testing.T) {
 	assert.False(t, f.InActive())
 }
 
+func TestNewFilterReferenceTime(t *testing.T) {
+	opt := DefaultOpt
+	opt.MinAge = fs.Duration(time.Hour)
+	opt.MaxAge = fs.Duration(24 * time.Hour)
+	opt.ReferenceTime = fs.Time(time.Unix(1440000000, 0))
+	f, err := NewFilter(&opt)
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1440000000-3600, 0), f.ModTimeTo)
+	assert.Equal(t, time.Unix(1440000000-24*3600, 0), f.ModTimeFrom)
+}
+
+func TestNewFilterMinAgeAndMaxAgeOrder(t *testing.T) {
+	opt := DefaultOpt
+	opt.ReferenceTime = fs.Time(time.Unix(1440000000, 0))
+
+	// --min-age smaller than --max-age selects the files between them
+	opt.MinAge = fs.Duration(time.Hour)
+	opt.MaxAge = fs.Duration(2 * time.Hour)
+	f, err := NewFilter(&opt)
+	require.NoError(t, err)
+	testInclude(t, f, []includeTest{
+		{"new.jpg", 100, 1440000000 - 1800, false},
+		{"middle.jpg", 100, 1440000000 - 5400, true},
+		{"old.jpg", 100, 1440000000 - 10800, false},
+	})
+
+	// --min-age larger than --max-age can't match anything
+	opt.MinAge = fs.Duration(2 * time.Hour)
+	opt.MaxAge = fs.Duration(time.Hour)
+	_, err = NewFilter(&opt)
+	assert.Error(t, err)
+}
+
 func TestNewFilterMatches(t *testing.T) {
 	f, err := NewFilter(nil)
 	require.NoError(t, err)
@@ -300,6 +333,38 @@ func TestNewFilterMatches(t *testing.T) {
 	assert.False(t, f.InActive())
 }
 
+func TestFilterIncludeReason(t *testing.T) {
+	f, err := NewFilter(nil)
+	require.NoError(t, err)
+	f.Opt.MinSize = 100
+	f.Opt.MaxSize = 200
+	err = f.AddRule("- *.png")
+	require.NoError(t, err)
+	for _, test := range []struct {
+		in      string
+		size    int64
+		want    bool
+		wantWhy string
+	}{
+		{"file.jpg", 150, true, ""},
+		{"file.jpg", 99, false, "size 99 smaller than --min-size 100"},
+		{"file.jpg", 201, false, "size 201 larger than --max-size 200"},
+		{"file.png", 150, false, `matched filter rule "- (^|/)[^/]*\\.png$"`},
+	} {
+		got, why := f.includeReason(test.in, test.size, time.Unix(0, 0))
+		assert.Equal(t, test.want, got, test.in)
+		assert.Equal(t, test.wantWhy, why, test.in)
+	}
+
+	f, err = NewFilter(nil)
+	require.NoError(t, err)
+	err = f.AddFile("file.jpg")
+	require.NoError(t, err)
+	got, why := f.includeReason("other.jpg", 0, time.Unix(0, 0))
+	assert.False(t, got)
+	assert.Equal(t, "not in --files-from", why)
+}
+
 func TestFilterAddDirRuleOrFileRule(t *testing.T) {
 	for _, test := range []struct {
 		included bool
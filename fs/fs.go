@@ -85,9 +85,17 @@ type Option struct {
 	Help       string
 	Optional   bool
 	IsPassword bool
+	Hide       bool           // not asked for by the config wizard, eg set by rclone itself or rarely needed
+	Type       string         // type of the value, OptionTypeBool or OptionTypeInt, or "" for a string
 	Examples   OptionExamples `json:",omitempty"`
 }
 
+// Types of Option which are checked when the config is validated
+const (
+	OptionTypeBool = "bool"
+	OptionTypeInt  = "int"
+)
+
 // OptionExamples is a slice of examples
 type OptionExamples []OptionExample
 
@@ -244,6 +252,67 @@ type MimeTyper interface {
 	MimeType() string
 }
 
+// CreatedTimer is an optional interface for Object
+type CreatedTimer interface {
+	// CreatedTime returns the time the Object was created if
+	// known, or the zero time if not
+	CreatedTime() time.Time
+}
+
+// GetTierer is an optional interface for Object
+type GetTierer interface {
+	// GetTier returns the storage tier or class of the Object if
+	// known, or "" if not
+	GetTier() string
+}
+
+// SetTierer is an optional interface for Object
+type SetTierer interface {
+	// SetTier changes the storage tier or class of the Object
+	SetTier(tier string) error
+}
+
+// RestoreStatus describes whether an archived Object can be read
+type RestoreStatus string
+
+// The values a RestoreStatus can take
+const (
+	RestoreStatusOnline    RestoreStatus = "online"    // not archived so can be read
+	RestoreStatusArchived  RestoreStatus = "archived"  // archived and not being restored
+	RestoreStatusRestoring RestoreStatus = "restoring" // being restored
+	RestoreStatusRestored  RestoreStatus = "restored"  // a restored copy can be read
+)
+
+// Restorer is an optional interface for Object
+type Restorer interface {
+	// Restore asks for an archived Object to be made readable
+	// for lifetime days.  priority says how quickly it should be
+	// done, in the terms of the backend, or "" for the default.
+	Restore(priority string, lifetime int) error
+
+	// RestoreStatus reads whether the Object is archived or
+	// being restored from the remote
+	RestoreStatus() (RestoreStatus, error)
+
+	// Archived returns whether the Object may be archived going
+	// by what was read when it was listed.  If it is false then
+	// the Object doesn't need restoring.
+	Archived() bool
+}
+
+// Metadataer is an optional interface for Object
+type Metadataer interface {
+	// Metadata returns the user metadata of the Object.  The keys
+	// are case insensitive and may be returned in any case.
+	Metadata() (map[string]string, error)
+}
+
+// SetMetadataer is an optional interface for Object
+type SetMetadataer interface {
+	// SetMetadata replaces the user metadata of the Object
+	SetMetadata(metadata map[string]string) error
+}
+
 // ObjectUnWrapper is an optional interface for Object
 type ObjectUnWrapper interface {
 	// UnWrap returns the Object that this Object is wrapping or
@@ -260,6 +329,23 @@ type ListRCallback func(entries DirEntries) error
 // ListRFn is defines the call used to recursively list a directory
 type ListRFn func(dir string, callback ListRCallback) error
 
+// ListOpt describes which Object metadata the caller of a listing is
+// going to use.
+//
+// Backends which implement ListP may defer reading any metadata which
+// isn't needed until it is asked for.
+type ListOpt struct {
+	Size    bool // Object.Size will be used
+	ModTime bool // Object.ModTime will be used
+}
+
+// ListOptAll says that all the Object metadata will be used
+var ListOptAll = ListOpt{Size: true, ModTime: true}
+
+// ListPFn is defines the call used to list a directory reading only
+// the metadata needed
+type ListPFn func(dir string, opt ListOpt) (entries DirEntries, err error)
+
 // Features describe the optional features of the Fs
 type Features struct {
 	// Feature flags, whether Fs
@@ -372,6 +458,39 @@ type Features struct {
 	// Don't implement this unless you have a more efficient way
 	// of listing recursively that doing a directory traversal.
 	ListR ListRFn
+
+	// ListP lists the objects and directories of the Fs in dir
+	// exactly like List, but the metadata not described by opt
+	// may be read lazily when it is first asked for.
+	//
+	// Don't implement this unless reading some of the metadata
+	// costs extra transactions.
+	ListP ListPFn
+
+	// ListPages lists the objects and directories of the Fs in
+	// dir exactly like List, but calls callback with each page
+	// of entries as it is read rather than returning them all at
+	// the end.
+	//
+	// Don't implement this unless the listing is read in parts
+	// which can be returned before the whole listing is complete.
+	ListPages func(dir string, callback ListRCallback) error
+
+	// UserInfo returns info about the connected user
+	UserInfo func() (map[string]string, error)
+
+	// Disconnect the current user by revoking the token
+	Disconnect func() error
+
+	// About gets quota information from the Fs
+	About func() (*Usage, error)
+
+	// OpenWriterAt opens with a handle for random access writes
+	//
+	// Pass in the remote desired and the size if known.
+	//
+	// It truncates any existing object
+	OpenWriterAt func(remote string, size int64) (WriterAtCloser, error)
 }
 
 // Disable nil's out the named feature.  If it isn't found then it
@@ -405,6 +524,23 @@ func (ft *Features) List() (out []string) {
 	return out
 }
 
+// Enabled returns a map of all the feature names to whether the
+// feature is set
+func (ft *Features) Enabled() (features map[string]bool) {
+	v := reflect.ValueOf(ft).Elem()
+	vType := v.Type()
+	features = make(map[string]bool, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Func {
+			features[vType.Field(i).Name] = !field.IsNil()
+		} else {
+			features[vType.Field(i).Name] = field.Bool()
+		}
+	}
+	return features
+}
+
 // DisableList nil's out the comma separated list of named features.
 // If it isn't found then it will log a message.
 func (ft *Features) DisableList(list []string) *Features {
@@ -417,6 +553,9 @@ func (ft *Features) DisableList(list []string) *Features {
 // Fill fills in the function pointers in the Features struct from the
 // optional interfaces.  It returns the original updated Features
 // struct passed in.
+//
+// Features disabled with --disable or the disable key of the remote's
+// config section are left unset.
 func (ft *Features) Fill(f Fs) *Features {
 	if do, ok := f.(Purger); ok {
 		ft.Purge = do.Purge
@@ -458,7 +597,25 @@ func (ft *Features) Fill(f Fs) *Features {
 	if do, ok := f.(ListRer); ok {
 		ft.ListR = do.ListR
 	}
-	return ft.DisableList(Config.DisableFeatures)
+	if do, ok := f.(ListPer); ok {
+		ft.ListP = do.ListP
+	}
+	if do, ok := f.(ListPager); ok {
+		ft.ListPages = do.ListPages
+	}
+	if do, ok := f.(UserInfoer); ok {
+		ft.UserInfo = do.UserInfo
+	}
+	if do, ok := f.(Disconnecter); ok {
+		ft.Disconnect = do.Disconnect
+	}
+	if do, ok := f.(Abouter); ok {
+		ft.About = do.About
+	}
+	if do, ok := f.(OpenWriterAter); ok {
+		ft.OpenWriterAt = do.OpenWriterAt
+	}
+	return ft.DisableList(ConfigForRemote(f.Name()).DisableFeatures)
 }
 
 // Mask the Features with the Fs passed in
@@ -468,6 +625,7 @@ func (ft *Features) Fill(f Fs) *Features {
 // aren't in both will be set to false/nil, except for UnWrap/Wrap which
 // will be left untouched.
 func (ft *Features) Mask(f Fs) *Features {
+	before := ft.Enabled()
 	mask := f.Features()
 	ft.CaseInsensitive = ft.CaseInsensitive && mask.CaseInsensitive
 	ft.DuplicateFiles = ft.DuplicateFiles && mask.DuplicateFiles
@@ -511,6 +669,34 @@ func (ft *Features) Mask(f Fs) *Features {
 	if mask.ListR == nil {
 		ft.ListR = nil
 	}
+	if mask.ListP == nil {
+		ft.ListP = nil
+	}
+	if mask.ListPages == nil {
+		ft.ListPages = nil
+	}
+	if mask.UserInfo == nil {
+		ft.UserInfo = nil
+	}
+	if mask.Disconnect == nil {
+		ft.Disconnect = nil
+	}
+	if mask.About == nil {
+		ft.About = nil
+	}
+	if mask.OpenWriterAt == nil {
+		ft.OpenWriterAt = nil
+	}
+	var masked []string
+	for name, enabled := range ft.Enabled() {
+		if before[name] && !enabled {
+			masked = append(masked, name)
+		}
+	}
+	if len(masked) > 0 {
+		sort.Strings(masked)
+		Debugf(f, "Disabling features not supported by this remote: %s", strings.Join(masked, ", "))
+	}
 	return ft.DisableList(Config.DisableFeatures)
 }
 
@@ -679,6 +865,81 @@ type ListRer interface {
 	ListR(dir string, callback ListRCallback) error
 }
 
+// ListPer is an optional interfaces for Fs
+type ListPer interface {
+	// ListP lists the objects and directories of the Fs in dir
+	// exactly like List, but the metadata not described by opt
+	// may be read lazily when it is first asked for.
+	//
+	// Don't implement this unless reading some of the metadata
+	// costs extra transactions.
+	ListP(dir string, opt ListOpt) (entries DirEntries, err error)
+}
+
+// ListPager is an optional interfaces for Fs
+type ListPager interface {
+	// ListPages lists the objects and directories of the Fs in
+	// dir exactly like List, but calls callback with each page
+	// of entries as it is read rather than returning them all at
+	// the end.
+	//
+	// Don't implement this unless the listing is read in parts
+	// which can be returned before the whole listing is complete.
+	ListPages(dir string, callback ListRCallback) error
+}
+
+// UserInfoer is an optional interface for Fs
+type UserInfoer interface {
+	// UserInfo returns info about the connected user
+	UserInfo() (map[string]string, error)
+}
+
+// Disconnecter is an optional interface for Fs
+type Disconnecter interface {
+	// Disconnect the current user by revoking the token
+	Disconnect() error
+}
+
+// Abouter is an optional interface for Fs
+type Abouter interface {
+	// About gets quota information from the Fs
+	About() (*Usage, error)
+}
+
+// OpenWriterAter is an optional interface for Fs
+type OpenWriterAter interface {
+	// OpenWriterAt opens with a handle for random access writes
+	//
+	// Pass in the remote desired and the size if known.
+	//
+	// It truncates any existing object
+	OpenWriterAt(remote string, size int64) (WriterAtCloser, error)
+}
+
+// WriterAtCloser wraps io.WriterAt and io.Closer
+type WriterAtCloser interface {
+	io.WriterAt
+	io.Closer
+}
+
+// NewUsageValue makes a valid value for a Usage field
+func NewUsageValue(value int64) *int64 {
+	p := new(int64)
+	*p = value
+	return p
+}
+
+// Usage is returned by the About call
+//
+// If a value is nil then it isn't supported by that backend
+type Usage struct {
+	Total   *int64 `json:"total,omitempty"`   // quota of bytes that can be used
+	Used    *int64 `json:"used,omitempty"`    // bytes in use
+	Trashed *int64 `json:"trashed,omitempty"` // bytes in trash
+	Other   *int64 `json:"other,omitempty"`   // other usage eg gmail in drive
+	Free    *int64 `json:"free,omitempty"`    // bytes which can be uploaded before reaching the quota
+}
+
 // RangeSeeker is the interface that wraps the RangeSeek method.
 //
 // Some of the returns from Object.Open() may optionally implement
@@ -732,17 +993,86 @@ func MustFind(name string) *RegInfo {
 	return fs
 }
 
+// ConnectionStringParam is a pattern to match one parameter of a
+// connection string, eg ",acl=private" or ",endpoint='a,b'"
+const ConnectionStringParam = `,[\w-]+=(?:"[^"]*"|'[^']*'|[^,:"']*)`
+
 // Matcher is a pattern to match an rclone URL
-var Matcher = regexp.MustCompile(`^([\w_ -]+):(.*)$`)
+var Matcher = regexp.MustCompile(`^([\w_ -]+)((?:` + ConnectionStringParam + `)*):(.*)$`)
+
+// quotedMatcher is a pattern to match an rclone URL with the remote
+// name in quotes, eg "my:remote":path or 'my "remote"':path
+var quotedMatcher = regexp.MustCompile(`^(?:"([^"]+)"|'([^']+)')((?:` + ConnectionStringParam + `)*):(.*)$`)
+
+// SplitRemote splits path into the name of the remote and the path
+// on it.  configName is returned as "" if path is a local path.
+//
+// The remote name may be put in double or single quotes so that it
+// can contain characters which aren't allowed in an unquoted name,
+// such as ":".  A quoted name is never taken as a drive letter so
+// "C":path refers to the remote called C even on Windows.
+//
+// The name may be followed by connection string parameters which
+// override the remote's config, eg remote,acl=private:path.  These
+// are returned as part of configName and are interpreted by the
+// config package.
+func SplitRemote(path string) (configName, fsPath string) {
+	if parts := quotedMatcher.FindStringSubmatch(path); parts != nil {
+		return parts[1] + parts[2] + parts[3], parts[4]
+	}
+	parts := Matcher.FindStringSubmatch(path)
+	if parts == nil || (parts[2] == "" && driveletter.IsDriveLetter(parts[1])) {
+		return "", path
+	}
+	return parts[1] + parts[2], parts[3]
+}
+
+// QuoteRemoteName returns name quoted if necessary so that it can be
+// used as the remote part of a remote:path.
+func QuoteRemoteName(name string) string {
+	if configName, _ := SplitRemote(name + ":"); configName == name {
+		return name
+	}
+	if strings.ContainsRune(name, '"') {
+		return "'" + name + "'"
+	}
+	return `"` + name + `"`
+}
+
+// CheckConfigName returns an error if name can't be used as the name
+// of a remote in the config file.
+//
+// Names containing characters other than letters, numbers, "_", "-"
+// and " " are allowed as long as they can be quoted, but they must
+// then be quoted when used, eg "my:remote":path.
+func CheckConfigName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("can't use empty name")
+	case driveletter.IsDriveLetter(name):
+		return errors.Errorf("can't use %q as it can be confused with a drive letter", name)
+	case strings.TrimSpace(name) != name:
+		return errors.Errorf("can't use %q as it starts or ends with a space", name)
+	case strings.ContainsAny(name, "[]"):
+		return errors.Errorf("can't use %q as it contains \"[\" or \"]\"", name)
+	case strings.ContainsRune(name, '"') && strings.ContainsRune(name, '\''):
+		return errors.Errorf("can't use %q as it contains both ' and \" so can't be quoted", name)
+	}
+	for _, c := range name {
+		if c < ' ' || c == 0x7F {
+			return errors.Errorf("can't use %q as it contains control characters", name)
+		}
+	}
+	return nil
+}
 
 // ParseRemote deconstructs a path into configName, fsPath, looking up
 // the fsName in the config file (returning NotFoundInConfigFile if not found)
 func ParseRemote(path string) (fsInfo *RegInfo, configName, fsPath string, err error) {
-	parts := Matcher.FindStringSubmatch(path)
 	var fsName string
 	fsName, configName, fsPath = "local", "local", path
-	if parts != nil && !driveletter.IsDriveLetter(parts[1]) {
-		configName, fsPath = parts[1], parts[2]
+	if name, remotePath := SplitRemote(path); name != "" {
+		configName, fsPath = name, remotePath
 		fsName = ConfigFileGet(configName, "type")
 		if fsName == "" {
 			return nil, "", "", ErrorNotFoundInConfigFile
@@ -762,15 +1092,55 @@ func ParseRemote(path string) (fsInfo *RegInfo, configName, fsPath string, err e
 // found then NotFoundInConfigFile will be returned.
 //
 // On Windows avoid single character remote names as they can be mixed
-// up with drive letters.
+// up with drive letters, or quote them, eg "C":path.
 func NewFs(path string) (Fs, error) {
 	fsInfo, configName, fsPath, err := ParseRemote(path)
 	if err != nil {
 		return nil, err
 	}
+	err = checkChain(configName)
+	if err != nil {
+		return nil, err
+	}
 	return fsInfo.NewFs(configName, fsPath)
 }
 
+// checkChain follows the chain of remotes wrapped by configName
+// through their "remote" config setting (as used by crypt, cache and
+// alias) and returns an error if the chain loops back on itself, wraps
+// a remote which doesn't exist, or contains more than one cache.
+//
+// This stops misconfigured remotes recursing until they run out of
+// stack.
+func checkChain(configName string) error {
+	chain := []string{configName}
+	seen := map[string]bool{configName: true}
+	caches := 0
+	for name := configName; ; {
+		if ConfigFileGet(name, "type") == "cache" {
+			caches++
+			if caches > 1 {
+				return errors.Errorf("invalid config chain %s - a cache remote can't wrap another cache remote, remove one of them", strings.Join(chain, " -> "))
+			}
+		}
+		remote := ConfigFileGet(name, "remote")
+		next, _ := SplitRemote(remote)
+		if next == "" {
+			// not wrapping anything or wrapping a local path
+			return nil
+		}
+		chain = append(chain, next)
+		if seen[next] {
+			return errors.Errorf("config loop %s - change the remote setting of %q so it doesn't point back at itself", strings.Join(chain, " -> "), name)
+		}
+		if ConfigFileGet(next, "type") == "" {
+			return errors.Errorf("remote setting %q of %q refers to %q which isn't in the config file", remote, name, next)
+		}
+		seen[next] = true
+		name = next
+	}
+}
+
 // TemporaryLocalFs creates a local FS in the OS's temporary directory.
 //
 // No cleanup is performed, the caller must call Purge on the Fs themselves.
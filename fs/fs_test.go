@@ -33,6 +33,21 @@ func TestFeaturesList(t *testing.T) {
 	assert.True(t, strings.Contains(names, ",Copy,"))
 }
 
+func TestFeaturesEnabled(t *testing.T) {
+	ft := new(Features)
+	ft.Copy = func(src Object, remote string) (Object, error) {
+		return nil, nil
+	}
+	ft.CaseInsensitive = true
+
+	features := ft.Enabled()
+	assert.Equal(t, len(ft.List()), len(features))
+	assert.True(t, features["Copy"])
+	assert.True(t, features["CaseInsensitive"])
+	assert.False(t, features["Purge"])
+	assert.False(t, features["DuplicateFiles"])
+}
+
 func TestFeaturesDisableList(t *testing.T) {
 	ft := new(Features)
 	ft.Copy = func(src Object, remote string) (Object, error) {
@@ -52,3 +67,120 @@ func TestFeaturesDisableList(t *testing.T) {
 	assert.False(t, ft.CaseInsensitive)
 	assert.False(t, ft.DuplicateFiles)
 }
+
+func TestCheckChain(t *testing.T) {
+	oldConfigFileGet := ConfigFileGet
+	defer func() { ConfigFileGet = oldConfigFileGet }()
+	config := map[string]map[string]string{
+		"local":   {},
+		"s3":      {"type": "s3"},
+		"crypt":   {"type": "crypt", "remote": "s3:bucket"},
+		"cache":   {"type": "cache", "remote": "crypt:"},
+		"cache2":  {"type": "cache", "remote": "crypt2:"},
+		"crypt2":  {"type": "crypt", "remote": "cache:dir"},
+		"self":    {"type": "crypt", "remote": "self:path"},
+		"loop1":   {"type": "alias", "remote": "loop2:"},
+		"loop2":   {"type": "crypt", "remote": "loop1:"},
+		"path":    {"type": "alias", "remote": "/path/to/dir"},
+		"missing": {"type": "crypt", "remote": "potato:path"},
+		"quoted":  {"type": "crypt", "remote": `"my:s3":bucket`},
+		"my:s3":   {"type": "s3"},
+	}
+	ConfigFileGet = func(section, key string, defaultVal ...string) string {
+		return config[section][key]
+	}
+	for _, test := range []struct {
+		name string
+		err  string
+	}{
+		{"local", ""},
+		{"s3", ""},
+		{"crypt", ""},
+		{"cache", ""},
+		{"crypt2", ""},
+		{"path", ""},
+		{"quoted", ""},
+		{"cache2", "invalid config chain cache2 -> crypt2 -> cache - a cache remote can't wrap another cache remote, remove one of them"},
+		{"self", `config loop self -> self - change the remote setting of "self" so it doesn't point back at itself`},
+		{"loop1", `config loop loop1 -> loop2 -> loop1 - change the remote setting of "loop2" so it doesn't point back at itself`},
+		{"missing", `remote setting "potato:path" of "missing" refers to "potato" which isn't in the config file`},
+	} {
+		err := checkChain(test.name)
+		if test.err == "" {
+			assert.NoError(t, err, test.name)
+		} else {
+			assert.EqualError(t, err, test.err, test.name)
+		}
+	}
+}
+
+func TestSplitRemote(t *testing.T) {
+	for _, test := range []struct {
+		path       string
+		configName string
+		fsPath     string
+	}{
+		{"remote:path", "remote", "path"},
+		{"my remote:path/to:file", "my remote", "path/to:file"},
+		{"remote:", "remote", ""},
+		{"/local/path", "", "/local/path"},
+		{"./sync:me", "", "./sync:me"},
+		{"sync:me/dir", "sync", "me/dir"},
+		{`"my:remote":path`, "my:remote", "path"},
+		{`'my "remote"':path`, `my "remote"`, "path"},
+		{`"remote":`, "remote", ""},
+		{`"":path`, "", `"":path`},
+		{`"unterminated:path`, "", `"unterminated:path`},
+		{"remote,acl=private:path", "remote,acl=private", "path"},
+		{`remote,a='x,y',b="c:d",c=:path`, `remote,a='x,y',b="c:d",c=`, "path"},
+		{`"my:remote",acl=private:path`, "my:remote,acl=private", "path"},
+		{"remote,acl:path", "", "remote,acl:path"},
+	} {
+		configName, fsPath := SplitRemote(test.path)
+		assert.Equal(t, test.configName, configName, test.path)
+		assert.Equal(t, test.fsPath, fsPath, test.path)
+	}
+}
+
+func TestQuoteRemoteName(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		want string
+	}{
+		{"remote", "remote"},
+		{"my remote", "my remote"},
+		{"my:remote", `"my:remote"`},
+		{`my "remote"`, `'my "remote"'`},
+	} {
+		got := QuoteRemoteName(test.name)
+		assert.Equal(t, test.want, got, test.name)
+		configName, fsPath := SplitRemote(got + ":path")
+		assert.Equal(t, test.name, configName, test.name)
+		assert.Equal(t, "path", fsPath, test.name)
+	}
+}
+
+func TestCheckConfigName(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		err  string
+	}{
+		{"remote", ""},
+		{"my remote", ""},
+		{"my:remote", ""},
+		{`my "remote"`, ""},
+		{"", "can't use empty name"},
+		{" remote", `can't use " remote" as it starts or ends with a space`},
+		{"remote ", `can't use "remote " as it starts or ends with a space`},
+		{"[remote]", `can't use "[remote]" as it contains "[" or "]"`},
+		{`"it's"`, `can't use "\"it's\"" as it contains both ' and " so can't be quoted`},
+		{"re\tmote", `can't use "re\tmote" as it contains control characters`},
+	} {
+		err := CheckConfigName(test.name)
+		if test.err == "" {
+			assert.NoError(t, err, test.name)
+		} else {
+			assert.EqualError(t, err, test.err, test.name)
+		}
+	}
+}
@@ -22,7 +22,7 @@ const (
 )
 
 var (
-	transport   http.RoundTripper
+	transport   *http.Transport
 	noTransport sync.Once
 	tpsBucket   *rate.Limiter // for limiting number of http transactions per second
 )
@@ -109,6 +109,10 @@ func setDefaults(a, b interface{}) {
 }
 
 // NewTransport returns an http.RoundTripper with the correct timeouts
+//
+// The underlying http.Transport is shared between all callers so
+// connections are pooled, but each caller gets its own wrapper so
+// per remote settings such as the User-Agent can differ.
 func NewTransport(ci *fs.ConfigInfo) http.RoundTripper {
 	noTransport.Do(func() {
 		// Start with a sensible set of defaults then override.
@@ -128,10 +132,10 @@ func NewTransport(ci *fs.ConfigInfo) http.RoundTripper {
 		//   t.IdelConnTimeout
 		//   t.ExpectContinueTimeout
 		initTransport(ci, t)
-		// Wrap that http.Transport in our own transport
-		transport = newTransport(ci, t)
+		transport = t
 	})
-	return transport
+	// Wrap that http.Transport in our own transport
+	return newTransport(ci, transport)
 }
 
 // NewClient returns an http.Client with the correct timeouts
@@ -292,6 +296,30 @@ func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error
 	return resp, err
 }
 
+// Network returns the network to dial for the network passed in,
+// restricting it to IPv4 or IPv6 if required by the --ipv4, --ipv6 or
+// --bind flags.
+func Network(ci *fs.ConfigInfo, network string) string {
+	switch network {
+	case "tcp", "udp", "ip":
+	default:
+		return network
+	}
+	switch {
+	case ci.IPv4Only:
+		return network + "4"
+	case ci.IPv6Only:
+		return network + "6"
+	case ci.BindAddr != nil:
+		// A local address can only be used to dial its own family
+		if ci.BindAddr.To4() != nil {
+			return network + "4"
+		}
+		return network + "6"
+	}
+	return network
+}
+
 // NewDialer creates a net.Dialer structure with Timeout, Keepalive
 // and LocalAddr set from rclone flags.
 func NewDialer(ci *fs.ConfigInfo) *net.Dialer {
@@ -16,7 +16,7 @@ import (
 // dial with context and timeouts
 func dialContextTimeout(ctx context.Context, network, address string, ci *fs.ConfigInfo) (net.Conn, error) {
 	dialer := NewDialer(ci)
-	c, err := dialer.DialContext(ctx, network, address)
+	c, err := dialer.DialContext(ctx, Network(ci, network), address)
 	if err != nil {
 		return c, err
 	}
@@ -14,7 +14,7 @@ import (
 // dial with timeouts
 func dialTimeout(network, address string, ci *fs.ConfigInfo) (net.Conn, error) {
 	dialer := NewDialer(ci)
-	c, err := dialer.Dial(network, address)
+	c, err := dialer.Dial(Network(ci, network), address)
 	if err != nil {
 		return c, err
 	}
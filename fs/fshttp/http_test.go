@@ -4,9 +4,11 @@ package fshttp
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"testing"
 
+	"github.com/ncw/rclone/fs"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -78,3 +80,30 @@ func TestCleanAuths(t *testing.T) {
 		assert.Equal(t, test.want, got, test.in)
 	}
 }
+
+func TestNetwork(t *testing.T) {
+	for _, test := range []struct {
+		ipv4Only bool
+		ipv6Only bool
+		bindAddr string
+		network  string
+		want     string
+	}{
+		{false, false, "", "tcp", "tcp"},
+		{true, false, "", "tcp", "tcp4"},
+		{false, true, "", "tcp", "tcp6"},
+		{false, false, "1.2.3.4", "tcp", "tcp4"},
+		{false, false, "1234::789a", "tcp", "tcp6"},
+		{true, false, "", "tcp6", "tcp6"},
+		{true, false, "", "unix", "unix"},
+	} {
+		ci := fs.NewConfig()
+		ci.IPv4Only = test.ipv4Only
+		ci.IPv6Only = test.ipv6Only
+		if test.bindAddr != "" {
+			ci.BindAddr = net.ParseIP(test.bindAddr)
+		}
+		got := Network(ci, test.network)
+		assert.Equal(t, test.want, got, fmt.Sprintf("%+v", test))
+	}
+}
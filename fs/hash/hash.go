@@ -3,9 +3,11 @@ package hash
 import (
 	"crypto/md5"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"hash"
+	"hash/crc32"
 	"io"
 	"strings"
 
@@ -31,21 +33,33 @@ const (
 	// https://www.dropbox.com/developers/reference/content-hash
 	Dropbox
 
+	// SHA256 indicates SHA-256 support
+	SHA256
+
+	// CRC32C indicates CRC-32 with the Castagnoli polynomial as used
+	// by iSCSI and Google Cloud Storage
+	CRC32C
+
 	// None indicates no hashes are supported
 	None Type = 0
 )
 
 // Supported returns a set of all the supported hashes by
 // HashStream and MultiHasher.
-var Supported = NewHashSet(MD5, SHA1, Dropbox)
+var Supported = NewHashSet(MD5, SHA1, Dropbox, SHA256, CRC32C)
 
 // Width returns the width in characters for any HashType
 var Width = map[Type]int{
 	MD5:     32,
 	SHA1:    40,
 	Dropbox: 64,
+	SHA256:  64,
+	CRC32C:  8,
 }
 
+// castagnoliTable is the table for calculating CRC32C
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
 // Stream will calculate hashes of all supported hash types.
 func Stream(r io.Reader) (map[Type]string, error) {
 	return StreamTypes(r, Supported)
@@ -81,6 +95,10 @@ func (h Type) String() string {
 		return "SHA-1"
 	case Dropbox:
 		return "DropboxHash"
+	case SHA256:
+		return "SHA-256"
+	case CRC32C:
+		return "CRC32C"
 	default:
 		err := fmt.Sprintf("internal error: unknown hash type: 0x%x", int(h))
 		panic(err)
@@ -98,6 +116,10 @@ func (h *Type) Set(s string) error {
 		*h = SHA1
 	case "DropboxHash":
 		*h = Dropbox
+	case "SHA-256":
+		*h = SHA256
+	case "CRC32C":
+		*h = CRC32C
 	default:
 		return errors.Errorf("Unknown hash type %q", s)
 	}
@@ -126,6 +148,10 @@ func fromTypes(set Set) (map[Type]hash.Hash, error) {
 			hashers[t] = sha1.New()
 		case Dropbox:
 			hashers[t] = dbhash.New()
+		case SHA256:
+			hashers[t] = sha256.New()
+		case CRC32C:
+			hashers[t] = crc32.New(castagnoliTable)
 		default:
 			err := fmt.Sprintf("internal error: Unsupported hash type %v", t)
 			panic(err)
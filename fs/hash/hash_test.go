@@ -72,6 +72,8 @@ var hashTestSet = []hashTest{
 			hash.MD5:     "bf13fc19e5151ac57d4252e0e0f87abe",
 			hash.SHA1:    "3ab6543c08a75f292a5ecedac87ec41642d12166",
 			hash.Dropbox: "214d2fcf3566e94c99ad2f59bd993daca46d8521a0c447adf4b324f53fddc0c7",
+			hash.SHA256:  "c839e57675862af5c21bd0a15413c3ec579e0d5522dab600bc6c3489b05b8f54",
+			hash.CRC32C:  "4d8ae017",
 		},
 	},
 	// Empty data set
@@ -81,6 +83,8 @@ var hashTestSet = []hashTest{
 			hash.MD5:     "d41d8cd98f00b204e9800998ecf8427e",
 			hash.SHA1:    "da39a3ee5e6b4b0d3255bfef95601890afd80709",
 			hash.Dropbox: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			hash.SHA256:  "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			hash.CRC32C:  "00000000",
 		},
 	},
 }
@@ -155,6 +159,8 @@ func TestHashSetStringer(t *testing.T) {
 	assert.Equal(t, h.String(), "[MD5, SHA-1, DropboxHash]")
 	h = hash.NewHashSet(hash.SHA1)
 	assert.Equal(t, h.String(), "[SHA-1]")
+	h = hash.NewHashSet(hash.CRC32C, hash.SHA256)
+	assert.Equal(t, h.String(), "[SHA-256, CRC32C]")
 	h = hash.NewHashSet()
 	assert.Equal(t, h.String(), "[]")
 }
@@ -165,3 +171,13 @@ func TestHashStringer(t *testing.T) {
 	h = hash.None
 	assert.Equal(t, h.String(), "None")
 }
+
+func TestHashSetFromString(t *testing.T) {
+	for _, want := range []hash.Type{hash.None, hash.MD5, hash.SHA1, hash.Dropbox, hash.SHA256, hash.CRC32C} {
+		var h hash.Type
+		require.NoError(t, h.Set(want.String()))
+		assert.Equal(t, want, h)
+	}
+	var h hash.Type
+	assert.Error(t, h.Set("potato"))
+}
@@ -20,7 +20,11 @@ import (
 // Files will be returned in sorted order
 func DirSorted(f fs.Fs, includeAll bool, dir string) (entries fs.DirEntries, err error) {
 	// Get unfiltered entries from the fs
-	entries, err = f.List(dir)
+	if listP := f.Features().ListP; listP != nil {
+		entries, err = listP(dir, Needed())
+	} else {
+		entries, err = f.List(dir)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -31,12 +35,76 @@ func DirSorted(f fs.Fs, includeAll bool, dir string) (entries fs.DirEntries, err
 		fs.Debugf(dir, "Excluded from sync (and deletion)")
 		return nil, nil
 	}
-	return filterAndSortDir(entries, includeAll, dir, filter.Active.IncludeObject, filter.Active.IncludeDirectory(f))
+	return filterAndSortDir(entries, includeAll, dir, filter.Active.IncludeObjectReason, filter.Active.IncludeDirectory(f))
+}
+
+// DirPages reads Object and *Dir into pages of entries for the given
+// Fs calling callback with each one.
+//
+// dir is the start directory, "" for root
+//
+// If includeAll is specified all files will be added, otherwise only
+// files and directories passing the filter will be added.
+//
+// If the Fs can list in pages then callback is called with each page
+// as it is read, otherwise it is called once with the whole listing.
+// Each page is sorted but the pages may come in any order.
+func DirPages(f fs.Fs, includeAll bool, dir string, callback fs.ListRCallback) error {
+	listPages := f.Features().ListPages
+	// The exclude file could be in any page so the listing has
+	// to be complete to check for it
+	if listPages == nil || (!includeAll && filter.Active.Opt.ExcludeFile != "") {
+		entries, err := DirSorted(f, includeAll, dir)
+		if err != nil {
+			return err
+		}
+		return callback(entries)
+	}
+	return listPages(dir, func(entries fs.DirEntries) error {
+		entries, err := filterAndSortDir(entries, includeAll, dir, filter.Active.IncludeObjectReason, filter.Active.IncludeDirectory(f))
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		return callback(entries)
+	})
+}
+
+// Needed returns the Object metadata which comparing the entries of
+// a listing will use with the current config and filters.
+//
+// Metadata used only when an Object is transferred isn't included as
+// that can be read when it is needed.
+func Needed() (opt fs.ListOpt) {
+	switch {
+	case fs.Config.IgnoreTimes || fs.Config.IgnoreExisting:
+		// Nothing is compared
+	case fs.Config.SizeOnly || fs.Config.CheckSum:
+		opt.Size = !fs.Config.IgnoreSize
+	default:
+		opt.Size = !fs.Config.IgnoreSize
+		opt.ModTime = true
+	}
+	if fs.Config.TrackRenames {
+		opt.Size = true
+	}
+	if fs.Config.UpdateOlder {
+		opt.ModTime = true
+	}
+	if filter.Active.Opt.MinSize >= 0 || filter.Active.Opt.MaxSize >= 0 {
+		opt.Size = true
+	}
+	if !filter.Active.ModTimeFrom.IsZero() || !filter.Active.ModTimeTo.IsZero() {
+		opt.ModTime = true
+	}
+	return opt
 }
 
 // filter (if required) and check the entries, then sort them
 func filterAndSortDir(entries fs.DirEntries, includeAll bool, dir string,
-	IncludeObject func(o fs.Object) bool,
+	IncludeObject func(o fs.Object) (bool, string),
 	IncludeDirectory func(remote string) (bool, error)) (newEntries fs.DirEntries, err error) {
 	newEntries = entries[:0] // in place filter
 	prefix := ""
@@ -49,9 +117,11 @@ func filterAndSortDir(entries fs.DirEntries, includeAll bool, dir string,
 		switch x := entry.(type) {
 		case fs.Object:
 			// Make sure we don't delete excluded files if not required
-			if !includeAll && !IncludeObject(x) {
-				ok = false
-				fs.Debugf(x, "Excluded from sync (and deletion)")
+			if !includeAll {
+				if include, reason := IncludeObject(x); !include {
+					ok = false
+					fs.Debugf(x, "Excluded from sync (and deletion): %s", reason)
+				}
 			}
 		case fs.Directory:
 			if !includeAll {
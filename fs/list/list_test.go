@@ -24,8 +24,8 @@ func TestFilterAndSortIncludeAll(t *testing.T) {
 	dd := mockdir.New("d")
 	oD := mockobject.Object("D")
 	entries := fs.DirEntries{da, oA, db, oB, dc, oC, dd, oD}
-	includeObject := func(o fs.Object) bool {
-		return o != oB
+	includeObject := func(o fs.Object) (bool, string) {
+		return o != oB, "is B"
 	}
 	includeDirectory := func(remote string) (bool, error) {
 		return remote != "c", nil
@@ -102,3 +102,34 @@ func TestFilterAndSortUnknown(t *testing.T) {
 	assert.Error(t, err, "error")
 	assert.Nil(t, newEntries)
 }
+
+func TestNeeded(t *testing.T) {
+	oldConfig := *fs.Config
+	defer func() {
+		*fs.Config = oldConfig
+	}()
+	for _, test := range []struct {
+		name   string
+		set    func(ci *fs.ConfigInfo)
+		expect fs.ListOpt
+	}{
+		{"default", func(ci *fs.ConfigInfo) {}, fs.ListOptAll},
+		{"size-only", func(ci *fs.ConfigInfo) { ci.SizeOnly = true }, fs.ListOpt{Size: true}},
+		{"checksum", func(ci *fs.ConfigInfo) { ci.CheckSum = true }, fs.ListOpt{Size: true}},
+		{"ignore-size", func(ci *fs.ConfigInfo) { ci.IgnoreSize = true }, fs.ListOpt{ModTime: true}},
+		{"ignore-times", func(ci *fs.ConfigInfo) { ci.IgnoreTimes = true }, fs.ListOpt{}},
+		{"ignore-existing", func(ci *fs.ConfigInfo) { ci.IgnoreExisting = true }, fs.ListOpt{}},
+		{"ignore-existing track-renames", func(ci *fs.ConfigInfo) {
+			ci.IgnoreExisting = true
+			ci.TrackRenames = true
+		}, fs.ListOpt{Size: true}},
+		{"size-only update", func(ci *fs.ConfigInfo) {
+			ci.SizeOnly = true
+			ci.UpdateOlder = true
+		}, fs.ListOptAll},
+	} {
+		*fs.Config = oldConfig
+		test.set(fs.Config)
+		assert.Equal(t, test.expect, Needed(), test.name)
+	}
+}
@@ -3,7 +3,6 @@ package log
 
 import (
 	"log"
-	"os"
 	"reflect"
 	"runtime"
 	"strings"
@@ -17,8 +16,13 @@ var (
 	logFile        = flags.StringP("log-file", "", "", "Log everything to this file")
 	useSyslog      = flags.BoolP("syslog", "", false, "Use Syslog for logging")
 	syslogFacility = flags.StringP("syslog-facility", "", "DAEMON", "Facility for syslog, eg KERN,USER,...")
+	logFileMaxSize = fs.SizeSuffix(-1)
 )
 
+func init() {
+	flags.VarP(&logFileMaxSize, "log-file-max-size", "", "Rotate the --log-file to FILE.1 when it reaches this size.")
+}
+
 // fnName returns the name of the calling +2 function
 func fnName() string {
 	pc, _, _, ok := runtime.Caller(2)
@@ -63,20 +67,28 @@ func Trace(o interface{}, format string, a ...interface{}) func(string, ...inter
 	}
 }
 
+// LogFile returns the path of the --log-file or "" if not in use
+func LogFile() string {
+	return *logFile
+}
+
+// Redirected returns true if the log output is going somewhere other
+// than stderr
+func Redirected() bool {
+	return *logFile != "" || *useSyslog
+}
+
 // InitLogging start the logging as per the command line flags
 func InitLogging() {
 	// Log file output
 	if *logFile != "" {
-		f, err := os.OpenFile(*logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+		w, err := newLogFileWriter(*logFile, int64(logFileMaxSize), true)
 		if err != nil {
 			log.Fatalf("Failed to open log file: %v", err)
 		}
-		_, err = f.Seek(0, os.SEEK_END)
-		if err != nil {
-			fs.Errorf(nil, "Failed to seek log file to end: %v", err)
-		}
-		log.SetOutput(f)
-		redirectStderr(f)
+		logFileOut = w
+		log.SetOutput(w)
+		startReopenSignalHandler()
 	}
 
 	// Syslog output
@@ -0,0 +1,129 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// logFileWriter writes to the --log-file.  It can reopen the file so
+// it works with external log rotation, and can rotate the file itself
+// when it gets too big.
+type logFileWriter struct {
+	mu      sync.Mutex
+	path    string   // path of the log file
+	maxSize int64    // rotate the log when it exceeds this, < 0 for off
+	stderr  bool     // if set point stderr at the log file too
+	f       *os.File // currently open file
+	size    int64    // size of the currently open file
+}
+
+// the log file in use or nil
+var logFileOut *logFileWriter
+
+// newLogFileWriter opens the log file at path, pointing stderr at it
+// if stderr is set
+func newLogFileWriter(path string, maxSize int64, stderr bool) (*logFileWriter, error) {
+	w := &logFileWriter{
+		path:    path,
+		maxSize: maxSize,
+		stderr:  stderr,
+	}
+	err := w.open()
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// open the log file for append and point stderr at it
+//
+// call with the lock held.  As the log is written through w, errors
+// must be written to the file directly rather than logged.
+func (w *logFileWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+	if err != nil {
+		return errors.Wrap(err, "failed to open log file")
+	}
+	size, err := f.Seek(0, os.SEEK_END)
+	if err != nil {
+		_, _ = fmt.Fprintf(f, "Failed to seek log file to end: %v\n", err)
+	}
+	if w.stderr {
+		redirectStderr(f)
+	}
+	if w.f != nil {
+		_ = w.f.Close()
+	}
+	w.f = f
+	w.size = size
+	return nil
+}
+
+// rotate renames the log file to path.1 and opens a new one
+//
+// The old file is renamed before the new one is opened so if rclone
+// crashes part way through the output is either in the old or the
+// new file.
+//
+// call with the lock held
+func (w *logFileWriter) rotate() error {
+	if runtime.GOOS == "windows" {
+		// Windows can't rename open files
+		_ = w.f.Close()
+		w.f = nil
+	}
+	err := os.Rename(w.path, w.path+".1")
+	if err != nil {
+		if w.f == nil {
+			if openErr := w.open(); openErr != nil {
+				return openErr
+			}
+		}
+		return errors.Wrap(err, "failed to rotate log file")
+	}
+	return w.open()
+}
+
+// Write p to the log file rotating it first if necessary
+func (w *logFileWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.maxSize >= 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		err = w.rotate()
+		if err != nil {
+			// carry on writing to the old file
+			_, _ = w.f.Write([]byte(err.Error() + "\n"))
+		}
+	}
+	n, err = w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Reopen closes and reopens the log file
+func (w *logFileWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.open()
+}
+
+// ReopenLogFile closes and reopens the --log-file if in use.
+//
+// Use this after the log file has been moved by an external log
+// rotation program so rclone stops writing to the old file.
+func ReopenLogFile() error {
+	if logFileOut == nil {
+		return nil
+	}
+	err := logFileOut.Reopen()
+	if err != nil {
+		return err
+	}
+	fs.Infof(nil, "Reopened log file %q", logFileOut.path)
+	return nil
+}
@@ -0,0 +1,8 @@
+// Reopen the log file on SIGHUP - for oses which don't have it
+
+// +build windows plan9 js
+
+package log
+
+// startReopenSignalHandler does nothing on this OS
+func startReopenSignalHandler() {}
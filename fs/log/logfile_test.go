@@ -0,0 +1,131 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestLogFile makes a logFileWriter in a temporary directory
+func newTestLogFile(t *testing.T, maxSize int64) (w *logFileWriter, logPath string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "rclone-logfile")
+	require.NoError(t, err)
+	logPath = filepath.Join(dir, "rclone.log")
+	w, err = newLogFileWriter(logPath, maxSize, false)
+	require.NoError(t, err)
+	return w, logPath, func() {
+		_ = w.f.Close()
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+// readFile returns the contents of path as a string
+func readFile(t *testing.T, path string) string {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestLogFileWrite(t *testing.T) {
+	w, logPath, cleanup := newTestLogFile(t, -1)
+	defer cleanup()
+
+	for i := 0; i < 10; i++ {
+		n, err := w.Write([]byte("0123456789\n"))
+		require.NoError(t, err)
+		assert.Equal(t, 11, n)
+	}
+	assert.Equal(t, int64(110), w.size)
+	assert.Equal(t, 110, len(readFile(t, logPath)))
+	_, err := os.Stat(logPath + ".1")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLogFileAppends(t *testing.T) {
+	w, logPath, cleanup := newTestLogFile(t, -1)
+	defer cleanup()
+	_, err := w.Write([]byte("one\n"))
+	require.NoError(t, err)
+
+	// A new writer carries on at the end of the file
+	w2, err := newLogFileWriter(logPath, -1, false)
+	require.NoError(t, err)
+	defer func() { _ = w2.f.Close() }()
+	assert.Equal(t, int64(4), w2.size)
+	_, err = w2.Write([]byte("two\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "one\ntwo\n", readFile(t, logPath))
+}
+
+func TestLogFileRotate(t *testing.T) {
+	w, logPath, cleanup := newTestLogFile(t, 10)
+	defer cleanup()
+
+	_, err := w.Write([]byte("first\n"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("second\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "first\n", readFile(t, logPath+".1"))
+	assert.Equal(t, "second\n", readFile(t, logPath))
+
+	// Rotating again replaces the old file
+	_, err = w.Write([]byte("third\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "second\n", readFile(t, logPath+".1"))
+	assert.Equal(t, "third\n", readFile(t, logPath))
+
+	// A write bigger than the limit to an empty file isn't rotated
+	w2, logPath2, cleanup2 := newTestLogFile(t, 10)
+	defer cleanup2()
+	_, err = w2.Write([]byte("a long line of output\n"))
+	require.NoError(t, err)
+	_, err = os.Stat(logPath2 + ".1")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLogFileReopen(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("can't rename open files on Windows")
+	}
+	w, logPath, cleanup := newTestLogFile(t, -1)
+	defer cleanup()
+
+	_, err := w.Write([]byte("before\n"))
+	require.NoError(t, err)
+
+	// Move the file away like logrotate does - the writer carries
+	// on writing to it until it is reopened
+	require.NoError(t, os.Rename(logPath, logPath+".old"))
+	_, err = w.Write([]byte("moved\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Reopen())
+	_, err = w.Write([]byte("after\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "before\nmoved\n", readFile(t, logPath+".old"))
+	assert.Equal(t, "after\n", readFile(t, logPath))
+	assert.Equal(t, int64(6), w.size)
+}
+
+func TestReopenLogFile(t *testing.T) {
+	oldLogFileOut := logFileOut
+	defer func() { logFileOut = oldLogFileOut }()
+
+	logFileOut = nil
+	assert.NoError(t, ReopenLogFile())
+
+	w, logPath, cleanup := newTestLogFile(t, -1)
+	defer cleanup()
+	logFileOut = w
+	_, err := w.Write([]byte("before\n"))
+	require.NoError(t, err)
+	require.NoError(t, ReopenLogFile())
+	_, err = w.Write([]byte("after\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "before\nafter\n", readFile(t, logPath))
+}
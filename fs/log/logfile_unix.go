@@ -0,0 +1,28 @@
+// Reopen the log file on SIGHUP under unix
+
+// +build !windows,!plan9,!js
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ncw/rclone/fs"
+)
+
+// startReopenSignalHandler reopens the log file when SIGHUP is
+// received so it can be used with logrotate
+func startReopenSignalHandler() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			err := ReopenLogFile()
+			if err != nil {
+				fs.Errorf(nil, "Failed to reopen log file on SIGHUP: %v", err)
+			}
+		}
+	}()
+}
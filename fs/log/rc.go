@@ -0,0 +1,61 @@
+// Define the rc functions for controlling logging
+
+package log
+
+import (
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "core/reopen-log",
+		Fn:    rcReopenLog,
+		Title: "Reopen the log file",
+		Help: `
+This closes and reopens the --log-file.  Use it after an external log
+rotation program has moved the log file.
+
+Unlike sending SIGHUP to a mount this doesn't clear the directory
+cache of the mount as well.`,
+	})
+	rc.Add(rc.Call{
+		Path:  "core/log-level",
+		Fn:    rcLogLevel,
+		Title: "Read or set the log level",
+		Help: `
+This sets the log level of the running rclone if the level parameter
+is supplied.  It should be one of DEBUG, INFO, NOTICE or ERROR.
+
+It returns the log level in use as level.`,
+	})
+}
+
+// Reopen the log file
+func rcReopenLog(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	if logFileOut == nil {
+		return nil, errors.New("not logging to a file")
+	}
+	return nil, ReopenLogFile()
+}
+
+// Read or set the log level
+func rcLogLevel(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	if _, ok := in["level"]; ok {
+		level, err := in.GetString("level")
+		if err != nil {
+			return nil, err
+		}
+		err = fs.Config.LogLevel.Set(level)
+		if err != nil {
+			return nil, err
+		}
+		fs.Infof(nil, "Log level set to %v", fs.Config.LogLevel)
+	}
+	out = rc.Params{
+		"level": fs.Config.LogLevel.String(),
+	}
+	return out, nil
+}
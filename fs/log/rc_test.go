@@ -0,0 +1,35 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestRcLogLevel(t *testing.T) {
+	oldLevel := fs.Config.LogLevel
+	defer func() { fs.Config.LogLevel = oldLevel }()
+
+	fs.Config.LogLevel = fs.LogLevelNotice
+	out, err := rcLogLevel(context.Background(), rc.Params{})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"level": "NOTICE"}, out)
+
+	out, err = rcLogLevel(context.Background(), rc.Params{"level": "DEBUG"})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"level": "DEBUG"}, out)
+	assert.Equal(t, fs.LogLevelDebug, fs.Config.LogLevel)
+
+	_, err = rcLogLevel(context.Background(), rc.Params{"level": "POTATO"})
+	assert.Error(t, err)
+	assert.Equal(t, fs.LogLevelDebug, fs.Config.LogLevel)
+}
+
+func TestRcReopenLog(t *testing.T) {
+	_, err := rcReopenLog(context.Background(), rc.Params{})
+	assert.Error(t, err)
+}
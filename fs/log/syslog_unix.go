@@ -1,6 +1,6 @@
 // Syslog interface for Unix variants only
 
-// +build !windows,!nacl,!plan9
+// +build !windows,!nacl,!plan9,!js
 
 package log
 
@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/filter"
 	"github.com/ncw/rclone/fs/list"
 	"github.com/ncw/rclone/fs/walk"
@@ -348,14 +349,14 @@ func (m *March) processJob(job listDirJob) (jobs []listDirJob) {
 	wg.Wait()
 	if srcListErr != nil {
 		fs.Errorf(job.srcRemote, "error reading source directory: %v", srcListErr)
-		fs.CountError(srcListErr)
+		accounting.CountError(m.fsrc, srcListErr)
 		return nil
 	}
 	if dstListErr == fs.ErrorDirNotFound {
 		// Copy the stuff anyway
 	} else if dstListErr != nil {
 		fs.Errorf(job.dstRemote, "error reading destination directory: %v", dstListErr)
-		fs.CountError(dstListErr)
+		accounting.CountError(m.fdst, dstListErr)
 		return nil
 	}
 
@@ -0,0 +1,154 @@
+// Package notify tells a webhook or a command when an rclone job
+// finishes or fails.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/ncw/rclone/fs/fshttp"
+	"github.com/pkg/errors"
+)
+
+// Flags
+var (
+	finishURL     = flags.StringP("on-finish-url", "", "", "POST a JSON summary to this URL when the command finishes.")
+	finishCommand = flags.StringP("on-finish-command", "", "", "Run this command with a JSON summary on stdin when the command finishes.")
+	errorURL      = flags.StringP("on-error-url", "", "", "POST a JSON summary to this URL if the command fails.")
+	errorCommand  = flags.StringP("on-error-command", "", "", "Run this command with a JSON summary on stdin if the command fails.")
+)
+
+// Summary describes how a job went
+type Summary struct {
+	Command             string    `json:"command"`             // name of the command or job
+	Args                []string  `json:"args"`                // arguments it was run with
+	Success             bool      `json:"success"`             // set if it succeeded
+	Error               string    `json:"error,omitempty"`     // error if it failed
+	Start               time.Time `json:"start"`               // when it started
+	End                 time.Time `json:"end"`                 // when it finished
+	Duration            float64   `json:"duration"`            // how long it took in seconds
+	Bytes               int64     `json:"bytes"`               // bytes transferred
+	Downloaded          int64     `json:"downloaded"`          // bytes read from the source of transfers
+	Checks              int64     `json:"checks"`              // files checked
+	Transfers           int64     `json:"transfers"`           // files transferred
+	ServerSideCopies    int64     `json:"serverSideCopies"`    // files copied server side
+	ServerSideCopyBytes int64     `json:"serverSideCopyBytes"` // bytes copied server side
+	ServerSideMoves     int64     `json:"serverSideMoves"`     // files moved server side
+	ServerSideMoveBytes int64     `json:"serverSideMoveBytes"` // bytes moved server side
+	Deletes             int64     `json:"deletes"`             // files deleted
+	Errors              int64     `json:"errors"`              // errors counted
+}
+
+// NewSummary makes a summary of the job started at start which
+// finished now with err, filling in the current stats.
+//
+// If err is nil but errors were counted then the job is reported as
+// failed with the last error.
+func NewSummary(command string, args []string, start time.Time, err error) *Summary {
+	end := time.Now()
+	s := &Summary{
+		Command:    command,
+		Args:       args,
+		Start:      start,
+		End:        end,
+		Duration:   end.Sub(start).Seconds(),
+		Bytes:      accounting.Stats.GetBytes(),
+		Downloaded: accounting.Stats.GetDownloaded(),
+		Checks:     accounting.Stats.GetChecks(),
+		Transfers:  accounting.Stats.GetTransfers(),
+		Deletes:    accounting.Stats.GetDeletes(),
+		Errors:     accounting.Stats.GetErrors(),
+	}
+	s.ServerSideCopies, s.ServerSideCopyBytes = accounting.Stats.GetServerSideCopies()
+	s.ServerSideMoves, s.ServerSideMoveBytes = accounting.Stats.GetServerSideMoves()
+	if s.Args == nil {
+		s.Args = []string{}
+	}
+	if err == nil && s.Errors != 0 {
+		err = accounting.Stats.GetLastError()
+		if err == nil {
+			err = errors.Errorf("%d errors", s.Errors)
+		}
+	}
+	s.Success = err == nil
+	if err != nil {
+		s.Error = err.Error()
+	}
+	return s
+}
+
+// Enabled returns whether any notifications are configured
+func Enabled() bool {
+	return *finishURL != "" || *finishCommand != "" || *errorURL != "" || *errorCommand != ""
+}
+
+// Send sends the summary to the configured URLs and commands.
+//
+// Failures to notify are logged but otherwise ignored so they don't
+// change the outcome of the job.
+func Send(s *Summary) {
+	if !Enabled() {
+		return
+	}
+	body, err := json.Marshal(s)
+	if err != nil {
+		fs.Errorf(nil, "Failed to make notification: %v", err)
+		return
+	}
+	send := func(url, command string) {
+		if url != "" {
+			err := post(url, body)
+			if err != nil {
+				fs.Errorf(nil, "Failed to notify %q: %v", url, err)
+			}
+		}
+		if command != "" {
+			err := run(command, body)
+			if err != nil {
+				fs.Errorf(nil, "Failed to notify %q: %v", command, err)
+			}
+		}
+	}
+	send(*finishURL, *finishCommand)
+	if !s.Success {
+		send(*errorURL, *errorCommand)
+	}
+}
+
+// post sends body to url as JSON
+func post(url string, body []byte) error {
+	resp, err := fshttp.NewClient(fs.Config).Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	_, _ = ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("HTTP error %s", resp.Status)
+	}
+	return nil
+}
+
+// run runs command with body on stdin
+//
+// The command is split into arguments on white space.
+func run(command string, body []byte) error {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return errors.New("empty command")
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(body)
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 {
+		fs.Debugf(nil, "%s: %s", args[0], out)
+	}
+	return err
+}
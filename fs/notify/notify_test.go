@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSummary(t *testing.T) {
+	start := time.Now().Add(-time.Minute)
+	s := NewSummary("sync", nil, start, nil)
+	assert.Equal(t, "sync", s.Command)
+	assert.Equal(t, []string{}, s.Args)
+	assert.True(t, s.Success)
+	assert.Equal(t, "", s.Error)
+	assert.True(t, s.Duration >= 60)
+
+	s = NewSummary("copy", []string{"a", "b"}, start, errors.New("potato"))
+	assert.Equal(t, []string{"a", "b"}, s.Args)
+	assert.False(t, s.Success)
+	assert.Equal(t, "potato", s.Error)
+}
+
+func TestSend(t *testing.T) {
+	var got []*Summary
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var s Summary
+		require.NoError(t, json.Unmarshal(body, &s))
+		got = append(got, &s)
+		paths = append(paths, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	oldFinishURL, oldErrorURL := *finishURL, *errorURL
+	defer func() {
+		*finishURL, *errorURL = oldFinishURL, oldErrorURL
+	}()
+	*finishURL = ts.URL + "/finish"
+	*errorURL = ts.URL + "/error"
+
+	Send(NewSummary("sync", []string{"a", "b"}, time.Now(), nil))
+	require.Equal(t, []string{"/finish"}, paths)
+	assert.Equal(t, "sync", got[0].Command)
+	assert.True(t, got[0].Success)
+
+	got, paths = nil, nil
+	Send(NewSummary("sync", nil, time.Now(), errors.New("potato")))
+	require.Equal(t, []string{"/finish", "/error"}, paths)
+	for _, s := range got {
+		assert.False(t, s.Success)
+		assert.Equal(t, "potato", s.Error)
+	}
+}
@@ -0,0 +1,123 @@
+package operations
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/pkg/errors"
+)
+
+const (
+	multithreadChunkSize = 64 << 10 // streams start on multiples of this
+)
+
+// offsetWriter writes to an io.WriterAt starting at offset
+type offsetWriter struct {
+	w      io.WriterAt
+	offset int64
+}
+
+// Write p at the current offset
+func (ow *offsetWriter) Write(p []byte) (n int, err error) {
+	n, err = ow.w.WriteAt(p, ow.offset)
+	ow.offset += int64(n)
+	return n, err
+}
+
+// multiThreadCopyPart copies the part of src from start to end into
+// out at the same offset accounting it in acc
+func multiThreadCopyPart(out io.WriterAt, src fs.Object, start, end int64, acc *accounting.Account) error {
+	in, err := src.Open(&fs.RangeOption{Start: start, End: end - 1})
+	if err != nil {
+		return errors.Wrap(err, "multi-thread copy: failed to open source")
+	}
+	n, err := io.Copy(&offsetWriter{w: out, offset: start}, acc.WrapStream(in))
+	closeErr := in.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "multi-thread copy: failed to copy")
+	}
+	if n != end-start {
+		return errors.Errorf("multi-thread copy: expecting %d bytes but got %d", end-start, n)
+	}
+	fs.Debugf(src, "multi-thread copy: copied bytes %d-%d", start, end-1)
+	return nil
+}
+
+// multiThreadCopy copies src to remote on f by downloading it in
+// streams parts at once and writing each part at its offset in the
+// destination.
+//
+// f must support OpenWriterAt and src must have a known size.
+func multiThreadCopy(f fs.Fs, remote string, src fs.Object, streams int) (newDst fs.Object, err error) {
+	size := src.Size()
+	partSize := (size + int64(streams) - 1) / int64(streams)
+	// round the part size up to a multiple of the chunk size
+	partSize = (partSize + multithreadChunkSize - 1) / multithreadChunkSize * multithreadChunkSize
+	streams = int((size + partSize - 1) / partSize)
+
+	out, err := f.Features().OpenWriterAt(remote, size)
+	if err != nil {
+		return nil, errors.Wrap(err, "multi-thread copy: failed to open destination")
+	}
+	acc := accounting.NewAccount(ioutil.NopCloser(strings.NewReader("")), src)
+	fs.Debugf(src, "Starting multi-thread copy with %d parts of size %v", streams, fs.SizeSuffix(partSize))
+
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
+	for stream := 0; stream < streams; stream++ {
+		start := int64(stream) * partSize
+		end := start + partSize
+		if end > size {
+			end = size
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := multiThreadCopyPart(out, src, start, end, acc)
+			if err != nil {
+				errMu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				errMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	err = firstErr
+	closeErr := out.Close()
+	if err == nil {
+		err = errors.Wrap(closeErr, "multi-thread copy: failed to close destination")
+	}
+	_ = acc.Close()
+
+	if err == nil {
+		newDst, err = f.NewObject(remote)
+		if err == nil {
+			err = newDst.SetModTime(src.ModTime())
+			if err == fs.ErrorCantSetModTime || err == fs.ErrorCantSetModTimeWithoutDelete {
+				err = nil
+			}
+		}
+	}
+	if err != nil {
+		// remove the partially written file
+		if o, findErr := f.NewObject(remote); findErr == nil {
+			if removeErr := o.Remove(); removeErr != nil {
+				fs.Errorf(o, "multi-thread copy: failed to remove partially written file: %v", removeErr)
+			}
+		}
+		return nil, err
+	}
+	return newDst, nil
+}
@@ -0,0 +1,48 @@
+package operations_test
+
+import (
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiThreadCopy(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if r.Flocal.Features().OpenWriterAt == nil {
+		t.Skip("multi-thread copy not supported by the destination")
+	}
+
+	oldCutoff, oldStreams := fs.Config.MultiThreadCutoff, fs.Config.MultiThreadStreams
+	defer func() {
+		fs.Config.MultiThreadCutoff, fs.Config.MultiThreadStreams = oldCutoff, oldStreams
+	}()
+	fs.Config.MultiThreadCutoff = 1
+	fs.Config.MultiThreadStreams = 4
+
+	for _, size := range []int{1, 1000, 64 * 1024, 1024*1024 + 17} {
+		contents := fstest.RandomString(size)
+		file1 := r.WriteObject("file1", contents, t1)
+		fstest.CheckItems(t, r.Fremote, file1)
+
+		src, err := r.Fremote.NewObject("file1")
+		require.NoError(t, err)
+		dst, err := operations.Copy(r.Flocal, nil, "sub/file1", src)
+		require.NoError(t, err, "size %d", size)
+		assert.Equal(t, int64(size), dst.Size())
+
+		file2 := file1
+		file2.Path = "sub/file1"
+		fstest.CheckItems(t, r.Flocal, file2)
+
+		// Copying again replaces the existing file
+		dst, err = operations.Copy(r.Flocal, dst, "sub/file1", src)
+		require.NoError(t, err)
+		fstest.CheckItems(t, r.Flocal, file2)
+		require.NoError(t, dst.Remove())
+	}
+}
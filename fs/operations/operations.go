@@ -51,7 +51,7 @@ func CheckHashes(src fs.ObjectInfo, dst fs.Object) (equal bool, ht hash.Type, er
 	ht = common.GetOne()
 	srcHash, err := src.Hash(ht)
 	if err != nil {
-		fs.CountError(err)
+		accounting.CountError(src.Fs(), err)
 		fs.Errorf(src, "Failed to calculate src hash: %v", err)
 		return false, ht, err
 	}
@@ -60,7 +60,7 @@ func CheckHashes(src fs.ObjectInfo, dst fs.Object) (equal bool, ht hash.Type, er
 	}
 	dstHash, err := dst.Hash(ht)
 	if err != nil {
-		fs.CountError(err)
+		accounting.CountError(dst.Fs(), err)
 		fs.Errorf(dst, "Failed to calculate dst hash: %v", err)
 		return false, ht, err
 	}
@@ -157,14 +157,13 @@ func equal(src fs.ObjectInfo, dst fs.Object, sizeOnly, checkSum bool) bool {
 	}
 	if ht == hash.None {
 		// if couldn't check hash, return that they differ
+		fs.Debugf(src, "No common hash to check modification time difference against")
 		return false
 	}
 
 	// mod time differs but hash is the same to reset mod time if required
 	if !fs.Config.NoUpdateModTime {
-		if fs.Config.DryRun {
-			fs.Logf(src, "Not updating modification time as --dry-run")
-		} else {
+		if !SkipDestructive(src, "update modification time") {
 			// Size and hash the same but mtime different
 			// Error if objects are treated as immutable
 			if fs.Config.Immutable {
@@ -188,7 +187,7 @@ func equal(src fs.ObjectInfo, dst fs.Object, sizeOnly, checkSum bool) bool {
 				}
 				return false
 			} else if err != nil {
-				fs.CountError(err)
+				accounting.CountError(dst.Fs(), err)
 				fs.Errorf(dst, "Failed to set modification time: %v", err)
 			} else {
 				fs.Infof(src, "Updated modification time in destination")
@@ -237,6 +236,51 @@ func (o *overrideRemoteObject) MimeType() string {
 // Check interface is satisfied
 var _ fs.MimeTyper = (*overrideRemoteObject)(nil)
 
+// Wrapper to override the size for an object info
+type overrideSizeObjectInfo struct {
+	fs.ObjectInfo
+	size int64
+}
+
+// Size returns the overriden size
+func (o *overrideSizeObjectInfo) Size() int64 {
+	return o.size
+}
+
+// MimeType returns the mime type of the underlying object or "" if it
+// can't be worked out
+func (o *overrideSizeObjectInfo) MimeType() string {
+	if do, ok := o.ObjectInfo.(fs.MimeTyper); ok {
+		return do.MimeType()
+	}
+	return ""
+}
+
+// Check interface is satisfied
+var _ fs.MimeTyper = (*overrideSizeObjectInfo)(nil)
+
+// readUnknownSize reads up to --streaming-upload-cutoff bytes from in
+// which is the contents of src, an object of unknown size.
+//
+// If it is all of in then it returns a reader and an object info for
+// a small object with a known size so it can be uploaded normally.
+// Otherwise it returns a reader which will read all of in and src
+// unchanged, with streaming true.
+//
+// This is held in memory so nothing is spooled to disk.
+func readUnknownSize(in io.Reader, src fs.ObjectInfo) (out io.Reader, info fs.ObjectInfo, streaming bool, err error) {
+	buf := make([]byte, fs.Config.StreamingUploadCutoff)
+	n, err := io.ReadFull(in, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		fs.Debugf(src, "File of unknown size is small (%d bytes), uploading instead of streaming", n)
+		return bytes.NewReader(buf[:n]), &overrideSizeObjectInfo{ObjectInfo: src, size: int64(n)}, false, nil
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return io.MultiReader(bytes.NewReader(buf), in), src, true, nil
+}
+
 // Copy src object to dst or f if nil.  If dst is nil then it uses
 // remote as the name of the new object.
 //
@@ -244,8 +288,7 @@ var _ fs.MimeTyper = (*overrideRemoteObject)(nil)
 // be nil.
 func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Object, err error) {
 	newDst = dst
-	if fs.Config.DryRun {
-		fs.Logf(src, "Not copying as --dry-run")
+	if SkipDestructive(src, "copy") {
 		return newDst, nil
 	}
 	maxTries := fs.Config.LowLevelRetries
@@ -271,34 +314,58 @@ func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Objec
 			newDst, err = doCopy(src, remote)
 			if err == nil {
 				dst = newDst
+				accounting.StatsFor(f).ServerSideCopy(src.Size())
 			}
 		} else {
 			err = fs.ErrorCantCopy
 		}
 		// If can't server side copy, do it manually
 		if err == fs.ErrorCantCopy {
-			var in0 io.ReadCloser
-			in0, err = src.Open(hashOption)
-			if err != nil {
-				err = errors.Wrap(err, "failed to open source object")
-			} else {
-				in := accounting.NewAccount(in0, src).WithBuffer() // account and buffer the transfer
-				var wrappedSrc fs.ObjectInfo = src
-				// We try to pass the original object if possible
-				if src.Remote() != remote {
-					wrappedSrc = &overrideRemoteObject{Object: src, remote: remote}
-				}
+			if doOpenWriterAt := f.Features().OpenWriterAt; doOpenWriterAt != nil && fs.Config.MultiThreadStreams > 1 && src.Size() > 0 && src.Size() >= int64(fs.Config.MultiThreadCutoff) {
+				// Copy in several streams at once if the
+				// destination can be written at any offset
+				actionTaken = "Multi-thread Copied (new)"
 				if doUpdate {
-					actionTaken = "Copied (replaced existing)"
-					err = dst.Update(in, wrappedSrc, hashOption)
-				} else {
-					actionTaken = "Copied (new)"
-					dst, err = f.Put(in, wrappedSrc, hashOption)
+					actionTaken = "Multi-thread Copied (replaced existing)"
 				}
-				closeErr := in.Close()
+				dst, err = multiThreadCopy(f, remote, src, fs.Config.MultiThreadStreams)
 				if err == nil {
 					newDst = dst
-					err = closeErr
+				}
+			} else {
+				var in0 io.ReadCloser
+				in0, err = src.Open(hashOption)
+				if err != nil {
+					err = errors.Wrap(err, "failed to open source object")
+				} else {
+					in := accounting.NewAccount(in0, src).WithBuffer() // account and buffer the transfer
+					var wrappedSrc fs.ObjectInfo = src
+					// We try to pass the original object if possible
+					if src.Remote() != remote {
+						wrappedSrc = &overrideRemoteObject{Object: src, remote: remote}
+					}
+					var upload io.Reader = in
+					streaming := false
+					if src.Size() < 0 {
+						upload, wrappedSrc, streaming, err = readUnknownSize(in, wrappedSrc)
+					}
+					if err != nil {
+						err = errors.Wrap(err, "failed to read source object")
+					} else if doUpdate {
+						actionTaken = "Copied (replaced existing)"
+						err = dst.Update(upload, wrappedSrc, hashOption)
+					} else if doPutStream := f.Features().PutStream; streaming && doPutStream != nil {
+						actionTaken = "Copied (new, streamed)"
+						dst, err = doPutStream(upload, wrappedSrc, hashOption)
+					} else {
+						actionTaken = "Copied (new)"
+						dst, err = f.Put(upload, wrappedSrc, hashOption)
+					}
+					closeErr := in.Close()
+					if err == nil {
+						newDst = dst
+						err = closeErr
+					}
 				}
 			}
 		}
@@ -315,7 +382,7 @@ func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Objec
 		break
 	}
 	if err != nil {
-		fs.CountError(err)
+		accounting.CountError(f, err)
 		fs.Errorf(src, "Failed to copy: %v", err)
 		return newDst, err
 	}
@@ -324,7 +391,7 @@ func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Objec
 	if sizeDiffers(src, dst) {
 		err = errors.Errorf("corrupted on transfer: sizes differ %d vs %d", src.Size(), dst.Size())
 		fs.Errorf(dst, "%v", err)
-		fs.CountError(err)
+		accounting.CountError(f, err)
 		removeFailedCopy(dst)
 		return newDst, err
 	}
@@ -336,18 +403,18 @@ func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Objec
 		var srcSum string
 		srcSum, err = src.Hash(hashType)
 		if err != nil {
-			fs.CountError(err)
+			accounting.CountError(f, err)
 			fs.Errorf(src, "Failed to read src hash: %v", err)
 		} else if srcSum != "" {
 			var dstSum string
 			dstSum, err = dst.Hash(hashType)
 			if err != nil {
-				fs.CountError(err)
+				accounting.CountError(f, err)
 				fs.Errorf(dst, "Failed to read hash: %v", err)
 			} else if !fs.Config.IgnoreChecksum && !hash.Equals(srcSum, dstSum) {
 				err = errors.Errorf("corrupted on transfer: %v hash differ %q vs %q", hashType, srcSum, dstSum)
 				fs.Errorf(dst, "%v", err)
-				fs.CountError(err)
+				accounting.CountError(f, err)
 				removeFailedCopy(dst)
 				return newDst, err
 			}
@@ -365,8 +432,7 @@ func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Objec
 // be nil.
 func Move(fdst fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Object, err error) {
 	newDst = dst
-	if fs.Config.DryRun {
-		fs.Logf(src, "Not moving as --dry-run")
+	if SkipDestructive(src, "move") {
 		return newDst, nil
 	}
 	// See if we have Move available
@@ -382,12 +448,13 @@ func Move(fdst fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Ob
 		newDst, err = doMove(src, remote)
 		switch err {
 		case nil:
+			accounting.StatsFor(fdst).ServerSideMove(src.Size())
 			fs.Infof(src, "Moved (server side)")
 			return newDst, nil
 		case fs.ErrorCantMove:
 			fs.Debugf(src, "Can't move, switching to copy")
 		default:
-			fs.CountError(err)
+			accounting.CountError(fdst, err)
 			fs.Errorf(src, "Couldn't move: %v", err)
 			return newDst, err
 		}
@@ -419,17 +486,19 @@ func CanServerSideMove(fdst fs.Fs) bool {
 // If backupDir is set then it moves the file to there instead of
 // deleting
 func DeleteFileWithBackupDir(dst fs.Object, backupDir fs.Fs) (err error) {
-	accounting.Stats.Checking(dst.Remote())
-	numDeletes := accounting.Stats.Deletes(1)
+	stats := accounting.StatsFor(dst.Fs())
+	stats.Checking(dst.Remote())
+	numDeletes := stats.Deletes(1)
 	if fs.Config.MaxDelete != -1 && numDeletes > fs.Config.MaxDelete {
 		return fserrors.FatalError(errors.New("--max-delete threshold reached"))
 	}
-	action, actioned, actioning := "delete", "Deleted", "deleting"
+	action, actioned := "delete", "Deleted"
 	if backupDir != nil {
-		action, actioned, actioning = "move into backup dir", "Moved into backup dir", "moving into backup dir"
+		action, actioned = "move into backup dir", "Moved into backup dir"
 	}
-	if fs.Config.DryRun {
-		fs.Logf(dst, "Not %s as --dry-run", actioning)
+	skip := SkipDestructive(dst, action)
+	if skip {
+		// do nothing
 	} else if backupDir != nil {
 		if !SameConfig(dst.Fs(), backupDir) {
 			err = errors.New("parameter to --backup-dir has to be on the same remote as destination")
@@ -442,12 +511,12 @@ func DeleteFileWithBackupDir(dst fs.Object, backupDir fs.Fs) (err error) {
 		err = dst.Remove()
 	}
 	if err != nil {
-		fs.CountError(err)
+		accounting.CountError(dst.Fs(), err)
 		fs.Errorf(dst, "Couldn't %s: %v", action, err)
-	} else if !fs.Config.DryRun {
+	} else if !skip {
 		fs.Infof(dst, actioned)
 	}
-	accounting.Stats.DoneChecking(dst.Remote())
+	stats.DoneChecking(dst.Remote())
 	return err
 }
 
@@ -465,12 +534,30 @@ func DeleteFile(dst fs.Object) (err error) {
 // If backupDir is set the files will be placed into that directory
 // instead of being deleted.
 func DeleteFilesWithBackupDir(toBeDeleted fs.ObjectsChan, backupDir fs.Fs) error {
+	return deleteFiles(toBeDeleted, backupDir, deleteWorkers(fs.Config.Transfers))
+}
+
+// deleteWorkers returns the number of deletions to run at once which
+// is --delete-workers if set or defaultWorkers if not
+func deleteWorkers(defaultWorkers int) int {
+	if fs.Config.DeleteWorkers > 0 {
+		return fs.Config.DeleteWorkers
+	}
+	return defaultWorkers
+}
+
+// deleteFiles removes all the files passed in the channel using
+// workers go routines to do the deletions
+func deleteFiles(toBeDeleted fs.ObjectsChan, backupDir fs.Fs, workers int) error {
+	if workers < 1 {
+		workers = 1
+	}
 	var wg sync.WaitGroup
-	wg.Add(fs.Config.Transfers)
+	wg.Add(workers)
 	var errorCount int32
 	var fatalErrorCount int32
 
-	for i := 0; i < fs.Config.Transfers; i++ {
+	for i := 0; i < workers; i++ {
 		go func() {
 			defer wg.Done()
 			for dst := range toBeDeleted {
@@ -565,7 +652,7 @@ func checkIdentical(dst, src fs.Object) (differ bool, noHash bool) {
 	if !same {
 		err = errors.Errorf("%v differ", ht)
 		fs.Errorf(src, "%v", err)
-		fs.CountError(err)
+		accounting.CountError(dst.Fs(), err)
 		return true, false
 	}
 	return false, false
@@ -574,26 +661,47 @@ func checkIdentical(dst, src fs.Object) (differ bool, noHash bool) {
 // checkFn is the the type of the checking function used in CheckFn()
 type checkFn func(a, b fs.Object) (differ bool, noHash bool)
 
+// CheckOpt contains options for the Check functions
+type CheckOpt struct {
+	Fdst, Fsrc   fs.Fs     // fses to check
+	Check        checkFn   // function to use for checking
+	Combined     io.Writer // a file with file names with leading sigils
+	MissingOnSrc io.Writer // files only in the destination
+	MissingOnDst io.Writer // files only in the source
+	Match        io.Writer // matching files
+	Differ       io.Writer // differing files
+}
+
 // checkMarch is used to march over two Fses in the same way as
 // sync/copy
 type checkMarch struct {
-	fdst, fsrc      fs.Fs
-	check           checkFn
+	opt             CheckOpt
 	differences     int32
 	noHashes        int32
 	srcFilesMissing int32
 	dstFilesMissing int32
 }
 
+// report outputs the fileName to out if required and to the combined log
+func (c *checkMarch) report(o fs.DirEntry, out io.Writer, sigil rune) {
+	if out != nil {
+		syncFprintf(out, "%s\n", o.Remote())
+	}
+	if c.opt.Combined != nil {
+		syncFprintf(c.opt.Combined, "%c %s\n", sigil, o.Remote())
+	}
+}
+
 // DstOnly have an object which is in the destination only
 func (c *checkMarch) DstOnly(dst fs.DirEntry) (recurse bool) {
 	switch dst.(type) {
 	case fs.Object:
-		err := errors.Errorf("File not in %v", c.fsrc)
+		err := errors.Errorf("File not in %v", c.opt.Fsrc)
 		fs.Errorf(dst, "%v", err)
-		fs.CountError(err)
+		accounting.CountError(c.opt.Fdst, err)
 		atomic.AddInt32(&c.differences, 1)
 		atomic.AddInt32(&c.srcFilesMissing, 1)
+		c.report(dst, c.opt.MissingOnSrc, '-')
 	case fs.Directory:
 		// Do the same thing to the entire contents of the directory
 		return true
@@ -607,11 +715,12 @@ func (c *checkMarch) DstOnly(dst fs.DirEntry) (recurse bool) {
 func (c *checkMarch) SrcOnly(src fs.DirEntry) (recurse bool) {
 	switch src.(type) {
 	case fs.Object:
-		err := errors.Errorf("File not in %v", c.fdst)
+		err := errors.Errorf("File not in %v", c.opt.Fdst)
 		fs.Errorf(src, "%v", err)
-		fs.CountError(err)
+		accounting.CountError(c.opt.Fdst, err)
 		atomic.AddInt32(&c.differences, 1)
 		atomic.AddInt32(&c.dstFilesMissing, 1)
+		c.report(src, c.opt.MissingOnDst, '+')
 	case fs.Directory:
 		// Do the same thing to the entire contents of the directory
 		return true
@@ -628,13 +737,13 @@ func (c *checkMarch) checkIdentical(dst, src fs.Object) (differ bool, noHash boo
 	if sizeDiffers(src, dst) {
 		err := errors.Errorf("Sizes differ")
 		fs.Errorf(src, "%v", err)
-		fs.CountError(err)
+		accounting.CountError(c.opt.Fdst, err)
 		return true, false
 	}
 	if fs.Config.SizeOnly {
 		return false, false
 	}
-	return c.check(dst, src)
+	return c.opt.Check(dst, src)
 }
 
 // Match is called when src and dst are present, so sync src to dst
@@ -646,18 +755,21 @@ func (c *checkMarch) Match(dst, src fs.DirEntry) (recurse bool) {
 			differ, noHash := c.checkIdentical(dstX, srcX)
 			if differ {
 				atomic.AddInt32(&c.differences, 1)
+				c.report(src, c.opt.Differ, '*')
 			} else {
 				fs.Debugf(dstX, "OK")
+				c.report(src, c.opt.Match, '=')
 			}
 			if noHash {
 				atomic.AddInt32(&c.noHashes, 1)
 			}
 		} else {
-			err := errors.Errorf("is file on %v but directory on %v", c.fsrc, c.fdst)
+			err := errors.Errorf("is file on %v but directory on %v", c.opt.Fsrc, c.opt.Fdst)
 			fs.Errorf(src, "%v", err)
-			fs.CountError(err)
+			accounting.CountError(c.opt.Fdst, err)
 			atomic.AddInt32(&c.differences, 1)
 			atomic.AddInt32(&c.dstFilesMissing, 1)
+			c.report(src, c.opt.MissingOnDst, '+')
 		}
 	case fs.Directory:
 		// Do the same thing to the entire contents of the directory
@@ -665,11 +777,12 @@ func (c *checkMarch) Match(dst, src fs.DirEntry) (recurse bool) {
 		if ok {
 			return true
 		}
-		err := errors.Errorf("is file on %v but directory on %v", c.fdst, c.fsrc)
+		err := errors.Errorf("is file on %v but directory on %v", c.opt.Fdst, c.opt.Fsrc)
 		fs.Errorf(dst, "%v", err)
-		fs.CountError(err)
+		accounting.CountError(c.opt.Fdst, err)
 		atomic.AddInt32(&c.differences, 1)
 		atomic.AddInt32(&c.srcFilesMissing, 1)
+		c.report(dst, c.opt.MissingOnSrc, '-')
 
 	default:
 		panic("Bad object in DirEntries")
@@ -677,35 +790,39 @@ func (c *checkMarch) Match(dst, src fs.DirEntry) (recurse bool) {
 	return false
 }
 
-// CheckFn checks the files in fsrc and fdst according to Size and
-// hash using checkFunction on each file to check the hashes.
+// CheckFn checks the files in opt.Fsrc and opt.Fdst according to Size
+// and hash using opt.Check on each file to check the hashes.
 //
-// checkFunction sees if dst and src are identical
+// opt.Check sees if dst and src are identical
 //
 // it returns true if differences were found
 // it also returns whether it couldn't be hashed
-func CheckFn(fdst, fsrc fs.Fs, check checkFn) error {
+//
+// The names of the files are written to the io.Writers in opt which
+// are set according to whether they match, differ or are missing.
+func CheckFn(opt *CheckOpt) error {
+	if opt.Check == nil {
+		return errors.New("internal error: nil check function")
+	}
 	c := &checkMarch{
-		fdst:  fdst,
-		fsrc:  fsrc,
-		check: check,
+		opt: *opt,
 	}
 
 	// set up a march over fdst and fsrc
-	m := march.New(context.Background(), fdst, fsrc, "", c)
-	fs.Infof(fdst, "Waiting for checks to finish")
+	m := march.New(context.Background(), opt.Fdst, opt.Fsrc, "", c)
+	fs.Infof(opt.Fdst, "Waiting for checks to finish")
 	m.Run()
 
 	if c.dstFilesMissing > 0 {
-		fs.Logf(fdst, "%d files missing", c.dstFilesMissing)
+		fs.Logf(opt.Fdst, "%d files missing", c.dstFilesMissing)
 	}
 	if c.srcFilesMissing > 0 {
-		fs.Logf(fsrc, "%d files missing", c.srcFilesMissing)
+		fs.Logf(opt.Fsrc, "%d files missing", c.srcFilesMissing)
 	}
 
-	fs.Logf(fdst, "%d differences found", accounting.Stats.GetErrors())
+	fs.Logf(opt.Fdst, "%d differences found", accounting.Stats.GetErrors())
 	if c.noHashes > 0 {
-		fs.Logf(fdst, "%d hashes could not be checked", c.noHashes)
+		fs.Logf(opt.Fdst, "%d hashes could not be checked", c.noHashes)
 	}
 	if c.differences > 0 {
 		return errors.Errorf("%d differences found", c.differences)
@@ -714,8 +831,10 @@ func CheckFn(fdst, fsrc fs.Fs, check checkFn) error {
 }
 
 // Check the files in fsrc and fdst according to Size and hash
-func Check(fdst, fsrc fs.Fs) error {
-	return CheckFn(fdst, fsrc, checkIdentical)
+func Check(opt *CheckOpt) error {
+	optCopy := *opt
+	optCopy.Check = checkIdentical
+	return CheckFn(&optCopy)
 }
 
 // CheckEqualReaders checks to see if in1 and in2 have the same
@@ -772,17 +891,18 @@ func CheckIdentical(dst, src fs.Object) (differ bool, err error) {
 
 // CheckDownload checks the files in fsrc and fdst according to Size
 // and the actual contents of the files.
-func CheckDownload(fdst, fsrc fs.Fs) error {
-	check := func(a, b fs.Object) (differ bool, noHash bool) {
+func CheckDownload(opt *CheckOpt) error {
+	optCopy := *opt
+	optCopy.Check = func(a, b fs.Object) (differ bool, noHash bool) {
 		differ, err := CheckIdentical(a, b)
 		if err != nil {
-			fs.CountError(err)
+			accounting.CountError(opt.Fdst, err)
 			fs.Errorf(a, "Failed to download: %v", err)
 			return true, true
 		}
 		return differ, false
 	}
-	return CheckFn(fdst, fsrc, check)
+	return CheckFn(&optCopy)
 }
 
 // ListFn lists the Fs to the supplied function
@@ -924,14 +1044,13 @@ func ListDir(f fs.Fs, w io.Writer) error {
 
 // Mkdir makes a destination directory or container
 func Mkdir(f fs.Fs, dir string) error {
-	if fs.Config.DryRun {
-		fs.Logf(fs.LogDirName(f, dir), "Not making directory as dry run is set")
+	if SkipDestructive(fs.LogDirName(f, dir), "make directory") {
 		return nil
 	}
 	fs.Debugf(fs.LogDirName(f, dir), "Making directory")
 	err := f.Mkdir(dir)
 	if err != nil {
-		fs.CountError(err)
+		accounting.CountError(f, err)
 		return err
 	}
 	return nil
@@ -940,8 +1059,7 @@ func Mkdir(f fs.Fs, dir string) error {
 // TryRmdir removes a container but not if not empty.  It doesn't
 // count errors but may return one.
 func TryRmdir(f fs.Fs, dir string) error {
-	if fs.Config.DryRun {
-		fs.Logf(fs.LogDirName(f, dir), "Not deleting as dry run is set")
+	if SkipDestructive(fs.LogDirName(f, dir), "remove directory") {
 		return nil
 	}
 	fs.Debugf(fs.LogDirName(f, dir), "Removing directory")
@@ -952,13 +1070,17 @@ func TryRmdir(f fs.Fs, dir string) error {
 func Rmdir(f fs.Fs, dir string) error {
 	err := TryRmdir(f, dir)
 	if err != nil {
-		fs.CountError(err)
+		accounting.CountError(f, err)
 		return err
 	}
 	return err
 }
 
 // Purge removes a directory and all of its contents
+//
+// If the Fs can't purge then the files are deleted one by one with
+// --delete-workers (or --checkers) workers and then the directories
+// are removed.
 func Purge(f fs.Fs, dir string) error {
 	doFallbackPurge := true
 	var err error
@@ -966,9 +1088,7 @@ func Purge(f fs.Fs, dir string) error {
 		// FIXME change the Purge interface so it takes a dir - see #1891
 		if doPurge := f.Features().Purge; doPurge != nil {
 			doFallbackPurge = false
-			if fs.Config.DryRun {
-				fs.Logf(f, "Not purging as --dry-run set")
-			} else {
+			if !SkipDestructive(f, "purge") {
 				err = doPurge()
 				if err == fs.ErrorCantPurge {
 					doFallbackPurge = true
@@ -977,15 +1097,21 @@ func Purge(f fs.Fs, dir string) error {
 		}
 	}
 	if doFallbackPurge {
-		// DeleteFiles and Rmdir observe --dry-run
-		err = DeleteFiles(listToChan(f, dir))
+		// Deleting is a cheap call so use --checkers workers
+		// rather than --transfers by default.  Each file
+		// deleted is logged and counted in the stats so
+		// progress can be seen.
+		workers := deleteWorkers(fs.Config.Checkers)
+		fs.Infof(f, "Purge not available - deleting files with %d workers", workers)
+		// deleteFiles and Rmdir observe --dry-run
+		err = deleteFiles(listToChan(f, dir), nil, workers)
 		if err != nil {
 			return err
 		}
 		err = Rmdirs(f, "", false)
 	}
 	if err != nil {
-		fs.CountError(err)
+		accounting.CountError(f, err)
 		return err
 	}
 	return nil
@@ -1021,17 +1147,16 @@ func dedupeRename(remote string, objs []fs.Object) {
 	base := remote[:len(remote)-len(ext)]
 	for i, o := range objs {
 		newName := fmt.Sprintf("%s-%d%s", base, i+1, ext)
-		if !fs.Config.DryRun {
-			newObj, err := doMove(o, newName)
-			if err != nil {
-				fs.CountError(err)
-				fs.Errorf(o, "Failed to rename: %v", err)
-				continue
-			}
-			fs.Infof(newObj, "renamed from: %v", o)
-		} else {
-			fs.Logf(remote, "Not renaming to %q as --dry-run", newName)
+		if SkipDestructive(o, "rename") {
+			continue
+		}
+		newObj, err := doMove(o, newName)
+		if err != nil {
+			accounting.CountError(o.Fs(), err)
+			fs.Errorf(o, "Failed to rename: %v", err)
+			continue
 		}
+		fs.Infof(newObj, "renamed from: %v", o)
 	}
 }
 
@@ -1185,28 +1310,30 @@ func dedupeFindDuplicateDirs(f fs.Fs) ([][]fs.Directory, error) {
 }
 
 // dedupeMergeDuplicateDirs merges all the duplicate directories found
-func dedupeMergeDuplicateDirs(f fs.Fs, duplicateDirs [][]fs.Directory) error {
+//
+// It returns the number of directories merged
+func dedupeMergeDuplicateDirs(f fs.Fs, duplicateDirs [][]fs.Directory) (merged int, err error) {
 	mergeDirs := f.Features().MergeDirs
 	if mergeDirs == nil {
-		return errors.Errorf("%v: can't merge directories", f)
+		return 0, errors.Errorf("%v: can't merge directories", f)
 	}
 	dirCacheFlush := f.Features().DirCacheFlush
 	if dirCacheFlush == nil {
-		return errors.Errorf("%v: can't flush dir cache", f)
+		return 0, errors.Errorf("%v: can't flush dir cache", f)
 	}
 	for _, dirs := range duplicateDirs {
-		if !fs.Config.DryRun {
-			fs.Infof(dirs[0], "Merging contents of duplicate directories")
-			err := mergeDirs(dirs)
-			if err != nil {
-				return errors.Wrap(err, "merge duplicate dirs")
-			}
-		} else {
-			fs.Infof(dirs[0], "NOT Merging contents of duplicate directories as --dry-run")
+		if SkipDestructive(dirs[0], "merge duplicate directories") {
+			continue
+		}
+		fs.Infof(dirs[0], "Merging contents of duplicate directories")
+		err := mergeDirs(dirs)
+		if err != nil {
+			return merged, errors.Wrap(err, "merge duplicate dirs")
 		}
+		merged++
 	}
 	dirCacheFlush()
-	return nil
+	return merged, nil
 }
 
 // Deduplicate interactively finds duplicate files and offers to
@@ -1225,11 +1352,12 @@ func Deduplicate(f fs.Fs, mode DeduplicateMode) error {
 		if len(duplicateDirs) == 0 {
 			break
 		}
-		err = dedupeMergeDuplicateDirs(f, duplicateDirs)
+		merged, err := dedupeMergeDuplicateDirs(f, duplicateDirs)
 		if err != nil {
 			return err
 		}
-		if fs.Config.DryRun {
+		// stop if any were skipped or they will be found again
+		if merged < len(duplicateDirs) {
 			break
 		}
 	}
@@ -1296,7 +1424,7 @@ func listToChan(f fs.Fs, dir string) fs.ObjectsChan {
 					return nil
 				}
 				err = errors.Errorf("Failed to list: %v", err)
-				fs.CountError(err)
+				accounting.CountError(f, err)
 				fs.Errorf(nil, "%v", err)
 				return nil
 			}
@@ -1315,8 +1443,7 @@ func CleanUp(f fs.Fs) error {
 	if doCleanUp == nil {
 		return errors.Errorf("%v doesn't support cleanup", f)
 	}
-	if fs.Config.DryRun {
-		fs.Logf(f, "Not running cleanup as --dry-run set")
+	if SkipDestructive(f, "clean up") {
 		return nil
 	}
 	return doCleanUp()
@@ -1358,7 +1485,7 @@ func Cat(f fs.Fs, w io.Writer, offset, count int64) error {
 		}
 		in, err := o.Open(options...)
 		if err != nil {
-			fs.CountError(err)
+			accounting.CountError(f, err)
 			fs.Errorf(o, "Failed to open: %v", err)
 			return
 		}
@@ -1373,7 +1500,7 @@ func Cat(f fs.Fs, w io.Writer, offset, count int64) error {
 		defer func() {
 			err = in.Close()
 			if err != nil {
-				fs.CountError(err)
+				accounting.CountError(f, err)
 				fs.Errorf(o, "Failed to close: %v", err)
 			}
 		}()
@@ -1382,7 +1509,7 @@ func Cat(f fs.Fs, w io.Writer, offset, count int64) error {
 		defer mu.Unlock()
 		_, err = io.Copy(w, in)
 		if err != nil {
-			fs.CountError(err)
+			accounting.CountError(f, err)
 			fs.Errorf(o, "Failed to send to output: %v", err)
 		}
 	})
@@ -1411,7 +1538,7 @@ func Rcat(fdst fs.Fs, dstFileName string, in io.ReadCloser, modTime time.Time) (
 		src := object.NewStaticObjectInfo(dstFileName, modTime, int64(readCounter.BytesRead()), false, hash.Sums(), fdst)
 		if !Equal(src, dst) {
 			err = errors.Errorf("corrupted on transfer")
-			fs.CountError(err)
+			accounting.CountError(fdst, err)
 			fs.Errorf(dst, "%v", err)
 			return err
 		}
@@ -1432,6 +1559,14 @@ func Rcat(fdst fs.Fs, dstFileName string, in io.ReadCloser, modTime time.Time) (
 		Closer: in,
 	}
 
+	// check before spooling to a temporary local FS so it gets
+	// cleaned up
+	if SkipDestructive(dstFileName, "upload") {
+		// prevents "broken pipe" errors
+		_, err = io.Copy(ioutil.Discard, in)
+		return nil, err
+	}
+
 	fStreamTo := fdst
 	canStream := fdst.Features().PutStream != nil
 	if !canStream {
@@ -1449,13 +1584,6 @@ func Rcat(fdst fs.Fs, dstFileName string, in io.ReadCloser, modTime time.Time) (
 		fStreamTo = tmpLocalFs
 	}
 
-	if fs.Config.DryRun {
-		fs.Logf("stdin", "Not uploading as --dry-run")
-		// prevents "broken pipe" errors
-		_, err = io.Copy(ioutil.Discard, in)
-		return nil, err
-	}
-
 	objInfo := object.NewStaticObjectInfo(dstFileName, modTime, -1, false, nil, nil)
 	if dst, err = fStreamTo.Features().PutStream(in, objInfo, hashOption); err != nil {
 		return dst, err
@@ -1477,7 +1605,7 @@ func Rmdirs(f fs.Fs, dir string, leaveRoot bool) error {
 	dirEmpty[""] = !leaveRoot
 	err := walk.Walk(f, dir, true, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
 		if err != nil {
-			fs.CountError(err)
+			accounting.CountError(f, err)
 			fs.Errorf(f, "Failed to list %q: %v", dirPath, err)
 			return nil
 		}
@@ -1524,7 +1652,7 @@ func Rmdirs(f fs.Fs, dir string, leaveRoot bool) error {
 		dir := toDelete[i]
 		err := TryRmdir(f, dir)
 		if err != nil {
-			fs.CountError(err)
+			accounting.CountError(f, err)
 			fs.Errorf(dir, "Failed to rmdir: %v", err)
 			return err
 		}
@@ -1584,6 +1712,7 @@ func NeedTransfer(dst, src fs.Object) bool {
 			fs.Debugf(src, "Unchanged skipping")
 			return false
 		}
+		fs.Debugf(src, "Changed, transferring")
 	}
 	return true
 }
@@ -1700,6 +1829,13 @@ func (l *ListFormat) AddHash(ht hash.Type) {
 	})
 }
 
+// AddTier adds the storage tier of objects to the output
+func (l *ListFormat) AddTier() {
+	l.AppendOutput(func() string {
+		return GetTier(l.entry)
+	})
+}
+
 // AppendOutput adds string generated by specific function to printed output
 func (l *ListFormat) AppendOutput(functionToAppend func() string) {
 	if len(l.output) > 0 {
@@ -1717,3 +1853,150 @@ func ListFormatted(entry *fs.DirEntry, list *ListFormat) string {
 	}
 	return out
 }
+
+// GetTier returns the storage tier of entry if it is an object whose
+// backend supports tiers, or "" otherwise
+func GetTier(entry fs.DirEntry) string {
+	do, ok := entry.(fs.GetTierer)
+	if !ok {
+		return ""
+	}
+	return do.GetTier()
+}
+
+// SetTier changes the storage tier of all the objects in f to tier
+func SetTier(f fs.Fs, tier string) error {
+	return walk.Walk(f, "", false, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			o, ok := entry.(fs.Object)
+			if !ok {
+				continue
+			}
+			do, ok := o.(fs.SetTierer)
+			if !ok {
+				return errors.Errorf("%v doesn't support storage tiers", f)
+			}
+			if SkipDestructive(o, "set tier") {
+				continue
+			}
+			err := do.SetTier(tier)
+			if err != nil {
+				accounting.CountError(f, err)
+				fs.Errorf(o, "Failed to set tier: %v", err)
+				continue
+			}
+			fs.Infof(o, "Set tier to %s", tier)
+		}
+		return nil
+	})
+}
+
+// Restore asks for the archived objects in f to be restored so they
+// can be read for lifetime days, as quickly as priority says.
+//
+// If wait is more than 0 then the objects being restored are checked
+// every wait until they all have been.
+func Restore(f fs.Fs, priority string, lifetime int, wait time.Duration) error {
+	var restoring []fs.Object
+	err := walk.Walk(f, "", false, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			o, ok := entry.(fs.Object)
+			if !ok {
+				continue
+			}
+			do, ok := o.(fs.Restorer)
+			if !ok {
+				return errors.Errorf("%v doesn't support restoring archived objects", f)
+			}
+			// Only read the status of objects which may be archived
+			if !do.Archived() {
+				fs.Debugf(o, "Not restoring as it isn't archived")
+				continue
+			}
+			status, err := do.RestoreStatus()
+			if err != nil {
+				accounting.CountError(f, err)
+				fs.Errorf(o, "Failed to read restore status: %v", err)
+				continue
+			}
+			switch status {
+			case fs.RestoreStatusArchived:
+				if SkipDestructive(o, "restore") {
+					continue
+				}
+				err = do.Restore(priority, lifetime)
+				if err != nil {
+					accounting.CountError(f, err)
+					fs.Errorf(o, "Failed to restore: %v", err)
+					continue
+				}
+				fs.Infof(o, "Restore started")
+			case fs.RestoreStatusRestoring:
+				fs.Infof(o, "Already being restored")
+			default:
+				fs.Debugf(o, "Not restoring as it is %s", status)
+				continue
+			}
+			restoring = append(restoring, o)
+		}
+		return nil
+	})
+	if err != nil || wait <= 0 {
+		return err
+	}
+	for len(restoring) > 0 {
+		fs.Logf(f, "Waiting for %d objects to be restored", len(restoring))
+		time.Sleep(wait)
+		var stillRestoring []fs.Object
+		for _, o := range restoring {
+			status, err := o.(fs.Restorer).RestoreStatus()
+			if err != nil {
+				accounting.CountError(f, err)
+				fs.Errorf(o, "Failed to read restore status: %v", err)
+				continue
+			}
+			if status == fs.RestoreStatusArchived || status == fs.RestoreStatusRestoring {
+				stillRestoring = append(stillRestoring, o)
+				continue
+			}
+			fs.Infof(o, "Restored")
+		}
+		restoring = stillRestoring
+	}
+	return nil
+}
+
+// FsInfo describes an Fs and the optional features it supports
+type FsInfo struct {
+	Name      string          // name of the remote as passed into NewFs
+	Root      string          // root of the remote as passed into NewFs
+	String    string          // description of the remote
+	Precision time.Duration   // precision of the modification times
+	Hashes    []string        // names of the supported hashes
+	Features  map[string]bool // optional features and whether they are supported
+}
+
+// GetFsInfo returns the information about f and its features
+//
+// The features are those f has after any wrapping remotes have
+// masked off the ones the remotes they wrap don't support.
+func GetFsInfo(f fs.Fs) *FsInfo {
+	info := &FsInfo{
+		Name:      f.Name(),
+		Root:      f.Root(),
+		String:    f.String(),
+		Precision: f.Precision(),
+		Hashes:    []string{},
+		Features:  f.Features().Enabled(),
+	}
+	for _, hashType := range f.Hashes().Array() {
+		info.Hashes = append(info.Hashes, hashType.String())
+	}
+	return info
+}
@@ -9,7 +9,9 @@ import (
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/object"
+	"github.com/ncw/rclone/fstest/mockobject"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSizeDiffers(t *testing.T) {
@@ -38,3 +40,105 @@ func TestSizeDiffers(t *testing.T) {
 		assert.Equal(t, test.want, got, fmt.Sprintf("ignoreSize=%v, srcSize=%v, dstSize=%v", test.ignoreSize, test.srcSize, test.dstSize))
 	}
 }
+
+func TestDeleteWorkers(t *testing.T) {
+	oldDeleteWorkers := fs.Config.DeleteWorkers
+	defer func() { fs.Config.DeleteWorkers = oldDeleteWorkers }()
+
+	fs.Config.DeleteWorkers = 0
+	assert.Equal(t, 4, deleteWorkers(4))
+	fs.Config.DeleteWorkers = 64
+	assert.Equal(t, 64, deleteWorkers(4))
+}
+
+func TestSkipDestructive(t *testing.T) {
+	oldDryRun, oldInteractive := fs.Config.DryRun, fs.Config.Interactive
+	defer func() {
+		fs.Config.DryRun, fs.Config.Interactive = oldDryRun, oldInteractive
+		interactiveAnswers = map[string]byte{}
+	}()
+
+	fs.Config.DryRun, fs.Config.Interactive = false, false
+	assert.False(t, SkipDestructive("file", "delete"))
+
+	fs.Config.DryRun = true
+	assert.True(t, SkipDestructive("file", "delete"))
+
+	// answers to "skip all" or "do all" are remembered per action
+	fs.Config.DryRun, fs.Config.Interactive = false, true
+	interactiveAnswers["delete"] = 's'
+	interactiveAnswers["copy"] = '!'
+	assert.True(t, SkipDestructive("file", "delete"))
+	assert.False(t, SkipDestructive("file", "copy"))
+}
+
+// tierObjectInfo is an fs.ObjectInfo with a storage tier
+type tierObjectInfo struct {
+	fs.ObjectInfo
+	tier string
+}
+
+func (o tierObjectInfo) GetTier() string { return o.tier }
+
+func TestGetTier(t *testing.T) {
+	info := object.NewStaticObjectInfo("a", time.Now(), 1, true, nil, nil)
+	assert.Equal(t, "", GetTier(info))
+
+	var entry fs.DirEntry = tierObjectInfo{ObjectInfo: info, tier: "STANDARD_IA"}
+	assert.Equal(t, "STANDARD_IA", GetTier(entry))
+
+	var list ListFormat
+	list.SetSeparator(";")
+	list.AddPath()
+	list.AddTier()
+	assert.Equal(t, "a;STANDARD_IA", ListFormatted(&entry, &list))
+}
+
+// restoreFs is an Fs which lists restoreObjects
+type restoreFs struct {
+	fs.Fs
+	entries fs.DirEntries
+}
+
+func (f *restoreFs) String() string                         { return "restoreFs" }
+func (f *restoreFs) Features() *fs.Features                 { return &fs.Features{} }
+func (f *restoreFs) List(dir string) (fs.DirEntries, error) { return f.entries, nil }
+
+// restoreObject is an object which counts the calls to restore it
+type restoreObject struct {
+	mockobject.Object
+	archived    bool
+	status      fs.RestoreStatus
+	statusReads int
+	restores    int
+}
+
+func (o *restoreObject) Archived() bool { return o.archived }
+
+func (o *restoreObject) RestoreStatus() (fs.RestoreStatus, error) {
+	o.statusReads++
+	return o.status, nil
+}
+
+func (o *restoreObject) Restore(priority string, lifetime int) error {
+	o.restores++
+	o.status = fs.RestoreStatusRestoring
+	return nil
+}
+
+func TestRestore(t *testing.T) {
+	online := &restoreObject{Object: "online", status: fs.RestoreStatusOnline}
+	archived := &restoreObject{Object: "archived", archived: true, status: fs.RestoreStatusArchived}
+	restored := &restoreObject{Object: "restored", archived: true, status: fs.RestoreStatusRestored}
+	f := &restoreFs{entries: fs.DirEntries{archived, online, restored}}
+
+	require.NoError(t, Restore(f, "", 1, 0))
+
+	// Objects which weren't listed as archived aren't read
+	assert.Equal(t, 0, online.statusReads)
+	assert.Equal(t, 0, online.restores)
+	assert.Equal(t, 1, archived.statusReads)
+	assert.Equal(t, 1, archived.restores)
+	assert.Equal(t, 1, restored.statusReads)
+	assert.Equal(t, 0, restored.restores)
+}
@@ -26,6 +26,7 @@ import (
 	"io"
 	"io/ioutil"
 	"regexp"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -36,6 +37,7 @@ import (
 	"github.com/ncw/rclone/fs/filter"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fs/list"
+	"github.com/ncw/rclone/fs/object"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/ncw/rclone/fs/walk"
 	"github.com/ncw/rclone/fstest"
@@ -239,14 +241,24 @@ func TestDelete(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file3)
 }
 
-func testCheck(t *testing.T, checkFunction func(fdst, fsrc fs.Fs) error) {
+func testCheck(t *testing.T, checkFunction func(opt *operations.CheckOpt) error) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 
-	check := func(i int, wantErrors int64) {
+	check := func(i int, wantErrors int64, wantCombined string) {
 		fs.Debugf(r.Fremote, "%d: Starting check test", i)
 		oldErrors := accounting.Stats.GetErrors()
-		err := checkFunction(r.Flocal, r.Fremote)
+		var combined, missingOnSrc, missingOnDst, match, differ bytes.Buffer
+		opt := operations.CheckOpt{
+			Fdst:         r.Flocal,
+			Fsrc:         r.Fremote,
+			Combined:     &combined,
+			MissingOnSrc: &missingOnSrc,
+			MissingOnDst: &missingOnDst,
+			Match:        &match,
+			Differ:       &differ,
+		}
+		err := checkFunction(&opt)
 		gotErrors := accounting.Stats.GetErrors() - oldErrors
 		if wantErrors == 0 && err != nil {
 			t.Errorf("%d: Got error when not expecting one: %v", i, err)
@@ -257,21 +269,43 @@ func testCheck(t *testing.T, checkFunction func(fdst, fsrc fs.Fs) error) {
 		if wantErrors != gotErrors {
 			t.Errorf("%d: Expecting %d errors but got %d", i, wantErrors, gotErrors)
 		}
+		sortLines := func(in string) []string {
+			lines := strings.Split(in, "\n")
+			sort.Strings(lines)
+			return lines
+		}
+		assert.Equal(t, sortLines(wantCombined), sortLines(combined.String()), fmt.Sprintf("%d: combined", i))
+		// the other outputs should be the lines of combined with
+		// their sigils removed
+		for sigil, out := range map[string]*bytes.Buffer{
+			"- ": &missingOnSrc,
+			"+ ": &missingOnDst,
+			"= ": &match,
+			"* ": &differ,
+		} {
+			want := ""
+			for _, line := range strings.Split(wantCombined, "\n") {
+				if strings.HasPrefix(line, sigil) {
+					want += line[len(sigil):] + "\n"
+				}
+			}
+			assert.Equal(t, sortLines(want), sortLines(out.String()), fmt.Sprintf("%d: %q", i, sigil))
+		}
 		fs.Debugf(r.Fremote, "%d: Ending check test", i)
 	}
 
 	file1 := r.WriteBoth("rutabaga", "is tasty", t3)
 	fstest.CheckItems(t, r.Fremote, file1)
 	fstest.CheckItems(t, r.Flocal, file1)
-	check(1, 0)
+	check(1, 0, "= rutabaga\n")
 
 	file2 := r.WriteFile("potato2", "------------------------------------------------------------", t1)
 	fstest.CheckItems(t, r.Flocal, file1, file2)
-	check(2, 1)
+	check(2, 1, "= rutabaga\n- potato2\n")
 
 	file3 := r.WriteObject("empty space", "", t2)
 	fstest.CheckItems(t, r.Fremote, file1, file3)
-	check(3, 2)
+	check(3, 2, "= rutabaga\n- potato2\n+ empty space\n")
 
 	file2r := file2
 	if fs.Config.SizeOnly {
@@ -280,11 +314,15 @@ func testCheck(t *testing.T, checkFunction func(fdst, fsrc fs.Fs) error) {
 		r.WriteObject("potato2", "------------------------------------------------------------", t1)
 	}
 	fstest.CheckItems(t, r.Fremote, file1, file2r, file3)
-	check(4, 1)
+	check(4, 1, "= rutabaga\n= potato2\n+ empty space\n")
+
+	file3l := r.WriteFile("empty space", "", t2)
+	fstest.CheckItems(t, r.Flocal, file1, file2, file3l)
+	check(5, 0, "= rutabaga\n= potato2\n= empty space\n")
 
-	r.WriteFile("empty space", "", t2)
-	fstest.CheckItems(t, r.Flocal, file1, file2, file3)
-	check(5, 0)
+	file2r = r.WriteObject("potato2", "------------------------------------------------------------!", t1)
+	fstest.CheckItems(t, r.Fremote, file1, file2r, file3)
+	check(6, 1, "= rutabaga\n* potato2\n= empty space\n")
 }
 
 func TestCheck(t *testing.T) {
@@ -534,6 +572,28 @@ func TestRcat(t *testing.T) {
 	check(false)
 }
 
+func TestPurgeFallback(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	r.Mkdir(r.Fremote)
+
+	fpurge, err := fs.NewFs(r.FremoteName + "/purge")
+	require.NoError(t, err)
+	// Make the purge fall back to deleting the files individually
+	fpurge.Features().Purge = nil
+
+	file1 := r.WriteObject("purge/one", "aaa", t1)
+	file2 := r.WriteObject("purge/A1/two", "bbb", t2)
+	file3 := r.WriteObject("keep", "ccc", t1)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	oldDeletes := accounting.Stats.GetDeletes()
+	require.NoError(t, operations.Purge(fpurge, ""))
+	assert.Equal(t, int64(2), accounting.Stats.GetDeletes()-oldDeletes)
+
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file3}, []string{}, fs.Config.ModifyWindow)
+}
+
 func TestRmdirsNoLeaveRoot(t *testing.T) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
@@ -657,6 +717,33 @@ func TestMoveFile(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file2)
 }
 
+func TestServerSideStats(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if r.Fremote.Features().Move == nil {
+		t.Skip("Can't test server side stats without Move")
+	}
+
+	file1 := r.WriteObject("file1", "file1 contents", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+	src, err := r.Fremote.NewObject(file1.Path)
+	require.NoError(t, err)
+
+	accounting.Stats.ResetCounters()
+	_, err = operations.Move(r.Fremote, nil, "file2", src)
+	require.NoError(t, err)
+	file1.Path = "file2"
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	moves, bytes := accounting.Stats.GetServerSideMoves()
+	assert.Equal(t, int64(1), moves)
+	assert.Equal(t, file1.Size, bytes)
+	copies, _ := accounting.Stats.GetServerSideCopies()
+	assert.Equal(t, int64(0), copies)
+	assert.Equal(t, int64(0), accounting.Stats.GetBytes())
+	assert.Contains(t, accounting.Stats.String(), "Server side moves: 1 (14 Bytes)")
+}
+
 func TestCopyFile(t *testing.T) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
@@ -683,6 +770,40 @@ func TestCopyFile(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file2)
 }
 
+// unknownSizeObject is an object which doesn't know its size
+type unknownSizeObject struct {
+	*object.MemoryObject
+}
+
+// Size returns -1 as the size isn't known
+func (o unknownSizeObject) Size() int64 {
+	return -1
+}
+
+func TestCopyUnknownSize(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	oldCutoff := fs.Config.StreamingUploadCutoff
+	defer func() { fs.Config.StreamingUploadCutoff = oldCutoff }()
+	fs.Config.StreamingUploadCutoff = 8
+
+	// smaller than the cutoff
+	src := unknownSizeObject{object.NewMemoryObject("small", t1, []byte("small"))}
+	dst, err := operations.Copy(r.Fremote, nil, "small", src)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), dst.Size())
+	file1 := fstest.NewItem("small", "small", t1)
+
+	// larger than the cutoff
+	src = unknownSizeObject{object.NewMemoryObject("large", t1, []byte("large contents"))}
+	dst, err = operations.Copy(r.Fremote, nil, "large", src)
+	require.NoError(t, err)
+	assert.Equal(t, int64(14), dst.Size())
+	file2 := fstest.NewItem("large", "large contents", t1)
+
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+}
+
 // testFsInfo is for unit testing fs.Info
 type testFsInfo struct {
 	name      string
@@ -904,3 +1025,18 @@ func TestListFormat(t *testing.T) {
 		}
 	}
 }
+
+func TestGetFsInfo(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	f := r.Fremote
+	info := operations.GetFsInfo(f)
+	assert.Equal(t, f.Name(), info.Name)
+	assert.Equal(t, f.Root(), info.Root)
+	assert.Equal(t, f.String(), info.String)
+	assert.Equal(t, f.Precision(), info.Precision)
+	assert.Equal(t, len(f.Hashes().Array()), len(info.Hashes))
+	assert.Equal(t, f.Features().Copy != nil, info.Features["Copy"])
+	assert.Equal(t, f.Features().CanHaveEmptyDirectories, info.Features["CanHaveEmptyDirectories"])
+}
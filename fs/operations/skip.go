@@ -0,0 +1,74 @@
+package operations
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+)
+
+// the answers given to "do all" or "skip all" in --interactive mode
+// indexed by action
+var (
+	interactiveMu      sync.Mutex
+	interactiveAnswers = map[string]byte{}
+)
+
+// SkipDestructive should be called before every operation which
+// changes the destination, eg copying, moving, deleting or making
+// directories.  It returns true if the operation should be skipped,
+// either because --dry-run is set or because the user declined it
+// when asked with --interactive.
+//
+// subject is the object or directory being changed and action is a
+// short description of the change, eg "delete" or "make directory".
+func SkipDestructive(subject interface{}, action string) (skip bool) {
+	var flag string
+	switch {
+	case fs.Config.DryRun:
+		flag = "--dry-run"
+		skip = true
+	case fs.Config.Interactive:
+		flag = "--interactive"
+		skip = skipInteractive(subject, action)
+	default:
+		return false
+	}
+	if skip {
+		fs.Logf(subject, "Skipped %s as %s is set", action, flag)
+	}
+	return skip
+}
+
+// skipInteractive asks the user whether to do action to subject
+func skipInteractive(subject interface{}, action string) bool {
+	interactiveMu.Lock()
+	defer interactiveMu.Unlock()
+	switch interactiveAnswers[action] {
+	case '!':
+		return false
+	case 's':
+		return true
+	}
+	fmt.Printf("rclone: %s %q?\n", action, fmt.Sprint(subject))
+	answer := config.Command([]string{
+		"yYes, this is OK",
+		"nNo, skip this",
+		"sSkip all " + action + " operations with no more questions",
+		"!Do all " + action + " operations with no more questions",
+		"qExit rclone now.",
+	})
+	switch answer {
+	case 'n':
+		return true
+	case 's', '!':
+		interactiveAnswers[action] = answer
+		return answer == 's'
+	case 'q':
+		fs.Logf(nil, "Quitting rclone now")
+		os.Exit(0)
+	}
+	return false
+}
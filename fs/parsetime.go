@@ -0,0 +1,62 @@
+package fs
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Time is a time.Time which can be used as a flag
+//
+// The zero value means the time is not set
+type Time time.Time
+
+// The time formats accepted by ParseTime in the order they are tried
+var timeFormats = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ParseTime parses a time string.  It accepts RFC3339 times, eg
+// "2006-01-02T15:04:05Z07:00", and also "2006-01-02T15:04:05",
+// "2006-01-02 15:04:05" and "2006-01-02" which are read in the local
+// time zone.
+func ParseTime(s string) (time.Time, error) {
+	for _, format := range timeFormats {
+		t, err := time.ParseInLocation(format, s, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("can't parse %q as a time", s)
+}
+
+// Turn Time into a string
+func (t Time) String() string {
+	if !t.IsSet() {
+		return "unset"
+	}
+	return time.Time(t).Format(time.RFC3339Nano)
+}
+
+// IsSet returns if the time has been set
+func (t Time) IsSet() bool {
+	return !time.Time(t).IsZero()
+}
+
+// Set a Time
+func (t *Time) Set(s string) error {
+	parsed, err := ParseTime(s)
+	if err != nil {
+		return err
+	}
+	*t = Time(parsed)
+	return nil
+}
+
+// Type of the value
+func (t Time) Type() string {
+	return "time"
+}
@@ -0,0 +1,47 @@
+package fs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Check it satisfies the interface
+var _ pflag.Value = (*Time)(nil)
+
+func TestParseTime(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want time.Time
+		err  bool
+	}{
+		{"2018-05-01T10:11:12Z", time.Date(2018, 5, 1, 10, 11, 12, 0, time.UTC), false},
+		{"2018-05-01T10:11:12+01:00", time.Date(2018, 5, 1, 9, 11, 12, 0, time.UTC), false},
+		{"2018-05-01T10:11:12", time.Date(2018, 5, 1, 10, 11, 12, 0, time.Local), false},
+		{"2018-05-01 10:11:12", time.Date(2018, 5, 1, 10, 11, 12, 0, time.Local), false},
+		{"2018-05-01", time.Date(2018, 5, 1, 0, 0, 0, 0, time.Local), false},
+		{"", time.Time{}, true},
+		{"yesterday", time.Time{}, true},
+	} {
+		got, err := ParseTime(test.in)
+		if test.err {
+			require.Error(t, err, test.in)
+		} else {
+			require.NoError(t, err, test.in)
+			assert.True(t, test.want.Equal(got), test.in)
+		}
+	}
+}
+
+func TestTimeSetString(t *testing.T) {
+	var tm Time
+	assert.False(t, tm.IsSet())
+	assert.Equal(t, "unset", tm.String())
+	require.NoError(t, tm.Set("2018-05-01T10:11:12Z"))
+	assert.True(t, tm.IsSet())
+	assert.Equal(t, "2018-05-01T10:11:12Z", tm.String())
+	assert.Error(t, tm.Set("potato"))
+}
@@ -0,0 +1,51 @@
+package rc
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestRcNoop(t *testing.T) {
+	call := Get("rc/noop")
+	require.NotNil(t, call)
+	in := Params{"potato": 1}
+	out, err := call.Fn(context.Background(), in)
+	require.NoError(t, err)
+	assert.Equal(t, in, out)
+}
+
+func TestRcError(t *testing.T) {
+	call := Get("rc/error")
+	require.NotNil(t, call)
+	_, err := call.Fn(context.Background(), Params{})
+	assert.Error(t, err)
+}
+
+func TestRcMemStats(t *testing.T) {
+	call := Get("core/memstats")
+	require.NotNil(t, call)
+	out, err := call.Fn(context.Background(), nil)
+	require.NoError(t, err)
+	for _, key := range []string{"Alloc", "HeapAlloc", "HeapSys", "Sys", "NumGC"} {
+		assert.Contains(t, out, key)
+	}
+	assert.True(t, out["HeapAlloc"].(uint64) > 0)
+	assert.True(t, out["Sys"].(uint64) >= out["HeapSys"].(uint64))
+	assert.True(t, out["NumGoroutine"].(int) > 0)
+}
+
+func TestRcGc(t *testing.T) {
+	call := Get("core/gc")
+	require.NotNil(t, call)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	out, err := call.Fn(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Nil(t, out)
+	runtime.ReadMemStats(&after)
+	assert.True(t, after.NumGC > before.NumGC)
+}
@@ -0,0 +1,288 @@
+// Manage background jobs that the rc is running
+
+package rc
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// jobExpireDuration is how long finished jobs are kept for so their
+// status can be read
+const jobExpireDuration = 60 * time.Second
+
+// Job describes an asynchronous task started via the rc
+type Job struct {
+	mu        sync.Mutex
+	ID        int64     // unique ID of the job
+	StartTime time.Time // when the job started
+	EndTime   time.Time // when the job finished
+	Error     string    // error from the job or "" if none
+	Finished  bool      // set when the job has finished
+	Success   bool      // set if the job finished without error
+	Duration  float64   // how long the job ran for in seconds
+	Output    Params    // output of the job
+	Group     string    // stats group the job is accounted to
+	cancel    context.CancelFunc
+}
+
+// jobs is a list of Job items
+type jobs struct {
+	mu    sync.RWMutex
+	jobs  map[int64]*Job
+	jobID int64
+}
+
+// The global running jobs
+var running = newJobs()
+
+// newJobs makes a new job list
+func newJobs() *jobs {
+	return &jobs{
+		jobs: make(map[int64]*Job),
+	}
+}
+
+// expire removes the jobs which finished more than jobExpireDuration
+// ago
+//
+// Call with jobs.mu held
+func (jobs *jobs) expire() {
+	now := time.Now()
+	for ID, job := range jobs.jobs {
+		job.mu.Lock()
+		if job.Finished && now.Sub(job.EndTime) > jobExpireDuration {
+			delete(jobs.jobs, ID)
+		}
+		job.mu.Unlock()
+	}
+}
+
+// IDs returns the IDs of the jobs in ascending order
+func (jobs *jobs) IDs() (IDs []int64) {
+	jobs.mu.RLock()
+	defer jobs.mu.RUnlock()
+	IDs = []int64{}
+	for ID := range jobs.jobs {
+		IDs = append(IDs, ID)
+	}
+	sort.Sort(int64s(IDs))
+	return IDs
+}
+
+// Get a job with a given ID or nil if it doesn't exist
+func (jobs *jobs) Get(ID int64) *Job {
+	jobs.mu.RLock()
+	defer jobs.mu.RUnlock()
+	return jobs.jobs[ID]
+}
+
+// finish marks the job as finished
+func (job *Job) finish(out Params, err error) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.EndTime = time.Now()
+	if out == nil {
+		out = make(Params)
+	}
+	job.Output = out
+	job.Duration = job.EndTime.Sub(job.StartTime).Seconds()
+	if err != nil {
+		job.Error = err.Error()
+		job.Success = false
+	} else {
+		job.Error = ""
+		job.Success = true
+	}
+	job.Finished = true
+}
+
+// run the job until completion writing the return status
+func (job *Job) run(ctx context.Context, fn Func, in Params) {
+	defer job.cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			job.finish(nil, errors.Errorf("panic received: %v", r))
+		}
+	}()
+	job.finish(fn(ctx, in))
+}
+
+// Stop the job by cancelling the context it was started with
+//
+// This does nothing if the job has finished.
+func (job *Job) Stop() {
+	job.cancel()
+}
+
+// status returns a copy of the job status which is safe to encode
+func (job *Job) status() Params {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	out := Params{
+		"id":        job.ID,
+		"startTime": job.StartTime,
+		"finished":  job.Finished,
+		"success":   job.Success,
+		"error":     job.Error,
+		"duration":  job.Duration,
+		"output":    job.Output,
+		"group":     job.Group,
+	}
+	if job.Finished {
+		out["endTime"] = job.EndTime
+	} else {
+		out["duration"] = time.Since(job.StartTime).Seconds()
+	}
+	return out
+}
+
+// NewJob starts a new Job running fn(ctx, in) in the background. ctx
+// is cancelled when the job is stopped.
+//
+// Unless in has a _group parameter the job is put in the stats group
+// "job/ID".
+func (jobs *jobs) NewJob(fn Func, in Params) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &Job{
+		StartTime: time.Now(),
+		cancel:    cancel,
+	}
+	jobs.mu.Lock()
+	jobs.expire()
+	jobs.jobID++
+	job.ID = jobs.jobID
+	jobs.jobs[job.ID] = job
+	jobs.mu.Unlock()
+	if group, ok := in["_group"].(string); ok {
+		job.Group = group
+	} else {
+		job.Group = fmt.Sprintf("job/%d", job.ID)
+		in["_group"] = job.Group
+	}
+	go job.run(ctx, fn, in)
+	return job
+}
+
+// StartJob starts a new job running fn(ctx, in) and returns the output
+// with the ID of the job in
+func StartJob(fn Func, in Params) (Params, error) {
+	job := running.NewJob(fn, in)
+	fs.Debugf(nil, "rc: started job %d", job.ID)
+	return Params{"jobid": job.ID}, nil
+}
+
+// Run calls fn(ctx, in) returning its output, or if the "_async"
+// parameter is true starts it as a job with StartJob and returns the
+// job ID. "_async" is removed from in before fn sees it.
+//
+// On error it also returns the HTTP status to report:
+// http.StatusBadRequest if "_async" couldn't be read, otherwise
+// http.StatusInternalServerError.
+func Run(ctx context.Context, fn Func, in Params) (out Params, status int, err error) {
+	async := false
+	if _, found := in["_async"]; found {
+		async, err = in.GetBool("_async")
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		delete(in, "_async")
+	}
+	if async {
+		out, err = StartJob(fn, in)
+	} else {
+		out, err = fn(ctx, in)
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "remote control command failed")
+	}
+	return out, http.StatusOK, nil
+}
+
+// int64s sorts a slice of int64
+type int64s []int64
+
+func (s int64s) Len() int           { return len(s) }
+func (s int64s) Less(i, j int) bool { return s[i] < s[j] }
+func (s int64s) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func init() {
+	Add(Call{
+		Path:  "job/status",
+		Fn:    rcJobStatus,
+		Title: "Reads the status of a background job",
+		Help: `
+This takes the jobid returned when a command was started with
+_async=true and returns
+
+- id - the jobid
+- startTime - when the job started
+- endTime - when the job finished, if it has
+- finished - true if the job has finished
+- success - true if the job finished without an error
+- error - the error from the job or "" if none
+- duration - how long the job has run for in seconds
+- output - the output of the command as it would have been returned if
+  it had been run synchronously
+- group - the stats group the job is accounted to - see core/stats
+
+Finished jobs are forgotten about after a minute.`,
+	})
+	Add(Call{
+		Path:  "job/list",
+		Fn:    rcJobList,
+		Title: "Lists the IDs of the background jobs",
+		Help: `
+This returns the IDs of the running background jobs and those which
+finished recently as a list in the jobids response.`,
+	})
+	Add(Call{
+		Path:  "job/stop",
+		Fn:    rcJobStop,
+		Title: "Stops a running background job",
+		Help: `
+This takes the jobid of a running job and stops it.  The job finishes
+with an error once it has stopped - read its status with job/status.
+
+Stopping a job which has finished does nothing.`,
+	})
+}
+
+// Returns the status of a job
+func rcJobStatus(ctx context.Context, in Params) (out Params, err error) {
+	jobID, err := in.GetInt64("jobid")
+	if err != nil {
+		return nil, err
+	}
+	job := running.Get(jobID)
+	if job == nil {
+		return nil, errors.New("job not found")
+	}
+	return job.status(), nil
+}
+
+// Returns the IDs of the jobs
+func rcJobList(ctx context.Context, in Params) (out Params, err error) {
+	return Params{"jobids": running.IDs()}, nil
+}
+
+// Stops a job
+func rcJobStop(ctx context.Context, in Params) (out Params, err error) {
+	jobID, err := in.GetInt64("jobid")
+	if err != nil {
+		return nil, err
+	}
+	job := running.Get(jobID)
+	if job == nil {
+		return nil, errors.New("job not found")
+	}
+	job.Stop()
+	return out, nil
+}
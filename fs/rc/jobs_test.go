@@ -0,0 +1,188 @@
+package rc
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// waitForJob waits for job to finish
+func waitForJob(t *testing.T, job *Job) {
+	for i := 0; i < 100; i++ {
+		job.mu.Lock()
+		finished := job.Finished
+		job.mu.Unlock()
+		if finished {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for job to finish")
+}
+
+func TestJobs(t *testing.T) {
+	jobs := newJobs()
+	assert.Equal(t, []int64{}, jobs.IDs())
+
+	job := jobs.NewJob(func(ctx context.Context, in Params) (Params, error) {
+		return Params{"out": in["in"]}, nil
+	}, Params{"in": "potato"})
+	assert.Equal(t, int64(1), job.ID)
+	waitForJob(t, job)
+	status := job.status()
+	assert.Equal(t, true, status["finished"])
+	assert.Equal(t, true, status["success"])
+	assert.Equal(t, "", status["error"])
+	assert.Equal(t, Params{"out": "potato"}, status["output"])
+	assert.Equal(t, "job/1", status["group"])
+
+	job = jobs.NewJob(func(ctx context.Context, in Params) (Params, error) {
+		return nil, errors.New("failed")
+	}, Params{})
+	waitForJob(t, job)
+	status = job.status()
+	assert.Equal(t, false, status["success"])
+	assert.Equal(t, "failed", status["error"])
+	assert.Equal(t, Params{}, status["output"])
+
+	job = jobs.NewJob(func(ctx context.Context, in Params) (Params, error) {
+		panic("boom")
+	}, Params{"_group": "potato"})
+	waitForJob(t, job)
+	assert.Equal(t, "panic received: boom", job.status()["error"])
+	assert.Equal(t, "potato", job.status()["group"])
+
+	assert.Equal(t, []int64{1, 2, 3}, jobs.IDs())
+	assert.Equal(t, job, jobs.Get(3))
+	assert.Nil(t, jobs.Get(4))
+
+	// old jobs are expired when new ones are made
+	jobs.Get(1).EndTime = time.Now().Add(-2 * jobExpireDuration)
+	job = jobs.NewJob(func(ctx context.Context, in Params) (Params, error) {
+		return nil, nil
+	}, Params{})
+	waitForJob(t, job)
+	assert.Equal(t, []int64{2, 3, 4}, jobs.IDs())
+}
+
+func TestRcJobStatus(t *testing.T) {
+	out, err := StartJob(func(ctx context.Context, in Params) (Params, error) {
+		return in, nil
+	}, Params{"a": "b"})
+	require.NoError(t, err)
+	jobID := out["jobid"].(int64)
+	waitForJob(t, running.Get(jobID))
+
+	out, err = rcJobStatus(context.Background(), Params{"jobid": float64(jobID)})
+	require.NoError(t, err)
+	assert.Equal(t, jobID, out["id"])
+	group := fmt.Sprintf("job/%d", jobID)
+	assert.Equal(t, group, out["group"])
+	assert.Equal(t, Params{"a": "b", "_group": group}, out["output"])
+
+	out, err = rcJobList(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Contains(t, out["jobids"], jobID)
+
+	_, err = rcJobStatus(context.Background(), Params{"jobid": "-1"})
+	assert.EqualError(t, err, "job not found")
+	_, err = rcJobStatus(context.Background(), Params{})
+	assert.Error(t, err)
+}
+
+func TestRcJobStop(t *testing.T) {
+	out, err := StartJob(func(ctx context.Context, in Params) (Params, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, Params{})
+	require.NoError(t, err)
+	jobID := out["jobid"].(int64)
+	job := running.Get(jobID)
+	assert.Equal(t, false, job.status()["finished"])
+
+	_, err = rcJobStop(context.Background(), Params{"jobid": float64(jobID)})
+	require.NoError(t, err)
+	waitForJob(t, job)
+	assert.Equal(t, false, job.status()["success"])
+	assert.Equal(t, context.Canceled.Error(), job.status()["error"])
+
+	// stopping a finished job does nothing
+	_, err = rcJobStop(context.Background(), Params{"jobid": float64(jobID)})
+	require.NoError(t, err)
+
+	_, err = rcJobStop(context.Background(), Params{"jobid": "-1"})
+	assert.EqualError(t, err, "job not found")
+}
+
+func TestRun(t *testing.T) {
+	fn := func(ctx context.Context, in Params) (Params, error) {
+		if _, found := in["_async"]; found {
+			return nil, errors.New("_async not removed")
+		}
+		if in["fail"] == true {
+			return nil, errors.New("failed")
+		}
+		return Params{"ok": true}, nil
+	}
+
+	out, status, err := Run(context.Background(), fn, Params{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, Params{"ok": true}, out)
+
+	out, status, err = Run(context.Background(), fn, Params{"_async": "false"})
+	require.NoError(t, err)
+	assert.Equal(t, Params{"ok": true}, out)
+
+	_, status, err = Run(context.Background(), fn, Params{"fail": true})
+	assert.EqualError(t, err, "remote control command failed: failed")
+	assert.Equal(t, http.StatusInternalServerError, status)
+
+	_, status, err = Run(context.Background(), fn, Params{"_async": "potato"})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	out, status, err = Run(context.Background(), fn, Params{"_async": true})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	job := running.Get(out["jobid"].(int64))
+	require.NotNil(t, job)
+	waitForJob(t, job)
+	assert.Equal(t, true, job.status()["success"])
+}
+
+func TestParamsGetInt64Bool(t *testing.T) {
+	in := Params{
+		"int":    17,
+		"float":  float64(3),
+		"string": "42",
+		"frac":   1.5,
+		"true":   true,
+		"false":  "false",
+		"potato": "potato",
+	}
+	for key, want := range map[string]int64{"int": 17, "float": 3, "string": 42} {
+		got, err := in.GetInt64(key)
+		require.NoError(t, err, key)
+		assert.Equal(t, want, got, key)
+	}
+	for _, key := range []string{"frac", "potato", "true", "missing"} {
+		_, err := in.GetInt64(key)
+		assert.Error(t, err, key)
+	}
+	for key, want := range map[string]bool{"true": true, "false": false} {
+		got, err := in.GetBool(key)
+		require.NoError(t, err, key)
+		assert.Equal(t, want, got, key)
+	}
+	for _, key := range []string{"int", "potato", "missing"} {
+		_, err := in.GetBool(key)
+		assert.Error(t, err, key)
+	}
+}
@@ -0,0 +1,170 @@
+// Read and set the global options
+
+package rc
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"golang.org/x/net/context"
+)
+
+// Options which can't be changed with options/set because they are
+// only read when rclone starts or need processing when they are
+// parsed.
+var readOnlyOptions = map[string]bool{
+	"ask-password":       true,
+	"bind":               true,
+	"bwlimit":            true, // use core/bwlimit instead
+	"config":             true,
+	"cpuprofile":         true,
+	"delete-after":       true,
+	"delete-before":      true,
+	"delete-during":      true,
+	"delete-excluded":    true,
+	"disable":            true,
+	"dump-bodies":        true,
+	"dump-headers":       true,
+	"exclude":            true,
+	"exclude-from":       true,
+	"exclude-if-present": true,
+	"files-from":         true,
+	"filter":             true,
+	"filter-from":        true,
+	"include":            true,
+	"include-from":       true,
+	"log-file":           true,
+	"max-age":            true,
+	"max-size":           true,
+	"memprofile":         true,
+	"min-age":            true,
+	"min-size":           true,
+	"no-traverse":        true,
+	"quiet":              true,
+	"reference-time":     true,
+	"stats":              true,
+	"stats-unit":         true,
+	"syslog":             true,
+	"syslog-facility":    true,
+	"tpslimit":           true,
+	"tpslimit-burst":     true,
+	"verbose":            true,
+}
+
+// optionReadOnly returns whether the option called name can't be set
+func optionReadOnly(name string) bool {
+	return readOnlyOptions[name] || name == "rc" || strings.HasPrefix(name, "rc-")
+}
+
+func init() {
+	Add(Call{
+		Path:  "options/get",
+		Fn:    rcOptionsGet,
+		Title: "Get all the global options",
+		Help: `
+This returns all the global options in the options response as a map
+of option name (without the leading --) to
+
+- type - the type of the option, eg "string", "int", "bool", "Duration"
+- default - the default value
+- value - the current value
+- help - the help text
+- readOnly - true if the option can't be changed with options/set
+
+The values are all returned as strings in the same format they would
+be given on the command line.`,
+	})
+	Add(Call{
+		Path:  "options/set",
+		Fn:    rcOptionsSet,
+		Title: "Set global options",
+		Help: `
+This takes option names (without the leading --) and their new values
+as parameters, eg
+
+    rclone rc options/set transfers=8 checksum=true
+
+Values are parsed in the same way as on the command line.  If any of
+the options don't exist, are readOnly (see options/get) or can't be
+parsed then nothing is changed.
+
+The new values are used by operations which start after the call -
+operations which are already running may or may not see them.  It is
+best not to change options while a transfer is in progress as the
+running operations read them without any locking.`,
+	})
+}
+
+// Get the options
+func rcOptionsGet(ctx context.Context, in Params) (out Params, err error) {
+	options := Params{}
+	pflag.CommandLine.VisitAll(func(flag *pflag.Flag) {
+		options[flag.Name] = Params{
+			"type":     flag.Value.Type(),
+			"default":  flag.DefValue,
+			"value":    flag.Value.String(),
+			"help":     flag.Usage,
+			"readOnly": optionReadOnly(flag.Name),
+		}
+	})
+	return Params{
+		"options": options,
+	}, nil
+}
+
+// optionsMu serialises calls to options/set
+//
+// Note that this doesn't protect the readers of the options (eg
+// fs.Config) which read them without any locking.
+var optionsMu sync.Mutex
+
+// optionValue formats value as it would be given on the command line
+func optionValue(value interface{}) string {
+	switch x := value.(type) {
+	case float64:
+		// JSON numbers are decoded as float64 - don't use
+		// exponent notation for large ones
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// Set the options
+func rcOptionsSet(ctx context.Context, in Params) (out Params, err error) {
+	optionsMu.Lock()
+	defer optionsMu.Unlock()
+	names := make([]string, 0, len(in))
+	for name := range in {
+		flag := pflag.CommandLine.Lookup(name)
+		if flag == nil {
+			return nil, errors.Errorf("unknown option %q", name)
+		}
+		if optionReadOnly(name) {
+			return nil, errors.Errorf("option %q can't be set while rclone is running", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// pflag may overwrite the value even if it fails to parse so
+	// put back all the old values on error
+	var oldValues []string
+	for _, name := range names {
+		flag := pflag.CommandLine.Lookup(name)
+		oldValues = append(oldValues, flag.Value.String())
+		value := optionValue(in[name])
+		err = flag.Value.Set(value)
+		if err != nil {
+			for i, oldValue := range oldValues {
+				_ = pflag.CommandLine.Lookup(names[i]).Value.Set(oldValue)
+			}
+			return nil, errors.Wrapf(err, "failed to set option %q to %q", name, value)
+		}
+		flag.Changed = true
+	}
+	return out, nil
+}
@@ -0,0 +1,64 @@
+package rc
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+var (
+	testOptionString = pflag.String("rc-test-option-string", "potato", "Test option")
+	testOptionInt    = pflag.Int("test-option-int", 17, "Test option")
+)
+
+func TestOptionsGet(t *testing.T) {
+	out, err := rcOptionsGet(context.Background(), nil)
+	require.NoError(t, err)
+	options := out["options"].(Params)
+	assert.Equal(t, Params{
+		"type":     "int",
+		"default":  "17",
+		"value":    "17",
+		"help":     "Test option",
+		"readOnly": false,
+	}, options["test-option-int"])
+	assert.Equal(t, true, options["rc-test-option-string"].(Params)["readOnly"])
+}
+
+func TestOptionsSet(t *testing.T) {
+	defer func() {
+		*testOptionInt = 17
+	}()
+
+	_, err := rcOptionsSet(context.Background(), Params{"test-option-int": 42.0})
+	require.NoError(t, err)
+	assert.Equal(t, 42, *testOptionInt)
+
+	_, err = rcOptionsSet(context.Background(), Params{"test-option-int": "43"})
+	require.NoError(t, err)
+	assert.Equal(t, 43, *testOptionInt)
+
+	// Large numbers from JSON aren't formatted with an exponent
+	_, err = rcOptionsSet(context.Background(), Params{"test-option-int": 16777216.0})
+	require.NoError(t, err)
+	assert.Equal(t, 16777216, *testOptionInt)
+
+	_, err = rcOptionsSet(context.Background(), Params{"test-option-int": "43"})
+	require.NoError(t, err)
+
+	_, err = rcOptionsSet(context.Background(), Params{"test-option-int": "potato"})
+	assert.Error(t, err)
+	assert.Equal(t, 43, *testOptionInt)
+
+	_, err = rcOptionsSet(context.Background(), Params{"test-option-int": 1, "not-an-option": "1"})
+	assert.Error(t, err)
+	assert.Equal(t, 43, *testOptionInt)
+
+	_, err = rcOptionsSet(context.Background(), Params{"test-option-int": 1, "rc-test-option-string": "carrot"})
+	assert.Error(t, err)
+	assert.Equal(t, 43, *testOptionInt)
+	assert.Equal(t, "potato", *testOptionString)
+}
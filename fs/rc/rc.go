@@ -10,11 +10,13 @@ package rc
 import (
 	"encoding/json"
 	"net/http"
+	_ "net/http/pprof" // install the pprof http handlers
 	"strings"
 
 	"github.com/ncw/rclone/cmd/serve/httplib"
 	"github.com/ncw/rclone/fs"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // Options contains options for the remote control server
@@ -41,6 +43,13 @@ func Start(opt *Options) {
 	}
 }
 
+// Serve runs the remote control server in the foreground - it doesn't
+// return
+func Serve(opt *Options) {
+	s := newServer(opt)
+	s.serve()
+}
+
 // server contains everything to run the server
 type server struct {
 	srv *httplib.Server
@@ -48,6 +57,7 @@ type server struct {
 
 func newServer(opt *Options) *server {
 	// Serve on the DefaultServeMux so can have global registrations appear
+	// such as the /debug/pprof/ handlers
 	mux := http.DefaultServeMux
 	s := &server{
 		srv: httplib.NewServer(mux, &opt.HTTPOptions),
@@ -117,9 +127,9 @@ func (s *server) handler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	fs.Debugf(nil, "rc: %q: with parameters %+v", path, in)
-	out, err := call.Fn(in)
+	out, status, err := Run(context.Background(), call.Fn, in)
 	if err != nil {
-		writeError(errors.Wrap(err, "remote control command failed"), http.StatusInternalServerError)
+		writeError(err, status)
 		return
 	}
 
@@ -3,17 +3,88 @@
 package rc
 
 import (
+	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // Params is the input and output type for the Func
 type Params map[string]interface{}
 
+// GetString gets the string parameter key from the input, returning
+// an error if it is missing or not a string.
+func (p Params) GetString(key string) (string, error) {
+	value, ok := p[key]
+	if !ok {
+		return "", errors.Errorf("didn't find key %q in input", key)
+	}
+	str, ok := value.(string)
+	if !ok {
+		return "", errors.Errorf("value must be string %q=%v", key, value)
+	}
+	return str, nil
+}
+
+// GetInt64 gets the integer parameter key from the input, returning
+// an error if it is missing or not an integer.
+//
+// It accepts JSON numbers and strings.
+func (p Params) GetInt64(key string) (int64, error) {
+	value, ok := p[key]
+	if !ok {
+		return 0, errors.Errorf("didn't find key %q in input", key)
+	}
+	switch x := value.(type) {
+	case int:
+		return int64(x), nil
+	case int64:
+		return x, nil
+	case float64:
+		if x != float64(int64(x)) {
+			return 0, errors.Errorf("value must be an integer %q=%v", key, value)
+		}
+		return int64(x), nil
+	case string:
+		i, err := strconv.ParseInt(x, 10, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "couldn't parse %q as integer", key)
+		}
+		return i, nil
+	}
+	return 0, errors.Errorf("value must be an integer %q=%v", key, value)
+}
+
+// GetBool gets the boolean parameter key from the input, returning
+// an error if it is missing or not a boolean.
+//
+// It accepts JSON booleans and strings like "true" and "false".
+func (p Params) GetBool(key string) (bool, error) {
+	value, ok := p[key]
+	if !ok {
+		return false, errors.Errorf("didn't find key %q in input", key)
+	}
+	switch x := value.(type) {
+	case bool:
+		return x, nil
+	case string:
+		b, err := strconv.ParseBool(x)
+		if err != nil {
+			return false, errors.Wrapf(err, "couldn't parse %q as boolean", key)
+		}
+		return b, nil
+	}
+	return false, errors.Errorf("value must be a boolean %q=%v", key, value)
+}
+
 // Func defines a type for a remote control function
-type Func func(in Params) (out Params, err error)
+//
+// ctx is cancelled if the call is stopped, eg by job/stop, so long
+// running functions should pass it on or check it.
+type Func func(ctx context.Context, in Params) (out Params, err error)
 
 // Call defines info about a remote control function and is used in
 // the Add function to create new entry points.
@@ -71,3 +142,9 @@ var registry = NewRegistry()
 func Add(call Call) {
 	registry.add(call)
 }
+
+// Get returns the function registered at path or nil if there isn't
+// one
+func Get(path string) *Call {
+	return registry.get(strings.Trim(path, "/"))
+}
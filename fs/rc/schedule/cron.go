@@ -0,0 +1,182 @@
+// Parse and match cron expressions
+
+package schedule
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cronField describes the range of one field of a cron expression
+type cronField struct {
+	name     string
+	min, max int
+}
+
+// The fields of a cron expression in order
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 0 and 7 are both Sunday
+}
+
+// Shortcuts for common expressions
+var cronShortcuts = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronSpec is a parsed cron expression
+//
+// Each field is a bitmask of the values which match.
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool // set if the field started with *
+}
+
+// parseCron parses a standard 5 field cron expression
+//
+//     minute hour day-of-month month day-of-week
+//
+// Each field may be *, a number, a range a-b, or a list of those
+// separated by commas.  * and ranges may be followed by /step.  The
+// shortcuts @hourly, @daily, @weekly, @monthly and @yearly are also
+// accepted.
+func parseCron(expr string) (*cronSpec, error) {
+	if shortcut, ok := cronShortcuts[strings.TrimSpace(expr)]; ok {
+		expr = shortcut
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, errors.Errorf("cron expression %q must have %d fields", expr, len(cronFields))
+	}
+	var masks [5]uint64
+	for i, field := range fields {
+		mask, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "cron expression %q", expr)
+		}
+		masks[i] = mask
+	}
+	// Sunday may be 0 or 7
+	if masks[4]&(1<<7) != 0 {
+		masks[4] |= 1
+	}
+	return &cronSpec{
+		minute:  masks[0],
+		hour:    masks[1],
+		dom:     masks[2],
+		month:   masks[3],
+		dow:     masks[4],
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parseCronField parses a single field returning the bitmask of
+// values which match
+func parseCronField(field string, f cronField) (mask uint64, err error) {
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.IndexRune(part, '/'); i >= 0 {
+			rangePart = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, errors.Errorf("bad step in %s %q", f.name, part)
+			}
+		}
+		var start, end int
+		switch {
+		case rangePart == "*":
+			start, end = f.min, f.max
+		case strings.ContainsRune(rangePart, '-'):
+			i := strings.IndexRune(rangePart, '-')
+			start, err = strconv.Atoi(rangePart[:i])
+			if err == nil {
+				end, err = strconv.Atoi(rangePart[i+1:])
+			}
+			if err != nil {
+				return 0, errors.Errorf("bad range in %s %q", f.name, part)
+			}
+		default:
+			start, err = strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, errors.Errorf("bad number in %s %q", f.name, part)
+			}
+			end = start
+			if step != 1 {
+				// a/n means from a to the max in steps of n
+				end = f.max
+			}
+		}
+		if start < f.min || end > f.max || start > end {
+			return 0, errors.Errorf("%s %q out of range %d-%d", f.name, part, f.min, f.max)
+		}
+		for v := start; v <= end; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+// has returns whether bit v is set in mask
+func has(mask uint64, v int) bool {
+	return mask&(1<<uint(v)) != 0
+}
+
+// matches returns true if the minute containing t matches the
+// expression
+//
+// As in the standard cron, if both day of month and day of week are
+// restricted then a time matches if either of them do.
+func (c *cronSpec) matches(t time.Time) bool {
+	return has(c.minute, t.Minute()) &&
+		has(c.hour, t.Hour()) &&
+		has(c.month, int(t.Month())) &&
+		c.matchesDay(t)
+}
+
+// next returns the start of the first minute after t which matches
+// the expression, or the zero time if there isn't one within 5 years
+func (c *cronSpec) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(5, 0, 0)
+	for t.Before(end) {
+		switch {
+		case !has(c.month, int(t.Month())):
+			// skip to the start of the next month
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.matchesDay(t):
+			// skip to the start of the next day
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !has(c.hour, t.Hour()):
+			// skip to the start of the next hour
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !has(c.minute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matchesDay returns whether the day containing t matches
+func (c *cronSpec) matchesDay(t time.Time) bool {
+	domMatch := has(c.dom, t.Day())
+	dowMatch := has(c.dow, int(t.Weekday()))
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,94 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron(t *testing.T) {
+	for _, test := range []struct {
+		expr    string
+		wantErr bool
+		minute  uint64
+		dow     uint64
+	}{
+		{expr: "* * * * *", minute: 1<<60 - 1, dow: 1<<8 - 1},
+		{expr: "0 2 * * *", minute: 1, dow: 1<<8 - 1},
+		{expr: "*/15 * * * 1-5", minute: 1 | 1<<15 | 1<<30 | 1<<45, dow: 0x3e},
+		{expr: "5,10-12 * * * 7", minute: 1<<5 | 1<<10 | 1<<11 | 1<<12, dow: 1 | 1<<7},
+		{expr: "30/10 * * * 0", minute: 1<<30 | 1<<40 | 1<<50, dow: 1},
+		{expr: "@hourly", minute: 1, dow: 1<<8 - 1},
+		{expr: "* * * *", wantErr: true},
+		{expr: "60 * * * *", wantErr: true},
+		{expr: "* 24 * * *", wantErr: true},
+		{expr: "* * 0 * *", wantErr: true},
+		{expr: "5-1 * * * *", wantErr: true},
+		{expr: "*/0 * * * *", wantErr: true},
+		{expr: "a * * * *", wantErr: true},
+		{expr: "1-a * * * *", wantErr: true},
+	} {
+		spec, err := parseCron(test.expr)
+		if test.wantErr {
+			assert.Error(t, err, test.expr)
+			continue
+		}
+		require.NoError(t, err, test.expr)
+		assert.Equal(t, test.minute, spec.minute, test.expr)
+		assert.Equal(t, test.dow, spec.dow, test.expr)
+	}
+}
+
+func TestCronMatches(t *testing.T) {
+	// 2018-03-05 is a Monday
+	monday := time.Date(2018, 3, 5, 2, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		expr string
+		t    time.Time
+		want bool
+	}{
+		{"0 2 * * *", monday, true},
+		{"0 2 * * *", monday.Add(time.Minute), false},
+		{"0 2 * * *", monday.Add(30 * time.Second), true},
+		{"0 2 * * 1", monday, true},
+		{"0 2 * * 2", monday, false},
+		{"0 2 5 3 *", monday, true},
+		{"0 2 5 4 *", monday, false},
+		// day of month and day of week both restricted means either
+		{"0 2 1 * 1", monday, true},
+		{"0 2 5 * 2", monday, true},
+		{"0 2 1 * 2", monday, false},
+		// */n counts as unrestricted
+		{"0 2 */2 * 2", monday, false},
+	} {
+		spec, err := parseCron(test.expr)
+		require.NoError(t, err)
+		assert.Equal(t, test.want, spec.matches(test.t), "%s at %v", test.expr, test.t)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	start := time.Date(2018, 3, 5, 2, 0, 30, 0, time.UTC)
+	for _, test := range []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2018, 3, 5, 2, 1, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2018, 3, 6, 2, 0, 0, 0, time.UTC)},
+		{"30 4 * * *", time.Date(2018, 3, 5, 4, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2018, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2018, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	} {
+		spec, err := parseCron(test.expr)
+		require.NoError(t, err)
+		got := spec.next(start)
+		assert.True(t, test.want.Equal(got), "%s: want %v got %v", test.expr, test.want, got)
+		if !got.IsZero() {
+			assert.True(t, spec.matches(got), test.expr)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+// Package schedule runs sync jobs on a timetable for the remote
+// control server.
+package schedule
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Unknwon/goconfig"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/cache"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/ncw/rclone/fs/notify"
+	"github.com/ncw/rclone/fs/rc"
+	fssync "github.com/ncw/rclone/fs/sync"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// Flags
+var (
+	scheduleFile = flags.StringP("rc-schedule", "", "", "Load jobs for the remote control scheduler from this file.")
+)
+
+// commands which a job can run
+var commands = map[string]func(fdst, fsrc fs.Fs) error{
+	"sync": fssync.Sync,
+	"copy": fssync.CopyDir,
+	"move": func(fdst, fsrc fs.Fs) error {
+		return fssync.MoveDir(fdst, fsrc, false)
+	},
+}
+
+// job is a command to run on a timetable
+type job struct {
+	name    string
+	cron    string
+	command string
+	src     string
+	dst     string
+	spec    *cronSpec
+
+	// status - protected by scheduler.mu
+	running    bool
+	runs       int
+	lastStart  time.Time
+	lastEnd    time.Time
+	lastError  string
+	replacedBy *job // set if the job was replaced while running
+}
+
+// newJob makes a new job checking the parameters
+func newJob(name, cron, command, src, dst string) (*job, error) {
+	if name == "" {
+		return nil, errors.New("job needs a name")
+	}
+	if _, ok := commands[command]; !ok {
+		return nil, errors.Errorf("job %q: unknown command %q - must be sync, copy or move", name, command)
+	}
+	if src == "" || dst == "" {
+		return nil, errors.Errorf("job %q: needs a source and a destination", name)
+	}
+	spec, err := parseCron(cron)
+	if err != nil {
+		return nil, errors.Wrapf(err, "job %q", name)
+	}
+	return &job{
+		name:    name,
+		cron:    cron,
+		command: command,
+		src:     src,
+		dst:     dst,
+		spec:    spec,
+	}, nil
+}
+
+// do runs the command of the job
+//
+// The remotes are kept in the Fs cache so they don't need to be
+// initialised again each time the job runs.
+func (j *job) do() error {
+	fsrc, err := cache.Get(j.src)
+	if err != nil {
+		return errors.Wrapf(err, "failed to make source %q", j.src)
+	}
+	fdst, err := cache.Get(j.dst)
+	if err != nil {
+		return errors.Wrapf(err, "failed to make destination %q", j.dst)
+	}
+	return commands[j.command](fdst, fsrc)
+}
+
+// scheduler holds the jobs and runs them when they are due
+type scheduler struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+	wg   sync.WaitGroup // for running jobs
+}
+
+// newScheduler makes an empty scheduler
+func newScheduler() *scheduler {
+	return &scheduler{
+		jobs: make(map[string]*job),
+	}
+}
+
+// The global scheduler used by the rc
+var global = newScheduler()
+
+// Start loads the jobs from the --rc-schedule file if set and starts
+// running jobs when they are due.
+func Start() error {
+	if *scheduleFile != "" {
+		err := global.load(*scheduleFile)
+		if err != nil {
+			return err
+		}
+	}
+	go global.run()
+	return nil
+}
+
+// load reads jobs from an ini style file with one section per job
+//
+//     [name]
+//     cron = 0 2 * * *
+//     command = sync
+//     src = /path/to/source
+//     dst = remote:backup
+func (s *scheduler) load(path string) error {
+	c, err := goconfig.LoadConfigFile(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to load schedule")
+	}
+	for _, name := range c.GetSectionList() {
+		if name == goconfig.DEFAULT_SECTION {
+			continue
+		}
+		j, err := newJob(name,
+			c.MustValue(name, "cron"),
+			c.MustValue(name, "command"),
+			c.MustValue(name, "src"),
+			c.MustValue(name, "dst"),
+		)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load schedule from %q", path)
+		}
+		s.add(j)
+	}
+	return nil
+}
+
+// add adds j replacing any job with the same name
+//
+// The status of the job being replaced is carried over so that if it
+// is still running j won't be started until that run finishes.
+func (s *scheduler) add(j *job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.jobs[j.name]; ok {
+		j.running = old.running
+		j.runs = old.runs
+		j.lastStart = old.lastStart
+		j.lastEnd = old.lastEnd
+		j.lastError = old.lastError
+		if old.running {
+			old.replacedBy = j
+		}
+	}
+	s.jobs[j.name] = j
+	fs.Infof(nil, "schedule: %q: %s %q to %q at %q", j.name, j.command, j.src, j.dst, j.cron)
+}
+
+// remove removes the job called name returning whether it was found
+//
+// If the job is running it runs to completion.
+func (s *scheduler) remove(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, found := s.jobs[name]
+	delete(s.jobs, name)
+	return found
+}
+
+// run starts the jobs which are due at the start of every minute
+func (s *scheduler) run() {
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		time.Sleep(next.Sub(now))
+		s.tick(next)
+	}
+}
+
+// tick starts all the jobs due at t
+func (s *scheduler) tick(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if j.spec.matches(t) {
+			s.start(j)
+		}
+	}
+}
+
+// start runs j in the background unless it is already running,
+// returning whether it was started.
+//
+// Call with s.mu held.
+func (s *scheduler) start(j *job) bool {
+	if j.running {
+		fs.Logf(nil, "schedule: %q: not starting as the previous run is still in progress", j.name)
+		return false
+	}
+	j.running = true
+	j.lastStart = time.Now()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		fs.Logf(nil, "schedule: %q: starting %s %q to %q", j.name, j.command, j.src, j.dst)
+		err := j.do()
+		notify.Send(notify.NewSummary(j.name, []string{j.command, j.src, j.dst}, j.lastStart, err))
+		s.mu.Lock()
+		// record the status on the job which replaced this one, if any
+		cur := j
+		for cur.replacedBy != nil {
+			cur = cur.replacedBy
+		}
+		j.replacedBy = nil
+		cur.running = false
+		cur.runs++
+		cur.lastEnd = time.Now()
+		cur.lastError = ""
+		if err != nil {
+			cur.lastError = err.Error()
+		}
+		duration := cur.lastEnd.Sub(cur.lastStart)
+		s.mu.Unlock()
+		if err != nil {
+			fs.Errorf(nil, "schedule: %q: failed after %v: %v", j.name, duration, err)
+		} else {
+			fs.Logf(nil, "schedule: %q: finished successfully after %v", j.name, duration)
+		}
+	}()
+	return true
+}
+
+// status returns the status of all the jobs sorted by name
+func (s *scheduler) status() []rc.Params {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	names := make([]string, 0, len(s.jobs))
+	for name := range s.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := []rc.Params{}
+	for _, name := range names {
+		j := s.jobs[name]
+		status := rc.Params{
+			"name":      j.name,
+			"cron":      j.cron,
+			"command":   j.command,
+			"srcFs":     j.src,
+			"dstFs":     j.dst,
+			"running":   j.running,
+			"runs":      j.runs,
+			"lastError": j.lastError,
+		}
+		if !j.lastStart.IsZero() {
+			status["lastStart"] = j.lastStart
+		}
+		if !j.lastEnd.IsZero() {
+			status["lastEnd"] = j.lastEnd
+		}
+		if next := j.spec.next(now); !next.IsZero() {
+			status["nextRun"] = next
+		}
+		out = append(out, status)
+	}
+	return out
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "schedule/list",
+		Fn:    rcList,
+		Title: "List the scheduled jobs and their status",
+		Help: `
+This returns the scheduled jobs in the jobs response, each with its
+name, cron, command, srcFs and dstFs and the status of the most recent
+run: running, runs, lastStart, lastEnd, lastError and nextRun.`,
+	})
+	rc.Add(rc.Call{
+		Path:  "schedule/add",
+		Fn:    rcAdd,
+		Title: "Add a scheduled job",
+		Help: `
+This takes the following parameters
+
+- name - name of the job - replaces any existing job with this name
+- cron - when to run the job as a 5 field cron expression, eg "0 2 * * *"
+- command - one of sync, copy or move
+- srcFs - the source, eg "/home/me" or "drive:"
+- dstFs - the destination, eg "s3:backup"
+
+If the previous run of a job is still in progress when it is due again
+then that run is skipped.`,
+	})
+	rc.Add(rc.Call{
+		Path:  "schedule/remove",
+		Fn:    rcRemove,
+		Title: "Remove a scheduled job",
+		Help: `
+This takes the name of the job to remove.  If it is running it will
+run to completion.`,
+	})
+	rc.Add(rc.Call{
+		Path:  "schedule/run",
+		Fn:    rcRun,
+		Title: "Run a scheduled job now",
+		Help: `
+This takes the name of the job to run.  It returns started as false if
+the job was already running.`,
+	})
+}
+
+// List the jobs
+func rcList(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	return rc.Params{
+		"jobs": global.status(),
+	}, nil
+}
+
+// Add a job
+func rcAdd(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	var name, cron, command, src, dst string
+	for _, param := range []struct {
+		key   string
+		value *string
+	}{
+		{"name", &name},
+		{"cron", &cron},
+		{"command", &command},
+		{"srcFs", &src},
+		{"dstFs", &dst},
+	} {
+		*param.value, err = in.GetString(param.key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	j, err := newJob(name, cron, command, src, dst)
+	if err != nil {
+		return nil, err
+	}
+	global.add(j)
+	return out, nil
+}
+
+// Remove a job
+func rcRemove(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	name, err := in.GetString("name")
+	if err != nil {
+		return nil, err
+	}
+	if !global.remove(name) {
+		return nil, errors.Errorf("job %q not found", name)
+	}
+	return out, nil
+}
+
+// Run a job now
+func rcRun(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	name, err := in.GetString("name")
+	if err != nil {
+		return nil, err
+	}
+	global.mu.Lock()
+	defer global.mu.Unlock()
+	j, ok := global.jobs[name]
+	if !ok {
+		return nil, errors.Errorf("job %q not found", name)
+	}
+	return rc.Params{
+		"started": global.start(j),
+	}, nil
+}
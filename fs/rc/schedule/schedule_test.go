@@ -0,0 +1,192 @@
+package schedule
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs/notify"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestNewJob(t *testing.T) {
+	_, err := newJob("", "* * * * *", "sync", "a", "b")
+	assert.Error(t, err)
+	_, err = newJob("j", "* * * * *", "potato", "a", "b")
+	assert.Error(t, err)
+	_, err = newJob("j", "* * * * *", "copy", "", "b")
+	assert.Error(t, err)
+	_, err = newJob("j", "* * *", "copy", "a", "b")
+	assert.Error(t, err)
+	j, err := newJob("j", "@daily", "move", "a", "b")
+	require.NoError(t, err)
+	assert.Equal(t, "j", j.name)
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-schedule")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	path := filepath.Join(dir, "schedule.conf")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`
+[nightly]
+cron = 0 2 * * *
+command = sync
+src = /tmp/a
+dst = remote:b
+
+[hourly]
+cron = @hourly
+command = copy
+src = /tmp/c
+dst = remote:d
+`), 0600))
+
+	s := newScheduler()
+	require.NoError(t, s.load(path))
+	status := s.status()
+	require.Equal(t, 2, len(status))
+	assert.Equal(t, "hourly", status[0]["name"])
+	assert.Equal(t, "nightly", status[1]["name"])
+	assert.Equal(t, "0 2 * * *", status[1]["cron"])
+	assert.Equal(t, "remote:b", status[1]["dstFs"])
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("[bad]\ncommand = sync\n"), 0600))
+	assert.Error(t, s.load(path))
+}
+
+func TestRunJob(t *testing.T) {
+	src, err := ioutil.TempDir("", "rclone-schedule-src")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(src))
+	}()
+	dst, err := ioutil.TempDir("", "rclone-schedule-dst")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dst))
+	}()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(src, "file.txt"), []byte("hello"), 0600))
+
+	s := newScheduler()
+	j, err := newJob("test", "* * * * *", "copy", src, dst)
+	require.NoError(t, err)
+	s.add(j)
+
+	// Can't start a job which is already running
+	s.mu.Lock()
+	j.running = true
+	assert.False(t, s.start(j))
+	j.running = false
+	assert.True(t, s.start(j))
+	s.mu.Unlock()
+	s.wg.Wait()
+
+	data, err := ioutil.ReadFile(filepath.Join(dst, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	status := s.status()
+	require.Equal(t, 1, len(status))
+	assert.Equal(t, false, status[0]["running"])
+	assert.Equal(t, 1, status[0]["runs"])
+	assert.Equal(t, "", status[0]["lastError"])
+
+	// A job which fails records its error
+	j.src = "notfoundremote:"
+	s.mu.Lock()
+	assert.True(t, s.start(j))
+	s.mu.Unlock()
+	s.wg.Wait()
+	status = s.status()
+	assert.NotEqual(t, "", status[0]["lastError"])
+
+	assert.True(t, s.remove("test"))
+	assert.False(t, s.remove("test"))
+}
+
+func TestRcAddRemove(t *testing.T) {
+	_, err := rcAdd(context.Background(), rc.Params{"name": "rc", "cron": "* * * * *", "command": "copy", "srcFs": "a"})
+	assert.Error(t, err)
+	_, err = rcAdd(context.Background(), rc.Params{"name": "rc", "cron": "* * * * *", "command": "copy", "srcFs": "a", "dstFs": "b"})
+	require.NoError(t, err)
+	out, err := rcList(context.Background(), nil)
+	require.NoError(t, err)
+	jobs := out["jobs"].([]rc.Params)
+	require.Equal(t, 1, len(jobs))
+	assert.Equal(t, "rc", jobs[0]["name"])
+	_, err = rcRemove(context.Background(), rc.Params{"name": "rc"})
+	require.NoError(t, err)
+	_, err = rcRemove(context.Background(), rc.Params{"name": "rc"})
+	assert.Error(t, err)
+}
+
+func TestReplaceRunningJob(t *testing.T) {
+	s := newScheduler()
+	j1, err := newJob("test", "* * * * *", "copy", "notfoundremote:", "/dst")
+	require.NoError(t, err)
+	s.add(j1)
+
+	// Replacing a running job carries over its status so the
+	// replacement doesn't start until the old run has finished
+	s.mu.Lock()
+	assert.True(t, s.start(j1))
+	s.mu.Unlock()
+	j2, err := newJob("test", "0 2 * * *", "sync", "notfoundremote:", "/dst2")
+	require.NoError(t, err)
+	s.add(j2)
+	s.mu.Lock()
+	if j2.running {
+		assert.False(t, s.start(j2))
+	}
+	s.mu.Unlock()
+
+	// When the old run finishes its status is recorded on the replacement
+	s.wg.Wait()
+	status := s.status()
+	require.Equal(t, 1, len(status))
+	assert.Equal(t, "sync", status[0]["command"])
+	assert.Equal(t, false, status[0]["running"])
+	assert.Equal(t, 1, status[0]["runs"])
+	assert.NotEqual(t, "", status[0]["lastError"])
+	assert.Nil(t, j1.replacedBy)
+}
+
+func TestRunJobNotifies(t *testing.T) {
+	summaries := make(chan notify.Summary, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var summary notify.Summary
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&summary))
+		summaries <- summary
+	}))
+	defer ts.Close()
+	require.NoError(t, pflag.Set("on-finish-url", ts.URL))
+	defer func() {
+		require.NoError(t, pflag.Set("on-finish-url", ""))
+	}()
+
+	s := newScheduler()
+	j, err := newJob("nightly", "* * * * *", "copy", "notfoundremote:", "/dst")
+	require.NoError(t, err)
+	s.add(j)
+	s.mu.Lock()
+	assert.True(t, s.start(j))
+	s.mu.Unlock()
+	s.wg.Wait()
+
+	summary := <-summaries
+	assert.Equal(t, "nightly", summary.Command)
+	assert.Equal(t, []string{"copy", "notfoundremote:", "/dst"}, summary.Args)
+	assert.False(t, summary.Success)
+}
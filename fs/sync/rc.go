@@ -0,0 +1,101 @@
+package sync
+
+import (
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/cache"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+func init() {
+	for _, name := range []string{"sync", "copy", "move"} {
+		name := name
+		moveHelp := ""
+		if name == "move" {
+			moveHelp = "- deleteEmptySrcDirs - delete empty src directories if set\n"
+		}
+		rc.Add(rc.Call{
+			Path: "sync/" + name,
+			Fn: func(ctx context.Context, in rc.Params) (rc.Params, error) {
+				return rcSyncCopyMove(ctx, in, name)
+			},
+			Title: name + " a directory from source remote to destination remote",
+			Help: `
+This takes the following parameters
+
+- srcFs - a remote name string eg "drive:src" for the source
+- dstFs - a remote name string eg "drive:dst" for the destination
+` + moveHelp + `
+This returns an empty result on success.  Add _async=true to run it in
+the background as a job - see job/status.
+
+Add _group=name to account the stats for this call in a stats group
+which can be read with core/stats.  Jobs are put in the group
+"job/ID" unless _group is set.
+
+See the [` + name + ` command](/commands/rclone_` + name + `/) command for more information on the above.`,
+		})
+	}
+}
+
+// Sync/Copy/Move a file
+func rcSyncCopyMove(ctx context.Context, in rc.Params, name string) (out rc.Params, err error) {
+	group := ""
+	if _, found := in["_group"]; found {
+		group, err = in.GetString("_group")
+		if err != nil {
+			return nil, err
+		}
+	}
+	srcFs, err := getFs(in, "srcFs", group != "")
+	if err != nil {
+		return nil, err
+	}
+	dstFs, err := getFs(in, "dstFs", group != "")
+	if err != nil {
+		return nil, err
+	}
+	if group != "" {
+		accounting.NewStatsGroup(group, srcFs, dstFs)
+		defer accounting.StopStatsGroup(group)
+	}
+	switch name {
+	case "sync":
+		return nil, runSyncCopyMove(ctx, dstFs, srcFs, fs.Config.DeleteMode, false, false)
+	case "copy":
+		return nil, runSyncCopyMove(ctx, dstFs, srcFs, fs.DeleteModeOff, false, false)
+	case "move":
+		deleteEmptySrcDirs := false
+		if _, found := in["deleteEmptySrcDirs"]; found {
+			deleteEmptySrcDirs, err = in.GetBool("deleteEmptySrcDirs")
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nil, moveDirContext(ctx, dstFs, srcFs, deleteEmptySrcDirs)
+	}
+	return nil, errors.Errorf("unknown sync/copy/move command %q", name)
+}
+
+// getFs gets the remote named by the key parameter in in, using the
+// Fs cache so it doesn't need to be made again each time.
+//
+// If private is set then a new Fs is made which isn't shared with
+// anything else, so its stats can be accounted separately.
+func getFs(in rc.Params, key string, private bool) (f fs.Fs, err error) {
+	remote, err := in.GetString(key)
+	if err != nil {
+		return nil, err
+	}
+	if private {
+		f, err = fs.NewFs(remote)
+	} else {
+		f, err = cache.Get(remote)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to make %s %q", key, remote)
+	}
+	return f, nil
+}
@@ -0,0 +1,240 @@
+// Persist the state of a sync so it can be resumed
+
+package sync
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/lib/kv"
+	"github.com/pkg/errors"
+)
+
+// The resume state is kept in a kv store in this namespace.
+//
+// The header key describes the sync.  Each file queued for transfer
+// is kept under queuedPrefix until it is done, each file to be
+// deleted under deletePrefix and the complete key is set when all the
+// decisions are recorded.
+const (
+	resumeNamespace   = "sync"
+	resumeHeaderKey   = "header"
+	resumeCompleteKey = "complete"
+	queuedPrefix      = "queued/"
+	deletePrefix      = "delete/"
+)
+
+// Changes to the resume state are written together when there are
+// this many or this long has passed since they were last written
+const (
+	resumeFlushChanges  = 1000
+	resumeFlushInterval = time.Second
+)
+
+// resumeHeader describes the sync the state is for
+type resumeHeader struct {
+	Src  string `json:"src"`  // source of the sync
+	Dst  string `json:"dst"`  // destination of the sync
+	Mode string `json:"mode"` // sync, copy or move
+}
+
+// resumeChange is a change to the resume state waiting to be written
+type resumeChange struct {
+	key    string
+	remove bool // set to remove key rather than set it
+}
+
+// resumeState records the decisions made by a sync in a kv store so
+// that an interrupted sync can be resumed without listing both sides
+// again.
+//
+// All the methods may be called on a nil *resumeState in which case
+// they do nothing.
+type resumeState struct {
+	path     string
+	mu       sync.Mutex
+	db       *kv.DB
+	ns       *kv.Namespace
+	changes  []resumeChange // changes not written yet
+	flushed  time.Time      // when the changes were last written
+	failed   bool           // set if writing the state failed
+	resuming bool           // set if resuming from a previous sync
+	pending  []string       // files still to be transferred if resuming
+	deletes  []string       // files to be deleted if resuming
+}
+
+// fsString returns a string describing f for the state file
+func fsString(f fs.Fs) string {
+	return f.Name() + ":" + f.Root()
+}
+
+// newResumeState opens the state file at path.
+//
+// If it contains a complete record of a previous sync with the same
+// parameters then the returned state is set up to resume it,
+// otherwise a new state is started.
+func newResumeState(path string, fdst, fsrc fs.Fs, mode string) (*resumeState, error) {
+	header := resumeHeader{
+		Src:  fsString(fsrc),
+		Dst:  fsString(fdst),
+		Mode: mode,
+	}
+	db, err := kv.OpenPath(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open resume state")
+	}
+	r := &resumeState{
+		path:    path,
+		db:      db,
+		ns:      db.Namespace(resumeNamespace),
+		flushed: time.Now(),
+	}
+	if r.read(header) {
+		fs.Logf(nil, "Resuming %s from %q with %d files to transfer and %d to delete", mode, path, len(r.pending), len(r.deletes))
+		r.resuming = true
+		return r, nil
+	}
+	err = r.ns.Clear()
+	if err == nil {
+		err = r.ns.PutJSON(resumeHeaderKey, header)
+	}
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "failed to write resume state")
+	}
+	return r, nil
+}
+
+// read reads an existing state returning true if it is a complete
+// record of a sync matching header.
+func (r *resumeState) read(header resumeHeader) bool {
+	var saved resumeHeader
+	err := r.ns.GetJSON(resumeHeaderKey, &saved)
+	if err == kv.ErrNotFound {
+		return false
+	} else if err != nil {
+		fs.Errorf(nil, "Ignoring resume state: %v", err)
+		return false
+	}
+	if saved != header {
+		fs.Logf(nil, "Not resuming from %q as it is for a different %s", r.path, saved.Mode)
+		return false
+	}
+	complete := false
+	err = r.ns.View(func(tx *kv.Tx) error {
+		return tx.ForEach(func(key string, _ []byte) error {
+			switch {
+			case strings.HasPrefix(key, queuedPrefix):
+				r.pending = append(r.pending, key[len(queuedPrefix):])
+			case strings.HasPrefix(key, deletePrefix):
+				r.deletes = append(r.deletes, key[len(deletePrefix):])
+			case key == resumeCompleteKey:
+				complete = true
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		fs.Errorf(nil, "Ignoring resume state: %v", err)
+	} else if !complete {
+		fs.Logf(nil, "Not resuming from %q as the previous run didn't finish checking", r.path)
+	}
+	if err != nil || !complete {
+		r.pending, r.deletes = nil, nil
+		return false
+	}
+	return true
+}
+
+// write queues change to be written to the state
+func (r *resumeState) write(change resumeChange) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, change)
+	if len(r.changes) >= resumeFlushChanges || time.Since(r.flushed) >= resumeFlushInterval {
+		r._flush()
+	}
+}
+
+// _flush writes the queued changes to the state in one transaction
+//
+// call with the lock held
+func (r *resumeState) _flush() {
+	if r.failed || len(r.changes) == 0 {
+		return
+	}
+	err := r.ns.Update(func(tx *kv.Tx) error {
+		for _, change := range r.changes {
+			var err error
+			if change.remove {
+				err = tx.Delete(change.key)
+			} else {
+				err = tx.Put(change.key, []byte{1})
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	r.changes = r.changes[:0]
+	r.flushed = time.Now()
+	if err != nil {
+		fs.Errorf(nil, "Failed to write resume state - it won't be possible to resume: %v", err)
+		r.failed = true
+	}
+}
+
+// queued records that remote is going to be transferred
+func (r *resumeState) queued(remote string) {
+	r.write(resumeChange{key: queuedPrefix + remote})
+}
+
+// done records that remote was transferred successfully
+func (r *resumeState) done(remote string) {
+	r.write(resumeChange{key: queuedPrefix + remote, remove: true})
+}
+
+// delete records that remote is going to be deleted
+func (r *resumeState) delete(remote string) {
+	r.write(resumeChange{key: deletePrefix + remote})
+}
+
+// complete records that all the decisions have been recorded
+func (r *resumeState) complete() {
+	if r == nil {
+		return
+	}
+	r.write(resumeChange{key: resumeCompleteKey})
+	r.mu.Lock()
+	r._flush()
+	r.mu.Unlock()
+}
+
+// finish closes the state removing it if the sync succeeded
+func (r *resumeState) finish(err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r._flush()
+	closeErr := r.db.Close()
+	if closeErr != nil {
+		fs.Errorf(nil, "Failed to close resume state: %v", closeErr)
+	}
+	if err != nil {
+		fs.Logf(nil, "Keeping resume state %q after error: %v", r.path, err)
+		return
+	}
+	removeErr := os.Remove(r.path)
+	if removeErr != nil {
+		fs.Errorf(nil, "Failed to remove resume state: %v", removeErr)
+	}
+}
@@ -0,0 +1,141 @@
+package sync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest"
+	"github.com/ncw/rclone/lib/kv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeResumeState writes a new resume state with the keys given
+// returning its path and a function to tidy up
+func writeResumeState(t *testing.T, keys ...string) (string, func()) {
+	dir, err := ioutil.TempDir("", "rclone-resume")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "state")
+	db, err := kv.OpenPath(path)
+	require.NoError(t, err)
+	require.NoError(t, db.Namespace(resumeNamespace).Update(func(tx *kv.Tx) error {
+		for _, key := range keys {
+			if err := tx.Put(key, []byte{1}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	require.NoError(t, db.Close())
+	return path, func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+// writeResumeHeader writes the resume header for a sync of mode from
+// r.Flocal to r.Fremote to the state at path
+func writeResumeHeader(t *testing.T, r *fstest.Run, path string, mode string) {
+	db, err := kv.OpenPath(path)
+	require.NoError(t, err)
+	require.NoError(t, db.Namespace(resumeNamespace).PutJSON(resumeHeaderKey, resumeHeader{
+		Src:  fsString(r.Flocal),
+		Dst:  fsString(r.Fremote),
+		Mode: mode,
+	}))
+	require.NoError(t, db.Close())
+}
+
+// readResumeState returns the keys in the resume state at path
+func readResumeState(t *testing.T, path string) []string {
+	db, err := kv.OpenPath(path)
+	require.NoError(t, err)
+	keys, err := db.Namespace(resumeNamespace).Keys()
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	return keys
+}
+
+func TestResumeStateRead(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	path, tidy := writeResumeState(t, "queued/a", "queued/c", "delete/d", "complete")
+	defer tidy()
+	writeResumeHeader(t, r, path, "sync")
+	state, err := newResumeState(path, r.Fremote, r.Flocal, "sync")
+	require.NoError(t, err)
+	assert.True(t, state.resuming)
+	assert.Equal(t, []string{"a", "c"}, state.pending)
+	assert.Equal(t, []string{"d"}, state.deletes)
+	state.finish(nil)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	// Different parameters
+	path, tidy = writeResumeState(t, "queued/a", "complete")
+	defer tidy()
+	writeResumeHeader(t, r, path, "sync")
+	state, err = newResumeState(path, r.Fremote, r.Flocal, "copy")
+	require.NoError(t, err)
+	assert.False(t, state.resuming)
+	state.queued("y")
+	state.queued("z")
+	state.done("y")
+	state.finish(fs.ErrorNotDeleting)
+	assert.Equal(t, []string{"header", "queued/z"}, readResumeState(t, path))
+
+	// Checking didn't finish
+	path, tidy = writeResumeState(t, "queued/a")
+	defer tidy()
+	writeResumeHeader(t, r, path, "sync")
+	state, err = newResumeState(path, r.Fremote, r.Flocal, "sync")
+	require.NoError(t, err)
+	assert.False(t, state.resuming)
+	assert.Equal(t, 0, len(state.pending))
+	state.finish(nil)
+}
+
+func TestSyncResume(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("queued", "queued", t1)
+	file2 := r.WriteFile("not queued", "not queued", t1)
+	file3 := r.WriteObject("to delete", "to delete", t1)
+	file4 := r.WriteObject("not to delete", "not to delete", t1)
+	file5 := r.WriteObject("recreated", "recreated", t1)
+	// recreated in the source since the delete was recorded
+	file6 := r.WriteFile("recreated", "recreated in the source", t2)
+	fstest.CheckItems(t, r.Flocal, file1, file2, file6)
+	fstest.CheckItems(t, r.Fremote, file3, file4, file5)
+
+	path, tidy := writeResumeState(t,
+		"queued/queued",
+		"queued/gone away",
+		"delete/to delete",
+		"delete/recreated",
+		"complete",
+	)
+	defer tidy()
+	writeResumeHeader(t, r, path, "sync")
+	fs.Config.ResumeState = path
+	defer func() { fs.Config.ResumeState = "" }()
+
+	err := Sync(r.Fremote, r.Flocal)
+	require.NoError(t, err)
+
+	// Only the recorded decisions are carried out, except for
+	// deletes of files which are now in the source
+	fstest.CheckItems(t, r.Fremote, file1, file4, file6)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	// A normal sync with a resume state removes it when done
+	err = Sync(r.Fremote, r.Flocal)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file6)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
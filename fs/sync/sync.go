@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"sync/atomic"
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/cache"
 	"github.com/ncw/rclone/fs/filter"
 	"github.com/ncw/rclone/fs/fserrors"
 	"github.com/ncw/rclone/fs/hash"
@@ -22,6 +24,7 @@ type syncCopyMove struct {
 	fdst               fs.Fs
 	fsrc               fs.Fs
 	deleteMode         fs.DeleteMode // how we are doing deletions
+	deleteExcluded     bool          // set if excluded files on the destination are deleted
 	DoMove             bool
 	deleteEmptySrcDirs bool
 	dir                string
@@ -59,13 +62,26 @@ type syncCopyMove struct {
 	renameCheck    []fs.Object            // accumulate files to check for rename here
 	backupDir      fs.Fs                  // place to store overwrites/deletes
 	suffix         string                 // suffix to add to files placed in backupDir
+	excludedCount  int64                  // number of excluded files deleted - use atomic
+	checkFirst     bool                   // set if all checks are done before transfers start
+	queueWg        sync.WaitGroup         // wait for the queuer
+	queue          []fs.ObjectPair        // transfers queued until the checks are complete
+	queueSize      int64                  // total size of the queued transfers
+	resume         *resumeState           // state saved for resuming if set
+	failedMu       sync.Mutex             // protects failed
+	failed         []failedTransfer       // failed transfers to retry at the end
+	retryRound     int                    // how many times the failed transfers have been retried
+	checkerTuner   *tuner                 // limits the active checkers if --auto-tune
+	transferTuner  *tuner                 // limits the active transfers if --auto-tune
+	stats          *accounting.StatsInfo  // stats the checks and transfers are accounted to
 }
 
-func newSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool) (*syncCopyMove, error) {
+func newSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool) (*syncCopyMove, error) {
 	s := &syncCopyMove{
 		fdst:               fdst,
 		fsrc:               fsrc,
 		deleteMode:         deleteMode,
+		deleteExcluded:     filter.Active.Opt.DeleteExcluded,
 		DoMove:             DoMove,
 		deleteEmptySrcDirs: deleteEmptySrcDirs,
 		dir:                "",
@@ -79,8 +95,16 @@ func newSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 		commonHash:         fsrc.Hashes().Overlap(fdst.Hashes()).GetOne(),
 		toBeRenamed:        make(fs.ObjectPairChan, fs.Config.Transfers),
 		trackRenamesCh:     make(chan fs.Object, fs.Config.Checkers),
+		checkFirst:         fs.Config.CheckFirst,
+		checkerTuner:       newTuner("checkers", fs.Config.Checkers, accounting.Stats.GetChecks),
+		transferTuner:      newTuner("transfers", fs.Config.Transfers, accounting.Stats.GetBytes),
+		stats:              accounting.StatsFor(fdst),
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	if s.deleteExcluded && s.deleteMode == fs.DeleteModeOff {
+		fs.Errorf(fdst, "Ignoring --delete-excluded as it only works with sync")
+		s.deleteExcluded = false
 	}
-	s.ctx, s.cancel = context.WithCancel(context.Background())
 	if s.trackRenames {
 		// Don't track renames for remotes without server-side move support.
 		if !operations.CanServerSideMove(fdst) {
@@ -102,10 +126,14 @@ func newSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 			s.deleteMode = fs.DeleteModeAfter
 		}
 	}
+	if s.checkFirst && s.deleteMode == fs.DeleteModeDuring {
+		// don't delete anything until the transfers are done
+		s.deleteMode = fs.DeleteModeAfter
+	}
 	// Make Fs for --backup-dir if required
 	if fs.Config.BackupDir != "" {
 		var err error
-		s.backupDir, err = fs.NewFs(fs.Config.BackupDir)
+		s.backupDir, err = cache.Get(fs.Config.BackupDir)
 		if err != nil {
 			return nil, fserrors.FatalError(errors.Errorf("Failed to make fs for --backup-dir %q: %v", fs.Config.BackupDir, err))
 		}
@@ -123,6 +151,26 @@ func newSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 		}
 		s.suffix = fs.Config.Suffix
 	}
+	// Save the state for resuming if required
+	if fs.Config.ResumeState != "" && s.deleteMode != fs.DeleteModeOnly {
+		mode := "copy"
+		if s.DoMove {
+			mode = "move"
+		} else if s.deleteMode != fs.DeleteModeOff {
+			// deletes are recorded and done after the transfers
+			mode = "sync"
+			s.deleteMode = fs.DeleteModeAfter
+		}
+		var err error
+		s.resume, err = newResumeState(fs.Config.ResumeState, fdst, fsrc, mode)
+		if err != nil {
+			return nil, fserrors.FatalError(err)
+		}
+		if s.resume.resuming && s.trackRenames {
+			fs.Logf(fdst, "Ignoring --track-renames when resuming")
+			s.trackRenames = false
+		}
+	}
 	return s, nil
 }
 
@@ -205,7 +253,8 @@ func (s *syncCopyMove) pairChecker(in fs.ObjectPairChan, out fs.ObjectPairChan,
 				return
 			}
 			src := pair.Src
-			accounting.Stats.Checking(src.Remote())
+			s.checkerTuner.acquire()
+			s.stats.Checking(src.Remote())
 			// Check to see if can store this
 			if src.Storable() {
 				if operations.NeedTransfer(pair.Dst, pair.Src) {
@@ -224,9 +273,11 @@ func (s *syncCopyMove) pairChecker(in fs.ObjectPairChan, out fs.ObjectPairChan,
 							} else {
 								// If successful zero out the dst as it is no longer there and copy the file
 								pair.Dst = nil
+								s.resume.queued(src.Remote())
 								out <- pair
 							}
 						} else {
+							s.resume.queued(src.Remote())
 							out <- pair
 						}
 					}
@@ -238,7 +289,8 @@ func (s *syncCopyMove) pairChecker(in fs.ObjectPairChan, out fs.ObjectPairChan,
 					}
 				}
 			}
-			accounting.Stats.DoneChecking(src.Remote())
+			s.stats.DoneChecking(src.Remote())
+			s.checkerTuner.release()
 		case <-s.ctx.Done():
 			return
 		}
@@ -261,6 +313,7 @@ func (s *syncCopyMove) pairRenamer(in fs.ObjectPairChan, out fs.ObjectPairChan,
 			src := pair.Src
 			if !s.tryRename(src) {
 				// pass on if not renamed
+				s.resume.queued(src.Remote())
 				out <- pair
 			}
 		case <-s.ctx.Done():
@@ -283,14 +336,20 @@ func (s *syncCopyMove) pairCopyOrMove(in fs.ObjectPairChan, fdst fs.Fs, wg *sync
 				return
 			}
 			src := pair.Src
-			accounting.Stats.Transferring(src.Remote())
+			s.transferTuner.acquire()
+			s.stats.Transferring(src.Remote())
 			if s.DoMove {
 				_, err = operations.Move(fdst, pair.Dst, src.Remote(), src)
 			} else {
 				_, err = operations.Copy(fdst, pair.Dst, src.Remote(), src)
 			}
-			s.processError(err)
-			accounting.Stats.DoneTransferring(src.Remote(), err == nil)
+			if err == nil {
+				s.resume.done(src.Remote())
+			} else if !s.requeue(pair, err) {
+				s.processError(err)
+			}
+			s.stats.DoneTransferring(src.Remote(), err == nil)
+			s.transferTuner.release()
 		case <-s.ctx.Done():
 			return
 		}
@@ -327,6 +386,97 @@ func (s *syncCopyMove) stopTransfers() {
 	s.transfersWg.Wait()
 }
 
+// failedTransfer is a transfer which failed with err
+type failedTransfer struct {
+	pair fs.ObjectPair
+	err  error
+}
+
+// requeue saves the failed transfer pair to be retried at the end of
+// the run if --file-retries allows, returning whether it did.
+func (s *syncCopyMove) requeue(pair fs.ObjectPair, err error) bool {
+	if s.retryRound >= fs.Config.FileRetries || s.aborting() || fserrors.IsFatalError(err) || fserrors.IsNoRetryError(err) {
+		return false
+	}
+	// Don't count the errors unless the retries fail too.  If the
+	// error can't be uncounted then don't retry it, otherwise it
+	// would stay counted even if the retry succeeds.
+	if s.stats.Uncount(err) == 0 {
+		return false
+	}
+	fs.Infof(pair.Src, "Will retry transfer at the end of the run: %v", err)
+	s.failedMu.Lock()
+	s.failed = append(s.failed, failedTransfer{pair: pair, err: err})
+	s.failedMu.Unlock()
+	return true
+}
+
+// retryFailed retries the transfers which failed, up to
+// --file-retries times each.
+//
+// Call after the transfers have stopped.
+func (s *syncCopyMove) retryFailed() {
+	for len(s.failed) > 0 && !s.aborting() {
+		failed := s.failed
+		s.failed = nil
+		s.retryRound++
+		fs.Logf(s.fdst, "Retrying %d failed transfers (retry %d/%d)", len(failed), s.retryRound, fs.Config.FileRetries)
+		s.toBeUploaded = make(fs.ObjectPairChan, fs.Config.Transfers)
+		s.startTransfers()
+	outer:
+		for i, failure := range failed {
+			select {
+			case s.toBeUploaded <- failure.pair:
+			case <-s.ctx.Done():
+				// keep the ones not retried to count their errors
+				s.failedMu.Lock()
+				s.failed = append(s.failed, failed[i:]...)
+				s.failedMu.Unlock()
+				break outer
+			}
+		}
+		s.stopTransfers()
+	}
+	// Count the errors of any transfers which weren't retried
+	for _, failure := range s.failed {
+		s.stats.Error(failure.err)
+		s.processError(failure.err)
+	}
+}
+
+// This starts the background queuer which holds the transfers until
+// the checks are complete if --check-first is set.
+func (s *syncCopyMove) startQueue() {
+	s.queueWg.Add(1)
+	go func() {
+		defer s.queueWg.Done()
+		for pair := range s.toBeUploaded {
+			s.queue = append(s.queue, pair)
+			if size := pair.Src.Size(); size > 0 {
+				s.queueSize += size
+			}
+		}
+	}()
+}
+
+// This stops the background queuer, logs what is going to be
+// transferred and sends the queued transfers to the transferers.
+func (s *syncCopyMove) stopQueue() {
+	close(s.toBeUploaded)
+	s.queueWg.Wait()
+	fs.Logf(s.fdst, "Checks complete: %d files (%v) to transfer", len(s.queue), fs.SizeSuffix(s.queueSize))
+	s.toBeUploaded = make(fs.ObjectPairChan, fs.Config.Transfers)
+	s.startTransfers()
+	for _, pair := range s.queue {
+		select {
+		case s.toBeUploaded <- pair:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+	s.queue = nil
+}
+
 // This starts the background renamers.
 func (s *syncCopyMove) startRenamers() {
 	if !s.trackRenames {
@@ -399,9 +549,12 @@ func (s *syncCopyMove) stopDeleters() {
 // checkSrcMap is clear then it assumes that the any source files that
 // have been found have been removed from dstFiles already.
 func (s *syncCopyMove) deleteFiles(checkSrcMap bool) error {
-	if accounting.Stats.Errored() {
-		fs.Errorf(s.fdst, "%v", fs.ErrorNotDeleting)
-		return fs.ErrorNotDeleting
+	if s.stats.Errored() {
+		if !fs.Config.IgnoreErrors {
+			fs.Errorf(s.fdst, "%v", fs.ErrorNotDeleting)
+			return fs.ErrorNotDeleting
+		}
+		fs.Errorf(s.fdst, "Deleting files despite IO errors as --ignore-errors is set")
 	}
 
 	// Delete the spare files
@@ -430,7 +583,7 @@ func deleteEmptyDirectories(f fs.Fs, entries fs.DirEntries) error {
 	if len(entries) == 0 {
 		return nil
 	}
-	if accounting.Stats.Errored() {
+	if accounting.StatsFor(f).Errored() && !fs.Config.IgnoreErrors {
 		fs.Errorf(f, "%v", fs.ErrorNotDeletingDirs)
 		return fs.ErrorNotDeletingDirs
 	}
@@ -529,12 +682,12 @@ func (s *syncCopyMove) makeRenameMap() {
 			for obj := range in {
 				// only create hash for dst fs.Object if its size could match
 				if _, found := possibleSizes[obj.Size()]; found {
-					accounting.Stats.Checking(obj.Remote())
+					s.stats.Checking(obj.Remote())
 					hash := s.renameHash(obj)
 					if hash != "" {
 						s.pushRenameMap(hash, obj)
 					}
-					accounting.Stats.DoneChecking(obj.Remote())
+					s.stats.DoneChecking(obj.Remote())
 				}
 			}
 		}()
@@ -546,8 +699,8 @@ func (s *syncCopyMove) makeRenameMap() {
 // tryRename renames a src object when doing track renames if
 // possible, it returns true if the object was renamed.
 func (s *syncCopyMove) tryRename(src fs.Object) bool {
-	accounting.Stats.Checking(src.Remote())
-	defer accounting.Stats.DoneChecking(src.Remote())
+	s.stats.Checking(src.Remote())
+	defer s.stats.DoneChecking(src.Remote())
 
 	// Calculate the hash of the src object
 	hash := s.renameHash(src)
@@ -587,24 +740,40 @@ func (s *syncCopyMove) tryRename(src fs.Object) bool {
 // If DoMove is true then files will be moved instead of copied
 //
 // dir is the start directory, "" for root
-func (s *syncCopyMove) run() error {
+func (s *syncCopyMove) run() (err error) {
+	defer func() {
+		s.resume.finish(err)
+	}()
 	if operations.Same(s.fdst, s.fsrc) {
 		fs.Errorf(s.fdst, "Nothing to do as source and destination are the same")
 		return nil
 	}
 
 	// Start background checking and transferring pipeline
+	s.checkerTuner.start()
+	defer s.checkerTuner.finish()
+	s.transferTuner.start()
+	defer s.transferTuner.finish()
 	s.startCheckers()
 	s.startRenamers()
-	s.startTransfers()
+	if s.checkFirst {
+		s.startQueue()
+	} else {
+		s.startTransfers()
+	}
 	s.startDeleters()
 	s.dstFiles = make(map[string]fs.Object)
 
 	s.startTrackRenames()
 
-	// set up a march over fdst and fsrc
-	m := march.New(s.ctx, s.fdst, s.fsrc, s.dir, s)
-	m.Run()
+	errorsBefore := s.stats.GetErrors()
+	if s.resume != nil && s.resume.resuming {
+		s.resumeQueue()
+	} else {
+		// set up a march over fdst and fsrc
+		m := march.New(s.ctx, s.fdst, s.fsrc, s.dir, s)
+		m.Run()
+	}
 
 	s.stopTrackRenames()
 	if s.trackRenames {
@@ -619,21 +788,40 @@ func (s *syncCopyMove) run() error {
 	// Stop background checking and transferring pipeline
 	s.stopCheckers()
 	s.stopRenamers()
+	if s.resume != nil && !s.resume.resuming && !s.aborting() {
+		// All the decisions are recorded now unless there were
+		// errors which may mean some are missing
+		if s.stats.GetErrors() == errorsBefore {
+			s.resume.complete()
+		}
+	}
+	if s.checkFirst {
+		s.stopQueue()
+	}
 	s.stopTransfers()
+	s.retryFailed()
 	s.stopDeleters()
 
 	// Delete files after
 	if s.deleteMode == fs.DeleteModeAfter {
-		if s.currentError() != nil {
+		if s.currentError() != nil && !fs.Config.IgnoreErrors {
 			fs.Errorf(s.fdst, "%v", fs.ErrorNotDeleting)
 		} else {
 			s.processError(s.deleteFiles(false))
 		}
 	}
 
+	if excluded := atomic.LoadInt64(&s.excludedCount); excluded > 0 {
+		if fs.Config.DryRun {
+			fs.Logf(s.fdst, "%d files excluded by filters would be deleted by --delete-excluded", excluded)
+		} else {
+			fs.Infof(s.fdst, "%d files excluded by filters deleted by --delete-excluded", excluded)
+		}
+	}
+
 	// Prune empty directories
 	if s.deleteMode != fs.DeleteModeOff {
-		if s.currentError() != nil {
+		if s.currentError() != nil && !fs.Config.IgnoreErrors {
 			fs.Errorf(s.fdst, "%v", fs.ErrorNotDeletingDirs)
 		} else {
 			s.processError(deleteEmptyDirectories(s.fdst, s.dstEmptyDirs))
@@ -646,7 +834,61 @@ func (s *syncCopyMove) run() error {
 		//delete empty subdirectories that were part of the move
 		s.processError(deleteEmptyDirectories(s.fsrc, s.srcEmptyDirs))
 	}
-	return s.currentError()
+	err = s.currentError()
+	if err == nil && s.aborting() {
+		// stopped by the caller cancelling the context
+		err = fserrors.FatalError(s.ctx.Err())
+	}
+	return err
+}
+
+// resumeQueue sends the files recorded by a previous run to be
+// checked and transferred, and the files to be deleted to be deleted,
+// instead of marching fdst and fsrc.
+func (s *syncCopyMove) resumeQueue() {
+	for _, remote := range s.resume.pending {
+		if s.aborting() {
+			return
+		}
+		src, err := s.fsrc.NewObject(remote)
+		if err != nil {
+			fs.Logf(remote, "Not resuming transfer as can't find source: %v", err)
+			continue
+		}
+		pair := fs.ObjectPair{Src: src}
+		dst, err := s.fdst.NewObject(remote)
+		if err == nil {
+			pair.Dst = dst
+		}
+		s.toBeChecked <- pair
+	}
+	for _, remote := range s.resume.deletes {
+		if s.aborting() {
+			return
+		}
+		dst, err := s.fdst.NewObject(remote)
+		if err != nil {
+			// already deleted
+			continue
+		}
+		// The source may have changed since the delete was
+		// recorded so check it is still missing
+		src, err := s.fsrc.NewObject(remote)
+		if err == nil {
+			if filter.Active.IncludeObject(src) {
+				fs.Logf(remote, "Not resuming delete as the source now exists")
+				s.toBeChecked <- fs.ObjectPair{Src: src, Dst: dst}
+				continue
+			}
+			if !s.deleteExcluded {
+				continue
+			}
+		} else if err != fs.ErrorObjectNotFound {
+			fs.Logf(remote, "Not resuming delete as can't check source: %v", err)
+			continue
+		}
+		s.dstFiles[remote] = dst
+	}
 }
 
 // DstOnly have an object which is in the destination only
@@ -656,9 +898,11 @@ func (s *syncCopyMove) DstOnly(dst fs.DirEntry) (recurse bool) {
 	}
 	switch x := dst.(type) {
 	case fs.Object:
+		s.checkExcluded(x)
 		switch s.deleteMode {
 		case fs.DeleteModeAfter:
 			// record object as needs deleting
+			s.resume.delete(x.Remote())
 			s.dstFilesMu.Lock()
 			s.dstFiles[x.Remote()] = x
 			s.dstFilesMu.Unlock()
@@ -683,6 +927,23 @@ func (s *syncCopyMove) DstOnly(dst fs.DirEntry) (recurse bool) {
 	return false
 }
 
+// checkExcluded logs and counts dst if it is only being deleted
+// because it is excluded by the filters and --delete-excluded is set.
+//
+// This means a --dry-run shows exactly which files would go because
+// of --delete-excluded as opposed to not being in the source.
+func (s *syncCopyMove) checkExcluded(dst fs.Object) {
+	if !s.deleteExcluded || filter.Active.IncludeObject(dst) {
+		return
+	}
+	atomic.AddInt64(&s.excludedCount, 1)
+	if fs.Config.DryRun {
+		fs.Logf(dst, "Excluded by filters - would be deleted by --delete-excluded")
+	} else {
+		fs.Infof(dst, "Excluded by filters - deleting due to --delete-excluded")
+	}
+}
+
 // SrcOnly have an object which is in the source only
 func (s *syncCopyMove) SrcOnly(src fs.DirEntry) (recurse bool) {
 	if s.deleteMode == fs.DeleteModeOnly {
@@ -695,6 +956,7 @@ func (s *syncCopyMove) SrcOnly(src fs.DirEntry) (recurse bool) {
 			s.trackRenamesCh <- x
 		} else {
 			// No need to check since doesn't exist
+			s.resume.queued(x.Remote())
 			s.toBeUploaded <- fs.ObjectPair{Src: x, Dst: nil}
 		}
 	case fs.Directory:
@@ -753,7 +1015,9 @@ func (s *syncCopyMove) Match(dst, src fs.DirEntry) (recurse bool) {
 // If DoMove is true then files will be moved instead of copied
 //
 // dir is the start directory, "" for root
-func runSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool) error {
+//
+// If ctx is cancelled then the sync stops as soon as it can
+func runSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool) error {
 	if deleteMode != fs.DeleteModeOff && DoMove {
 		return fserrors.FatalError(errors.New("can't delete and move at the same time"))
 	}
@@ -763,7 +1027,7 @@ func runSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 			return fserrors.FatalError(errors.New("can't use --delete-before with --track-renames"))
 		}
 		// only delete stuff during in this pass
-		do, err := newSyncCopyMove(fdst, fsrc, fs.DeleteModeOnly, false, deleteEmptySrcDirs)
+		do, err := newSyncCopyMove(ctx, fdst, fsrc, fs.DeleteModeOnly, false, deleteEmptySrcDirs)
 		if err != nil {
 			return err
 		}
@@ -774,7 +1038,7 @@ func runSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 		// Next pass does a copy only
 		deleteMode = fs.DeleteModeOff
 	}
-	do, err := newSyncCopyMove(fdst, fsrc, deleteMode, DoMove, deleteEmptySrcDirs)
+	do, err := newSyncCopyMove(ctx, fdst, fsrc, deleteMode, DoMove, deleteEmptySrcDirs)
 	if err != nil {
 		return err
 	}
@@ -783,21 +1047,27 @@ func runSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 
 // Sync fsrc into fdst
 func Sync(fdst, fsrc fs.Fs) error {
-	return runSyncCopyMove(fdst, fsrc, fs.Config.DeleteMode, false, false)
+	return runSyncCopyMove(context.Background(), fdst, fsrc, fs.Config.DeleteMode, false, false)
 }
 
 // CopyDir copies fsrc into fdst
 func CopyDir(fdst, fsrc fs.Fs) error {
-	return runSyncCopyMove(fdst, fsrc, fs.DeleteModeOff, false, false)
+	return runSyncCopyMove(context.Background(), fdst, fsrc, fs.DeleteModeOff, false, false)
 }
 
 // moveDir moves fsrc into fdst
-func moveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
-	return runSyncCopyMove(fdst, fsrc, fs.DeleteModeOff, true, deleteEmptySrcDirs)
+func moveDir(ctx context.Context, fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
+	return runSyncCopyMove(ctx, fdst, fsrc, fs.DeleteModeOff, true, deleteEmptySrcDirs)
 }
 
 // MoveDir moves fsrc into fdst
 func MoveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
+	return moveDirContext(context.Background(), fdst, fsrc, deleteEmptySrcDirs)
+}
+
+// moveDirContext moves fsrc into fdst stopping the file moves if ctx
+// is cancelled
+func moveDirContext(ctx context.Context, fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
 	if operations.Same(fdst, fsrc) {
 		fs.Errorf(fdst, "Nothing to do as source and destination are the same")
 		return nil
@@ -805,8 +1075,7 @@ func MoveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
 
 	// First attempt to use DirMover if exists, same Fs and no filters are active
 	if fdstDirMove := fdst.Features().DirMove; fdstDirMove != nil && operations.SameConfig(fsrc, fdst) && filter.Active.InActive() {
-		if fs.Config.DryRun {
-			fs.Logf(fdst, "Not doing server side directory move as --dry-run")
+		if operations.SkipDestructive(fdst, "server side directory move") {
 			return nil
 		}
 		fs.Debugf(fdst, "Using server side directory move")
@@ -818,7 +1087,7 @@ func MoveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
 			fs.Infof(fdst, "Server side directory move succeeded")
 			return nil
 		default:
-			fs.CountError(err)
+			accounting.CountError(fdst, err)
 			fs.Errorf(fdst, "Server side directory move failed: %v", err)
 			return err
 		}
@@ -832,5 +1101,5 @@ func MoveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
 	}
 
 	// Otherwise move the files one by one
-	return moveDir(fdst, fsrc, deleteEmptySrcDirs)
+	return moveDir(ctx, fdst, fsrc, deleteEmptySrcDirs)
 }
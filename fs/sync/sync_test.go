@@ -3,6 +3,7 @@
 package sync
 
 import (
+	"errors"
 	"runtime"
 	"testing"
 	"time"
@@ -11,11 +12,13 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/filter"
+	"github.com/ncw/rclone/fs/fserrors"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/ncw/rclone/fstest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 	"golang.org/x/text/unicode/norm"
 )
 
@@ -61,6 +64,28 @@ func TestCopy(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file1)
 }
 
+// Test sync with --check-first
+func TestSyncWithCheckFirst(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("check first/one", "one", t1)
+	file2 := r.WriteFile("check first/two", "two two", t1)
+	file3 := r.WriteObject("check first/three", "three", t2)
+	fstest.CheckItems(t, r.Flocal, file1, file2)
+	fstest.CheckItems(t, r.Fremote, file3)
+
+	fs.Config.CheckFirst = true
+	defer func() { fs.Config.CheckFirst = false }()
+
+	accounting.Stats.ResetCounters()
+	err := Sync(r.Fremote, r.Flocal)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), accounting.Stats.GetTransfers())
+
+	fstest.CheckItems(t, r.Flocal, file1, file2)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+}
+
 // Test copy with depth
 func TestCopyWithDepth(t *testing.T) {
 	r := fstest.NewRun(t)
@@ -453,6 +478,32 @@ func TestSyncAfterRemovingAFileAndAddingAFile(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file1, file3)
 }
 
+// Sync in a stats group only stops deleting on errors in that group
+func TestSyncStatsGroupErrors(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("potato2", "------------------------------------------------------------", t1)
+	file2 := r.WriteObject("potato", "SMALLER BUT SAME DATE", t2)
+	fstest.CheckItems(t, r.Fremote, file2)
+	fstest.CheckItems(t, r.Flocal, file1)
+
+	accounting.Stats.ResetCounters()
+	group := accounting.NewStatsGroup("test-sync", r.Fremote, r.Flocal)
+	defer accounting.RemoveStatsGroup("test-sync")
+	accounting.Stats.Error(errors.New("error outside the group"))
+	err := Sync(r.Fremote, r.Flocal)
+	require.NoError(t, err)
+	assert.False(t, group.Errored())
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	accounting.CountError(r.Fremote, errors.New("error in the group"))
+	file3 := r.WriteObject("potato3", "SMALLER BUT SAME DATE", t2)
+	err = Sync(r.Fremote, r.Flocal)
+	assert.Equal(t, fs.ErrorNotDeleting, err)
+	fstest.CheckItems(t, r.Fremote, file1, file3)
+	accounting.Stats.ResetCounters()
+}
+
 // Sync after removing a file and adding a file
 func TestSyncAfterRemovingAFileAndAddingAFileSubDir(t *testing.T) {
 	r := fstest.NewRun(t)
@@ -597,6 +648,113 @@ func TestSyncAfterRemovingAFileAndAddingAFileSubDirWithErrors(t *testing.T) {
 	)
 }
 
+// Sync after removing a file and adding a file with IO Errors
+// ignored with --ignore-errors
+func TestSyncAfterRemovingAFileAndAddingAFileSubDirWithErrorsIgnored(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("a/potato2", "------------------------------------------------------------", t1)
+	r.WriteObject("b/potato", "SMALLER BUT SAME DATE", t2)
+	file3 := r.WriteBoth("c/non empty space", "AhHa!", t2)
+	require.NoError(t, operations.Mkdir(r.Fremote, "d"))
+
+	fs.Config.IgnoreErrors = true
+	defer func() { fs.Config.IgnoreErrors = false }()
+
+	accounting.Stats.ResetCounters()
+	fs.CountError(nil)
+	err := Sync(r.Fremote, r.Flocal)
+	require.NoError(t, err)
+	assert.True(t, accounting.Stats.Errored())
+
+	fstest.CheckListingWithPrecision(
+		t,
+		r.Fremote,
+		[]fstest.Item{
+			file1,
+			file3,
+		},
+		[]string{
+			"a",
+			"c",
+		},
+		fs.Config.ModifyWindow,
+	)
+}
+
+// Test failed transfers are requeued and retried with --file-retries
+func TestSyncRetryFailed(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("retry me", "retry me", t1)
+	fstest.CheckItems(t, r.Flocal, file1)
+	r.Mkdir(r.Fremote)
+
+	fs.Config.FileRetries = 1
+	defer func() { fs.Config.FileRetries = 0 }()
+
+	s, err := newSyncCopyMove(context.Background(), r.Fremote, r.Flocal, fs.DeleteModeOff, false, false)
+	require.NoError(t, err)
+	src, err := r.Flocal.NewObject("retry me")
+	require.NoError(t, err)
+	pair := fs.ObjectPair{Src: src}
+
+	// Fatal and no retry errors are never requeued
+	assert.False(t, s.requeue(pair, fserrors.FatalError(errors.New("fatal"))))
+	assert.False(t, s.requeue(pair, fserrors.NoRetryError(errors.New("no retry"))))
+
+	// Other errors are requeued and not counted however many
+	// times they were counted
+	accounting.Stats.ResetCounters()
+	retryErr := errors.New("retry")
+	accounting.Stats.Error(retryErr)
+	accounting.Stats.Error(retryErr)
+	assert.True(t, s.requeue(pair, retryErr))
+	assert.Equal(t, int64(0), accounting.Stats.GetErrors())
+	assert.Nil(t, accounting.Stats.GetLastError())
+	assert.Equal(t, 1, len(s.failed))
+
+	// Errors which aren't pointers are requeued and not counted
+	// too, but only once as they may be from other transfers
+	accounting.Stats.Error(fserrors.RetryErrorf("retry value"))
+	accounting.Stats.Error(fserrors.RetryErrorf("retry value"))
+	assert.True(t, s.requeue(pair, fserrors.RetryErrorf("retry value")))
+	assert.Equal(t, int64(1), accounting.Stats.GetErrors())
+	assert.True(t, s.requeue(pair, fserrors.RetryErrorf("retry value")))
+	assert.Equal(t, int64(0), accounting.Stats.GetErrors())
+	assert.Equal(t, 3, len(s.failed))
+
+	// Errors which weren't counted aren't requeued so they can't
+	// be counted twice
+	assert.False(t, s.requeue(pair, errors.New("not counted")))
+
+	s.retryFailed()
+	assert.Equal(t, 1, s.retryRound)
+	assert.Equal(t, 0, len(s.failed))
+	assert.Equal(t, int64(0), accounting.Stats.GetErrors())
+	assert.False(t, accounting.Stats.Errored())
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	// Once the retries are used up errors aren't requeued
+	assert.False(t, s.requeue(pair, retryErr))
+}
+
+// Test a sync stops when its context is cancelled
+func TestSyncCancelled(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("sync me", "sync me", t1)
+	fstest.CheckItems(t, r.Flocal, file1)
+	r.Mkdir(r.Fremote)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := runSyncCopyMove(ctx, r.Fremote, r.Flocal, fs.DeleteModeOff, false, false)
+	require.Error(t, err)
+	assert.True(t, fserrors.IsFatalError(err))
+	fstest.CheckItems(t, r.Fremote)
+}
+
 // Sync test delete after
 func TestSyncDeleteAfter(t *testing.T) {
 	// This is the default so we've checked this already
@@ -705,6 +863,40 @@ func TestSyncWithExcludeAndDeleteExcluded(t *testing.T) {
 	fstest.CheckItems(t, r.Flocal, file2)
 }
 
+// Test with exclude and delete excluded with --dry-run
+func TestSyncWithExcludeAndDeleteExcludedDryRun(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteBoth("potato2", "------------------------------------------------------------", t1) // 60 bytes
+	file2 := r.WriteBoth("empty space", "", t2)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+	fstest.CheckItems(t, r.Flocal, file1, file2)
+
+	filter.Active.Opt.MaxSize = 40
+	filter.Active.Opt.DeleteExcluded = true
+	defer func() {
+		filter.Active.Opt.MaxSize = -1
+		filter.Active.Opt.DeleteExcluded = false
+	}()
+
+	s, err := newSyncCopyMove(context.Background(), r.Fremote, r.Flocal, fs.Config.DeleteMode, false, false)
+	require.NoError(t, err)
+	assert.True(t, s.deleteExcluded)
+
+	fs.Config.DryRun = true
+	accounting.Stats.ResetCounters()
+	err = s.run()
+	fs.Config.DryRun = false
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), s.excludedCount)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	// Check --delete-excluded is ignored for copy
+	s, err = newSyncCopyMove(context.Background(), r.Fremote, r.Flocal, fs.DeleteModeOff, false, false)
+	require.NoError(t, err)
+	assert.False(t, s.deleteExcluded)
+}
+
 // Test with UpdateOlder set
 func TestSyncWithUpdateOlder(t *testing.T) {
 	if fs.Config.ModifyWindow == fs.ModTimeNotSupported {
@@ -0,0 +1,152 @@
+// Adjust the number of checkers and transfers running with --auto-tune
+
+package sync
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+)
+
+// How often the tuners look at the stats
+var tuneInterval = 10 * time.Second
+
+// tuner limits how many of a pool of workers may be active at once
+// and adjusts that limit between 1 and max depending on how many
+// errors and low level retries there have been and whether more
+// workers increased the throughput.
+//
+// A nil *tuner doesn't limit anything.
+type tuner struct {
+	name     string       // name for logging, eg "transfers"
+	max      int          // maximum number of workers
+	progress func() int64 // measures the throughput, eg bytes transferred
+	stop     chan struct{}
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	limit  int // how many workers may be active
+	active int // how many workers are active
+
+	// state from the last interval
+	lastProgress int64 // the value of progress
+	lastProblems int64 // the number of errors and retries
+	lastRate     int64 // progress made in the interval
+	increased    bool  // whether the limit was increased
+}
+
+// newTuner makes a tuner for max workers if --auto-tune is set,
+// otherwise it returns nil.
+func newTuner(name string, max int, progress func() int64) *tuner {
+	if !fs.Config.AutoTune || max <= 1 {
+		return nil
+	}
+	t := &tuner{
+		name:     name,
+		max:      max,
+		progress: progress,
+		limit:    (max + 1) / 2,
+	}
+	t.cond = sync.NewCond(&t.mu)
+	return t
+}
+
+// problems returns the number of errors and low level retries so far
+func problems() int64 {
+	return accounting.Stats.GetErrors() + accounting.Stats.GetLowLevelRetries()
+}
+
+// start the tuner running in the background
+func (t *tuner) start() {
+	if t == nil {
+		return
+	}
+	t.stop = make(chan struct{})
+	t.lastProgress = t.progress()
+	t.lastProblems = problems()
+	fs.Debugf(nil, "Auto tune: starting with %d/%d %s", t.limit, t.max, t.name)
+	go func() {
+		ticker := time.NewTicker(tuneInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.tick()
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+}
+
+// finish stops the tuner running in the background
+func (t *tuner) finish() {
+	if t == nil {
+		return
+	}
+	close(t.stop)
+}
+
+// tick reads the stats and adjusts the limit
+func (t *tuner) tick() {
+	progress, newProblems := t.progress(), problems()
+	t.adjust(progress-t.lastProgress, newProblems-t.lastProblems)
+	t.lastProgress, t.lastProblems = progress, newProblems
+}
+
+// adjust the limit given the progress made and the number of
+// problems seen in the last interval
+//
+// The limit is halved if there were any problems.  Otherwise it is
+// increased by one unless the last increase didn't increase the
+// throughput in which case that increase is undone.
+func (t *tuner) adjust(rate, problems int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	oldLimit := t.limit
+	increased := false
+	switch {
+	case problems > 0:
+		t.limit /= 2
+		if t.limit < 1 {
+			t.limit = 1
+		}
+	case t.increased && rate <= t.lastRate:
+		t.limit--
+	case t.limit < t.max && rate > 0:
+		t.limit++
+		increased = true
+	}
+	t.increased = increased
+	t.lastRate = rate
+	if t.limit != oldLimit {
+		fs.Debugf(nil, "Auto tune: %d %s (was %d, %d errors and retries)", t.limit, t.name, oldLimit, problems)
+		t.cond.Broadcast()
+	}
+}
+
+// acquire waits until the worker may be active
+func (t *tuner) acquire() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	for t.active >= t.limit {
+		t.cond.Wait()
+	}
+	t.active++
+	t.mu.Unlock()
+}
+
+// release marks the worker as no longer active
+func (t *tuner) release() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.active--
+	t.mu.Unlock()
+	t.cond.Signal()
+}
@@ -0,0 +1,105 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTuner(t *testing.T, max int) *tuner {
+	fs.Config.AutoTune = true
+	defer func() { fs.Config.AutoTune = false }()
+	tu := newTuner("test", max, func() int64 { return 0 })
+	require.NotNil(t, tu)
+	return tu
+}
+
+func TestNewTuner(t *testing.T) {
+	assert.Nil(t, newTuner("test", 8, nil))
+
+	tu := newTestTuner(t, 8)
+	assert.Equal(t, 4, tu.limit)
+	tu = newTestTuner(t, 3)
+	assert.Equal(t, 2, tu.limit)
+
+	fs.Config.AutoTune = true
+	defer func() { fs.Config.AutoTune = false }()
+	assert.Nil(t, newTuner("test", 1, nil))
+}
+
+func TestTunerAdjust(t *testing.T) {
+	tu := newTestTuner(t, 8)
+	for _, test := range []struct {
+		rate     int64
+		problems int64
+		want     int
+	}{
+		{100, 0, 5}, // no problems so increase
+		{200, 0, 6}, // the increase helped so increase again
+		{200, 0, 5}, // the increase didn't help so undo it
+		{200, 0, 6}, // try again
+		{300, 0, 7},
+		{400, 0, 8},
+		{500, 0, 8}, // at the maximum
+		{500, 1, 4}, // problems so halve
+		{100, 3, 2},
+		{100, 1, 1},
+		{100, 1, 1}, // at the minimum
+		{0, 0, 1},   // no progress so don't increase
+		{100, 0, 2},
+	} {
+		tu.adjust(test.rate, test.problems)
+		assert.Equal(t, test.want, tu.limit, "rate=%d, problems=%d", test.rate, test.problems)
+	}
+}
+
+func TestTunerAcquire(t *testing.T) {
+	var nilTuner *tuner
+	nilTuner.acquire()
+	nilTuner.release()
+
+	tu := newTestTuner(t, 4)
+	tu.acquire()
+	tu.acquire()
+	acquired := make(chan struct{})
+	go func() {
+		tu.acquire()
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquired more than the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Raising the limit lets the waiting worker run
+	tu.adjust(100, 0)
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("didn't acquire after limit raised")
+	}
+	tu.release()
+	tu.release()
+	tu.release()
+	assert.Equal(t, 0, tu.active)
+}
+
+func TestSyncAutoTune(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	file2 := r.WriteFile("sub dir/file2", "file2 contents", t2)
+	fstest.CheckItems(t, r.Flocal, file1, file2)
+
+	fs.Config.AutoTune = true
+	defer func() { fs.Config.AutoTune = false }()
+
+	err := Sync(r.Fremote, r.Flocal)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+}
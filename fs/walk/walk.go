@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/filter"
 	"github.com/ncw/rclone/fs/list"
 	"github.com/pkg/errors"
@@ -136,7 +137,7 @@ func walk(f fs.Fs, path string, includeAll bool, maxLevel int, fn Func, listDir
 					// NB once we have passed entries to fn we mustn't touch it again
 					if err != nil && err != ErrorSkipDir {
 						traversing.Done()
-						fs.CountError(err)
+						accounting.CountError(f, err)
 						fs.Errorf(job.remote, "error listing: %v", err)
 						closeQuit()
 						// Send error to error channel if space
@@ -355,16 +356,44 @@ func walkRDirTree(f fs.Fs, startPath string, includeAll bool, maxLevel int, list
 	// all directories to exclude later.
 	toPrune := make(map[string]bool)
 	includeDirectory := filter.Active.IncludeDirectory(f)
+	// Entries in directories excluded by the filters are discarded
+	// as soon as they arrive so they use no memory and don't need
+	// checking against the file rules.  This makes ListR obey the
+	// same directory pruning as a directory by directory walk.
+	dirIncluded := make(map[string]bool)
+	var parentIncluded func(remote string) bool
+	parentIncluded = func(remote string) bool {
+		dirPath := parentDir(remote)
+		if len(dirPath) <= len(startPath) {
+			return true
+		}
+		if include, found := dirIncluded[dirPath]; found {
+			return include
+		}
+		include := parentIncluded(dirPath) && filter.Active.IncludeDirectoryRules(dirPath)
+		if !include {
+			fs.Debugf(dirPath, "Excluded from sync (and deletion)")
+		}
+		dirIncluded[dirPath] = include
+		return include
+	}
 	var mu sync.Mutex
 	err := listR(startPath, func(entries fs.DirEntries) error {
 		mu.Lock()
 		defer mu.Unlock()
 		for _, entry := range entries {
+			if !includeAll && !parentIncluded(entry.Remote()) {
+				continue
+			}
 			slashes := strings.Count(entry.Remote(), "/")
 			switch x := entry.(type) {
 			case fs.Object:
 				// Make sure we don't delete excluded files if not required
-				if includeAll || filter.Active.IncludeObject(x) {
+				include, reason := true, ""
+				if !includeAll {
+					include, reason = filter.Active.IncludeObjectReason(x)
+				}
+				if include {
 					if maxLevel < 0 || slashes <= maxLevel-1 {
 						dirs.add(x)
 					} else {
@@ -376,7 +405,7 @@ func walkRDirTree(f fs.Fs, startPath string, includeAll bool, maxLevel int, list
 						dirs.checkParent(startPath, dirPath)
 					}
 				} else {
-					fs.Debugf(x, "Excluded from sync (and deletion)")
+					fs.Debugf(x, "Excluded from sync (and deletion): %s", reason)
 				}
 				// Check if we need to prune a directory later.
 				if !includeAll && len(filter.Active.Opt.ExcludeFile) > 0 {
@@ -402,6 +431,7 @@ func walkRDirTree(f fs.Fs, startPath string, includeAll bool, maxLevel int, list
 						}
 					}
 				} else {
+					dirIncluded[x.Remote()] = false
 					fs.Debugf(x, "Excluded from sync (and deletion)")
 				}
 			default:
@@ -634,3 +634,31 @@ b/c/d/
 	// Set to default value, to avoid side effects
 	filter.Active.Opt.ExcludeFile = ""
 }
+
+func TestWalkRDirTreePruneExcludedDirs(t *testing.T) {
+	require.NoError(t, filter.Active.Add(false, "node_modules/**"))
+	// a directory only rule doesn't match the files within
+	require.NoError(t, filter.Active.Add(false, "secret/"))
+	defer filter.Active.Clear()
+	entries := fs.DirEntries{
+		mockobject.Object("a"),
+		mockobject.Object("b/node_modules/x"),
+		mockobject.Object("b/node_modules/y/z"),
+		mockobject.Object("b/c"),
+		mockobject.Object("node_modules/d"),
+		mockobject.Object("secret/e"),
+	}
+	r, err := walkRDirTree(nil, "", false, -1, makeListRCallback(entries, nil))
+	require.NoError(t, err)
+	assert.Equal(t, `/
+  a
+  b/
+b/
+  c
+`, r.String())
+
+	// includeAll should see everything
+	r, err = walkRDirTree(nil, "", true, -1, makeListRCallback(entries, nil))
+	require.NoError(t, err)
+	assert.Contains(t, r.String(), "node_modules/")
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/config"
 	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/object"
 	"github.com/ncw/rclone/fs/walk"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -335,6 +336,40 @@ func CheckItems(t *testing.T, f fs.Fs, items ...Item) {
 	CheckListingWithPrecision(t, f, items, nil, fs.Config.ModifyWindow)
 }
 
+// PutContents uploads contents to remote in f with the modTime given
+func PutContents(t *testing.T, f fs.Fs, remote, contents string, modTime time.Time) fs.Object {
+	src := object.NewStaticObjectInfo(remote, modTime, int64(len(contents)), true, nil, nil)
+	o, err := f.Put(bytes.NewBufferString(contents), src)
+	require.NoError(t, err)
+	return o
+}
+
+// ReadObject reads the object with the options given
+func ReadObject(t *testing.T, o fs.Object, options ...fs.OpenOption) string {
+	in, err := o.Open(options...)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	return string(data)
+}
+
+// ListNames lists dir in f returning the sorted names with a "/" on
+// the end of directories
+func ListNames(t *testing.T, f fs.Fs, dir string) (names []string) {
+	entries, err := f.List(dir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		name := entry.Remote()
+		if _, isDir := entry.(fs.Directory); isDir {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // Time parses a time string or logs a fatal error
 func Time(timeString string) time.Time {
 	t, err := time.Parse(time.RFC3339Nano, timeString)
@@ -66,7 +66,7 @@ import (
 	"github.com/ncw/rclone/backend/{{ .FsName }}"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fstest/fstests"
-{{ if (or (eq .FsName "crypt") (eq .FsName "cache")) }}	_ "github.com/ncw/rclone/backend/local"
+{{ if (or (eq .FsName "crypt") (eq .FsName "cache") (eq .FsName "cas") (eq .FsName "union") (eq .FsName "chunker") (eq .FsName "compress")) }}	_ "github.com/ncw/rclone/backend/local"
 {{end}})
 
 func TestSetup{{ .Suffix }}(t *testing.T)() {
@@ -167,5 +167,14 @@ func main() {
 	generateTestProgram(t, fns, "Pcloud")
 	generateTestProgram(t, fns, "Webdav")
 	generateTestProgram(t, fns, "Cache", buildConstraint("!plan9,go1.7"))
+	generateTestProgram(t, fns, "Cas")
+	generateTestProgram(t, fns, "Hdfs", buildConstraint("go1.7"))
+	generateTestProgram(t, fns, "Smb")
+	generateTestProgram(t, fns, "Nfs")
+	generateTestProgram(t, fns, "InternetArchive")
+	generateTestProgram(t, fns, "Zoho")
+	generateTestProgram(t, fns, "Union")
+	generateTestProgram(t, fns, "Chunker")
+	generateTestProgram(t, fns, "Compress")
 	log.Printf("Done")
 }
@@ -0,0 +1,36 @@
+// Package file provides path manipulation and filesystem functions
+// which smooth over OS differences, such as long paths on Windows
+// and preallocating file space.
+package file
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Pattern to match a windows absolute path: "c:\" and similar
+var isAbsWinDrive = regexp.MustCompile(`^[a-zA-Z]\:\\`)
+
+// UNCPath converts an absolute Windows path to a UNC long path.
+//
+// Paths which are already long paths, device paths (`\\.\`) and
+// relative paths are returned with only their separators converted.
+func UNCPath(s string) string {
+	// UNC can NOT use "/", so convert all to "\"
+	s = strings.Replace(s, `/`, `\`, -1)
+
+	// If prefix is "\\", we already have a UNC path or server.
+	if strings.HasPrefix(s, `\\`) {
+		// If already long path or a device path, just keep it
+		if strings.HasPrefix(s, `\\?\`) || strings.HasPrefix(s, `\\.\`) {
+			return s
+		}
+
+		// Trim "\\" from path and add UNC prefix.
+		return `\\?\UNC\` + strings.TrimPrefix(s, `\\`)
+	}
+	if isAbsWinDrive.MatchString(s) {
+		return `\\?\` + s
+	}
+	return s
+}
@@ -0,0 +1,40 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMkdirAll(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "rclone-mkdirall")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tmp))
+	}()
+	root := tmp
+	if runtime.GOOS == "windows" {
+		// Check long paths work too
+		root = UNCPath(tmp)
+	}
+
+	dir := filepath.Join(root, "a", "b", "c")
+	require.NoError(t, MkdirAll(dir, 0777))
+	fi, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.True(t, fi.IsDir())
+
+	// Already existing is OK
+	require.NoError(t, MkdirAll(dir, 0777))
+
+	// A file in the way is not
+	filePath := filepath.Join(root, "file")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("hello"), 0666))
+	assert.Error(t, MkdirAll(filePath, 0777))
+	assert.Error(t, MkdirAll(filepath.Join(filePath, "sub"), 0777))
+}
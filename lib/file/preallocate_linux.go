@@ -0,0 +1,30 @@
+// +build linux
+
+package file
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// PreAllocate the file for performance reasons
+//
+// The space is reserved with fallocate without changing the file
+// size, so a partially written file never looks complete.  File
+// systems which don't support fallocate are silently ignored.
+func PreAllocate(size int64, out *os.File) error {
+	if size <= 0 {
+		return nil
+	}
+	err := unix.Fallocate(int(out.Fd()), unix.FALLOC_FL_KEEP_SIZE, 0, size)
+	switch err {
+	case nil:
+		return nil
+	case unix.ENOTSUP, unix.ENOSYS:
+		// File system doesn't support it
+		return nil
+	}
+	return errors.Wrap(err, "preallocate: fallocate failed")
+}
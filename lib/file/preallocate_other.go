@@ -0,0 +1,12 @@
+// +build !windows,!linux
+
+package file
+
+import "os"
+
+// PreAllocate the file for performance reasons
+//
+// This is a no-op on this OS.
+func PreAllocate(size int64, out *os.File) error {
+	return nil
+}
@@ -0,0 +1,34 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreAllocate(t *testing.T) {
+	out, err := ioutil.TempFile("", "rclone-preallocate")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, out.Close())
+		require.NoError(t, os.Remove(out.Name()))
+	}()
+
+	require.NoError(t, PreAllocate(0, out))
+	require.NoError(t, PreAllocate(1<<20, out))
+
+	// The size must not change
+	fi, err := out.Stat()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), fi.Size())
+
+	// Writing still works as normal
+	_, err = out.Write([]byte("hello"))
+	require.NoError(t, err)
+	fi, err = out.Stat()
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), fi.Size())
+}
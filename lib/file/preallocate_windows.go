@@ -0,0 +1,52 @@
+// +build windows
+
+package file
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ntdll                = syscall.NewLazyDLL("ntdll.dll")
+	ntSetInformationFile = ntdll.NewProc("NtSetInformationFile")
+)
+
+// FILE_INFORMATION_CLASS value for FileAllocationInformation
+const fileAllocationInformationClass = 19
+
+type fileAllocationInformation struct {
+	AllocationSize uint64
+}
+
+type ioStatusBlock struct {
+	Status, Information uintptr
+}
+
+// PreAllocate the file for performance reasons
+//
+// This sets the allocation size of the file which reserves the space
+// for it in one piece without changing the file size.
+func PreAllocate(size int64, out *os.File) error {
+	if size <= 0 {
+		return nil
+	}
+	var iosb ioStatusBlock
+	allocInfo := fileAllocationInformation{
+		AllocationSize: uint64(size),
+	}
+	status, _, _ := ntSetInformationFile.Call(
+		out.Fd(),
+		uintptr(unsafe.Pointer(&iosb)),
+		uintptr(unsafe.Pointer(&allocInfo)),
+		unsafe.Sizeof(allocInfo),
+		fileAllocationInformationClass,
+	)
+	if status != 0 {
+		return errors.Errorf("preallocate: NtSetInformationFile failed: status=%#x", status)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+// +build windows
+
+package file
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// FSCTL_SET_SPARSE from winioctl.h
+const fsctlSetSparse = 0x000900c4
+
+// SetSparse makes the file be a sparse file
+//
+// This means that writing beyond the end of the file doesn't need
+// the gap to be filled with zeroes first, so parts of a file can be
+// written in any order.
+func SetSparse(out *os.File) error {
+	var bytesReturned uint32
+	err := syscall.DeviceIoControl(syscall.Handle(out.Fd()), fsctlSetSparse, nil, 0, nil, 0, &bytesReturned, nil)
+	if err != nil {
+		return errors.Wrap(err, "DeviceIoControl FSCTL_SET_SPARSE")
+	}
+	return nil
+}
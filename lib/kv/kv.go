@@ -0,0 +1,286 @@
+// +build !plan9,!js,go1.7
+
+// Package kv provides a persistent key-value store for the parts of
+// rclone which need to keep state between runs, so they don't each
+// need their own file format.
+//
+// Each user of the store opens it with its own facility name which
+// selects the database file, and keeps its keys in one or more
+// namespaces within it.  Changes are written in transactions which
+// are synced to disk before they return so a crash leaves the store
+// either before or after each change.
+package kv
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/pkg/errors"
+)
+
+// Errors returned by the store
+var (
+	ErrNotFound    = errors.New("key not found")
+	ErrUnsupported = errors.New("kv store not supported on this platform")
+)
+
+// OpenTimeout is how long to wait for another rclone using the same
+// database file to close it
+var OpenTimeout = 5 * time.Second
+
+// Dir returns the directory the database files are kept in
+func Dir() string {
+	return filepath.Join(config.CacheDir, "kv")
+}
+
+var (
+	dbsMu sync.Mutex
+	dbs   = map[string]*DB{}
+)
+
+// DB is an open key-value store
+type DB struct {
+	path string
+	db   *bolt.DB
+	refs int // number of times this has been opened and not closed
+}
+
+// Open opens the store for facility, creating it in Dir if necessary.
+//
+// The store is shared with other users in this process which open the
+// same facility.  Each call to Open should be matched with a call to
+// Close.
+func Open(facility string) (*DB, error) {
+	return OpenPath(filepath.Join(Dir(), facility+".bolt"))
+}
+
+// OpenPath opens the store in the database file at path, creating it
+// if necessary.
+//
+// If the file is corrupt it is moved out of the way to path+".corrupt"
+// and a new one is created.
+func OpenPath(path string) (*DB, error) {
+	dbsMu.Lock()
+	defer dbsMu.Unlock()
+	if db, ok := dbs[path]; ok {
+		db.refs++
+		return db, nil
+	}
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make kv store directory")
+	}
+	boltDB, err := openBolt(path)
+	if err == bolt.ErrInvalid || err == bolt.ErrChecksum || err == bolt.ErrVersionMismatch {
+		fs.Errorf(nil, "kv store %q is corrupt - moving it out of the way and starting again: %v", path, err)
+		err = os.Rename(path, path+".corrupt")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to move corrupt kv store")
+		}
+		boltDB, err = openBolt(path)
+	}
+	if err == bolt.ErrTimeout {
+		return nil, errors.Errorf("kv store %q is in use - is there another rclone running?", path)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open kv store %q", path)
+	}
+	db := &DB{
+		path: path,
+		db:   boltDB,
+		refs: 1,
+	}
+	dbs[path] = db
+	return db, nil
+}
+
+// openBolt opens the bolt database at path
+func openBolt(path string) (*bolt.DB, error) {
+	return bolt.Open(path, 0600, &bolt.Options{Timeout: OpenTimeout})
+}
+
+// String returns the path of the database file
+func (db *DB) String() string {
+	return db.path
+}
+
+// Close closes the store once all the users which opened it have
+// closed it
+func (db *DB) Close() error {
+	dbsMu.Lock()
+	defer dbsMu.Unlock()
+	db.refs--
+	if db.refs > 0 {
+		return nil
+	}
+	delete(dbs, db.path)
+	return db.db.Close()
+}
+
+// Namespace returns the namespace called name in the store.
+//
+// Keys in one namespace are kept separate from those in any other.
+func (db *DB) Namespace(name string) *Namespace {
+	return &Namespace{
+		db:   db,
+		name: []byte(name),
+	}
+}
+
+// Namespaces returns the names of the namespaces in the store which
+// have keys in
+func (db *DB) Namespaces() (names []string, err error) {
+	err = db.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			names = append(names, string(name))
+			return nil
+		})
+	})
+	return names, err
+}
+
+// Namespace is a set of keys in a DB
+type Namespace struct {
+	db   *DB
+	name []byte
+}
+
+// Tx is a transaction on a Namespace.  Changes made in it are only
+// written if the function it is passed to returns nil.
+type Tx struct {
+	bucket *bolt.Bucket
+}
+
+// Get returns the value of key or ErrNotFound.
+//
+// The value is only valid until the transaction ends.
+func (tx *Tx) Get(key string) ([]byte, error) {
+	if tx.bucket == nil {
+		return nil, ErrNotFound
+	}
+	value := tx.bucket.Get([]byte(key))
+	if value == nil {
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+// Put sets key to value
+func (tx *Tx) Put(key string, value []byte) error {
+	return tx.bucket.Put([]byte(key), value)
+}
+
+// Delete removes key.  It is not an error if key doesn't exist.
+func (tx *Tx) Delete(key string) error {
+	if tx.bucket == nil {
+		return nil
+	}
+	return tx.bucket.Delete([]byte(key))
+}
+
+// ForEach calls fn for each key in order with its value, stopping if
+// fn returns an error.
+//
+// fn must not change the namespace and the value is only valid until
+// fn returns.
+func (tx *Tx) ForEach(fn func(key string, value []byte) error) error {
+	if tx.bucket == nil {
+		return nil
+	}
+	return tx.bucket.ForEach(func(key, value []byte) error {
+		return fn(string(key), value)
+	})
+}
+
+// View calls fn with a read only transaction on the namespace
+func (ns *Namespace) View(fn func(tx *Tx) error) error {
+	return ns.db.db.View(func(boltTx *bolt.Tx) error {
+		return fn(&Tx{bucket: boltTx.Bucket(ns.name)})
+	})
+}
+
+// Update calls fn with a read write transaction on the namespace.
+//
+// All the changes made by fn are written together if it returns nil
+// and none of them if it returns an error.
+func (ns *Namespace) Update(fn func(tx *Tx) error) error {
+	return ns.db.db.Update(func(boltTx *bolt.Tx) error {
+		bucket, err := boltTx.CreateBucketIfNotExists(ns.name)
+		if err != nil {
+			return err
+		}
+		return fn(&Tx{bucket: bucket})
+	})
+}
+
+// Get returns a copy of the value of key or ErrNotFound
+func (ns *Namespace) Get(key string) (value []byte, err error) {
+	err = ns.View(func(tx *Tx) error {
+		v, err := tx.Get(key)
+		value = append([]byte(nil), v...)
+		return err
+	})
+	return value, err
+}
+
+// Put sets key to value
+func (ns *Namespace) Put(key string, value []byte) error {
+	return ns.Update(func(tx *Tx) error {
+		return tx.Put(key, value)
+	})
+}
+
+// Delete removes key.  It is not an error if key doesn't exist.
+func (ns *Namespace) Delete(key string) error {
+	return ns.Update(func(tx *Tx) error {
+		return tx.Delete(key)
+	})
+}
+
+// GetJSON decodes the JSON value of key into v or returns ErrNotFound
+func (ns *Namespace) GetJSON(key string, v interface{}) error {
+	return ns.View(func(tx *Tx) error {
+		value, err := tx.Get(key)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(value, v)
+	})
+}
+
+// PutJSON sets key to v encoded as JSON
+func (ns *Namespace) PutJSON(key string, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ns.Put(key, value)
+}
+
+// Keys returns all the keys in the namespace in order
+func (ns *Namespace) Keys() (keys []string, err error) {
+	err = ns.View(func(tx *Tx) error {
+		return tx.ForEach(func(key string, _ []byte) error {
+			keys = append(keys, key)
+			return nil
+		})
+	})
+	return keys, err
+}
+
+// Clear removes all the keys in the namespace
+func (ns *Namespace) Clear() error {
+	return ns.db.db.Update(func(boltTx *bolt.Tx) error {
+		err := boltTx.DeleteBucket(ns.name)
+		if err == bolt.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	})
+}
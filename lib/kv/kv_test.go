@@ -0,0 +1,135 @@
+// +build !plan9,!js,go1.7
+
+package kv
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTest(t *testing.T) (db *DB, cleanup func()) {
+	dir, err := ioutil.TempDir("", "rclone-kv-test")
+	require.NoError(t, err)
+	db, err = OpenPath(filepath.Join(dir, "test.bolt"))
+	require.NoError(t, err)
+	return db, func() {
+		assert.NoError(t, db.Close())
+		assert.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	db, cleanup := openTest(t)
+	defer cleanup()
+	one, two := db.Namespace("one"), db.Namespace("two")
+
+	_, err := one.Get("a")
+	assert.Equal(t, ErrNotFound, err)
+	keys, err := one.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string(nil), keys)
+
+	require.NoError(t, one.Put("b", []byte("B")))
+	require.NoError(t, one.Put("a", []byte("A")))
+	require.NoError(t, two.Put("a", []byte("other")))
+
+	value, err := one.Get("a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("A"), value)
+	keys, err = one.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, keys)
+	names, err := db.Namespaces()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"one", "two"}, names)
+
+	require.NoError(t, one.Delete("a"))
+	require.NoError(t, one.Delete("missing"))
+	_, err = one.Get("a")
+	assert.Equal(t, ErrNotFound, err)
+
+	require.NoError(t, one.Clear())
+	require.NoError(t, one.Clear())
+	keys, err = one.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string(nil), keys)
+	value, err = two.Get("a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("other"), value)
+}
+
+func TestJSON(t *testing.T) {
+	db, cleanup := openTest(t)
+	defer cleanup()
+	ns := db.Namespace("json")
+
+	type state struct {
+		Offset int64
+		ID     string
+	}
+	require.NoError(t, ns.PutJSON("upload", state{Offset: 42, ID: "xyz"}))
+	var got state
+	require.NoError(t, ns.GetJSON("upload", &got))
+	assert.Equal(t, state{Offset: 42, ID: "xyz"}, got)
+	assert.Equal(t, ErrNotFound, ns.GetJSON("missing", &got))
+}
+
+func TestUpdateRollback(t *testing.T) {
+	db, cleanup := openTest(t)
+	defer cleanup()
+	ns := db.Namespace("tx")
+
+	require.NoError(t, ns.Put("a", []byte("1")))
+	errFailed := errors.New("failed")
+	err := ns.Update(func(tx *Tx) error {
+		require.NoError(t, tx.Put("a", []byte("2")))
+		require.NoError(t, tx.Put("b", []byte("2")))
+		return errFailed
+	})
+	assert.Equal(t, errFailed, err)
+	value, err := ns.Get("a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), value)
+	_, err = ns.Get("b")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestOpenShared(t *testing.T) {
+	db, cleanup := openTest(t)
+	defer cleanup()
+
+	db2, err := OpenPath(db.path)
+	require.NoError(t, err)
+	assert.True(t, db == db2)
+	require.NoError(t, db2.Close())
+
+	// still open for the first user
+	require.NoError(t, db.Namespace("ns").Put("a", []byte("A")))
+}
+
+func TestOpenCorrupt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-kv-test")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(dir))
+	}()
+	path := filepath.Join(dir, "corrupt.bolt")
+	garbage := make([]byte, 8192)
+	for i := range garbage {
+		garbage[i] = byte(i)
+	}
+	require.NoError(t, ioutil.WriteFile(path, garbage, 0600))
+
+	db, err := OpenPath(path)
+	require.NoError(t, err)
+	require.NoError(t, db.Namespace("ns").Put("a", []byte("A")))
+	require.NoError(t, db.Close())
+	_, err = os.Stat(path + ".corrupt")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,129 @@
+// +build plan9 js !go1.7
+
+// Package kv provides a persistent key-value store for the parts of
+// rclone which need to keep state between runs.
+//
+// It isn't supported on this platform.
+package kv
+
+import (
+	"path/filepath"
+
+	"github.com/ncw/rclone/fs/config"
+	"github.com/pkg/errors"
+)
+
+// Errors returned by the store
+var (
+	ErrNotFound    = errors.New("key not found")
+	ErrUnsupported = errors.New("kv store not supported on this platform")
+)
+
+// DB is an open key-value store
+type DB struct{}
+
+// Namespace is a set of keys in a DB
+type Namespace struct{}
+
+// Dir returns the directory the database files would be kept in
+func Dir() string {
+	return filepath.Join(config.CacheDir, "kv")
+}
+
+// Open returns ErrUnsupported
+func Open(facility string) (*DB, error) {
+	return nil, ErrUnsupported
+}
+
+// OpenPath returns ErrUnsupported
+func OpenPath(path string) (*DB, error) {
+	return nil, ErrUnsupported
+}
+
+// String returns a description of the store
+func (db *DB) String() string {
+	return "kv store"
+}
+
+// Close does nothing
+func (db *DB) Close() error {
+	return nil
+}
+
+// Namespace returns the namespace called name
+func (db *DB) Namespace(name string) *Namespace {
+	return &Namespace{}
+}
+
+// Namespaces returns ErrUnsupported
+func (db *DB) Namespaces() (names []string, err error) {
+	return nil, ErrUnsupported
+}
+
+// Tx is a transaction on a Namespace
+type Tx struct{}
+
+// Get returns ErrUnsupported
+func (tx *Tx) Get(key string) ([]byte, error) {
+	return nil, ErrUnsupported
+}
+
+// Put returns ErrUnsupported
+func (tx *Tx) Put(key string, value []byte) error {
+	return ErrUnsupported
+}
+
+// Delete returns ErrUnsupported
+func (tx *Tx) Delete(key string) error {
+	return ErrUnsupported
+}
+
+// ForEach returns ErrUnsupported
+func (tx *Tx) ForEach(fn func(key string, value []byte) error) error {
+	return ErrUnsupported
+}
+
+// View returns ErrUnsupported
+func (ns *Namespace) View(fn func(tx *Tx) error) error {
+	return ErrUnsupported
+}
+
+// Update returns ErrUnsupported
+func (ns *Namespace) Update(fn func(tx *Tx) error) error {
+	return ErrUnsupported
+}
+
+// Get returns ErrUnsupported
+func (ns *Namespace) Get(key string) (value []byte, err error) {
+	return nil, ErrUnsupported
+}
+
+// Put returns ErrUnsupported
+func (ns *Namespace) Put(key string, value []byte) error {
+	return ErrUnsupported
+}
+
+// Delete returns ErrUnsupported
+func (ns *Namespace) Delete(key string) error {
+	return ErrUnsupported
+}
+
+// GetJSON returns ErrUnsupported
+func (ns *Namespace) GetJSON(key string, v interface{}) error {
+	return ErrUnsupported
+}
+
+// PutJSON returns ErrUnsupported
+func (ns *Namespace) PutJSON(key string, v interface{}) error {
+	return ErrUnsupported
+}
+
+// Keys returns ErrUnsupported
+func (ns *Namespace) Keys() (keys []string, err error) {
+	return nil, ErrUnsupported
+}
+
+// Clear returns ErrUnsupported
+func (ns *Namespace) Clear() error {
+	return ErrUnsupported
+}
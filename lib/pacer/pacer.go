@@ -337,6 +337,7 @@ func (p *Pacer) call(fn Paced, retries int) (err error) {
 		if !retry {
 			break
 		}
+		fs.CountLowLevelRetry()
 		fs.Debugf("pacer", "low level retry %d/%d (error %v)", i, retries, err)
 	}
 	if retry {
@@ -0,0 +1,66 @@
+// Package main builds librclone, a shared library exporting the
+// remote control calls to C and anything which can call C.
+//
+// Build it with
+//
+//     go build --buildmode=c-shared -o librclone.so github.com/ncw/rclone/librclone
+//
+// which makes librclone.so and the header librclone.h.
+package main
+
+/*
+#include <stdlib.h>
+
+struct RcloneRPCResult {
+	char*	Output;
+	int	Status;
+};
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/ncw/rclone/librclone/librclone"
+)
+
+// RcloneInitialize initializes rclone as a library
+//
+//export RcloneInitialize
+func RcloneInitialize() {
+	librclone.Initialize()
+}
+
+// RcloneFinalize finalizes the library
+//
+//export RcloneFinalize
+func RcloneFinalize() {
+	librclone.Finalize()
+}
+
+// RcloneRPC calls the remote control method with the JSON encoded
+// input and returns the JSON encoded output and an HTTP style status
+// code, 200 for success.
+//
+// The caller must free the Output with RcloneFreeString.
+//
+// See the remote control docs for the methods and their parameters,
+// eg "core/stats" or "sync/copy".
+//
+//export RcloneRPC
+func RcloneRPC(method *C.char, input *C.char) (result C.struct_RcloneRPCResult) {
+	output, status := librclone.RPC(C.GoString(method), C.GoString(input))
+	result.Output = C.CString(output)
+	result.Status = C.int(status)
+	return result
+}
+
+// RcloneFreeString frees the memory of a string returned by rclone
+//
+//export RcloneFreeString
+func RcloneFreeString(str *C.char) {
+	C.free(unsafe.Pointer(str))
+}
+
+// do nothing here - necessary for building into a C library
+func main() {}
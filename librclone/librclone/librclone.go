@@ -0,0 +1,103 @@
+// Package librclone exports the remote control calls so rclone can
+// be embedded in other programs.
+//
+// The C interface in the parent directory is built on this, but it
+// can be used from Go directly too.
+package librclone
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	_ "github.com/ncw/rclone/backend/all" // import all backends
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/config/configflags"
+	fslog "github.com/ncw/rclone/fs/log"
+	"github.com/ncw/rclone/fs/rc"
+	_ "github.com/ncw/rclone/fs/sync" // import the sync/* calls
+	"github.com/ncw/rclone/lib/atexit"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+var initOnce sync.Once
+
+// Initialize sets up rclone ready for RPC to be called.
+//
+// It loads the config file and starts the logging and the bandwidth
+// limiter.  Calling it more than once does nothing.
+func Initialize() {
+	initOnce.Do(func() {
+		fslog.InitLogging()
+		configflags.SetFlags()
+		config.LoadConfig()
+	})
+}
+
+// Finalize tidies up when rclone is no longer needed.
+//
+// This runs the exit handlers rclone has registered, eg to remove
+// temporary files.
+func Finalize() {
+	atexit.Run()
+}
+
+// RPC calls the remote control method with the JSON encoded input
+// parameters.
+//
+// It returns the JSON encoded output and an HTTP style status code,
+// 200 for success.  On error the output is a JSON object with an
+// "error" key describing the problem.
+//
+// Set "_async" to true in the input to run the call in the
+// background as a job.
+func RPC(method string, input string) (output string, status int) {
+	in := rc.Params{}
+
+	writeError := func(err error, status int) (string, int) {
+		fs.Errorf(nil, "rc: %q: error: %v", method, err)
+		var buf bytes.Buffer
+		err = rc.WriteJSON(&buf, rc.Params{
+			"error":  err.Error(),
+			"input":  in,
+			"path":   method,
+			"status": status,
+		})
+		if err != nil {
+			return `{"error":"failed to encode error as JSON"}`, http.StatusInternalServerError
+		}
+		return buf.String(), status
+	}
+
+	call := rc.Get(method)
+	if call == nil {
+		return writeError(errors.Errorf("couldn't find method %q", method), http.StatusNotFound)
+	}
+
+	if input != "" {
+		err := json.Unmarshal([]byte(input), &in)
+		if err != nil {
+			return writeError(errors.Wrap(err, "failed to read input JSON"), http.StatusBadRequest)
+		}
+	}
+
+	fs.Debugf(nil, "rc: %q: with parameters %+v", method, in)
+	out, status, err := rc.Run(context.Background(), call.Fn, in)
+	if err != nil {
+		return writeError(err, status)
+	}
+	if out == nil {
+		out = rc.Params{}
+	}
+
+	fs.Debugf(nil, "rc: %q: reply %+v", method, out)
+	var buf bytes.Buffer
+	err = rc.WriteJSON(&buf, out)
+	if err != nil {
+		return writeError(errors.Wrap(err, "failed to write JSON output"), http.StatusInternalServerError)
+	}
+	return buf.String(), http.StatusOK
+}
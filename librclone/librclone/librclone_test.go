@@ -0,0 +1,48 @@
+package librclone
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRPC(t *testing.T) {
+	Initialize()
+	defer Finalize()
+
+	// Parse the output of RPC as JSON
+	parse := func(output string) (out rc.Params) {
+		require.NoError(t, json.Unmarshal([]byte(output), &out))
+		return out
+	}
+
+	output, status := RPC("rc/noop", `{"potato":1}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, rc.Params{"potato": 1.0}, parse(output))
+
+	output, status = RPC("rc/noop", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, rc.Params{}, parse(output))
+
+	output, status = RPC("rc/error", `{"potato":1}`)
+	assert.Equal(t, http.StatusInternalServerError, status)
+	out := parse(output)
+	assert.Contains(t, out["error"], "arbitrary error")
+	assert.Equal(t, "rc/error", out["path"])
+
+	output, status = RPC("potato/sausage", "{}")
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Contains(t, parse(output)["error"], "couldn't find method")
+
+	output, status = RPC("rc/noop", "not JSON")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, parse(output)["error"], "failed to read input JSON")
+
+	output, status = RPC("rc/noop", `{"_async":true}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, parse(output), "jobid")
+}
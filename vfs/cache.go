@@ -13,9 +13,10 @@ import (
 	"sync"
 	"time"
 
-	"github.com/djherbis/times"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/lib/file"
+	"github.com/ncw/rclone/lib/kv"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 )
@@ -64,18 +65,22 @@ func (l *CacheMode) Type() string {
 
 // cache opened files
 type cache struct {
-	f      fs.Fs                 // fs for the cache directory
-	opt    *Options              // vfs Options
-	root   string                // root of the cache directory
-	itemMu sync.Mutex            // protects the next two maps
-	item   map[string]*cacheItem // files/directories in the cache
+	f         fs.Fs                 // fs for the cache directory
+	opt       *Options              // vfs Options
+	root      string                // root of the cache directory
+	metaRoot  string                // root of the metadata for partially downloaded files
+	itemMu    sync.Mutex            // protects the next two maps
+	item      map[string]*cacheItem // files/directories in the cache
+	writeBack *writeBack            // files waiting to be uploaded to the remote - nil if not writing back
 }
 
 // cacheItem is stored in the item map
 type cacheItem struct {
-	opens  int       // number of times file is open
-	atime  time.Time // last time file was accessed
-	isFile bool      // if this is a file or a directory
+	opens  int         // number of times file is open
+	atime  time.Time   // last time file was accessed
+	isFile bool        // if this is a file or a directory
+	size   int64       // size of the file in the cache
+	sparse *sparseInfo // set if the file is only partially downloaded
 }
 
 // newCacheItem returns an item for the cache
@@ -83,11 +88,9 @@ func newCacheItem(isFile bool) *cacheItem {
 	return &cacheItem{atime: time.Now(), isFile: isFile}
 }
 
-// newCache creates a new cache heirachy for f
-//
-// This starts background goroutines which can be cancelled with the
-// context passed in.
-func newCache(ctx context.Context, f fs.Fs, opt *Options) (*cache, error) {
+// fsCachePath returns the relative path under the cache directories
+// for the things cached about f
+func fsCachePath(f fs.Fs) string {
 	fRoot := filepath.FromSlash(f.Root())
 	if runtime.GOOS == "windows" {
 		if strings.HasPrefix(fRoot, `\\?`) {
@@ -95,19 +98,49 @@ func newCache(ctx context.Context, f fs.Fs, opt *Options) (*cache, error) {
 		}
 		fRoot = strings.Replace(fRoot, ":", "", -1)
 	}
-	root := filepath.Join(config.CacheDir, "vfs", f.Name(), fRoot)
+	return filepath.Join(f.Name(), fRoot)
+}
+
+// openStateDB opens the kv store the state of the VFS for f is saved
+// in between runs, which is shared by the write back queue and the
+// saved directory cache.  Each call should be matched by a Close.
+func openStateDB(f fs.Fs) (*kv.DB, error) {
+	return kv.OpenPath(filepath.Join(kv.Dir(), "vfs", fsCachePath(f)+".bolt"))
+}
+
+// newCache creates a new cache heirachy for f
+//
+// This starts background goroutines which can be cancelled with the
+// context passed in.
+func newCache(ctx context.Context, f fs.Fs, opt *Options) (*cache, error) {
+	root := filepath.Join(config.CacheDir, "vfs", fsCachePath(f))
+	metaRoot := filepath.Join(config.CacheDir, "vfsMeta", fsCachePath(f))
 	fs.Debugf(nil, "vfs cache root is %q", root)
 
-	f, err := fs.NewFs(root)
+	fCache, err := fs.NewFs(root)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create cache remote")
 	}
+	if runtime.GOOS == "windows" {
+		// Use a long path for the OS paths in the cache so deep
+		// directory trees can be cached
+		if absRoot, err := filepath.Abs(root); err == nil {
+			root = file.UNCPath(absRoot)
+		}
+		if absMetaRoot, err := filepath.Abs(metaRoot); err == nil {
+			metaRoot = file.UNCPath(absMetaRoot)
+		}
+	}
 
 	c := &cache{
-		f:    f,
-		opt:  opt,
-		root: root,
-		item: make(map[string]*cacheItem),
+		f:        fCache,
+		opt:      opt,
+		root:     root,
+		metaRoot: metaRoot,
+		item:     make(map[string]*cacheItem),
+	}
+	if opt.WriteBack {
+		c.writeBack = newWriteBack(ctx, c, f, opt)
 	}
 
 	go c.cleaner(ctx)
@@ -139,13 +172,19 @@ func (c *cache) toOSPath(name string) string {
 	return filepath.Join(c.root, filepath.FromSlash(name))
 }
 
+// toMetaPath turns a remote relative name into an OS path for its
+// metadata in the cache
+func (c *cache) toMetaPath(name string) string {
+	return filepath.Join(c.metaRoot, filepath.FromSlash(name))
+}
+
 // mkdir makes the directory for name in the cache and returns an os
 // path for the file
 func (c *cache) mkdir(name string) (string, error) {
 	parent := findParent(name)
 	leaf := path.Base(name)
 	parentPath := c.toOSPath(parent)
-	err := os.MkdirAll(parentPath, 0700)
+	err := file.MkdirAll(parentPath, 0700)
 	if err != nil {
 		return "", errors.Wrap(err, "make cache directory failed")
 	}
@@ -211,6 +250,17 @@ func (c *cache) updateTime(name string, when time.Time) {
 	c.itemMu.Unlock()
 }
 
+// updateSize sets the size of the file name in the cache
+//
+// name should be a remote path not an osPath
+func (c *cache) updateSize(name string, size int64) {
+	name = clean(name)
+	c.itemMu.Lock()
+	item, _ := c._get(true, name)
+	item.size = size
+	c.itemMu.Unlock()
+}
+
 // _open marks name as open, must be called with the lock held
 //
 // name should be a remote path not an osPath
@@ -293,6 +343,11 @@ func (c *cache) remove(name string) {
 	} else {
 		fs.Debugf(name, "Removed from cache")
 	}
+	// Remove the record of which parts were downloaded if any
+	err = os.Remove(c.toMetaPath(name))
+	if err != nil && !os.IsNotExist(err) {
+		fs.Errorf(name, "Failed to remove cache metadata: %v", err)
+	}
 }
 
 // removeDir should be called if dir is deleted and returns true if
@@ -314,6 +369,10 @@ func (c *cache) removeDir(dir string) bool {
 
 // cleanUp empties the cache of everything
 func (c *cache) cleanUp() error {
+	err := os.RemoveAll(c.metaRoot)
+	if err != nil {
+		return err
+	}
 	return os.RemoveAll(c.root)
 }
 
@@ -343,8 +402,9 @@ func (c *cache) updateAtimes() error {
 	return c.walk(func(osPath string, fi os.FileInfo, name string) error {
 		if !fi.IsDir() {
 			// Update the atime with that of the file
-			atime := times.Get(fi).AccessTime()
+			atime := accessTime(fi)
 			c.updateTime(name, atime)
+			c.updateSize(name, fi.Size())
 		} else {
 			c.cacheDir(name)
 		}
@@ -391,6 +451,56 @@ func (c *cache) _purgeOld(maxAge time.Duration, remove func(name string), remove
 	}
 }
 
+// purgeOverQuota removes the least recently used files until the
+// cache is under maxSize bytes
+func (c *cache) purgeOverQuota(maxSize int64) {
+	c._purgeOverQuota(maxSize, c.remove)
+}
+
+func (c *cache) _purgeOverQuota(maxSize int64, remove func(name string)) {
+	c.itemMu.Lock()
+	defer c.itemMu.Unlock()
+	var used int64
+	var items cacheNamedItems
+	for name, item := range c.item {
+		if !item.isFile {
+			continue
+		}
+		used += item.size
+		// Files which are open may be being written or uploaded
+		if item.opens == 0 {
+			items = append(items, cacheNamedItem{name: name, item: item})
+		}
+	}
+	if used <= maxSize {
+		return
+	}
+	// remove the files with the oldest access times first
+	sort.Sort(items)
+	for _, namedItem := range items {
+		if used <= maxSize {
+			break
+		}
+		fs.Debugf(namedItem.name, "Removing from cache as over quota: used %d > max %d", used, maxSize)
+		remove(namedItem.name)
+		delete(c.item, namedItem.name)
+		used -= namedItem.item.size
+	}
+}
+
+// cacheNamedItem is a cacheItem with its name
+type cacheNamedItem struct {
+	name string
+	item *cacheItem
+}
+
+// cacheNamedItems is a slice of cacheNamedItem sorted by atime
+type cacheNamedItems []cacheNamedItem
+
+func (v cacheNamedItems) Len() int           { return len(v) }
+func (v cacheNamedItems) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
+func (v cacheNamedItems) Less(i, j int) bool { return v[i].item.atime.Before(v[j].item.atime) }
+
 // clean empties the cache of stuff if it can
 func (c *cache) clean() {
 	// Cache may be empty so end
@@ -407,6 +517,12 @@ func (c *cache) clean() {
 		fs.Errorf(nil, "Error traversing cache %q: %v", c.root, err)
 	}
 
+	// Remove the least recently used files if the cache is too
+	// big
+	if c.opt.CacheMaxSize >= 0 {
+		c.purgeOverQuota(int64(c.opt.CacheMaxSize))
+	}
+
 	// Now remove any files that are over age and any empty
 	// directories
 	c.purgeOld(c.opt.CacheMaxAge)
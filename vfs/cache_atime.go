@@ -0,0 +1,17 @@
+// Read the access time of cache files
+
+// +build !js
+
+package vfs
+
+import (
+	"os"
+	"time"
+
+	"github.com/djherbis/times"
+)
+
+// accessTime returns the access time of the cache file fi
+func accessTime(fi os.FileInfo) time.Time {
+	return times.Get(fi).AccessTime()
+}
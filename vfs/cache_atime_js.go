@@ -0,0 +1,16 @@
+// Read the access time of cache files - for js which doesn't have it
+
+// +build js
+
+package vfs
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime returns the modification time of the cache file fi as
+// the access time isn't available
+func accessTime(fi os.FileInfo) time.Time {
+	return fi.ModTime()
+}
@@ -460,3 +460,52 @@ func TestCachePurgeOld(t *testing.T) {
 
 	assert.Equal(t, []string(nil), itemAsString(c))
 }
+
+func TestCachePurgeOverQuota(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := newCache(ctx, r.Fremote, &DefaultOpt)
+	require.NoError(t, err)
+
+	// Test funcs
+	var removed []string
+	removeFile := func(name string) {
+		removed = append(removed, name)
+	}
+
+	now := time.Now()
+	for i, name := range []string{"sub/newest", "sub/middle", "oldest"} {
+		c.open(name)
+		c.close(name)
+		c.updateSize(name, 100)
+		c.get(name).atime = now.Add(-time.Duration(i) * time.Minute)
+	}
+	c.open("sub/open")
+	c.updateSize("sub/open", 100)
+	c.get("sub/open").atime = now.Add(-time.Hour)
+
+	// under quota so nothing removed
+	removed = nil
+	c._purgeOverQuota(400, removeFile)
+	assert.Equal(t, []string(nil), removed)
+
+	// remove the least recently used closed files first
+	removed = nil
+	c._purgeOverQuota(200, removeFile)
+	assert.Equal(t, []string{"oldest", "sub/middle"}, removed)
+
+	// open files are never removed
+	removed = nil
+	c._purgeOverQuota(0, removeFile)
+	assert.Equal(t, []string{"sub/newest"}, removed)
+
+	assert.Equal(t, []string{
+		`name="" isFile=false opens=1`,
+		`name="sub" isFile=false opens=1`,
+		`name="sub/open" isFile=true opens=1`,
+	}, itemAsString(c))
+}
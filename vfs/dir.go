@@ -16,7 +16,6 @@ import (
 // Dir represents a directory entry
 type Dir struct {
 	vfs     *VFS
-	inode   uint64 // inode number
 	f       fs.Fs
 	parent  *Dir // parent, nil for root
 	path    string
@@ -35,7 +34,6 @@ func newDir(vfs *VFS, f fs.Fs, parent *Dir, fsDir fs.Directory) *Dir {
 		entry:   fsDir,
 		path:    fsDir.Remote(),
 		modTime: fsDir.ModTime(),
-		inode:   newInode(),
 		items:   make(map[string]Node),
 	}
 }
@@ -84,7 +82,7 @@ func (d *Dir) Sys() interface{} {
 
 // Inode returns the inode number - satisfies Node interface
 func (d *Dir) Inode() uint64 {
-	return d.inode
+	return pathInode(d.Path())
 }
 
 // Node returns the Node assocuated with this - satisfies Noder interface
@@ -169,12 +167,18 @@ func (d *Dir) delObject(leaf string) {
 
 // read the directory and sets d.items - must be called with the lock held
 func (d *Dir) _readDir() error {
+	return d._readDirMaxAge(d.vfs.Opt.DirCacheTime)
+}
+
+// read the directory and sets d.items if the items are older than
+// maxAge - must be called with the lock held
+func (d *Dir) _readDirMaxAge(maxAge time.Duration) error {
 	when := time.Now()
 	if d.read.IsZero() {
 		// fs.Debugf(d.path, "Reading directory")
 	} else {
 		age := when.Sub(d.read)
-		if age < d.vfs.Opt.DirCacheTime {
+		if age < maxAge {
 			return nil
 		}
 		fs.Debugf(d.path, "Re-reading directory (%v old)", age)
@@ -189,6 +193,34 @@ func (d *Dir) _readDir() error {
 
 	// Cache the items by name
 	found := make(map[string]struct{})
+	_, err = d._addEntries(entries, found)
+	if err != nil {
+		return err
+	}
+	// delete unused entries
+	for name, node := range d.items {
+		if _, ok := found[name]; !ok && !keepUnlisted(node) {
+			delete(d.items, name)
+		}
+	}
+	d.read = when
+	return nil
+}
+
+// keepUnlisted returns true if node should be kept in the directory
+// even though it wasn't in the listing from the remote, which is true
+// for files still waiting to be uploaded from the cache.
+func keepUnlisted(node Node) bool {
+	file, ok := node.(*File)
+	return ok && file.writeBackPending()
+}
+
+// _addEntries adds the entries read from the remote to d.items
+// reusing the existing nodes where possible.  It marks the names as
+// found and returns the nodes for the entries.
+//
+// Call with the lock held
+func (d *Dir) _addEntries(entries fs.DirEntries, found map[string]struct{}) (items Nodes, err error) {
 	for _, entry := range entries {
 		name := path.Base(entry.Remote())
 		node := d.items[name]
@@ -196,9 +228,13 @@ func (d *Dir) _readDir() error {
 		switch item := entry.(type) {
 		case fs.Object:
 			obj := item
-			// Reuse old file value if it exists
+			// Reuse old file value if it exists, keeping its
+			// object if it is waiting to be uploaded as the one
+			// on the remote is out of date
 			if file, ok := node.(*File); node != nil && ok {
-				file.setObjectNoUpdate(obj)
+				if !file.writeBackPending() {
+					file.setObjectNoUpdate(obj)
+				}
 			} else {
 				node = newFile(d, obj, name)
 			}
@@ -211,18 +247,50 @@ func (d *Dir) _readDir() error {
 		default:
 			err = errors.Errorf("unknown type %T", item)
 			fs.Errorf(d, "readDir error: %v", err)
-			return err
+			return nil, err
 		}
 		d.items[name] = node
+		items = append(items, node)
 	}
-	// delete unused entries
-	for name := range d.items {
-		if _, ok := found[name]; !ok {
-			delete(d.items, name)
+	return items, nil
+}
+
+// refresh re-reads the directory from the remote regardless of how
+// old the cached listing is
+func (d *Dir) refresh() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d._readDirMaxAge(0)
+}
+
+// refreshRecursive re-reads the directory and all the directories
+// under it from the remote.
+//
+// It carries on if a directory can't be read, returning the first
+// error.
+func (d *Dir) refreshRecursive() error {
+	err := d.refresh()
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	var dirs []*Dir
+	for _, node := range d.items {
+		if dir, ok := node.(*Dir); ok {
+			dirs = append(dirs, dir)
 		}
 	}
-	d.read = when
-	return nil
+	d.mu.Unlock()
+	for _, dir := range dirs {
+		dirErr := dir.refreshRecursive()
+		if dirErr != nil {
+			fs.Errorf(dir, "Failed to refresh directory: %v", dirErr)
+			if err == nil {
+				err = dirErr
+			}
+		}
+	}
+	return err
 }
 
 // stat a single item in the directory
@@ -236,6 +304,15 @@ func (d *Dir) stat(leaf string) (Node, error) {
 		return nil, err
 	}
 	item, ok := d.items[leaf]
+	if !ok && d.vfs.Opt.NegativeCacheTime < d.vfs.Opt.DirCacheTime {
+		// Don't remember that leaf doesn't exist for longer
+		// than --dir-cache-negative-time
+		err = d._readDirMaxAge(d.vfs.Opt.NegativeCacheTime)
+		if err != nil {
+			return nil, err
+		}
+		item, ok = d.items[leaf]
+	}
 	if !ok {
 		return nil, ENOENT
 	}
@@ -312,6 +389,77 @@ func (d *Dir) ReadDirAll() (items Nodes, err error) {
 	return items, nil
 }
 
+// ReadDirPages reads the contents of the directory calling fn with
+// each page of items as it is read.
+//
+// If the directory listing is cached fn is called once with all the
+// items sorted.  Otherwise, if the remote can list in pages, fn is
+// called with each page as it arrives so the start of a very large
+// directory can be used before the listing is complete.  The items in
+// each page are sorted but the pages aren't sorted with respect to
+// each other.
+//
+// If fn returns an error the listing stops and that error is
+// returned.
+func (d *Dir) ReadDirPages(fn func(items Nodes) error) error {
+	d.mu.Lock()
+	if !d.read.IsZero() && time.Since(d.read) < d.vfs.Opt.DirCacheTime {
+		items := make(Nodes, 0, len(d.items))
+		for _, item := range d.items {
+			items = append(items, item)
+		}
+		d.mu.Unlock()
+		sort.Sort(items)
+		return fn(items)
+	}
+	// Remember what was there before so only those items are
+	// removed if they weren't listed
+	before := make(map[string]struct{}, len(d.items))
+	for name := range d.items {
+		before[name] = struct{}{}
+	}
+	d.mu.Unlock()
+
+	when := time.Now()
+	found := make(map[string]struct{})
+	err := list.DirPages(d.f, false, d.path, func(entries fs.DirEntries) error {
+		d.mu.Lock()
+		items, err := d._addEntries(entries, found)
+		d.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		return fn(items)
+	})
+	if err == fs.ErrorDirNotFound {
+		// We treat directory not found as empty because we
+		// create directories on the fly
+	} else if err != nil {
+		fs.Debugf(d.path, "Dir.ReadDirPages error: %v", err)
+		return err
+	}
+
+	d.mu.Lock()
+	var unlisted Nodes
+	for name := range before {
+		if _, ok := found[name]; !ok {
+			if node := d.items[name]; keepUnlisted(node) {
+				unlisted = append(unlisted, node)
+				continue
+			}
+			delete(d.items, name)
+		}
+	}
+	d.read = when
+	d.mu.Unlock()
+	if len(unlisted) > 0 {
+		// files waiting to be uploaded aren't on the remote yet
+		sort.Sort(unlisted)
+		return fn(unlisted)
+	}
+	return nil
+}
+
 // accessModeMask masks off the read modes from the flags
 const accessModeMask = (os.O_RDONLY | os.O_WRONLY | os.O_RDWR)
 
@@ -437,12 +585,18 @@ func (d *Dir) Rename(oldName, newName string, destDir *Dir) error {
 		fs.Errorf(oldPath, "Dir.Rename error: %v", err)
 		return err
 	}
+	// Upload anything waiting to be written back first so it is
+	// renamed too
+	err = d.vfs.cache.writeBack.wait(oldPath)
+	if err != nil {
+		fs.Errorf(oldPath, "Dir.Rename error: %v", err)
+		return err
+	}
 	switch x := oldNode.DirEntry().(type) {
 	case nil:
 		fs.Errorf(oldPath, "Dir.Rename cant rename open file")
 		return EPERM
 	case fs.Object:
-		oldObject := x
 		// FIXME: could Copy then Delete if Move not available
 		// - though care needed if case insensitive...
 		doMove := d.f.Features().Move
@@ -451,6 +605,11 @@ func (d *Dir) Rename(oldName, newName string, destDir *Dir) error {
 			fs.Errorf(oldPath, "Dir.Rename error: %v", err)
 			return err
 		}
+		oldObject, err := realObject(x)
+		if err != nil {
+			fs.Errorf(oldPath, "Dir.Rename error: %v", err)
+			return err
+		}
 		newObject, err := doMove(oldObject, newPath)
 		if err != nil {
 			fs.Errorf(oldPath, "Dir.Rename error: %v", err)
@@ -499,6 +658,58 @@ func (d *Dir) Rename(oldName, newName string, destDir *Dir) error {
 	return nil
 }
 
+// Link makes a copy of file called newName in d using a server side
+// copy, returning the new file.
+//
+// This is used to emulate hard links on file systems which don't
+// have them.  Unlike a hard link the copy is independent of the
+// original once it is made.
+func (d *Dir) Link(file *File, newName string) (*File, error) {
+	if d.vfs.Opt.ReadOnly {
+		return nil, EROFS
+	}
+	oldPath := file.Path()
+	newPath := path.Join(d.path, newName)
+	_, err := d.stat(newName)
+	if err == nil {
+		return nil, EEXIST
+	} else if err != ENOENT {
+		fs.Errorf(newPath, "Dir.Link error: %v", err)
+		return nil, err
+	}
+	// Upload anything waiting to be written back first so it is
+	// copied too
+	err = d.vfs.cache.writeBack.wait(oldPath)
+	if err != nil {
+		fs.Errorf(oldPath, "Dir.Link error: %v", err)
+		return nil, err
+	}
+	oldObject, ok := file.DirEntry().(fs.Object)
+	if !ok || oldObject == nil {
+		fs.Errorf(oldPath, "Dir.Link can't link open file")
+		return nil, EPERM
+	}
+	doCopy := d.f.Features().Copy
+	if doCopy == nil {
+		err := errors.Errorf("Fs %q can't link files (no Copy)", d.f)
+		fs.Errorf(oldPath, "Dir.Link error: %v", err)
+		return nil, EPERM
+	}
+	oldObject, err = realObject(oldObject)
+	if err != nil {
+		fs.Errorf(oldPath, "Dir.Link error: %v", err)
+		return nil, err
+	}
+	newObject, err := doCopy(oldObject, newPath)
+	if err != nil {
+		fs.Errorf(oldPath, "Dir.Link error: %v", err)
+		return nil, err
+	}
+	linked := newFile(d, newObject, newName)
+	d.addObject(linked)
+	return linked, nil
+}
+
 // Sync the directory
 //
 // Note that we don't do anything except return OK
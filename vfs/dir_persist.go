@@ -0,0 +1,311 @@
+// Save the directory cache to disk so it survives remounts
+
+package vfs
+
+import (
+	"encoding/json"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/lib/kv"
+	"github.com/pkg/errors"
+)
+
+// dirCacheVersion is the version of the saved directory cache format
+const dirCacheVersion = 1
+
+// The saved directory cache is kept in the kv store.  The version key
+// holds dirCacheVersion and each directory listing is kept under its
+// path with dirKeyPrefix in front.
+const (
+	dirVersionKey = "version"
+	dirKeyPrefix  = "/"
+)
+
+// savedEntry is a file or directory in a saved directory listing
+type savedEntry struct {
+	Name    string    `json:"name"`
+	IsDir   bool      `json:"isDir,omitempty"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// updateDirCache opens the kv store and calls fn with the namespace
+// the directory cache is saved in
+func (vfs *VFS) updateDirCache(fn func(ns *kv.Namespace) error) error {
+	db, err := openStateDB(vfs.f)
+	if err != nil {
+		return err
+	}
+	err = fn(db.Namespace("dirs"))
+	closeErr := db.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// saveDirCache writes the directory listings read so far to disk
+// replacing the ones saved before
+func (vfs *VFS) saveDirCache() error {
+	dirs := make(map[string][]byte)
+	var err error
+	vfs.root.walk("", func(d *Dir) {
+		// NB d.mu is held by walk() here
+		if d.read.IsZero() || err != nil {
+			return
+		}
+		names := make([]string, 0, len(d.items))
+		for name := range d.items {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		entries := make([]savedEntry, 0, len(names))
+		for _, name := range names {
+			switch x := d.items[name].(type) {
+			case *File:
+				o := x.getObject()
+				if o == nil {
+					// still being written so not on the remote yet
+					continue
+				}
+				entries = append(entries, savedEntry{Name: name, Size: o.Size(), ModTime: o.ModTime()})
+			case *Dir:
+				entries = append(entries, savedEntry{Name: name, IsDir: true, ModTime: x.ModTime()})
+			}
+		}
+		dirs[d.path], err = json.Marshal(entries)
+	})
+	if err != nil {
+		return err
+	}
+	return vfs.updateDirCache(func(ns *kv.Namespace) error {
+		return ns.Update(func(tx *kv.Tx) error {
+			var oldKeys []string
+			err := tx.ForEach(func(key string, _ []byte) error {
+				oldKeys = append(oldKeys, key)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, key := range oldKeys {
+				err = tx.Delete(key)
+				if err != nil {
+					return err
+				}
+			}
+			err = tx.Put(dirVersionKey, []byte{dirCacheVersion})
+			if err != nil {
+				return err
+			}
+			for dir, data := range dirs {
+				err = tx.Put(dirKeyPrefix+dir, data)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// loadDirCache fills the directory tree from the listings saved by
+// saveDirCache if there are any.
+//
+// The listings are treated as if they had just been read so they are
+// used until --dir-cache-time has passed and are then read again from
+// the remote the next time they are needed.
+//
+// This must be called before the VFS is in use.
+func (vfs *VFS) loadDirCache() error {
+	saved := make(map[string][]savedEntry)
+	version := -1
+	err := vfs.updateDirCache(func(ns *kv.Namespace) error {
+		return ns.View(func(tx *kv.Tx) error {
+			return tx.ForEach(func(key string, value []byte) error {
+				if key == dirVersionKey && len(value) == 1 {
+					version = int(value[0])
+				}
+				if !strings.HasPrefix(key, dirKeyPrefix) {
+					return nil
+				}
+				var entries []savedEntry
+				err := json.Unmarshal(value, &entries)
+				if err != nil {
+					return errors.Wrapf(err, "corrupted directory cache for %q", key[len(dirKeyPrefix):])
+				}
+				saved[key[len(dirKeyPrefix):]] = entries
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if len(saved) == 0 {
+		return nil
+	}
+	if version != dirCacheVersion {
+		fs.Debugf(nil, "Ignoring directory cache with version %d", version)
+		return nil
+	}
+	now := time.Now()
+	dirs := 0
+	var load func(d *Dir)
+	load = func(d *Dir) {
+		entries, ok := saved[d.path]
+		if !ok {
+			return
+		}
+		for _, entry := range entries {
+			remote := path.Join(d.path, entry.Name)
+			if entry.IsDir {
+				dir := newDir(vfs, vfs.f, d, fs.NewDir(remote, entry.ModTime))
+				d.items[entry.Name] = dir
+				load(dir)
+			} else {
+				o := &savedObject{f: vfs.f, remote: remote, size: entry.Size, modTime: entry.ModTime}
+				d.items[entry.Name] = newFile(d, o, entry.Name)
+			}
+		}
+		d.read = now
+		dirs++
+	}
+	load(vfs.root)
+	fs.Debugf(nil, "Loaded %d directories from the directory cache", dirs)
+	return nil
+}
+
+// savedObject is a file from a saved directory listing.
+//
+// It knows the name, size and modification time of the file and finds
+// the object on the remote the first time anything else is needed.
+type savedObject struct {
+	f       fs.Fs
+	remote  string
+	size    int64
+	modTime time.Time
+	mu      sync.Mutex
+	o       fs.Object // the object on the remote once found
+}
+
+// realObject returns the object on the remote for o which may be from
+// a saved directory listing
+func realObject(o fs.Object) (fs.Object, error) {
+	if saved, ok := o.(*savedObject); ok {
+		return saved.object()
+	}
+	return o, nil
+}
+
+// object finds the object on the remote
+func (o *savedObject) object() (fs.Object, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.o == nil {
+		obj, err := o.f.NewObject(o.remote)
+		if err != nil {
+			return nil, err
+		}
+		o.o = obj
+	}
+	return o.o, nil
+}
+
+// found returns the object on the remote if it has been found already
+func (o *savedObject) found() fs.Object {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.o
+}
+
+// Fs returns the parent Fs
+func (o *savedObject) Fs() fs.Info {
+	return o.f
+}
+
+// String returns a description of the Object
+func (o *savedObject) String() string {
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *savedObject) Remote() string {
+	return o.remote
+}
+
+// ModTime returns the modification time of the object
+func (o *savedObject) ModTime() time.Time {
+	if obj := o.found(); obj != nil {
+		return obj.ModTime()
+	}
+	return o.modTime
+}
+
+// Size returns the size of the object in bytes
+func (o *savedObject) Size() int64 {
+	if obj := o.found(); obj != nil {
+		return obj.Size()
+	}
+	return o.size
+}
+
+// Storable says whether this object can be stored
+func (o *savedObject) Storable() bool {
+	return true
+}
+
+// Hash returns the requested hash of the object
+func (o *savedObject) Hash(ht hash.Type) (string, error) {
+	obj, err := o.object()
+	if err != nil {
+		return "", err
+	}
+	return obj.Hash(ht)
+}
+
+// SetModTime sets the modification time of the object
+func (o *savedObject) SetModTime(modTime time.Time) error {
+	obj, err := o.object()
+	if err != nil {
+		return err
+	}
+	return obj.SetModTime(modTime)
+}
+
+// Open opens the object for reading
+func (o *savedObject) Open(options ...fs.OpenOption) (io.ReadCloser, error) {
+	obj, err := o.object()
+	if err != nil {
+		return nil, err
+	}
+	return obj.Open(options...)
+}
+
+// Update replaces the contents of the object
+func (o *savedObject) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	obj, err := o.object()
+	if err != nil {
+		return err
+	}
+	return obj.Update(in, src, options...)
+}
+
+// Remove removes the object
+func (o *savedObject) Remove() error {
+	obj, err := o.object()
+	if err != nil {
+		return err
+	}
+	return obj.Remove()
+}
+
+// Check the interfaces are satisfied
+var _ fs.Object = (*savedObject)(nil)
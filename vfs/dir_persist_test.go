@@ -0,0 +1,75 @@
+package vfs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirCachePersist(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	oldCacheDir := config.CacheDir
+	cacheDir, err := ioutil.TempDir("", "rclone-vfs-dirs")
+	require.NoError(t, err)
+	config.CacheDir = cacheDir
+	defer func() {
+		config.CacheDir = oldCacheDir
+		_ = os.RemoveAll(cacheDir)
+	}()
+
+	file1 := r.WriteObject("dir/file1", "file1 contents", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	opt := DefaultOpt
+	opt.DirCachePersist = true
+
+	// Nothing saved yet
+	vfs := New(r.Fremote, &opt)
+	assert.True(t, vfs.root.read.IsZero())
+	checkListing(t, vfs.root, []string{"dir,0,true"})
+	node, err := vfs.Stat("dir")
+	require.NoError(t, err)
+	checkListing(t, node.(*Dir), []string{"file1,14,false"})
+	vfs.Shutdown()
+
+	// Listings read from the saved cache
+	vfs = New(r.Fremote, &opt)
+	defer vfs.Shutdown()
+	assert.False(t, vfs.root.read.IsZero())
+	dir, ok := vfs.root.items["dir"].(*Dir)
+	require.True(t, ok)
+	assert.False(t, dir.read.IsZero())
+	file, ok := dir.items["file1"].(*File)
+	require.True(t, ok)
+	saved, ok := file.getObject().(*savedObject)
+	require.True(t, ok)
+	assert.Nil(t, saved.found())
+	assert.Equal(t, int64(14), file.Size())
+	assert.Equal(t, t1, file.ModTime())
+
+	// The object on the remote is found when the file is read
+	fd, err := file.Open(os.O_RDONLY)
+	require.NoError(t, err)
+	contents, err := ioutil.ReadAll(fd)
+	require.NoError(t, err)
+	require.NoError(t, fd.Close())
+	assert.Equal(t, "file1 contents", string(contents))
+	assert.NotNil(t, saved.found())
+
+	// Files from the saved cache can be renamed
+	if r.Fremote.Features().Move != nil {
+		file2, ok := dir.items["file1"].(*File)
+		require.True(t, ok)
+		file2.o = &savedObject{f: r.Fremote, remote: "dir/file1", size: 14, modTime: t1}
+		require.NoError(t, dir.Rename("file1", "file2", dir))
+		file1.Path = "dir/file2"
+		fstest.CheckItems(t, r.Fremote, file1)
+	}
+}
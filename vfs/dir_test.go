@@ -9,6 +9,7 @@ import (
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fstest"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -222,6 +223,26 @@ func TestDirStat(t *testing.T) {
 	assert.Equal(t, ENOENT, err)
 }
 
+func TestDirStatNegativeCache(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	vfs, dir, _ := dirCreate(t, r)
+
+	_, err := dir.Stat("file2")
+	assert.Equal(t, ENOENT, err)
+
+	// The not found is remembered with the default options
+	r.WriteObject("dir/file2", "file2 contents", t1)
+	_, err = dir.Stat("file2")
+	assert.Equal(t, ENOENT, err)
+
+	// But not if the negative cache time has expired
+	vfs.Opt.NegativeCacheTime = 0
+	node, err := dir.Stat("file2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(14), node.Size())
+}
+
 // This lists dir and checks the listing is as expected
 func checkListing(t *testing.T, dir *Dir, want []string) {
 	var got []string
@@ -262,6 +283,49 @@ func TestDirReadDirAll(t *testing.T) {
 	checkListing(t, dir, []string{"file3,16,false"})
 }
 
+func TestDirReadDirPages(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	vfs := New(r.Fremote, nil)
+
+	file1 := r.WriteObject("dir/file1", "file1 contents", t1)
+	file2 := r.WriteObject("dir/file2", "file2- contents", t2)
+	file3 := r.WriteObject("dir/subdir/file3", "file3-- contents", t3)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	node, err := vfs.Stat("dir")
+	require.NoError(t, err)
+	dir := node.(*Dir)
+
+	readNames := func() (names []string) {
+		err := dir.ReadDirPages(func(items Nodes) error {
+			for _, item := range items {
+				names = append(names, item.Name())
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		sort.Strings(names)
+		return names
+	}
+	assert.Equal(t, []string{"file1", "file2", "subdir"}, readNames())
+	checkListing(t, dir, []string{"file1,14,false", "file2,15,false", "subdir,0,true"})
+
+	// An error from the callback stops the listing
+	stop := errors.New("stop")
+	vfs.Opt.DirCacheTime = 0
+	err = dir.ReadDirPages(func(items Nodes) error {
+		return stop
+	})
+	assert.Equal(t, stop, err)
+
+	// Items removed from the remote are removed when re-read
+	o, err := r.Fremote.NewObject(file2.Path)
+	require.NoError(t, err)
+	require.NoError(t, o.Remove())
+	assert.Equal(t, []string{"file1", "subdir"}, readNames())
+}
+
 func TestDirOpen(t *testing.T) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
@@ -460,3 +524,35 @@ func TestDirRename(t *testing.T) {
 	err = dir.Rename("potato", "tuba", dir)
 	assert.Equal(t, EROFS, err)
 }
+
+func TestDirLink(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	vfs, dir, file1 := dirCreate(t, r)
+
+	node, err := dir.Stat("file1")
+	require.NoError(t, err)
+	file := node.(*File)
+
+	// Can't link over an existing file
+	_, err = dir.Link(file, "file1")
+	assert.Equal(t, EEXIST, err)
+
+	newFile, err := dir.Link(file, "file2")
+	if r.Fremote.Features().Copy == nil {
+		// needs server side copy
+		assert.Equal(t, EPERM, err)
+	} else {
+		require.NoError(t, err)
+		assert.Equal(t, "dir/file2", newFile.Path())
+		checkListing(t, dir, []string{"file1,14,false", "file2,14,false"})
+		file2 := file1
+		file2.Path = "dir/file2"
+		fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1, file2}, []string{"dir"}, r.Fremote.Precision())
+	}
+
+	// read only check
+	vfs.Opt.ReadOnly = true
+	_, err = dir.Link(file, "potato")
+	assert.Equal(t, EROFS, err)
+}
@@ -0,0 +1,113 @@
+// Track which parts of a file have been downloaded
+
+package vfs
+
+// extent is a contiguous range of bytes in a file
+type extent struct {
+	Pos  int64 // offset of the start of the extent
+	Size int64 // number of bytes in the extent
+}
+
+// end returns the offset of the byte after the extent
+func (e extent) end() int64 {
+	return e.Pos + e.Size
+}
+
+// isEmpty returns true if the extent has no bytes in it
+func (e extent) isEmpty() bool {
+	return e.Size <= 0
+}
+
+// clip returns the part of the extent which is inside a file of size
+// bytes
+func (e extent) clip(size int64) extent {
+	if e.Pos < 0 {
+		e.Size += e.Pos
+		e.Pos = 0
+	}
+	if e.end() > size {
+		e.Size = size - e.Pos
+	}
+	if e.Size < 0 {
+		e.Size = 0
+	}
+	return e
+}
+
+// extents is a sorted list of non overlapping, non adjacent extents
+type extents []extent
+
+// insert adds e to the extents, merging it with any extents it
+// overlaps or touches
+func (xs *extents) insert(e extent) {
+	if e.isEmpty() {
+		return
+	}
+	var result extents
+	i := 0
+	old := *xs
+	// copy the extents wholly before e
+	for ; i < len(old) && old[i].end() < e.Pos; i++ {
+		result = append(result, old[i])
+	}
+	// merge the extents which overlap or touch e
+	for ; i < len(old) && old[i].Pos <= e.end(); i++ {
+		start, end := e.Pos, e.end()
+		if old[i].Pos < start {
+			start = old[i].Pos
+		}
+		if old[i].end() > end {
+			end = old[i].end()
+		}
+		e = extent{Pos: start, Size: end - start}
+	}
+	result = append(result, e)
+	// copy the extents wholly after e
+	result = append(result, old[i:]...)
+	*xs = result
+}
+
+// present returns true if all of e is in the extents
+func (xs extents) present(e extent) bool {
+	return xs.findMissing(e).isEmpty()
+}
+
+// findMissing returns the first part of e which isn't in the
+// extents.  This stops at the start of the next extent which is
+// present, so it may be shorter than the gap in e.
+//
+// It returns an empty extent if all of e is present.
+func (xs extents) findMissing(e extent) extent {
+	for _, x := range xs {
+		if e.isEmpty() {
+			break
+		}
+		if x.end() <= e.Pos {
+			continue
+		}
+		if x.Pos > e.Pos {
+			// gap before x
+			if x.Pos < e.end() {
+				e.Size = x.Pos - e.Pos
+			}
+			return e
+		}
+		// x covers the start of e so skip past it
+		if x.end() >= e.end() {
+			return extent{Pos: e.end()}
+		}
+		e = extent{Pos: x.end(), Size: e.end() - x.end()}
+	}
+	if e.isEmpty() {
+		return extent{Pos: e.Pos}
+	}
+	return e
+}
+
+// size returns the total number of bytes in the extents
+func (xs extents) size() (total int64) {
+	for _, x := range xs {
+		total += x.Size
+	}
+	return total
+}
@@ -0,0 +1,67 @@
+package vfs
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtentClip(t *testing.T) {
+	for _, test := range []struct {
+		in   extent
+		size int64
+		want extent
+	}{
+		{extent{Pos: 0, Size: 10}, 20, extent{Pos: 0, Size: 10}},
+		{extent{Pos: 15, Size: 10}, 20, extent{Pos: 15, Size: 5}},
+		{extent{Pos: 25, Size: 10}, 20, extent{Pos: 25, Size: 0}},
+		{extent{Pos: -5, Size: 10}, 20, extent{Pos: 0, Size: 5}},
+	} {
+		assert.Equal(t, test.want, test.in.clip(test.size), fmt.Sprintf("%+v", test))
+	}
+}
+
+func TestExtentsInsert(t *testing.T) {
+	for _, test := range []struct {
+		xs   extents
+		in   extent
+		want extents
+	}{
+		{nil, extent{Pos: 1, Size: 0}, nil},
+		{nil, extent{Pos: 1, Size: 2}, extents{{1, 2}}},
+		{extents{{1, 2}}, extent{Pos: 5, Size: 2}, extents{{1, 2}, {5, 2}}},
+		{extents{{5, 2}}, extent{Pos: 1, Size: 2}, extents{{1, 2}, {5, 2}}},
+		{extents{{1, 2}}, extent{Pos: 3, Size: 2}, extents{{1, 4}}},
+		{extents{{3, 2}}, extent{Pos: 1, Size: 2}, extents{{1, 4}}},
+		{extents{{1, 2}, {5, 2}}, extent{Pos: 2, Size: 4}, extents{{1, 6}}},
+		{extents{{1, 2}, {5, 2}, {10, 1}}, extent{Pos: 0, Size: 8}, extents{{0, 8}, {10, 1}}},
+		{extents{{1, 10}}, extent{Pos: 2, Size: 3}, extents{{1, 10}}},
+	} {
+		xs := append(extents(nil), test.xs...)
+		xs.insert(test.in)
+		assert.Equal(t, test.want, xs, fmt.Sprintf("%+v", test))
+	}
+}
+
+func TestExtentsFindMissing(t *testing.T) {
+	xs := extents{{10, 10}, {30, 10}}
+	for _, test := range []struct {
+		in   extent
+		want extent
+	}{
+		{extent{Pos: 0, Size: 5}, extent{Pos: 0, Size: 5}},
+		{extent{Pos: 0, Size: 15}, extent{Pos: 0, Size: 10}},
+		{extent{Pos: 10, Size: 10}, extent{Pos: 20, Size: 0}},
+		{extent{Pos: 12, Size: 5}, extent{Pos: 17, Size: 0}},
+		{extent{Pos: 15, Size: 10}, extent{Pos: 20, Size: 5}},
+		{extent{Pos: 15, Size: 30}, extent{Pos: 20, Size: 10}},
+		{extent{Pos: 35, Size: 10}, extent{Pos: 40, Size: 5}},
+		{extent{Pos: 50, Size: 10}, extent{Pos: 50, Size: 10}},
+	} {
+		got := xs.findMissing(test.in)
+		assert.Equal(t, test.want, got, fmt.Sprintf("%+v", test))
+		assert.Equal(t, got.isEmpty(), xs.present(test.in))
+	}
+	assert.Equal(t, int64(20), xs.size())
+}
@@ -14,9 +14,8 @@ import (
 
 // File represents a file
 type File struct {
-	inode uint64 // inode number
-	size  int64  // size of file - read and written with atomic int64 - must be 64 bit aligned
-	d     *Dir   // parent directory - read only
+	size int64 // size of file - read and written with atomic int64 - must be 64 bit aligned
+	d    *Dir  // parent directory - read only
 
 	mu                sync.Mutex // protects the following
 	o                 fs.Object  // NB o may be nil if file is being written
@@ -35,10 +34,9 @@ type File struct {
 // newFile creates a new File
 func newFile(d *Dir, o fs.Object, leaf string) *File {
 	return &File{
-		d:     d,
-		o:     o,
-		leaf:  leaf,
-		inode: newInode(),
+		d:    d,
+		o:    o,
+		leaf: leaf,
 	}
 }
 
@@ -82,7 +80,7 @@ func (f *File) Sys() interface{} {
 
 // Inode returns the inode number - satisfies Node interface
 func (f *File) Inode() uint64 {
-	return f.inode
+	return pathInode(f.Path())
 }
 
 // Node returns the Node assocuated with this - satisfies Noder interface
@@ -202,6 +200,18 @@ func (f *File) ModTime() (modTime time.Time) {
 	return f.d.modTime
 }
 
+// CreatedTime returns the time the file was created if the backend
+// reports it, or the zero time if not
+func (f *File) CreatedTime() time.Time {
+	f.mu.Lock()
+	o := f.o
+	f.mu.Unlock()
+	if do, ok := o.(fs.CreatedTimer); ok {
+		return do.CreatedTime()
+	}
+	return time.Time{}
+}
+
 // nonNegative returns 0 if i is -ve, i otherwise
 func nonNegative(i int64) int64 {
 	if i >= 0 {
@@ -215,8 +225,9 @@ func (f *File) Size() int64 {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	// if o is nil it isn't valid yet or there are writers, so return the size so far
-	if f.o == nil || len(f.writers) != 0 || f.readWriterClosing {
+	// if o is nil it isn't valid yet or there are writers, so return the size so far,
+	// likewise if the file is waiting to be uploaded so o is out of date
+	if f.o == nil || len(f.writers) != 0 || f.readWriterClosing || f.writeBackPending() {
 		return atomic.LoadInt64(&f.size)
 	}
 	return nonNegative(f.o.Size())
@@ -272,6 +283,12 @@ func (f *File) setSize(n int64) {
 	atomic.StoreInt64(&f.size, n)
 }
 
+// writeBackPending returns true if the file is waiting to be uploaded
+// from the cache
+func (f *File) writeBackPending() bool {
+	return f.d.vfs.cache.writeBack.pending(f.Path())
+}
+
 // Update the object when written and add it to the directory
 func (f *File) setObject(o fs.Object) {
 	f.mu.Lock()
@@ -389,6 +406,8 @@ func (f *File) Remove() error {
 	if f.d.vfs.Opt.ReadOnly {
 		return EROFS
 	}
+	// Stop the file being uploaded if it is waiting to be
+	f.d.vfs.cache.writeBack.remove(f.Path())
 	if f.o != nil {
 		err := f.o.Remove()
 		if err != nil {
@@ -477,6 +496,11 @@ func (f *File) Open(flags int) (fd Handle, err error) {
 		write = true
 	}
 
+	// Refuse writes on a read only VFS whatever sort of handle is used
+	if write && f.d.vfs.Opt.ReadOnly {
+		return nil, EROFS
+	}
+
 	// FIXME discover if file is in cache or not?
 
 	// Open the correct sort of handle
@@ -514,6 +538,10 @@ func (f *File) Open(flags int) (fd Handle, err error) {
 
 // Truncate changes the size of the named file.
 func (f *File) Truncate(size int64) (err error) {
+	if f.d.vfs.Opt.ReadOnly {
+		return EROFS
+	}
+
 	// make a copy of fh.writers with the lock held then unlock so
 	// we can call other file methods.
 	f.mu.Lock()
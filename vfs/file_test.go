@@ -181,4 +181,15 @@ func TestFileOpen(t *testing.T) {
 
 	fd, err = file.Open(3)
 	assert.Equal(t, EPERM, err)
+
+	// only reads are allowed on a read only VFS
+	file.d.vfs.Opt.ReadOnly = true
+	for _, flags := range []int{os.O_WRONLY, os.O_RDWR, os.O_WRONLY | os.O_TRUNC, os.O_RDWR | os.O_APPEND} {
+		_, err = file.Open(flags)
+		assert.Equal(t, EROFS, err, decodeOpenFlags(flags))
+	}
+	fd, err = file.Open(os.O_RDONLY)
+	require.NoError(t, err)
+	require.NoError(t, fd.Close())
+	assert.Equal(t, EROFS, file.Truncate(0))
 }
@@ -11,6 +11,21 @@ backend. Changes made locally in the mount may appear immediately or
 invalidate the cache. However, changes done on the remote will only
 be picked up once the cache expires.
 
+When a file or directory which doesn't exist is looked up, rclone
+remembers that it doesn't exist until the directory listing is
+refreshed.  Programs which poll for a file to appear can see it late
+because of this, so use the ` + "`--dir-cache-negative-time`" + ` flag
+to re-read the directory when a name isn't found in a listing older
+than this.  Set it to 0 to always re-read the directory.  It has no
+effect if it is longer than ` + "`--dir-cache-time`" + `.
+
+Some remotes, currently Amazon Drive, Google Drive, Dropbox and
+OneDrive, can notify rclone of changes made to them.  Rclone checks
+for these every ` + "`--poll-interval`" + ` (1m by default) and forgets
+the directories which have changed, so the ` + "`--dir-cache-time`" + `
+can be set much longer on these remotes.  Set ` + "`--poll-interval 0`" + `
+to disable this.
+
 Alternatively, you can send a ` + "`SIGHUP`" + ` signal to rclone for
 it to flush all directory caches, regardless of how old they are.
 Assuming only one rclone instance is running, you can reset the cache
@@ -27,6 +42,50 @@ Or individual files or directories:
 
     rclone rc vfs/forget file=path/to/file dir=path/to/dir
 
+Or to re-read directories from the remote straight away, for example
+after uploading to the remote with another program:
+
+    rclone rc vfs/refresh dir=path/to/dir
+
+Add ` + "`recursive=true`" + ` to read all the directories under it
+too.  To fill the directory cache with the whole tree when starting,
+so programs which scan everything, such as media servers, don't wait
+for each directory to be listed, use the ` + "`--vfs-refresh`" + ` flag.
+This reads the directories in the background and files can be used
+while it does.
+
+Listing a large remote can take a long time, so with
+` + "`--dir-cache-persist`" + ` rclone saves the directory cache in the
+` + "`--cache-dir`" + ` when it exits and loads it again the next time
+the same remote is used.  The saved listings are used for up to
+` + "`--dir-cache-time`" + ` after starting and each directory is then
+read again from the remote the next time it is used.  Changes made to
+the remote while rclone wasn't running won't be seen until then, so use
+` + "`rclone rc vfs/forget`" + ` or ` + "`SIGHUP`" + ` if they need to be.
+
+### Read only access
+
+With ` + "`--read-only`" + ` nothing can change the remote.  Opening a
+file for writing, truncating it, creating, renaming or deleting files
+and directories and setting modification times all fail with a "Read
+only file system" error.  These are refused by rclone itself as well
+as by the kernel, so programs such as desktop indexers can't modify
+the remote by mistake.
+
+### Coalescing reads
+
+Programs which read small pieces of files out of order, such as media
+scanners, make rclone seek in the file for each one, and each seek
+opens a new request to the remote.  On remotes which charge per
+request this can be expensive.
+
+Use ` + "`--vfs-read-coalesce`" + ` with a size such as ` + "`1M`" + ` to
+stop this.  Reads up to this far ahead of the last one carry on with
+the same request, discarding the data in between, and the last part of
+the file read, up to this size, is kept in memory for reads behind it.
+Seeks further than this still open a new request.  It is off (0) by
+default.
+
 ### File Caching
 
 **NB** File caching is **EXPERIMENTAL** - use with care!
@@ -41,10 +100,14 @@ and write simultaneously to a file.  See below for more details.
 Note that the VFS cache works in addition to the cache backend and you
 may find that you need one or the other or both.
 
-    --cache-dir string                   Directory rclone will use for caching.
-    --vfs-cache-max-age duration         Max age of objects in the cache. (default 1h0m0s)
-    --vfs-cache-mode string              Cache mode off|minimal|writes|full (default "off")
-    --vfs-cache-poll-interval duration   Interval to poll the cache for stale objects. (default 1m0s)
+    --cache-dir string                      Directory rclone will use for caching.
+    --vfs-cache-max-age duration            Max age of objects in the cache. (default 1h0m0s)
+    --vfs-cache-max-size int                Max total size of objects in the cache. (default off)
+    --vfs-cache-mode string                 Cache mode off|minimal|writes|full (default "off")
+    --vfs-cache-poll-interval duration      Interval to poll the cache for stale objects. (default 1m0s)
+    --vfs-write-back                        Upload files from the cache in the background after they are closed.
+    --vfs-write-back-max-backoff duration   Max time to wait before retrying a failed background upload. (default 5m0s)
+    --vfs-write-back-uploads int            Number of files to upload in the background at once. (default 4)
 
 If run with ` + "`-vv`" + ` rclone will print the location of the file cache.  The
 files are stored in the user cache file area which is OS dependent but
@@ -101,7 +164,13 @@ If an upload fails it will be retried up to --low-level-retries times.
 #### --vfs-cache-mode full
 
 In this mode all reads and writes are buffered to and from disk.  When
-a file is opened for read it will be downloaded in its entirety first.
+a file is opened for read only the parts of it which are read are
+downloaded into the cache, so seeking around in a large file, eg a
+video, doesn't need the whole file to be downloaded first.  Which parts
+of the file are in the cache is remembered, so they don't need to be
+downloaded again when the file is next opened.  When a partially
+downloaded file is opened for write the rest of it will be downloaded
+first.
 
 This may be appropriate for your needs, or you may prefer to look at
 the cache backend which does a much more sophisticated job of caching,
@@ -111,8 +180,38 @@ In this mode, unlike the others, when a file is written to the disk,
 it will be kept on the disk after it is written to the remote.  It
 will be purged on a schedule according to ` + "`--vfs-cache-max-age`" + `.
 
+If ` + "`--vfs-cache-max-size`" + ` is set then when the cache grows bigger
+than this the least recently accessed files will be removed until it
+is under the limit.  Only files which are closed, and so have been
+written back to the remote, are removed, so the cache may exceed the
+limit while lots of files are open.  The cache is checked every
+` + "`--vfs-cache-poll-interval`" + `.
+
 This mode should support all normal file system operations.
 
 If an upload or download fails it will be retried up to
 --low-level-retries times.
+
+#### --vfs-write-back
+
+Normally closing a file which was written through the cache waits
+until the file has been uploaded to the remote.  With
+` + "`--vfs-write-back`" + ` the file is queued to be uploaded in the
+background instead and closing it returns straight away.  This needs
+` + "`--vfs-cache-mode writes`" + ` or ` + "`full`" + `.
+
+Up to ` + "`--vfs-write-back-uploads`" + ` files are uploaded at once.  If an
+upload fails it is tried again after a second, then after twice as
+long each time it fails, up to ` + "`--vfs-write-back-max-backoff`" + `
+between tries.  Files are kept in the cache until they have been
+uploaded.
+
+The queue of files waiting to be uploaded is saved in the rclone cache
+directory, so if rclone is stopped before they are all uploaded the
+rest will be uploaded when it is next started with the same remote.
+
+Files waiting to be uploaded are shown in directory listings with
+their new size even though they aren't on the remote yet.  Renaming a
+file which is waiting to be uploaded, or a directory containing one,
+waits for it to be uploaded first.
 `
@@ -6,13 +6,14 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/rc"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // Add remote control for the VFS
 func (vfs *VFS) addRC() {
 	rc.Add(rc.Call{
 		Path: "vfs/forget",
-		Fn: func(in rc.Params) (out rc.Params, err error) {
+		Fn: func(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 			root, err := vfs.Root()
 			if err != nil {
 				return nil, err
@@ -61,4 +62,95 @@ starting with dir will forget that dir, eg
 
 `,
 	})
+	rc.Add(rc.Call{
+		Path:  "vfs/refresh",
+		Fn:    vfs.rcRefresh,
+		Title: "Refresh the directory cache.",
+		Help: `
+This reads the directories for the specified paths from the remote
+straight away and freshens the directory cache, so changes made to the
+remote other than through the VFS show up without waiting for
+--dir-cache-time to expire.
+
+If no paths are passed in then it will refresh the root directory.
+
+    rclone rc vfs/refresh
+
+Otherwise pass directories in as dir=path. Any parameter key
+starting with dir will refresh that directory, eg
+
+    rclone rc vfs/refresh dir=home/junk dir2=data/misc
+
+If the parameter recursive=true is given then all the directories
+under them are read too, which fills the directory cache with the
+whole tree, eg
+
+    rclone rc vfs/refresh recursive=true
+
+This can take a long time on big remotes, so add _async=true to run it
+in the background.
+
+The result is a map of the directories passed in to "OK" or the error
+reading them.
+`,
+	})
+}
+
+// rcRefresh re-reads the directories passed in
+func (vfs *VFS) rcRefresh(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	root, err := vfs.Root()
+	if err != nil {
+		return nil, err
+	}
+	recursive := false
+	if _, found := in["recursive"]; found {
+		recursive, err = in.GetBool("recursive")
+		if err != nil {
+			return nil, err
+		}
+		delete(in, "recursive")
+	}
+	refreshDir := func(dir *Dir) error {
+		if recursive {
+			return dir.refreshRecursive()
+		}
+		return dir.refresh()
+	}
+	result := map[string]string{}
+	refresh := func(path string) {
+		node, err := vfs.Stat(path)
+		if err == nil {
+			if dir, ok := node.(*Dir); ok {
+				err = refreshDir(dir)
+			} else {
+				err = errors.New("not a directory")
+			}
+		}
+		if err != nil {
+			result[path] = err.Error()
+		} else {
+			result[path] = "OK"
+		}
+	}
+	if len(in) == 0 {
+		err = refreshDir(root)
+		if err != nil {
+			return nil, err
+		}
+		result[""] = "OK"
+	} else {
+		for k, v := range in {
+			path, ok := v.(string)
+			if !ok {
+				return out, errors.Errorf("value must be string %q=%v", k, v)
+			}
+			if !strings.HasPrefix(k, "dir") {
+				return out, errors.Errorf("unknown key %q", k)
+			}
+			refresh(strings.Trim(path, "/"))
+		}
+	}
+	return rc.Params{
+		"result": result,
+	}, nil
 }
@@ -0,0 +1,85 @@
+package vfs
+
+import (
+	"testing"
+
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestRcRefresh(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	vfs := New(r.Fremote, nil)
+
+	file1 := r.WriteObject("dir/file1", "file1 contents", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	// read the directories into the cache
+	_, err := vfs.Stat("dir/file1")
+	require.NoError(t, err)
+
+	// changes made behind the back of the VFS aren't seen
+	file2 := r.WriteObject("dir/file2", "file2 contents", t1)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+	_, err = vfs.Stat("dir/file2")
+	assert.Equal(t, ENOENT, err)
+
+	out, err := vfs.rcRefresh(context.Background(), rc.Params{"dir": "dir", "dir2": "dir/file1", "dir3": "potato"})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{
+		"result": map[string]string{
+			"dir":       "OK",
+			"dir/file1": "not a directory",
+			"potato":    ENOENT.Error(),
+		},
+	}, out)
+
+	_, err = vfs.Stat("dir/file2")
+	require.NoError(t, err)
+
+	// refresh the root
+	out, err = vfs.rcRefresh(context.Background(), rc.Params{})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"result": map[string]string{"": "OK"}}, out)
+
+	_, err = vfs.rcRefresh(context.Background(), rc.Params{"potato": "dir"})
+	assert.Error(t, err)
+}
+
+func TestRcRefreshRecursive(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	vfs := New(r.Fremote, nil)
+
+	file1 := r.WriteObject("dir/sub/file1", "file1 contents", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	out, err := vfs.rcRefresh(context.Background(), rc.Params{"recursive": "true"})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"result": map[string]string{"": "OK"}}, out)
+
+	// all the directories are in the cache
+	dir, ok := vfs.root.items["dir"].(*Dir)
+	require.True(t, ok)
+	sub, ok := dir.items["sub"].(*Dir)
+	require.True(t, ok)
+	assert.False(t, sub.read.IsZero())
+	_, ok = sub.items["file1"].(*File)
+	assert.True(t, ok)
+
+	// changes made behind the back of the VFS are seen under dir
+	file2 := r.WriteObject("dir/sub/file2", "file2 contents", t1)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+	out, err = vfs.rcRefresh(context.Background(), rc.Params{"dir": "dir", "recursive": true})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"result": map[string]string{"dir": "OK"}}, out)
+	_, ok = sub.items["file2"].(*File)
+	assert.True(t, ok)
+
+	_, err = vfs.rcRefresh(context.Background(), rc.Params{"recursive": "potato"})
+	assert.Error(t, err)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/chunkedreader"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/pkg/errors"
 )
@@ -64,7 +65,7 @@ func (fh *ReadFileHandle) openPending() (err error) {
 		return nil
 	}
 	o := fh.file.getObject()
-	r, err := o.Open()
+	r, err := fh.openAt(o, 0)
 	if err != nil {
 		return err
 	}
@@ -74,6 +75,27 @@ func (fh *ReadFileHandle) openPending() (err error) {
 	return nil
 }
 
+// openAt opens o for reading from offset.
+//
+// If --vfs-read-coalesce is set then it is read through a
+// ChunkedReader so reads close to each other share a request.
+func (fh *ReadFileHandle) openAt(o fs.Object, offset int64) (io.ReadCloser, error) {
+	if window := int64(fh.file.d.vfs.Opt.ReadCoalesce); window > 0 {
+		cr := chunkedreader.New(o, 0, false).WithCoalesceWindow(window)
+		if offset > 0 {
+			_, err := cr.Seek(offset, 0)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return cr, nil
+	}
+	if offset > 0 {
+		return o.Open(&fs.SeekOption{Offset: offset})
+	}
+	return o.Open()
+}
+
 // String converts it to printable
 func (fh *ReadFileHandle) String() string {
 	if fh == nil {
@@ -124,7 +146,7 @@ func (fh *ReadFileHandle) seek(offset int64, reopen bool) (err error) {
 		}
 		// re-open with a seek
 		o := fh.file.getObject()
-		r, err = o.Open(&fs.SeekOption{Offset: offset})
+		r, err = fh.openAt(o, offset)
 		if err != nil {
 			fs.Debugf(fh.remote, "ReadFileHandle.Read seek failed: %v", err)
 			return err
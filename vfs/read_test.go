@@ -180,6 +180,42 @@ func TestReadFileHandleReadAt(t *testing.T) {
 	assert.Equal(t, ECLOSED, err)
 }
 
+func TestReadFileHandleReadAtCoalesce(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	opt := DefaultOpt
+	opt.ReadCoalesce = 4
+	vfs := New(r.Fremote, &opt)
+
+	file1 := r.WriteObject("dir/file1", "0123456789abcdef", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	h, err := vfs.OpenFile("dir/file1", os.O_RDONLY, 0777)
+	require.NoError(t, err)
+	fh, ok := h.(*ReadFileHandle)
+	require.True(t, ok)
+
+	for _, test := range []struct {
+		off  int64
+		size int
+		want string
+	}{
+		{0, 1, "0"},
+		{3, 2, "34"},  // forwards within the window
+		{1, 3, "123"}, // backwards within the window
+		{12, 2, "cd"}, // forwards further than the window
+		{2, 1, "2"},   // backwards further than the window
+		{10, 6, "abcdef"},
+	} {
+		buf := make([]byte, test.size)
+		n, err := fh.ReadAt(buf, test.off)
+		require.NoError(t, err)
+		assert.Equal(t, test.want, string(buf[:n]), "at %d", test.off)
+	}
+
+	assert.NoError(t, fh.Close())
+}
+
 func TestReadFileHandleFlush(t *testing.T) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
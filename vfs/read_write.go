@@ -27,10 +27,11 @@ type RWFileHandle struct {
 	file        *File
 	d           *Dir
 	opened      bool
-	flags       int    // open flags
-	osPath      string // path to the file in the cache
-	writeCalled bool   // if any Write() methods have been called
-	changed     bool   // file contents was changed in any other way
+	flags       int               // open flags
+	osPath      string            // path to the file in the cache
+	writeCalled bool              // if any Write() methods have been called
+	changed     bool              // file contents was changed in any other way
+	downloader  *sparseDownloader // set if the file is downloaded as it is read
 }
 
 // Check interfaces
@@ -111,9 +112,37 @@ func (fh *RWFileHandle) openPending(truncate bool) (err error) {
 	cacheFileOpenFlags := fh.flags
 	// if not truncating the file, need to read it first
 	if fh.flags&os.O_TRUNC == 0 && !truncate {
+		// In full cache mode files opened read only are
+		// downloaded into the cache as they are read
+		if o != nil && fh.flags&accessModeMask == os.O_RDONLY && fh.d.vfs.Opt.CacheMode >= CacheModeFull {
+			sparse, err := fh.d.vfs.cache.startSparse(fh.remote, o)
+			if err != nil {
+				return errors.Wrap(err, "open RW handle failed to start download into cache")
+			}
+			if sparse {
+				fh.downloader, err = newSparseDownloader(fh.d.vfs.cache, fh.remote, o)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// If the cached file is only partially downloaded then
+		// fetch the rest of it before using it
+		if fh.downloader == nil && o != nil && fh.d.vfs.cache.isSparse(fh.remote, o) {
+			dl, err := newSparseDownloader(fh.d.vfs.cache, fh.remote, o)
+			if err == nil {
+				err = dl.fill()
+			}
+			if err != nil {
+				return errors.Wrap(err, "open RW handle failed to finish downloading cached file")
+			}
+		}
+
 		// If the remote object exists AND its cached file exists locally AND there are no
-		// other RW handles with it open, then attempt to update it.
-		if o != nil && fh.file.rwOpens() == 0 {
+		// other RW handles with it open AND it isn't waiting to be uploaded, then attempt
+		// to update it.
+		if fh.downloader == nil && o != nil && fh.file.rwOpens() == 0 && !fh.d.vfs.cache.writeBack.pending(fh.remote) {
 			cacheObj, err := fh.d.vfs.cache.f.NewObject(fh.remote)
 			if err == nil && cacheObj != nil {
 				cacheObj, err = copyObj(fh.d.vfs.cache.f, cacheObj, fh.remote, o)
@@ -161,6 +190,7 @@ func (fh *RWFileHandle) openPending(truncate bool) (err error) {
 		// Set the size to 0 since we are truncating and flag we need to write it back
 		fh.file.setSize(0)
 		fh.changed = true
+		fh.d.vfs.cache.stopSparse(fh.remote)
 		if fh.flags&os.O_CREATE == 0 && fh.file.exists() {
 			// create an empty file if it exists on the source
 			err = ioutil.WriteFile(fh.osPath, []byte{}, 0600)
@@ -248,6 +278,13 @@ func (fh *RWFileHandle) close() (err error) {
 	}
 	fh.closed = true
 	defer func() {
+		if fh.downloader != nil {
+			closeErr := fh.downloader.close()
+			if closeErr != nil {
+				fs.Errorf(fh.logPrefix(), "Failed to close download into cache: %v", closeErr)
+			}
+			fh.downloader = nil
+		}
 		if fh.opened {
 			fh.file.delRWOpen()
 		}
@@ -293,7 +330,10 @@ func (fh *RWFileHandle) close() (err error) {
 		}
 	}
 
-	if copy {
+	if copy && fh.d.vfs.Opt.WriteBack {
+		// Upload the file in the background
+		fh.d.vfs.cache.writeBack.add(fh.remote, fh.file)
+	} else if copy {
 		// Transfer the temp file to the remote
 		cacheObj, err := fh.d.vfs.cache.f.NewObject(fh.remote)
 		if err != nil {
@@ -415,9 +455,30 @@ func (fh *RWFileHandle) readFn(read func() (int, error)) (n int, err error) {
 	return read()
 }
 
+// download fetches the size bytes at off into the cache file if it is
+// being downloaded as it is read
+//
+// call with the lock held
+func (fh *RWFileHandle) download(off int64, size int) error {
+	if fh.downloader == nil {
+		return nil
+	}
+	return fh.downloader.ensure(extent{Pos: off, Size: int64(size)})
+}
+
 // Read bytes from the file
 func (fh *RWFileHandle) Read(b []byte) (n int, err error) {
 	return fh.readFn(func() (int, error) {
+		if fh.downloader != nil {
+			off, err := fh.File.Seek(0, os.SEEK_CUR)
+			if err != nil {
+				return 0, err
+			}
+			err = fh.download(off, len(b))
+			if err != nil {
+				return 0, err
+			}
+		}
 		return fh.File.Read(b)
 	})
 }
@@ -425,6 +486,10 @@ func (fh *RWFileHandle) Read(b []byte) (n int, err error) {
 // ReadAt bytes from the file at off
 func (fh *RWFileHandle) ReadAt(b []byte, off int64) (n int, err error) {
 	return fh.readFn(func() (int, error) {
+		err := fh.download(off, len(b))
+		if err != nil {
+			return 0, err
+		}
 		return fh.File.ReadAt(b, off)
 	})
 }
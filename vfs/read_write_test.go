@@ -4,6 +4,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -217,6 +218,60 @@ func TestRWFileHandleReadAt(t *testing.T) {
 	assert.Equal(t, ECLOSED, err)
 }
 
+func TestRWFileHandleSparseRead(t *testing.T) {
+	r := fstest.NewRun(t)
+	vfs := New(r.Fremote, nil)
+	vfs.Opt.CacheMode = CacheModeFull
+	defer cleanup(t, r, vfs)
+
+	contents := strings.Repeat("0123456789abcdef", 3*sparseChunkSize/16)
+	file1 := r.WriteObject("file1", contents, t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+	size := int64(len(contents))
+	c := vfs.cache
+
+	openRead := func() *RWFileHandle {
+		h, err := vfs.OpenFile("file1", os.O_RDONLY, 0777)
+		require.NoError(t, err)
+		fh, ok := h.(*RWFileHandle)
+		require.True(t, ok)
+		return fh
+	}
+
+	// reading the middle of the file only downloads that part
+	fh := openRead()
+	buf := make([]byte, 16)
+	n, err := fh.ReadAt(buf, sparseChunkSize+16)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789abcdef", string(buf[:n]))
+	assert.Equal(t, extent{Pos: 0, Size: sparseChunkSize + 16}, c.findMissing("file1", extent{Size: size}))
+	assert.Equal(t, extent{Pos: 2*sparseChunkSize + 16, Size: sparseChunkSize - 16}, c.findMissing("file1", extent{Pos: sparseChunkSize + 16, Size: size}))
+	assert.NoError(t, fh.Close())
+
+	// what was downloaded is remembered in the metadata
+	_, err = os.Stat(c.toMetaPath("file1"))
+	require.NoError(t, err)
+	c.item["file1"].sparse = nil
+
+	assert.True(t, c.isSparse("file1", fh.file.getObject()))
+	assert.Equal(t, extent{Pos: 0, Size: sparseChunkSize + 16}, c.findMissing("file1", extent{Size: size}))
+
+	// reading the rest completes the file
+	fh = openRead()
+	got, err := ioutil.ReadAll(fh)
+	require.NoError(t, err)
+	assert.Equal(t, contents, string(got))
+	assert.True(t, c.findMissing("file1", extent{Size: size}).isEmpty())
+	assert.NoError(t, fh.Close())
+
+	_, err = os.Stat(c.toMetaPath("file1"))
+	assert.True(t, os.IsNotExist(err))
+	fi, err := os.Stat(c.toOSPath("file1"))
+	require.NoError(t, err)
+	assert.Equal(t, size, fi.Size())
+	assert.False(t, c.isSparse("file1", nil))
+}
+
 func TestRWFileHandleFlushRead(t *testing.T) {
 	r := fstest.NewRun(t)
 	vfs, fh := rwHandleCreateReadOnly(t, r)
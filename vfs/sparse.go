@@ -0,0 +1,389 @@
+// Download files into the cache as they are read
+
+package vfs
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/lib/file"
+	"github.com/pkg/errors"
+)
+
+// sparseChunkSize is the minimum amount downloaded into the cache in
+// one go so small reads don't each cause a request
+const sparseChunkSize = 1024 * 1024
+
+// sparseInfo records which parts of a partially downloaded file are
+// in the cache.
+//
+// It is saved in the metadata directory of the cache so downloads can
+// carry on where they left off when the file is next opened.
+type sparseInfo struct {
+	ModTime time.Time // modification time of the object being downloaded
+	Size    int64     // size of the object being downloaded
+	Extents extents   // parts of the object in the cache file
+}
+
+// matches returns true if info is for a download of o
+func (info *sparseInfo) matches(o fs.Object) bool {
+	return info.Size == o.Size() && info.ModTime.Equal(o.ModTime())
+}
+
+// _loadSparse reads the sparse info for name from the metadata
+// returning nil if there isn't any
+//
+// must be called with itemMu held
+func (c *cache) _loadSparse(name string) *sparseInfo {
+	data, err := ioutil.ReadFile(c.toMetaPath(name))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fs.Errorf(name, "Failed to read cache metadata: %v", err)
+		}
+		return nil
+	}
+	info := new(sparseInfo)
+	err = json.Unmarshal(data, info)
+	if err != nil {
+		fs.Errorf(name, "Ignoring corrupted cache metadata: %v", err)
+		return nil
+	}
+	return info
+}
+
+// _saveSparse writes info for name to the metadata
+//
+// must be called with itemMu held
+func (c *cache) _saveSparse(name string, info *sparseInfo) error {
+	metaPath := c.toMetaPath(name)
+	err := file.MkdirAll(filepath.Dir(metaPath), 0700)
+	if err != nil {
+		return errors.Wrap(err, "make cache metadata directory failed")
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode cache metadata")
+	}
+	err = ioutil.WriteFile(metaPath, data, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to write cache metadata")
+	}
+	return nil
+}
+
+// _removeSparse forgets the partial download of name
+//
+// must be called with itemMu held
+func (c *cache) _removeSparse(name string, item *cacheItem) {
+	item.sparse = nil
+	err := os.Remove(c.toMetaPath(name))
+	if err != nil && !os.IsNotExist(err) {
+		fs.Errorf(name, "Failed to remove cache metadata: %v", err)
+	}
+}
+
+// _findSparse finds the sparse info for name, loading it from the
+// metadata if necessary.  It returns nil if the file isn't in the
+// cache or is complete.
+//
+// must be called with itemMu held
+func (c *cache) _findSparse(name string, item *cacheItem) *sparseInfo {
+	if _, err := os.Stat(c.toOSPath(name)); err != nil {
+		item.sparse = nil
+		return nil
+	}
+	if item.sparse == nil {
+		item.sparse = c._loadSparse(name)
+	}
+	return item.sparse
+}
+
+// startSparse is called when name is opened for reading in full
+// cache mode.
+//
+// It returns true if the file in the cache should be downloaded as it
+// is read, which is the case if there isn't a copy of o in the cache
+// or if there is a partially downloaded copy of o.  In this case it
+// makes sure there is a cache file of the right size to download
+// into.
+//
+// If it returns false then the cache file should be used as normal.
+//
+// name should be a remote path not an osPath
+func (c *cache) startSparse(name string, o fs.Object) (bool, error) {
+	if o.Size() <= 0 {
+		return false, nil
+	}
+	name = clean(name)
+	osPath := c.toOSPath(name)
+	c.itemMu.Lock()
+	defer c.itemMu.Unlock()
+	item, _ := c._get(true, name)
+	info := c._findSparse(name, item)
+	if info != nil && info.matches(o) {
+		return true, nil
+	}
+	if info == nil {
+		if _, err := os.Stat(osPath); err == nil {
+			// there is a complete copy in the cache
+			return false, nil
+		}
+	}
+
+	// Start the download afresh - write the metadata first so
+	// the cache file is never mistaken for a complete copy
+	fs.Debugf(name, "Starting download into cache as file is read")
+	info = &sparseInfo{
+		ModTime: o.ModTime(),
+		Size:    o.Size(),
+	}
+	err := c._saveSparse(name, info)
+	if err != nil {
+		return false, err
+	}
+	fd, err := os.OpenFile(osPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to create cache file")
+	}
+	err = fd.Truncate(info.Size)
+	closeErr := fd.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "failed to size cache file")
+	}
+	item.sparse = info
+	return true, nil
+}
+
+// isSparse returns true if name is partially downloaded into the
+// cache from o.
+//
+// If a partial download isn't of o then it is out of date so it is
+// removed from the cache.
+//
+// name should be a remote path not an osPath
+func (c *cache) isSparse(name string, o fs.Object) bool {
+	name = clean(name)
+	c.itemMu.Lock()
+	defer c.itemMu.Unlock()
+	item, _ := c._get(true, name)
+	info := c._findSparse(name, item)
+	if info == nil {
+		return false
+	}
+	if o != nil && info.matches(o) {
+		return true
+	}
+	fs.Debugf(name, "Removing out of date partial download from cache")
+	err := os.Remove(c.toOSPath(name))
+	if err != nil && !os.IsNotExist(err) {
+		fs.Errorf(name, "Failed to remove from cache: %v", err)
+	}
+	c._removeSparse(name, item)
+	return false
+}
+
+// stopSparse forgets any partial download of name, eg because the
+// cache file is being truncated
+//
+// name should be a remote path not an osPath
+func (c *cache) stopSparse(name string) {
+	name = clean(name)
+	c.itemMu.Lock()
+	defer c.itemMu.Unlock()
+	item, _ := c._get(true, name)
+	c._removeSparse(name, item)
+}
+
+// findMissing returns the first part of e which hasn't been
+// downloaded into the cache file for name.
+//
+// It returns an empty extent if all of e is present.
+//
+// name should be a remote path not an osPath
+func (c *cache) findMissing(name string, e extent) extent {
+	name = clean(name)
+	c.itemMu.Lock()
+	defer c.itemMu.Unlock()
+	item := c.item[name]
+	if item == nil || item.sparse == nil {
+		return extent{Pos: e.end()}
+	}
+	return item.sparse.Extents.findMissing(e.clip(item.sparse.Size))
+}
+
+// addExtent records that e has been downloaded into the cache file
+// for name.
+//
+// When the whole file has been downloaded the file is marked as
+// complete.
+//
+// name should be a remote path not an osPath
+func (c *cache) addExtent(name string, e extent) {
+	name = clean(name)
+	c.itemMu.Lock()
+	defer c.itemMu.Unlock()
+	item := c.item[name]
+	if item == nil || item.sparse == nil {
+		return
+	}
+	info := item.sparse
+	info.Extents.insert(e)
+	if !info.Extents.present(extent{Size: info.Size}) {
+		return
+	}
+	fs.Debugf(name, "Finished downloading into cache")
+	// Set the modification time so the cached copy is seen as
+	// up to date with the object
+	err := os.Chtimes(c.toOSPath(name), time.Now(), info.ModTime)
+	if err != nil {
+		fs.Errorf(name, "Failed to set modification time of cache file: %v", err)
+	}
+	c._removeSparse(name, item)
+}
+
+// saveSparse saves which parts of name have been downloaded so far
+// to the metadata
+//
+// name should be a remote path not an osPath
+func (c *cache) saveSparse(name string) {
+	name = clean(name)
+	c.itemMu.Lock()
+	defer c.itemMu.Unlock()
+	item := c.item[name]
+	if item == nil || item.sparse == nil {
+		return
+	}
+	err := c._saveSparse(name, item.sparse)
+	if err != nil {
+		fs.Errorf(name, "Failed to save cache metadata: %v", err)
+	}
+}
+
+// sparseDownloader downloads the parts of an object which are read
+// into a partially downloaded file in the cache
+type sparseDownloader struct {
+	c      *cache
+	name   string
+	o      fs.Object
+	fd     *os.File            // cache file open for writing
+	in     *accounting.Account // open stream from the object or nil
+	offset int64               // offset in the object of in
+	buf    []byte
+}
+
+// newSparseDownloader makes a downloader for name in the cache from
+// o
+func newSparseDownloader(c *cache, name string, o fs.Object) (*sparseDownloader, error) {
+	fd, err := os.OpenFile(c.toOSPath(name), os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open cache file for download")
+	}
+	return &sparseDownloader{
+		c:    c,
+		name: clean(name),
+		o:    o,
+		fd:   fd,
+	}, nil
+}
+
+// ensure downloads any parts of e which aren't in the cache file
+func (dl *sparseDownloader) ensure(e extent) error {
+	for {
+		missing := dl.c.findMissing(dl.name, e)
+		if missing.isEmpty() {
+			return nil
+		}
+		// Download at least sparseChunkSize if possible
+		if missing.Size < sparseChunkSize {
+			missing = dl.c.findMissing(dl.name, extent{Pos: missing.Pos, Size: sparseChunkSize})
+		}
+		err := dl.download(missing)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// download e from the object into the cache file
+func (dl *sparseDownloader) download(e extent) error {
+	// Carry on reading from the open stream if possible
+	if dl.in != nil && dl.offset != e.Pos {
+		dl.closeIn()
+	}
+	if dl.in == nil {
+		fs.Debugf(dl.name, "Downloading into cache from offset %d", e.Pos)
+		in, err := dl.o.Open(&fs.SeekOption{Offset: e.Pos})
+		if err != nil {
+			return errors.Wrap(err, "failed to open object to download into cache")
+		}
+		dl.in = accounting.NewAccount(in, dl.o)
+		accounting.Stats.Transferring(dl.o.Remote())
+		dl.offset = e.Pos
+	}
+	if dl.buf == nil {
+		dl.buf = make([]byte, sparseChunkSize)
+	}
+	for e.Size > 0 {
+		buf := dl.buf
+		if int64(len(buf)) > e.Size {
+			buf = buf[:e.Size]
+		}
+		n, err := io.ReadFull(dl.in, buf)
+		if n > 0 {
+			_, writeErr := dl.fd.WriteAt(buf[:n], e.Pos)
+			if writeErr != nil {
+				dl.closeIn()
+				return errors.Wrap(writeErr, "failed to write to cache file")
+			}
+			dl.c.addExtent(dl.name, extent{Pos: e.Pos, Size: int64(n)})
+			e.Pos += int64(n)
+			e.Size -= int64(n)
+			dl.offset += int64(n)
+		}
+		if err != nil {
+			dl.closeIn()
+			return errors.Wrap(err, "failed to download into cache")
+		}
+	}
+	return nil
+}
+
+// closeIn closes the stream from the object if it is open
+func (dl *sparseDownloader) closeIn() {
+	if dl.in == nil {
+		return
+	}
+	err := dl.in.Close()
+	if err != nil {
+		fs.Debugf(dl.name, "Error closing download into cache: %v", err)
+	}
+	accounting.Stats.DoneTransferring(dl.o.Remote(), true)
+	dl.in = nil
+}
+
+// close the downloader, saving which parts have been downloaded
+func (dl *sparseDownloader) close() error {
+	dl.closeIn()
+	dl.c.saveSparse(dl.name)
+	return dl.fd.Close()
+}
+
+// fill downloads all of the object into the cache file then closes
+// the downloader
+func (dl *sparseDownloader) fill() error {
+	err := dl.ensure(extent{Size: dl.o.Size()})
+	closeErr := dl.close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
@@ -20,10 +20,11 @@ package vfs
 
 import (
 	"fmt"
+	"hash/fnv"
 	"os"
 	"path"
 	"strings"
-	"sync/atomic"
+	"sync"
 	"time"
 
 	"github.com/ncw/rclone/fs"
@@ -37,6 +38,7 @@ var DefaultOpt = Options{
 	NoChecksum:        false,
 	NoSeek:            false,
 	DirCacheTime:      5 * 60 * time.Second,
+	NegativeCacheTime: 5 * 60 * time.Second,
 	PollInterval:      time.Minute,
 	ReadOnly:          false,
 	Umask:             0,
@@ -46,7 +48,14 @@ var DefaultOpt = Options{
 	FilePerms:         os.FileMode(0666),
 	CacheMode:         CacheModeOff,
 	CacheMaxAge:       3600 * time.Second,
+	CacheMaxSize:      -1,
+	WriteBack:         false,
+	WriteBackUploads:  4,
+	WriteBackBackoff:  5 * time.Minute,
 	CachePollInterval: 60 * time.Second,
+	DirCachePersist:   false,
+	Refresh:           false,
+	ReadCoalesce:      0,
 }
 
 // Node represents either a directory (*Dir) or a file (*File)
@@ -165,11 +174,14 @@ var (
 
 // VFS represents the top level filing system
 type VFS struct {
-	f      fs.Fs
-	root   *Dir
-	Opt    Options
-	cache  *cache
-	cancel context.CancelFunc
+	f         fs.Fs
+	root      *Dir
+	Opt       Options
+	cache     *cache
+	cancel    context.CancelFunc
+	usageMu   sync.Mutex
+	usageTime time.Time
+	usage     *fs.Usage
 }
 
 // Options is options for creating the vfs
@@ -179,6 +191,7 @@ type Options struct {
 	ReadOnly          bool          // if set VFS is read only
 	NoModTime         bool          // don't read mod times for files
 	DirCacheTime      time.Duration // how long to consider directory listing cache valid
+	NegativeCacheTime time.Duration // how long to remember a name isn't in a directory
 	PollInterval      time.Duration
 	Umask             int
 	UID               uint32
@@ -187,7 +200,14 @@ type Options struct {
 	FilePerms         os.FileMode
 	CacheMode         CacheMode
 	CacheMaxAge       time.Duration
+	CacheMaxSize      fs.SizeSuffix // max size of the cache or -1 for unlimited
+	WriteBack         bool          // upload files in the background when they are closed
+	WriteBackUploads  int           // number of background uploads to run at once
+	WriteBackBackoff  time.Duration // max time to wait before retrying a background upload
 	CachePollInterval time.Duration
+	DirCachePersist   bool          // save the directory cache on shutdown and load it on startup
+	Refresh           bool          // read the whole directory tree in the background on startup
+	ReadCoalesce      fs.SizeSuffix // read in one request from the remote when reads are this close, 0 for off
 }
 
 // New creates a new VFS and root directory.  If opt is nil, then
@@ -215,6 +235,14 @@ func New(f fs.Fs, opt *Options) *VFS {
 	// Create root directory
 	vfs.root = newDir(vfs, f, nil, fsDir)
 
+	// Load the directory cache from the last run if required
+	if vfs.Opt.DirCachePersist {
+		err := vfs.loadDirCache()
+		if err != nil {
+			fs.Errorf(f, "Failed to load directory cache: %v", err)
+		}
+	}
+
 	// Start polling if required
 	if vfs.Opt.PollInterval > 0 {
 		if do := vfs.f.Features().ChangeNotify; do != nil {
@@ -236,6 +264,19 @@ func New(f fs.Fs, opt *Options) *VFS {
 
 	// add the remote control
 	vfs.addRC()
+
+	// Fill the directory cache in the background if required
+	if vfs.Opt.Refresh {
+		go func() {
+			fs.Infof(f, "Refreshing the directory cache")
+			err := vfs.root.refreshRecursive()
+			if err != nil {
+				fs.Errorf(f, "Failed to refresh the directory cache: %v", err)
+				return
+			}
+			fs.Infof(f, "Finished refreshing the directory cache")
+		}()
+	}
 	return vfs
 }
 
@@ -244,6 +285,12 @@ func (vfs *VFS) Shutdown() {
 	if vfs.cancel != nil {
 		vfs.cancel()
 		vfs.cancel = nil
+		if vfs.Opt.DirCachePersist {
+			err := vfs.saveDirCache()
+			if err != nil {
+				fs.Errorf(vfs.f, "Failed to save directory cache: %v", err)
+			}
+		}
 	}
 }
 
@@ -257,8 +304,9 @@ func (vfs *VFS) FlushDirCache() {
 	vfs.root.ForgetAll()
 }
 
-// WaitForWriters sleeps until all writers have finished or
-// time.Duration has elapsed
+// WaitForWriters sleeps until all writers have finished and all the
+// files waiting to be uploaded have been, or time.Duration has
+// elapsed
 func (vfs *VFS) WaitForWriters(timeout time.Duration) {
 	defer log.Trace(nil, "timeout=%v", timeout)("")
 	const tickTime = 1 * time.Second
@@ -269,6 +317,7 @@ func (vfs *VFS) WaitForWriters(timeout time.Duration) {
 	tick.Stop()
 	for {
 		writers := 0
+		uploads := vfs.cache.writeBack.queued()
 		vfs.root.walk("", func(d *Dir) {
 			fs.Debugf(d.path, "Looking for writers")
 			// NB d.mu is held by walk() here
@@ -283,16 +332,16 @@ func (vfs *VFS) WaitForWriters(timeout time.Duration) {
 				}
 			}
 		})
-		if writers == 0 {
+		if writers == 0 && uploads == 0 {
 			return
 		}
-		fs.Debugf(nil, "Still %d writers active, waiting %v", writers, tickTime)
+		fs.Debugf(nil, "Still %d writers active and %d files to upload, waiting %v", writers, uploads, tickTime)
 		tick.Reset(tickTime)
 		select {
 		case <-tick.C:
 			break
 		case <-deadline.C:
-			fs.Errorf(nil, "Exiting even though %d writers are active after %v", writers, timeout)
+			fs.Errorf(nil, "Exiting even though %d writers are active and %d files are waiting to upload after %v", writers, uploads, timeout)
 			return
 		}
 	}
@@ -304,11 +353,24 @@ func (vfs *VFS) Root() (*Dir, error) {
 	return vfs.root, nil
 }
 
-var inodeCount uint64
-
-// newInode creates a new unique inode number
-func newInode() (inode uint64) {
-	return atomic.AddUint64(&inodeCount, 1)
+// pathInode returns the inode number for the node at remote.
+//
+// This is a hash of the path so the same file or directory gets the
+// same inode number every time the remote is mounted, which programs
+// which look at inode numbers, eg to find hard links, rely on.  The
+// root is always 1.
+func pathInode(remote string) uint64 {
+	if remote == "" {
+		return 1
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(remote))
+	inode := h.Sum64()
+	if inode <= 1 {
+		// keep clear of 0 which isn't a valid inode and the root
+		inode += 2
+	}
+	return inode
 }
 
 // Stat finds the Node by path starting from the root
@@ -439,3 +501,69 @@ func (vfs *VFS) Rename(oldName, newName string) error {
 	}
 	return nil
 }
+
+// Link makes a copy of the file oldName called newName with a server
+// side copy to emulate a hard link.  See Dir.Link.
+func (vfs *VFS) Link(oldName, newName string) error {
+	node, err := vfs.Stat(oldName)
+	if err != nil {
+		return err
+	}
+	file, ok := node.(*File)
+	if !ok {
+		return EPERM
+	}
+	newDir, newLeaf, err := vfs.StatParent(newName)
+	if err != nil {
+		return err
+	}
+	_, err = newDir.Link(file, newLeaf)
+	return err
+}
+
+// Statfs returns info about the filing system if known
+//
+// The values will be -1 if they aren't known
+//
+// This information is cached for the DirCacheTime interval
+func (vfs *VFS) Statfs() (total, used, free int64) {
+	vfs.usageMu.Lock()
+	defer vfs.usageMu.Unlock()
+	total, used, free = -1, -1, -1
+	doAbout := vfs.f.Features().About
+	if doAbout == nil {
+		return
+	}
+	if vfs.usageTime.IsZero() || time.Since(vfs.usageTime) >= vfs.Opt.DirCacheTime {
+		usage, err := doAbout()
+		vfs.usageTime = time.Now()
+		if err != nil {
+			fs.Errorf(vfs.f, "Statfs failed: %v", err)
+			vfs.usage = nil
+			return
+		}
+		vfs.usage = usage
+	}
+	if u := vfs.usage; u != nil {
+		if u.Total != nil {
+			total = *u.Total
+		}
+		if u.Free != nil {
+			free = *u.Free
+		}
+		if u.Used != nil {
+			used = *u.Used
+		}
+	}
+	// fill in the missing value if we can
+	if total < 0 && free >= 0 && used >= 0 {
+		total = free + used
+	}
+	if free < 0 && total >= 0 && used >= 0 {
+		free = total - used
+		if free < 0 {
+			free = 0
+		}
+	}
+	return
+}
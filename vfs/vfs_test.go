@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	_ "github.com/ncw/rclone/backend/all" // import all the backends
+	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fstest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -120,6 +121,35 @@ func TestVFSRoot(t *testing.T) {
 	assert.Equal(t, vfs.root, root)
 	assert.True(t, root.IsDir())
 	assert.Equal(t, vfs.Opt.DirPerms.Perm(), root.Mode().Perm())
+	assert.Equal(t, uint64(1), root.Inode())
+}
+
+func TestVFSInodesStable(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	r.WriteObject("dir/file1", "file1 contents", t1)
+	r.WriteObject("dir/file2", "file2 contents", t2)
+
+	// inodes of a new VFS are the same as those of the old one
+	inodes := func(vfs *VFS) (inodes []uint64) {
+		for _, name := range []string{"dir", "dir/file1", "dir/file2"} {
+			node, err := vfs.Stat(name)
+			require.NoError(t, err)
+			inodes = append(inodes, node.Inode())
+		}
+		return inodes
+	}
+	vfs := New(r.Fremote, nil)
+	first := inodes(vfs)
+	assert.Equal(t, first, inodes(New(r.Fremote, nil)))
+	assert.NotEqual(t, first[0], first[1])
+	assert.NotEqual(t, first[1], first[2])
+
+	// renaming a file gives it the inode of its new path
+	require.NoError(t, vfs.Rename("dir/file1", "dir/file3"))
+	node, err := vfs.Stat("dir/file3")
+	require.NoError(t, err)
+	assert.Equal(t, pathInode("dir/file3"), node.Inode())
 }
 
 func TestVFSStat(t *testing.T) {
@@ -250,3 +280,68 @@ func TestVFSRename(t *testing.T) {
 	err = vfs.Rename("file0", "not found/file0")
 	assert.Equal(t, os.ErrNotExist, err)
 }
+
+// aboutFs is an fs.Fs which returns usage from About
+type aboutFs struct {
+	fs.Fs
+	features *fs.Features
+	usage    fs.Usage
+	calls    int
+}
+
+// newAboutFs wraps f returning usage from About
+func newAboutFs(f fs.Fs, usage fs.Usage) *aboutFs {
+	a := &aboutFs{Fs: f, usage: usage}
+	a.features = (&fs.Features{}).Fill(f)
+	a.features.About = a.About
+	return a
+}
+
+// Features returns the optional features of this Fs
+func (a *aboutFs) Features() *fs.Features {
+	return a.features
+}
+
+// About returns the usage
+func (a *aboutFs) About() (*fs.Usage, error) {
+	a.calls++
+	usage := a.usage
+	return &usage, nil
+}
+
+func TestVFSStatfs(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	// Remotes without About return unknown values
+	if r.Fremote.Features().About == nil {
+		vfs := New(r.Fremote, nil)
+		total, used, free := vfs.Statfs()
+		assert.Equal(t, int64(-1), total)
+		assert.Equal(t, int64(-1), used)
+		assert.Equal(t, int64(-1), free)
+	}
+
+	for _, test := range []struct {
+		usage             fs.Usage
+		total, used, free int64
+	}{
+		{fs.Usage{Total: fs.NewUsageValue(100), Used: fs.NewUsageValue(30), Free: fs.NewUsageValue(60)}, 100, 30, 60},
+		{fs.Usage{Used: fs.NewUsageValue(30), Free: fs.NewUsageValue(70)}, 100, 30, 70},
+		{fs.Usage{Total: fs.NewUsageValue(100), Used: fs.NewUsageValue(30)}, 100, 30, 70},
+		{fs.Usage{Total: fs.NewUsageValue(100), Used: fs.NewUsageValue(130)}, 100, 130, 0},
+		{fs.Usage{Used: fs.NewUsageValue(30)}, -1, 30, -1},
+		{fs.Usage{}, -1, -1, -1},
+	} {
+		f := newAboutFs(r.Fremote, test.usage)
+		vfs := New(f, nil)
+		total, used, free := vfs.Statfs()
+		assert.Equal(t, test.total, total)
+		assert.Equal(t, test.used, used)
+		assert.Equal(t, test.free, free)
+
+		// check the result is cached
+		_, _, _ = vfs.Statfs()
+		assert.Equal(t, 1, f.calls)
+	}
+}
@@ -18,10 +18,18 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.BoolVarP(flagSet, &Opt.NoChecksum, "no-checksum", "", Opt.NoChecksum, "Don't compare checksums on up/download.")
 	flags.BoolVarP(flagSet, &Opt.NoSeek, "no-seek", "", Opt.NoSeek, "Don't allow seeking in files.")
 	flags.DurationVarP(flagSet, &Opt.DirCacheTime, "dir-cache-time", "", Opt.DirCacheTime, "Time to cache directory entries for.")
+	flags.DurationVarP(flagSet, &Opt.NegativeCacheTime, "dir-cache-negative-time", "", Opt.NegativeCacheTime, "Time to remember a file or directory doesn't exist for.")
+	flags.BoolVarP(flagSet, &Opt.DirCachePersist, "dir-cache-persist", "", Opt.DirCachePersist, "Save the directory cache on exit and load it on the next start.")
+	flags.BoolVarP(flagSet, &Opt.Refresh, "vfs-refresh", "", Opt.Refresh, "Read all the directories in the background on start to fill the directory cache.")
 	flags.DurationVarP(flagSet, &Opt.PollInterval, "poll-interval", "", Opt.PollInterval, "Time to wait between polling for changes. Must be smaller than dir-cache-time. Only on supported remotes. Set to 0 to disable.")
-	flags.BoolVarP(flagSet, &Opt.ReadOnly, "read-only", "", Opt.ReadOnly, "Mount read-only.")
+	flags.BoolVarP(flagSet, &Opt.ReadOnly, "read-only", "", Opt.ReadOnly, "Only allow read-only access.")
 	flags.FVarP(flagSet, &Opt.CacheMode, "vfs-cache-mode", "", "Cache mode off|minimal|writes|full")
 	flags.DurationVarP(flagSet, &Opt.CachePollInterval, "vfs-cache-poll-interval", "", Opt.CachePollInterval, "Interval to poll the cache for stale objects.")
 	flags.DurationVarP(flagSet, &Opt.CacheMaxAge, "vfs-cache-max-age", "", Opt.CacheMaxAge, "Max age of objects in the cache.")
+	flags.FVarP(flagSet, &Opt.CacheMaxSize, "vfs-cache-max-size", "", "Max total size of objects in the cache.")
+	flags.FVarP(flagSet, &Opt.ReadCoalesce, "vfs-read-coalesce", "", "Read from the remote in one request when reads are this close together. 0 to disable.")
+	flags.BoolVarP(flagSet, &Opt.WriteBack, "vfs-write-back", "", Opt.WriteBack, "Upload files from the cache in the background after they are closed.")
+	flags.IntVarP(flagSet, &Opt.WriteBackUploads, "vfs-write-back-uploads", "", Opt.WriteBackUploads, "Number of files to upload in the background at once.")
+	flags.DurationVarP(flagSet, &Opt.WriteBackBackoff, "vfs-write-back-max-backoff", "", Opt.WriteBackBackoff, "Max time to wait before retrying a failed background upload.")
 	platformFlags(flagSet)
 }
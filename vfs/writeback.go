@@ -0,0 +1,427 @@
+// Upload files from the cache to the remote in the background
+
+package vfs
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/lib/kv"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context" // switch to "context" when we stop supporting go1.6
+)
+
+// writeBackMinBackoff is the time to wait before retrying the first
+// failed upload of a file.  This doubles for each failure up to
+// Options.WriteBackBackoff.
+const writeBackMinBackoff = time.Second
+
+// writeBackItem is a file in the cache waiting to be uploaded
+type writeBackItem struct {
+	Tries     int       // number of failed uploads
+	nextTry   time.Time // don't upload before this time
+	file      *File     // file to update when uploaded - may be nil
+	uploading bool      // set if an upload is in progress
+	again     bool      // set if the file was changed during the upload
+	attempts  int       // number of uploads finished
+	err       error     // error from the last upload
+}
+
+// writeBack is a queue of files in the cache to upload to the remote
+//
+// The queue is saved in the key-value store so uploads which haven't
+// finished when rclone stops are done when it is next started.
+//
+// The methods which look at the queue may be called on a nil
+// *writeBack, which is used when --vfs-write-back is off, in which
+// case the queue is empty.
+type writeBack struct {
+	c     *cache
+	f     fs.Fs    // remote to upload to
+	opt   *Options // vfs Options
+	mu    sync.Mutex
+	cond  *sync.Cond                // signalled when an upload finishes
+	items map[string]*writeBackItem // files waiting to be uploaded
+	kick  chan struct{}             // wakes up a worker
+	db    *kv.DB                    // the store the queue is saved in - nil if it isn't saved
+	ns    *kv.Namespace             // the namespace of the queue in db
+}
+
+// newWriteBack makes a write back queue for the cache and starts the
+// workers which do the uploads.
+//
+// The workers stop and the store is closed when the context is
+// cancelled.
+func newWriteBack(ctx context.Context, c *cache, f fs.Fs, opt *Options) *writeBack {
+	wb := &writeBack{
+		c:     c,
+		f:     f,
+		opt:   opt,
+		items: make(map[string]*writeBackItem),
+		kick:  make(chan struct{}, 1),
+	}
+	wb.cond = sync.NewCond(&wb.mu)
+	wb.open()
+	wb.load()
+	uploads := opt.WriteBackUploads
+	if uploads < 1 {
+		uploads = 1
+	}
+	for i := 0; i < uploads; i++ {
+		go wb.worker(ctx)
+	}
+	go func() {
+		<-ctx.Done()
+		wb.close()
+	}()
+	return wb
+}
+
+// open opens the key-value store the queue is saved in.  If it can't
+// be opened the queue isn't saved.
+func (wb *writeBack) open() {
+	db, err := openStateDB(wb.f)
+	if err == kv.ErrUnsupported {
+		return
+	} else if err != nil {
+		fs.Errorf(nil, "Files waiting to be uploaded from the cache won't be uploaded if rclone stops: %v", err)
+		return
+	}
+	wb.db = db
+	wb.ns = db.Namespace("writeback")
+}
+
+// close closes the key-value store the queue is saved in
+func (wb *writeBack) close() {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	if wb.db == nil {
+		return
+	}
+	err := wb.db.Close()
+	if err != nil {
+		fs.Errorf(nil, "Failed to close the upload queue: %v", err)
+	}
+	wb.db, wb.ns = nil, nil
+}
+
+// load reads the uploads which were waiting when rclone last stopped
+func (wb *writeBack) load() {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	if wb.ns == nil {
+		return
+	}
+	keys, err := wb.ns.Keys()
+	if err != nil {
+		fs.Errorf(nil, "Failed to read the files waiting to be uploaded from the cache: %v", err)
+		return
+	}
+	for _, name := range keys {
+		item := new(writeBackItem)
+		err = wb.ns.GetJSON(name, item)
+		if err == nil {
+			_, err = wb.c.f.NewObject(name)
+		}
+		if err != nil {
+			fs.Errorf(name, "Can't resume upload from cache: %v", err)
+			wb._delete(name)
+			continue
+		}
+		item.nextTry = time.Now()
+		wb.items[name] = item
+		// keep the file in the cache until it is uploaded
+		wb.c.open(name)
+	}
+	if len(wb.items) > 0 {
+		fs.Logf(nil, "Resuming upload of %d files from the cache", len(wb.items))
+	}
+}
+
+// _save writes item to the key-value store
+//
+// call with the lock held
+func (wb *writeBack) _save(name string, item *writeBackItem) {
+	if wb.ns == nil {
+		return
+	}
+	err := wb.ns.PutJSON(name, item)
+	if err != nil {
+		fs.Errorf(name, "Failed to save upload to the queue: %v", err)
+	}
+}
+
+// _delete removes name from the key-value store
+//
+// call with the lock held
+func (wb *writeBack) _delete(name string) {
+	if wb.ns == nil {
+		return
+	}
+	err := wb.ns.Delete(name)
+	if err != nil {
+		fs.Errorf(name, "Failed to remove upload from the queue: %v", err)
+	}
+}
+
+// _kick wakes up a worker if one is waiting
+//
+// call with the lock held
+func (wb *writeBack) _kick() {
+	select {
+	case wb.kick <- struct{}{}:
+	default:
+	}
+}
+
+// add queues the cache file for name to be uploaded to the remote.
+//
+// file is updated with the new object when the upload is done.
+//
+// name should be a remote path not an osPath
+func (wb *writeBack) add(name string, file *File) {
+	name = clean(name)
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	item, found := wb.items[name]
+	if !found {
+		item = new(writeBackItem)
+		wb.items[name] = item
+		// keep the file in the cache until it is uploaded
+		wb.c.open(name)
+	}
+	fs.Debugf(name, "Queued for upload from cache")
+	item.file = file
+	item.Tries = 0
+	item.nextTry = time.Now()
+	if item.uploading {
+		item.again = true
+	}
+	wb._save(name, item)
+	wb._kick()
+}
+
+// pending returns true if name is waiting to be uploaded
+//
+// name should be a remote path not an osPath
+func (wb *writeBack) pending(name string) bool {
+	if wb == nil {
+		return false
+	}
+	name = clean(name)
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	_, found := wb.items[name]
+	return found
+}
+
+// queued returns the number of files waiting to be uploaded
+func (wb *writeBack) queued() int {
+	if wb == nil {
+		return 0
+	}
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	return len(wb.items)
+}
+
+// inDir returns true if name is dir or is inside dir
+func inDir(dir, name string) bool {
+	return dir == "" || name == dir || strings.HasPrefix(name, dir+"/")
+}
+
+// wait uploads the file name, or the files in the directory name,
+// straight away if they are queued and waits for the uploads to
+// finish.
+//
+// It returns the error from the first failed upload.
+//
+// name should be a remote path not an osPath
+func (wb *writeBack) wait(name string) error {
+	if wb == nil {
+		return nil
+	}
+	name = clean(name)
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	start := map[*writeBackItem]int{}
+	for {
+		waiting := false
+		for itemName, item := range wb.items {
+			if !inDir(name, itemName) {
+				continue
+			}
+			if _, found := start[item]; !found {
+				start[item] = item.attempts
+				item.nextTry = time.Now()
+			}
+			if item.attempts > start[item] && item.err != nil {
+				return item.err
+			}
+			waiting = true
+		}
+		if !waiting {
+			return nil
+		}
+		wb._kick()
+		wb.cond.Wait()
+	}
+}
+
+// remove takes name out of the queue, eg because it has been
+// deleted, waiting for any upload in progress to finish first.
+//
+// name should be a remote path not an osPath
+func (wb *writeBack) remove(name string) {
+	if wb == nil {
+		return
+	}
+	name = clean(name)
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	for {
+		item, found := wb.items[name]
+		if !found {
+			return
+		}
+		if !item.uploading {
+			fs.Debugf(name, "Removed from upload queue")
+			wb._remove(name)
+			return
+		}
+		wb.cond.Wait()
+	}
+}
+
+// _remove takes name out of the queue
+//
+// call with the lock held
+func (wb *writeBack) _remove(name string) {
+	delete(wb.items, name)
+	wb._delete(name)
+	wb.c.close(name)
+	wb.cond.Broadcast()
+}
+
+// next finds the next file which is ready to upload and marks it as
+// uploading.
+//
+// If there isn't one it returns how long to wait until there is, or
+// 0 if there isn't anything queued.
+func (wb *writeBack) next() (name string, item *writeBackItem, wait time.Duration) {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	now := time.Now()
+	for itemName, it := range wb.items {
+		if it.uploading {
+			continue
+		}
+		if !it.nextTry.After(now) {
+			if item == nil || it.nextTry.Before(item.nextTry) {
+				name, item = itemName, it
+			}
+			continue
+		}
+		if dt := it.nextTry.Sub(now); wait == 0 || dt < wait {
+			wait = dt
+		}
+	}
+	if item != nil {
+		item.uploading = true
+		item.again = false
+		// wake up another worker in case there is more to do
+		wb._kick()
+	}
+	return name, item, wait
+}
+
+// worker uploads files from the queue until the context is cancelled
+func (wb *writeBack) worker(ctx context.Context) {
+	for {
+		name, item, wait := wb.next()
+		if item != nil {
+			wb.upload(name, item)
+			continue
+		}
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timeout = time.After(wait)
+		}
+		select {
+		case <-wb.kick:
+		case <-timeout:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// upload transfers name to the remote and updates the queue with the
+// result, scheduling a retry if it failed.
+func (wb *writeBack) upload(name string, item *writeBackItem) {
+	wb.mu.Lock()
+	file := item.file
+	wb.mu.Unlock()
+
+	err := wb.transfer(name, file)
+
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	item.uploading = false
+	item.attempts++
+	item.err = err
+	defer wb.cond.Broadcast()
+	if err != nil {
+		item.Tries++
+		backoff := writeBackMinBackoff << uint(item.Tries-1)
+		if backoff > wb.opt.WriteBackBackoff || backoff <= 0 {
+			backoff = wb.opt.WriteBackBackoff
+		}
+		if item.again {
+			backoff = 0
+		}
+		item.nextTry = time.Now().Add(backoff)
+		fs.Errorf(name, "Failed to upload from cache (try %d) - retrying in %v: %v", item.Tries, backoff, err)
+		wb._save(name, item)
+		return
+	}
+	if item.again {
+		// upload it again as it changed during the upload
+		item.Tries = 0
+		item.nextTry = time.Now()
+		wb._kick()
+		return
+	}
+	if wb.items[name] == item {
+		wb._remove(name)
+	}
+}
+
+// transfer copies the cache file for name to the remote, updating
+// file with the new object if it is set.
+func (wb *writeBack) transfer(name string, file *File) error {
+	cacheObj, err := wb.c.f.NewObject(name)
+	if err != nil {
+		return errors.Wrap(err, "failed to find cache file")
+	}
+	var dst fs.Object
+	if file != nil {
+		dst = file.getObject()
+	} else {
+		dst, err = wb.f.NewObject(name)
+		if err != nil {
+			dst = nil
+		}
+	}
+	o, err := copyObj(wb.f, dst, name, cacheObj)
+	if err != nil {
+		return errors.Wrap(err, "failed to transfer file from cache to remote")
+	}
+	if file != nil {
+		file.setObject(o)
+	}
+	fs.Debugf(o, "transferred to remote")
+	return nil
+}
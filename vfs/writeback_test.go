@@ -0,0 +1,198 @@
+package vfs
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fstest"
+	"github.com/ncw/rclone/lib/kv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newWriteBackVFS makes a VFS which uploads files in the background
+func newWriteBackVFS(r *fstest.Run) *VFS {
+	opt := DefaultOpt
+	opt.CacheMode = CacheModeWrites
+	opt.WriteBack = true
+	opt.WriteBackBackoff = 10 * time.Millisecond
+	return New(r.Fremote, &opt)
+}
+
+// checkRemoteContents checks remote on r.Fremote has contents
+func checkRemoteContents(t *testing.T, r *fstest.Run, remote, contents string) {
+	o, err := r.Fremote.NewObject(remote)
+	require.NoError(t, err)
+	in, err := o.Open()
+	require.NoError(t, err)
+	got, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, contents, string(got))
+}
+
+// stopWriteBack replaces the write back queue of vfs with one without
+// any workers so files stay in the queue
+func stopWriteBack(vfs *VFS) *writeBack {
+	wb := &writeBack{
+		c:     vfs.cache,
+		f:     vfs.f,
+		opt:   &vfs.Opt,
+		items: make(map[string]*writeBackItem),
+		kick:  make(chan struct{}, 1),
+	}
+	wb.cond = sync.NewCond(&wb.mu)
+	vfs.cache.writeBack = wb
+	return wb
+}
+
+// writeFile writes contents to name in vfs
+func writeFile(t *testing.T, vfs *VFS, name, contents string) {
+	h, err := vfs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0777)
+	require.NoError(t, err)
+	_, err = h.WriteString(contents)
+	require.NoError(t, err)
+	require.NoError(t, h.Close())
+}
+
+func TestWriteBackOff(t *testing.T) {
+	r := fstest.NewRun(t)
+	opt := DefaultOpt
+	opt.CacheMode = CacheModeWrites
+	vfs := New(r.Fremote, &opt)
+	defer cleanup(t, r, vfs)
+
+	assert.Nil(t, vfs.cache.writeBack)
+	writeFile(t, vfs, "file1", "hello")
+	checkRemoteContents(t, r, "file1", "hello")
+}
+
+func TestWriteBackListing(t *testing.T) {
+	r := fstest.NewRun(t)
+	vfs := newWriteBackVFS(r)
+	defer cleanup(t, r, vfs)
+	r.WriteObject("existing", "old contents", t1)
+	checkListing(t, vfs.root, []string{"existing,12,false"})
+	wb := stopWriteBack(vfs)
+
+	writeFile(t, vfs, "existing", "hello")
+	writeFile(t, vfs, "new", "potato")
+	assert.True(t, wb.pending("existing"))
+	assert.True(t, wb.pending("new"))
+
+	// the queued files and their sizes are kept when the directory
+	// is read again from the remote
+	require.NoError(t, vfs.root.refresh())
+	checkListing(t, vfs.root, []string{"existing,5,false", "new,6,false"})
+
+	vfs.root.mu.Lock()
+	vfs.root.read = time.Time{}
+	vfs.root.mu.Unlock()
+	var names []string
+	require.NoError(t, vfs.root.ReadDirPages(func(items Nodes) error {
+		for _, item := range items {
+			names = append(names, item.Name())
+		}
+		return nil
+	}))
+	sort.Strings(names)
+	assert.Equal(t, []string{"existing", "new"}, names)
+
+	wb.remove("existing")
+	wb.remove("new")
+}
+
+func TestWriteBackUpload(t *testing.T) {
+	r := fstest.NewRun(t)
+	vfs := newWriteBackVFS(r)
+	defer cleanup(t, r, vfs)
+
+	h, err := vfs.OpenFile("file1", os.O_WRONLY|os.O_CREATE, 0777)
+	require.NoError(t, err)
+	_, err = h.WriteString("hello")
+	require.NoError(t, err)
+	require.NoError(t, h.Close())
+
+	vfs.WaitForWriters(10 * time.Second)
+	assert.Equal(t, 0, vfs.cache.writeBack.queued())
+	assert.Equal(t, 0, vfs.cache.opens("file1"))
+	checkRemoteContents(t, r, "file1", "hello")
+
+	// the file knows about the uploaded object
+	node, err := vfs.Stat("file1")
+	require.NoError(t, err)
+	assert.NotNil(t, node.(*File).getObject())
+}
+
+func TestWriteBackRename(t *testing.T) {
+	r := fstest.NewRun(t)
+	vfs := newWriteBackVFS(r)
+	defer cleanup(t, r, vfs)
+
+	h, err := vfs.OpenFile("file1", os.O_WRONLY|os.O_CREATE, 0777)
+	require.NoError(t, err)
+	_, err = h.WriteString("potato")
+	require.NoError(t, err)
+	require.NoError(t, h.Close())
+
+	// renaming waits for the upload
+	require.NoError(t, vfs.Rename("file1", "file2"))
+	assert.Equal(t, 0, vfs.cache.writeBack.queued())
+	checkRemoteContents(t, r, "file2", "potato")
+}
+
+func TestWriteBackRetry(t *testing.T) {
+	r := fstest.NewRun(t)
+	vfs := newWriteBackVFS(r)
+	defer cleanup(t, r, vfs)
+	wb := vfs.cache.writeBack
+
+	// there is no cache file so the upload fails
+	wb.add("missing", nil)
+	assert.True(t, wb.pending("missing"))
+	err := wb.wait("missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to find cache file")
+
+	// it is retried until removed from the queue
+	err = wb.wait("")
+	require.Error(t, err)
+	wb.mu.Lock()
+	assert.True(t, wb.items["missing"].Tries >= 2)
+	wb.mu.Unlock()
+
+	wb.remove("missing")
+	assert.False(t, wb.pending("missing"))
+	assert.Equal(t, 0, vfs.cache.opens("missing"))
+}
+
+func TestWriteBackResume(t *testing.T) {
+	r := fstest.NewRun(t)
+	vfs := newWriteBackVFS(r)
+	defer cleanup(t, r, vfs)
+	wb := vfs.cache.writeBack
+
+	// Pretend rclone stopped with a file waiting to upload
+	osPath, err := vfs.cache.mkdir("dir/file1")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(osPath, []byte("resumed"), 0600))
+	if wb.ns == nil {
+		t.Skip("key-value store not supported")
+	}
+	require.NoError(t, wb.ns.PutJSON("dir/file1", &writeBackItem{Tries: 3}))
+
+	// a new VFS uploads it
+	vfs2 := newWriteBackVFS(r)
+	defer vfs2.Shutdown()
+	vfs2.WaitForWriters(10 * time.Second)
+	assert.Equal(t, 0, vfs2.cache.writeBack.queued())
+	checkRemoteContents(t, r, "dir/file1", "resumed")
+
+	// and it is removed from the store
+	_, err = wb.ns.Get("dir/file1")
+	assert.Equal(t, kv.ErrNotFound, err)
+}
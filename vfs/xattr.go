@@ -0,0 +1,235 @@
+// Extended attributes stored in the metadata of objects
+
+package vfs
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+)
+
+// xattrBase64Prefix marks a metadata value which is a base64 encoded
+// extended attribute value
+const xattrBase64Prefix = "base64:"
+
+// xattrNamespace returns the prefix extended attribute names need to
+// be user extended attributes, which are the only ones stored
+func xattrNamespace() string {
+	if runtime.GOOS == "linux" {
+		return "user."
+	}
+	return ""
+}
+
+// isXattrKeyChar returns true if c can be used as is in a metadata
+// key.  Upper case letters aren't used as metadata keys are case
+// insensitive.
+func isXattrKeyChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.'
+}
+
+// xattrToKey converts an extended attribute name into a metadata key
+//
+// Metadata keys are stored as HTTP headers so any characters which
+// can't be used in them, and upper case letters, are encoded as %xx.
+//
+// It returns ENOTSUP if name isn't a user extended attribute.
+func xattrToKey(name string) (string, error) {
+	namespace := xattrNamespace()
+	if !strings.HasPrefix(name, namespace) {
+		return "", ENOTSUP
+	}
+	name = name[len(namespace):]
+	if name == "" {
+		return "", EINVAL
+	}
+	var out bytes.Buffer
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if isXattrKeyChar(c) {
+			out.WriteByte(c)
+		} else {
+			fmt.Fprintf(&out, "%%%02x", c)
+		}
+	}
+	return out.String(), nil
+}
+
+// keyToXattr converts a metadata key into an extended attribute name
+func keyToXattr(key string) string {
+	key = strings.ToLower(key)
+	var out bytes.Buffer
+	out.WriteString(xattrNamespace())
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c == '%' && i+2 < len(key) {
+			if b, err := strconv.ParseUint(key[i+1:i+3], 16, 8); err == nil {
+				out.WriteByte(byte(b))
+				i += 2
+				continue
+			}
+		}
+		out.WriteByte(c)
+	}
+	return out.String()
+}
+
+// encodeXattrValue converts an extended attribute value into a
+// metadata value.
+//
+// Values which aren't printable ASCII, or which could be changed by
+// being stored in an HTTP header, are base64 encoded.
+func encodeXattrValue(value []byte) string {
+	s := string(value)
+	encode := strings.HasPrefix(s, xattrBase64Prefix) || strings.TrimSpace(s) != s
+	for _, c := range value {
+		if c < 0x20 || c > 0x7e {
+			encode = true
+			break
+		}
+	}
+	if encode {
+		return xattrBase64Prefix + base64.StdEncoding.EncodeToString(value)
+	}
+	return s
+}
+
+// decodeXattrValue converts a metadata value into an extended
+// attribute value
+func decodeXattrValue(s string) []byte {
+	if strings.HasPrefix(s, xattrBase64Prefix) {
+		value, err := base64.StdEncoding.DecodeString(s[len(xattrBase64Prefix):])
+		if err == nil {
+			return value
+		}
+	}
+	return []byte(s)
+}
+
+// findXattr returns the key in metadata which matches key, comparing
+// case insensitively
+func findXattr(metadata map[string]string, key string) (string, bool) {
+	for k := range metadata {
+		if strings.EqualFold(k, key) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// metadata returns the user metadata of the file, or nil if it
+// doesn't have any
+func (f *File) metadata() (map[string]string, error) {
+	o := f.getObject()
+	if o == nil {
+		return nil, nil
+	}
+	o, err := realObject(o)
+	if err != nil {
+		return nil, err
+	}
+	do, ok := o.(fs.Metadataer)
+	if !ok {
+		return nil, nil
+	}
+	return do.Metadata()
+}
+
+// ListXattr returns the names of the extended attributes of the file
+func (f *File) ListXattr() ([]string, error) {
+	metadata, err := f.metadata()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(metadata))
+	for k := range metadata {
+		names = append(names, keyToXattr(k))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// GetXattr returns the value of the extended attribute name of the
+// file.
+//
+// It returns ENOATTR if it isn't found.
+func (f *File) GetXattr(name string) ([]byte, error) {
+	key, err := xattrToKey(name)
+	if err != nil {
+		return nil, ENOATTR
+	}
+	metadata, err := f.metadata()
+	if err != nil {
+		return nil, err
+	}
+	k, found := findXattr(metadata, key)
+	if !found {
+		return nil, ENOATTR
+	}
+	return decodeXattrValue(metadata[k]), nil
+}
+
+// updateXattr calls fn with a copy of the metadata of the file then
+// stores the result
+func (f *File) updateXattr(name string, fn func(metadata map[string]string, key string) error) error {
+	if f.d.vfs.Opt.ReadOnly {
+		return EROFS
+	}
+	key, err := xattrToKey(name)
+	if err != nil {
+		return err
+	}
+	o := f.getObject()
+	getter, ok := o.(fs.Metadataer)
+	if !ok {
+		return ENOTSUP
+	}
+	setter, ok := o.(fs.SetMetadataer)
+	if !ok {
+		return ENOTSUP
+	}
+	old, err := getter.Metadata()
+	if err != nil {
+		return err
+	}
+	metadata := make(map[string]string, len(old)+1)
+	for k, v := range old {
+		metadata[k] = v
+	}
+	err = fn(metadata, key)
+	if err != nil {
+		return err
+	}
+	return setter.SetMetadata(metadata)
+}
+
+// SetXattr sets the extended attribute name of the file to value
+func (f *File) SetXattr(name string, value []byte) error {
+	return f.updateXattr(name, func(metadata map[string]string, key string) error {
+		if k, found := findXattr(metadata, key); found {
+			delete(metadata, k)
+		}
+		metadata[key] = encodeXattrValue(value)
+		return nil
+	})
+}
+
+// RemoveXattr removes the extended attribute name from the file.
+//
+// It returns ENOATTR if it isn't found.
+func (f *File) RemoveXattr(name string) error {
+	return f.updateXattr(name, func(metadata map[string]string, key string) error {
+		k, found := findXattr(metadata, key)
+		if !found {
+			return ENOATTR
+		}
+		delete(metadata, k)
+		return nil
+	})
+}
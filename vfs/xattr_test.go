@@ -0,0 +1,139 @@
+package vfs
+
+import (
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXattrKey(t *testing.T) {
+	ns := xattrNamespace()
+	for _, test := range []struct {
+		name string
+		key  string
+	}{
+		{"colour", "colour"},
+		{"mime_type", "mime_type"},
+		{"Colour", "%43olour"},
+		{"com.apple.metadata:_kMDItemUserTags", "com.apple.metadata%3a_k%4d%44%49tem%55ser%54ags"},
+		{"a b%", "a%20b%25"},
+	} {
+		key, err := xattrToKey(ns + test.name)
+		require.NoError(t, err, test.name)
+		assert.Equal(t, test.key, key, test.name)
+		assert.Equal(t, ns+test.name, keyToXattr(key), test.name)
+		// keys may come back in a different case
+		assert.Equal(t, ns+test.name, keyToXattr(canonicalKey(key)), test.name)
+	}
+
+	_, err := xattrToKey(ns)
+	assert.Equal(t, EINVAL, err)
+	if ns != "" {
+		_, err = xattrToKey("security.selinux")
+		assert.Equal(t, ENOTSUP, err)
+	}
+
+	// keys set by other programs
+	assert.Equal(t, ns+"colour", keyToXattr("Colour"))
+	assert.Equal(t, ns+"100%", keyToXattr("100%"))
+	assert.Equal(t, ns+"%zz", keyToXattr("%zz"))
+}
+
+// canonicalKey returns key in the canonical form for HTTP headers
+func canonicalKey(key string) string {
+	out := []byte(key)
+	upper := true
+	for i, c := range out {
+		if upper && c >= 'a' && c <= 'z' {
+			out[i] = c - 'a' + 'A'
+		}
+		upper = c == '-'
+	}
+	return string(out)
+}
+
+func TestXattrValue(t *testing.T) {
+	for _, test := range []struct {
+		value   string
+		encoded string
+	}{
+		{"", ""},
+		{"blue", "blue"},
+		{"light blue", "light blue"},
+		{" blue", "base64:IGJsdWU="},
+		{"base64:", "base64:YmFzZTY0Og=="},
+		{"\x00\x01\xff", "base64:AAH/"},
+		{"bleué", "base64:YmxldcOp"},
+	} {
+		assert.Equal(t, test.encoded, encodeXattrValue([]byte(test.value)), test.value)
+		assert.Equal(t, test.value, string(decodeXattrValue(test.encoded)), test.value)
+	}
+	assert.Equal(t, "base64:!", string(decodeXattrValue("base64:!")))
+}
+
+// metadataObject is an fs.Object with user metadata
+type metadataObject struct {
+	fs.Object
+	metadata map[string]string
+}
+
+// Metadata returns the user metadata of the object
+func (o *metadataObject) Metadata() (map[string]string, error) {
+	return o.metadata, nil
+}
+
+// SetMetadata replaces the user metadata of the object
+func (o *metadataObject) SetMetadata(metadata map[string]string) error {
+	o.metadata = metadata
+	return nil
+}
+
+func TestFileXattr(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	vfs, file, _ := fileCreate(t, r)
+	ns := xattrNamespace()
+
+	// No metadata support
+	names, err := file.ListXattr()
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, names)
+	_, err = file.GetXattr(ns + "colour")
+	assert.Equal(t, ENOATTR, err)
+	assert.Equal(t, ENOTSUP, file.SetXattr(ns+"colour", []byte("blue")))
+
+	o := &metadataObject{
+		Object:   file.getObject(),
+		metadata: map[string]string{"Colour": "red"},
+	}
+	file.setObjectNoUpdate(o)
+
+	names, err = file.ListXattr()
+	require.NoError(t, err)
+	assert.Equal(t, []string{ns + "colour"}, names)
+
+	value, err := file.GetXattr(ns + "colour")
+	require.NoError(t, err)
+	assert.Equal(t, "red", string(value))
+
+	_, err = file.GetXattr(ns + "size")
+	assert.Equal(t, ENOATTR, err)
+
+	require.NoError(t, file.SetXattr(ns+"colour", []byte("blue")))
+	require.NoError(t, file.SetXattr(ns+"bytes", []byte{0, 1}))
+	assert.Equal(t, map[string]string{"colour": "blue", "bytes": "base64:AAE="}, o.metadata)
+
+	value, err = file.GetXattr(ns + "bytes")
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 1}, value)
+
+	require.NoError(t, file.RemoveXattr(ns+"colour"))
+	assert.Equal(t, ENOATTR, file.RemoveXattr(ns+"colour"))
+	assert.Equal(t, map[string]string{"bytes": "base64:AAE="}, o.metadata)
+
+	vfs.Opt.ReadOnly = true
+	assert.Equal(t, EROFS, file.SetXattr(ns+"colour", []byte("blue")))
+}